@@ -5,6 +5,7 @@ import { Layout } from './components/Layout';
 import { Login } from './components/Login';
 import { Dashboard } from './components/Dashboard';
 import { AttackList } from './components/AttackList';
+import { ThreatSearch } from './components/ThreatSearch';
 import { AttackSource } from './components/AttackSource';
 import { AccountResources } from './components/AccountResources';
 import { ScanningPerception } from './components/ScanningPerception';
@@ -16,6 +17,9 @@ import { TemplateManagement } from './components/TemplateManagement';
 import { ServiceManagement } from './components/ServiceManagement';
 import { ReportManagement } from './components/ReportManagement';
 import { SystemConfig } from './components/SystemConfig';
+import { NotificationChannels } from './components/NotificationChannels';
+import { SiemExport } from './components/SiemExport';
+import { SensorIngest } from './components/SensorIngest';
 import { SystemInfo } from './components/SystemInfo';
 import { MessageCenter } from './components/MessageCenter';
 import { AccessControl } from './components/AccessControl';
@@ -85,6 +89,7 @@ const AppContent: React.FC = () => {
           <Route path="/threat-perception/list" element={<AttackList />} />
           <Route path="/threat-perception/scanning" element={<ScanningPerception />} />
           <Route path="/threat-perception/compromise" element={<CompromisePerception />} />
+          <Route path="/threat-perception/search" element={<ThreatSearch />} />
           <Route path="/threat-perception/*" element={<ConstructionPage title="Threat Perception" />} />
           
           <Route path="/threat-entities/sources" element={<AttackSource />} />
@@ -102,6 +107,9 @@ const AppContent: React.FC = () => {
           <Route path="/env-management/services" element={<ServiceManagement />} />
           
           <Route path="/system/config" element={<SystemConfig />} />
+          <Route path="/system/notifications" element={<NotificationChannels />} />
+          <Route path="/system/siem" element={<SiemExport />} />
+          <Route path="/system/ingest" element={<SensorIngest />} />
           <Route path="/system/info" element={<SystemInfo />} />
           <Route path="/system/reports" element={<ReportManagement />} />
           
@@ -27,6 +27,8 @@ interface AppContextType {
   markAllRead: () => void;
   deleteMessage: (id: string) => void;
   toggleRead: (id: string) => void;
+  upsertMessage: (msg: Message) => void;
+  refreshUnread: () => void;
   // Module State
   modules: ModuleState;
   toggleModule: (key: keyof ModuleState) => void;
@@ -58,6 +60,7 @@ export const AppProvider: React.FC<{ children: ReactNode }> = ({ children }) =>
 
   // Message State
   const [messages, setMessages] = useState<Message[]>(MOCK_MESSAGES_ZH);
+  const [unreadCount, setUnreadCount] = useState(0);
 
   // Module State (Global Switch State)
   const [modules, setModules] = useState<ModuleState>({
@@ -150,13 +153,9 @@ export const AppProvider: React.FC<{ children: ReactNode }> = ({ children }) =>
           
           if (msgRes.ok) {
             const rawMessages = await msgRes.json();
-            setMessages(rawMessages.map((msg: any) => ({
-              ...msg,
-              title: translateMessage(msg.title, lang),
-              content: translateMessage(msg.content, lang),
-              time: formatTime(msg.time)
-            })));
+            setMessages(rawMessages.map((msg: any) => localizeMessage(msg, lang)));
           }
+          refreshUnread();
           if (attackRes.ok) setAttacks(await attackRes.json());
         } catch (e) {
           console.error("Failed to fetch initial data", e);
@@ -172,7 +171,9 @@ export const AppProvider: React.FC<{ children: ReactNode }> = ({ children }) =>
     if (paramsStr) {
       const params: any = {};
       paramsStr.split(',').forEach(p => {
-        const [k, v] = p.split(':');
+        // Values may hold colons, e.g. addresses and error texts
+        const [k, ...rest] = p.split(':');
+        const v = rest.join(':');
         if (k && v) params[k] = v;
       });
       return t(key, currentLang, params);
@@ -180,17 +181,41 @@ export const AppProvider: React.FC<{ children: ReactNode }> = ({ children }) =>
     return t(text, currentLang);
   };
 
+  const localizeMessage = (msg: any, currentLang: Lang): Message => ({
+    ...msg,
+    title: translateMessage(msg.title, currentLang),
+    content: translateMessage(msg.content, currentLang),
+    time: formatTime(msg.time),
+    lastSeen: msg.lastSeen ? formatTime(msg.lastSeen) : undefined
+  });
+
   const processedMessageIds = React.useRef(new Set<string>());
 
   // WebSocket Connection
   useEffect(() => {
     let socket: WebSocket | null = null;
     let reconnectTimer: NodeJS.Timeout;
+    let stopped = false;
 
-    const connect = () => {
+    const connect = async () => {
       const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
       const host = window.location.host === 'localhost:3000' ? 'localhost:8080' : window.location.host;
-      socket = new WebSocket(`${protocol}//${host}/api/v1/ws`);
+      // A single use ticket tells the server whose subscription filters the message pushes,
+      // the session token itself stays out of the URL
+      let query = '';
+      if (localStorage.getItem('prts_token')) {
+        try {
+          const res = await authFetch('/api/v1/ws-ticket', { method: 'POST' });
+          if (res.ok) {
+            const data = await res.json();
+            query = `?ticket=${encodeURIComponent(data.ticket)}`;
+          }
+        } catch (e) {
+          console.error("WS ticket error:", e);
+        }
+      }
+      if (stopped) return;
+      socket = new WebSocket(`${protocol}//${host}/api/v1/ws${query}`);
 
       socket.onmessage = (event) => {
         try {
@@ -215,24 +240,24 @@ export const AppProvider: React.FC<{ children: ReactNode }> = ({ children }) =>
             }
             processedMessageIds.current.add(msg.id);
 
-            const translatedMsg = {
-              ...msg,
-              title: translateMessage(msg.title, lang),
-              content: translateMessage(msg.content, lang),
-              time: formatTime(msg.time)
-            };
+            const translatedMsg = localizeMessage(msg, lang);
             
             setMessages(prev => {
               // Double check state just in case
               if (prev.some(m => m.id === msg.id)) return prev;
               return [translatedMsg, ...prev];
             });
+            setUnreadCount(prev => prev + 1);
             
             notify(
               msg.type === 'security' ? 'error' : 'info',
               translatedMsg.title,
               translatedMsg.content
             );
+          } else if (message.type === 'MESSAGE_UPDATE') {
+            // A repeat folded into an alert or its lifecycle changed
+            upsertMessage(message.data);
+            refreshUnread();
           }
         } catch (e) {
           console.error("WS Message Error:", e);
@@ -252,13 +277,14 @@ export const AppProvider: React.FC<{ children: ReactNode }> = ({ children }) =>
 
     connect();
     return () => {
+      stopped = true;
       if (socket) {
         socket.onclose = null; // Prevent reconnect loop on unmount
         socket.close();
       }
       clearTimeout(reconnectTimer);
     };
-  }, []);
+  }, [user]);
 
   const toggleModule = async (key: keyof ModuleState) => {
     const newState = !modules[key];
@@ -341,17 +367,20 @@ export const AppProvider: React.FC<{ children: ReactNode }> = ({ children }) =>
       const res = await authFetch('/api/v1/messages/read-all', { method: 'POST' });
       if (res.ok) {
         setMessages(prev => prev.map(msg => ({ ...msg, read: true })));
+        setUnreadCount(0);
       }
     } catch (e) {
       console.error("Failed to mark all read", e);
     }
   };
 
+  // Dismisses the message for this user only
   const deleteMessage = async (id: string) => {
     try {
       const res = await authFetch(`/api/v1/messages/${id}`, { method: 'DELETE' });
       if (res.ok) {
         setMessages(prev => prev.filter(msg => msg.id !== id));
+        refreshUnread();
         return true;
       }
     } catch (e) {
@@ -360,17 +389,49 @@ export const AppProvider: React.FC<{ children: ReactNode }> = ({ children }) =>
     return false;
   };
 
-  const toggleRead = (id: string) => {
-    // For now, just local toggle or we could add a specific API
-    setMessages(prev => prev.map(msg => msg.id === id ? { ...msg, read: !msg.read } : msg));
+  const upsertMessage = (raw: any) => {
+    if (raw.archived) {
+      setMessages(prev => prev.filter(m => m.id !== raw.id));
+      return;
+    }
+    const msg = localizeMessage(raw, lang);
+    setMessages(prev => prev.some(m => m.id === msg.id)
+      ? prev.map(m => m.id === msg.id ? { ...m, ...msg } : m)
+      : [msg, ...prev]);
+  };
+
+  // The inbox only holds the latest page, the server counts all of it
+  const refreshUnread = async () => {
+    try {
+      const res = await authFetch('/api/v1/messages/unread-count');
+      if (res.ok) setUnreadCount((await res.json()).unread);
+    } catch (e) {
+      console.error("Failed to fetch unread count", e);
+    }
   };
 
-  const unreadCount = messages.filter(m => !m.read).length;
+  const toggleRead = async (id: string) => {
+    const current = messages.find(msg => msg.id === id);
+    if (!current) return;
+    try {
+      const res = await authFetch(`/api/v1/messages/${id}/read`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ read: !current.read })
+      });
+      if (res.ok) {
+        setMessages(prev => prev.map(msg => msg.id === id ? { ...msg, read: !current.read } : msg));
+        setUnreadCount(prev => Math.max(0, prev + (current.read ? 1 : -1)));
+      }
+    } catch (e) {
+      console.error("Failed to update message", e);
+    }
+  };
 
   return (
     <AppContext.Provider value={{ 
         user, login, logout, lang, toggleLang, darkMode, toggleTheme,
-        messages, unreadCount, markAllRead, deleteMessage, toggleRead,
+        messages, unreadCount, markAllRead, deleteMessage, toggleRead, upsertMessage, refreshUnread,
         modules, toggleModule, attacks, authFetch, loginPolicy
     }}>
       {children}
@@ -0,0 +1,126 @@
+// opensearch-backfill indexes the history in prts.db into OpenSearch or Elasticsearch,
+// the same documents the live output sends. It takes the settings of a configured output
+// by name, or a cluster given on the command line:
+//
+//	opensearch-backfill -output soc -since 2025-01-01
+//	opensearch-backfill -url http://localhost:9200 -kinds attack,scan
+//
+// Documents keep their IDs, running it again replaces them rather than adding copies.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"backend/internal/api"
+	"backend/internal/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var allKinds = []string{"attack", "scan", "credential", "decoy", "sample", "login"}
+
+func main() {
+	dbPath := flag.String("db", "prts.db", "SQLite database of the server")
+	output := flag.String("output", "", "name of a configured OpenSearch output")
+	clusterURL := flag.String("url", "", "cluster URL, instead of or over the output's")
+	username := flag.String("username", "", "basic auth user")
+	password := flag.String("password", "", "basic auth password")
+	apiKey := flag.String("api-key", "", "base64 id:key API key")
+	prefix := flag.String("prefix", "", "index prefix, prts unless the output sets one")
+	insecure := flag.Bool("insecure", false, "accept any TLS certificate")
+	kinds := flag.String("kinds", "", "comma-separated kinds to backfill, empty for all of "+strings.Join(allKinds, ","))
+	since := flag.String("since", "", "first day or time to include, 2006-01-02 or RFC 3339")
+	until := flag.String("until", "", "day or time to stop before")
+	batch := flag.Int("batch", 1000, "documents per bulk request")
+	flag.Parse()
+
+	// SQLite would create an empty database for a mistyped path
+	if _, err := os.Stat(*dbPath); err != nil {
+		log.Fatal(err)
+	}
+	db, err := gorm.Open(sqlite.Open(*dbPath), &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+		Logger:  logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		log.Fatalf("opening %s: %v", *dbPath, err)
+	}
+
+	var out model.OpenSearchOutput
+	if *output != "" {
+		if err := db.Where("name = ?", *output).First(&out).Error; err != nil {
+			log.Fatalf("no OpenSearch output named %q", *output)
+		}
+	} else if *clusterURL == "" {
+		fmt.Fprintln(os.Stderr, "either -output or -url is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	out.Name = "backfill"
+	if *clusterURL != "" {
+		out.URL = *clusterURL
+	}
+	if *username != "" {
+		out.Username, out.Password = *username, *password
+	}
+	if *apiKey != "" {
+		out.APIKey = *apiKey
+	}
+	if *prefix != "" {
+		out.IndexPrefix = *prefix
+	}
+	if *insecure {
+		out.InsecureSkipVerify = true
+	}
+
+	opts := api.BackfillOptions{BatchSize: *batch}
+	for _, kind := range strings.Split(*kinds, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			opts.Kinds = append(opts.Kinds, kind)
+		}
+	}
+	if opts.Since, err = parseTime(*since); err != nil {
+		log.Fatalf("-since: %v", err)
+	}
+	if opts.Until, err = parseTime(*until); err != nil {
+		log.Fatalf("-until: %v", err)
+	}
+	last := time.Now()
+	opts.Progress = func(kind string, indexed, rejected int) {
+		if time.Since(last) >= 2*time.Second {
+			log.Printf("%s: %d indexed, %d rejected", kind, indexed, rejected)
+			last = time.Now()
+		}
+	}
+
+	start := time.Now()
+	res, err := api.BackfillOpenSearch(db, out, opts)
+	total := 0
+	for _, kind := range allKinds {
+		if n, ok := res.Indexed[kind]; ok {
+			log.Printf("%s: %d indexed, %d rejected", kind, n, res.Rejected[kind])
+			total += n
+		}
+	}
+	if err != nil {
+		log.Fatalf("backfill stopped after %d documents: %v", total, err)
+	}
+	log.Printf("Indexed %d documents in %s", total, time.Since(start).Round(time.Millisecond))
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
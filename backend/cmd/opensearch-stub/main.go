@@ -0,0 +1,154 @@
+// opensearch-stub answers the few OpenSearch endpoints the bulk output uses, for trying it
+// out without a cluster. It keeps the documents in memory, prints every request and can
+// throttle like a busy cluster: -throttle answers whole bulk requests with 429, -reject
+// answers single documents with 429.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	mu        sync.Mutex
+	indices   = map[string]map[string]json.RawMessage{} // Documents by index and ID
+	templates = map[string]json.RawMessage{}
+)
+
+func main() {
+	addr := flag.String("addr", ":9200", "listen address")
+	throttle := flag.Float64("throttle", 0, "share of bulk requests refused with 429")
+	reject := flag.Float64("reject", 0, "share of documents refused with 429")
+	verbose := flag.Bool("v", false, "print every document")
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/" && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"name": "opensearch-stub", "cluster_name": "stub",
+				"version": map[string]string{"number": "2.11.0", "distribution": "opensearch"},
+			})
+		case strings.HasPrefix(r.URL.Path, "/_index_template/") && r.Method == http.MethodPut:
+			name := strings.TrimPrefix(r.URL.Path, "/_index_template/")
+			body, _ := io.ReadAll(r.Body)
+			if !json.Valid(body) {
+				writeJSON(w, http.StatusBadRequest, errorBody("parse_exception", "template is not JSON"))
+				return
+			}
+			mu.Lock()
+			templates[name] = body
+			mu.Unlock()
+			log.Printf("template %s installed (%d bytes)", name, len(body))
+			writeJSON(w, http.StatusOK, map[string]bool{"acknowledged": true})
+		case r.URL.Path == "/_bulk" && r.Method == http.MethodPost:
+			if rand.Float64() < *throttle {
+				log.Printf("bulk throttled")
+				w.Header().Set("Retry-After", "1")
+				writeJSON(w, http.StatusTooManyRequests, errorBody("es_rejected_execution_exception", "rejected execution, write queue full"))
+				return
+			}
+			bulk(w, r, *reject, *verbose)
+		case r.URL.Path == "/_cat/indices" && r.Method == http.MethodGet:
+			mu.Lock()
+			names := make([]string, 0, len(indices))
+			for name := range indices {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintf(w, "%s %d\n", name, len(indices[name]))
+			}
+			mu.Unlock()
+		default:
+			writeJSON(w, http.StatusNotFound, errorBody("not_found", r.Method+" "+r.URL.Path+" is not stubbed"))
+		}
+	})
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func bulk(w http.ResponseWriter, r *http.Request, reject float64, verbose bool) {
+	type itemResult struct {
+		Index  string      `json:"_index"`
+		ID     string      `json:"_id"`
+		Status int         `json:"status"`
+		Result string      `json:"result,omitempty"`
+		Error  interface{} `json:"error,omitempty"`
+	}
+	var items []map[string]itemResult
+	hasErrors := false
+	indexed, refused := 0, 0
+
+	sc := bufio.NewScanner(r.Body)
+	sc.Buffer(make([]byte, 1<<20), 64<<20)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var action map[string]struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}
+		if err := json.Unmarshal(line, &action); err != nil || action["index"].Index == "" {
+			writeJSON(w, http.StatusBadRequest, errorBody("illegal_argument_exception", "malformed action line: "+string(line)))
+			return
+		}
+		meta := action["index"]
+		if !sc.Scan() {
+			writeJSON(w, http.StatusBadRequest, errorBody("illegal_argument_exception", "action without a source line"))
+			return
+		}
+		source := append(json.RawMessage(nil), sc.Bytes()...)
+		res := itemResult{Index: meta.Index, ID: meta.ID}
+		switch {
+		case !json.Valid(source):
+			res.Status, res.Error = http.StatusBadRequest, map[string]string{"type": "mapper_parsing_exception", "reason": "failed to parse"}
+			hasErrors = true
+		case rand.Float64() < reject:
+			res.Status, res.Error = http.StatusTooManyRequests, map[string]string{"type": "es_rejected_execution_exception", "reason": "rejected execution"}
+			hasErrors = true
+			refused++
+		default:
+			mu.Lock()
+			if indices[meta.Index] == nil {
+				indices[meta.Index] = map[string]json.RawMessage{}
+			}
+			_, exists := indices[meta.Index][meta.ID]
+			indices[meta.Index][meta.ID] = source
+			mu.Unlock()
+			res.Status, res.Result = http.StatusCreated, "created"
+			if exists {
+				res.Status, res.Result = http.StatusOK, "updated"
+			}
+			indexed++
+			if verbose {
+				fmt.Printf("%s %s %s\n", meta.Index, meta.ID, source)
+			}
+		}
+		items = append(items, map[string]itemResult{"index": res})
+	}
+	log.Printf("bulk of %d: %d indexed, %d throttled", len(items), indexed, refused)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"took": 1, "errors": hasErrors, "items": items})
+}
+
+func errorBody(kind, reason string) map[string]interface{} {
+	return map[string]interface{}{"error": map[string]string{"type": kind, "reason": reason}}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
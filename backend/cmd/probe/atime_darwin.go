@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func fileAccessTime(info os.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Atimespec.Sec, st.Atimespec.Nsec)
+	}
+	return info.ModTime()
+}
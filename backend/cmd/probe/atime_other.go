@@ -0,0 +1,13 @@
+//go:build !linux && !windows && !darwin
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// Access times are not exposed portably here, decoys fall back to change detection
+func fileAccessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func fileAccessTime(info os.FileInfo) time.Time {
+	if attr, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, attr.LastAccessTime.Nanoseconds())
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	psnet "github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// DecoyCommand mirrors the DEPLOY_DECOY / REMOVE_DECOY payload sent by the server.
+type DecoyCommand struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Path     string            `json:"path,omitempty"`
+	Content  string            `json:"content,omitempty"`
+	Encoding string            `json:"encoding,omitempty"`
+	Values   map[string]string `json:"values,omitempty"`
+}
+
+type DecoyEvent struct {
+	DecoyID  string `json:"decoyId"`
+	Event    string `json:"event"`
+	Path     string `json:"path"`
+	Process  string `json:"process"`
+	PID      int32  `json:"pid"`
+	SourceIP string `json:"sourceIp"`
+	Detail   string `json:"detail"`
+	Time     string `json:"time"`
+}
+
+type deployedDecoy struct {
+	cmd   DecoyCommand
+	path  string
+	atime time.Time
+	mtime time.Time
+	pid   int32
+	peers map[string]bool // remote addresses already reported for process decoys
+}
+
+var (
+	decoyMu sync.Mutex
+	decoys  = map[string]*deployedDecoy{}
+)
+
+func handleDeployDecoy(c *websocket.Conn, cmd DecoyCommand) {
+	d, err := deployDecoy(cmd)
+	result := map[string]string{"id": cmd.ID, "status": "Active"}
+	if err != nil {
+		log.Printf("Failed to deploy decoy %s (%s): %v", cmd.ID, cmd.Name, err)
+		result["status"] = "Failed"
+		result["error"] = err.Error()
+	} else {
+		result["path"] = d.path
+		log.Printf("Deployed %s decoy %s at %s", cmd.Type, cmd.Name, d.path)
+	}
+	sendMessage(c, "DECOY_DEPLOYED", result)
+}
+
+func handleRemoveDecoy(c *websocket.Conn, cmd DecoyCommand) {
+	decoyMu.Lock()
+	d, ok := decoys[cmd.ID]
+	delete(decoys, cmd.ID)
+	decoyMu.Unlock()
+	if !ok {
+		return
+	}
+	removeDecoyArtifacts(d)
+	sendMessage(c, "DECOY_DEPLOYED", map[string]string{"id": cmd.ID, "status": "Removed", "path": d.path})
+}
+
+func deployDecoy(cmd DecoyCommand) (*deployedDecoy, error) {
+	decoyMu.Lock()
+	old, exists := decoys[cmd.ID]
+	decoyMu.Unlock()
+	if exists {
+		removeDecoyArtifacts(old)
+	}
+
+	if cmd.Encoding == "base64" {
+		data, err := base64.StdEncoding.DecodeString(cmd.Content)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Content = string(data)
+		cmd.Encoding = ""
+	}
+
+	d := &deployedDecoy{cmd: cmd, peers: map[string]bool{}}
+	var err error
+	switch cmd.Type {
+	case "Registry":
+		err = deployRegistryDecoy(d)
+	case "Process":
+		err = deployProcessDecoy(d)
+	default:
+		err = deployFileDecoy(d)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decoyMu.Lock()
+	decoys[cmd.ID] = d
+	decoyMu.Unlock()
+	return d, nil
+}
+
+func decoyFilePath(cmd DecoyCommand) (string, error) {
+	if cmd.Path != "" {
+		return cmd.Path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch cmd.Type {
+	case "SSHKey":
+		return filepath.Join(home, ".ssh", cmd.Name), nil
+	case "AWSCredentials":
+		return filepath.Join(home, ".aws", cmd.Name), nil
+	case "BrowserPassword", "Document":
+		return filepath.Join(home, "Documents", cmd.Name), nil
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "Desktop", cmd.Name), nil
+	}
+	return filepath.Join(home, cmd.Name), nil
+}
+
+func deployFileDecoy(d *deployedDecoy) error {
+	path, err := decoyFilePath(d.cmd)
+	if err != nil {
+		return err
+	}
+	// Never overwrite a real file with bait, only our own earlier deployment
+	if existing, err := os.ReadFile(path); err == nil && string(existing) != d.cmd.Content && !isOwnDecoy(path) {
+		return fmt.Errorf("refusing to overwrite existing file %s", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(d.cmd.Content), 0600); err != nil {
+		return err
+	}
+	d.path = path
+	return resetDecoyTimes(d)
+}
+
+func isOwnDecoy(path string) bool {
+	decoyMu.Lock()
+	defer decoyMu.Unlock()
+	for _, d := range decoys {
+		if d.path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// resetDecoyTimes backdates the access time below the modification time so that a
+// relatime mount records the next read, then remembers the new baseline.
+func resetDecoyTimes(d *deployedDecoy) error {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return err
+	}
+	mtime := info.ModTime()
+	if err := os.Chtimes(d.path, mtime.Add(-time.Hour), mtime); err != nil {
+		return err
+	}
+	info, err = os.Stat(d.path)
+	if err != nil {
+		return err
+	}
+	d.atime = fileAccessTime(info)
+	d.mtime = info.ModTime()
+	return nil
+}
+
+func deployRegistryDecoy(d *deployedDecoy) error {
+	if runtime.GOOS != "windows" {
+		return fmt.Errorf("registry decoys are only supported on Windows")
+	}
+	for name, value := range d.cmd.Values {
+		output, err := exec.Command("reg", "add", d.cmd.Path, "/v", name, "/t", "REG_SZ", "/d", value, "/f").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+		}
+	}
+	d.path = d.cmd.Path
+	return nil
+}
+
+func deployProcessDecoy(d *deployedDecoy) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(d.cmd.Name, filepath.Ext(d.cmd.Name))
+	dir := d.cmd.Path
+	if dir == "" {
+		if runtime.GOOS == "windows" {
+			dir = filepath.Join(os.Getenv("ProgramData"), base)
+		} else {
+			dir = filepath.Join("/opt", base)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		dir = filepath.Join(os.TempDir(), base)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	target := filepath.Join(dir, d.cmd.Name)
+	d.path = target
+
+	// A decoy left running by a previous probe instance is adopted instead of replaced
+	if pid := findProcessByExe(target); pid > 0 {
+		d.pid = pid
+		return nil
+	}
+	if err := copyFile(self, target); err != nil {
+		return err
+	}
+	return startProcessDecoy(d)
+}
+
+func startProcessDecoy(d *deployedDecoy) error {
+	proc := exec.Command(d.path, "-decoy-idle")
+	if err := proc.Start(); err != nil {
+		return err
+	}
+	d.pid = int32(proc.Process.Pid)
+	go proc.Wait()
+	return nil
+}
+
+func findProcessByExe(path string) int32 {
+	procs, err := process.Processes()
+	if err != nil {
+		return 0
+	}
+	for _, p := range procs {
+		if exe, err := p.Exe(); err == nil && exe == path {
+			return p.Pid
+		}
+	}
+	return 0
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func removeDecoyArtifacts(d *deployedDecoy) {
+	switch d.cmd.Type {
+	case "Registry":
+		exec.Command("reg", "delete", d.path, "/f").Run()
+	case "Process":
+		if p, err := process.NewProcess(d.pid); err == nil {
+			p.Kill()
+			time.Sleep(500 * time.Millisecond)
+		}
+		os.Remove(d.path)
+	default:
+		os.Remove(d.path)
+	}
+}
+
+// watchDecoys polls every planted decoy and reports any access or use to the server
+func watchDecoys(c *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			decoyMu.Lock()
+			list := make([]*deployedDecoy, 0, len(decoys))
+			for _, d := range decoys {
+				list = append(list, d)
+			}
+			decoyMu.Unlock()
+
+			for _, d := range list {
+				for _, ev := range checkDecoy(d) {
+					log.Printf("Decoy %s %s by %s (%s)", d.cmd.Name, ev.Event, ev.Process, ev.SourceIP)
+					sendMessage(c, "DECOY_EVENT", ev)
+				}
+			}
+		}
+	}
+}
+
+func checkDecoy(d *deployedDecoy) []DecoyEvent {
+	switch d.cmd.Type {
+	case "Registry":
+		return checkRegistryDecoy(d)
+	case "Process":
+		return checkProcessDecoy(d)
+	default:
+		return checkFileDecoy(d)
+	}
+}
+
+func checkFileDecoy(d *deployedDecoy) []DecoyEvent {
+	info, err := os.Stat(d.path)
+	if os.IsNotExist(err) {
+		ev := newDecoyEvent(d, "deleted", "")
+		// Plant it again so later access is still observed
+		if err := deployFileDecoy(d); err != nil {
+			ev.Detail = "redeploy failed: " + err.Error()
+		}
+		return []DecoyEvent{ev}
+	}
+	if err != nil {
+		return nil
+	}
+
+	event := ""
+	if !info.ModTime().Equal(d.mtime) {
+		event = "modified"
+	} else if fileAccessTime(info).After(d.atime) {
+		event = "accessed"
+	}
+	if event == "" {
+		return nil
+	}
+
+	ev := newDecoyEvent(d, event, "")
+	if p := findFileOpener(d.path); p != nil {
+		fillProcessInfo(&ev, p)
+	}
+	if event == "modified" {
+		os.WriteFile(d.path, []byte(d.cmd.Content), 0600)
+	}
+	resetDecoyTimes(d)
+	return []DecoyEvent{ev}
+}
+
+func checkRegistryDecoy(d *deployedDecoy) []DecoyEvent {
+	var events []DecoyEvent
+	for name, value := range d.cmd.Values {
+		output, err := exec.Command("reg", "query", d.path, "/v", name).CombinedOutput()
+		if err != nil {
+			events = append(events, newDecoyEvent(d, "deleted", "value "+name))
+			deployRegistryDecoy(d)
+			break
+		}
+		if !strings.Contains(string(output), value) {
+			events = append(events, newDecoyEvent(d, "modified", "value "+name))
+			deployRegistryDecoy(d)
+			break
+		}
+	}
+	return events
+}
+
+func checkProcessDecoy(d *deployedDecoy) []DecoyEvent {
+	if d.pid == 0 {
+		return nil
+	}
+	pid := d.pid
+
+	running, _ := process.PidExists(pid)
+	if !running {
+		ev := newDecoyEvent(d, "terminated", "")
+		ev.PID = pid
+		if err := startProcessDecoy(d); err != nil {
+			ev.Detail = "restart failed: " + err.Error()
+		}
+		return []DecoyEvent{ev}
+	}
+
+	var events []DecoyEvent
+	conns, _ := psnet.ConnectionsPid("all", pid)
+	for _, conn := range conns {
+		remote := conn.Raddr.IP
+		if remote == "" || isLocalAddr(remote) || d.peers[remote] {
+			continue
+		}
+		d.peers[remote] = true
+		ev := newDecoyEvent(d, "connected", fmt.Sprintf("port %d", conn.Laddr.Port))
+		ev.PID = pid
+		ev.Process = d.cmd.Name
+		ev.SourceIP = remote
+		events = append(events, ev)
+	}
+	return events
+}
+
+func newDecoyEvent(d *deployedDecoy, event, detail string) DecoyEvent {
+	return DecoyEvent{
+		DecoyID: d.cmd.ID,
+		Event:   event,
+		Path:    d.path,
+		Detail:  detail,
+		Time:    probeNow().Format(time.RFC3339Nano),
+	}
+}
+
+// findFileOpener looks for a process (other than us) holding the decoy open
+func findFileOpener(path string) *process.Process {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil
+	}
+	self := int32(os.Getpid())
+	for _, p := range procs {
+		if p.Pid == self {
+			continue
+		}
+		files, err := p.OpenFiles()
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			if f.Path == path {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// fillProcessInfo records the process name and walks up its parents (e.g. cat <- bash <- sshd)
+// looking for a remote peer to attribute the access to.
+func fillProcessInfo(ev *DecoyEvent, p *process.Process) {
+	ev.PID = p.Pid
+	if name, err := p.Name(); err == nil {
+		ev.Process = name
+	}
+	cur := p
+	for depth := 0; cur != nil && depth < 8; depth++ {
+		if conns, err := cur.Connections(); err == nil {
+			for _, conn := range conns {
+				if conn.Raddr.IP != "" && !isLocalAddr(conn.Raddr.IP) && conn.Status == "ESTABLISHED" {
+					ev.SourceIP = conn.Raddr.IP
+					return
+				}
+			}
+		}
+		parent, err := cur.Parent()
+		if err != nil || parent.Pid <= 1 {
+			return
+		}
+		cur = parent
+	}
+}
+
+func isLocalAddr(ip string) bool {
+	return ip == "127.0.0.1" || ip == "::1" || ip == "0.0.0.0" || ip == "::" || strings.HasPrefix(ip, "127.")
+}
+
+func decodeDecoyCommand(data interface{}) (DecoyCommand, error) {
+	var cmd DecoyCommand
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return cmd, err
+	}
+	err = json.Unmarshal(raw, &cmd)
+	return cmd, err
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/metrics"
+)
+
+var (
+	probeStartTime = time.Now()
+
+	// lastStatus is the report last collected, the listener serves it instead of sampling
+	// again so the network rates keep their report interval
+	lastStatus atomic.Pointer[NodeStatus]
+
+	messagesSent = metrics.NewCounterVec("prts_probe_messages_sent_total",
+		"Messages sent to the server by type.", "type")
+	commandsReceived = metrics.NewCounterVec("prts_probe_commands_total",
+		"Commands received from the server.", "command")
+	ruleUpdates = metrics.NewCounterVec("prts_probe_rule_updates_total",
+		"Rule set messages from the server by type and outcome.", "type", "result")
+)
+
+// serveMetrics runs the local scrape listener, with a token set every request needs it
+// as a bearer token
+func serveMetrics(listen, token string, rules *ruleStore) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			given, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			a, b := sha256.Sum256([]byte(given)), sha256.Sum256([]byte(token))
+			if subtle.ConstantTimeCompare(a[:], b[:]) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="prts probe metrics"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		contentType, openMetrics := metrics.Negotiate(r.Header.Get("Accept"))
+		var buf bytes.Buffer
+		mw := metrics.NewWriter(&buf, openMetrics)
+		writeProbeMetrics(mw, rules)
+		mw.Close()
+		w.Header().Set("Content-Type", contentType)
+		w.Write(buf.Bytes())
+	})
+
+	log.Printf("Serving metrics on %s/metrics", listen)
+	if err := http.ListenAndServe(listen, mux); err != nil {
+		log.Printf("metrics listener: %v", err)
+	}
+}
+
+func writeProbeMetrics(w *metrics.Writer, rules *ruleStore) {
+	w.Header("prts_probe_info", "gauge", "Identity of the probe, always 1.")
+	w.Sample("prts_probe_info", 1, "node", *id, "name", *name, "server", *addr, "os", runtime.GOOS, "firewall", rules.fw.Name())
+	w.Gauge("prts_probe_start_time_seconds", "Start time of the probe since the Unix epoch.", float64(probeStartTime.Unix()))
+
+	messagesSent.Write(w)
+	commandsReceived.Write(w)
+	ruleUpdates.Write(w)
+
+	w.Gauge("prts_probe_rule_version", "Access rule set version applied to the firewall.", float64(rules.Version()))
+	w.Gauge("prts_probe_access_rules", "Access rules held by the probe.", float64(rules.Count()))
+	fwState := rules.fw.Status()
+	w.Header("prts_probe_firewall_status", "gauge", "Firewall backend state, 1 for the current state.")
+	for _, state := range []string{"active", "inactive", "error"} {
+		w.Sample("prts_probe_firewall_status", metrics.Bool(fwState.Status == state), "state", state)
+	}
+
+	status := lastStatus.Load()
+	if status == nil {
+		return
+	}
+	w.Gauge("prts_probe_last_report_timestamp_seconds", "When the last status report was collected.", float64(status.collectedAt.Unix()))
+	w.Gauge("prts_probe_load_percent", "CPU load of the host.", float64(status.Load))
+	w.Gauge("prts_probe_memory_usage_percent", "Memory in use on the host.", float64(status.MemoryUsage))
+	w.Gauge("prts_probe_memory_total_bytes", "Memory of the host.", float64(status.MemoryTotal)*1024*1024)
+	w.Gauge("prts_probe_temperature_celsius", "CPU temperature of the host, 0 when no sensor is readable.", status.Temperature)
+	w.Gauge("prts_probe_network_transmit_bytes_per_second", "Outbound traffic of the host.", status.NetUp*1024*1024)
+	w.Gauge("prts_probe_network_receive_bytes_per_second", "Inbound traffic of the host.", status.NetDown*1024*1024)
+	w.Gauge("prts_probe_uptime_seconds", "Uptime of the host.", float64(status.uptimeSeconds))
+}
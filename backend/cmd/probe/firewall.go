@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FirewallBackend enforces the access rules pushed by the server
+type FirewallBackend interface {
+	Name() string
+	// Apply replaces the whole PRTS rule set
+	Apply(rules []AccessControlRule) error
+	// Enable and Disable toggle enforcement, the loaded rule set is preserved
+	Enable() error
+	Disable() error
+	Status() FirewallState
+}
+
+// FirewallState is what NODE_REPORT carries in FirewallStatus, FirewallError and FirewallInfo
+type FirewallState struct {
+	Status string // active, inactive, error
+	Error  string
+	Info   string
+}
+
+// firewallBase holds the toggle and the last outcome shared by every backend
+type firewallBase struct {
+	mu      sync.Mutex
+	enabled bool
+	lastErr string
+	info    string
+}
+
+func (b *firewallBase) set(err error, info string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastErr = ""
+	if err != nil {
+		b.lastErr = err.Error()
+	}
+	if info != "" {
+		b.info = info
+	}
+}
+
+func (b *firewallBase) isEnabled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.enabled
+}
+
+func (b *firewallBase) setEnabled(enabled bool) {
+	b.mu.Lock()
+	b.enabled = enabled
+	b.mu.Unlock()
+}
+
+func (b *firewallBase) state() FirewallState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := FirewallState{Status: "active", Error: b.lastErr, Info: b.info}
+	if !b.enabled {
+		// If PRTS toggle is off, we report as inactive regardless of system firewall state
+		st.Status = "inactive"
+	} else if b.lastErr != "" {
+		st.Status = "error"
+	}
+	return st
+}
+
+// ruleGroup collects the addresses that share an action and a scope, backends render
+// one firewall rule or set per group
+type ruleGroup struct {
+	Action    string   `json:"action"`   // allow, block
+	Family    int      `json:"family"`   // 4 or 6, 0 when families are mixed
+	Protocol  string   `json:"protocol"` // any, tcp, udp, icmp
+	Ports     string   `json:"ports"`
+	Direction string   `json:"direction"` // inbound, outbound, both
+	Addrs     []string `json:"addrs"`
+}
+
+// scoped reports whether the group is narrower than all inbound traffic
+func (g ruleGroup) scoped() bool {
+	return g.Protocol != "any" || g.Ports != "" || g.Direction != "inbound"
+}
+
+func (g ruleGroup) inbound() bool  { return g.Direction != "outbound" }
+func (g ruleGroup) outbound() bool { return g.Direction != "inbound" }
+
+// parseRuleAddress checks an address, CIDR block or start-end range and returns its
+// canonical form and family
+func parseRuleAddress(s string) (string, int, bool) {
+	s = strings.TrimSpace(s)
+	family := func(a netip.Addr) int {
+		if a.Is4() {
+			return 4
+		}
+		return 6
+	}
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return "", 0, false
+		}
+		p = p.Masked()
+		if p.IsSingleIP() {
+			return p.Addr().String(), family(p.Addr()), true
+		}
+		return p.String(), family(p.Addr()), true
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		start, err1 := netip.ParseAddr(strings.TrimSpace(s[:i]))
+		end, err2 := netip.ParseAddr(strings.TrimSpace(s[i+1:]))
+		if err1 != nil || err2 != nil {
+			return "", 0, false
+		}
+		start, end = start.Unmap(), end.Unmap()
+		if start.Is4() != end.Is4() || start.Compare(end) > 0 {
+			return "", 0, false
+		}
+		return start.String() + "-" + end.String(), family(start), true
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return "", 0, false
+	}
+	addr = addr.Unmap().WithZone("")
+	return addr.String(), family(addr), true
+}
+
+// groupRules validates the active rules and groups them by scope. Allow groups come
+// first because every backend evaluates them before the block groups.
+func groupRules(rules []AccessControlRule, splitFamily bool) (groups []ruleGroup, skipped []string) {
+	index := map[string]int{}
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		if rule.Status != "active" {
+			continue
+		}
+		if strings.TrimSpace(rule.IP) == "" {
+			continue
+		}
+		addr, family, ok := parseRuleAddress(rule.IP)
+		if !ok {
+			skipped = append(skipped, rule.IP)
+			continue
+		}
+
+		g := ruleGroup{Action: "block", Protocol: "any", Ports: strings.ReplaceAll(rule.Ports, " ", ""), Direction: "inbound"}
+		if rule.Type == "whitelist" {
+			g.Action = "allow"
+		}
+		if p := strings.ToLower(rule.Protocol); p == "tcp" || p == "udp" || p == "icmp" {
+			g.Protocol = p
+		}
+		if g.Protocol != "tcp" && g.Protocol != "udp" {
+			g.Ports = ""
+		}
+		if d := strings.ToLower(rule.Direction); d == "outbound" || d == "both" {
+			g.Direction = d
+		}
+		if splitFamily {
+			g.Family = family
+		}
+
+		key := fmt.Sprintf("%s|%d|%s|%s|%s", g.Action, g.Family, g.Protocol, g.Ports, g.Direction)
+		if seen[key+"|"+addr] {
+			continue
+		}
+		seen[key+"|"+addr] = true
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, g)
+		}
+		groups[i].Addrs = append(groups[i].Addrs, addr)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Action != groups[j].Action {
+			return groups[i].Action == "allow"
+		}
+		return !groups[i].scoped() && groups[j].scoped()
+	})
+	return groups, skipped
+}
+
+// groupSummary counts addresses per action for FirewallInfo
+func groupSummary(groups []ruleGroup, skipped []string) string {
+	blocked, allowed, scoped := 0, 0, 0
+	for _, g := range groups {
+		if g.Action == "allow" {
+			allowed += len(g.Addrs)
+		} else {
+			blocked += len(g.Addrs)
+		}
+		if g.scoped() {
+			scoped++
+		}
+	}
+	info := fmt.Sprintf("%d blocked, %d allowed", blocked, allowed)
+	if scoped > 0 {
+		info += fmt.Sprintf(", %d scoped groups", scoped)
+	}
+	if len(skipped) > 0 {
+		info += fmt.Sprintf(", %d invalid entries skipped", len(skipped))
+	}
+	return info
+}
+
+// newFirewallBackend resolves the -firewall flag, auto picks the enforcing backend of the host OS
+func newFirewallBackend(kind, dryRunFile string) (FirewallBackend, error) {
+	if kind == "auto" {
+		switch runtime.GOOS {
+		case "windows":
+			kind = "windows"
+		case "linux":
+			kind = "linux"
+		default:
+			log.Printf("No firewall backend for %s, recording rules to %s instead", runtime.GOOS, dryRunFile)
+			kind = "dry-run"
+		}
+	}
+
+	switch kind {
+	case "windows":
+		return newWindowsFirewall(), nil
+	case "linux":
+		return newLinuxFirewall(), nil
+	case "dry-run":
+		return newDryRunFirewall(dryRunFile), nil
+	}
+	return nil, fmt.Errorf("unknown firewall backend %q (auto, windows, linux, dry-run)", kind)
+}
+
+// dryRunFirewall enforces nothing, it records the rule set it would apply so probes can
+// run monitor-only and rule distribution can be checked without touching a firewall
+type dryRunFirewall struct {
+	firewallBase
+	path  string
+	rules []AccessControlRule
+}
+
+type dryRunRecord struct {
+	Backend   string              `json:"backend"`
+	Enabled   bool                `json:"enabled"`
+	UpdatedAt string              `json:"updatedAt"`
+	Block     []string            `json:"block"` // Addresses of unscoped inbound block rules
+	Allow     []string            `json:"allow"`
+	Groups    []ruleGroup         `json:"groups"`
+	Skipped   []string            `json:"skipped"`
+	Rules     []AccessControlRule `json:"rules"`
+}
+
+func newDryRunFirewall(path string) *dryRunFirewall {
+	return &dryRunFirewall{firewallBase: firewallBase{enabled: true}, path: path}
+}
+
+func (f *dryRunFirewall) Name() string { return "dry-run" }
+
+func (f *dryRunFirewall) Apply(rules []AccessControlRule) error {
+	f.mu.Lock()
+	f.rules = rules
+	f.mu.Unlock()
+	return f.write()
+}
+
+func (f *dryRunFirewall) Enable() error {
+	f.setEnabled(true)
+	return f.write()
+}
+
+func (f *dryRunFirewall) Disable() error {
+	f.setEnabled(false)
+	return f.write()
+}
+
+func (f *dryRunFirewall) Status() FirewallState { return f.state() }
+
+// write replaces the record file through a rename so readers never see a partial file
+func (f *dryRunFirewall) write() error {
+	f.mu.Lock()
+	rec := dryRunRecord{
+		Backend:   f.Name(),
+		Enabled:   f.enabled,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+		Rules:     f.rules,
+	}
+	f.mu.Unlock()
+	rec.Groups, rec.Skipped = groupRules(rec.Rules, false)
+	rec.Block, rec.Allow = []string{}, []string{}
+	for _, g := range rec.Groups {
+		if g.scoped() {
+			continue
+		}
+		if g.Action == "allow" {
+			rec.Allow = append(rec.Allow, g.Addrs...)
+		} else {
+			rec.Block = append(rec.Block, g.Addrs...)
+		}
+	}
+	if rec.Groups == nil {
+		rec.Groups = []ruleGroup{}
+	}
+	if rec.Skipped == nil {
+		rec.Skipped = []string{}
+	}
+	if rec.Rules == nil {
+		rec.Rules = []AccessControlRule{}
+	}
+
+	data, _ := json.MarshalIndent(rec, "", "  ")
+	tmp := f.path + ".tmp"
+	if dir := filepath.Dir(f.path); dir != "" {
+		os.MkdirAll(dir, 0755)
+	}
+	err := os.WriteFile(tmp, data, 0644)
+	if err == nil {
+		err = os.Rename(tmp, f.path)
+	}
+	if err != nil {
+		f.set(fmt.Errorf("dry-run: %v", err), "")
+		return err
+	}
+	f.set(nil, fmt.Sprintf("Dry run, not enforced: %s recorded to %s", groupSummary(rec.Groups, rec.Skipped), f.path))
+	return nil
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Linux enforcement keeps PRTS rules apart from the host policy: a dedicated nftables
+// table, or when nft is missing, ipset sets referenced from a PRTS iptables chain.
+const (
+	nftTable     = "prts"
+	iptChain     = "PRTS"
+	iptOutChain  = "PRTS-OUT"
+	ipsetPrefix  = "prts-"
+	ipsetMaxElem = 1048576
+	// iptables multiport takes at most 15 ports, a range counts twice
+	multiportMax = 15
+)
+
+// linuxFirewall drives nftables, or iptables with ipset when nft is not usable
+type linuxFirewall struct {
+	firewallBase
+	backend    string // nftables, iptables, or empty when none is usable
+	backendErr string
+	rules      []AccessControlRule
+}
+
+func newLinuxFirewall() *linuxFirewall {
+	return &linuxFirewall{firewallBase: firewallBase{enabled: true}}
+}
+
+func (f *linuxFirewall) Name() string { return "linux" }
+
+// detect picks nftables when the kernel accepts it, otherwise iptables with ipset
+func (f *linuxFirewall) detect() {
+	if f.backend != "" || f.backendErr != "" {
+		return
+	}
+	if os.Geteuid() != 0 {
+		f.backendErr = "Root privileges required for firewall management"
+		return
+	}
+	if _, err := exec.LookPath("nft"); err == nil {
+		if err := exec.Command("nft", "list", "tables").Run(); err == nil {
+			f.backend = "nftables"
+			return
+		}
+	}
+	_, iptErr := exec.LookPath("iptables")
+	_, ipsetErr := exec.LookPath("ipset")
+	if iptErr == nil && ipsetErr == nil {
+		f.backend = "iptables"
+		return
+	}
+	f.backendErr = "Neither nftables nor iptables with ipset is available"
+}
+
+// Apply replaces the PRTS sets in one transaction. Sets are loaded even while disabled,
+// only the input hook follows the toggle.
+func (f *linuxFirewall) Apply(rules []AccessControlRule) error {
+	f.mu.Lock()
+	f.rules = rules
+	f.detect()
+	backend, backendErr, enabled := f.backend, f.backendErr, f.enabled
+	f.mu.Unlock()
+	if backendErr != "" {
+		err := errors.New(backendErr)
+		f.set(err, "")
+		return err
+	}
+
+	groups, skipped := groupRules(rules, true)
+	summary := groupSummary(groups, skipped)
+	log.Printf("Applying %s rules... (Received %d rules, %s)", backend, len(rules), summary)
+	for _, bad := range skipped {
+		log.Printf("Skipping invalid firewall address: %s", bad)
+	}
+
+	var err error
+	if backend == "nftables" {
+		err = applyNftables(groups, enabled)
+	} else {
+		err = applyIptables(groups, enabled)
+	}
+	if err != nil {
+		log.Printf("Failed to apply %s rules: %v", backend, err)
+		f.set(err, "")
+		return err
+	}
+
+	state := "enforcing"
+	if !enabled {
+		state = "loaded, enforcement disabled"
+	}
+	info := fmt.Sprintf("%s: %s (%s)", linuxBackendName(backend), summary, state)
+	log.Println(info)
+	f.set(nil, info)
+	return nil
+}
+
+// Enable and Disable hook the PRTS table in or out, the sets are preserved
+func (f *linuxFirewall) Enable() error {
+	f.setEnabled(true)
+	return f.Apply(f.loadedRules())
+}
+
+func (f *linuxFirewall) Disable() error {
+	f.setEnabled(false)
+	return f.Apply(f.loadedRules())
+}
+
+func (f *linuxFirewall) loadedRules() []AccessControlRule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rules
+}
+
+// Status reports error when no backend can be driven
+func (f *linuxFirewall) Status() FirewallState {
+	f.mu.Lock()
+	f.detect()
+	backendErr := f.backendErr
+	f.mu.Unlock()
+	st := f.state()
+	if backendErr != "" {
+		st.Status = "error"
+		st.Error = backendErr
+	}
+	return st
+}
+
+func linuxBackendName(backend string) string {
+	if backend == "nftables" {
+		return "nftables table inet " + nftTable
+	}
+	return "iptables chain " + iptChain + " with ipset"
+}
+
+// setName gives unscoped groups the fixed names of the main lists, scoped groups are numbered
+func setName(g ruleGroup, i int, sep string) string {
+	kind := "blacklist"
+	if g.Action == "allow" {
+		kind = "whitelist"
+	}
+	if !g.scoped() {
+		return fmt.Sprintf("%s%d", kind, g.Family)
+	}
+	return fmt.Sprintf("%s%d%sscope%s%d", kind, g.Family, sep, sep, i)
+}
+
+// nftMatch renders the protocol and port part of a group's rule
+func nftMatch(g ruleGroup) string {
+	switch g.Protocol {
+	case "tcp", "udp":
+		m := " meta l4proto " + g.Protocol
+		if g.Ports != "" {
+			m += fmt.Sprintf(" %s dport { %s }", g.Protocol, strings.ReplaceAll(g.Ports, ",", ", "))
+		}
+		return m
+	case "icmp":
+		if g.Family == 6 {
+			return " meta l4proto ipv6-icmp"
+		}
+		return " meta l4proto icmp"
+	}
+	return ""
+}
+
+// applyNftables rebuilds the table inside a single nft transaction, so the kernel swaps
+// the old sets for the new ones without a window where nothing is filtered
+func applyNftables(groups []ruleGroup, enabled bool) error {
+	var b, input, output strings.Builder
+	fmt.Fprintf(&b, "add table inet %s\ndelete table inet %s\ntable inet %s {\n", nftTable, nftTable, nftTable)
+
+	// The main lists always exist so the table layout stays stable for operators
+	declared := map[string]bool{}
+	groups = append(groups,
+		ruleGroup{Action: "allow", Family: 4, Protocol: "any", Direction: "inbound"},
+		ruleGroup{Action: "allow", Family: 6, Protocol: "any", Direction: "inbound"},
+		ruleGroup{Action: "block", Family: 4, Protocol: "any", Direction: "inbound"},
+		ruleGroup{Action: "block", Family: 6, Protocol: "any", Direction: "inbound"},
+	)
+
+	for i, g := range groups {
+		name := setName(g, i, "_")
+		if declared[name] {
+			continue
+		}
+		declared[name] = true
+
+		addrType, fam := "ipv4_addr", "ip"
+		if g.Family == 6 {
+			addrType, fam = "ipv6_addr", "ip6"
+		}
+		fmt.Fprintf(&b, "\tset %s {\n\t\ttype %s\n\t\tflags interval\n\t\tauto-merge\n", name, addrType)
+		if len(g.Addrs) > 0 {
+			fmt.Fprintf(&b, "\t\telements = { %s }\n", strings.Join(g.Addrs, ", "))
+		}
+		b.WriteString("\t}\n")
+
+		if len(g.Addrs) == 0 {
+			continue
+		}
+		verdict := "counter drop"
+		if g.Action == "allow" {
+			verdict = "accept"
+		}
+		if g.inbound() {
+			fmt.Fprintf(&input, "\t\t%s saddr @%s%s %s\n", fam, name, nftMatch(g), verdict)
+		}
+		if g.outbound() {
+			fmt.Fprintf(&output, "\t\t%s daddr @%s%s %s\n", fam, name, nftMatch(g), verdict)
+		}
+	}
+
+	b.WriteString("\tchain input {\n\t\ttype filter hook input priority -10; policy accept;\n")
+	if enabled {
+		b.WriteString(input.String())
+	}
+	b.WriteString("\t}\n\tchain output {\n\t\ttype filter hook output priority -10; policy accept;\n")
+	if enabled {
+		b.WriteString(output.String())
+	}
+	b.WriteString("\t}\n}\n")
+
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(b.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nft: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// multiportChunks splits "22,80-90" into --dports arguments that fit multiport
+func multiportChunks(ports string) []string {
+	if ports == "" {
+		return []string{""}
+	}
+	var chunks []string
+	var cur []string
+	used := 0
+	for _, p := range strings.Split(ports, ",") {
+		cost := 1
+		if strings.Contains(p, "-") {
+			cost = 2
+		}
+		if used+cost > multiportMax {
+			chunks = append(chunks, strings.Join(cur, ","))
+			cur, used = nil, 0
+		}
+		cur = append(cur, strings.ReplaceAll(p, "-", ":"))
+		used += cost
+	}
+	return append(chunks, strings.Join(cur, ","))
+}
+
+// applyIptables fills temporary ipsets and swaps them with the live ones in one ipset
+// restore, then replaces the PRTS chains in one iptables-restore commit
+func applyIptables(groups []ruleGroup, enabled bool) error {
+	var sets bytes.Buffer
+	rules := map[int]*strings.Builder{4: {}, 6: {}}
+	used := map[string]bool{}
+	for i, g := range groups {
+		name := ipsetPrefix + setName(g, i, "-")
+		used[name] = true
+		family := "inet"
+		if g.Family == 6 {
+			family = "inet6"
+		}
+		tmp := name + "-t"
+		fmt.Fprintf(&sets, "create %s hash:net family %s maxelem %d -exist\n", name, family, ipsetMaxElem)
+		fmt.Fprintf(&sets, "create %s hash:net family %s maxelem %d -exist\n", tmp, family, ipsetMaxElem)
+		fmt.Fprintf(&sets, "flush %s\n", tmp)
+		for _, e := range g.Addrs {
+			// hash:net turns ranges into the covering networks
+			fmt.Fprintf(&sets, "add %s %s -exist\n", tmp, e)
+		}
+		fmt.Fprintf(&sets, "swap %s %s\ndestroy %s\n", tmp, name, tmp)
+
+		target := "DROP"
+		if g.Action == "allow" {
+			target = "RETURN"
+		}
+		proto := ""
+		switch g.Protocol {
+		case "tcp", "udp":
+			proto = " -p " + g.Protocol
+		case "icmp":
+			proto = " -p icmp"
+			if g.Family == 6 {
+				proto = " -p ipv6-icmp"
+			}
+		}
+		for _, ports := range multiportChunks(g.Ports) {
+			match := proto
+			if ports != "" {
+				match += " -m multiport --dports " + ports
+			}
+			if g.inbound() {
+				fmt.Fprintf(rules[g.Family], "-A %s -m set --match-set %s src%s -j %s\n", iptChain, name, match, target)
+			}
+			if g.outbound() {
+				fmt.Fprintf(rules[g.Family], "-A %s -m set --match-set %s dst%s -j %s\n", iptOutChain, name, match, target)
+			}
+		}
+	}
+
+	cmd := exec.Command("ipset", "restore")
+	cmd.Stdin = &sets
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ipset: %s", strings.TrimSpace(string(output)))
+	}
+
+	for _, bin := range []struct {
+		restore, tables string
+		family          int
+	}{{"iptables-restore", "iptables", 4}, {"ip6tables-restore", "ip6tables", 6}} {
+		if _, err := exec.LookPath(bin.restore); err != nil {
+			if bin.family == 4 {
+				return fmt.Errorf("%s not found", bin.restore)
+			}
+			continue
+		}
+		// Declared user chains are flushed and refilled inside one commit
+		script := fmt.Sprintf("*filter\n:%s - [0:0]\n:%s - [0:0]\n%sCOMMIT\n", iptChain, iptOutChain, rules[bin.family].String())
+		cmd := exec.Command(bin.restore, "--noflush")
+		cmd.Stdin = strings.NewReader(script)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %s", bin.restore, strings.TrimSpace(string(output)))
+		}
+		if err := hookIptablesChain(bin.tables, "INPUT", iptChain, enabled); err != nil {
+			return err
+		}
+		if err := hookIptablesChain(bin.tables, "OUTPUT", iptOutChain, enabled); err != nil {
+			return err
+		}
+	}
+
+	// Sets of groups that disappeared are no longer referenced and can go
+	if output, err := exec.Command("ipset", "list", "-n").Output(); err == nil {
+		for _, name := range strings.Fields(string(output)) {
+			if strings.HasPrefix(name, ipsetPrefix) && !used[name] {
+				exec.Command("ipset", "destroy", name).Run()
+			}
+		}
+	}
+	return nil
+}
+
+// hookIptablesChain toggles the jump from a built-in chain into a PRTS chain
+func hookIptablesChain(bin, from, chain string, enabled bool) error {
+	hooked := exec.Command(bin, "-C", from, "-j", chain).Run() == nil
+	var args []string
+	switch {
+	case enabled && !hooked:
+		args = []string{"-I", from, "1", "-j", chain}
+	case !enabled && hooked:
+		args = []string{"-D", from, "-j", chain}
+	default:
+		return nil
+	}
+	if output, err := exec.Command(bin, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s: %s", bin, strings.Join(args, " "), strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"embed"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -15,6 +16,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -56,6 +58,11 @@ type NodeStatus struct {
 	FirewallStatus string  `json:"firewallStatus"`
 	FirewallError  string  `json:"firewallError"`
 	FirewallInfo   string  `json:"firewallInfo"`
+	RuleVersion    int64   `json:"ruleVersion"`
+	Clock          string  `json:"clock"` // Local time when the report was sent, the server works out the drift
+
+	collectedAt   time.Time // For the local metrics listener, not reported
+	uptimeSeconds uint64
 }
 
 type Message struct {
@@ -66,7 +73,10 @@ type Message struct {
 type AccessControlRule struct {
 	ID         string `json:"id"`
 	IP         string `json:"ip"`
-	Type       string `json:"type"` // blacklist, whitelist
+	Type       string `json:"type"`      // blacklist, whitelist
+	Protocol   string `json:"protocol"`  // any, tcp, udp, icmp
+	Ports      string `json:"ports"`     // e.g. 22,80,8000-8100
+	Direction  string `json:"direction"` // inbound, outbound, both
 	Reason     string `json:"reason"`
 	Source     string `json:"source"`
 	ExpireTime string `json:"expireTime"`
@@ -78,22 +88,66 @@ var (
 	addr = flag.String("addr", "localhost:8080", "http service address")
 	id   = flag.String("id", "probe-windows-01", "node id")
 	name = flag.String("name", "Windows-Probe", "node name")
+
+	// decoyIdle is passed to copies of this binary spawned as process decoys
+	decoyIdle = flag.Bool("decoy-idle", false, "run as an idle process decoy (internal)")
+
+	firewallKind = flag.String("firewall", "auto", "firewall backend: auto, windows, linux, dry-run")
+	firewallFile = flag.String("firewall-file", "prts-firewall-rules.json", "rule set file written by the dry-run backend")
+
+	clockSkew = flag.Duration("clock-skew", 0, "shift the reported clock, for testing drift detection")
+
+	metricsListen = flag.String("metrics-listen", "", "serve Prometheus metrics on this address, e.g. 127.0.0.1:9101, off when empty")
+	metricsToken  = flag.String("metrics-token", "", "bearer token required by the metrics listener")
 )
 
+// probeNow is the local clock as reported to the server
+func probeNow() time.Time {
+	return time.Now().Add(*clockSkew)
+}
+
 var (
-	lastBytesSent     uint64
-	lastBytesRecv     uint64
-	lastTime          time.Time
-	loadHistory       []int
-	firewallEnabled   = true
-	lastFirewallError string
-	lastFirewallInfo  string
+	lastBytesSent uint64
+	lastBytesRecv uint64
+	lastTime      time.Time
+	loadHistory   []int
+	writeMu       sync.Mutex
 )
 
+// sendMessage serializes writes, the connection is shared by the reader, reporter and decoy watcher
+func sendMessage(c *websocket.Conn, msgType string, data interface{}) error {
+	payload, err := json.Marshal(Message{Type: msgType, Data: data})
+	if err != nil {
+		return err
+	}
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	if err := c.WriteMessage(websocket.TextMessage, payload); err != nil {
+		return err
+	}
+	messagesSent.Inc(msgType)
+	return nil
+}
+
 func main() {
 	flag.Parse()
 	log.SetFlags(0)
 
+	if *decoyIdle {
+		select {}
+	}
+
+	fw, err := newFirewallBackend(*firewallKind, *firewallFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Using %s firewall backend", fw.Name())
+	rules := newRuleStore(fw)
+
+	if *metricsListen != "" {
+		go serveMetrics(*metricsListen, *metricsToken, rules)
+	}
+
 	lastTime = time.Now()
 	currentNet, _ := psnet.IOCounters(false)
 	if len(currentNet) > 0 {
@@ -129,6 +183,7 @@ func main() {
 				if msg.Type == "COMMAND" {
 					cmd := msg.Data.(string)
 					log.Printf("Received command: %s", cmd)
+					commandsReceived.Inc(cmd)
 
 					switch cmd {
 					case "STOP":
@@ -141,76 +196,49 @@ func main() {
 						os.Exit(0)
 					case "ENABLE_FIREWALL":
 						log.Println("Enabling PRTS firewall protection...")
-						firewallEnabled = true
-						lastFirewallError = ""
-						lastFirewallInfo = "PRTS Firewall Protection Enabled"
-						if runtime.GOOS == "windows" {
-							output, err := exec.Command("powershell", "-Command", "Enable-NetFirewallRule -Group 'PRTS-Honeypot' -ErrorAction Stop").CombinedOutput()
-							if err != nil {
-								// Ignore "ObjectNotFound" error which happens if no rules exist yet
-								outputStr := string(output)
-								if !strings.Contains(outputStr, "ObjectNotFound") && !strings.Contains(outputStr, "对象未找到") {
-									lastFirewallError = strings.TrimSpace(outputStr)
-									log.Printf("Failed to enable rules: %v\nOutput: %s", err, lastFirewallError)
-								}
-							}
-						}
+						fw.Enable()
 						// Send immediate status update to reflect change in UI instantly
-						status := collectStatus()
-						resp, _ := json.Marshal(Message{
-							Type: "SYNC_COMPLETE",
-							Data: status,
-						})
-						c.WriteMessage(websocket.TextMessage, resp)
+						sendMessage(c, "SYNC_COMPLETE", collectStatus(rules))
 
 					case "DISABLE_FIREWALL":
 						log.Println("Disabling PRTS firewall protection...")
-						firewallEnabled = false
-						lastFirewallError = ""
-						lastFirewallInfo = "PRTS Firewall Protection Disabled (Rules preserved)"
-						// Disable PRTS rules instead of removing them
-						if runtime.GOOS == "windows" {
-							output, err := exec.Command("powershell", "-Command", "Disable-NetFirewallRule -Group 'PRTS-Honeypot' -ErrorAction Stop").CombinedOutput()
-							if err != nil {
-								// Ignore "ObjectNotFound" error which happens if no rules exist yet
-								outputStr := string(output)
-								if !strings.Contains(outputStr, "ObjectNotFound") && !strings.Contains(outputStr, "对象未找到") {
-									lastFirewallError = strings.TrimSpace(outputStr)
-									log.Printf("Failed to disable rules: %v\nOutput: %s", err, lastFirewallError)
-								}
-							}
-						}
+						fw.Disable()
 						// Send immediate status update to reflect change in UI instantly
-						status := collectStatus()
-						resp, _ := json.Marshal(Message{
-							Type: "SYNC_COMPLETE",
-							Data: status,
-						})
-						c.WriteMessage(websocket.TextMessage, resp)
+						sendMessage(c, "SYNC_COMPLETE", collectStatus(rules))
 					}
-				} else if msg.Type == "SYNC_RULES" {
-					log.Printf("Received firewall rules sync request")
-
-					// Unmarshal rules
-					jsonData, _ := json.Marshal(msg.Data)
-					var rules []AccessControlRule
-					if err := json.Unmarshal(jsonData, &rules); err == nil {
-						applyFirewallRules(rules)
-						// Send immediate status update after sync
-						status := collectStatus()
-						resp, _ := json.Marshal(Message{
-							Type: "SYNC_COMPLETE",
-							Data: status,
-						})
-						c.WriteMessage(websocket.TextMessage, resp)
+				} else if msg.Type == "SYNC_RULES" || msg.Type == "RULES_DIFF" {
+					log.Printf("Received firewall rules sync request (%s)", msg.Type)
+
+					if err := rules.handle(msg.Type, msg.Data); errors.Is(err, errRuleGap) {
+						log.Printf("%v, requesting a full snapshot", err)
+						ruleUpdates.Inc(msg.Type, "gap")
+						sendMessage(c, "RULES_RESYNC", rules.Version())
+					} else if err != nil {
+						log.Printf("Failed to apply rules: %v", err)
+						ruleUpdates.Inc(msg.Type, "error")
 					} else {
-						log.Printf("Failed to unmarshal rules: %v", err)
+						ruleUpdates.Inc(msg.Type, "applied")
+					}
+					// Send immediate status update after sync
+					sendMessage(c, "SYNC_COMPLETE", collectStatus(rules))
+				} else if msg.Type == "DEPLOY_DECOY" || msg.Type == "REMOVE_DECOY" {
+					cmd, err := decodeDecoyCommand(msg.Data)
+					if err != nil {
+						log.Printf("Failed to unmarshal decoy command: %v", err)
+						continue
+					}
+					if msg.Type == "DEPLOY_DECOY" {
+						go handleDeployDecoy(c, cmd)
+					} else {
+						go handleRemoveDecoy(c, cmd)
 					}
 				}
 			}
 		}
 	}()
 
+	go watchDecoys(c, done)
+
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
@@ -219,15 +247,9 @@ func main() {
 		case <-done:
 			return
 		case <-ticker.C:
-			status := collectStatus()
+			status := collectStatus(rules)
 			log.Printf("Reporting status: Load=%d%%, Uptime=%s", status.Load, status.Uptime)
-			msg := Message{
-				Type: "NODE_REPORT",
-				Data: status,
-			}
-
-			data, _ := json.Marshal(msg)
-			err := c.WriteMessage(websocket.TextMessage, data)
+			err := sendMessage(c, "NODE_REPORT", status)
 			if err != nil {
 				log.Println("write:", err)
 				return
@@ -237,7 +259,9 @@ func main() {
 
 			// Cleanly close the connection by sending a close message and then
 			// waiting (with timeout) for the server to close the connection.
+			writeMu.Lock()
 			err := c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			writeMu.Unlock()
 			if err != nil {
 				log.Println("write close:", err)
 				return
@@ -357,7 +381,7 @@ func getFallbackTemperature() float64 {
 	return 0
 }
 
-func collectStatus() NodeStatus {
+func collectStatus(rules *ruleStore) NodeStatus {
 	v, _ := mem.VirtualMemory()
 	c, _ := cpu.Percent(0, false)
 	h, _ := host.Info()
@@ -481,7 +505,9 @@ func collectStatus() NodeStatus {
 		}
 	}
 
-	return NodeStatus{
+	fwState := rules.fw.Status()
+
+	status := NodeStatus{
 		ID:             *id,
 		Name:           *name,
 		Region:         "CN-SH",
@@ -500,127 +526,15 @@ func collectStatus() NodeStatus {
 		Version:        fmt.Sprintf("v1.2.0-%s", h.KernelVersion),
 		Interface:      ifaceName,
 		MAC:            mac,
-		FirewallStatus: getFirewallStatus(),
-		FirewallError:  lastFirewallError,
-		FirewallInfo:   lastFirewallInfo,
-	}
-}
-
-func getFirewallStatus() string {
-	if runtime.GOOS == "windows" {
-		// Check if running as admin by trying to fetch firewall status
-		cmd := exec.Command("netsh", "advfirewall", "show", "currentprofile")
-		_, err := cmd.CombinedOutput()
-		if err != nil {
-			lastFirewallError = "Admin privileges required for firewall management"
-			return "error"
-		}
-	}
-
-	// If PRTS toggle is off, we report as inactive regardless of system firewall state
-	if !firewallEnabled {
-		return "inactive"
-	}
-
-	if lastFirewallError != "" {
-		return "error"
-	}
-
-	return "active"
-}
-
-func applyFirewallRules(rules []AccessControlRule) {
-	if !firewallEnabled {
-		log.Println("Firewall protection is disabled, skipping rule application")
-		return
-	}
-	if runtime.GOOS != "windows" {
-		log.Println("Firewall control only supported on Windows for now")
-		return
-	}
-
-	log.Printf("Applying Windows Firewall rules... (Received %d rules)", len(rules))
-	lastFirewallError = "" // Reset on new attempt
-	lastFirewallInfo = ""
-
-	// Group IPs by action
-	var blockIPs []string
-	var allowIPs []string
-
-	for _, rule := range rules {
-		if rule.Status != "active" {
-			continue
-		}
-		targetIP := strings.TrimSpace(rule.IP)
-		if targetIP == "" {
-			continue
-		}
-
-		if rule.Type == "whitelist" {
-			allowIPs = append(allowIPs, targetIP)
-		} else {
-			blockIPs = append(blockIPs, targetIP)
-		}
-	}
-
-	log.Printf("Processing IPs: %d to block, %d to allow", len(blockIPs), len(allowIPs))
-
-	// 1. Clear existing PRTS rules using Group
-	// We use PowerShell for better group management
-	log.Println("Clearing existing PRTS rules...")
-	clearCmd := exec.Command("powershell", "-Command", "Remove-NetFirewallRule -Group 'PRTS-Honeypot' -ErrorAction SilentlyContinue")
-	clearCmd.Run()
-
-	// 2. Add combined rules
-	// Blacklist Rule
-	if len(blockIPs) > 0 {
-		ips := strings.Join(blockIPs, ",")
-		log.Printf("Creating block rule for IPs: %s", ips)
-		addBlockCmd := exec.Command("powershell", "-Command", fmt.Sprintf(
-			"New-NetFirewallRule -DisplayName 'PRTS Inbound Block List' -Name 'PRTS-Block-Rules' -Direction Inbound -Action Block -RemoteAddress %s -Group 'PRTS-Honeypot' -Description 'Managed by PRTS Honeypot'",
-			ips,
-		))
-		output, err := addBlockCmd.CombinedOutput()
-		if err != nil {
-			lastFirewallError = strings.TrimSpace(string(output))
-			log.Printf("Failed to add block rules: %v\nOutput: %s", err, lastFirewallError)
-		} else {
-			msg := fmt.Sprintf("Successfully added block rule for %d IPs", len(blockIPs))
-			log.Println(msg)
-			if lastFirewallInfo != "" {
-				lastFirewallInfo += "; " + msg
-			} else {
-				lastFirewallInfo = msg
-			}
-		}
-	} else {
-		log.Println("No block IPs to apply.")
-	}
-
-	// Whitelist Rule
-	if len(allowIPs) > 0 {
-		ips := strings.Join(allowIPs, ",")
-		log.Printf("Creating allow rule for IPs: %s", ips)
-		addAllowCmd := exec.Command("powershell", "-Command", fmt.Sprintf(
-			"New-NetFirewallRule -DisplayName 'PRTS Inbound Allow List' -Name 'PRTS-Allow-Rules' -Direction Inbound -Action Allow -RemoteAddress %s -Group 'PRTS-Honeypot' -Description 'Managed by PRTS Honeypot'",
-			ips,
-		))
-		output, err := addAllowCmd.CombinedOutput()
-		if err != nil {
-			if lastFirewallError == "" {
-				lastFirewallError = strings.TrimSpace(string(output))
-			}
-			log.Printf("Failed to add allow rules: %v\nOutput: %s", err, string(output))
-		} else {
-			msg := fmt.Sprintf("Successfully added allow rule for %d IPs", len(allowIPs))
-			log.Println(msg)
-			if lastFirewallInfo != "" {
-				lastFirewallInfo += "; " + msg
-			} else {
-				lastFirewallInfo = msg
-			}
-		}
-	} else {
-		log.Println("No allow IPs to apply.")
+		FirewallStatus: fwState.Status,
+		FirewallError:  fwState.Error,
+		FirewallInfo:   fwState.Info,
+		RuleVersion:    rules.Version(),
+		Clock:          probeNow().Format(time.RFC3339Nano),
+
+		collectedAt:   now,
+		uptimeSeconds: uptimeSec,
 	}
+	lastStatus.Store(&status)
+	return status
 }
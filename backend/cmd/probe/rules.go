@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// errRuleGap means a diff starts after the version this probe holds
+var errRuleGap = errors.New("rule diff does not follow the applied version")
+
+type ruleSnapshot struct {
+	Version int64               `json:"version"`
+	Rules   []AccessControlRule `json:"rules"`
+}
+
+type ruleDiff struct {
+	From    int64               `json:"from"`
+	Version int64               `json:"version"`
+	Add     []AccessControlRule `json:"add"`
+	Remove  []string            `json:"remove"`
+}
+
+// ruleStore is the probe's copy of the access rules. Every change is handed to the
+// firewall backend as a whole set, the backends swap it in without clearing first.
+type ruleStore struct {
+	mu      sync.Mutex
+	fw      FirewallBackend
+	version int64
+	rules   map[string]AccessControlRule
+}
+
+func newRuleStore(fw FirewallBackend) *ruleStore {
+	return &ruleStore{fw: fw, rules: map[string]AccessControlRule{}}
+}
+
+// Version is the rule set version last applied, reported in NODE_REPORT
+func (s *ruleStore) Version() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// Count is the number of rules held
+func (s *ruleStore) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.rules)
+}
+
+// handle applies a SYNC_RULES snapshot or a RULES_DIFF message
+func (s *ruleStore) handle(msgType string, data interface{}) error {
+	raw, _ := json.Marshal(data)
+	if msgType == "RULES_DIFF" {
+		var diff ruleDiff
+		if err := json.Unmarshal(raw, &diff); err != nil {
+			return err
+		}
+		return s.applyDiff(diff)
+	}
+
+	var snap ruleSnapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		// Servers before versioning send a bare rule list
+		var rules []AccessControlRule
+		if err := json.Unmarshal(raw, &rules); err != nil {
+			return err
+		}
+		snap = ruleSnapshot{Rules: rules}
+	}
+	return s.applySnapshot(snap)
+}
+
+func (s *ruleStore) applySnapshot(snap ruleSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := make(map[string]AccessControlRule, len(snap.Rules))
+	for _, rule := range snap.Rules {
+		next[rule.ID] = rule
+	}
+	return s.commit(next, snap.Version)
+}
+
+func (s *ruleStore) applyDiff(diff ruleDiff) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if diff.Version <= s.version {
+		return nil
+	}
+	if diff.From > s.version {
+		return fmt.Errorf("%w: holding v%d, diff is v%d -> v%d", errRuleGap, s.version, diff.From, diff.Version)
+	}
+
+	// Diffs carry the final state of each touched rule, so applying one that starts
+	// before our version is still correct
+	next := make(map[string]AccessControlRule, len(s.rules)+len(diff.Add))
+	for id, rule := range s.rules {
+		next[id] = rule
+	}
+	for _, id := range diff.Remove {
+		delete(next, id)
+	}
+	for _, rule := range diff.Add {
+		next[rule.ID] = rule
+	}
+	return s.commit(next, diff.Version)
+}
+
+// commit hands the new set to the backend. The version only moves on success so the
+// server keeps retrying a set that failed to apply.
+func (s *ruleStore) commit(next map[string]AccessControlRule, version int64) error {
+	list := make([]AccessControlRule, 0, len(next))
+	for _, rule := range next {
+		list = append(list, rule)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	s.rules = next
+	if err := s.fw.Apply(list); err != nil {
+		return err
+	}
+	s.version = version
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+)
+
+// windowsFirewall manages two combined rules in the 'PRTS-Honeypot' group of Windows Defender Firewall
+type windowsFirewall struct {
+	firewallBase
+}
+
+func newWindowsFirewall() *windowsFirewall {
+	return &windowsFirewall{firewallBase: firewallBase{enabled: true}}
+}
+
+func (f *windowsFirewall) Name() string { return "windows" }
+
+// setGroupEnabled runs Enable- or Disable-NetFirewallRule on the PRTS group
+func (f *windowsFirewall) setGroupEnabled(enabled bool) error {
+	verb, info := "Enable", "PRTS Firewall Protection Enabled"
+	if !enabled {
+		verb, info = "Disable", "PRTS Firewall Protection Disabled (Rules preserved)"
+	}
+	f.setEnabled(enabled)
+
+	output, err := exec.Command("powershell", "-Command", verb+"-NetFirewallRule -Group 'PRTS-Honeypot' -ErrorAction Stop").CombinedOutput()
+	if err != nil {
+		// Ignore "ObjectNotFound" error which happens if no rules exist yet
+		outputStr := string(output)
+		if !strings.Contains(outputStr, "ObjectNotFound") && !strings.Contains(outputStr, "对象未找到") {
+			msg := strings.TrimSpace(outputStr)
+			log.Printf("Failed to %s rules: %v\nOutput: %s", strings.ToLower(verb), err, msg)
+			f.set(errors.New(msg), info)
+			return err
+		}
+	}
+	f.set(nil, info)
+	return nil
+}
+
+func (f *windowsFirewall) Enable() error  { return f.setGroupEnabled(true) }
+func (f *windowsFirewall) Disable() error { return f.setGroupEnabled(false) }
+
+func (f *windowsFirewall) Status() FirewallState {
+	// Check if running as admin by trying to fetch firewall status
+	if _, err := exec.Command("netsh", "advfirewall", "show", "currentprofile").CombinedOutput(); err != nil {
+		st := f.state()
+		st.Status = "error"
+		st.Error = "Admin privileges required for firewall management"
+		return st
+	}
+	return f.state()
+}
+
+// windowsRule is one New-NetFirewallRule rendered from a rule group
+type windowsRule struct {
+	name, display, direction, action, protocol, ports string
+	addrs                                             []string
+}
+
+// windowsRules maps groups to named rules. Unscoped inbound groups keep the historical
+// PRTS-Block-Rules and PRTS-Allow-Rules names, scoped ones carry their scope in the name
+// so an update only has to touch -RemoteAddress. The name leaves out whether a group
+// also covers the other direction, groups that end up with the same name share the rule.
+func windowsRules(groups []ruleGroup) []windowsRule {
+	var out []windowsRule
+	byName := map[string]int{}
+	for _, g := range groups {
+		action, label := "Block", "Block"
+		if g.Action == "allow" {
+			action, label = "Allow", "Allow"
+		}
+
+		// Windows has no protocol that covers ICMP for both families
+		protoAddrs := map[string][]string{}
+		switch g.Protocol {
+		case "any":
+			protoAddrs["Any"] = g.Addrs
+		case "icmp":
+			for _, addr := range g.Addrs {
+				if _, family, _ := parseRuleAddress(addr); family == 6 {
+					protoAddrs["ICMPv6"] = append(protoAddrs["ICMPv6"], addr)
+				} else {
+					protoAddrs["ICMPv4"] = append(protoAddrs["ICMPv4"], addr)
+				}
+			}
+		default:
+			protoAddrs[strings.ToUpper(g.Protocol)] = g.Addrs
+		}
+
+		for _, dir := range []string{"Inbound", "Outbound"} {
+			if (dir == "Inbound" && !g.inbound()) || (dir == "Outbound" && !g.outbound()) {
+				continue
+			}
+			for _, proto := range []string{"Any", "TCP", "UDP", "ICMPv4", "ICMPv6"} {
+				addrs := protoAddrs[proto]
+				if len(addrs) == 0 {
+					continue
+				}
+				r := windowsRule{direction: dir, action: action, protocol: proto, ports: g.Ports, addrs: addrs}
+				if !g.scoped() {
+					r.name = fmt.Sprintf("PRTS-%s-Rules", label)
+					r.display = fmt.Sprintf("PRTS Inbound %s List", label)
+				} else {
+					scope := proto
+					if g.Ports != "" {
+						scope += "-" + strings.ReplaceAll(g.Ports, ",", "_")
+					}
+					r.name = fmt.Sprintf("PRTS-%s-%s-%s", label, dir, scope)
+					r.display = fmt.Sprintf("PRTS %s %s List (%s %s)", dir, label, proto, g.Ports)
+				}
+				if i, ok := byName[r.name]; ok {
+					out[i].addrs = mergeAddrs(out[i].addrs, addrs)
+					continue
+				}
+				byName[r.name] = len(out)
+				out = append(out, r)
+			}
+		}
+	}
+	return out
+}
+
+// mergeAddrs returns the addresses of both lists without repeats
+func mergeAddrs(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	out := make([]string, 0, len(a)+len(b))
+	for _, addr := range append(append([]string(nil), a...), b...) {
+		if !seen[addr] {
+			seen[addr] = true
+			out = append(out, addr)
+		}
+	}
+	return out
+}
+
+func (f *windowsFirewall) Apply(rules []AccessControlRule) error {
+	// Nothing is enforced while disabled, the version must not count as applied so drift
+	// shows until protection is switched on and a snapshot comes
+	if !f.isEnabled() {
+		return errors.New("firewall protection is disabled, rules not applied")
+	}
+
+	log.Printf("Applying Windows Firewall rules... (Received %d rules)", len(rules))
+
+	// Group IPs by action and scope
+	groups, skipped := groupRules(rules, false)
+	for _, bad := range skipped {
+		log.Printf("Skipping invalid firewall address: %s", bad)
+	}
+	log.Printf("Processing rules: %s", groupSummary(groups, skipped))
+
+	// Each group is one named rule that is updated in place, so the previous addresses
+	// stay enforced until Set-NetFirewallRule swaps them
+	var firstErr error
+	var infos []string
+	var keep []string
+	for _, r := range windowsRules(groups) {
+		keep = append(keep, "'"+r.name+"'")
+		ips := strings.Join(r.addrs, ",")
+		create := fmt.Sprintf("New-NetFirewallRule -DisplayName '%s' -Name '%s' -Direction %s -Action %s -Protocol %s -RemoteAddress %s -Group 'PRTS-Honeypot' -Description 'Managed by PRTS Honeypot' -ErrorAction Stop",
+			r.display, r.name, r.direction, r.action, r.protocol, ips)
+		if r.ports != "" {
+			if r.direction == "Inbound" {
+				create += " -LocalPort " + r.ports
+			} else {
+				create += " -RemotePort " + r.ports
+			}
+		}
+		log.Printf("Updating rule %s for IPs: %s", r.name, ips)
+		output, err := exec.Command("powershell", "-Command", fmt.Sprintf(
+			"if (Get-NetFirewallRule -Name '%[1]s' -ErrorAction SilentlyContinue) { Set-NetFirewallRule -Name '%[1]s' -RemoteAddress %[2]s -ErrorAction Stop } else { %[3]s }",
+			r.name, ips, create,
+		)).CombinedOutput()
+		if err != nil {
+			log.Printf("Failed to apply rule %s: %v\nOutput: %s", r.name, err, string(output))
+			if firstErr == nil {
+				firstErr = errors.New(strings.TrimSpace(string(output)))
+			}
+			continue
+		}
+		msg := fmt.Sprintf("Successfully applied %s for %d IPs", r.name, len(r.addrs))
+		log.Println(msg)
+		infos = append(infos, msg)
+	}
+
+	// Drop rules of groups that no longer exist
+	cleanup := "Get-NetFirewallRule -Group 'PRTS-Honeypot' -ErrorAction SilentlyContinue | Remove-NetFirewallRule"
+	if len(keep) > 0 {
+		cleanup = fmt.Sprintf("Get-NetFirewallRule -Group 'PRTS-Honeypot' -ErrorAction SilentlyContinue | Where-Object { @(%s) -notcontains $_.Name } | Remove-NetFirewallRule", strings.Join(keep, ","))
+	}
+	exec.Command("powershell", "-Command", cleanup).Run()
+
+	f.mu.Lock()
+	f.info = strings.Join(infos, "; ")
+	f.mu.Unlock()
+	f.set(firstErr, "")
+	return firstErr
+}
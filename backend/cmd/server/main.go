@@ -17,18 +17,31 @@ import (
 
 func main() {
 	// Initialize Database
-	db, err := gorm.Open(sqlite.Open("prts.db"), &gorm.Config{})
+	// Timestamps are stored in UTC so that text comparison in SQLite orders them correctly
+	db, err := gorm.Open(sqlite.Open("prts.db"), &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
 	if err != nil {
 		log.Fatal("failed to connect database")
 	}
+	if err := api.InstrumentDB(db); err != nil {
+		log.Fatal("failed to instrument database: ", err)
+	}
 
 	// Auto Migrate
 	log.Println("Running database migrations...")
+	// Credential rows recorded before attempts were upserted repeat their key
+	if err := api.MergeDuplicateCredentials(db); err != nil {
+		log.Fatal("failed to merge duplicated credentials: ", err)
+	}
 	err = db.AutoMigrate(
 		&model.User{},
 		&model.AttackLog{},
 		&model.NodeStatus{},
-		&model.Message{},
+		&model.Message{}, &model.MessageState{}, &model.MessageSubscription{}, &model.AlertSilence{},
+		&model.NotificationChannel{}, &model.NotificationRule{}, &model.NotificationDelivery{},
+		&model.SyslogDestination{}, &model.OpenSearchOutput{}, &model.EventBusOutput{},
+		&model.IngestAdapter{}, &model.IngestTail{}, &model.IngestKey{}, &model.IngestRejection{},
 		&model.SystemConfig{},
 		&model.Template{},
 		&model.Service{},
@@ -36,12 +49,13 @@ func main() {
 		&model.AccountCredential{},
 		&model.ScanLog{},
 		&model.DecoyLog{},
+		&model.CanaryToken{}, &model.InventoryAccount{},
 		&model.SampleLog{},
 		&model.VulnRule{},
 		&model.TrafficRule{},
 		&model.DefenseStrategy{},
-		&model.AccessControlRule{},
-		&model.LoginLog{},
+		&model.AccessControlRule{}, &model.AccessRuleChange{}, &model.BlocklistSubscription{},
+		&model.LoginLog{}, &model.TimeSyncLog{}, &model.SearchDocument{}, &model.AttackRollup{}, &model.NodeMetric{},
 		&model.Report{},
 		&model.LoginAttempt{},
 		&model.LoginPolicy{},
@@ -50,6 +64,29 @@ func main() {
 	if err != nil {
 		log.Fatal("failed to migrate database: ", err)
 	}
+	// Messages from before alert tracking sort by their creation time
+	db.Model(&model.Message{}).Where("last_seen IS NULL").Update("last_seen", gorm.Expr("time"))
+	// Read used to be one flag for everyone, hand it to every user before dropping it
+	if db.Migrator().HasColumn(&model.Message{}, "read") {
+		db.Exec(`INSERT OR IGNORE INTO message_states (message_id, username, read, read_at, archived)
+			SELECT m.id, u.username, 1, m.last_seen, 0 FROM messages m, users u
+			WHERE m.read = 1 AND u.deleted_at IS NULL`)
+		// SQLite rebuilds the table to drop a column, migrating again restores the indexes
+		if err := db.Migrator().DropColumn(&model.Message{}, "read"); err != nil {
+			log.Fatal("failed to drop messages.read: ", err)
+		}
+		if err := db.AutoMigrate(&model.Message{}); err != nil {
+			log.Fatal("failed to migrate database: ", err)
+		}
+	}
+	if err := api.MigrateTimeColumns(db); err != nil {
+		log.Fatal("failed to migrate time columns: ", err)
+	}
+	// Leaked credential alerts go to the notification channels, the old webhook URL could be
+	// set by any user and is not carried over
+	if res := db.Where("key = ?", "leak_alert_webhook").Delete(&model.SystemConfig{}); res.RowsAffected > 0 {
+		log.Println("Dropped leak_alert_webhook, route leaked_credential alerts to a notification channel instead")
+	}
 
 	// Reset all nodes to offline on startup
 	db.Model(&model.NodeStatus{}).Where("1 = 1").Update("status", "offline")
@@ -71,30 +108,76 @@ func main() {
 
 	h = &api.Handler{DB: db, Hub: hub}
 
-	// Load persisted time offset
+	// Load persisted time offset, then keep it in sync with the NTP servers
 	h.LoadTimeOffset()
-
-	// Start automatic rule cleanup task
-	h.StartAutoCleanup()
+	go h.RunNtpSync()
 
 	// Seed Data
 	seedData(db)
 
+	// Pick the full-text engine and index records written by older versions
+	h.StartSearchIndex()
+
+	// Roll up attacks recorded before trends were kept, then expire fine buckets
+	h.StartAttackRollups()
+	go h.RunRollupRetention()
+
+	// Store probe telemetry in downsampled tiers and sample the server itself
+	go h.RunNodeMetrics()
+	go h.RunSystemSampler()
+
+	// Send queued alert notifications and retry failed ones
+	go h.RunNotifications()
+
+	// Forward events to the SIEM collectors, starting with what was queued before a restart
+	h.StartSiemForwarding()
+	h.StartOpenSearchOutputs()
+	h.StartEventBusOutputs()
+
+	// Follow the log files of other sensors from where each tail stopped
+	h.StartIngestTails()
+	go h.RunIngestRejectionRetention()
+
+	// Version the access rule set and retire expired rules in the background
+	h.EnsureRuleVersion()
+	go h.RunAccessRuleExpiry()
+	go h.RunBlocklistRefresh()
+
+	// Start the authoritative DNS responder for canary tokens if a zone is configured
+	h.StartCanaryDNS()
+
 	r := gin.Default()
 
 	// Middleware
 	r.Use(middleware.CORSMiddleware())
 
+	// Prometheus scrape endpoint, authenticated by its own settings instead of a console login
+	r.GET("/metrics", h.Metrics)
+
 	// Routes
 	v1 := r.Group("/api/v1")
 	{
 		v1.GET("/public/login-policy", h.GetPublicLoginPolicy)
 		v1.POST("/login", h.LoginHandler)
-		v1.POST("/ingest", h.IngestAttack)
+
+		// Sensors post with an ingest key instead of a console login
+		sensors := v1.Group("/ingest")
+		sensors.Use(h.IngestKeyRequired())
+		{
+			sensors.POST("", h.IngestAttack)
+			sensors.POST("/credentials", h.IngestCredential)
+			sensors.POST("/logs/:adapter", h.IngestAdapterLogs)
+		}
+
+		// Canary token callbacks, must stay reachable without authentication
+		v1.GET("/canary/:token", h.CanaryCallback)
+		v1.GET("/canary/:token/*path", h.CanaryCallback)
+		v1.Any("/canary-aws", h.CanaryAWSCallback)
+		v1.Any("/canary-aws/*path", h.CanaryAWSCallback)
 
 		// WebSocket endpoint
 		v1.GET("/ws", func(c *gin.Context) {
-			websocket.ServeWs(hub, c.Writer, c.Request)
+			websocket.ServeWs(hub, c.Writer, c.Request, h.SocketUser(c))
 		})
 
 		// Protected routes
@@ -105,11 +188,24 @@ func main() {
 			protected.GET("/nodes", h.GetNodes)
 			protected.POST("/nodes/command", h.HandleNodeCommand)
 			protected.DELETE("/nodes/:id", h.DeleteNode)
+			protected.GET("/nodes/:id/metrics", h.GetNodeMetrics)
+			protected.GET("/nodes/groups", h.GetNodeGroups)
+			protected.POST("/nodes/:id/groups", h.UpdateNodeGroups)
 			protected.GET("/stats/dashboard", h.GetDashboardStats)
 			protected.GET("/stats/system", h.GetSystemStats)
+			protected.GET("/stats/trends", h.GetAttackTrends)
 
 			// Messages
+			protected.POST("/ws-ticket", h.IssueSocketTicket)
 			protected.GET("/messages", h.GetMessages)
+			protected.GET("/messages/unread-count", h.GetUnreadCount)
+			protected.GET("/messages/subscription", h.GetMessageSubscription)
+			protected.POST("/messages/subscription", h.UpdateMessageSubscription)
+			protected.POST("/messages/:id/read", h.SetMessageRead)
+			protected.POST("/messages/:id/archive", h.ArchiveMessage)
+			protected.POST("/messages/:id/ack", h.AcknowledgeMessage)
+			protected.POST("/messages/:id/assign", h.AssignMessage)
+			protected.POST("/messages/:id/resolve", h.ResolveMessage)
 			protected.POST("/messages/read-all", h.MarkAllMessagesRead)
 			protected.DELETE("/messages/:id", h.DeleteMessage)
 
@@ -118,16 +214,27 @@ func main() {
 			protected.POST("/config", h.UpdateConfig)
 			protected.POST("/system/defense-level", h.UpdateDefenseLevel)
 			protected.POST("/system/ntp-sync", h.NtpSync)
+			protected.GET("/system/ntp-history", h.GetNtpHistory)
+			protected.GET("/system/time-status", h.GetTimeStatus)
 			protected.POST("/system/db-clean", h.CleanDatabase)
+			protected.GET("/system/metrics", middleware.AdminRequired(), h.GetMetricsConfig)
+			protected.POST("/system/metrics", middleware.AdminRequired(), h.UpdateMetricsConfig)
 
 			// Templates & Services
 			protected.GET("/templates", h.GetTemplates)
 			protected.GET("/services", h.GetServices)
 			protected.GET("/attack-sources", h.GetAttackSources)
 			protected.GET("/account-credentials", h.GetAccountCredentials)
+			protected.GET("/account-credentials/analytics", h.GetCredentialAnalytics)
+			protected.GET("/account-credentials/wordlist", h.ExportCredentialWordlist)
 			protected.GET("/scans", h.GetScans)
 			protected.GET("/decoys", h.GetDecoys)
-			protected.POST("/decoys", h.DeployDecoy)
+			protected.POST("/decoys", middleware.AdminRequired(), h.DeployDecoy)
+			protected.DELETE("/decoys/:id", middleware.AdminRequired(), h.RemoveDecoy)
+			protected.GET("/canary-tokens", h.GetCanaryTokens)
+			protected.POST("/canary-tokens", h.CreateCanaryToken)
+			protected.DELETE("/canary-tokens/:id", h.DeleteCanaryToken)
+			protected.GET("/canary-tokens/:id/document", h.DownloadCanaryDocument)
 			protected.GET("/samples", h.GetSamples)
 			protected.DELETE("/samples/:id", h.DeleteSample)
 			protected.GET("/vuln-rules", h.GetVulnRules)
@@ -143,8 +250,14 @@ func main() {
 			protected.GET("/access-rules", h.GetAccessControlRules)
 			protected.POST("/access-rules", h.CreateAccessControlRule)
 			protected.DELETE("/access-rules/:id", h.DeleteAccessControlRule)
+			protected.POST("/access-rules/:id/scope", h.UpdateAccessRuleScope)
 			protected.POST("/access-rules/sync", h.SyncAccessRules)
+			protected.GET("/access-rules/drift", h.GetRuleDrift)
+			protected.GET("/access-rules/lookup", h.LookupAccessRules)
+			protected.POST("/access-rules/validate", h.ValidateAccessRule)
 			protected.GET("/login-logs", h.GetLoginLogs)
+			protected.GET("/search", h.Search)
+			protected.POST("/search/reindex", middleware.AdminRequired(), h.ReindexSearch)
 			protected.GET("/reports", h.GetReports)
 			protected.POST("/reports", h.CreateReport)
 
@@ -152,6 +265,114 @@ func main() {
 			protected.GET("/modules", h.GetModules)
 			protected.POST("/modules/:name", h.UpdateModule)
 
+			// External blocklists reconciled into access rules
+			blocklists := protected.Group("/blocklists")
+			blocklists.Use(middleware.AdminRequired())
+			{
+				blocklists.GET("", h.GetBlocklists)
+				blocklists.POST("", h.CreateBlocklist)
+				blocklists.POST("/:id", h.UpdateBlocklist)
+				blocklists.DELETE("/:id", h.DeleteBlocklist)
+				blocklists.POST("/:id/preview", h.PreviewBlocklist)
+				blocklists.POST("/:id/activate", h.ActivateBlocklist)
+				blocklists.POST("/:id/refresh", h.RefreshBlocklist)
+			}
+
+			// Account inventory for leaked credential alerts
+			inventory := protected.Group("/inventory")
+			inventory.Use(middleware.AdminRequired())
+			{
+				inventory.GET("/accounts", h.GetInventoryAccounts)
+				inventory.DELETE("/accounts/:id", h.DeleteInventoryAccount)
+				inventory.POST("/import/csv", h.ImportInventoryCSV)
+				inventory.GET("/ldap", h.GetLDAPInventoryConfig)
+				inventory.POST("/ldap", h.UpdateLDAPInventoryConfig)
+				inventory.POST("/ldap/sync", h.SyncInventoryLDAP)
+			}
+
+			// Alert folding windows, rate limit and maintenance silences
+			alerts := protected.Group("/alerts")
+			alerts.Use(middleware.AdminRequired())
+			{
+				alerts.GET("/config", h.GetAlertConfig)
+				alerts.POST("/config", h.UpdateAlertConfig)
+				alerts.GET("/silences", h.GetAlertSilences)
+				alerts.POST("/silences", h.CreateAlertSilence)
+				alerts.DELETE("/silences/:id", h.DeleteAlertSilence)
+			}
+
+			// Alert notification channels, routing rules and the delivery log
+			notifications := protected.Group("/notifications")
+			notifications.Use(middleware.AdminRequired())
+			{
+				notifications.GET("/channels", h.GetNotificationChannels)
+				notifications.POST("/channels", h.CreateNotificationChannel)
+				notifications.POST("/channels/:id", h.UpdateNotificationChannel)
+				notifications.DELETE("/channels/:id", h.DeleteNotificationChannel)
+				notifications.POST("/channels/:id/test", h.TestNotificationChannel)
+				notifications.GET("/rules", h.GetNotificationRules)
+				notifications.POST("/rules", h.CreateNotificationRule)
+				notifications.POST("/rules/:id", h.UpdateNotificationRule)
+				notifications.DELETE("/rules/:id", h.DeleteNotificationRule)
+				notifications.GET("/deliveries", h.GetNotificationDeliveries)
+				notifications.POST("/deliveries/:id/retry", h.RetryNotificationDelivery)
+			}
+
+			// SIEM forwarding over syslog
+			siem := protected.Group("/siem")
+			siem.Use(middleware.AdminRequired())
+			{
+				siem.GET("/destinations", h.GetSyslogDestinations)
+				siem.POST("/destinations", h.CreateSyslogDestination)
+				siem.POST("/destinations/:id", h.UpdateSyslogDestination)
+				siem.DELETE("/destinations/:id", h.DeleteSyslogDestination)
+				siem.POST("/destinations/:id/test", h.TestSyslogDestination)
+			}
+
+			// Bulk indexing into OpenSearch or Elasticsearch
+			opensearch := protected.Group("/opensearch")
+			opensearch.Use(middleware.AdminRequired())
+			{
+				opensearch.GET("/outputs", h.GetOpenSearchOutputs)
+				opensearch.POST("/outputs", h.CreateOpenSearchOutput)
+				opensearch.POST("/outputs/:id", h.UpdateOpenSearchOutput)
+				opensearch.DELETE("/outputs/:id", h.DeleteOpenSearchOutput)
+				opensearch.POST("/outputs/:id/test", h.TestOpenSearchOutput)
+			}
+
+			// Kafka and NATS outputs for downstream pipelines
+			eventBus := protected.Group("/eventbus")
+			eventBus.Use(middleware.AdminRequired())
+			{
+				eventBus.GET("/outputs", h.GetEventBusOutputs)
+				eventBus.POST("/outputs", h.CreateEventBusOutput)
+				eventBus.POST("/outputs/:id", h.UpdateEventBusOutput)
+				eventBus.DELETE("/outputs/:id", h.DeleteEventBusOutput)
+				eventBus.POST("/outputs/:id/test", h.TestEventBusOutput)
+			}
+
+			// Adapters mapping the logs of other sensors, and the files they follow
+			ingestAdmin := protected.Group("/ingest")
+			ingestAdmin.Use(middleware.AdminRequired())
+			{
+				ingestAdmin.GET("/adapters", h.GetIngestAdapters)
+				ingestAdmin.POST("/adapters", h.CreateIngestAdapter)
+				ingestAdmin.POST("/adapters/preview", h.PreviewIngestAdapter)
+				ingestAdmin.POST("/adapters/:id", h.UpdateIngestAdapter)
+				ingestAdmin.DELETE("/adapters/:id", h.DeleteIngestAdapter)
+				ingestAdmin.GET("/tails", h.GetIngestTails)
+				ingestAdmin.POST("/tails", h.CreateIngestTail)
+				ingestAdmin.POST("/tails/:id", h.UpdateIngestTail)
+				ingestAdmin.DELETE("/tails/:id", h.DeleteIngestTail)
+				ingestAdmin.GET("/keys", h.GetIngestKeys)
+				ingestAdmin.POST("/keys", h.CreateIngestKey)
+				ingestAdmin.POST("/keys/:id", h.UpdateIngestKey)
+				ingestAdmin.POST("/keys/:id/rotate", h.RotateIngestKey)
+				ingestAdmin.DELETE("/keys/:id", h.DeleteIngestKey)
+				ingestAdmin.GET("/rejections", h.GetIngestRejections)
+				ingestAdmin.DELETE("/rejections", h.DeleteIngestRejections)
+			}
+
 			// User Management
 			protected.GET("/users", h.GetUsers)
 			protected.POST("/users", h.CreateUser)
@@ -164,6 +385,17 @@ func main() {
 	r.Run(":8080")
 }
 
+// seedTime reads a local wall-clock seed timestamp, everything is stored in UTC
+func seedTime(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+	return t.UTC()
+}
+
+func seedTimePtr(s string) *time.Time {
+	t := seedTime(s)
+	return &t
+}
+
 func seedData(db *gorm.DB) {
 	// Seed Users
 	var count int64
@@ -184,8 +416,8 @@ func seedData(db *gorm.DB) {
 	db.Model(&model.Message{}).Count(&count)
 	if count == 0 {
 		messages := []model.Message{
-			{ID: "msg-1", Title: "System Update Completed", Content: "PRTS Honeypot System has been updated to v3.3.7.", Time: api.GetNow(), Type: "system", Read: false},
-			{ID: "msg-2", Title: "High Risk Attack Detected", Content: "Multiple failed login attempts detected from IP 192.168.1.105.", Time: api.GetNow().Add(-time.Hour), Type: "security", Read: false},
+			{ID: "msg-1", Title: "System Update Completed", Content: "PRTS Honeypot System has been updated to v3.3.7.", Time: api.GetNow(), LastSeen: api.GetNow(), Type: "system"},
+			{ID: "msg-2", Title: "High Risk Attack Detected", Content: "Multiple failed login attempts detected from IP 192.168.1.105.", Time: api.GetNow().Add(-time.Hour), LastSeen: api.GetNow().Add(-time.Hour), Type: "security"},
 		}
 		db.Create(&messages)
 	}
@@ -208,8 +440,8 @@ func seedData(db *gorm.DB) {
 	db.Model(&model.AttackSource{}).Count(&count)
 	if count == 0 {
 		sources := []model.AttackSource{
-			{ID: "AS-001", IP: "3.84.203.101", Country: "US", Verdict: "high", AttackCount: 2704, ScanCount: 2695, Nodes: "Internal Node", FirstTime: "2025/11/23 23:19:27", Tags: "scan,dynamic_ip"},
-			{ID: "AS-002", IP: "117.132.188.205", Country: "CN", Verdict: "medium", AttackCount: 300, ScanCount: 18, Nodes: "Internal Node", FirstTime: "2025/11/20 12:16:10", Tags: "trash_mail"},
+			{ID: "AS-001", IP: "3.84.203.101", Country: "US", Verdict: "high", AttackCount: 2704, ScanCount: 2695, Nodes: "Internal Node", FirstTime: seedTime("2025-11-23 23:19:27"), Tags: "scan,dynamic_ip"},
+			{ID: "AS-002", IP: "117.132.188.205", Country: "CN", Verdict: "medium", AttackCount: 300, ScanCount: 18, Nodes: "Internal Node", FirstTime: seedTime("2025-11-20 12:16:10"), Tags: "trash_mail"},
 		}
 		db.Create(&sources)
 	}
@@ -218,8 +450,8 @@ func seedData(db *gorm.DB) {
 	db.Model(&model.AccountCredential{}).Count(&count)
 	if count == 0 {
 		creds := []model.AccountCredential{
-			{ID: "AC-001", Service: "SSH", Username: "admin", Password: "password123", IP: "192.168.1.105", Count: 12, Time: "2025/12/01 10:00:00"},
-			{ID: "AC-002", Service: "MySQL", Username: "root", Password: "root", IP: "192.168.1.106", Count: 5, Time: "2025/12/01 11:00:00"},
+			{ID: "AC-001", Service: "SSH", Username: "admin", Password: "password123", IP: "192.168.1.105", Count: 12, Time: seedTime("2025-12-01 10:00:00")},
+			{ID: "AC-002", Service: "MySQL", Username: "root", Password: "root", IP: "192.168.1.106", Count: 5, Time: seedTime("2025-12-01 11:00:00")},
 		}
 		db.Create(&creds)
 	}
@@ -238,8 +470,8 @@ func seedData(db *gorm.DB) {
 	db.Model(&model.ScanLog{}).Count(&count)
 	if count == 0 {
 		scans := []model.ScanLog{
-			{ID: "scan-1", IP: "192.168.1.105", Threat: "Malicious", Node: "Chernobog-A", Location: "Internal", Type: "TCP", Count: 150, Ports: "22, 80, 443", Start: "2025-11-06 10:00:00", Duration: "5m 12s"},
-			{ID: "scan-2", IP: "10.0.0.50", Threat: "Suspicious", Node: "Lungmen-01", Location: "Internal", Type: "UDP", Count: 45, Ports: "53, 123", Start: "2025-11-06 11:30:00", Duration: "1m 45s"},
+			{ID: "scan-1", IP: "192.168.1.105", Threat: "Malicious", Node: "Chernobog-A", Location: "Internal", Type: "TCP", Count: 150, Ports: "22, 80, 443", Start: seedTime("2025-11-06 10:00:00"), Duration: "5m 12s"},
+			{ID: "scan-2", IP: "10.0.0.50", Threat: "Suspicious", Node: "Lungmen-01", Location: "Internal", Type: "UDP", Count: 45, Ports: "53, 123", Start: seedTime("2025-11-06 11:30:00"), Duration: "1m 45s"},
 		}
 		db.Create(&scans)
 	}
@@ -254,10 +486,10 @@ func seedData(db *gorm.DB) {
 				Status:     "Compromised",
 				Device:     "WIN-SRV-01",
 				SourceIP:   "192.168.1.109",
-				Time:       "2025/12/06 10:15:22",
+				Time:       seedTime("2025-12-06 10:15:22"),
 				Result:     "Handled",
 				DecoyName:  "passwords.txt",
-				DeployTime: "2025/11/20 09:00:00",
+				DeployTime: seedTime("2025-11-20 09:00:00"),
 				Node:       "Internal Node",
 			},
 			{
@@ -266,10 +498,10 @@ func seedData(db *gorm.DB) {
 				Status:     "Compromised",
 				Device:     "DB-MASTER",
 				SourceIP:   "192.168.1.55",
-				Time:       "2025/12/06 09:30:15",
+				Time:       seedTime("2025-12-06 09:30:15"),
 				Result:     "Pending",
 				DecoyName:  "backup_service.exe",
-				DeployTime: "2025/11/22 14:30:00",
+				DeployTime: seedTime("2025-11-22 14:30:00"),
 				Node:       "Internal Node",
 			},
 		}
@@ -288,7 +520,7 @@ func seedData(db *gorm.DB) {
 				ThreatLevel:  "malicious",
 				Status:       "completed",
 				CaptureCount: 15,
-				LastTime:     "2023-10-24 12:45:00",
+				LastTime:     seedTime("2023-10-24 12:45:00"),
 				AttackerIP:   "192.168.1.55",
 				SourceNode:   "Chernobog-A",
 				SHA256:       "a1b2c3d4e5f6...",
@@ -301,7 +533,7 @@ func seedData(db *gorm.DB) {
 				ThreatLevel:  "malicious",
 				Status:       "completed",
 				CaptureCount: 3,
-				LastTime:     "2023-10-24 13:02:15",
+				LastTime:     seedTime("2023-10-24 13:02:15"),
 				AttackerIP:   "45.148.10.247",
 				SourceNode:   "Lungmen-Gateway",
 				SHA256:       "8877665544...",
@@ -320,10 +552,10 @@ func seedData(db *gorm.DB) {
 				Type:        "Behavior - Internal",
 				Severity:    "high",
 				HitCount:    19,
-				LastHitTime: "2025/12/06 15:52:25",
+				LastHitTime: seedTimePtr("2025-12-06 15:52:25"),
 				Creator:     "admin",
 				Status:      "active",
-				UpdateTime:  "2025/11/18 01:47:28",
+				UpdateTime:  seedTime("2025-11-18 01:47:28"),
 				Updater:     "admin",
 			},
 			{
@@ -332,10 +564,10 @@ func seedData(db *gorm.DB) {
 				Type:        "Web - External",
 				Severity:    "medium",
 				HitCount:    45,
-				LastHitTime: "2025/12/06 16:00:00",
+				LastHitTime: seedTimePtr("2025-12-06 16:00:00"),
 				Creator:     "admin",
 				Status:      "active",
-				UpdateTime:  "2025/11/20 10:00:00",
+				UpdateTime:  seedTime("2025-11-20 10:00:00"),
 				Updater:     "admin",
 			},
 		}
@@ -419,19 +651,18 @@ func seedData(db *gorm.DB) {
 				Type:       "blacklist",
 				Reason:     "Repeated SSH failures",
 				Source:     "PRTS",
-				ExpireTime: "2025-12-07 10:00:00",
-				AddTime:    "2025-12-06 10:00:00",
+				ExpireTime: seedTimePtr("2025-12-07 10:00:00"),
+				AddTime:    seedTime("2025-12-06 10:00:00"),
 				Status:     "active",
 			},
 			{
-				ID:         "AC-003",
-				IP:         "45.148.10.247",
-				Type:       "blacklist",
-				Reason:     "Known malicious actor",
-				Source:     "PRTS",
-				ExpireTime: "",
-				AddTime:    "2025-12-05 15:30:00",
-				Status:     "active",
+				ID:      "AC-003",
+				IP:      "45.148.10.247",
+				Type:    "blacklist",
+				Reason:  "Known malicious actor",
+				Source:  "PRTS",
+				AddTime: seedTime("2025-12-05 15:30:00"),
+				Status:  "active",
 			},
 		}
 		db.Create(&rules)
@@ -441,9 +672,9 @@ func seedData(db *gorm.DB) {
 	db.Model(&model.LoginLog{}).Count(&count)
 	if count == 0 {
 		logs := []model.LoginLog{
-			{Username: "admin", Time: "2025-12-06 10:23:45", IP: "192.168.1.50", Status: "success", Device: "Chrome 118 / Windows 10"},
-			{Username: "unknown", Time: "2025-12-05 18:12:11", IP: "192.168.1.55", Status: "failure", Device: "Firefox 115 / Linux"},
-			{Username: "operator01", Time: "2025-12-05 09:30:00", IP: "10.0.0.5", Status: "success", Device: "Safari / macOS"},
+			{Username: "admin", Time: seedTime("2025-12-06 10:23:45"), IP: "192.168.1.50", Status: "success", Device: "Chrome 118 / Windows 10"},
+			{Username: "unknown", Time: seedTime("2025-12-05 18:12:11"), IP: "192.168.1.55", Status: "failure", Device: "Firefox 115 / Linux"},
+			{Username: "operator01", Time: seedTime("2025-12-05 09:30:00"), IP: "10.0.0.5", Status: "success", Device: "Safari / macOS"},
 		}
 		db.Create(&logs)
 	}
@@ -452,8 +683,8 @@ func seedData(db *gorm.DB) {
 	db.Model(&model.Report{}).Count(&count)
 	if count == 0 {
 		reports := []model.Report{
-			{ID: "RPT-001", Name: "Daily Security Summary", Module: "Threat Perception", Type: "daily", Size: "1.2 MB", Status: "success", Creator: "SYSTEM", CreateTime: "2025-12-06 00:00:00"},
-			{ID: "RPT-002", Name: "Weekly Attack Analysis", Module: "All Modules", Type: "weekly", Size: "4.5 MB", Status: "success", Creator: "admin", CreateTime: "2025-12-01 08:00:00"},
+			{ID: "RPT-001", Name: "Daily Security Summary", Module: "Threat Perception", Type: "daily", Size: "1.2 MB", Status: "success", Creator: "SYSTEM", CreateTime: seedTime("2025-12-06 00:00:00")},
+			{ID: "RPT-002", Name: "Weekly Attack Analysis", Module: "All Modules", Type: "weekly", Size: "4.5 MB", Status: "success", Creator: "admin", CreateTime: seedTime("2025-12-01 08:00:00")},
 		}
 		db.Create(&reports)
 	}
@@ -1,16 +1,26 @@
+// simulator posts random attacks to the ingest API. It needs an ingest key, sent as a
+// bearer token or, with -sign, used to sign each request.
 package main
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"log"
 	"math/rand"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type AttackLog struct {
-	ID        string    `json:"id"`
 	Timestamp time.Time `json:"timestamp"`
 	SourceIP  string    `json:"sourceIp"`
 	Location  string    `json:"location"`
@@ -25,15 +35,23 @@ var locations = []string{"Shanghai, CN", "Moscow, RU", "California, US", "London
 var severities = []string{"low", "medium", "high", "critical"}
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
-	url := "http://localhost:8080/api/v1/ingest"
+	target := flag.String("url", "http://localhost:8080/api/v1/ingest", "ingest endpoint")
+	key := flag.String("key", os.Getenv("PRTS_INGEST_KEY"), "ingest key, prts_<id>_<secret>")
+	sign := flag.Bool("sign", false, "sign requests with the key instead of sending it")
+	flag.Parse()
+	if *key == "" {
+		log.Fatal("an ingest key is required, pass -key or set PRTS_INGEST_KEY")
+	}
+	u, err := url.Parse(*target)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	fmt.Println("PRTS Attack Simulator Started...")
-	fmt.Printf("Target: %s\n", url)
+	fmt.Printf("Target: %s\n", *target)
 
 	for {
 		attack := AttackLog{
-			ID:        fmt.Sprintf("SIM-%d", rand.Intn(9999)),
 			Timestamp: time.Now(),
 			SourceIP:  fmt.Sprintf("%d.%d.%d.%d", rand.Intn(255), rand.Intn(255), rand.Intn(255), rand.Intn(255)),
 			Location:  locations[rand.Intn(len(locations))],
@@ -44,15 +62,43 @@ func main() {
 		}
 
 		jsonData, _ := json.Marshal(attack)
-		resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+		req, _ := http.NewRequest(http.MethodPost, *target, bytes.NewReader(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		if *sign {
+			signRequest(req, *key, u.RequestURI(), jsonData)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+*key)
+		}
+		resp, err := http.DefaultClient.Do(req)
 
 		if err != nil {
 			fmt.Printf("Error sending attack: %v\n", err)
 		} else {
-			fmt.Printf("Sent attack: %s from %s [%s]\n", attack.ID, attack.SourceIP, attack.Method)
+			var res struct {
+				ID    string `json:"id"`
+				Error string `json:"error"`
+			}
+			json.NewDecoder(resp.Body).Decode(&res)
 			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				fmt.Printf("Attack refused: %s %s\n", resp.Status, res.Error)
+			} else {
+				fmt.Printf("Sent attack: %s from %s [%s]\n", res.ID, attack.SourceIP, attack.Method)
+			}
 		}
 
 		time.Sleep(time.Duration(rand.Intn(5)+2) * time.Second)
 	}
 }
+
+// signRequest sets the headers of a signed ingest request, see api.IngestSignature
+func signRequest(req *http.Request, key, requestURI string, body []byte) {
+	keyID, _, _ := strings.Cut(strings.TrimPrefix(key, "prts_"), "_")
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	digest := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s", timestamp, req.Method, requestURI, hex.EncodeToString(digest[:]))
+	req.Header.Set("X-Prts-Key-Id", keyID)
+	req.Header.Set("X-Prts-Timestamp", timestamp)
+	req.Header.Set("X-Prts-Signature", hex.EncodeToString(mac.Sum(nil)))
+}
@@ -0,0 +1,168 @@
+// syslog-listen is a local syslog collector for trying out SIEM forwarding. It prints
+// every message it receives over UDP, TCP or TLS, one per line, and tells octet-counted
+// from newline framed streams itself. Without -cert it serves TLS with a throwaway
+// self-signed certificate, so the destination needs insecureSkipVerify.
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var out = struct {
+	sync.Mutex
+	w *bufio.Writer
+}{w: bufio.NewWriter(os.Stdout)}
+
+func main() {
+	udpAddr := flag.String("udp", ":5514", "UDP address, empty to disable")
+	tcpAddr := flag.String("tcp", ":5514", "TCP address, empty to disable")
+	tlsAddr := flag.String("tls", ":6514", "TLS address, empty to disable")
+	certFile := flag.String("cert", "", "TLS certificate, PEM")
+	keyFile := flag.String("key", "", "TLS key, PEM")
+	flag.Parse()
+
+	if *udpAddr != "" {
+		conn, err := net.ListenPacket("udp", *udpAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Listening on udp %s", *udpAddr)
+		go serveUDP(conn)
+	}
+	if *tcpAddr != "" {
+		ln, err := net.Listen("tcp", *tcpAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Listening on tcp %s", *tcpAddr)
+		go serveStream(ln, "tcp")
+	}
+	if *tlsAddr != "" {
+		cert, err := loadCertificate(*certFile, *keyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ln, err := tls.Listen("tcp", *tlsAddr, &tls.Config{Certificates: []tls.Certificate{cert}})
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Listening on tls %s", *tlsAddr)
+		go serveStream(ln, "tls")
+	}
+	select {}
+}
+
+func emit(proto, peer string, msg []byte) {
+	out.Lock()
+	defer out.Unlock()
+	fmt.Fprintf(out.w, "%s %s %s\n", proto, peer, msg)
+	out.w.Flush()
+}
+
+func serveUDP(conn net.PacketConn) {
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			log.Printf("udp: %v", err)
+			continue
+		}
+		emit("udp", addr.String(), buf[:n])
+	}
+}
+
+func serveStream(ln net.Listener, proto string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Printf("%s: %v", proto, err)
+			continue
+		}
+		go readStream(conn, proto)
+	}
+}
+
+// readStream splits a stream into messages. RFC 6587: a frame starting with a digit is
+// octet counted, anything else runs to the next newline.
+func readStream(conn net.Conn, proto string) {
+	defer conn.Close()
+	peer := conn.RemoteAddr().String()
+	r := bufio.NewReader(conn)
+	for {
+		first, err := r.Peek(1)
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("%s %s: %v", proto, peer, err)
+			}
+			return
+		}
+		if first[0] >= '0' && first[0] <= '9' {
+			length, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(length[:len(length)-1])
+			if err != nil || n <= 0 || n > 1<<20 {
+				log.Printf("%s %s: bad frame length %q", proto, peer, length)
+				return
+			}
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			emit(proto, peer, msg)
+			continue
+		}
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			line = line[:len(line)-1]
+		}
+		if len(line) > 0 {
+			emit(proto, peer, line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func loadCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	if certFile != "" {
+		return tls.LoadX509KeyPair(certFile, keyFile)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "syslog-listen"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
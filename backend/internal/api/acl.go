@@ -0,0 +1,292 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// addrRange is the inclusive span of addresses an access rule matches
+type addrRange struct {
+	start, end netip.Addr
+}
+
+func (r addrRange) contains(o addrRange) bool {
+	return r.start.Compare(o.start) <= 0 && r.end.Compare(o.end) >= 0
+}
+
+func (r addrRange) overlaps(o addrRange) bool {
+	return r.start.BitLen() == o.start.BitLen() && r.start.Compare(o.end) <= 0 && o.start.Compare(r.end) <= 0
+}
+
+// prefixLast returns the highest address inside a prefix
+func prefixLast(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	for bit := p.Bits(); bit < len(b)*8; bit++ {
+		b[bit/8] |= 0x80 >> (bit % 8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// parseRuleAddress accepts an address, a CIDR block or a start-end range and returns the
+// canonical spelling with the span it covers
+func parseRuleAddress(s string) (string, addrRange, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return "", addrRange{}, errors.New("address is required")
+
+	case strings.Contains(s, "/"):
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return "", addrRange{}, fmt.Errorf("invalid CIDR %q", s)
+		}
+		if p.Addr().Is4In6() {
+			return "", addrRange{}, fmt.Errorf("use the IPv4 form of %q", s)
+		}
+		p = p.Masked()
+		r := addrRange{p.Addr(), prefixLast(p)}
+		if p.IsSingleIP() {
+			return p.Addr().String(), r, nil
+		}
+		return p.String(), r, nil
+
+	case strings.Contains(s, "-"):
+		parts := strings.SplitN(s, "-", 2)
+		start, err1 := netip.ParseAddr(strings.TrimSpace(parts[0]))
+		end, err2 := netip.ParseAddr(strings.TrimSpace(parts[1]))
+		if err1 != nil || err2 != nil {
+			return "", addrRange{}, fmt.Errorf("invalid range %q", s)
+		}
+		start, end = start.Unmap(), end.Unmap()
+		if start.Is4() != end.Is4() {
+			return "", addrRange{}, fmt.Errorf("range %q mixes IPv4 and IPv6", s)
+		}
+		if start.Compare(end) > 0 {
+			return "", addrRange{}, fmt.Errorf("range %q ends before it starts", s)
+		}
+		if start == end {
+			return start.String(), addrRange{start, end}, nil
+		}
+		return start.String() + "-" + end.String(), addrRange{start, end}, nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return "", addrRange{}, fmt.Errorf("invalid IP address %q", s)
+	}
+	addr = addr.Unmap().WithZone("")
+	return addr.String(), addrRange{addr, addr}, nil
+}
+
+// parsePorts turns "443, 80,8000-8100" into sorted port spans
+func parsePorts(s string) ([][2]int, error) {
+	var spans [][2]int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			lo, hi = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		}
+		a, err1 := strconv.Atoi(lo)
+		b, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || a < 1 || b > 65535 || a > b {
+			return nil, fmt.Errorf("invalid port %q", part)
+		}
+		spans = append(spans, [2]int{a, b})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	return spans, nil
+}
+
+func formatPorts(spans [][2]int) string {
+	parts := make([]string, 0, len(spans))
+	for _, sp := range spans {
+		if sp[0] == sp[1] {
+			parts = append(parts, strconv.Itoa(sp[0]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sp[0], sp[1]))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// normalizeAccessRule validates a rule and rewrites its fields to their canonical form
+func normalizeAccessRule(rule *model.AccessControlRule) error {
+	ip, _, err := parseRuleAddress(rule.IP)
+	if err != nil {
+		return err
+	}
+	rule.IP = ip
+
+	switch rule.Type {
+	case "blacklist", "whitelist":
+	default:
+		return fmt.Errorf("type must be blacklist or whitelist")
+	}
+
+	rule.Protocol = strings.ToLower(strings.TrimSpace(rule.Protocol))
+	switch rule.Protocol {
+	case "", "any", "all":
+		rule.Protocol = "any"
+	case "tcp", "udp", "icmp":
+	default:
+		return fmt.Errorf("protocol must be any, tcp, udp or icmp")
+	}
+
+	spans, err := parsePorts(rule.Ports)
+	if err != nil {
+		return err
+	}
+	if len(spans) > 0 && rule.Protocol != "tcp" && rule.Protocol != "udp" {
+		return errors.New("ports require protocol tcp or udp")
+	}
+	rule.Ports = formatPorts(spans)
+
+	rule.Direction = strings.ToLower(strings.TrimSpace(rule.Direction))
+	switch rule.Direction {
+	case "":
+		rule.Direction = "inbound"
+	case "inbound", "outbound", "both":
+	default:
+		return fmt.Errorf("direction must be inbound, outbound or both")
+	}
+	return normalizeRuleTargets(rule)
+}
+
+// scopesIntersect reports whether two rules can match the same packet apart from the address
+func scopesIntersect(a, b model.AccessControlRule) bool {
+	dirA, dirB := a.Direction, b.Direction
+	if dirA == "" {
+		dirA = "inbound"
+	}
+	if dirB == "" {
+		dirB = "inbound"
+	}
+	if dirA != "both" && dirB != "both" && dirA != dirB {
+		return false
+	}
+
+	protoA, protoB := a.Protocol, b.Protocol
+	if protoA != "" && protoA != "any" && protoB != "" && protoB != "any" && protoA != protoB {
+		return false
+	}
+
+	portsA, _ := parsePorts(a.Ports)
+	portsB, _ := parsePorts(b.Ports)
+	if len(portsA) == 0 || len(portsB) == 0 {
+		return true
+	}
+	for _, x := range portsA {
+		for _, y := range portsB {
+			if x[0] <= y[1] && y[0] <= x[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type ruleOverlap struct {
+	Rule     model.AccessControlRule `json:"rule"`
+	Relation string                  `json:"relation"` // duplicate, contains, inside, overlaps
+	Conflict bool                    `json:"conflict"` // Opposite type, e.g. a whitelist inside a blacklisted block
+}
+
+// findRuleOverlaps compares a normalized rule with the active rules
+func (h *Handler) findRuleOverlaps(rule model.AccessControlRule) []ruleOverlap {
+	_, span, err := parseRuleAddress(rule.IP)
+	if err != nil {
+		return nil
+	}
+
+	var existing []model.AccessControlRule
+	h.DB.Where("status = ? AND id <> ?", "active", rule.ID).Find(&existing)
+	var nodes []model.NodeStatus
+	h.DB.Select("id", "groups").Find(&nodes)
+
+	overlaps := []ruleOverlap{}
+	for _, other := range existing {
+		_, otherSpan, err := parseRuleAddress(other.IP)
+		if err != nil || !span.overlaps(otherSpan) || !scopesIntersect(rule, other) || !ruleTargetsIntersect(rule, other, nodes) {
+			continue
+		}
+		relation := "overlaps"
+		switch {
+		case span == otherSpan:
+			relation = "duplicate"
+		case otherSpan.contains(span):
+			relation = "inside"
+		case span.contains(otherSpan):
+			relation = "contains"
+		}
+		overlaps = append(overlaps, ruleOverlap{Rule: other, Relation: relation, Conflict: other.Type != rule.Type})
+	}
+	return overlaps
+}
+
+// ValidateAccessRule normalizes a rule without saving it and lists the rules it overlaps
+func (h *Handler) ValidateAccessRule(c *gin.Context) {
+	var rule model.AccessControlRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeAccessRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rule": rule, "overlaps": h.findRuleOverlaps(rule)})
+}
+
+// LookupAccessRules answers which rules affect an address, on one node when node is
+// given. Whitelist entries are evaluated before the blacklist on every backend, so any
+// match there wins.
+func (h *Handler) LookupAccessRules(c *gin.Context) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(c.Query("ip")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ip must be a single IPv4 or IPv6 address"})
+		return
+	}
+	addr = addr.Unmap().WithZone("")
+	target := addrRange{addr, addr}
+
+	var rules []model.AccessControlRule
+	h.DB.Where("status = ?", "active").Find(&rules)
+	if nodeID := c.Query("node"); nodeID != "" {
+		rules = rulesForNode(rules, h.loadTargetNode(nodeID))
+	}
+
+	matched := []model.AccessControlRule{}
+	verdict := "none"
+	scoped := false
+	for _, rule := range rules {
+		_, span, err := parseRuleAddress(rule.IP)
+		if err != nil || !span.contains(target) {
+			continue
+		}
+		matched = append(matched, rule)
+		if rule.Ports != "" || (rule.Protocol != "" && rule.Protocol != "any") || rule.Direction == "outbound" {
+			scoped = true
+		}
+		if rule.Type == "whitelist" {
+			verdict = "allowed"
+		} else if verdict == "none" {
+			verdict = "blocked"
+		}
+	}
+	// A verdict from scoped rules only holds for the protocols and ports they name
+	c.JSON(http.StatusOK, gin.H{"ip": addr.String(), "verdict": verdict, "partial": scoped, "rules": matched})
+}
@@ -0,0 +1,852 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/ingest"
+	"backend/internal/model"
+	"backend/internal/ulid"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// adapterMaxErrors is how many failures a response or preview spells out
+	adapterMaxErrors = 20
+	// adapterPreviewEvents is how many events of a sample a preview maps
+	adapterPreviewEvents = 100
+
+	ingestTailInterval = time.Second
+	// ingestTailBatch bounds the lines of one poll, so progress is saved along the way
+	ingestTailBatch = 5000
+
+	// scanMergeWindow is how long after its first probe a scan takes further probes from
+	// the same source seen by the same sensor
+	scanMergeWindow = 10 * time.Minute
+	// scanMaxPorts bounds the ports listed on a scan, the count keeps going
+	scanMaxPorts = 64
+)
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// adapterCache holds compiled adapters by name, stored ones before built-ins, until an
+// adapter is changed
+var adapterCache = struct {
+	sync.RWMutex
+	m map[string]*ingest.Adapter
+}{m: map[string]*ingest.Adapter{}}
+
+func resetAdapterCache() {
+	adapterCache.Lock()
+	adapterCache.m = map[string]*ingest.Adapter{}
+	adapterCache.Unlock()
+}
+
+// ingestAdapter returns the adapter reached under a name
+func (h *Handler) ingestAdapter(name string) (*ingest.Adapter, error) {
+	adapterCache.RLock()
+	a := adapterCache.m[name]
+	adapterCache.RUnlock()
+	if a != nil {
+		return a, nil
+	}
+	var stored model.IngestAdapter
+	if h.DB.Where("name = ?", name).Limit(1).Find(&stored).RowsAffected > 0 {
+		var err error
+		if a, err = ingest.ParseSpec([]byte(stored.Spec)); err != nil {
+			return nil, fmt.Errorf("adapter %s: %w", name, err)
+		}
+	} else if builtin, ok := ingest.Builtin(name); ok {
+		a = builtin
+	} else {
+		return nil, fmt.Errorf("unknown adapter %s", name)
+	}
+	adapterCache.Lock()
+	adapterCache.m[name] = a
+	adapterCache.Unlock()
+	return a, nil
+}
+
+// adapterOutcome tells what became of the events of one batch
+type adapterOutcome struct {
+	Status  string         `json:"status"`
+	Events  int            `json:"events"`
+	Records map[string]int `json:"records"` // By record kind
+	Skipped int            `json:"skipped"` // No rule applies, such as Suricata flow events
+	Failed  int            `json:"failed"`
+	Errors  []string       `json:"errors,omitempty"` // The first ones
+	Error   string         `json:"error,omitempty"`  // The batch could not be read to its end
+}
+
+func newAdapterOutcome() *adapterOutcome {
+	return &adapterOutcome{Status: "success", Records: map[string]int{}}
+}
+
+func (o *adapterOutcome) fail(where string, err error) {
+	o.Failed++
+	if len(o.Errors) < adapterMaxErrors {
+		o.Errors = append(o.Errors, where+": "+err.Error())
+	}
+}
+
+func (o *adapterOutcome) records() int {
+	n := 0
+	for _, count := range o.Records {
+		n += count
+	}
+	return n
+}
+
+// ingestEvent maps an event and stores its records, it returns the first failure. sensor
+// names the sensor when the event does not, or whatever the event says with pinSensor.
+func (h *Handler) ingestEvent(a *ingest.Adapter, sensor string, pinSensor bool, ev ingest.Event, out *adapterOutcome, where string) error {
+	out.Events++
+	records, failure := a.Map(ev)
+	if failure != nil {
+		out.fail(where, failure)
+	}
+	for _, rec := range records {
+		recSensor := sensor
+		if rec.Sensor != "" && !pinSensor {
+			recSensor = rec.Sensor
+		}
+		if err := h.storeAdapterRecord(a.Name(), recSensor, rec); err != nil {
+			if failure == nil {
+				out.fail(where, err)
+				failure = err
+			}
+			continue
+		}
+		out.Records[rec.Kind]++
+		adapterRecords.Inc(a.Name(), rec.Kind)
+	}
+	switch {
+	case failure != nil:
+		adapterEvents.Inc(a.Name(), "failed")
+	case len(records) == 0:
+		out.Skipped++
+		adapterEvents.Inc(a.Name(), "skipped")
+	default:
+		adapterEvents.Inc(a.Name(), "mapped")
+	}
+	return failure
+}
+
+func (h *Handler) ingestDecodeError(a *ingest.Adapter, err error, out *adapterOutcome, where string) {
+	out.Events++
+	out.fail(where, err)
+	adapterEvents.Inc(a.Name(), "failed")
+}
+
+// storeAdapterRecord saves a mapped record like its native ingest endpoint would, tagged
+// with adapter/sensor
+func (h *Handler) storeAdapterRecord(adapter, sensor string, rec ingest.Record) error {
+	tag := adapter
+	if sensor = strings.TrimSpace(sensor); sensor != "" {
+		tag += "/" + truncateRunes(sensor, 64)
+	}
+	at := h.Now()
+	if rec.Time != nil && rec.Time.Before(at) {
+		// A sensor clock ahead of ours would put events in the future
+		at = *rec.Time
+	}
+	f := rec.Fields
+
+	switch rec.Kind {
+	case "attack":
+		ip, err := adapterIP("sourceIp", f["sourceIp"])
+		if err != nil {
+			return err
+		}
+		severity := strings.ToLower(f["severity"])
+		if !slices.Contains([]string{"low", "medium", "high", "critical"}, severity) {
+			severity = "low"
+		}
+		attack := model.AttackLog{
+			ID: ulid.New(), Timestamp: at, SourceIP: ip,
+			Location: f["location"], Method: f["method"], Payload: f["payload"],
+			Severity: severity, Status: valueOr(strings.ToLower(f["status"]), "monitored"), Sensor: tag,
+		}
+		return h.recordAttack(attack)
+	case "credential":
+		ip, err := adapterIP("ip", f["ip"])
+		if err != nil {
+			return err
+		}
+		_, err = h.RecordCredential(model.AccountCredential{
+			Service: f["service"], Username: f["username"], Password: f["password"], IP: ip, Time: at, Sensor: tag,
+		})
+		return err
+	case "scan":
+		ip, err := adapterIP("ip", f["ip"])
+		if err != nil {
+			return err
+		}
+		count, err := strconv.Atoi(f["count"])
+		if err != nil || count < 1 {
+			count = 1
+		}
+		return h.recordScan(model.ScanLog{
+			IP: ip, Threat: valueOr(f["threat"], "Low"), Location: f["location"], Type: strings.ToUpper(f["type"]),
+			Count: count, Ports: mergeScanPorts("", f["ports"]), Start: at, Duration: f["duration"], Sensor: tag,
+		})
+	case "sample":
+		hash := strings.ToLower(f["sha256"])
+		if !sha256Pattern.MatchString(hash) {
+			return fmt.Errorf("sha256 %q is not a SHA-256 hex digest", truncateRunes(f["sha256"], 80))
+		}
+		var ip string
+		if f["attackerIp"] != "" {
+			var err error
+			if ip, err = adapterIP("attackerIp", f["attackerIp"]); err != nil {
+				return err
+			}
+		}
+		size := f["fileSize"]
+		if n, err := strconv.ParseInt(size, 10, 64); err == nil {
+			size = formatFileSize(n)
+		}
+		fileType := f["fileType"]
+		if fileType == "" {
+			fileType = strings.ToUpper(strings.TrimPrefix(filepath.Ext(f["fileName"]), "."))
+		}
+		return h.recordSample(model.SampleLog{
+			FileName: valueOr(f["fileName"], hash[:12]), FileSize: size, FileType: fileType,
+			ThreatLevel: valueOr(strings.ToLower(f["threatLevel"]), "unknown"), Status: valueOr(strings.ToLower(f["status"]), "queued"),
+			LastTime: at, AttackerIP: ip, SHA256: hash, Sensor: tag,
+		})
+	}
+	return fmt.Errorf("unknown record %s", rec.Kind)
+}
+
+func adapterIP(field, s string) (string, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return "", fmt.Errorf("%s %q is not an IP address", field, truncateRunes(s, 80))
+	}
+	return addr.Unmap().String(), nil
+}
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+func formatFileSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// recordScan stores a scan, or adds its probes to the open scan of the same source seen
+// by the same sensor. Only new scans are exported, their growth stays in the console.
+func (h *Handler) recordScan(scan model.ScanLog) error {
+	var open model.ScanLog
+	res := h.DB.Where("ip = ? AND sensor = ? AND type = ? AND start BETWEEN ? AND ?",
+		scan.IP, scan.Sensor, scan.Type, scan.Start.Add(-scanMergeWindow), scan.Start).
+		Order("start desc").Limit(1).Find(&open)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		updates := map[string]interface{}{
+			"count": gorm.Expr("count + ?", scan.Count),
+			"ports": mergeScanPorts(open.Ports, scan.Ports),
+		}
+		if d := scan.Start.Sub(open.Start); d > 0 {
+			updates["duration"] = formatScanDuration(d)
+		}
+		if scanThreatRank(scan.Threat) > scanThreatRank(open.Threat) {
+			updates["threat"] = scan.Threat
+		}
+		return h.DB.Model(&open).Updates(updates).Error
+	}
+	scan.ID = ulid.New()
+	if scan.Duration == "" {
+		scan.Duration = "0s"
+	}
+	if err := h.DB.Create(&scan).Error; err != nil {
+		return err
+	}
+	h.exportEvent(scanSiemEvent(scan))
+	return nil
+}
+
+var scanThreats = []string{"low", "suspicious", "high risk", "malicious"}
+
+func scanThreatRank(threat string) int {
+	return slices.Index(scanThreats, strings.ToLower(threat))
+}
+
+// mergeScanPorts joins port lists the way scans show them, "22, 80, 443"
+func mergeScanPorts(existing, more string) string {
+	var ports []string
+	for _, p := range strings.Split(existing+","+more, ",") {
+		if p = strings.TrimSpace(p); p != "" && !slices.Contains(ports, p) {
+			ports = append(ports, p)
+		}
+	}
+	slices.SortStableFunc(ports, func(a, b string) int {
+		x, errA := strconv.Atoi(a)
+		y, errB := strconv.Atoi(b)
+		if errA != nil || errB != nil {
+			return strings.Compare(a, b)
+		}
+		return x - y
+	})
+	if len(ports) > scanMaxPorts {
+		ports = ports[:scanMaxPorts]
+	}
+	return strings.Join(ports, ", ")
+}
+
+func formatScanDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", d/time.Hour, d%time.Hour/time.Minute)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm %ds", d/time.Minute, d%time.Minute/time.Second)
+	}
+	return fmt.Sprintf("%ds", d/time.Second)
+}
+
+// recordSample stores a captured file, captures of a known hash count up on its sample
+func (h *Handler) recordSample(sample model.SampleLog) error {
+	var known model.SampleLog
+	res := h.DB.Where("sha256 = ?", sample.SHA256).Limit(1).Find(&known)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		known.CaptureCount++
+		updates := map[string]interface{}{"capture_count": gorm.Expr("capture_count + 1"), "sensor": sample.Sensor}
+		if sample.LastTime.After(known.LastTime) {
+			known.LastTime, known.AttackerIP = sample.LastTime, valueOr(sample.AttackerIP, known.AttackerIP)
+			updates["last_time"], updates["attacker_ip"] = known.LastTime, known.AttackerIP
+		}
+		known.Sensor = sample.Sensor
+		if err := h.DB.Model(&known).Updates(updates).Error; err != nil {
+			return err
+		}
+		h.touchSearchDocument("sample", known.ID, known.LastTime)
+		h.exportEvent(sampleSiemEvent(known))
+		return nil
+	}
+	sample.ID = ulid.New()
+	sample.CaptureCount = 1
+	if err := h.DB.Create(&sample).Error; err != nil {
+		return err
+	}
+	h.indexSearchDocument(sampleSearchDocument(sample))
+	h.exportEvent(sampleSiemEvent(sample))
+	return nil
+}
+
+// IngestAdapterLogs maps a batch of another sensor's log through an adapter: JSON lines, a
+// JSON array or Zeek's tab separated format. Records are tagged with the sensor of the
+// ingest key, path names the Zeek log when the batch has no #path header. Each event is
+// charged to the key's rate limit, a batch that runs out is stored up to there.
+func (h *Handler) IngestAdapterLogs(c *gin.Context) {
+	key := c.MustGet(ingestKeyContext).(model.IngestKey)
+	a, err := h.ingestAdapter(c.Param("adapter"))
+	if err != nil {
+		ingestFailures.Inc("invalid")
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if sensor := c.Query("sensor"); sensor != "" && sensor != key.Sensor {
+		ingestFailures.Inc("invalid")
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("The key is scoped to sensor %s", key.Sensor)})
+		return
+	}
+
+	endpoint := "logs/" + a.Name()
+	out := newAdapterOutcome()
+	var wait time.Duration
+	dec := ingest.NewDecoder(c.Query("path"))
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, ingestMaxBody)
+	err = dec.Each(body, func(line int, raw string, ev ingest.Event, err error) bool {
+		var ok bool
+		if ok, wait = takeIngestTokens(key, 1); !ok {
+			return false
+		}
+		where := fmt.Sprintf("line %d", line)
+		if err != nil {
+			h.ingestDecodeError(a, err, out, where)
+		} else {
+			err = h.ingestEvent(a, key.Sensor, true, ev, out, where)
+		}
+		if err != nil {
+			h.rejectIngest(c, key, endpoint, line, raw, err)
+		}
+		return true
+	})
+	h.noteIngestKeyUse(c, key, out.Events-out.Failed, out.Failed, wait > 0)
+	if out.Failed > 0 {
+		ingestFailures.Add(float64(out.Failed), "adapter")
+	}
+	switch {
+	case err != nil:
+		// What was read before the error is stored, the sender learns how far it got
+		ingestFailures.Inc("invalid")
+		h.rejectIngest(c, key, endpoint, 0, "", err)
+		out.Status, out.Error = "error", err.Error()
+		c.JSON(http.StatusBadRequest, out)
+	case wait > 0:
+		ingestFailures.Inc("rate_limited")
+		out.Status, out.Error = "throttled", fmt.Sprintf("Rate limit of the key reached after %d events", out.Events)
+		c.Header("Retry-After", retryAfter(wait))
+		c.JSON(http.StatusTooManyRequests, out)
+	default:
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// ingestAdapterView is a built-in or stored adapter as the console lists it
+type ingestAdapterView struct {
+	ID          string          `json:"id,omitempty"` // Stored adapters only
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Builtin     bool            `json:"builtin"`   // Shipped with the server
+	Overrides   bool            `json:"overrides"` // Stored under the name of a built-in, which it replaces
+	Rules       int             `json:"rules"`
+	Spec        json.RawMessage `json:"spec"`
+	UpdatedAt   *time.Time      `json:"updatedAt"`
+}
+
+func (h *Handler) GetIngestAdapters(c *gin.Context) {
+	var stored []model.IngestAdapter
+	h.DB.Order("name").Find(&stored)
+	byName := map[string]model.IngestAdapter{}
+	for _, s := range stored {
+		byName[s.Name] = s
+	}
+
+	var views []ingestAdapterView
+	for _, a := range ingest.Builtins() {
+		if s, ok := byName[a.Name()]; ok {
+			views = append(views, storedAdapterView(s, true))
+			delete(byName, a.Name())
+			continue
+		}
+		spec, _ := json.Marshal(a.Spec)
+		views = append(views, ingestAdapterView{
+			Name: a.Name(), Description: a.Spec.Description, Builtin: true, Rules: len(a.Spec.Rules), Spec: spec,
+		})
+	}
+	for _, s := range stored {
+		if _, ok := byName[s.Name]; ok {
+			views = append(views, storedAdapterView(s, false))
+		}
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func storedAdapterView(s model.IngestAdapter, builtin bool) ingestAdapterView {
+	v := ingestAdapterView{ID: s.ID, Name: s.Name, Builtin: builtin, Overrides: builtin, Spec: json.RawMessage(s.Spec), UpdatedAt: &s.UpdatedAt}
+	var spec ingest.Spec
+	if json.Unmarshal([]byte(s.Spec), &spec) == nil {
+		v.Description, v.Rules = spec.Description, len(spec.Rules)
+	}
+	return v
+}
+
+// readAdapterSpec takes the spec from the request body and checks that it compiles. The
+// stored JSON is the compact form of the body.
+func readAdapterSpec(c *gin.Context) (*ingest.Adapter, string, error) {
+	data, err := c.GetRawData()
+	if err != nil {
+		return nil, "", err
+	}
+	a, err := ingest.ParseSpec(data)
+	if err != nil {
+		return nil, "", err
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, "", err
+	}
+	return a, compact.String(), nil
+}
+
+// CreateIngestAdapter stores an adapter from its spec, using the name of a built-in
+// replaces that one
+func (h *Handler) CreateIngestAdapter(c *gin.Context) {
+	a, spec, err := readAdapterSpec(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	stored := model.IngestAdapter{
+		ID: fmt.Sprintf("IA-%d", time.Now().UnixNano()), Name: a.Name(), Spec: spec,
+		CreatedAt: h.Now(), UpdatedAt: h.Now(),
+	}
+	if err := h.DB.Create(&stored).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name is already used by another adapter"})
+		return
+	}
+	resetAdapterCache()
+	_, builtin := ingest.Builtin(stored.Name)
+	c.JSON(http.StatusOK, storedAdapterView(stored, builtin))
+}
+
+func (h *Handler) UpdateIngestAdapter(c *gin.Context) {
+	var stored model.IngestAdapter
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&stored).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Adapter not found"})
+		return
+	}
+	a, spec, err := readAdapterSpec(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	stored.Name, stored.Spec, stored.UpdatedAt = a.Name(), spec, h.Now()
+	if err := h.DB.Save(&stored).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name is already used by another adapter"})
+		return
+	}
+	resetAdapterCache()
+	_, builtin := ingest.Builtin(stored.Name)
+	c.JSON(http.StatusOK, storedAdapterView(stored, builtin))
+}
+
+// DeleteIngestAdapter removes a stored adapter, the built-in of its name comes back
+func (h *Handler) DeleteIngestAdapter(c *gin.Context) {
+	res := h.DB.Delete(&model.IngestAdapter{}, "id = ?", c.Param("id"))
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete adapter"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Adapter not found"})
+		return
+	}
+	resetAdapterCache()
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// adapterPreview is one event of a sample and what the adapter made of it
+type adapterPreview struct {
+	Line    int             `json:"line"`
+	Event   ingest.Event    `json:"event,omitempty"`
+	Records []ingest.Record `json:"records"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// PreviewIngestAdapter maps sample log lines without storing anything, through the spec
+// in the request or else the adapter of that name
+func (h *Handler) PreviewIngestAdapter(c *gin.Context) {
+	var req struct {
+		Adapter string          `json:"adapter"`
+		Spec    json.RawMessage `json:"spec"`
+		Sample  string          `json:"sample" binding:"required"`
+		Path    string          `json:"path"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var a *ingest.Adapter
+	var err error
+	if len(req.Spec) > 0 && string(req.Spec) != "null" {
+		a, err = ingest.ParseSpec(req.Spec)
+	} else {
+		a, err = h.ingestAdapter(req.Adapter)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	previews := []adapterPreview{}
+	err = ingest.NewDecoder(req.Path).Each(strings.NewReader(req.Sample), func(line int, _ string, ev ingest.Event, err error) bool {
+		p := adapterPreview{Line: line, Event: ev, Records: []ingest.Record{}}
+		if err == nil {
+			var records []ingest.Record
+			records, err = a.Map(ev)
+			p.Records = append(p.Records, records...)
+		}
+		if err != nil {
+			p.Error = err.Error()
+		}
+		previews = append(previews, p)
+		return len(previews) < adapterPreviewEvents
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, previews)
+}
+
+// ingestTail follows one file, its progress and errors go straight to its row
+type ingestTail struct {
+	tail model.IngestTail
+	stop chan struct{}
+	done chan struct{}
+}
+
+// ingestTails are the running tails by ID, disabled tails have none
+var ingestTails = struct {
+	sync.RWMutex
+	m map[string]*ingestTail
+}{m: map[string]*ingestTail{}}
+
+// StartIngestTails follows the files of every enabled tail from where it stopped
+func (h *Handler) StartIngestTails() {
+	var tails []model.IngestTail
+	h.DB.Find(&tails)
+	for _, t := range tails {
+		if t.Enabled {
+			h.startIngestTail(t)
+		}
+	}
+}
+
+func (h *Handler) startIngestTail(t model.IngestTail) {
+	it := &ingestTail{tail: t, stop: make(chan struct{}), done: make(chan struct{})}
+	ingestTails.Lock()
+	ingestTails.m[t.ID] = it
+	ingestTails.Unlock()
+	go it.run(h)
+}
+
+func stopIngestTail(id string) {
+	ingestTails.Lock()
+	it := ingestTails.m[id]
+	delete(ingestTails.m, id)
+	ingestTails.Unlock()
+	if it != nil {
+		close(it.stop)
+		<-it.done
+	}
+}
+
+func (it *ingestTail) run(h *Handler) {
+	defer close(it.done)
+	t := it.tail
+	follower := &ingest.Follower{Path: t.Path, Offset: t.Offset, Limit: ingestTailBatch}
+	defer follower.Close()
+	dec := ingest.NewDecoder(zeekLogPath(t.Path))
+	ticker := time.NewTicker(ingestTailInterval)
+	defer ticker.Stop()
+
+	lastError := t.LastError
+	for {
+		more := false
+		a, err := h.ingestAdapter(t.Adapter)
+		if err == nil {
+			out := newAdapterOutcome()
+			offset := follower.Offset
+			var lines int
+			lines, more, err = follower.Poll(func(line string) {
+				ev, err := dec.Decode(line)
+				where := fmt.Sprintf("%s at byte %d", filepath.Base(t.Path), follower.Offset)
+				switch {
+				case err != nil:
+					h.ingestDecodeError(a, err, out, where)
+				case ev != nil:
+					h.ingestEvent(a, t.Sensor, false, ev, out, where)
+				}
+			})
+			if lines > 0 || follower.Offset != offset {
+				now := h.Now()
+				updates := map[string]interface{}{
+					"offset":       follower.Offset,
+					"events":       gorm.Expr("events + ?", out.Events),
+					"records":      gorm.Expr("records + ?", out.records()),
+					"failed":       gorm.Expr("failed + ?", out.Failed),
+					"last_read_at": now,
+				}
+				if len(out.Errors) > 0 {
+					updates["last_error"], updates["last_error_at"] = out.Errors[len(out.Errors)-1], now
+					lastError = out.Errors[len(out.Errors)-1]
+				}
+				h.DB.Model(&model.IngestTail{}).Where("id = ?", t.ID).Updates(updates)
+			}
+		}
+		if err != nil && err.Error() != lastError {
+			lastError = err.Error()
+			log.Printf("Ingest tail %s: %v", t.Path, err)
+			h.DB.Model(&model.IngestTail{}).Where("id = ?", t.ID).
+				Updates(map[string]interface{}{"last_error": lastError, "last_error_at": h.Now()})
+		}
+		if more {
+			// A backlog, go on unless asked to stop
+			select {
+			case <-it.stop:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-it.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// zeekLogPath names a Zeek log by its file, conn.log and the rotated
+// conn.2026-10-18-10-00-00.log are both conn
+func zeekLogPath(file string) string {
+	name, _, _ := strings.Cut(filepath.Base(file), ".")
+	return name
+}
+
+func (h *Handler) normalizeIngestTail(t *model.IngestTail) error {
+	t.Path = strings.TrimSpace(t.Path)
+	if !filepath.IsAbs(t.Path) {
+		return errors.New("path must be absolute")
+	}
+	t.Path = filepath.Clean(t.Path)
+	if _, err := h.ingestAdapter(t.Adapter); err != nil {
+		return err
+	}
+	t.Sensor = strings.TrimSpace(t.Sensor)
+	if len([]rune(t.Sensor)) > 64 {
+		return errors.New("sensor can be at most 64 characters")
+	}
+	return nil
+}
+
+// startOffset is where a new tail begins: the start of the file, or its current end to
+// take only new lines
+func startOffset(t model.IngestTail) int64 {
+	if t.FromStart {
+		return 0
+	}
+	if info, err := os.Stat(t.Path); err == nil {
+		return info.Size()
+	}
+	return 0
+}
+
+// ingestTailView adds whether the tail is running
+type ingestTailView struct {
+	model.IngestTail
+	Running bool `json:"running"`
+}
+
+func viewIngestTail(t model.IngestTail) ingestTailView {
+	ingestTails.RLock()
+	_, running := ingestTails.m[t.ID]
+	ingestTails.RUnlock()
+	return ingestTailView{IngestTail: t, Running: running}
+}
+
+func (h *Handler) GetIngestTails(c *gin.Context) {
+	var tails []model.IngestTail
+	h.DB.Order("created_at").Find(&tails)
+	views := make([]ingestTailView, len(tails))
+	for i, t := range tails {
+		views[i] = viewIngestTail(t)
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func (h *Handler) CreateIngestTail(c *gin.Context) {
+	var t model.IngestTail
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.normalizeIngestTail(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t.ID = fmt.Sprintf("IT-%d", time.Now().UnixNano())
+	t.Offset = startOffset(t)
+	t.Events, t.Records, t.Failed = 0, 0, 0
+	t.LastError, t.LastErrorAt, t.LastReadAt = "", nil, nil
+	t.CreatedAt, t.UpdatedAt = h.Now(), h.Now()
+	if err := h.DB.Create(&t).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "The file is already followed by another tail"})
+		return
+	}
+	if t.Enabled {
+		h.startIngestTail(t)
+	}
+	c.JSON(http.StatusOK, viewIngestTail(t))
+}
+
+// UpdateIngestTail changes a tail and restarts it. It goes on where it stopped unless it
+// now follows another file.
+func (h *Handler) UpdateIngestTail(c *gin.Context) {
+	var old model.IngestTail
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&old).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tail not found"})
+		return
+	}
+	var t model.IngestTail
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.normalizeIngestTail(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	stopIngestTail(old.ID)
+	// The stopped tail saved its progress
+	h.DB.Where("id = ?", old.ID).First(&old)
+	t.ID, t.CreatedAt, t.UpdatedAt = old.ID, old.CreatedAt, h.Now()
+	t.Events, t.Records, t.Failed = old.Events, old.Records, old.Failed
+	t.LastError, t.LastErrorAt, t.LastReadAt = old.LastError, old.LastErrorAt, old.LastReadAt
+	t.Offset = old.Offset
+	if t.Path != old.Path {
+		t.Offset = startOffset(t)
+	}
+	if err := h.DB.Save(&t).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "The file is already followed by another tail"})
+		if old.Enabled {
+			h.startIngestTail(old)
+		}
+		return
+	}
+	if t.Enabled {
+		h.startIngestTail(t)
+	}
+	c.JSON(http.StatusOK, viewIngestTail(t))
+}
+
+func (h *Handler) DeleteIngestTail(c *gin.Context) {
+	id := c.Param("id")
+	stopIngestTail(id)
+	res := h.DB.Delete(&model.IngestTail{}, "id = ?", id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tail"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tail not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
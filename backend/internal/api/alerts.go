@@ -0,0 +1,445 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// alert is one occurrence of something the message center reports. Occurrences with the
+// same fingerprint inside the window fold into the open message instead of adding one.
+type alert struct {
+	Title    string // i18n key, as on Message
+	Content  string // "key|name:value" text, as on Message
+	Type     string
+	Severity string
+	Node     string // Node ID the alert is about, silences match it
+	Source   string // Counted distinct in groups, e.g. the attacking address
+	// Fingerprint identifies repeats, empty for title and content
+	Fingerprint string
+	// GroupContent turns folded repeats into a summary, the "key|params" text gets the
+	// count and the number of distinct sources added
+	GroupContent string
+	// Resolves is the fingerprint of an open alert this one ends, node online ends node offline
+	Resolves string
+}
+
+// alertConfig is stored under alert_config, windows are in minutes
+type alertConfig struct {
+	DedupeWindow int `json:"dedupeWindow"` // Identical alerts fold inside it
+	GroupWindow  int `json:"groupWindow"`  // Related alerts fold into a summary inside it
+	RateLimit    int `json:"rateLimit"`    // New messages per minute before the rest is folded, 0 for no limit
+}
+
+const (
+	alertMaxSources = 256
+	alertMaxWindow  = 24 * 60
+)
+
+var (
+	// alertMu serializes the fold-or-create decision and its write so concurrent repeats can
+	// not both create
+	alertMu sync.Mutex
+	// alertCreated holds when the messages of the last minute were created, for the rate limit
+	alertCreated []time.Time
+	messageSeq   atomic.Uint64
+)
+
+func (h *Handler) getAlertConfig() alertConfig {
+	var cfg model.SystemConfig
+	h.DB.Where("key = ?", "alert_config").Find(&cfg)
+
+	alertCfg := alertConfig{DedupeWindow: 10, GroupWindow: 10, RateLimit: 30}
+	if cfg.Value != "" {
+		json.Unmarshal([]byte(cfg.Value), &alertCfg)
+	}
+	return alertCfg
+}
+
+// newMessageID keeps IDs unique when several messages are created in the same instant
+func newMessageID() string {
+	return fmt.Sprintf("msg-%d-%d", time.Now().UnixNano(), messageSeq.Add(1))
+}
+
+// alertEvent is the event kind of a title key, msg_node_offline_title is node_offline
+func alertEvent(title string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(title, "msg_"), "_title")
+}
+
+// raiseAlert is the way into the message center. It drops silenced alerts, folds repeats
+// and related alerts into the open message, holds new messages to the rate limit and only
+// notifies the channels for new messages.
+func (h *Handler) raiseAlert(a alert) {
+	now := h.Now()
+	cfg := h.getAlertConfig()
+	if a.Severity == "" {
+		a.Severity = "info"
+	}
+	if a.Fingerprint == "" {
+		a.Fingerprint = a.Title + "|" + a.Content
+	}
+
+	if a.Resolves != "" {
+		h.resolveAlerts(a.Resolves, now)
+	}
+	if h.silenceAlert(a, now) {
+		return
+	}
+
+	// Only the fold-or-create decision and its write are serialized, users and channels
+	// are told afterwards
+	alertMu.Lock()
+	msg, created := h.storeAlert(cfg, a, now)
+	alertMu.Unlock()
+
+	if created {
+		h.announceMessage(msg)
+	} else {
+		h.broadcastMessageUpdate(msg)
+	}
+}
+
+// storeAlert folds the alert into its open message or creates a new one, and reports
+// whether the message is new. The caller holds alertMu.
+func (h *Handler) storeAlert(cfg alertConfig, a alert, now time.Time) (model.Message, bool) {
+	window := cfg.DedupeWindow
+	if a.GroupContent != "" {
+		window = cfg.GroupWindow
+	}
+	var open model.Message
+	if h.DB.Where("fingerprint = ? AND last_seen >= ?", a.Fingerprint, now.Add(-time.Duration(window)*time.Minute)).
+		Order("last_seen desc").Limit(1).Find(&open).RowsAffected > 0 {
+		return h.foldAlert(&open, a, now)
+	}
+
+	if !h.allowNewAlert(cfg, now) {
+		return h.foldAlert(nil, alert{
+			Title:        "msg_alert_rate_limited_title",
+			Content:      "msg_alert_rate_limited_content|count:1,sources:1",
+			Type:         "system",
+			Severity:     "warning",
+			Source:       alertEvent(a.Title),
+			Fingerprint:  "alert_rate_limited",
+			GroupContent: "msg_alert_rate_limited_content",
+		}, now)
+	}
+
+	msg := model.Message{
+		ID:          newMessageID(),
+		Title:       a.Title,
+		Content:     a.Content,
+		Time:        now,
+		Type:        a.Type,
+		Severity:    a.Severity,
+		Fingerprint: a.Fingerprint,
+		Node:        a.Node,
+		Count:       1,
+		Sources:     a.Source,
+		LastSeen:    now,
+		Status:      "firing",
+	}
+	h.DB.Create(&msg)
+	return msg, true
+}
+
+// allowNewAlert counts a new message against the per-minute limit
+func (h *Handler) allowNewAlert(cfg alertConfig, now time.Time) bool {
+	cutoff := now.Add(-time.Minute)
+	alertCreated = slices.DeleteFunc(alertCreated, func(t time.Time) bool { return t.Before(cutoff) })
+	if cfg.RateLimit > 0 && len(alertCreated) >= cfg.RateLimit {
+		return false
+	}
+	alertCreated = append(alertCreated, now)
+	return true
+}
+
+// foldAlert adds an occurrence to an open message. Without one, as for the overflow of the
+// rate limit, the message of the fingerprint is looked up or created past the limit. It
+// reports whether the message is new.
+func (h *Handler) foldAlert(open *model.Message, a alert, now time.Time) (model.Message, bool) {
+	if open == nil {
+		var msg model.Message
+		if h.DB.Where("fingerprint = ? AND status <> ?", a.Fingerprint, "resolved").Order("last_seen desc").Limit(1).Find(&msg).RowsAffected == 0 {
+			msg = model.Message{
+				ID: newMessageID(), Title: a.Title, Content: a.Content, Time: now, Type: a.Type,
+				Severity: a.Severity, Fingerprint: a.Fingerprint, Count: 1, Sources: a.Source,
+				LastSeen: now, Status: "firing",
+			}
+			h.DB.Create(&msg)
+			return msg, true
+		}
+		open = &msg
+	}
+
+	open.Count++
+	open.LastSeen = now
+	sources := splitList(open.Sources)
+	if a.Source != "" && !slices.Contains(sources, a.Source) && len(sources) < alertMaxSources {
+		sources = append(sources, a.Source)
+		open.Sources = strings.Join(sources, ",")
+	}
+	if a.GroupContent != "" {
+		open.Content = groupContent(a.GroupContent, open.Count, len(sources))
+	}
+	// A repeat of a resolved alert means it is back. Inside the window that is flapping,
+	// the channels were told when the message was new.
+	if open.Status == "resolved" {
+		open.Status, open.ResolvedBy, open.ResolvedAt = "firing", "", nil
+		h.DB.Model(&model.MessageState{}).Where("message_id = ?", open.ID).
+			Updates(map[string]interface{}{"read": false, "read_at": nil})
+	}
+	h.DB.Save(open)
+	return *open, false
+}
+
+// groupContent adds the count and the distinct sources to the parameters of a summary
+func groupContent(content string, count, sources int) string {
+	key, params, _ := strings.Cut(content, "|")
+	if params != "" {
+		params += ","
+	}
+	return fmt.Sprintf("%s|%scount:%d,sources:%d", key, params, count, sources)
+}
+
+// resolveAlerts ends the open alerts of a fingerprint, e.g. when the node is back
+func (h *Handler) resolveAlerts(fingerprint string, now time.Time) {
+	var open []model.Message
+	h.DB.Where("fingerprint = ? AND status <> ?", fingerprint, "resolved").Find(&open)
+	for _, msg := range open {
+		msg.Status, msg.ResolvedBy, msg.ResolvedAt = "resolved", "system", &now
+		h.DB.Save(&msg)
+		h.broadcastMessageUpdate(msg)
+	}
+}
+
+// silenceAlert checks the silences running now and counts the alert on the first that matches
+func (h *Handler) silenceAlert(a alert, now time.Time) bool {
+	var silence model.AlertSilence
+	event := alertEvent(a.Title)
+	res := h.DB.Where("starts_at <= ? AND ends_at > ?", now, now).
+		Where("node = '' OR node = ?", a.Node).
+		Where("event = '' OR event = ?", event).
+		Order("ends_at desc").Limit(1).Find(&silence)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false
+	}
+	h.DB.Model(&silence).Update("suppressed", gorm.Expr("suppressed + 1"))
+	return true
+}
+
+// broadcastMessageUpdate pushes a changed message to the users subscribed to it
+func (h *Handler) broadcastMessageUpdate(msg model.Message) {
+	h.pushMessage("MESSAGE_UPDATE", msg)
+}
+
+// alertAttack raises critical attacks, grouped per method so a brute force run becomes
+// one message counting its attempts and addresses
+func (h *Handler) alertAttack(a model.AttackLog) {
+	if strings.ToLower(a.Severity) != "critical" {
+		return
+	}
+	clean := strings.NewReplacer(",", " ", "|", " ")
+	payload := []rune(clean.Replace(a.Payload))
+	if len(payload) > 120 {
+		payload = append(payload[:120], '…')
+	}
+	method := clean.Replace(a.Method)
+	h.raiseAlert(alert{
+		Title: "msg_attack_title",
+		Content: fmt.Sprintf("msg_attack_content|method:%s,ip:%s,node:%s,status:%s,payload:%s",
+			method, a.SourceIP, clean.Replace(a.Node), clean.Replace(a.Status), string(payload)),
+		Type:         "security",
+		Severity:     "critical",
+		Node:         a.Node,
+		Source:       a.SourceIP,
+		Fingerprint:  "attack:" + strings.ToLower(method),
+		GroupContent: "msg_attack_group_content|method:" + method,
+	})
+}
+
+var messageListSpec = listSpec{
+	timeColumn:  "time",
+	sorts:       map[string]string{"time": "time", "lastSeen": "last_seen", "count": "count"},
+	defaultSort: "-lastSeen",
+	filters: map[string]string{
+		"status": "status", "type": "type", "severity": "severity",
+		"assignee": "assignee", "node": "node", "fingerprint": "fingerprint",
+	},
+	search: []string{"title", "content"},
+}
+
+// updateMessage loads a message, applies a lifecycle change and pushes the result
+func (h *Handler) updateMessage(c *gin.Context, change func(msg *model.Message, user string, now time.Time) error) {
+	user, _ := c.Get("username")
+	username, _ := user.(string)
+
+	alertMu.Lock()
+	var msg model.Message
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&msg).Error; err != nil {
+		alertMu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	if err := change(&msg, username, h.Now()); err != nil {
+		alertMu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err := h.DB.Save(&msg).Error
+	alertMu.Unlock()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update message"})
+		return
+	}
+	// Whoever acted on the message has seen it
+	read := true
+	h.setMessageState(username, []string{msg.ID}, &read, nil)
+	h.broadcastMessageUpdate(msg)
+	c.JSON(http.StatusOK, inboxMessage{Message: msg, Read: true})
+}
+
+// AcknowledgeMessage marks a firing alert as being looked at, by default by whoever acknowledged it
+func (h *Handler) AcknowledgeMessage(c *gin.Context) {
+	h.updateMessage(c, func(msg *model.Message, user string, now time.Time) error {
+		if msg.Status != "firing" {
+			return fmt.Errorf("only firing alerts can be acknowledged, this one is %s", msg.Status)
+		}
+		msg.Status, msg.AcknowledgedBy, msg.AcknowledgedAt = "acknowledged", user, &now
+		if msg.Assignee == "" {
+			msg.Assignee = user
+		}
+		return nil
+	})
+}
+
+func (h *Handler) AssignMessage(c *gin.Context) {
+	var req struct {
+		Assignee string `json:"assignee"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Assignee = strings.TrimSpace(req.Assignee)
+	if req.Assignee != "" {
+		var count int64
+		h.DB.Model(&model.User{}).Where("username = ?", req.Assignee).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown user " + req.Assignee})
+			return
+		}
+	}
+	h.updateMessage(c, func(msg *model.Message, user string, now time.Time) error {
+		msg.Assignee = req.Assignee
+		return nil
+	})
+}
+
+func (h *Handler) ResolveMessage(c *gin.Context) {
+	h.updateMessage(c, func(msg *model.Message, user string, now time.Time) error {
+		if msg.Status == "resolved" {
+			return errors.New("the alert is already resolved")
+		}
+		msg.Status, msg.ResolvedBy, msg.ResolvedAt = "resolved", user, &now
+		return nil
+	})
+}
+
+func (h *Handler) GetAlertSilences(c *gin.Context) {
+	var silences []model.AlertSilence
+	h.DB.Order("ends_at desc").Find(&silences)
+	c.JSON(http.StatusOK, silences)
+}
+
+// CreateAlertSilence starts a maintenance window, without a start time it begins now
+func (h *Handler) CreateAlertSilence(c *gin.Context) {
+	var silence model.AlertSilence
+	if err := c.ShouldBindJSON(&silence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	now := h.Now()
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if !silence.EndsAt.After(silence.StartsAt) || !silence.EndsAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endsAt must be after startsAt and in the future"})
+		return
+	}
+	silence.Node = strings.TrimSpace(silence.Node)
+	if silence.Node != "" {
+		var count int64
+		h.DB.Model(&model.NodeStatus{}).Where("id = ?", silence.Node).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown node " + silence.Node})
+			return
+		}
+	}
+	silence.Event = strings.TrimSpace(silence.Event)
+	if silence.Event != "" && !slices.Contains(notificationEvents, silence.Event) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown event %q", silence.Event)})
+		return
+	}
+	user, _ := c.Get("username")
+	silence.CreatedBy, _ = user.(string)
+	silence.ID = fmt.Sprintf("SIL-%d", time.Now().UnixNano())
+	silence.StartsAt, silence.EndsAt = silence.StartsAt.UTC(), silence.EndsAt.UTC()
+	silence.CreatedAt, silence.Suppressed = now, 0
+	if err := h.DB.Create(&silence).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create silence"})
+		return
+	}
+	log.Printf("Alerts silenced for node %q event %q until %s: %s", silence.Node, silence.Event, silence.EndsAt.Format(time.RFC3339), silence.Reason)
+	c.JSON(http.StatusOK, silence)
+}
+
+// DeleteAlertSilence ends a running silence early and drops a past or future one
+func (h *Handler) DeleteAlertSilence(c *gin.Context) {
+	var silence model.AlertSilence
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&silence).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Silence not found"})
+		return
+	}
+	now := h.Now()
+	if silence.StartsAt.Before(now) && silence.EndsAt.After(now) {
+		h.DB.Model(&silence).Update("ends_at", now)
+	} else {
+		h.DB.Delete(&silence)
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *Handler) GetAlertConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.getAlertConfig())
+}
+
+func (h *Handler) UpdateAlertConfig(c *gin.Context) {
+	var req alertConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if req.DedupeWindow < 0 || req.DedupeWindow > alertMaxWindow || req.GroupWindow < 0 || req.GroupWindow > alertMaxWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("windows must be between 0 and %d minutes", alertMaxWindow)})
+		return
+	}
+	if req.RateLimit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rateLimit must not be negative"})
+		return
+	}
+	val, _ := json.Marshal(req)
+	h.saveConfigValue("alert_config", string(val))
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
@@ -0,0 +1,720 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// blocklistMaxBytes caps a downloaded or read list
+	blocklistMaxBytes = 32 << 20
+	// blocklistDefaultMaxEntries applies when a subscription sets no limit, blocklistHardMaxEntries always does
+	blocklistDefaultMaxEntries = 50000
+	blocklistHardMaxEntries    = 500000
+	// blocklistDefaultInterval and blocklistMinInterval are refresh intervals in minutes
+	blocklistDefaultInterval = 60
+	blocklistMinInterval     = 5
+	// blocklistPreviewSample is how many addresses a preview lists per category
+	blocklistPreviewSample = 100
+)
+
+// blocklistMu serializes refreshes so scheduled and manual runs never reconcile the same list twice
+var blocklistMu sync.Mutex
+
+var blocklistClient = &http.Client{Timeout: 60 * time.Second}
+
+// blocklistEntry is one address of a list with the comment it carried, e.g. a Spamhaus SBL id
+type blocklistEntry struct {
+	Addr    string
+	Comment string
+}
+
+// parseBlocklist reads a list into canonical addresses. Lines that are not addresses are
+// returned as skipped so a preview can show them.
+func parseBlocklist(format string, data []byte) ([]blocklistEntry, []string, error) {
+	var raw []blocklistEntry
+	var skipped []string
+
+	switch format {
+	case "csv":
+		r := csv.NewReader(bytes.NewReader(data))
+		r.FieldsPerRecord = -1
+		r.Comment = '#'
+		r.TrimLeadingSpace = true
+		records, err := r.ReadAll()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		addrCol, commentCol := 0, -1
+		if len(records) > 0 {
+			if _, _, err := parseRuleAddress(records[0][0]); err != nil {
+				// First row is a header
+				for i, col := range records[0] {
+					switch strings.ToLower(strings.TrimSpace(col)) {
+					case "ip", "cidr", "address", "network", "range":
+						addrCol = i
+					case "comment", "reason", "description":
+						commentCol = i
+					}
+				}
+				records = records[1:]
+			}
+		}
+		for _, rec := range records {
+			if addrCol >= len(rec) {
+				continue
+			}
+			e := blocklistEntry{Addr: rec[addrCol]}
+			if commentCol >= 0 && commentCol < len(rec) {
+				e.Comment = rec[commentCol]
+			}
+			raw = append(raw, e)
+		}
+
+	case "plain", "spamhaus":
+		// FireHOL netsets, Spamhaus DROP and in-house lists are one entry per line with
+		// # or ; comments. Spamhaus also publishes the DROP list as JSON lines.
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//") {
+				continue
+			}
+			if strings.HasPrefix(line, "{") {
+				var obj struct {
+					CIDR  string `json:"cidr"`
+					SBLID string `json:"sblid"`
+				}
+				if err := json.Unmarshal([]byte(line), &obj); err != nil || obj.CIDR == "" {
+					continue // Metadata lines carry no cidr
+				}
+				raw = append(raw, blocklistEntry{Addr: obj.CIDR, Comment: obj.SBLID})
+				continue
+			}
+			e := blocklistEntry{}
+			if i := strings.IndexAny(line, ";#"); i >= 0 {
+				e.Comment = strings.TrimSpace(line[i+1:])
+				line = strings.TrimSpace(line[:i])
+			}
+			if fields := strings.Fields(line); len(fields) > 0 {
+				e.Addr = fields[0]
+			}
+			raw = append(raw, e)
+		}
+
+	default:
+		return nil, nil, fmt.Errorf("format must be plain, spamhaus or csv")
+	}
+
+	seen := map[string]bool{}
+	entries := make([]blocklistEntry, 0, len(raw))
+	for _, e := range raw {
+		addr, _, err := parseRuleAddress(e.Addr)
+		if err != nil {
+			skipped = append(skipped, strings.TrimSpace(e.Addr))
+			continue
+		}
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		entries = append(entries, blocklistEntry{Addr: addr, Comment: strings.TrimSpace(e.Comment)})
+	}
+	return entries, skipped, nil
+}
+
+// fetchBlocklist reads the list of a subscription. With conditional set an unchanged
+// remote list returns notModified instead of a body.
+func fetchBlocklist(sub *model.BlocklistSubscription, conditional bool) (data []byte, notModified bool, err error) {
+	if sub.Path != "" {
+		info, err := os.Stat(sub.Path)
+		if err != nil {
+			return nil, false, err
+		}
+		if info.Size() > blocklistMaxBytes {
+			return nil, false, fmt.Errorf("list is larger than %d MB", blocklistMaxBytes>>20)
+		}
+		data, err = os.ReadFile(sub.Path)
+		return data, false, err
+	}
+
+	req, err := http.NewRequest("GET", sub.URL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("User-Agent", "PRTS-Blocklist/1.0")
+	if conditional {
+		if sub.ETag != "" {
+			req.Header.Set("If-None-Match", sub.ETag)
+		}
+		if sub.LastModified != "" {
+			req.Header.Set("If-Modified-Since", sub.LastModified)
+		}
+	}
+	resp, err := blocklistClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && conditional {
+		return nil, true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err = io.ReadAll(io.LimitReader(resp.Body, blocklistMaxBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) > blocklistMaxBytes {
+		return nil, false, fmt.Errorf("list is larger than %d MB", blocklistMaxBytes>>20)
+	}
+	sub.ETag = resp.Header.Get("ETag")
+	sub.LastModified = resp.Header.Get("Last-Modified")
+	return data, false, nil
+}
+
+// loadBlocklist fetches and parses a list and enforces the entry limit
+func loadBlocklist(sub *model.BlocklistSubscription, conditional bool) ([]blocklistEntry, []string, bool, error) {
+	data, notModified, err := fetchBlocklist(sub, conditional)
+	if err != nil || notModified {
+		return nil, nil, notModified, err
+	}
+	entries, skipped, err := parseBlocklist(sub.Format, data)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if len(entries) > sub.MaxEntries {
+		return entries, skipped, false, fmt.Errorf("list has %d entries, the limit is %d", len(entries), sub.MaxEntries)
+	}
+	return entries, skipped, false, nil
+}
+
+// blocklistPlan is what a reconcile would change
+type blocklistPlan struct {
+	add     []blocklistEntry
+	remove  []model.AccessControlRule
+	update  []model.AccessControlRule // Kept rules whose status, type or scope changes
+	keep    []model.AccessControlRule
+	entries int
+}
+
+func (h *Handler) planBlocklist(sub model.BlocklistSubscription, entries []blocklistEntry) blocklistPlan {
+	var existing []model.AccessControlRule
+	h.DB.Where("source = ?", sub.Name).Find(&existing)
+
+	wanted := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		wanted[e.Addr] = true
+	}
+	plan := blocklistPlan{entries: len(entries)}
+	have := make(map[string]bool, len(existing))
+	for _, rule := range existing {
+		if !wanted[rule.IP] || have[rule.IP] {
+			plan.remove = append(plan.remove, rule)
+			continue
+		}
+		have[rule.IP] = true
+		if rule.Status != "active" || rule.Type != sub.RuleType || rule.Scope != sub.Scope || rule.Targets != sub.Targets {
+			plan.update = append(plan.update, rule)
+		} else {
+			plan.keep = append(plan.keep, rule)
+		}
+	}
+	for _, e := range entries {
+		if !have[e.Addr] {
+			plan.add = append(plan.add, e)
+		}
+	}
+	return plan
+}
+
+// logRuleChanges records changes per rule, or a single reset when there are more than a
+// diff could carry so that probes take a snapshot
+func logRuleChanges(tx *gorm.DB, added, removed, updated []string) error {
+	if len(added)+len(removed)+len(updated) > ruleDiffMaxChanges {
+		_, err := recordRuleChange(tx, "reset", "")
+		return err
+	}
+	for op, ids := range map[string][]string{"add": added, "remove": removed, "update": updated} {
+		if len(ids) == 0 {
+			continue
+		}
+		if _, err := recordRuleChange(tx, op, ids...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileBlocklist makes the rules owned by a subscription match the list. Every
+// successful fetch pushes the expiry of the kept rules out by the TTL.
+func (h *Handler) reconcileBlocklist(sub model.BlocklistSubscription, entries []blocklistEntry) (blocklistPlan, error) {
+	plan := h.planBlocklist(sub, entries)
+	now := h.Now()
+	var expire *time.Time
+	if sub.RuleTTL > 0 {
+		t := now.Add(time.Duration(sub.RuleTTL) * time.Minute)
+		expire = &t
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		base := time.Now().UnixNano()
+		rules := make([]model.AccessControlRule, 0, len(plan.add))
+		added := make([]string, 0, len(plan.add))
+		for i, e := range plan.add {
+			reason := e.Comment
+			if reason == "" {
+				reason = "Blocklist " + sub.Name
+			}
+			rule := model.AccessControlRule{
+				ID:         fmt.Sprintf("AC-%d", base+int64(i)),
+				IP:         e.Addr,
+				Type:       sub.RuleType,
+				Protocol:   "any",
+				Direction:  "inbound",
+				Scope:      sub.Scope,
+				Targets:    sub.Targets,
+				Reason:     reason,
+				Source:     sub.Name,
+				ExpireTime: expire,
+				AddTime:    now,
+				Status:     "active",
+			}
+			rules = append(rules, rule)
+			added = append(added, rule.ID)
+		}
+		if len(rules) > 0 {
+			if err := tx.CreateInBatches(rules, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		removed := make([]string, 0, len(plan.remove))
+		for _, rule := range plan.remove {
+			removed = append(removed, rule.ID)
+		}
+		for i := 0; i < len(removed); i += 500 {
+			end := min(i+500, len(removed))
+			if err := tx.Where("id IN ?", removed[i:end]).Delete(&model.AccessControlRule{}).Error; err != nil {
+				return err
+			}
+		}
+
+		updated := make([]string, 0, len(plan.update))
+		for _, rule := range plan.update {
+			updated = append(updated, rule.ID)
+		}
+		for i := 0; i < len(updated); i += 500 {
+			end := min(i+500, len(updated))
+			if err := tx.Model(&model.AccessControlRule{}).Where("id IN ?", updated[i:end]).Updates(map[string]interface{}{
+				"status": "active", "type": sub.RuleType, "scope": sub.Scope, "targets": sub.Targets,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		// The expiry is only read by the server, so moving it is not a rule change
+		if err := tx.Model(&model.AccessControlRule{}).Where("source = ?", sub.Name).Update("expire_time", expire).Error; err != nil {
+			return err
+		}
+		return logRuleChanges(tx, added, removed, updated)
+	})
+	return plan, err
+}
+
+// refreshBlocklist fetches a subscription and reconciles its rules, failures are kept on
+// the subscription and raised as a system message once until the list recovers
+func (h *Handler) refreshBlocklist(sub model.BlocklistSubscription, conditional bool) (blocklistPlan, bool, error) {
+	blocklistMu.Lock()
+	defer blocklistMu.Unlock()
+
+	prevErr := sub.LastError
+	now := h.Now()
+	entries, _, notModified, err := loadBlocklist(&sub, conditional)
+	if err == nil && !notModified && len(entries) == 0 && sub.EntryCount > 0 {
+		err = errors.New("list is empty, keeping the current rules")
+	}
+
+	var plan blocklistPlan
+	if err == nil {
+		if notModified {
+			// Unchanged upstream still counts as a successful fetch for the TTL
+			var rules []model.AccessControlRule
+			h.DB.Select("ip").Where("source = ?", sub.Name).Find(&rules)
+			entries = make([]blocklistEntry, 0, len(rules))
+			for _, r := range rules {
+				entries = append(entries, blocklistEntry{Addr: r.IP})
+			}
+		}
+		plan, err = h.reconcileBlocklist(sub, entries)
+	}
+
+	updates := map[string]interface{}{"last_fetch": now, "etag": sub.ETag, "last_modified": sub.LastModified}
+	if err != nil {
+		updates["last_error"] = err.Error()
+		log.Printf("Blocklist %s refresh failed: %v", sub.Name, err)
+		if prevErr == "" {
+			h.raiseBlocklistFailure(sub, err)
+		}
+	} else {
+		updates["last_error"] = ""
+		updates["last_success"] = now
+		updates["entry_count"] = len(entries)
+		if !notModified {
+			log.Printf("Blocklist %s: %d entries, +%d -%d ~%d rules", sub.Name, len(entries), len(plan.add), len(plan.remove), len(plan.update))
+		}
+	}
+	h.DB.Model(&model.BlocklistSubscription{}).Where("id = ?", sub.ID).Updates(updates)
+
+	if err == nil && len(plan.add)+len(plan.remove)+len(plan.update) > 0 {
+		go h.pushRuleChanges()
+	}
+	return plan, notModified, err
+}
+
+func (h *Handler) raiseBlocklistFailure(sub model.BlocklistSubscription, err error) {
+	reason := strings.NewReplacer(",", " ", "|", " ").Replace(err.Error())
+	h.raiseAlert(alert{
+		Title:       "msg_blocklist_failed_title",
+		Content:     fmt.Sprintf("msg_blocklist_failed|name:%s,error:%s", sub.Name, reason),
+		Type:        "system",
+		Severity:    "warning",
+		Fingerprint: "blocklist_failed:" + sub.ID,
+	})
+}
+
+// RunBlocklistRefresh refreshes active subscriptions when their interval has passed
+func (h *Handler) RunBlocklistRefresh() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		var subs []model.BlocklistSubscription
+		h.DB.Where("status = ?", "active").Find(&subs)
+		now := h.Now()
+		for _, sub := range subs {
+			if sub.LastFetch != nil && now.Sub(*sub.LastFetch) < time.Duration(sub.RefreshInterval)*time.Minute {
+				continue
+			}
+			h.refreshBlocklist(sub, true)
+		}
+	}
+}
+
+// normalizeBlocklist validates a subscription and fills in the defaults
+func (h *Handler) normalizeBlocklist(sub *model.BlocklistSubscription) error {
+	sub.Name = strings.TrimSpace(sub.Name)
+	if sub.Name == "" {
+		return errors.New("name is required")
+	}
+	switch strings.ToUpper(sub.Name) {
+	case "PRTS", "SYSTEM":
+		return fmt.Errorf("%s is reserved for rules managed here", sub.Name)
+	}
+
+	sub.URL, sub.Path = strings.TrimSpace(sub.URL), strings.TrimSpace(sub.Path)
+	if (sub.URL == "") == (sub.Path == "") {
+		return errors.New("set either url or path")
+	}
+	if sub.URL != "" {
+		u, err := url.Parse(sub.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("url must be an http or https address")
+		}
+	}
+
+	sub.Format = strings.ToLower(strings.TrimSpace(sub.Format))
+	switch sub.Format {
+	case "":
+		sub.Format = "plain"
+	case "plain", "spamhaus", "csv":
+	default:
+		return errors.New("format must be plain, spamhaus or csv")
+	}
+
+	switch sub.RuleType {
+	case "":
+		sub.RuleType = "blacklist"
+	case "blacklist", "whitelist":
+	default:
+		return errors.New("ruleType must be blacklist or whitelist")
+	}
+
+	scope := model.AccessControlRule{Scope: sub.Scope, Targets: sub.Targets}
+	if err := normalizeRuleTargets(&scope); err != nil {
+		return err
+	}
+	sub.Scope, sub.Targets = scope.Scope, scope.Targets
+
+	if sub.RefreshInterval == 0 {
+		sub.RefreshInterval = blocklistDefaultInterval
+	}
+	if sub.RefreshInterval < blocklistMinInterval {
+		return fmt.Errorf("refreshInterval must be at least %d minutes", blocklistMinInterval)
+	}
+	if sub.RuleTTL < 0 {
+		return errors.New("ruleTtl cannot be negative")
+	}
+	if sub.RuleTTL > 0 && sub.RuleTTL < sub.RefreshInterval {
+		return errors.New("ruleTtl must be longer than refreshInterval or rules expire between fetches")
+	}
+	if sub.MaxEntries == 0 {
+		sub.MaxEntries = blocklistDefaultMaxEntries
+	}
+	if sub.MaxEntries < 0 || sub.MaxEntries > blocklistHardMaxEntries {
+		return fmt.Errorf("maxEntries must be between 1 and %d", blocklistHardMaxEntries)
+	}
+	return nil
+}
+
+func (h *Handler) GetBlocklists(c *gin.Context) {
+	var subs []model.BlocklistSubscription
+	h.DB.Order("created_at asc").Find(&subs)
+	c.JSON(http.StatusOK, subs)
+}
+
+// CreateBlocklist saves a subscription as pending, it is only fetched on a schedule after
+// a preview and an explicit activation
+func (h *Handler) CreateBlocklist(c *gin.Context) {
+	var sub model.BlocklistSubscription
+	if err := c.ShouldBindJSON(&sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.normalizeBlocklist(&sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The subscription owns every rule with its name as source, it must not adopt manual rules
+	var count int64
+	h.DB.Model(&model.BlocklistSubscription{}).Where("name = ?", sub.Name).Count(&count)
+	if count == 0 {
+		h.DB.Model(&model.AccessControlRule{}).Where("source = ?", sub.Name).Count(&count)
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name is already used as a rule source"})
+		return
+	}
+
+	sub.ID = fmt.Sprintf("BL-%d", time.Now().UnixNano())
+	sub.Status = "pending"
+	sub.EntryCount, sub.LastFetch, sub.LastSuccess, sub.LastError, sub.PreviewedAt = 0, nil, nil, "", nil
+	sub.CreatedAt = h.Now()
+	if err := h.DB.Create(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subscription"})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// UpdateBlocklist changes a subscription, the name is fixed because rules are matched by it.
+// Setting status toggles between active and paused.
+func (h *Handler) UpdateBlocklist(c *gin.Context) {
+	var sub model.BlocklistSubscription
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&sub).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	prev := sub
+	if err := c.ShouldBindJSON(&sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(sub.Name) != prev.Name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be changed"})
+		return
+	}
+	if err := h.normalizeBlocklist(&sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch {
+	case sub.Status == prev.Status:
+	case prev.Status != "pending" && (sub.Status == "active" || sub.Status == "paused"):
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status can only switch between active and paused, use activate for a pending subscription"})
+		return
+	}
+
+	// Keep server-managed fields, a changed source has to be previewed again
+	sub.ID, sub.CreatedAt = prev.ID, prev.CreatedAt
+	sub.EntryCount, sub.LastFetch, sub.LastSuccess, sub.LastError = prev.EntryCount, prev.LastFetch, prev.LastSuccess, prev.LastError
+	sub.PreviewedAt, sub.ETag, sub.LastModified = prev.PreviewedAt, prev.ETag, prev.LastModified
+	if sub.URL != prev.URL || sub.Path != prev.Path || sub.Format != prev.Format {
+		sub.ETag, sub.LastModified = "", ""
+		if sub.Status == "pending" {
+			sub.PreviewedAt = nil
+		}
+	}
+	if err := h.DB.Save(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subscription"})
+		return
+	}
+
+	if sub.Status == "active" {
+		_, _, err := h.refreshBlocklist(sub, false)
+		h.DB.Where("id = ?", sub.ID).First(&sub)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"subscription": sub, "error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"subscription": sub})
+}
+
+// DeleteBlocklist removes a subscription together with the rules it created
+func (h *Handler) DeleteBlocklist(c *gin.Context) {
+	var sub model.BlocklistSubscription
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&sub).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	blocklistMu.Lock()
+	var ids []string
+	h.DB.Model(&model.AccessControlRule{}).Where("source = ?", sub.Name).Pluck("id", &ids)
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source = ?", sub.Name).Delete(&model.AccessControlRule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&sub).Error; err != nil {
+			return err
+		}
+		return logRuleChanges(tx, nil, ids, nil)
+	})
+	blocklistMu.Unlock()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete subscription"})
+		return
+	}
+	if len(ids) > 0 {
+		go h.pushRuleChanges()
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "removedRules": len(ids)})
+}
+
+// sampleEntries returns at most blocklistPreviewSample sorted addresses
+func sampleEntries(addrs []string) []string {
+	sort.Strings(addrs)
+	if len(addrs) > blocklistPreviewSample {
+		addrs = addrs[:blocklistPreviewSample]
+	}
+	return addrs
+}
+
+// PreviewBlocklist fetches the list and shows what the next reconcile would change
+// without touching any rule. A pending subscription needs one before activation.
+func (h *Handler) PreviewBlocklist(c *gin.Context) {
+	var sub model.BlocklistSubscription
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&sub).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	entries, skipped, _, err := loadBlocklist(&sub, false)
+	if err != nil && entries == nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	plan := h.planBlocklist(sub, entries)
+
+	add := make([]string, 0, len(plan.add))
+	for _, e := range plan.add {
+		add = append(add, e.Addr)
+	}
+	remove := make([]string, 0, len(plan.remove))
+	for _, r := range plan.remove {
+		remove = append(remove, r.IP)
+	}
+	update := make([]string, 0, len(plan.update))
+	for _, r := range plan.update {
+		update = append(update, r.IP)
+	}
+	if skipped == nil {
+		skipped = []string{}
+	}
+
+	resp := gin.H{
+		"entries":      len(entries),
+		"maxEntries":   sub.MaxEntries,
+		"addCount":     len(add),
+		"removeCount":  len(remove),
+		"updateCount":  len(update),
+		"unchanged":    len(plan.keep),
+		"skippedCount": len(skipped),
+		"add":          sampleEntries(add),
+		"remove":       sampleEntries(remove),
+		"update":       sampleEntries(update),
+		"skipped":      sampleEntries(skipped),
+	}
+	if err != nil {
+		// Over the entry limit, the preview still shows the size of the list
+		resp["error"] = err.Error()
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	now := h.Now()
+	h.DB.Model(&sub).Update("previewed_at", now)
+	resp["previewedAt"] = now
+	c.JSON(http.StatusOK, resp)
+}
+
+// ActivateBlocklist turns on a previewed subscription and runs its first reconcile
+func (h *Handler) ActivateBlocklist(c *gin.Context) {
+	var sub model.BlocklistSubscription
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&sub).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	if sub.Status == "pending" && sub.PreviewedAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Preview the list before activating it"})
+		return
+	}
+
+	plan, _, err := h.refreshBlocklist(sub, false)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	h.DB.Model(&sub).Update("status", "active")
+	c.JSON(http.StatusOK, gin.H{"status": "success", "entries": plan.entries, "added": len(plan.add), "removed": len(plan.remove), "updated": len(plan.update)})
+}
+
+// RefreshBlocklist fetches an active subscription now instead of waiting for its interval
+func (h *Handler) RefreshBlocklist(c *gin.Context) {
+	var sub model.BlocklistSubscription
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&sub).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	if sub.Status != "active" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Subscription is not active"})
+		return
+	}
+
+	plan, notModified, err := h.refreshBlocklist(sub, c.Query("force") != "true")
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "notModified": notModified, "entries": plan.entries, "added": len(plan.add), "removed": len(plan.remove), "updated": len(plan.update)})
+}
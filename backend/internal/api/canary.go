@@ -0,0 +1,488 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/dns/dnsmessage"
+	"gorm.io/gorm"
+)
+
+// Canary token types
+const (
+	CanaryTypeHTTP = "http"
+	CanaryTypeDNS  = "dns"
+	CanaryTypeDocx = "docx"
+	CanaryTypePDF  = "pdf"
+	CanaryTypeAWS  = "aws"
+)
+
+const tokenAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+var awsKeyIDPattern = regexp.MustCompile(`AKIA[A-Z2-7]{16}`)
+
+// Scanners and resolvers repeat themselves, each token fires once a minute per source
+var (
+	canaryRecentMu    sync.Mutex
+	canaryRecent      = map[string]time.Time{}
+	canaryRecentPrune time.Time
+)
+
+// canaryConfig is stored as JSON under the "canary_config" SystemConfig key.
+// BaseURL is the externally reachable address of this server used in callback URLs,
+// DNSZone is the zone delegated to the built-in responder listening on DNSListen.
+type canaryConfig struct {
+	BaseURL   string `json:"baseUrl"`
+	DNSZone   string `json:"dnsZone"`
+	DNSListen string `json:"dnsListen"`
+	DNSAnswer string `json:"dnsAnswer"`
+}
+
+func (h *Handler) getCanaryConfig() canaryConfig {
+	var cfg model.SystemConfig
+	h.DB.Where("key = ?", "canary_config").Find(&cfg)
+
+	canaryCfg := canaryConfig{DNSAnswer: "127.0.0.1"}
+	if cfg.Value != "" {
+		json.Unmarshal([]byte(cfg.Value), &canaryCfg)
+	}
+	canaryCfg.BaseURL = strings.TrimRight(canaryCfg.BaseURL, "/")
+	canaryCfg.DNSZone = strings.Trim(strings.ToLower(canaryCfg.DNSZone), ".")
+	return canaryCfg
+}
+
+// canaryBaseURL falls back to the address the request came in on when no base URL is configured
+func (h *Handler) canaryBaseURL(c *gin.Context, cfg canaryConfig) string {
+	if cfg.BaseURL != "" {
+		return cfg.BaseURL
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+func (h *Handler) mintCanaryToken(kind, decoyID, memo, baseURL string, cfg canaryConfig) (model.CanaryToken, error) {
+	tok := model.CanaryToken{
+		ID:         fmt.Sprintf("CT-%d", time.Now().UnixNano()),
+		Token:      randomString(20, tokenAlphabet),
+		Type:       kind,
+		DecoyID:    decoyID,
+		Memo:       memo,
+		Status:     "active",
+		CreateTime: h.Now(),
+	}
+
+	switch kind {
+	case CanaryTypeHTTP, CanaryTypeDocx, CanaryTypePDF:
+		tok.URL = baseURL + "/api/v1/canary/" + tok.Token
+	case CanaryTypeDNS:
+		if cfg.DNSZone == "" {
+			return tok, fmt.Errorf("canary DNS zone is not configured")
+		}
+		tok.Hostname = tok.Token + "." + cfg.DNSZone
+	case CanaryTypeAWS:
+		tok.AccessKeyID = "AKIA" + randomString(16, awsKeyIDAlphabet)
+		tok.SecretKey = randomString(40, awsSecretAlphabet)
+		tok.URL = baseURL + "/api/v1/canary-aws"
+	default:
+		return tok, fmt.Errorf("unsupported canary type %q", kind)
+	}
+
+	if cfg.DNSZone != "" && tok.Hostname == "" {
+		tok.Hostname = tok.Token + "." + cfg.DNSZone
+	}
+
+	return tok, h.DB.Create(&tok).Error
+}
+
+// mintDecoyTokens creates the canary tokens a decoy of the given type can carry
+func (h *Handler) mintDecoyTokens(decoy *model.DecoyLog, baseURL string, cfg canaryConfig) error {
+	var kinds []string
+	switch decoy.Type {
+	case DecoyTypeFile, DecoyTypeBrowserPassword:
+		kinds = []string{CanaryTypeHTTP}
+	case DecoyTypeAWSCredentials:
+		kinds = []string{CanaryTypeAWS}
+	case DecoyTypeSSHKey, DecoyTypeRegistry:
+		// Only a hostname fits into these, so they need the DNS responder
+		if cfg.DNSZone != "" {
+			kinds = []string{CanaryTypeDNS}
+		}
+	case DecoyTypeDocument:
+		kinds = []string{CanaryTypeDocx}
+		if strings.HasSuffix(strings.ToLower(decoy.DecoyName), ".pdf") {
+			kinds = []string{CanaryTypePDF}
+		}
+	}
+
+	for _, kind := range kinds {
+		if _, err := h.mintCanaryToken(kind, decoy.ID, decoy.DecoyName, baseURL, cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Handler) decoyTokens(decoyID string) map[string]model.CanaryToken {
+	var tokens []model.CanaryToken
+	h.DB.Where("decoy_id = ? AND status = ?", decoyID, "active").Find(&tokens)
+	res := make(map[string]model.CanaryToken)
+	for _, t := range tokens {
+		res[t.Type] = t
+	}
+	return res
+}
+
+func (h *Handler) GetCanaryTokens(c *gin.Context) {
+	var tokens []model.CanaryToken
+	h.DB.Order("create_time desc").Find(&tokens)
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateCanaryToken mints a standalone token. It is backed by its own DecoyLog row so
+// triggers are recorded the same way as for tokens embedded in planted decoys.
+func (h *Handler) CreateCanaryToken(c *gin.Context) {
+	var req struct {
+		Type string `json:"type"`
+		Memo string `json:"memo"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if req.Memo == "" {
+		req.Memo = req.Type + " canary token"
+	}
+
+	now := h.Now()
+	decoy := model.DecoyLog{
+		ID:         fmt.Sprintf("DL-%d", time.Now().UnixNano()),
+		Type:       DecoyTypeCanaryToken,
+		Status:     "Active",
+		DecoyName:  req.Memo,
+		Time:       now,
+		DeployTime: now,
+	}
+
+	cfg := h.getCanaryConfig()
+	tok, err := h.mintCanaryToken(req.Type, decoy.ID, req.Memo, h.canaryBaseURL(c, cfg), cfg)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.DB.Create(&decoy).Error; err != nil {
+		h.DB.Delete(&tok)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create canary token"})
+		return
+	}
+	h.exportEvent(decoySiemEvent(decoy))
+
+	c.JSON(http.StatusOK, tok)
+}
+
+func (h *Handler) DeleteCanaryToken(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.DB.Model(&model.CanaryToken{}).Where("id = ?", id).Update("status", "disabled").Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// DownloadCanaryDocument returns the beaconing Office/PDF document of a docx or pdf token
+func (h *Handler) DownloadCanaryDocument(c *gin.Context) {
+	var tok model.CanaryToken
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&tok).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	title := strings.TrimSuffix(strings.TrimSuffix(tok.Memo, ".docx"), ".pdf")
+	switch tok.Type {
+	case CanaryTypeDocx:
+		data, err := buildCanaryDocx(tok.URL, title)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build document"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", title+".docx"))
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", data)
+	case CanaryTypePDF:
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", title+".pdf"))
+		c.Data(http.StatusOK, "application/pdf", buildCanaryPDF(tok.URL, title))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token has no document"})
+	}
+}
+
+// transparent 1x1 GIF returned to beacons so documents render normally
+var canaryPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// CanaryCallback is the public endpoint behind HTTP, docx and pdf tokens
+func (h *Handler) CanaryCallback(c *gin.Context) {
+	var tok model.CanaryToken
+	if err := h.DB.Where("token = ? AND status = ?", c.Param("token"), "active").First(&tok).Error; err == nil && !canaryRepeat(tok.Token, c.ClientIP()) {
+		detail := fmt.Sprintf("canary %s %s %s", tok.Type, c.Request.Method, c.Request.URL.Path)
+		h.triggerCanary(tok, c.ClientIP(), c.GetHeader("User-Agent"), detail)
+	}
+	c.Data(http.StatusOK, "image/gif", canaryPixel)
+}
+
+// CanaryAWSCallback answers like an S3-compatible endpoint and reports any request signed
+// with one of our fake access keys.
+func (h *Handler) CanaryAWSCallback(c *gin.Context) {
+	keyID := ""
+	if m := awsKeyIDPattern.FindString(c.GetHeader("Authorization")); m != "" {
+		keyID = m
+	} else if m := awsKeyIDPattern.FindString(c.Query("X-Amz-Credential") + c.Query("AWSAccessKeyId")); m != "" {
+		keyID = m
+	}
+
+	if keyID != "" {
+		var tok model.CanaryToken
+		if err := h.DB.Where("access_key_id = ? AND status = ?", keyID, "active").First(&tok).Error; err == nil && !canaryRepeat(keyID, c.ClientIP()) {
+			detail := fmt.Sprintf("canary aws key %s used: %s %s", keyID, c.Request.Method, c.Request.URL.Path)
+			h.triggerCanary(tok, c.ClientIP(), c.GetHeader("User-Agent"), detail)
+		}
+	}
+
+	c.Data(http.StatusForbidden, "application/xml", []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+}
+
+// checkCanaryKeys reports fake AWS keys that show up in data captured by the honeypots,
+// e.g. an attacker replaying a planted key against one of our services.
+func (h *Handler) checkCanaryKeys(text, sourceIP, where string) {
+	for _, keyID := range awsKeyIDPattern.FindAllString(text, -1) {
+		var tok model.CanaryToken
+		if err := h.DB.Where("access_key_id = ? AND status = ?", keyID, "active").First(&tok).Error; err == nil {
+			h.triggerCanary(tok, sourceIP, "", fmt.Sprintf("canary aws key %s seen in %s", keyID, where))
+		}
+	}
+}
+
+func (h *Handler) triggerCanary(tok model.CanaryToken, ip, userAgent, detail string) {
+	now := h.Now()
+	h.DB.Model(&tok).Updates(map[string]interface{}{
+		"trigger_count":  gorm.Expr("trigger_count + 1"),
+		"last_triggered": now,
+	})
+
+	var decoy model.DecoyLog
+	if err := h.DB.Where("id = ?", tok.DecoyID).First(&decoy).Error; err != nil {
+		log.Printf("Canary token %s triggered from %s but its decoy %s is gone", tok.Token, ip, tok.DecoyID)
+		return
+	}
+	h.recordDecoyTrigger(decoy, h.Now(), ip, "", userAgent, decoy.Path, detail)
+}
+
+// StartCanaryDNS runs the authoritative responder for the canary zone. Any lookup of a
+// name below the zone containing an active token label counts as a trigger.
+func (h *Handler) StartCanaryDNS() {
+	cfg := h.getCanaryConfig()
+	if cfg.DNSZone == "" || cfg.DNSListen == "" {
+		return
+	}
+
+	conn, err := net.ListenPacket("udp", cfg.DNSListen)
+	if err != nil {
+		log.Printf("Failed to start canary DNS responder on %s: %v", cfg.DNSListen, err)
+		return
+	}
+	log.Printf("Canary DNS responder for %s listening on %s", cfg.DNSZone, cfg.DNSListen)
+
+	answer := net.ParseIP(cfg.DNSAnswer).To4()
+	if answer == nil {
+		answer = net.IPv4(127, 0, 0, 1).To4()
+	}
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				log.Printf("Canary DNS responder stopped: %v", err)
+				return
+			}
+			resp, token := buildCanaryDNSResponse(buf[:n], cfg.DNSZone, answer)
+			if resp != nil {
+				conn.WriteTo(resp, addr)
+			}
+			if token == "" {
+				continue
+			}
+
+			ip, _, _ := net.SplitHostPort(addr.String())
+			go func(token, ip string) {
+				var tok model.CanaryToken
+				if err := h.DB.Where("token = ? AND status = ?", token, "active").First(&tok).Error; err == nil && !canaryRepeat(token, ip) {
+					h.triggerCanary(tok, ip, "", fmt.Sprintf("canary %s lookup via resolver %s", tok.Type, ip))
+				}
+			}(token, ip)
+		}
+	}()
+}
+
+// canaryRepeat reports whether the token already fired for this source within the last
+// minute and otherwise remembers it. Only tokens that exist are passed in, so made-up
+// ones can not grow the map.
+func canaryRepeat(token, source string) bool {
+	now := time.Now()
+	key := token + "|" + source
+
+	canaryRecentMu.Lock()
+	defer canaryRecentMu.Unlock()
+	if now.Sub(canaryRecentPrune) > time.Minute {
+		for k, last := range canaryRecent {
+			if now.Sub(last) >= time.Minute {
+				delete(canaryRecent, k)
+			}
+		}
+		canaryRecentPrune = now
+	}
+	if last, seen := canaryRecent[key]; seen && now.Sub(last) < time.Minute {
+		return true
+	}
+	canaryRecent[key] = now
+	return false
+}
+
+// buildCanaryDNSResponse answers A queries below zone and returns the label that looks
+// like a token, if any.
+func buildCanaryDNSResponse(msg []byte, zone string, answer net.IP) ([]byte, string) {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(msg)
+	if err != nil || hdr.Response {
+		return nil, ""
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, ""
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(q.Name.String(), "."))
+	inZone := name == zone || strings.HasSuffix(name, "."+zone)
+
+	respHdr := dnsmessage.Header{ID: hdr.ID, Response: true, Authoritative: inZone, RecursionDesired: hdr.RecursionDesired}
+	if !inZone {
+		respHdr.RCode = dnsmessage.RCodeRefused
+	}
+	b := dnsmessage.NewBuilder(nil, respHdr)
+	b.EnableCompression()
+	b.StartQuestions()
+	b.Question(q)
+
+	token := ""
+	if inZone {
+		for _, label := range strings.Split(strings.TrimSuffix(name, zone), ".") {
+			if len(label) == 20 {
+				token = label
+			}
+		}
+		if q.Type == dnsmessage.TypeA {
+			b.StartAnswers()
+			var a dnsmessage.AResource
+			copy(a.A[:], answer)
+			b.AResource(dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 0}, a)
+		}
+	}
+
+	resp, err := b.Finish()
+	if err != nil {
+		return nil, token
+	}
+	return resp, token
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// buildCanaryDocx produces a Word document with an externally linked 1px picture, Word
+// fetches the picture from the callback URL when the document is opened.
+func buildCanaryDocx(url, title string) ([]byte, error) {
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/></Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/></Relationships>`},
+		{"word/_rels/document.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId10" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="` + xmlEscape(url) + `" TargetMode="External"/></Relationships>`},
+		{"word/document.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture"><w:body>` +
+			`<w:p><w:r><w:rPr><w:b/><w:sz w:val="32"/></w:rPr><w:t>` + xmlEscape(title) + `</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>CONFIDENTIAL - internal distribution only.</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:drawing><wp:inline><wp:extent cx="9525" cy="9525"/><wp:docPr id="1" name="logo"/><a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture"><pic:pic><pic:nvPicPr><pic:cNvPr id="1" name="logo.gif"/><pic:cNvPicPr/></pic:nvPicPr><pic:blipFill><a:blip r:link="rId10"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill><pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="9525" cy="9525"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr></pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>` +
+			`<w:sectPr/></w:body></w:document>`},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, part := range parts {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: part.name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func pdfEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+	return r.Replace(s)
+}
+
+// buildCanaryPDF produces a one page PDF whose OpenAction visits the callback URL
+func buildCanaryPDF(url, title string) []byte {
+	content := fmt.Sprintf("BT /F1 18 Tf 72 720 Td (%s) Tj ET\nBT /F1 11 Tf 72 690 Td (CONFIDENTIAL - internal distribution only.) Tj ET\n", pdfEscape(title))
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R /OpenAction 4 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 5 0 R /Resources << /Font << /F1 6 0 R >> >> >>",
+		fmt.Sprintf("<< /S /URI /URI (%s) >>", pdfEscape(url)),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
@@ -0,0 +1,362 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/beevik/ntp"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ntpQueryTimeout = 3 * time.Second
+	// ntpOutlierMin is the smallest deviation from the median offset that counts as an
+	// outlier, servers that only disagree by network jitter are all kept
+	ntpOutlierMin = 50 * time.Millisecond
+	// ntpHistoryDays is how long sync rounds are kept
+	ntpHistoryDays = 30
+)
+
+// ntpConfig is the ntp_config setting
+type ntpConfig struct {
+	Server            string `json:"server"`            // Comma-separated, host or host:port
+	Interval          int    `json:"interval"`          // Minutes between scheduled syncs, 0 turns them off
+	DriftThreshold    int64  `json:"driftThreshold"`    // Milliseconds a probe clock may be off before an alert
+	CorrectTimestamps bool   `json:"correctTimestamps"` // Shift event times sent by probes by the offset of their clock
+}
+
+func (h *Handler) loadNtpConfig() ntpConfig {
+	cfg := ntpConfig{Server: "pool.ntp.org", Interval: 60, DriftThreshold: 2000, CorrectTimestamps: true}
+	var row model.SystemConfig
+	h.DB.Where("key = ?", "ntp_config").Find(&row)
+	if row.Value != "" {
+		json.Unmarshal([]byte(row.Value), &cfg)
+	}
+	if cfg.Interval < 0 {
+		cfg.Interval = 0
+	}
+	if cfg.DriftThreshold <= 0 {
+		cfg.DriftThreshold = 2000
+	}
+	return cfg
+}
+
+func (c ntpConfig) servers() []string {
+	if list := splitList(c.Server); len(list) > 0 {
+		return list
+	}
+	return []string{"pool.ntp.org"}
+}
+
+// ntpSample is the answer of one server in a sync round
+type ntpSample struct {
+	Server   string  `json:"server"`
+	Offset   float64 `json:"offset"` // Seconds
+	RTT      float64 `json:"rtt"`    // Seconds
+	Stratum  uint8   `json:"stratum"`
+	Error    string  `json:"error,omitempty"`
+	Rejected bool    `json:"rejected,omitempty"`
+
+	offset time.Duration
+}
+
+// queryNtpServers asks every server at once, a server that does not answer in time or
+// fails validation is kept with its error
+func queryNtpServers(servers []string) []ntpSample {
+	samples := make([]ntpSample, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server string) {
+			defer wg.Done()
+			sample := ntpSample{Server: server}
+			resp, err := ntp.QueryWithOptions(server, ntp.QueryOptions{Timeout: ntpQueryTimeout})
+			if err == nil {
+				err = resp.Validate()
+			}
+			if err != nil {
+				sample.Error = err.Error()
+			} else {
+				sample.offset = resp.ClockOffset
+				sample.Offset = resp.ClockOffset.Seconds()
+				sample.RTT = resp.RTT.Seconds()
+				sample.Stratum = resp.Stratum
+			}
+			samples[i] = sample
+		}(i, server)
+	}
+	wg.Wait()
+	return samples
+}
+
+func medianDuration(values []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// rejectNtpOutliers marks the answers that are far off the median and returns the median
+// offset of the rest. With fewer than three answers there is no majority to judge by.
+func rejectNtpOutliers(samples []ntpSample) (time.Duration, int) {
+	var offsets []time.Duration
+	for _, s := range samples {
+		if s.Error == "" {
+			offsets = append(offsets, s.offset)
+		}
+	}
+	if len(offsets) == 0 {
+		return 0, 0
+	}
+	median := medianDuration(offsets)
+	if len(offsets) < 3 {
+		return median, len(offsets)
+	}
+
+	deviations := make([]time.Duration, len(offsets))
+	for i, off := range offsets {
+		deviations[i] = (off - median).Abs()
+	}
+	limit := max(3*medianDuration(deviations), ntpOutlierMin)
+
+	var kept []time.Duration
+	for i := range samples {
+		if samples[i].Error != "" {
+			continue
+		}
+		if (samples[i].offset - median).Abs() > limit {
+			samples[i].Rejected = true
+			continue
+		}
+		kept = append(kept, samples[i].offset)
+	}
+	return medianDuration(kept), len(kept)
+}
+
+// syncClock runs one sync round. When no server answers the current offset stays in
+// effect, so the server keeps its last known correction while offline.
+func (h *Handler) syncClock(trigger string) model.TimeSyncLog {
+	cfg := h.loadNtpConfig()
+	samples := queryNtpServers(cfg.servers())
+	offset, used := rejectNtpOutliers(samples)
+
+	var previous model.TimeSyncLog
+	h.DB.Order("id desc").Limit(1).Find(&previous)
+
+	results, _ := json.Marshal(samples)
+	entry := model.TimeSyncLog{
+		Trigger: trigger,
+		Servers: len(samples),
+		Used:    used,
+		Results: string(results),
+	}
+	if used == 0 {
+		var errs []string
+		for _, s := range samples {
+			errs = append(errs, s.Server+": "+s.Error)
+		}
+		entry.Status = "failed"
+		entry.Error = strings.Join(errs, "; ")
+		entry.Offset = time.Duration(atomic.LoadInt64(&globalTimeOffset)).Seconds()
+		log.Printf("NTP sync failed, keeping offset %.3fs: %s", entry.Offset, entry.Error)
+	} else {
+		atomic.StoreInt64(&globalTimeOffset, int64(offset))
+		h.saveConfigValue("time_offset", strconv.FormatInt(int64(offset), 10))
+		h.saveConfigValue("last_sync_time", h.Now().Format(time.RFC3339))
+		entry.Status = "synchronized"
+		entry.Offset = offset.Seconds()
+		log.Printf("NTP sync: offset %.4fs from %d of %d servers", entry.Offset, used, len(samples))
+	}
+	entry.Time = h.Now()
+	h.DB.Create(&entry)
+	h.DB.Where("time < ?", entry.Time.AddDate(0, 0, -ntpHistoryDays)).Delete(&model.TimeSyncLog{})
+
+	// Raise the outage once, not on every failed round
+	if trigger == "schedule" && entry.Status == "failed" && previous.Status != "failed" {
+		servers := strings.Join(cfg.servers(), " ")
+		h.postSystemMessage("msg_ntp_failed_title", fmt.Sprintf("msg_ntp_failed_content|servers:%s,offset:%.3f", servers, entry.Offset), "warning")
+	}
+	return entry
+}
+
+func (h *Handler) saveConfigValue(key, value string) {
+	var cfg model.SystemConfig
+	h.DB.Where("key = ?", key).Find(&cfg)
+	cfg.Key = key
+	cfg.Value = value
+	h.DB.Save(&cfg)
+}
+
+// RunNtpSync syncs the clock at start and then on the interval of the NTP settings
+func (h *Handler) RunNtpSync() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		cfg := h.loadNtpConfig()
+		if cfg.Interval > 0 {
+			var last model.TimeSyncLog
+			h.DB.Order("id desc").Limit(1).Find(&last)
+			if last.ID == 0 || h.Now().Sub(last.Time) >= time.Duration(cfg.Interval)*time.Minute {
+				h.syncClock("schedule")
+			}
+		}
+		<-ticker.C
+	}
+}
+
+func (h *Handler) NtpSync(c *gin.Context) {
+	entry := h.syncClock("manual")
+	var samples []ntpSample
+	json.Unmarshal([]byte(entry.Results), &samples)
+
+	if entry.Status != "synchronized" {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"error":   "Failed to sync with NTP servers: " + entry.Error,
+			"servers": samples,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"offset":     entry.Offset,
+		"remoteTime": h.Now().Format(time.RFC3339),
+		"used":       entry.Used,
+		"servers":    samples,
+	})
+}
+
+// GetNtpHistory lists the latest sync rounds, newest first
+func (h *Handler) GetNtpHistory(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 1000 {
+		limit = 50
+	}
+	var entries []model.TimeSyncLog
+	h.DB.Order("id desc").Limit(limit).Find(&entries)
+	c.JSON(http.StatusOK, entries)
+}
+
+// GetTimeStatus reports the offset of the server clock and the drift of every probe
+func (h *Handler) GetTimeStatus(c *gin.Context) {
+	cfg := h.loadNtpConfig()
+	var last model.TimeSyncLog
+	h.DB.Order("id desc").Limit(1).Find(&last)
+	var lastSuccess model.TimeSyncLog
+	h.DB.Where("status = ?", "synchronized").Order("id desc").Limit(1).Find(&lastSuccess)
+
+	status := "unknown"
+	if last.ID != 0 {
+		status = last.Status
+	}
+	var lastSync *time.Time
+	if lastSuccess.ID != 0 {
+		lastSync = &lastSuccess.Time
+	}
+
+	var nodes []model.NodeStatus
+	h.DB.Select("id", "name", "status", "clock_offset", "clock_checked_at", "clock_drift").Order("id").Find(&nodes)
+	clocks := make([]gin.H, 0, len(nodes))
+	for _, n := range nodes {
+		clocks = append(clocks, gin.H{
+			"id":        n.ID,
+			"name":      n.Name,
+			"status":    n.Status,
+			"offset":    n.ClockOffset,
+			"checkedAt": n.ClockCheckedAt,
+			"drift":     n.ClockDrift,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":         status,
+		"offset":         time.Duration(atomic.LoadInt64(&globalTimeOffset)).Seconds(),
+		"lastSync":       lastSync,
+		"servers":        cfg.servers(),
+		"driftThreshold": cfg.DriftThreshold,
+		"nodes":          clocks,
+	})
+}
+
+// observeNodeClock works out the clock offset of a reporting probe and whether it is past
+// the drift threshold. The report is read a moment after it was sent, so the offset comes
+// out a few milliseconds low. A drifting node has to get back under 90% of the threshold
+// before it counts as recovered, so an offset near the threshold does not flap.
+func (h *Handler) observeNodeClock(node *model.NodeStatus, wasDrifting bool) {
+	now := h.Now()
+	node.ClockOffset = node.Clock.Sub(now).Milliseconds()
+	node.ClockCheckedAt = &now
+
+	threshold := h.loadNtpConfig().DriftThreshold
+	offset := int64(math.Abs(float64(node.ClockOffset)))
+	node.ClockDrift = offset > threshold || (wasDrifting && offset > threshold*9/10)
+}
+
+// raiseClockDrift reports a node whose clock crossed the drift threshold either way
+func (h *Handler) raiseClockDrift(node model.NodeStatus) {
+	if node.ClockDrift {
+		log.Printf("Clock of node %s is off by %dms", node.ID, node.ClockOffset)
+		h.raiseAlert(alert{
+			Title:       "msg_node_clock_drift_title",
+			Content:     fmt.Sprintf("msg_node_clock_drift_content|name:%s,id:%s,offset:%d", node.Name, node.ID, node.ClockOffset),
+			Type:        "system",
+			Severity:    "warning",
+			Node:        node.ID,
+			Fingerprint: "node_clock_drift:" + node.ID,
+		})
+		return
+	}
+	log.Printf("Clock of node %s is back in sync (%dms)", node.ID, node.ClockOffset)
+	h.raiseAlert(alert{
+		Title:       "msg_node_clock_synced_title",
+		Content:     fmt.Sprintf("msg_node_clock_synced_content|name:%s,id:%s,offset:%d", node.Name, node.ID, node.ClockOffset),
+		Type:        "system",
+		Node:        node.ID,
+		Fingerprint: "node_clock_synced:" + node.ID,
+		Resolves:    "node_clock_drift:" + node.ID,
+	})
+}
+
+// probeEventTime turns a time stamped by a probe into server time using the offset seen
+// in the last report of the node. Times from the future are capped at now.
+func (h *Handler) probeEventTime(nodeID string, stamped time.Time) time.Time {
+	now := h.Now()
+	if stamped.IsZero() {
+		return now
+	}
+	stamped = h.probeClockTime(nodeID, stamped)
+	if stamped.After(now) {
+		return now
+	}
+	return stamped
+}
+
+// probeClockTime only removes the clock offset of the node, callers that bound the
+// result themselves need the time before it is capped
+func (h *Handler) probeClockTime(nodeID string, stamped time.Time) time.Time {
+	stamped = stamped.UTC()
+	if nodeID == "" || !h.loadNtpConfig().CorrectTimestamps {
+		return stamped
+	}
+	var node model.NodeStatus
+	h.DB.Select("id", "clock_offset", "clock_checked_at").Where("id = ?", nodeID).Limit(1).Find(&node)
+	if node.ClockCheckedAt != nil {
+		stamped = stamped.Add(-time.Duration(node.ClockOffset) * time.Millisecond)
+	}
+	return stamped
+}
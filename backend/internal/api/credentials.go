@@ -0,0 +1,443 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"backend/internal/model"
+	"backend/internal/ulid"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// credentialDictionaries are well known lists replayed by bots, keyed by name.
+// Entries are "username:password", an empty password is written as "username:".
+var credentialDictionaries = map[string][]string{
+	// Default credential table compiled into the Mirai scanner
+	"mirai": {
+		"root:xc3511", "root:vizxv", "root:admin", "admin:admin", "root:888888", "root:xmhdipc",
+		"root:default", "root:juantech", "root:123456", "root:54321", "support:support", "root:",
+		"admin:password", "root:root", "root:12345", "user:user", "admin:", "root:pass",
+		"admin:admin1234", "root:1111", "admin:smcadmin", "admin:1111", "root:666666", "root:password",
+		"root:1234", "root:klv123", "Administrator:admin", "service:service", "supervisor:supervisor",
+		"guest:guest", "guest:12345", "admin1:password", "administrator:1234", "666666:666666",
+		"888888:888888", "ubnt:ubnt", "root:klv1234", "root:Zte521", "root:hi3518", "root:jvbzd",
+		"root:anko", "root:zlxx.", "root:7ujMko0vizxv", "root:7ujMko0admin", "root:system",
+		"root:ikwb", "root:dreambox", "root:user", "root:realtek", "root:00000000", "admin:1111111",
+		"admin:1234", "admin:12345", "admin:54321", "admin:123456", "admin:7ujMko0admin",
+		"admin:pass", "admin:meinsm", "tech:tech", "mother:fucker",
+	},
+}
+
+// credentialKeyIndex is the unique index over (service, username, password, ip)
+const credentialKeyIndex = "idx_credential_key"
+
+// dictionaryMinMatches is how many distinct pairs of a list one IP must try before it is
+// reported as replaying that list
+const dictionaryMinMatches = 5
+
+// RecordCredential upserts a captured login attempt keyed on (service, username, password, ip).
+// Time defaults to now, a repeat keeps the later of both times.
+func (h *Handler) RecordCredential(attempt model.AccountCredential) (model.AccountCredential, error) {
+	now := attempt.Time
+	if now.IsZero() {
+		now = h.Now()
+	}
+	service, username, password, ip := attempt.Service, attempt.Username, attempt.Password, attempt.IP
+
+	// One statement so that concurrent repeats of a new key cannot both insert it
+	id := ulid.New()
+	err := h.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "service"}, {Name: "username"}, {Name: "password"}, {Name: "ip"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":  gorm.Expr("account_credentials.count + 1"),
+			"time":   gorm.Expr("MAX(account_credentials.time, excluded.time)"),
+			"sensor": gorm.Expr("CASE WHEN excluded.sensor <> '' THEN excluded.sensor ELSE account_credentials.sensor END"),
+		}),
+	}).Create(&model.AccountCredential{
+		ID:       id,
+		Service:  service,
+		Username: username,
+		Password: password,
+		IP:       ip,
+		Count:    1,
+		Time:     now,
+		Sensor:   attempt.Sensor,
+	}).Error
+	if err != nil {
+		return model.AccountCredential{}, err
+	}
+
+	var cred model.AccountCredential
+	if err := h.DB.Where("service = ? AND username = ? AND password = ? AND ip = ?", service, username, password, ip).Take(&cred).Error; err != nil {
+		return cred, err
+	}
+	repeat := cred.ID != id
+	if repeat {
+		h.touchSearchDocument("credential", cred.ID, cred.Time)
+	} else {
+		h.indexSearchDocument(credentialSearchDocument(cred))
+	}
+	// Every attempt goes out, the SIEM counts them itself
+	h.exportEvent(credentialSiemEvent(cred))
+
+	h.checkCanaryKeys(username+" "+password, ip, service+" login")
+	h.checkDecoyBait(service, username, password, ip)
+	h.checkLeakedCredential(service, username, password, ip)
+	return cred, nil
+}
+
+// checkDecoyBait flags decoys whose planted credentials are replayed against a honeypot
+func (h *Handler) checkDecoyBait(service, username, password, ip string) {
+	if password == "" {
+		return
+	}
+	var decoys []model.DecoyLog
+	h.DB.Where("decoy_id = ? AND bait_secret = ? AND status <> ?", "", password, "Removed").Find(&decoys)
+	for _, decoy := range decoys {
+		h.recordDecoyTrigger(decoy, h.Now(), ip, "", "", decoy.Path, fmt.Sprintf("bait credential %s used against %s", username, service))
+	}
+}
+
+// MergeDuplicateCredentials folds rows recorded before the upsert existed into one row per
+// key, then makes the key unique. It runs before AutoMigrate, which cannot create the unique
+// index over repeats. Merged rows leave stale search documents, the index is rebuilt for them.
+func MergeDuplicateCredentials(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&model.AccountCredential{}) {
+		return nil
+	}
+	type dupKey struct {
+		Service, Username, Password, IP string
+		Dups                            int
+	}
+	var dups []dupKey
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.AccountCredential{}).
+			Select("service, username, password, ip, COUNT(*) AS dups").
+			Group("service, username, password, ip").Having("COUNT(*) > 1").Scan(&dups).Error; err != nil {
+			return err
+		}
+		for _, d := range dups {
+			// Only id and count, time may still be a legacy string column
+			var rows []struct {
+				ID    string
+				Count int
+			}
+			if err := tx.Model(&model.AccountCredential{}).Select("id, count").
+				Where("service = ? AND username = ? AND password = ? AND ip = ?", d.Service, d.Username, d.Password, d.IP).
+				Order("time desc").Scan(&rows).Error; err != nil {
+				return err
+			}
+			if len(rows) < 2 {
+				continue
+			}
+			keep := rows[0]
+			total := 0
+			var drop []string
+			for _, r := range rows {
+				total += r.Count
+				if r.ID != keep.ID {
+					drop = append(drop, r.ID)
+				}
+			}
+			if err := tx.Model(&model.AccountCredential{}).Where("id = ?", keep.ID).Update("count", total).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", drop).Delete(&model.AccountCredential{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(dups) > 0 {
+		log.Printf("Merged %d duplicated credential keys", len(dups))
+		if m.HasTable(&model.SystemConfig{}) {
+			db.Where("key = ?", "search_index").Delete(&model.SystemConfig{})
+		}
+	}
+
+	// An index from before the key was unique is left alone by AutoMigrate
+	var unique bool
+	db.Raw(`SELECT "unique" FROM pragma_index_list('account_credentials') WHERE name = ?`, credentialKeyIndex).Scan(&unique)
+	if !unique && m.HasIndex(&model.AccountCredential{}, credentialKeyIndex) {
+		return m.DropIndex(&model.AccountCredential{}, credentialKeyIndex)
+	}
+	return nil
+}
+
+// IngestCredential accepts captured credentials from a sensor posting with its ingest key,
+// one or a batch
+func (h *Handler) IngestCredential(c *gin.Context) {
+	h.ingestBatch(c, "credential", h.acceptCredential)
+}
+
+// credentialScope applies the common service/time filters of the analytics endpoints
+func (h *Handler) credentialScope(c *gin.Context) *gorm.DB {
+	q := h.DB.Model(&model.AccountCredential{})
+	if service := c.Query("service"); service != "" {
+		q = q.Where("service = ?", service)
+	}
+	if from, ok := parseTimeParam(c.Query("from")); ok {
+		q = q.Where("time >= ?", from)
+	}
+	if to, ok := parseTimeParam(c.Query("to")); ok {
+		q = q.Where("time <= ?", to)
+	}
+	return q
+}
+
+type credentialCount struct {
+	Service  string `json:"service,omitempty"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Total    int    `json:"count"`
+}
+
+type credentialReuse struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Services string `json:"services,omitempty"`
+	IPs      int    `json:"ips,omitempty"`
+	Total    int    `json:"count"`
+}
+
+type dictionaryHit struct {
+	Name     string            `json:"name"`
+	Size     int               `json:"size"`
+	Matched  int               `json:"matched"`
+	Coverage float64           `json:"coverage"`
+	IPs      []dictionaryIPHit `json:"ips"`
+}
+
+type dictionaryIPHit struct {
+	IP       string  `json:"ip"`
+	Matched  int     `json:"matched"`
+	Coverage float64 `json:"coverage"`
+}
+
+func (h *Handler) GetCredentialAnalytics(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 || limit > 1000 {
+		limit = 10
+	}
+
+	var topUsernames, topPasswords, topPairs []credentialCount
+	h.credentialScope(c).Select("username, SUM(count) AS total").Group("username").Order("total desc").Limit(limit).Scan(&topUsernames)
+	h.credentialScope(c).Select("password, SUM(count) AS total").Group("password").Order("total desc").Limit(limit).Scan(&topPasswords)
+
+	// Top pairs are ranked within each service
+	var services []string
+	h.credentialScope(c).Distinct("service").Pluck("service", &services)
+	pairsByService := make(map[string][]credentialCount)
+	for _, svc := range services {
+		var pairs []credentialCount
+		h.credentialScope(c).Where("service = ?", svc).
+			Select("service, username, password, SUM(count) AS total").
+			Group("service, username, password").Order("total desc").Limit(limit).Scan(&pairs)
+		pairsByService[svc] = pairs
+		topPairs = append(topPairs, pairs...)
+	}
+	sort.SliceStable(topPairs, func(i, j int) bool { return topPairs[i].Total > topPairs[j].Total })
+	if len(topPairs) > limit {
+		topPairs = topPairs[:limit]
+	}
+
+	// Length and charset distributions are weighted by attempt count
+	var passwords []credentialCount
+	h.credentialScope(c).Select("password, SUM(count) AS total").Group("password").Scan(&passwords)
+	lengths := make(map[int]int)
+	charsets := make(map[string]int)
+	for _, p := range passwords {
+		lengths[len([]rune(p.Password))] += p.Total
+		charsets[passwordCharset(p.Password)] += p.Total
+	}
+	type lengthBucket struct {
+		Length int `json:"length"`
+		Count  int `json:"count"`
+	}
+	lengthDist := []lengthBucket{}
+	for l, n := range lengths {
+		lengthDist = append(lengthDist, lengthBucket{Length: l, Count: n})
+	}
+	sort.Slice(lengthDist, func(i, j int) bool { return lengthDist[i].Length < lengthDist[j].Length })
+
+	// Pairs seen on more than one service or from more than one IP
+	var crossService, crossIP []credentialReuse
+	h.credentialScope(c).Select("username, password, GROUP_CONCAT(DISTINCT service) AS services, SUM(count) AS total").
+		Group("username, password").Having("COUNT(DISTINCT service) > 1").Order("total desc").Limit(limit).Scan(&crossService)
+	h.credentialScope(c).Select("username, password, COUNT(DISTINCT ip) AS ips, SUM(count) AS total").
+		Group("username, password").Having("COUNT(DISTINCT ip) > 1").Order("ips desc, total desc").Limit(limit).Scan(&crossIP)
+
+	c.JSON(http.StatusOK, gin.H{
+		"topUsernames":   topUsernames,
+		"topPasswords":   topPasswords,
+		"topPairs":       topPairs,
+		"pairsByService": pairsByService,
+		"passwordLength": lengthDist,
+		"charsets":       charsets,
+		"reuse": gin.H{
+			"acrossServices": crossService,
+			"acrossIps":      crossIP,
+		},
+		"dictionaries": h.detectDictionaries(c),
+	})
+}
+
+// passwordCharset classifies a password by the character classes it uses
+func passwordCharset(p string) string {
+	if p == "" {
+		return "empty"
+	}
+	var lower, upper, digit, other bool
+	for _, r := range p {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	switch {
+	case other:
+		return "special"
+	case digit && !lower && !upper:
+		return "numeric"
+	case (lower || upper) && !digit:
+		if lower && upper {
+			return "mixedalpha"
+		}
+		if lower {
+			return "loweralpha"
+		}
+		return "upperalpha"
+	default:
+		return "alphanumeric"
+	}
+}
+
+// detectDictionaries reports which known lists are being replayed and by which IPs
+func (h *Handler) detectDictionaries(c *gin.Context) []dictionaryHit {
+	type pairIP struct {
+		Username, Password, IP string
+	}
+	var rows []pairIP
+	h.credentialScope(c).Select("DISTINCT username, password, ip").Scan(&rows)
+
+	hits := []dictionaryHit{}
+	for name, list := range h.credentialDictionaryLists() {
+		entries := make(map[string]bool, len(list))
+		for _, e := range list {
+			entries[e] = true
+		}
+
+		matched := make(map[string]bool)
+		perIP := make(map[string]map[string]bool)
+		for _, r := range rows {
+			key := r.Username + ":" + r.Password
+			if !entries[key] {
+				continue
+			}
+			matched[key] = true
+			if perIP[r.IP] == nil {
+				perIP[r.IP] = make(map[string]bool)
+			}
+			perIP[r.IP][key] = true
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		hit := dictionaryHit{
+			Name:     name,
+			Size:     len(entries),
+			Matched:  len(matched),
+			Coverage: float64(len(matched)) / float64(len(entries)),
+			IPs:      []dictionaryIPHit{},
+		}
+		for ip, keys := range perIP {
+			if len(keys) < dictionaryMinMatches {
+				continue
+			}
+			hit.IPs = append(hit.IPs, dictionaryIPHit{IP: ip, Matched: len(keys), Coverage: float64(len(keys)) / float64(len(entries))})
+		}
+		sort.Slice(hit.IPs, func(i, j int) bool { return hit.IPs[i].Matched > hit.IPs[j].Matched })
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Coverage > hits[j].Coverage })
+	return hits
+}
+
+// credentialDictionaryLists merges the built-in lists with custom ones stored as JSON
+// ({"name": ["user:pass", ...]}) under the "credential_dictionaries" config key
+func (h *Handler) credentialDictionaryLists() map[string][]string {
+	lists := make(map[string][]string, len(credentialDictionaries))
+	for name, list := range credentialDictionaries {
+		lists[name] = list
+	}
+	var cfg model.SystemConfig
+	h.DB.Where("key = ?", "credential_dictionaries").Find(&cfg)
+	if cfg.Value != "" {
+		var custom map[string][]string
+		if err := json.Unmarshal([]byte(cfg.Value), &custom); err == nil {
+			for name, list := range custom {
+				lists[name] = list
+			}
+		}
+	}
+	return lists
+}
+
+// ExportCredentialWordlist writes usernames, passwords or user:pass pairs ordered by frequency
+func (h *Handler) ExportCredentialWordlist(c *gin.Context) {
+	kind := c.DefaultQuery("type", "passwords")
+	minCount, _ := strconv.Atoi(c.DefaultQuery("minCount", "1"))
+
+	var column string
+	switch kind {
+	case "usernames":
+		column = "username"
+	case "passwords":
+		column = "password"
+	case "pairs":
+		column = "username, password"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be usernames, passwords or pairs"})
+		return
+	}
+
+	var rows []credentialCount
+	h.credentialScope(c).Select(column+", SUM(count) AS total").Group(column).
+		Having("SUM(count) >= ?", minCount).Order("total desc").Scan(&rows)
+
+	var sb strings.Builder
+	for _, r := range rows {
+		switch kind {
+		case "usernames":
+			sb.WriteString(r.Username)
+		case "passwords":
+			sb.WriteString(r.Password)
+		default:
+			sb.WriteString(r.Username + ":" + r.Password)
+		}
+		sb.WriteByte('\n')
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "prts_"+kind+".txt"))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(sb.String()))
+}
@@ -0,0 +1,459 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"backend/internal/model"
+	"backend/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+)
+
+// Decoy types understood by the probe
+const (
+	DecoyTypeFile            = "File"
+	DecoyTypeSSHKey          = "SSHKey"
+	DecoyTypeBrowserPassword = "BrowserPassword"
+	DecoyTypeAWSCredentials  = "AWSCredentials"
+	DecoyTypeRegistry        = "Registry"
+	DecoyTypeProcess         = "Process"
+	DecoyTypeDocument        = "Document"
+
+	// DecoyTypeCanaryToken rows back standalone canary tokens, nothing is planted on a node
+	DecoyTypeCanaryToken = "CanaryToken"
+)
+
+var decoyDefaultNames = map[string]string{
+	DecoyTypeFile:            "passwords.txt",
+	DecoyTypeSSHKey:          "id_rsa_backup",
+	DecoyTypeBrowserPassword: "chrome_passwords.csv",
+	DecoyTypeAWSCredentials:  "credentials",
+	DecoyTypeRegistry:        "prod-db",
+	DecoyTypeProcess:         "backup_service.exe",
+	DecoyTypeDocument:        "Q3_salary_review.docx",
+}
+
+// DecoyCommand is the payload of a DEPLOY_DECOY / REMOVE_DECOY message sent to a probe.
+// For Registry decoys Path is the key and Values holds the value names and data.
+// Binary content (documents) is sent base64 encoded with Encoding set to "base64".
+type DecoyCommand struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Path     string            `json:"path,omitempty"`
+	Content  string            `json:"content,omitempty"`
+	Encoding string            `json:"encoding,omitempty"`
+	Values   map[string]string `json:"values,omitempty"`
+}
+
+// decoyEvent is reported by the probe whenever a planted breadcrumb is touched.
+type decoyEvent struct {
+	DecoyID  string `json:"decoyId"`
+	Event    string `json:"event"` // accessed, modified, deleted, connected, terminated
+	Path     string `json:"path"`
+	Process  string `json:"process"`
+	PID      int32  `json:"pid"`
+	SourceIP string `json:"sourceIp"`
+	Detail   string `json:"detail"`
+	Time     string `json:"time"`
+}
+
+type decoyDeployResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // Active, Failed, Removed
+	Path   string `json:"path"`
+	Error  string `json:"error"`
+}
+
+func (h *Handler) DeployDecoy(c *gin.Context) {
+	var req struct {
+		Name   string `json:"name"`
+		Type   string `json:"type"`
+		NodeID string `json:"nodeId"`
+		Path   string `json:"path"`
+		Canary bool   `json:"canary"` // Embed canary tokens into the planted content
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if _, ok := decoyDefaultNames[req.Type]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported decoy type"})
+		return
+	}
+	if req.NodeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nodeId is required"})
+		return
+	}
+
+	var node model.NodeStatus
+	if err := h.DB.Where("id = ?", req.NodeID).First(&node).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+	if req.Type == DecoyTypeRegistry && node.OS != "windows" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Registry decoys require a Windows node"})
+		return
+	}
+	if req.Name == "" {
+		req.Name = decoyDefaultNames[req.Type]
+	}
+
+	now := h.Now()
+	decoy := model.DecoyLog{
+		ID:         fmt.Sprintf("DL-%d", time.Now().UnixNano()),
+		DecoyName:  req.Name,
+		Type:       req.Type,
+		Status:     "Deploying",
+		Device:     node.Name,
+		Node:       node.ID,
+		Path:       req.Path,
+		Time:       now,
+		DeployTime: now,
+	}
+
+	// Documents are only useful as beacons, they always carry a token
+	if req.Canary || req.Type == DecoyTypeDocument {
+		cfg := h.getCanaryConfig()
+		if err := h.mintDecoyTokens(&decoy, h.canaryBaseURL(c, cfg), cfg); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mint canary tokens: " + err.Error()})
+			return
+		}
+	}
+
+	cmd, err := h.buildDecoyCommand(&decoy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate decoy content"})
+		return
+	}
+
+	if err := h.DB.Create(&decoy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save decoy"})
+		return
+	}
+
+	if !h.sendDecoyCommand(node.ID, "DEPLOY_DECOY", cmd) {
+		h.DB.Model(&decoy).Updates(map[string]interface{}{"status": "Failed", "detail": "node not connected"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "node not connected"})
+		return
+	}
+	h.exportEvent(decoySiemEvent(decoy))
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "decoy": decoy})
+}
+
+func (h *Handler) RemoveDecoy(c *gin.Context) {
+	id := c.Param("id")
+	var decoy model.DecoyLog
+	if err := h.DB.Where("id = ? AND decoy_id = ?", id, "").First(&decoy).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Decoy not found"})
+		return
+	}
+
+	h.sendDecoyCommand(decoy.Node, "REMOVE_DECOY", DecoyCommand{ID: decoy.ID, Name: decoy.DecoyName, Type: decoy.Type, Path: decoy.Path})
+	h.DB.Model(&decoy).Update("status", "Removed")
+	h.DB.Model(&model.CanaryToken{}).Where("decoy_id = ?", decoy.ID).Update("status", "disabled")
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *Handler) sendDecoyCommand(nodeID, msgType string, cmd DecoyCommand) bool {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type": msgType,
+		"data": cmd,
+	})
+	return h.Hub.SendToNode(nodeID, msg)
+}
+
+// redeployDecoys re-sends every live decoy of a node, used when a probe comes back online
+// since probes keep their watch list in memory only.
+func (h *Handler) redeployDecoys(nodeID string) {
+	var decoys []model.DecoyLog
+	h.DB.Where("node = ? AND decoy_id = ? AND status IN ?", nodeID, "", []string{"Deploying", "Active", "Compromised"}).Find(&decoys)
+	for i := range decoys {
+		cmd, err := h.buildDecoyCommand(&decoys[i])
+		if err != nil {
+			continue
+		}
+		h.DB.Model(&decoys[i]).Updates(map[string]interface{}{
+			"bait_user":   decoys[i].BaitUser,
+			"bait_secret": decoys[i].BaitSecret,
+		})
+		h.sendDecoyCommand(nodeID, "DEPLOY_DECOY", cmd)
+	}
+}
+
+func (h *Handler) handleDecoyDeployed(client *websocket.Client, data json.RawMessage) {
+	var res decoyDeployResult
+	if err := json.Unmarshal(data, &res); err != nil || res.ID == "" {
+		return
+	}
+
+	updates := map[string]interface{}{"detail": res.Error}
+	if res.Path != "" {
+		updates["path"] = res.Path
+	}
+	// Only the node a decoy was deployed to may report on it, and only on the deployment row
+	var decoy model.DecoyLog
+	if err := h.DB.Where("id = ? AND decoy_id = ?", res.ID, "").First(&decoy).Error; err != nil {
+		return
+	}
+	if decoy.Node != client.NodeID {
+		log.Printf("Ignoring decoy status for %s from %s, it belongs to %s", decoy.ID, client.NodeID, decoy.Node)
+		return
+	}
+	// A redeploy must not clear an earlier compromise
+	if decoy.Status != "Compromised" || res.Status != "Active" {
+		updates["status"] = res.Status
+	}
+	h.DB.Model(&decoy).Updates(updates)
+	h.DB.First(&decoy, "id = ?", res.ID)
+
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type": "DECOY_UPDATE",
+		"data": decoy,
+	})
+	h.Hub.Broadcast(msg)
+}
+
+func (h *Handler) handleDecoyEvent(client *websocket.Client, data json.RawMessage) {
+	var ev decoyEvent
+	if err := json.Unmarshal(data, &ev); err != nil || ev.DecoyID == "" {
+		return
+	}
+
+	var decoy model.DecoyLog
+	if err := h.DB.Where("id = ? AND decoy_id = ?", ev.DecoyID, "").First(&decoy).Error; err != nil {
+		log.Printf("Decoy event for unknown decoy %s from %s", ev.DecoyID, client.NodeID)
+		return
+	}
+	if decoy.Node != client.NodeID {
+		log.Printf("Ignoring decoy event for %s from %s, it belongs to %s", decoy.ID, client.NodeID, decoy.Node)
+		return
+	}
+
+	process := ev.Process
+	if ev.PID > 0 {
+		process = fmt.Sprintf("%s (pid %d)", ev.Process, ev.PID)
+	}
+	path := ev.Path
+	if path == "" {
+		path = decoy.Path
+	}
+
+	// The probe stamps the event with its own clock
+	stamped, _ := time.Parse(time.RFC3339Nano, ev.Time)
+	at := h.probeEventTime(client.NodeID, stamped)
+
+	h.recordDecoyTrigger(decoy, at, ev.SourceIP, process, "", path, strings.TrimSpace(ev.Event+" "+ev.Detail))
+}
+
+// recordDecoyTrigger stores a Compromised DecoyLog for the decoy triggered at the given time,
+// flags the deployment itself and notifies the frontend.
+func (h *Handler) recordDecoyTrigger(decoy model.DecoyLog, now time.Time, sourceIP, process, userAgent, path, detail string) model.DecoyLog {
+	entry := model.DecoyLog{
+		ID:         fmt.Sprintf("DL-%d", time.Now().UnixNano()),
+		DecoyID:    decoy.ID,
+		Type:       decoy.Type,
+		Status:     "Compromised",
+		Device:     decoy.Device,
+		SourceIP:   sourceIP,
+		Time:       now,
+		Result:     "Pending",
+		DecoyName:  decoy.DecoyName,
+		DeployTime: decoy.DeployTime,
+		Node:       decoy.Node,
+		Path:       path,
+		Process:    process,
+		Detail:     detail,
+		UserAgent:  userAgent,
+	}
+	h.DB.Create(&entry)
+	h.exportEvent(decoySiemEvent(entry))
+
+	h.DB.Model(&decoy).Updates(map[string]interface{}{
+		"status":    "Compromised",
+		"source_ip": sourceIP,
+		"time":      now,
+		"result":    "Pending",
+		"process":   process,
+	})
+
+	eventMsg, _ := json.Marshal(map[string]interface{}{
+		"type": "DECOY_EVENT",
+		"data": entry,
+	})
+	h.Hub.Broadcast(eventMsg)
+
+	h.raiseAlert(alert{
+		Title:    "msg_decoy_compromised_title",
+		Content:  fmt.Sprintf("msg_decoy_compromised_content|name:%s,node:%s,process:%s,ip:%s", decoy.DecoyName, decoy.Device, process, sourceIP),
+		Type:     "security",
+		Severity: "critical",
+		Node:     decoy.Node,
+	})
+
+	log.Printf("Decoy %s (%s) on %s compromised: %s", decoy.ID, decoy.DecoyName, decoy.Node, detail)
+	return entry
+}
+
+// buildDecoyCommand generates the breadcrumb content for a decoy. Bait credentials are
+// generated once and kept on the deployment row so redeploys plant the same secrets,
+// canary tokens minted for the decoy are woven into the content.
+func (h *Handler) buildDecoyCommand(decoy *model.DecoyLog) (DecoyCommand, error) {
+	cmd := DecoyCommand{ID: decoy.ID, Name: decoy.DecoyName, Type: decoy.Type, Path: decoy.Path}
+	tokens := h.decoyTokens(decoy.ID)
+
+	if decoy.BaitUser == "" {
+		decoy.BaitUser = randomChoice([]string{"svc_backup", "admin_ops", "deploy", "dbadmin", "it.support"})
+	}
+
+	switch decoy.Type {
+	case DecoyTypeFile:
+		if decoy.BaitSecret == "" {
+			decoy.BaitSecret = randomString(14, passwordAlphabet)
+		}
+		cmd.Content = fmt.Sprintf("# server accounts - keep private\r\n\r\nprod-db-01   %s / %s\r\nvpn gateway  %s / %s\r\nnas backup   admin / %s\r\n",
+			decoy.BaitUser, decoy.BaitSecret, decoy.BaitUser, decoy.BaitSecret, decoy.BaitSecret)
+		if tok, ok := tokens[CanaryTypeHTTP]; ok {
+			cmd.Content += fmt.Sprintf("wiki         %s / %s  %s\r\n", decoy.BaitUser, decoy.BaitSecret, tok.URL)
+		}
+
+	case DecoyTypeSSHKey:
+		// The private key is never stored, so every (re)deploy plants a fresh one
+		host := "prod-db-01"
+		if tok, ok := tokens[CanaryTypeDNS]; ok {
+			host = tok.Hostname
+		}
+		key, fingerprint, err := generateSSHKey(decoy.BaitUser + "@" + host)
+		if err != nil {
+			return cmd, err
+		}
+		decoy.BaitSecret = fingerprint
+		cmd.Content = key
+
+	case DecoyTypeBrowserPassword:
+		if decoy.BaitSecret == "" {
+			decoy.BaitSecret = randomString(12, passwordAlphabet)
+		}
+		intranet := "https://intranet.corp.local/login"
+		if tok, ok := tokens[CanaryTypeHTTP]; ok {
+			intranet = tok.URL
+		}
+		cmd.Content = fmt.Sprintf("name,url,username,password\nintranet,%s,%s,%s\nvpn,https://vpn.corp.local/,%s,%s\n",
+			intranet, decoy.BaitUser, decoy.BaitSecret, decoy.BaitUser, decoy.BaitSecret)
+
+	case DecoyTypeAWSCredentials:
+		if tok, ok := tokens[CanaryTypeAWS]; ok {
+			decoy.BaitUser = tok.AccessKeyID
+			decoy.BaitSecret = tok.SecretKey
+		} else if decoy.BaitSecret == "" {
+			decoy.BaitSecret = randomString(40, awsSecretAlphabet)
+			decoy.BaitUser = "AKIA" + randomString(16, awsKeyIDAlphabet)
+		}
+		cmd.Content = fmt.Sprintf("[default]\naws_access_key_id = %s\naws_secret_access_key = %s\nregion = us-east-1\n",
+			decoy.BaitUser, decoy.BaitSecret)
+		if tok, ok := tokens[CanaryTypeAWS]; ok {
+			// Use of the key is seen when it is replayed against the honeypots or this endpoint
+			cmd.Content += fmt.Sprintf("\n[backup]\naws_access_key_id = %s\naws_secret_access_key = %s\n# s3 compatible storage: %s\n",
+				decoy.BaitUser, decoy.BaitSecret, tok.URL)
+		}
+
+	case DecoyTypeRegistry:
+		if decoy.BaitSecret == "" {
+			decoy.BaitSecret = randomString(12, passwordAlphabet)
+		}
+		if cmd.Path == "" {
+			cmd.Path = `HKCU\Software\SimonTatham\PuTTY\Sessions\` + decoy.DecoyName
+		}
+		host := "10.20.1.15"
+		if tok, ok := tokens[CanaryTypeDNS]; ok {
+			host = tok.Hostname
+		}
+		cmd.Values = map[string]string{
+			"HostName": host,
+			"UserName": decoy.BaitUser,
+			"Password": decoy.BaitSecret,
+		}
+
+	case DecoyTypeProcess:
+		// The probe spawns an idle copy of itself under the decoy name
+
+	case DecoyTypeDocument:
+		title := strings.TrimSuffix(decoy.DecoyName, filepath.Ext(decoy.DecoyName))
+		var data []byte
+		if tok, ok := tokens[CanaryTypePDF]; ok {
+			data = buildCanaryPDF(tok.URL, title)
+		} else if tok, ok := tokens[CanaryTypeDocx]; ok {
+			var err error
+			if data, err = buildCanaryDocx(tok.URL, title); err != nil {
+				return cmd, err
+			}
+		} else {
+			return cmd, fmt.Errorf("document decoy %s has no canary token", decoy.ID)
+		}
+		cmd.Content = base64.StdEncoding.EncodeToString(data)
+		cmd.Encoding = "base64"
+
+	default:
+		return cmd, fmt.Errorf("unsupported decoy type %q", decoy.Type)
+	}
+
+	return cmd, nil
+}
+
+const (
+	passwordAlphabet  = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789!@#$%"
+	awsKeyIDAlphabet  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	awsSecretAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789+/"
+)
+
+func randomString(n int, alphabet string) string {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			idx = big.NewInt(int64(i % len(alphabet)))
+		}
+		b[i] = alphabet[idx.Int64()]
+	}
+	return string(b)
+}
+
+func randomChoice(options []string) string {
+	idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(options))))
+	if err != nil {
+		return options[0]
+	}
+	return options[idx.Int64()]
+}
+
+// generateSSHKey returns an OpenSSH formatted ed25519 private key and the SHA256 fingerprint
+// of its public half.
+func generateSSHKey(comment string) (string, string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	block, err := ssh.MarshalPrivateKey(priv, comment)
+	if err != nil {
+		return "", "", err
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return "", "", err
+	}
+	return string(pem.EncodeToMemory(block)), ssh.FingerprintSHA256(sshPub), nil
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"backend/internal/model"
+)
+
+// ecsVersion is the Elastic Common Schema release the documents follow
+const ecsVersion = "8.11.0"
+
+// ecsCategories are event.category and event.type per kind
+var ecsCategories = map[string][2][]string{
+	"attack":     {{"intrusion_detection", "network"}, {"info"}},
+	"scan":       {{"network", "intrusion_detection"}, {"connection"}},
+	"credential": {{"authentication", "intrusion_detection"}, {"start"}},
+	"decoy":      {{"intrusion_detection"}, {"indicator"}},
+	"sample":     {{"malware", "file"}, {"info"}},
+	"login":      {{"authentication"}, {"start"}},
+}
+
+type ecsDoc map[string]interface{}
+
+// set writes a value at a dotted path, empty strings are left out
+func (d ecsDoc) set(path string, value interface{}) {
+	if s, ok := value.(string); ok && s == "" {
+		return
+	}
+	keys := strings.Split(path, ".")
+	m := d
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(ecsDoc)
+		if !ok {
+			next = ecsDoc{}
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = value
+}
+
+// ecsDocument maps an event onto ECS fields. The stored row goes along under prts.<kind>
+// for everything ECS has no field for.
+func ecsDocument(ev siemEvent) ecsDoc {
+	doc := ecsDoc{}
+	doc.set("@timestamp", ev.Time.UTC().Format(time.RFC3339Nano))
+	doc.set("ecs.version", ecsVersion)
+	doc.set("message", ev.Name)
+	doc.set("event.id", ev.ID)
+	doc.set("event.kind", "alert")
+	doc.set("event.category", ecsCategories[ev.Kind][0])
+	doc.set("event.type", ecsCategories[ev.Kind][1])
+	doc.set("event.module", siemAppName)
+	doc.set("event.dataset", siemAppName+"."+ev.Kind)
+	doc.set("event.code", ev.Class)
+	doc.set("event.severity", siemSeverities[ev.Severity].cef)
+	doc.set("log.level", ev.Severity)
+	doc.set("observer.vendor", siemVendor)
+	doc.set("observer.product", siemProduct)
+	doc.set("observer.type", "honeypot")
+	doc.set("observer.name", ev.Node)
+	if addr, err := netip.ParseAddr(ev.SourceIP); err == nil {
+		doc.set("source.ip", addr.Unmap().String())
+	}
+
+	switch r := ev.Record.(type) {
+	case model.AttackLog:
+		doc.set("network.protocol", strings.ToLower(r.Method))
+		doc.set("event.action", r.Status)
+		doc.set("source.geo.name", r.Location)
+	case model.ScanLog:
+		doc.set("network.transport", strings.ToLower(r.Type))
+		doc.set("source.geo.name", r.Location)
+	case model.AccountCredential:
+		doc.set("event.outcome", "failure")
+		doc.set("network.protocol", strings.ToLower(r.Service))
+		doc.set("user.name", r.Username)
+	case model.DecoyLog:
+		doc.set("event.action", strings.ToLower(r.Status))
+		doc.set("host.name", r.Device)
+		doc.set("file.path", r.Path)
+		doc.set("process.name", r.Process)
+		doc.set("user.name", r.BaitUser)
+		doc.set("user_agent.original", r.UserAgent)
+	case model.SampleLog:
+		doc.set("file.name", r.FileName)
+		doc.set("file.hash.sha256", strings.ToLower(r.SHA256))
+		doc.set("event.action", r.Status)
+	case model.LoginLog:
+		doc.set("event.kind", "event")
+		doc.set("event.outcome", map[bool]string{true: "success", false: "failure"}[r.Status == "success"])
+		doc.set("user.name", r.Username)
+		doc.set("user_agent.original", r.Device)
+	}
+	doc.set(siemAppName+"."+ev.Kind, ev.Record)
+	return doc
+}
+
+// indexName is the daily index of an event, the day is that of the event in UTC so a
+// backfill lands next to the live documents
+func indexName(prefix, kind string, t time.Time) string {
+	return fmt.Sprintf("%s-%s-%s", prefix, kind, t.UTC().Format("2006.01.02"))
+}
+
+// bulkRecord renders an event as the action and source lines of a bulk request. The
+// event ID is the document ID, sending it again replaces rather than duplicates it.
+func bulkRecord(prefix string, ev siemEvent) ([]byte, error) {
+	action, _ := json.Marshal(map[string]interface{}{
+		"index": map[string]string{"_index": indexName(prefix, ev.Kind, ev.Time), "_id": ev.ID},
+	})
+	source, err := json.Marshal(ecsDocument(ev))
+	if err != nil {
+		return nil, err
+	}
+	rec := make([]byte, 0, len(action)+len(source)+2)
+	rec = append(append(rec, action...), '\n')
+	rec = append(append(rec, source...), '\n')
+	return rec, nil
+}
+
+// indexTemplate is the composable template of the prefix. Strings outside the mapped
+// ECS fields are keywords, and dates in them are not guessed, so a payload that happens
+// to look like a date can not break the mapping of later documents.
+func indexTemplate(prefix string) map[string]interface{} {
+	keyword := map[string]interface{}{"type": "keyword", "ignore_above": 1024}
+	props := func(fields ...string) map[string]interface{} {
+		m := map[string]interface{}{}
+		for _, f := range fields {
+			m[f] = keyword
+		}
+		return map[string]interface{}{"properties": m}
+	}
+	with := func(base map[string]interface{}, key string, value interface{}) map[string]interface{} {
+		base["properties"].(map[string]interface{})[key] = value
+		return base
+	}
+	return map[string]interface{}{
+		"index_patterns": []string{prefix + "-*"},
+		"priority":       200,
+		"_meta":          map[string]interface{}{"managed_by": siemAppName, "ecs_version": ecsVersion},
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{"number_of_shards": 1},
+			"mappings": map[string]interface{}{
+				"date_detection": false,
+				"dynamic_templates": []interface{}{
+					map[string]interface{}{"strings_as_keyword": map[string]interface{}{
+						"match_mapping_type": "string",
+						"mapping":            keyword,
+					}},
+				},
+				"properties": map[string]interface{}{
+					"@timestamp": map[string]interface{}{"type": "date"},
+					"message":    map[string]interface{}{"type": "text"},
+					"ecs":        props("version"),
+					"event": with(props("id", "kind", "category", "type", "module", "dataset", "code", "action", "outcome"),
+						"severity", map[string]interface{}{"type": "long"}),
+					"log":      props("level"),
+					"observer": props("vendor", "product", "type", "name"),
+					"source": map[string]interface{}{"properties": map[string]interface{}{
+						"ip":  map[string]interface{}{"type": "ip"},
+						"geo": props("name"),
+					}},
+					"network":    props("protocol", "transport"),
+					"user":       props("name"),
+					"user_agent": props("original"),
+					"host":       props("name"),
+					"process":    props("name"),
+					"file":       with(props("name", "path"), "hash", props("sha256")),
+					siemAppName:  map[string]interface{}{"type": "object"},
+				},
+			},
+		},
+	}
+}
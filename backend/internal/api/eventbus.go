@@ -0,0 +1,607 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/bus"
+	"backend/internal/metrics"
+	"backend/internal/model"
+	"backend/internal/spool"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventBusSpoolDir holds one spool per output, named by its ID
+const eventBusSpoolDir = "spool/eventbus"
+
+const (
+	eventBusBatchSize = 500
+	// eventBusMaxBatchBytes stays under the 1MB message limit Kafka and NATS default to
+	eventBusMaxBatchBytes = 900 << 10
+	eventBusTimeout       = 10 * time.Second
+	eventBusMaxBackoff    = time.Minute
+)
+
+// eventBusKinds are the event types an output filters on, node status changes come on
+// top of the records the SIEM outputs take
+var eventBusKinds = append(slices.Clone(siemKinds), "node")
+
+var (
+	kafkaTopicPattern  = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,249}$`)
+	natsSubjectPattern = regexp.MustCompile(`^[^\s*>.]+(\.[^\s*>.]+)*$`)
+)
+
+// eventBusOutput publishes the spool of one output to its broker
+type eventBusOutput struct {
+	out       model.EventBusOutput
+	publisher bus.Publisher // Only touched by the run loop
+	spool     *spool.Spool
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+
+	queued, published, rejected, failures, spoolErrors atomic.Uint64
+
+	mu              sync.Mutex
+	connected       bool
+	lastError       string
+	lastErrorAt     *time.Time
+	lastPublishedAt *time.Time
+}
+
+// eventBusOutputs are the running outputs by ID, disabled outputs have none
+var eventBusOutputs = struct {
+	sync.RWMutex
+	m map[string]*eventBusOutput
+}{m: map[string]*eventBusOutput{}}
+
+// StartEventBusOutputs opens the spool of every enabled output and starts publishing,
+// messages queued before a restart go out first
+func (h *Handler) StartEventBusOutputs() {
+	var outs []model.EventBusOutput
+	h.DB.Find(&outs)
+	for _, out := range outs {
+		if out.Enabled {
+			if err := startEventBusOutput(out); err != nil {
+				log.Printf("Event bus: failed to start %s: %v", out.Name, err)
+			}
+		}
+	}
+}
+
+func startEventBusOutput(out model.EventBusOutput) error {
+	publisher, err := newBusPublisher(out)
+	if err != nil {
+		return err
+	}
+	sp, err := spool.Open(filepath.Join(eventBusSpoolDir, out.ID), int64(out.MaxQueueMB)<<20)
+	if err != nil {
+		return err
+	}
+	o := &eventBusOutput{
+		out: out, publisher: publisher, spool: sp,
+		wake: make(chan struct{}, 1), stop: make(chan struct{}), done: make(chan struct{}),
+	}
+	eventBusOutputs.Lock()
+	eventBusOutputs.m[out.ID] = o
+	eventBusOutputs.Unlock()
+	go o.run()
+	return nil
+}
+
+// stopEventBusOutput ends an output, the spool is kept unless it is removed
+func stopEventBusOutput(id string, remove bool) {
+	eventBusOutputs.Lock()
+	o := eventBusOutputs.m[id]
+	delete(eventBusOutputs.m, id)
+	eventBusOutputs.Unlock()
+	if o == nil {
+		if remove {
+			os.RemoveAll(filepath.Join(eventBusSpoolDir, id))
+		}
+		return
+	}
+	close(o.stop)
+	<-o.done
+	if remove {
+		o.spool.Remove()
+	} else {
+		o.spool.Close()
+	}
+}
+
+func newBusPublisher(out model.EventBusOutput) (bus.Publisher, error) {
+	cfg := bus.Config{
+		Servers:  splitList(out.Servers),
+		Username: out.Username, Password: out.Password, Token: out.Token,
+		ClientID: siemAppName, Timeout: eventBusTimeout,
+	}
+	if out.TLS {
+		tlsCfg, err := outputTLSConfig(out.CACert, out.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLS = tlsCfg
+	}
+	return bus.New(out.Type, cfg, out.JetStream)
+}
+
+// nodeBusEvent is a probe going online or offline, only the event bus takes these
+func nodeBusEvent(node model.NodeStatus, at time.Time) siemEvent {
+	severity, name := "info", "Node online"
+	if node.Status != "online" {
+		severity, name = "medium", "Node offline"
+	}
+	// The traffic history is chart data of the dashboard
+	node.TrafficHistory = ""
+	return siemEvent{
+		Kind: "node", ID: node.ID, Class: "node:" + node.Status, Name: name,
+		Severity: severity, Time: at, Node: node.ID,
+		Record: node,
+	}
+}
+
+// publishNodeChange hands a status change of a probe to the event bus outputs
+func (h *Handler) publishNodeChange(node model.NodeStatus) {
+	forwardEventBus(nodeBusEvent(node, h.Now()))
+}
+
+// busMessage wraps an event in the versioned envelope. Messages are keyed by source IP so
+// the events of one attacker stay in order on one partition, node changes by the node.
+// The message ID is new for every message, records such as credentials are sent again
+// under their ID when they change.
+func busMessage(out model.EventBusOutput, ev siemEvent) (bus.Message, error) {
+	data, err := json.Marshal(ev.Record)
+	if err != nil {
+		return bus.Message{}, err
+	}
+	envelope := bus.Event{
+		Schema: bus.Schema, ID: ev.ID, Type: ev.Kind, Class: ev.Class, Name: ev.Name,
+		Severity: ev.Severity, Time: ev.Time.UTC(), SourceIP: ev.SourceIP, Node: ev.Node,
+		Data: data,
+	}
+	value, contentType, err := envelope.Encode(out.Encoding == "protobuf")
+	if err != nil {
+		return bus.Message{}, err
+	}
+	msg := bus.Message{
+		ID:    fmt.Sprintf("%s-%s-%d", ev.Kind, ev.ID, time.Now().UnixNano()),
+		Topic: strings.ReplaceAll(out.Topic, "{type}", ev.Kind),
+		Value: value,
+		Time:  ev.Time,
+	}
+	if addr, err := netip.ParseAddr(ev.SourceIP); err == nil {
+		msg.Key = []byte(addr.Unmap().String())
+	} else if ev.Node != "" {
+		msg.Key = []byte(ev.Node)
+	}
+	msg.Headers = []bus.Header{
+		{Key: "content-type", Value: contentType},
+		{Key: "prts-schema", Value: bus.Schema},
+		{Key: "prts-message-id", Value: msg.ID},
+		{Key: "prts-event-id", Value: ev.ID},
+		{Key: "prts-event-type", Value: ev.Kind},
+		{Key: "prts-node-id", Value: ev.Node},
+	}
+	return msg, nil
+}
+
+// forwardEventBus queues an event for every output whose filter takes it
+func forwardEventBus(ev siemEvent) {
+	eventBusOutputs.RLock()
+	defer eventBusOutputs.RUnlock()
+	for _, o := range eventBusOutputs.m {
+		if !ev.matches(o.out.Kinds, o.out.MinSeverity) {
+			continue
+		}
+		msg, err := busMessage(o.out, ev)
+		var rec []byte
+		if err == nil {
+			rec, err = json.Marshal(msg)
+		}
+		if err == nil {
+			err = o.spool.Append(rec)
+		}
+		if err != nil {
+			o.spoolErrors.Add(1)
+			log.Printf("Event bus: failed to queue %s %s for %s: %v", ev.Kind, ev.ID, o.out.Name, err)
+			continue
+		}
+		o.queued.Add(1)
+		select {
+		case o.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run publishes what is queued and waits for more. The spool only moves on once the
+// broker has taken the batch, a failed batch is sent again whole, so consumers see every
+// message at least once and can drop repeats by the prts-message-id header.
+func (o *eventBusOutput) run() {
+	defer close(o.done)
+	defer o.publisher.Close()
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	backoff := time.Second
+	for {
+		recs, pos, err := o.spool.ReadBytes(eventBusBatchSize, eventBusMaxBatchBytes)
+		if err == nil && len(recs) > 0 {
+			err = o.publish(recs)
+			if err == nil {
+				o.spool.Commit(pos, len(recs))
+				backoff = time.Second
+				continue
+			}
+		}
+		if err != nil {
+			o.failures.Add(1)
+			o.publisher.Close()
+			o.fail(err)
+			o.mu.Lock()
+			o.connected = false
+			o.mu.Unlock()
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-o.stop:
+				timer.Stop()
+				return
+			}
+			backoff = min(backoff*2, eventBusMaxBackoff)
+			continue
+		}
+		select {
+		case <-ticker.C:
+		case <-o.wake:
+		case <-o.stop:
+			return
+		}
+	}
+}
+
+func (o *eventBusOutput) publish(recs [][]byte) error {
+	msgs := make([]bus.Message, 0, len(recs))
+	for _, rec := range recs {
+		var msg bus.Message
+		if err := json.Unmarshal(rec, &msg); err != nil {
+			// Not a message this version wrote, it could never be sent
+			o.rejected.Add(1)
+			o.fail(fmt.Errorf("dropping an unreadable queued message: %w", err))
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	rejected, err := o.publisher.Publish(msgs)
+	if err != nil {
+		return err
+	}
+	for _, r := range rejected {
+		o.fail(fmt.Errorf("%s refused %s: %s", o.out.Type, msgs[r.Index].ID, r.Reason))
+	}
+	o.rejected.Add(uint64(len(rejected)))
+	o.published.Add(uint64(len(msgs) - len(rejected)))
+	now := time.Now().UTC()
+	o.mu.Lock()
+	o.connected, o.lastPublishedAt = true, &now
+	o.mu.Unlock()
+	return nil
+}
+
+func (o *eventBusOutput) fail(err error) {
+	now := time.Now().UTC()
+	o.mu.Lock()
+	o.lastError, o.lastErrorAt = err.Error(), &now
+	o.mu.Unlock()
+	log.Printf("Event bus: %s: %v", o.out.Name, err)
+}
+
+// normalizeEventBusOutput validates an output and fills in the defaults
+func normalizeEventBusOutput(out *model.EventBusOutput) error {
+	out.Name = strings.TrimSpace(out.Name)
+	if out.Name == "" {
+		return errors.New("name is required")
+	}
+	if out.Type != "kafka" && out.Type != "nats" {
+		return errors.New("type must be kafka or nats")
+	}
+	servers := splitList(out.Servers)
+	if len(servers) == 0 {
+		return errors.New("servers is required")
+	}
+	for _, server := range servers {
+		if err := bus.CheckServer(out.Type, server); err != nil {
+			return fmt.Errorf("server %s: %v", server, err)
+		}
+	}
+	out.Servers = strings.Join(servers, ",")
+	out.Topic = strings.TrimSpace(out.Topic)
+	if out.Type == "kafka" {
+		if out.Topic == "" {
+			out.Topic = "prts-events"
+		}
+		if topic := strings.ReplaceAll(out.Topic, "{type}", "credential"); !kafkaTopicPattern.MatchString(topic) || topic == "." || topic == ".." {
+			return errors.New("topic must be letters, digits, '.', '_', '-' or {type}")
+		}
+		out.JetStream, out.Token = false, ""
+	} else {
+		if out.Topic == "" {
+			out.Topic = "prts.events.{type}"
+		}
+		if !natsSubjectPattern.MatchString(out.Topic) {
+			return errors.New("subject must be dot-separated tokens without spaces or wildcards")
+		}
+	}
+	if out.Encoding == "" {
+		out.Encoding = "json"
+	}
+	if out.Encoding != "json" && out.Encoding != "protobuf" {
+		return errors.New("encoding must be json or protobuf")
+	}
+	kinds := splitList(out.Kinds)
+	for _, kind := range kinds {
+		if !slices.Contains(eventBusKinds, kind) {
+			return fmt.Errorf("unknown event type %s", kind)
+		}
+	}
+	out.Kinds = strings.Join(kinds, ",")
+	if out.MinSeverity == "" {
+		out.MinSeverity = "info"
+	}
+	if _, ok := siemSeverities[out.MinSeverity]; !ok {
+		return errors.New("minSeverity must be info, low, medium, high or critical")
+	}
+	if out.TLS {
+		if _, err := outputTLSConfig(out.CACert, out.InsecureSkipVerify); err != nil {
+			return err
+		}
+	}
+	if out.MaxQueueMB <= 0 {
+		out.MaxQueueMB = 256
+	}
+	if out.MaxQueueMB > 4096 {
+		return errors.New("maxQueueMb can be at most 4096")
+	}
+	return nil
+}
+
+// eventBusOutputView adds the delivery state of the running output, secrets stay on the server
+type eventBusOutputView struct {
+	model.EventBusOutput
+	HasPassword     bool       `json:"hasPassword"`
+	HasToken        bool       `json:"hasToken"`
+	Running         bool       `json:"running"`
+	Connected       bool       `json:"connected"`
+	QueueLength     int        `json:"queueLength"`
+	QueueBytes      int64      `json:"queueBytes"`
+	Queued          uint64     `json:"queued"` // Counters since the server started
+	Published       uint64     `json:"published"`
+	Rejected        uint64     `json:"rejected"`
+	Failures        uint64     `json:"failures"`
+	Dropped         uint64     `json:"dropped"`
+	LastError       string     `json:"lastError"`
+	LastErrorAt     *time.Time `json:"lastErrorAt"`
+	LastPublishedAt *time.Time `json:"lastPublishedAt"`
+}
+
+func viewEventBusOutput(out model.EventBusOutput) eventBusOutputView {
+	v := eventBusOutputView{EventBusOutput: out, HasPassword: out.Password != "", HasToken: out.Token != ""}
+	v.Password, v.Token = "", ""
+	eventBusOutputs.RLock()
+	o := eventBusOutputs.m[out.ID]
+	eventBusOutputs.RUnlock()
+	if o == nil {
+		return v
+	}
+	var dropped uint64
+	v.Running = true
+	v.QueueLength, v.QueueBytes, dropped = o.spool.Stats()
+	v.Queued, v.Published, v.Rejected, v.Failures = o.queued.Load(), o.published.Load(), o.rejected.Load(), o.failures.Load()
+	v.Dropped = dropped + o.spoolErrors.Load()
+	o.mu.Lock()
+	v.Connected, v.LastError, v.LastErrorAt, v.LastPublishedAt = o.connected, o.lastError, o.lastErrorAt, o.lastPublishedAt
+	o.mu.Unlock()
+	return v
+}
+
+func (h *Handler) GetEventBusOutputs(c *gin.Context) {
+	var outs []model.EventBusOutput
+	h.DB.Order("created_at").Find(&outs)
+	views := make([]eventBusOutputView, len(outs))
+	for i, out := range outs {
+		views[i] = viewEventBusOutput(out)
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func (h *Handler) CreateEventBusOutput(c *gin.Context) {
+	var out model.EventBusOutput
+	if err := c.ShouldBindJSON(&out); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeEventBusOutput(&out); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	out.ID = fmt.Sprintf("EB-%d", time.Now().UnixNano())
+	out.CreatedAt, out.UpdatedAt = h.Now(), h.Now()
+	if err := h.DB.Create(&out).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name is already used by another output"})
+		return
+	}
+	if out.Enabled {
+		if err := startEventBusOutput(out); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open the queue: " + err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, viewEventBusOutput(out))
+}
+
+// UpdateEventBusOutput replaces an output and restarts it, empty credentials keep the
+// stored ones. Queued messages keep the topic and encoding they were queued with.
+func (h *Handler) UpdateEventBusOutput(c *gin.Context) {
+	var old model.EventBusOutput
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&old).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Output not found"})
+		return
+	}
+	var out model.EventBusOutput
+	if err := c.ShouldBindJSON(&out); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeEventBusOutput(&out); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if out.Password == "" && out.Username != "" {
+		out.Password = old.Password
+	}
+	if out.Token == "" && out.Type == "nats" {
+		out.Token = old.Token
+	}
+	out.ID, out.CreatedAt, out.UpdatedAt = old.ID, old.CreatedAt, h.Now()
+	if err := h.DB.Save(&out).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name is already used by another output"})
+		return
+	}
+	stopEventBusOutput(out.ID, false)
+	if out.Enabled {
+		if err := startEventBusOutput(out); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open the queue: " + err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, viewEventBusOutput(out))
+}
+
+// DeleteEventBusOutput removes an output and discards its queue
+func (h *Handler) DeleteEventBusOutput(c *gin.Context) {
+	id := c.Param("id")
+	res := h.DB.Delete(&model.EventBusOutput{}, "id = ?", id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete output"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Output not found"})
+		return
+	}
+	stopEventBusOutput(id, true)
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// TestEventBusOutput publishes the latest record of every type the output takes, and the
+// state of the newest node, straight to the broker past the queue, so consumers can check
+// their decoding
+func (h *Handler) TestEventBusOutput(c *gin.Context) {
+	var out model.EventBusOutput
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&out).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Output not found"})
+		return
+	}
+	events := h.latestEvents()
+	var node model.NodeStatus
+	if h.DB.Order("id").Limit(1).Find(&node).RowsAffected > 0 {
+		events = append(events, nodeBusEvent(node, h.Now()))
+	}
+
+	var msgs []bus.Message
+	var sent []string
+	for _, ev := range events {
+		if kinds := splitList(out.Kinds); len(kinds) > 0 && !slices.Contains(kinds, ev.Kind) {
+			continue
+		}
+		msg, err := busMessage(out, ev)
+		if err != nil {
+			continue
+		}
+		msgs = append(msgs, msg)
+		sent = append(sent, ev.Kind)
+	}
+	if len(msgs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No records of the types this output takes"})
+		return
+	}
+	publisher, err := newBusPublisher(out)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer publisher.Close()
+	rejected, err := publisher.Publish(msgs)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rejected) > 0 {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("%s was refused: %s", sent[rejected[0].Index], rejected[0].Reason)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "sent": sent})
+}
+
+// writeEventBusMetrics exports the delivery counters and queue depth of every running output
+func writeEventBusMetrics(w *metrics.Writer) {
+	eventBusOutputs.RLock()
+	outputs := make([]*eventBusOutput, 0, len(eventBusOutputs.m))
+	for _, o := range eventBusOutputs.m {
+		outputs = append(outputs, o)
+	}
+	eventBusOutputs.RUnlock()
+	if len(outputs) == 0 {
+		return
+	}
+	slices.SortFunc(outputs, func(a, b *eventBusOutput) int { return strings.Compare(a.out.Name, b.out.Name) })
+
+	family := func(name, kind, help string, value func(*eventBusOutput) float64) {
+		w.Header(name, kind, help)
+		for _, o := range outputs {
+			w.Sample(name, value(o), "output", o.out.Name, "type", o.out.Type)
+		}
+	}
+	family("prts_eventbus_messages_queued_total", "counter", "Messages queued for an event bus output.",
+		func(o *eventBusOutput) float64 { return float64(o.queued.Load()) })
+	family("prts_eventbus_messages_published_total", "counter", "Messages the broker acknowledged.",
+		func(o *eventBusOutput) float64 { return float64(o.published.Load()) })
+	family("prts_eventbus_messages_rejected_total", "counter", "Messages the broker refused for good, such as oversized ones.",
+		func(o *eventBusOutput) float64 { return float64(o.rejected.Load()) })
+	family("prts_eventbus_publish_failures_total", "counter", "Batches that failed and were sent again.",
+		func(o *eventBusOutput) float64 { return float64(o.failures.Load()) })
+	family("prts_eventbus_messages_dropped_total", "counter", "Messages lost because the queue was full or could not be written.",
+		func(o *eventBusOutput) float64 {
+			_, _, dropped := o.spool.Stats()
+			return float64(dropped + o.spoolErrors.Load())
+		})
+	family("prts_eventbus_queue_messages", "gauge", "Messages waiting in the on-disk queue of an event bus output.",
+		func(o *eventBusOutput) float64 {
+			pending, _, _ := o.spool.Stats()
+			return float64(pending)
+		})
+	family("prts_eventbus_queue_bytes", "gauge", "Size of the on-disk queue of an event bus output.",
+		func(o *eventBusOutput) float64 {
+			_, size, _ := o.spool.Stats()
+			return float64(size)
+		})
+	family("prts_eventbus_connected", "gauge", "Whether the last batch reached the broker.",
+		func(o *eventBusOutput) float64 {
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			return metrics.Bool(o.connected)
+		})
+}
@@ -0,0 +1,426 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/metrics"
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Counters kept between scrapes, everything else is read when /metrics is requested
+var (
+	ingestedAttacks = metrics.NewCounterVec("prts_attacks_ingested_total",
+		"Attack events stored by the ingest API.", "service", "severity")
+	ingestFailures = metrics.NewCounterVec("prts_ingest_failures_total",
+		"Ingest requests that were rejected or could not be stored.", "reason")
+	ingestKeyEvents = metrics.NewCounterVec("prts_ingest_key_events_total",
+		"Events posted with each ingest key, by whether they were stored, and requests over its rate limit.", "key", "result")
+	adapterEvents = metrics.NewCounterVec("prts_adapter_events_total",
+		"Log events of other sensors read by ingest adapters, by whether they became records.", "adapter", "result")
+	adapterRecords = metrics.NewCounterVec("prts_adapter_records_total",
+		"Records stored from the logs of other sensors.", "adapter", "kind")
+	nodeReports = metrics.NewCounterVec("prts_node_reports_total",
+		"Status reports received from probes.", "node")
+	dbQueryDuration = metrics.NewHistogramVec("prts_db_query_duration_seconds",
+		"Time spent in database statements.",
+		[]float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}, "operation")
+)
+
+var serverStartTime = time.Now()
+
+// nodeReportsMaxNodes bounds the node label of prts_node_reports_total
+const nodeReportsMaxNodes = 500
+
+// Probes name themselves on an unauthenticated socket, only nodes that were registered
+// before a report get their own series
+var (
+	nodeReportMu    sync.Mutex
+	nodeReportNodes = map[string]bool{}
+)
+
+// countNodeReport counts a status report under its node, or under "unknown" for a node
+// that registered itself with this report or once nodeReportsMaxNodes have a series
+func countNodeReport(id string, registered bool) {
+	label := "unknown"
+	if registered && id != "unknown" {
+		nodeReportMu.Lock()
+		if nodeReportNodes[id] || len(nodeReportNodes) < nodeReportsMaxNodes {
+			nodeReportNodes[id] = true
+			label = id
+		}
+		nodeReportMu.Unlock()
+	}
+	nodeReports.Inc(label)
+}
+
+// forgetNodeReports drops the series of a deleted node
+func forgetNodeReports(id string) {
+	nodeReportMu.Lock()
+	if nodeReportNodes[id] {
+		delete(nodeReportNodes, id)
+		nodeReports.Delete(id)
+	}
+	nodeReportMu.Unlock()
+}
+
+// countIngestedAttack labels an attack the way the trend rollups group it
+func countIngestedAttack(a model.AttackLog) {
+	service := strings.ToUpper(a.Method)
+	if service == "" {
+		service = "unknown"
+	}
+	severity := strings.ToLower(a.Severity)
+	if severity == "" {
+		severity = "unknown"
+	}
+	ingestedAttacks.Inc(service, severity)
+}
+
+// InstrumentDB times every statement into the query duration histogram
+func InstrumentDB(db *gorm.DB) error {
+	start := func(tx *gorm.DB) {
+		tx.InstanceSet("prts:query_start", time.Now())
+	}
+	observe := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			if v, ok := tx.InstanceGet("prts:query_start"); ok {
+				dbQueryDuration.Observe(time.Since(v.(time.Time)).Seconds(), operation)
+			}
+		}
+	}
+
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("prts:metrics_start", start),
+		cb.Create().After("*").Register("prts:metrics_observe", observe("create")),
+		cb.Query().Before("*").Register("prts:metrics_start", start),
+		cb.Query().After("*").Register("prts:metrics_observe", observe("query")),
+		cb.Update().Before("*").Register("prts:metrics_start", start),
+		cb.Update().After("*").Register("prts:metrics_observe", observe("update")),
+		cb.Delete().Before("*").Register("prts:metrics_start", start),
+		cb.Delete().After("*").Register("prts:metrics_observe", observe("delete")),
+		cb.Row().Before("*").Register("prts:metrics_start", start),
+		cb.Row().After("*").Register("prts:metrics_observe", observe("row")),
+		cb.Raw().Before("*").Register("prts:metrics_start", start),
+		cb.Raw().After("*").Register("prts:metrics_observe", observe("raw")),
+	)
+}
+
+// metricsConfig is stored as JSON under the "metrics_config" SystemConfig key. The
+// endpoint is off until enabled and checks its own credentials, scrapers never hold a
+// console login. AllowedNets limits it to addresses or CIDR blocks, comma-separated.
+type metricsConfig struct {
+	Enabled     bool   `json:"enabled"`
+	Auth        string `json:"auth"` // none, bearer, basic
+	Token       string `json:"token,omitempty"`
+	Username    string `json:"username"`
+	Password    string `json:"password,omitempty"`
+	AllowedNets string `json:"allowedNets"`
+}
+
+func (h *Handler) getMetricsConfig() metricsConfig {
+	var cfg model.SystemConfig
+	h.DB.Where("key = ?", "metrics_config").Find(&cfg)
+
+	metricsCfg := metricsConfig{Auth: "bearer"}
+	if cfg.Value != "" {
+		json.Unmarshal([]byte(cfg.Value), &metricsCfg)
+	}
+	return metricsCfg
+}
+
+// parseAllowedNets reads the address allowlist, a bare address is a single host
+func parseAllowedNets(s string) ([]netip.Prefix, error) {
+	var nets []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(part); err == nil {
+			nets = append(nets, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an address or CIDR block", part)
+		}
+		nets = append(nets, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return nets, nil
+}
+
+// secretEqual compares in constant time, hashing first so the length does not leak either
+func secretEqual(given, want string) bool {
+	a := sha256.Sum256([]byte(given))
+	b := sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1
+}
+
+// authorizeScrape applies the allowlist and the credentials of the metrics settings. The
+// allowlist checks the connecting address, forwarded headers are not trusted for it.
+func authorizeScrape(c *gin.Context, cfg metricsConfig) (int, string) {
+	if cfg.AllowedNets != "" {
+		nets, err := parseAllowedNets(cfg.AllowedNets)
+		if err != nil {
+			return http.StatusForbidden, "invalid address allowlist"
+		}
+		addr, err := netip.ParseAddr(c.RemoteIP())
+		allowed := false
+		for _, prefix := range nets {
+			if err == nil && prefix.Contains(addr.Unmap()) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return http.StatusForbidden, "address not allowed"
+		}
+	}
+
+	switch cfg.Auth {
+	case "none":
+		return 0, ""
+	case "basic":
+		user, pass, ok := c.Request.BasicAuth()
+		if ok && cfg.Password != "" && secretEqual(user, cfg.Username) && secretEqual(pass, cfg.Password) {
+			return 0, ""
+		}
+		c.Header("WWW-Authenticate", `Basic realm="prts metrics"`)
+	default:
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if ok && cfg.Token != "" && secretEqual(token, cfg.Token) {
+			return 0, ""
+		}
+		c.Header("WWW-Authenticate", `Bearer realm="prts metrics"`)
+	}
+	return http.StatusUnauthorized, "unauthorized"
+}
+
+// Metrics serves the Prometheus exposition, or OpenMetrics when the scraper asks for it
+func (h *Handler) Metrics(c *gin.Context) {
+	cfg := h.getMetricsConfig()
+	if !cfg.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "metrics endpoint is disabled"})
+		return
+	}
+	if status, msg := authorizeScrape(c, cfg); status != 0 {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	contentType, openMetrics := metrics.Negotiate(c.GetHeader("Accept"))
+	var buf bytes.Buffer
+	w := metrics.NewWriter(&buf, openMetrics)
+	h.writeServerMetrics(w)
+	h.writeRuleMetrics(w)
+	h.writeNodeMetrics(w)
+	writeSiemMetrics(w)
+	writeOpenSearchMetrics(w)
+	writeEventBusMetrics(w)
+	w.Close()
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+func (h *Handler) writeServerMetrics(w *metrics.Writer) {
+	w.Gauge("prts_server_start_time_seconds", "Start time of the server since the Unix epoch.", float64(serverStartTime.Unix()))
+	w.Gauge("prts_go_goroutines", "Goroutines of the server process.", float64(runtime.NumGoroutine()))
+
+	ingestedAttacks.Write(w)
+	ingestFailures.Write(w)
+	ingestKeyEvents.Write(w)
+	adapterEvents.Write(w)
+	adapterRecords.Write(w)
+	nodeReports.Write(w)
+
+	stats := h.Hub.Stats()
+	w.Header("prts_websocket_clients", "gauge", "Open websocket connections by peer kind.")
+	w.Sample("prts_websocket_clients", float64(stats.Nodes), "kind", "probe")
+	w.Sample("prts_websocket_clients", float64(stats.Clients-stats.Nodes), "kind", "console")
+	w.Gauge("prts_websocket_broadcast_queue_length", "Broadcast messages waiting to be fanned out.", float64(stats.QueueLength))
+	w.Gauge("prts_websocket_broadcast_queue_capacity", "Size of the broadcast queue, producers block when it is full.", float64(stats.QueueCapacity))
+	w.Header("prts_websocket_broadcasts_total", "counter", "Broadcast messages fanned out to the clients.")
+	w.Sample("prts_websocket_broadcasts_total", float64(stats.Broadcasts))
+	w.Header("prts_websocket_dropped_messages_total", "counter", "Deliveries skipped because a client could not keep up.")
+	w.Sample("prts_websocket_dropped_messages_total", float64(stats.Dropped))
+
+	// A round trip through the driver, a slow or locked database shows here first
+	begin := time.Now()
+	var one int
+	pingErr := h.DB.Raw("SELECT 1").Scan(&one).Error
+	w.Gauge("prts_db_up", "Whether the database answered the scrape's probe query.", metrics.Bool(pingErr == nil))
+	w.Gauge("prts_db_ping_seconds", "Duration of the scrape's probe query.", time.Since(begin).Seconds())
+	if sqlDB, err := h.DB.DB(); err == nil {
+		dbStats := sqlDB.Stats()
+		w.Gauge("prts_db_open_connections", "Open connections of the database pool.", float64(dbStats.OpenConnections))
+		w.Gauge("prts_db_in_use_connections", "Connections of the pool currently in use.", float64(dbStats.InUse))
+		w.Header("prts_db_wait_seconds_total", "counter", "Time spent waiting for a free connection.")
+		w.Sample("prts_db_wait_seconds_total", dbStats.WaitDuration.Seconds())
+	}
+	dbQueryDuration.Write(w)
+}
+
+// writeRuleMetrics exports the hit counters kept on the rule tables
+func (h *Handler) writeRuleMetrics(w *metrics.Writer) {
+	var vulnRules []model.VulnRule
+	h.DB.Order("id").Find(&vulnRules)
+	w.Header("prts_vuln_rule_hits_total", "counter", "Matches of each vulnerability simulation rule.")
+	for _, r := range vulnRules {
+		w.Sample("prts_vuln_rule_hits_total", float64(r.HitCount), "rule", r.ID, "name", r.Name, "severity", r.Severity, "status", r.Status)
+	}
+
+	var trafficRules []model.TrafficRule
+	h.DB.Order("id").Find(&trafficRules)
+	w.Header("prts_traffic_rule_hits_total", "counter", "Matches of each traffic filtration rule.")
+	for _, r := range trafficRules {
+		w.Sample("prts_traffic_rule_hits_total", float64(r.Hits), "rule", r.ID, "name", r.Name, "category", r.Category, "status", r.Status)
+	}
+
+	var strategies []model.DefenseStrategy
+	h.DB.Order("id").Find(&strategies)
+	w.Header("prts_defense_strategy_hits_total", "counter", "Triggers of each automatic defense strategy.")
+	for _, s := range strategies {
+		w.Sample("prts_defense_strategy_hits_total", float64(s.HitCount), "strategy", s.ID, "name", s.Name, "status", s.Status)
+	}
+
+	var accessRules []struct {
+		Type   string
+		Status string
+		Count  int64
+	}
+	h.DB.Model(&model.AccessControlRule{}).Select("type, status, COUNT(*) AS count").Group("type, status").Order("type, status").Scan(&accessRules)
+	w.Header("prts_access_rules", "gauge", "Access control rules by type and status.")
+	for _, r := range accessRules {
+		w.Sample("prts_access_rules", float64(r.Count), "type", r.Type, "status", r.Status)
+	}
+	w.Gauge("prts_access_rule_version", "Current version of the access rule set, probes report the version they applied.", float64(h.currentRuleVersion()))
+}
+
+// firewallStates are exported as a state set so a missing probe status reads as all zero
+var firewallStates = []string{"active", "inactive", "error"}
+
+// writeNodeMetrics exports online status and identity for every known node and the
+// telemetry of the last report for the online ones, stale values of offline nodes would
+// look current
+func (h *Handler) writeNodeMetrics(w *metrics.Writer) {
+	var nodes []model.NodeStatus
+	h.DB.Order("id").Find(&nodes)
+	var online []model.NodeStatus
+	for _, n := range nodes {
+		if n.Status == "online" {
+			online = append(online, n)
+		}
+	}
+
+	w.Header("prts_node_up", "gauge", "Whether the probe is connected and reporting.")
+	for _, n := range nodes {
+		w.Sample("prts_node_up", metrics.Bool(n.Status == "online"), "node", n.ID, "name", n.Name, "region", n.Region)
+	}
+	w.Header("prts_node_info", "gauge", "Identity of the probe as last reported, always 1.")
+	for _, n := range nodes {
+		w.Sample("prts_node_info", 1, "node", n.ID, "name", n.Name, "region", n.Region, "ip", n.IP, "os", n.OS,
+			"version", n.Version, "template", n.Template, "interface", n.Interface, "mac", n.MAC, "groups", n.Groups)
+	}
+
+	gauges := []struct {
+		name, help string
+		value      func(model.NodeStatus) float64
+	}{
+		{"prts_node_load_percent", "CPU load of the probe host.", func(n model.NodeStatus) float64 { return float64(n.Load) }},
+		{"prts_node_memory_usage_percent", "Memory in use on the probe host.", func(n model.NodeStatus) float64 { return float64(n.MemoryUsage) }},
+		{"prts_node_memory_total_bytes", "Memory of the probe host.", func(n model.NodeStatus) float64 { return float64(n.MemoryTotal) * 1024 * 1024 }},
+		{"prts_node_temperature_celsius", "CPU temperature of the probe host, 0 when no sensor is readable.", func(n model.NodeStatus) float64 { return n.Temperature }},
+		{"prts_node_network_transmit_bytes_per_second", "Outbound traffic of the probe host.", func(n model.NodeStatus) float64 { return n.NetUp * 1024 * 1024 }},
+		{"prts_node_network_receive_bytes_per_second", "Inbound traffic of the probe host.", func(n model.NodeStatus) float64 { return n.NetDown * 1024 * 1024 }},
+		{"prts_node_uptime_seconds", "Uptime of the probe host, to the minute.", func(n model.NodeStatus) float64 { return parseNodeUptime(n.Uptime) }},
+		{"prts_node_rule_version", "Access rule set version applied by the probe.", func(n model.NodeStatus) float64 { return float64(n.RuleVersion) }},
+		{"prts_node_clock_offset_seconds", "How far the probe clock is ahead of the server.", func(n model.NodeStatus) float64 { return float64(n.ClockOffset) / 1000 }},
+		{"prts_node_clock_drift", "Whether the clock offset is beyond the drift threshold.", func(n model.NodeStatus) float64 { return metrics.Bool(n.ClockDrift) }},
+	}
+	for _, g := range gauges {
+		w.Header(g.name, "gauge", g.help)
+		for _, n := range online {
+			w.Sample(g.name, g.value(n), "node", n.ID)
+		}
+	}
+
+	w.Header("prts_node_firewall_status", "gauge", "Firewall backend state of the probe, 1 for the current state.")
+	for _, n := range online {
+		for _, state := range firewallStates {
+			w.Sample("prts_node_firewall_status", metrics.Bool(n.FirewallStatus == state), "node", n.ID, "state", state)
+		}
+	}
+}
+
+// parseNodeUptime reads the "3d 04h 15m" uptime of a probe report
+func parseNodeUptime(s string) float64 {
+	var days, hours, mins int
+	if _, err := fmt.Sscanf(s, "%dd %dh %dm", &days, &hours, &mins); err != nil {
+		return 0
+	}
+	return float64(((days*24+hours)*60 + mins) * 60)
+}
+
+// GetMetricsConfig returns the scrape settings without the token and password
+func (h *Handler) GetMetricsConfig(c *gin.Context) {
+	cfg := h.getMetricsConfig()
+	hasToken, hasPassword := cfg.Token != "", cfg.Password != ""
+	cfg.Token, cfg.Password = "", ""
+	c.JSON(http.StatusOK, gin.H{"config": cfg, "hasToken": hasToken, "hasPassword": hasPassword})
+}
+
+// UpdateMetricsConfig stores the scrape settings, an empty token or password keeps the old one
+func (h *Handler) UpdateMetricsConfig(c *gin.Context) {
+	var req metricsConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	old := h.getMetricsConfig()
+	if req.Token == "" {
+		req.Token = old.Token
+	}
+	if req.Password == "" {
+		req.Password = old.Password
+	}
+	req.Username = strings.TrimSpace(req.Username)
+
+	switch req.Auth {
+	case "none":
+	case "bearer":
+		if req.Enabled && req.Token == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bearer authentication needs a token"})
+			return
+		}
+	case "basic":
+		if req.Enabled && (req.Username == "" || req.Password == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "basic authentication needs a username and password"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "auth must be none, bearer or basic"})
+		return
+	}
+	if _, err := parseAllowedNets(req.AllowedNets); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	val, _ := json.Marshal(req)
+	h.saveConfigValue("metrics_config", string(val))
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
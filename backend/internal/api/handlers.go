@@ -15,12 +15,8 @@ import (
 
 	"sync/atomic"
 
-	"github.com/beevik/ntp"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/mem"
-	"github.com/shirou/gopsutil/v3/net"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -28,9 +24,10 @@ import (
 var JwtSecret = []byte("PRTS_SYSTEM_SECRET_KEY_2025")
 var globalTimeOffset int64 // nanoseconds, atomic
 
+// GetNow is the NTP corrected time in UTC, the zone every stored timestamp uses
 func GetNow() time.Time {
 	offset := atomic.LoadInt64(&globalTimeOffset)
-	return time.Now().Add(time.Duration(offset))
+	return time.Now().Add(time.Duration(offset)).UTC()
 }
 
 func (h *Handler) Now() time.Time {
@@ -70,43 +67,6 @@ func (h *Handler) getLoginPolicy() model.LoginPolicy {
 	return policy
 }
 
-func (h *Handler) StartAutoCleanup() {
-	ticker := time.NewTicker(1 * time.Minute)
-	go func() {
-		for range ticker.C {
-			var expiredRules []model.AccessControlRule
-			now := h.Now()
-
-			// Find rules that just expired
-			h.DB.Where("status = ? AND expire_time != ? AND expire_time != ? AND expire_time != ?",
-				"active", "", "永久", "Permanent").Find(&expiredRules)
-
-			hasChanges := false
-			for _, rule := range expiredRules {
-				// Use ParseInLocation with time.Local to match the base of h.Now()
-				expireTime, err := time.ParseInLocation("2006/01/02 15:04:05", rule.ExpireTime, time.Local)
-				if err == nil && expireTime.Before(now) {
-					h.DB.Model(&rule).Update("status", "expired")
-					hasChanges = true
-					log.Printf("Rule %s (%s) has expired automatically at %s (NTP Time: %s)",
-						rule.ID, rule.IP, rule.ExpireTime, now.Format("2006/01/02 15:04:05"))
-				}
-			}
-
-			// If any rule expired, broadcast sync to all nodes
-			if hasChanges {
-				var activeRules []model.AccessControlRule
-				h.DB.Where("status = ?", "active").Find(&activeRules)
-				msg, _ := json.Marshal(map[string]interface{}{
-					"type": "SYNC_RULES",
-					"data": activeRules,
-				})
-				h.Hub.Broadcast(msg)
-			}
-		}
-	}()
-}
-
 func (h *Handler) GetPublicLoginPolicy(c *gin.Context) {
 	policy := h.getLoginPolicy()
 	// Don't return whitelist to public
@@ -146,7 +106,7 @@ func (h *Handler) LoginHandler(c *gin.Context) {
 	if attempt.LockedUntil.After(h.Now()) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error": "Account locked",
-			"until": attempt.LockedUntil.Format("2006-01-02 15:04:05"),
+			"until": attempt.LockedUntil.UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -215,10 +175,17 @@ func (h *Handler) LoginHandler(c *gin.Context) {
 	})
 }
 
+var attackListSpec = listSpec{
+	timeColumn:  "timestamp",
+	sorts:       map[string]string{"timestamp": "timestamp", "severity": "severity", "sourceIp": "source_ip", "method": "method", "status": "status"},
+	defaultSort: "-timestamp",
+	filters:     map[string]string{"severity": "severity", "method": "method", "status": "status", "node": "node"},
+	ipFilters:   map[string]string{"ip": "source_ip"},
+	search:      []string{"payload", "location"},
+}
+
 func (h *Handler) GetAttacks(c *gin.Context) {
-	var attacks []model.AttackLog
-	h.DB.Find(&attacks)
-	c.JSON(http.StatusOK, attacks)
+	listQuery[model.AttackLog](h, c, attackListSpec)
 }
 
 func (h *Handler) GetNodes(c *gin.Context) {
@@ -233,6 +200,8 @@ func (h *Handler) DeleteNode(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete node"})
 		return
 	}
+	h.DB.Where("node_id = ?", id).Delete(&model.NodeMetric{})
+	forgetNodeReports(id)
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
 
@@ -294,6 +263,10 @@ func (h *Handler) GetDashboardStats(c *gin.Context) {
 		})
 	}
 
+	// Attacks per service over the last day, from the hourly rollups
+	now := h.Now()
+	trend, _ := h.attackTrend(trendQuery{from: now.Add(-23 * time.Hour), to: now, interval: time.Hour, groupBy: "service", top: 3, loc: time.UTC})
+
 	c.JSON(http.StatusOK, gin.H{
 		"totalAttacks":     totalAttacks,
 		"activeNodes":      activeNodes,
@@ -306,56 +279,47 @@ func (h *Handler) GetDashboardStats(c *gin.Context) {
 		"topSources":       topSourcesList,
 		"honeypotStats":    honeypotStats,
 		"hackerProfiles":   topSources, // Use top sources as profiles for now
-		"trendData": []gin.H{
-			{"name": "00:00", "coremail": 400, "esxi": 240, "elastic": 240},
-			{"name": "04:00", "coremail": 300, "esxi": 139, "elastic": 221},
-			{"name": "08:00", "coremail": 200, "esxi": 980, "elastic": 229},
-			{"name": "12:00", "coremail": 278, "esxi": 390, "elastic": 200},
-			{"name": "16:00", "coremail": 189, "esxi": 480, "elastic": 218},
-			{"name": "20:00", "coremail": 239, "esxi": 380, "elastic": 250},
-			{"name": "23:59", "coremail": 349, "esxi": 430, "elastic": 210},
-		},
+		"attackTrend":      trend,
 	})
 }
 
-func (h *Handler) GetScans(c *gin.Context) {
-	var scans []model.ScanLog
-	h.DB.Order("start desc").Find(&scans)
-	c.JSON(http.StatusOK, scans)
+var scanListSpec = listSpec{
+	timeColumn:  "start",
+	sorts:       map[string]string{"start": "start", "count": "count", "ip": "ip", "threat": "threat"},
+	defaultSort: "-start",
+	filters:     map[string]string{"threat": "threat", "type": "type", "node": "node"},
+	ipFilters:   map[string]string{"ip": "ip"},
+	search:      []string{"location", "ports"},
 }
 
-func (h *Handler) GetDecoys(c *gin.Context) {
-	var decoys []model.DecoyLog
-	h.DB.Order("time desc").Find(&decoys)
-	c.JSON(http.StatusOK, decoys)
+func (h *Handler) GetScans(c *gin.Context) {
+	listQuery[model.ScanLog](h, c, scanListSpec)
 }
 
-func (h *Handler) DeployDecoy(c *gin.Context) {
-	var req struct {
-		Name string `json:"name"`
-		Type string `json:"type"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
-		return
-	}
+var decoyListSpec = listSpec{
+	timeColumn:  "time",
+	sorts:       map[string]string{"time": "time", "deployTime": "deploy_time", "status": "status", "type": "type"},
+	defaultSort: "-time",
+	filters:     map[string]string{"status": "status", "type": "type", "node": "node", "decoyId": "decoy_id"},
+	ipFilters:   map[string]string{"ip": "source_ip"},
+	search:      []string{"decoy_name", "path", "process", "detail"},
+}
 
-	// Simulate deployment
-	decoy := model.DecoyLog{
-		DecoyName: req.Name,
-		Type:      req.Type,
-		Status:    "Active",
-		Time:      h.Now().Format("2006-01-02 15:04:05"),
-	}
-	h.DB.Create(&decoy)
+func (h *Handler) GetDecoys(c *gin.Context) {
+	listQuery[model.DecoyLog](h, c, decoyListSpec)
+}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "decoy": decoy})
+var sampleListSpec = listSpec{
+	timeColumn:  "last_time",
+	sorts:       map[string]string{"lastTime": "last_time", "captureCount": "capture_count", "threatLevel": "threat_level", "fileName": "file_name"},
+	defaultSort: "-lastTime",
+	filters:     map[string]string{"threatLevel": "threat_level", "status": "status", "node": "source_node", "sha256": "sha256"},
+	ipFilters:   map[string]string{"ip": "attacker_ip"},
+	search:      []string{"file_name", "file_type"},
 }
 
 func (h *Handler) GetSamples(c *gin.Context) {
-	var samples []model.SampleLog
-	h.DB.Order("last_time desc").Find(&samples)
-	c.JSON(http.StatusOK, samples)
+	listQuery[model.SampleLog](h, c, sampleListSpec)
 }
 
 func (h *Handler) DeleteSample(c *gin.Context) {
@@ -364,6 +328,7 @@ func (h *Handler) DeleteSample(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sample"})
 		return
 	}
+	h.unindexSearchDocuments("kind = ? AND ref_id = ?", "sample", id)
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
 
@@ -425,83 +390,46 @@ func (h *Handler) DeleteTrafficRule(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
 
-func (h *Handler) GetSystemStats(c *gin.Context) {
-	v, _ := mem.VirtualMemory()
-	cpuPerc, _ := cpu.Percent(0, false)
-	netIO, _ := net.IOCounters(false)
-
-	cpuVal := 0.0
-	if len(cpuPerc) > 0 {
-		cpuVal = cpuPerc[0]
-	}
-
-	memVal := v.UsedPercent
-
-	up := 0.0
-	down := 0.0
-	if len(netIO) > 0 {
-		// For a real rate we'd need to sample twice, but for the dashboard
-		// we'll use a simplified representation for now.
-		up = float64(netIO[0].BytesSent%1024) / 102.4
-		down = float64(netIO[0].BytesRecv%1024) / 102.4
-	}
-
-	now := h.Now()
-	history := []gin.H{}
-	for i := 0; i < 20; i++ {
-		history = append(history, gin.H{
-			"time": now.Add(time.Duration(-i*5) * time.Second).Format("15:04:05"),
-			"up":   up + (float64(i%3) * 0.1),
-			"down": down + (float64(i%2) * 0.1),
-		})
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"cpu":     int(cpuVal),
-		"memory":  int(memVal),
-		"network": gin.H{"up": up, "down": down},
-		"history": history,
-	})
-}
-
+// IngestAttack accepts attacks from a sensor posting with its ingest key, one or a batch
 func (h *Handler) IngestAttack(c *gin.Context) {
-	var attack model.AttackLog
-	if err := c.ShouldBindJSON(&attack); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	if attack.Timestamp.IsZero() {
-		attack.Timestamp = h.Now()
-	}
+	h.ingestBatch(c, "attack", h.acceptAttack)
+}
 
-	// Save to DB
+// recordAttack stores an attack and passes it on to alerts, search, trends, the exports
+// and the live feed
+func (h *Handler) recordAttack(attack model.AttackLog) error {
 	if err := h.DB.Create(&attack).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save attack"})
-		return
+		return err
 	}
 
+	countIngestedAttack(attack)
+	h.alertAttack(attack)
+	h.indexSearchDocument(attackSearchDocument(attack))
+	h.rollupAttack(attack)
+	h.exportEvent(attackSiemEvent(attack))
+
 	// Broadcast via WebSocket
 	h.Hub.BroadcastAttack(attack)
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "id": attack.ID})
+	// Planted AWS keys replayed against a honeypot
+	h.checkCanaryKeys(attack.Payload, attack.SourceIP, "attack payload")
+	return nil
 }
 
-func (h *Handler) GetMessages(c *gin.Context) {
-	var messages []model.Message
-	h.DB.Order("time desc").Find(&messages)
-	c.JSON(http.StatusOK, messages)
+// postSystemMessage raises a system alert that is not about a node
+func (h *Handler) postSystemMessage(title, content, severity string) {
+	h.raiseAlert(alert{Title: title, Content: content, Type: "system", Severity: severity})
 }
 
-func (h *Handler) MarkAllMessagesRead(c *gin.Context) {
-	h.DB.Model(&model.Message{}).Where("read = ?", false).Update("read", true)
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
-}
-
-func (h *Handler) DeleteMessage(c *gin.Context) {
-	id := c.Param("id")
-	h.DB.Delete(&model.Message{}, "id = ?", id)
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+// privateConfigKeys hold secrets or validated settings and are only served and written
+// by their admin endpoints, or record migrations the server runs itself on start
+var privateConfigKeys = map[string]bool{
+	"ldap_inventory_config": true, // GetLDAPInventoryConfig
+	"metrics_config":        true, // GetMetricsConfig
+	"alert_config":          true, // GetAlertConfig
+	"time_schema":           true, // MigrateTimeColumns
+	"rollup_schema":         true, // StartAttackRollups
+	"search_index":          true, // StartSearchIndex
 }
 
 func (h *Handler) GetConfig(c *gin.Context) {
@@ -510,6 +438,9 @@ func (h *Handler) GetConfig(c *gin.Context) {
 
 	configMap := make(map[string]string)
 	for _, cfg := range configs {
+		if privateConfigKeys[cfg.Key] {
+			continue
+		}
 		configMap[cfg.Key] = cfg.Value
 	}
 
@@ -529,6 +460,9 @@ func (h *Handler) UpdateConfig(c *gin.Context) {
 	}
 
 	for k, v := range req {
+		if privateConfigKeys[k] {
+			continue
+		}
 		if k == "login_policy" {
 			var policy model.LoginPolicy
 			if err := json.Unmarshal([]byte(v), &policy); err == nil {
@@ -578,16 +512,30 @@ func (h *Handler) GetServices(c *gin.Context) {
 	c.JSON(http.StatusOK, services)
 }
 
+var attackSourceListSpec = listSpec{
+	timeColumn:  "first_time",
+	sorts:       map[string]string{"firstTime": "first_time", "attackCount": "attack_count", "scanCount": "scan_count", "ip": "ip"},
+	defaultSort: "-firstTime",
+	filters:     map[string]string{"verdict": "verdict", "country": "country"},
+	ipFilters:   map[string]string{"ip": "ip"},
+	search:      []string{"tags", "nodes"},
+}
+
 func (h *Handler) GetAttackSources(c *gin.Context) {
-	var sources []model.AttackSource
-	h.DB.Find(&sources)
-	c.JSON(http.StatusOK, sources)
+	listQuery[model.AttackSource](h, c, attackSourceListSpec)
+}
+
+var credentialListSpec = listSpec{
+	timeColumn:  "time",
+	sorts:       map[string]string{"time": "time", "count": "count", "username": "username", "service": "service"},
+	defaultSort: "-time",
+	filters:     map[string]string{"service": "service", "username": "username"},
+	ipFilters:   map[string]string{"ip": "ip"},
+	search:      []string{"username", "password"},
 }
 
 func (h *Handler) GetAccountCredentials(c *gin.Context) {
-	var creds []model.AccountCredential
-	h.DB.Find(&creds)
-	c.JSON(http.StatusOK, creds)
+	listQuery[model.AccountCredential](h, c, credentialListSpec)
 }
 
 func (h *Handler) GetTrafficRules(c *gin.Context) {
@@ -644,71 +592,17 @@ func (h *Handler) DeleteDefenseStrategy(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
 
-func (h *Handler) GetAccessControlRules(c *gin.Context) {
-	var rules []model.AccessControlRule
-	h.DB.Find(&rules)
-	c.JSON(http.StatusOK, rules)
-}
-
-func (h *Handler) CreateAccessControlRule(c *gin.Context) {
-	var rule model.AccessControlRule
-	if err := c.ShouldBindJSON(&rule); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	if rule.ID == "" {
-		rule.ID = fmt.Sprintf("AC-%d", time.Now().Unix())
-	}
-	if rule.AddTime == "" {
-		rule.AddTime = time.Now().Format("2006/01/02 15:04:05")
-	}
-	if rule.Status == "" {
-		rule.Status = "active"
-	}
-	h.DB.Create(&rule)
-	c.JSON(http.StatusOK, rule)
-}
-
-func (h *Handler) DeleteAccessControlRule(c *gin.Context) {
-	id := c.Param("id")
-	h.DB.Delete(&model.AccessControlRule{}, "id = ?", id)
-	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted"})
-}
-
-func (h *Handler) SyncAccessRules(c *gin.Context) {
-	// Fetch all active rules
-	var allRules []model.AccessControlRule
-	h.DB.Where("status = ?", "active").Find(&allRules)
-
-	// Filter expired rules
-	var rules []model.AccessControlRule
-	now := h.Now()
-	for _, rule := range allRules {
-		if rule.ExpireTime != "" && rule.ExpireTime != "永久" && rule.ExpireTime != "Permanent" {
-			// Use ParseInLocation with time.Local to match the base of h.Now()
-			expireTime, err := time.ParseInLocation("2006/01/02 15:04:05", rule.ExpireTime, time.Local)
-			if err == nil && expireTime.Before(now) {
-				// Rule has expired, update its status in DB
-				h.DB.Model(&rule).Update("status", "expired")
-				continue
-			}
-		}
-		rules = append(rules, rule)
-	}
-
-	msg, _ := json.Marshal(map[string]interface{}{
-		"type": "SYNC_RULES",
-		"data": rules,
-	})
-
-	h.Hub.Broadcast(msg)
-	c.JSON(http.StatusOK, gin.H{"message": "Sync command broadcasted", "count": len(rules)})
+var loginLogListSpec = listSpec{
+	timeColumn:  "time",
+	sorts:       map[string]string{"time": "time", "username": "username"},
+	defaultSort: "-time",
+	filters:     map[string]string{"status": "status", "username": "username"},
+	ipFilters:   map[string]string{"ip": "ip"},
+	search:      []string{"device"},
 }
 
 func (h *Handler) GetLoginLogs(c *gin.Context) {
-	var logs []model.LoginLog
-	h.DB.Order("time desc").Find(&logs)
-	c.JSON(http.StatusOK, logs)
+	listQuery[model.LoginLog](h, c, loginLogListSpec)
 }
 
 func (h *Handler) GetReports(c *gin.Context) {
@@ -727,8 +621,8 @@ func (h *Handler) CreateReport(c *gin.Context) {
 	if report.ID == "" {
 		report.ID = fmt.Sprintf("REP-%d", time.Now().Unix())
 	}
-	if report.CreateTime == "" {
-		report.CreateTime = h.Now().Format("2006-01-02 15:04:05")
+	if report.CreateTime.IsZero() {
+		report.CreateTime = h.Now()
 	}
 	if report.Status == "" {
 		report.Status = "success"
@@ -750,9 +644,11 @@ func (h *Handler) recordLoginLog(username, ip, status, device string) {
 		IP:       ip,
 		Status:   status,
 		Device:   device,
-		Time:     h.Now().Format("2006-01-02 15:04:05"),
+		Time:     h.Now(),
+	}
+	if h.DB.Create(&log).Error == nil {
+		h.exportEvent(loginSiemEvent(log))
 	}
-	h.DB.Create(&log)
 }
 
 func (h *Handler) GetUsers(c *gin.Context) {
@@ -835,6 +731,11 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
+	if user.Username != "" {
+		h.DB.Where("username = ?", user.Username).Delete(&model.MessageState{})
+		h.DB.Where("username = ?", user.Username).Delete(&model.MessageSubscription{})
+		h.Hub.DisconnectUser(user.Username)
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
 
@@ -893,69 +794,6 @@ func (h *Handler) UpdatePassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Password updated successfully"})
 }
 
-func (h *Handler) NtpSync(c *gin.Context) {
-	var cfg model.SystemConfig
-	// Use Find instead of First to avoid "record not found" error log when config doesn't exist
-	h.DB.Where("key = ?", "ntp_config").Find(&cfg)
-
-	var ntpCfg struct {
-		Server string `json:"server"`
-	}
-	if cfg.Value != "" {
-		json.Unmarshal([]byte(cfg.Value), &ntpCfg)
-	} else {
-		ntpCfg.Server = "pool.ntp.org"
-	}
-
-	// Perform real NTP sync
-	response, err := ntp.Query(ntpCfg.Server)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status": "error",
-			"error":  "Failed to sync with NTP server: " + err.Error(),
-		})
-		return
-	}
-
-	err = response.Validate()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status": "error",
-			"error":  "NTP response validation failed: " + err.Error(),
-		})
-		return
-	}
-
-	// Update global time offset
-	atomic.StoreInt64(&globalTimeOffset, int64(response.ClockOffset))
-
-	// Persist to DB
-	var timeCfg model.SystemConfig
-	h.DB.Where("key = ?", "time_offset").First(&timeCfg)
-	timeCfg.Key = "time_offset"
-	timeCfg.Value = fmt.Sprintf("%d", int64(response.ClockOffset))
-	h.DB.Save(&timeCfg)
-
-	remoteTime := h.Now()
-
-	var syncTimeCfg model.SystemConfig
-	h.DB.Where("key = ?", "last_sync_time").First(&syncTimeCfg)
-	syncTimeCfg.Key = "last_sync_time"
-	syncTimeCfg.Value = remoteTime.Format(time.RFC3339)
-	h.DB.Save(&syncTimeCfg)
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":     "success",
-		"offset":     response.ClockOffset.Seconds(),
-		"remoteTime": remoteTime.Format(time.RFC3339),
-		"stratum":    response.Stratum,
-		"precision":  response.Precision,
-		"rootDelay":  response.RootDelay.Seconds(),
-		"rootDisp":   response.RootDispersion.Seconds(),
-		"leap":       response.Leap,
-	})
-}
-
 func (h *Handler) CleanDatabase(c *gin.Context) {
 	// Clean logs older than retention period
 	var retentionStr model.SystemConfig
@@ -967,7 +805,8 @@ func (h *Handler) CleanDatabase(c *gin.Context) {
 	cutoff := h.Now().AddDate(0, 0, -retentionDays)
 
 	h.DB.Where("timestamp < ?", cutoff).Delete(&model.AttackLog{})
-	h.DB.Where("start < ?", cutoff.Format("2006/01/02 15:04:05")).Delete(&model.ScanLog{})
+	h.unindexSearchDocuments("kind = ? AND time < ?", "attack", cutoff)
+	h.DB.Where("start < ?", cutoff).Delete(&model.ScanLog{})
 	// Add other logs as needed
 
 	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Database cleaned"})
@@ -997,7 +836,16 @@ func (h *Handler) HandleWebSocketMessage(msg []byte, client *websocket.Client) {
 		// Update DB
 		var existing model.NodeStatus
 		var previousStatus string
-		if err := h.DB.Where("id = ?", nodeStatus.ID).First(&existing).Error; err != nil {
+		found := h.DB.Where("id = ?", nodeStatus.ID).First(&existing).Error == nil
+
+		// Older probes do not send their clock
+		clockChecked := nodeStatus.Clock != nil
+		wasDrifting := existing.ClockDrift
+		if clockChecked {
+			h.observeNodeClock(&nodeStatus, wasDrifting)
+		}
+
+		if !found {
 			h.DB.Create(&nodeStatus)
 			previousStatus = "" // New node
 		} else {
@@ -1023,6 +871,12 @@ func (h *Handler) HandleWebSocketMessage(msg []byte, client *websocket.Client) {
 				"firewall_status": nodeStatus.FirewallStatus,
 				"firewall_error":  nodeStatus.FirewallError,
 				"firewall_info":   nodeStatus.FirewallInfo,
+				"rule_version":    nodeStatus.RuleVersion,
+			}
+			if clockChecked {
+				updates["clock_offset"] = nodeStatus.ClockOffset
+				updates["clock_checked_at"] = nodeStatus.ClockCheckedAt
+				updates["clock_drift"] = nodeStatus.ClockDrift
 			}
 			h.DB.Model(&existing).Updates(updates)
 			// Refresh existing object from DB to get the latest state
@@ -1043,25 +897,46 @@ func (h *Handler) HandleWebSocketMessage(msg []byte, client *websocket.Client) {
 		log.Printf("Broadcasting %s for %s (Status: %s)", broadcastType, nodeStatus.ID, nodeStatus.Status)
 		h.Hub.Broadcast(broadcastMsg)
 
+		if message.Type == "NODE_REPORT" {
+			recordNodeMetrics(nodeStatus, h.Now())
+			countNodeReport(nodeStatus.ID, found)
+		}
+
+		// Catch the probe up if its applied rule set is behind
+		go h.reconcileNodeRules(nodeStatus.ID, nodeStatus.RuleVersion)
+
+		if clockChecked && nodeStatus.ClockDrift != wasDrifting {
+			h.raiseClockDrift(nodeStatus)
+		}
+
 		// Create system message for online status if it was offline or new
 		if previousStatus == "" || previousStatus != "online" {
-			msgID := fmt.Sprintf("msg-%d", time.Now().UnixNano())
-			sysMsg := model.Message{
-				ID:      msgID,
-				Title:   "msg_node_online_title",
-				Content: fmt.Sprintf("msg_node_online_content|name:%s,id:%s", nodeStatus.Name, nodeStatus.ID),
-				Time:    h.Now(),
-				Type:    "system",
-				Read:    false,
-			}
-			h.DB.Create(&sysMsg)
-
-			// Broadcast message to frontend
-			msgNotify, _ := json.Marshal(map[string]interface{}{
-				"type": "NEW_MESSAGE",
-				"data": sysMsg,
+			// A flapping probe folds into one message, coming back ends its offline alert
+			h.raiseAlert(alert{
+				Title:       "msg_node_online_title",
+				Content:     fmt.Sprintf("msg_node_online_content|name:%s,id:%s", nodeStatus.Name, nodeStatus.ID),
+				Type:        "system",
+				Node:        nodeStatus.ID,
+				Fingerprint: "node_online:" + nodeStatus.ID,
+				Resolves:    "node_offline:" + nodeStatus.ID,
 			})
-			h.Hub.Broadcast(msgNotify)
+			h.publishNodeChange(nodeStatus)
+
+			// Probes keep planted decoys in memory only, hand them back after a reconnect
+			go h.redeployDecoys(nodeStatus.ID)
+		}
+		return
+	}
+
+	switch message.Type {
+	case "DECOY_DEPLOYED":
+		h.handleDecoyDeployed(client, message.Data)
+	case "DECOY_EVENT":
+		h.handleDecoyEvent(client, message.Data)
+	case "RULES_RESYNC":
+		// The probe saw a diff it cannot apply on top of its rule set
+		if client.NodeID != "" {
+			h.sendRules(client.NodeID, 0, true)
 		}
 	}
 }
@@ -1085,24 +960,16 @@ func (h *Handler) HandleWebSocketDisconnect(client *websocket.Client) {
 		})
 		h.Hub.Broadcast(broadcastMsg)
 
-		// Create system message for offline status
-		msgID := fmt.Sprintf("msg-%d", time.Now().UnixNano())
-		sysMsg := model.Message{
-			ID:      msgID,
-			Title:   "msg_node_offline_title",
-			Content: fmt.Sprintf("msg_node_offline_content|name:%s,id:%s", node.Name, node.ID),
-			Time:    h.Now(),
-			Type:    "security",
-			Read:    false,
-		}
-		h.DB.Create(&sysMsg)
-
-		// Broadcast message to frontend
-		msgNotify, _ := json.Marshal(map[string]interface{}{
-			"type": "NEW_MESSAGE",
-			"data": sysMsg,
+		// Create system message for offline status, routed to the notification channels
+		h.raiseAlert(alert{
+			Title:       "msg_node_offline_title",
+			Content:     fmt.Sprintf("msg_node_offline_content|name:%s,id:%s", node.Name, node.ID),
+			Type:        "security",
+			Severity:    "warning",
+			Node:        node.ID,
+			Fingerprint: "node_offline:" + node.ID,
 		})
-		h.Hub.Broadcast(msgNotify)
+		h.publishNodeChange(node)
 	}
 }
 
@@ -1137,31 +1004,6 @@ func (h *Handler) HandleNodeCommand(c *gin.Context) {
 	}
 }
 
-func (h *Handler) syncRulesToNode(nodeID string) {
-	var allRules []model.AccessControlRule
-	h.DB.Where("status = ?", "active").Find(&allRules)
-
-	var rules []model.AccessControlRule
-	now := h.Now()
-	for _, rule := range allRules {
-		if rule.ExpireTime != "" && rule.ExpireTime != "永久" && rule.ExpireTime != "Permanent" {
-			expireTime, err := time.Parse("2006/01/02 15:04:05", rule.ExpireTime)
-			if err == nil && expireTime.Before(now) {
-				h.DB.Model(&rule).Update("status", "expired")
-				continue
-			}
-		}
-		rules = append(rules, rule)
-	}
-
-	msg, _ := json.Marshal(map[string]interface{}{
-		"type": "SYNC_RULES",
-		"data": rules,
-	})
-
-	h.Hub.SendToNode(nodeID, msg)
-}
-
 func (h *Handler) GetModules(c *gin.Context) {
 	var modules []model.ModuleStatus
 	h.DB.Find(&modules)
@@ -0,0 +1,335 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// messageTypes are the kinds of message a user can subscribe to
+var messageTypes = []string{"system", "security", "report"}
+
+// inboxMessage is a message as one user sees it, with their read and archived state
+type inboxMessage struct {
+	model.Message
+	Read     bool `json:"read" gorm:"column:user_read;->"`
+	Archived bool `json:"archived" gorm:"column:user_archived;->"`
+}
+
+func (inboxMessage) TableName() string { return "messages" }
+
+// messageSubscription loads the subscription of a user, without one they get everything
+func (h *Handler) messageSubscription(username string) model.MessageSubscription {
+	sub := model.MessageSubscription{Username: username, MinSeverity: "info"}
+	h.DB.Where("username = ?", username).Limit(1).Find(&sub)
+	return sub
+}
+
+// subscribedTo checks a message against a subscription, the Go side of inboxScope
+func subscribedTo(sub model.MessageSubscription, msg model.Message) bool {
+	if types := splitList(sub.Types); len(types) > 0 && !slices.Contains(types, msg.Type) {
+		return false
+	}
+	return severityRanks[msg.Severity] >= severityRanks[sub.MinSeverity]
+}
+
+// inboxColumns fill an inboxMessage, s is the state joined by inboxScope
+const inboxColumns = "messages.*, COALESCE(s.read, 0) AS user_read, COALESCE(s.archived, 0) AS user_archived"
+
+// inboxScope joins the state of the user and keeps the messages of their subscription
+func (h *Handler) inboxScope(username string) func(*gorm.DB) *gorm.DB {
+	sub := h.messageSubscription(username)
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Joins("LEFT JOIN message_states s ON s.message_id = messages.id AND s.username = ?", username)
+		if types := splitList(sub.Types); len(types) > 0 {
+			db = db.Where("messages.type IN ?", types)
+		}
+		if min := severityRanks[sub.MinSeverity]; min > 0 {
+			var severities []string
+			for severity, rank := range severityRanks {
+				if rank >= min {
+					severities = append(severities, severity)
+				}
+			}
+			db = db.Where("messages.severity IN ?", severities)
+		}
+		return db
+	}
+}
+
+// GetMessages lists the inbox of the caller. Archived messages are left out unless
+// archived=true asks for them instead, unread=true keeps the unread ones.
+func (h *Handler) GetMessages(c *gin.Context) {
+	user, _ := c.Get("username")
+	username, _ := user.(string)
+	archived := c.Query("archived") == "true"
+	listQuery[inboxMessage](h, c, messageListSpec, h.inboxScope(username), func(db *gorm.DB) *gorm.DB {
+		db = db.Select(inboxColumns)
+		if c.Query("unread") == "true" {
+			db = db.Where("COALESCE(s.read, 0) = ?", false)
+		}
+		return db.Where("COALESCE(s.archived, 0) = ?", archived)
+	})
+}
+
+// GetUnreadCount counts the unread messages in the inbox of the caller
+func (h *Handler) GetUnreadCount(c *gin.Context) {
+	user, _ := c.Get("username")
+	username, _ := user.(string)
+	var unread int64
+	err := h.DB.Model(&inboxMessage{}).Scopes(h.inboxScope(username)).
+		Where("COALESCE(s.read, 0) = ? AND COALESCE(s.archived, 0) = ?", false, false).
+		Count(&unread).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread": unread})
+}
+
+// setMessageState records read and archived for a user, a nil flag keeps what was there
+func (h *Handler) setMessageState(username string, ids []string, read, archived *bool) error {
+	if len(ids) == 0 || (read == nil && archived == nil) {
+		return nil
+	}
+	now := h.Now()
+	var columns []string
+	states := make([]model.MessageState, len(ids))
+	for i, id := range ids {
+		states[i] = model.MessageState{MessageID: id, Username: username}
+		if read != nil {
+			states[i].Read = *read
+			if *read {
+				states[i].ReadAt = &now
+			}
+		}
+		if archived != nil {
+			states[i].Archived = *archived
+			if *archived {
+				states[i].ArchivedAt = &now
+			}
+		}
+	}
+	if read != nil {
+		columns = append(columns, "read", "read_at")
+	}
+	if archived != nil {
+		columns = append(columns, "archived", "archived_at")
+	}
+	return h.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "message_id"}, {Name: "username"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).CreateInBatches(&states, 500).Error
+}
+
+// updateMessageState applies a read or archived change of the caller to one message
+func (h *Handler) updateMessageState(c *gin.Context, read, archived *bool) {
+	user, _ := c.Get("username")
+	username, _ := user.(string)
+	var msg inboxMessage
+	if err := h.DB.Model(&inboxMessage{}).Scopes(h.inboxScope(username)).Select(inboxColumns).
+		Where("messages.id = ?", c.Param("id")).First(&msg).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	if err := h.setMessageState(username, []string{msg.ID}, read, archived); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update message"})
+		return
+	}
+	if read != nil {
+		msg.Read = *read
+	}
+	if archived != nil {
+		msg.Archived = *archived
+	}
+	c.JSON(http.StatusOK, msg)
+}
+
+// SetMessageRead marks a message read for the caller, {"read": false} marks it unread again
+func (h *Handler) SetMessageRead(c *gin.Context) {
+	var req struct {
+		Read *bool `json:"read"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	read := req.Read == nil || *req.Read
+	h.updateMessageState(c, &read, nil)
+}
+
+// ArchiveMessage moves a message out of the inbox of the caller, {"archived": false} restores it
+func (h *Handler) ArchiveMessage(c *gin.Context) {
+	var req struct {
+		Archived *bool `json:"archived"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	archived := req.Archived == nil || *req.Archived
+	h.updateMessageState(c, nil, &archived)
+}
+
+// DeleteMessage dismisses a message for the caller only, it stays for everyone else
+func (h *Handler) DeleteMessage(c *gin.Context) {
+	archived := true
+	h.updateMessageState(c, nil, &archived)
+}
+
+// MarkAllMessagesRead marks the inbox of the caller read
+func (h *Handler) MarkAllMessagesRead(c *gin.Context) {
+	user, _ := c.Get("username")
+	username, _ := user.(string)
+	var ids []string
+	h.DB.Model(&inboxMessage{}).Scopes(h.inboxScope(username)).
+		Where("COALESCE(s.read, 0) = ? AND COALESCE(s.archived, 0) = ?", false, false).
+		Pluck("messages.id", &ids)
+	read := true
+	if err := h.setMessageState(username, ids, &read, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "updated": len(ids)})
+}
+
+func (h *Handler) GetMessageSubscription(c *gin.Context) {
+	user, _ := c.Get("username")
+	username, _ := user.(string)
+	c.JSON(http.StatusOK, h.messageSubscription(username))
+}
+
+// UpdateMessageSubscription picks the message types and the lowest severity the caller
+// sees in the inbox and gets pushed
+func (h *Handler) UpdateMessageSubscription(c *gin.Context) {
+	var sub model.MessageSubscription
+	if err := c.ShouldBindJSON(&sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	types := splitList(sub.Types)
+	for _, t := range types {
+		if !slices.Contains(messageTypes, t) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown message type " + t})
+			return
+		}
+	}
+	if sub.MinSeverity == "" {
+		sub.MinSeverity = "info"
+	}
+	if _, ok := severityRanks[sub.MinSeverity]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown severity " + sub.MinSeverity})
+		return
+	}
+	user, _ := c.Get("username")
+	sub.Username, _ = user.(string)
+	sub.Types = strings.Join(types, ",")
+	sub.UpdatedAt = h.Now()
+	if err := h.DB.Save(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save subscription"})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// pushMessage sends a NEW_MESSAGE or MESSAGE_UPDATE to the users subscribed to the
+// message, each with their own read state. Users who archived it are left alone, only
+// accounts that still exist are looked up so a deleted user's socket gets nothing.
+func (h *Handler) pushMessage(kind string, msg model.Message) {
+	var usernames []string
+	h.DB.Model(&model.User{}).Pluck("username", &usernames)
+	var subs []model.MessageSubscription
+	h.DB.Find(&subs)
+	byUser := make(map[string]model.MessageSubscription, len(subs))
+	for _, sub := range subs {
+		byUser[sub.Username] = sub
+	}
+	var states []model.MessageState
+	h.DB.Where("message_id = ?", msg.ID).Find(&states)
+	byState := make(map[string]model.MessageState, len(states))
+	for _, state := range states {
+		byState[state.Username] = state
+	}
+
+	for _, username := range usernames {
+		sub, ok := byUser[username]
+		if !ok {
+			sub = model.MessageSubscription{MinSeverity: "info"}
+		}
+		state := byState[username]
+		if state.Archived || !subscribedTo(sub, msg) {
+			continue
+		}
+		payload, _ := json.Marshal(map[string]interface{}{
+			"type": kind,
+			"data": inboxMessage{Message: msg, Read: state.Read},
+		})
+		h.Hub.SendToUser(username, payload)
+	}
+}
+
+// socketTicketTTL is how long a socket ticket waits for its upgrade request
+const socketTicketTTL = 30 * time.Second
+
+type socketTicket struct {
+	username string
+	expires  time.Time
+}
+
+var (
+	socketTicketMu sync.Mutex
+	socketTickets  = make(map[string]socketTicket)
+)
+
+// IssueSocketTicket hands the signed in user a single use ticket for the websocket
+// upgrade. Browsers can not set headers on it and query strings end up in access
+// logs, so the session token itself never goes in the URL.
+func (h *Handler) IssueSocketTicket(c *gin.Context) {
+	username := c.GetString("username")
+	ticket := hex.EncodeToString(randomBytes(24))
+	now := h.Now()
+	socketTicketMu.Lock()
+	for key, t := range socketTickets {
+		if now.After(t.expires) {
+			delete(socketTickets, key)
+		}
+	}
+	socketTickets[ticket] = socketTicket{username: username, expires: now.Add(socketTicketTTL)}
+	socketTicketMu.Unlock()
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket, "expiresIn": int(socketTicketTTL.Seconds())})
+}
+
+// SocketUser redeems the ticket parameter of a websocket connection for its user.
+// Probes connect without one, an unknown, expired or reused ticket or a user that
+// was deleted since gives an anonymous connection.
+func (h *Handler) SocketUser(c *gin.Context) string {
+	raw := c.Query("ticket")
+	if raw == "" {
+		return ""
+	}
+	socketTicketMu.Lock()
+	t, ok := socketTickets[raw]
+	delete(socketTickets, raw)
+	socketTicketMu.Unlock()
+	if !ok || h.Now().After(t.expires) {
+		return ""
+	}
+	var count int64
+	h.DB.Model(&model.User{}).Where("username = ?", t.username).Count(&count)
+	if count == 0 {
+		return ""
+	}
+	return t.username
+}
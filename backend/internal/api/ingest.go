@@ -0,0 +1,396 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"backend/internal/model"
+	"backend/internal/ulid"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ingestMaxBody bounds one request to the ingest API or an adapter, signed or not,
+	// ingestMaxBatch the events in it
+	ingestMaxBody  = 8 << 20
+	ingestMaxBatch = 1000
+	// ingestMaxFuture and ingestMaxAge bound the time a sensor may give an event, a time
+	// ahead of ours within ingestMaxFuture is taken as now
+	ingestMaxFuture  = 5 * time.Minute
+	ingestMaxAge     = 7 * 24 * time.Hour
+	ingestMaxPayload = 64 << 10
+	// rejectionMaxPayload is how much of a refused event its dead letter keeps
+	rejectionMaxPayload = 16 << 10
+)
+
+var (
+	attackSeverities = []string{"low", "medium", "high", "critical"}
+	attackStatuses   = []string{"blocked", "monitored", "compromised"}
+	// ingestServicePattern is what an attack method or a credential service may look like
+	ingestServicePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 ._/+-]{0,31}$`)
+	// ndjsonTypes are the content types that post one event per line
+	ndjsonTypes = map[string]bool{
+		"application/x-ndjson": true, "application/ndjson": true, "application/jsonl": true, "application/x-jsonlines": true,
+	}
+)
+
+// ingestAccept validates one event posted with a key. It returns how to store the event,
+// store answers with its ID and whatever else the endpoint reports, or why it is refused.
+type ingestAccept func(key model.IngestKey, raw json.RawMessage) (store func() (gin.H, error), err error)
+
+// ingestBatchOutcome answers a batch, events are stored or refused one by one
+type ingestBatchOutcome struct {
+	Status   string             `json:"status"` // success, partial, rejected
+	Accepted int                `json:"accepted"`
+	Rejected int                `json:"rejected"`
+	IDs      []string           `json:"ids"`              // In the order of the batch, empty where the event was refused
+	Errors   []ingestEventError `json:"errors,omitempty"` // The first ones
+}
+
+type ingestEventError struct {
+	Index       int    `json:"index"` // From 1
+	Error       string `json:"error"`
+	RejectionID string `json:"rejectionId,omitempty"`
+}
+
+// ingestBatch stores the events of a request posted with a key: one JSON object, a JSON
+// array of them or NDJSON. The whole batch is charged to the rate limit of the key up
+// front, then every event that passes validation is stored under a ULID and every other
+// one is kept as a dead letter. A lone object is answered like before batches.
+func (h *Handler) ingestBatch(c *gin.Context, endpoint string, accept ingestAccept) {
+	key := c.MustGet(ingestKeyContext).(model.IngestKey)
+	items, single, err := readIngestBatch(c)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status, err = http.StatusRequestEntityTooLarge, fmt.Errorf("Request body is larger than %d bytes", ingestMaxBody)
+		}
+		ingestFailures.Inc("invalid")
+		h.rejectIngest(c, key, endpoint, 0, "", err)
+		h.noteIngestKeyUse(c, key, 0, 1, false)
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if len(items) > ingestMaxBatch {
+		ingestFailures.Inc("invalid")
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("A batch holds at most %d events", ingestMaxBatch)})
+		return
+	}
+	if ok, wait := takeIngestTokens(key, len(items)); !ok {
+		ingestFailures.Inc("rate_limited")
+		h.noteIngestKeyUse(c, key, 0, 0, true)
+		if wait == 0 {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("%d events are more than the burst of %d of this key", len(items), ingestBurst(key))})
+			return
+		}
+		c.Header("Retry-After", retryAfter(wait))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit of the key exceeded"})
+		return
+	}
+
+	out := ingestBatchOutcome{IDs: make([]string, len(items))}
+	var stored gin.H
+	var refused ingestEventError
+	storageFailed := false
+	for i, raw := range items {
+		store, err := accept(key, raw)
+		var res gin.H
+		reason := err
+		if err != nil {
+			ingestFailures.Inc("invalid")
+		} else if res, err = store(); err != nil {
+			log.Printf("Failed to save ingested %s: %v", endpoint, err)
+			ingestFailures.Inc("storage")
+			storageFailed = true
+			reason = fmt.Errorf("failed to save %s", endpoint)
+		}
+		if err != nil {
+			out.Rejected++
+			refused = ingestEventError{Index: i + 1, Error: reason.Error(), RejectionID: h.rejectIngest(c, key, endpoint, i+1, string(raw), err)}
+			if len(out.Errors) < adapterMaxErrors {
+				out.Errors = append(out.Errors, refused)
+			}
+			continue
+		}
+		out.Accepted++
+		out.IDs[i], _ = res["id"].(string)
+		stored = res
+	}
+	h.noteIngestKeyUse(c, key, out.Accepted, out.Rejected, false)
+
+	if single {
+		switch {
+		case out.Accepted == 1:
+			stored["status"] = "success"
+			c.JSON(http.StatusOK, stored)
+		case storageFailed:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": refused.Error, "rejectionId": refused.RejectionID})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": refused.Error, "rejectionId": refused.RejectionID})
+		}
+		return
+	}
+	status := http.StatusOK
+	switch {
+	case out.Rejected == 0:
+		out.Status = "success"
+	case out.Accepted > 0:
+		out.Status = "partial"
+	default:
+		out.Status, status = "rejected", http.StatusBadRequest
+	}
+	c.JSON(status, out)
+}
+
+// readIngestBatch splits the body into its events, single tells a lone object from a
+// batch of one. NDJSON lines are split as they are so that one broken line is refused on
+// its own, JSON must be valid throughout.
+func readIngestBatch(c *gin.Context) (items []json.RawMessage, single bool, err error) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, ingestMaxBody))
+	if err != nil {
+		return nil, false, err
+	}
+	body = bytes.TrimSpace(body)
+	switch {
+	case len(body) == 0:
+		return nil, false, errors.New("Request body is empty")
+	case body[0] == '[':
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, false, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		return items, false, nil
+	case ndjsonTypes[c.ContentType()]:
+		for _, line := range bytes.Split(body, []byte("\n")) {
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				items = append(items, json.RawMessage(line))
+			}
+		}
+		return items, false, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, false, fmt.Errorf("invalid JSON after %d events: %w", len(items), err)
+		}
+		items = append(items, raw)
+	}
+	return items, len(items) == 1, nil
+}
+
+// decodeIngestEvent decodes one event into v, fields v does not have are refused
+func decodeIngestEvent(raw json.RawMessage, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid event: %s", strings.TrimPrefix(err.Error(), "json: "))
+	}
+	if dec.More() {
+		return errors.New("invalid event: data after the object")
+	}
+	return nil
+}
+
+// rejectIngest keeps a refused event as a dead letter and returns its ID
+func (h *Handler) rejectIngest(c *gin.Context, key model.IngestKey, endpoint string, index int, payload string, reason error) string {
+	if len(payload) > rejectionMaxPayload {
+		payload = strings.ToValidUTF8(payload[:rejectionMaxPayload], "")
+	}
+	r := model.IngestRejection{
+		ID: ulid.New(), Time: h.Now(), Endpoint: endpoint, KeyID: key.KeyID, Sensor: key.Sensor,
+		RemoteIP: c.RemoteIP(), Index: index, Error: truncateRunes(reason.Error(), 1000), Payload: payload,
+	}
+	if err := h.DB.Create(&r).Error; err != nil {
+		log.Printf("Failed to keep refused ingest event: %v", err)
+		return ""
+	}
+	return r.ID
+}
+
+// checkIngestSensor refuses events that claim to come from another sensor than the key's
+func checkIngestSensor(key model.IngestKey, names ...string) error {
+	for _, name := range names {
+		if name != "" && name != key.Sensor {
+			return fmt.Errorf("the key is scoped to sensor %s, not %s", key.Sensor, truncateRunes(name, 64))
+		}
+	}
+	return nil
+}
+
+// ingestEnum lowercases a value and checks it against the allowed ones, an empty value
+// is the fallback or, without one, missing
+func ingestEnum(field, value string, allowed []string, fallback string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		value = fallback
+	}
+	if value == "" {
+		return "", fmt.Errorf("%s is required", field)
+	}
+	if !slices.Contains(allowed, value) {
+		return "", fmt.Errorf("%s %q is not one of %s", field, truncateRunes(value, 32), strings.Join(allowed, ", "))
+	}
+	return value, nil
+}
+
+func ingestIP(field, value string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("%s is required", field)
+	}
+	return adapterIP(field, value)
+}
+
+func checkIngestLength(field, value string, max int) error {
+	if len([]rune(value)) > max {
+		return fmt.Errorf("%s can be at most %d characters", field, max)
+	}
+	return nil
+}
+
+// ingestTime checks the time a sensor gave an event, zero when it gave none
+func (h *Handler) ingestTime(field string, t *time.Time) (time.Time, error) {
+	if t == nil || t.IsZero() {
+		return time.Time{}, nil
+	}
+	now := h.Now()
+	at := t.UTC()
+	switch {
+	case at.After(now.Add(ingestMaxFuture)):
+		return at, fmt.Errorf("%s %s is ahead of the server clock", field, at.Format(time.RFC3339))
+	case at.Before(now.Add(-ingestMaxAge)):
+		return at, fmt.Errorf("%s %s is more than %d days old", field, at.Format(time.RFC3339), int(ingestMaxAge/(24*time.Hour)))
+	case at.After(now):
+		return now, nil
+	}
+	return at, nil
+}
+
+// attackEvent is an attack as sensors post it
+type attackEvent struct {
+	ID        string     `json:"id"` // Ignored, the server assigns IDs, older senders still set one
+	Timestamp *time.Time `json:"timestamp"`
+	SourceIP  string     `json:"sourceIp"`
+	Location  string     `json:"location"`
+	Method    string     `json:"method"`
+	Payload   string     `json:"payload"`
+	Severity  string     `json:"severity"`
+	Status    string     `json:"status"`
+	Node      string     `json:"node"`   // A probe posting for itself, its clock drift is corrected
+	Sensor    string     `json:"sensor"` // Must be the sensor of the key when given
+}
+
+func (h *Handler) acceptAttack(key model.IngestKey, raw json.RawMessage) (func() (gin.H, error), error) {
+	var ev attackEvent
+	if err := decodeIngestEvent(raw, &ev); err != nil {
+		return nil, err
+	}
+	if err := checkIngestSensor(key, ev.Node, ev.Sensor); err != nil {
+		return nil, err
+	}
+	ip, err := ingestIP("sourceIp", ev.SourceIP)
+	if err != nil {
+		return nil, err
+	}
+	severity, err := ingestEnum("severity", ev.Severity, attackSeverities, "")
+	if err != nil {
+		return nil, err
+	}
+	status, err := ingestEnum("status", ev.Status, attackStatuses, "monitored")
+	if err != nil {
+		return nil, err
+	}
+	if !ingestServicePattern.MatchString(ev.Method) {
+		return nil, errors.New("method must be 1 to 32 letters, digits, spaces and . _ / + -")
+	}
+	if err := checkIngestLength("location", ev.Location, 128); err != nil {
+		return nil, err
+	}
+	if len(ev.Payload) > ingestMaxPayload {
+		return nil, fmt.Errorf("payload can be at most %d bytes", ingestMaxPayload)
+	}
+	// A probe's clock is corrected first so that its drift does not get the event refused
+	stamped := ev.Timestamp
+	if stamped != nil && !stamped.IsZero() {
+		corrected := h.probeClockTime(ev.Node, *stamped)
+		stamped = &corrected
+	}
+	at, err := h.ingestTime("timestamp", stamped)
+	if err != nil {
+		return nil, err
+	}
+	if at.IsZero() {
+		at = h.Now()
+	}
+
+	attack := model.AttackLog{
+		ID: ulid.New(), Timestamp: at, SourceIP: ip, Location: ev.Location,
+		Method: ev.Method, Payload: ev.Payload, Severity: severity, Status: status, Node: ev.Node, Sensor: key.Sensor,
+	}
+	return func() (gin.H, error) {
+		if err := h.recordAttack(attack); err != nil {
+			return nil, err
+		}
+		return gin.H{"id": attack.ID}, nil
+	}, nil
+}
+
+// credentialEvent is a captured login attempt as sensors post it
+type credentialEvent struct {
+	Service  string     `json:"service"`
+	Username string     `json:"username"`
+	Password string     `json:"password"`
+	IP       string     `json:"ip"`
+	Time     *time.Time `json:"time"`
+	Sensor   string     `json:"sensor"` // Must be the sensor of the key when given
+}
+
+func (h *Handler) acceptCredential(key model.IngestKey, raw json.RawMessage) (func() (gin.H, error), error) {
+	var ev credentialEvent
+	if err := decodeIngestEvent(raw, &ev); err != nil {
+		return nil, err
+	}
+	if err := checkIngestSensor(key, ev.Sensor); err != nil {
+		return nil, err
+	}
+	if !ingestServicePattern.MatchString(ev.Service) {
+		return nil, errors.New("service must be 1 to 32 letters, digits, spaces and . _ / + -")
+	}
+	ip, err := ingestIP("ip", ev.IP)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIngestLength("username", ev.Username, 256); err != nil {
+		return nil, err
+	}
+	if err := checkIngestLength("password", ev.Password, 256); err != nil {
+		return nil, err
+	}
+	at, err := h.ingestTime("time", ev.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt := model.AccountCredential{
+		Service: ev.Service, Username: ev.Username, Password: ev.Password, IP: ip, Time: at, Sensor: key.Sensor,
+	}
+	return func() (gin.H, error) {
+		cred, err := h.RecordCredential(attempt)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"id": cred.ID, "count": cred.Count}, nil
+	}, nil
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testIngestKey = model.IngestKey{KeyID: "k1", Sensor: "probe-1"}
+
+func attackJSON(fields map[string]interface{}) json.RawMessage {
+	ev := map[string]interface{}{"sourceIp": "203.0.113.9", "method": "SSH", "severity": "high"}
+	for k, v := range fields {
+		if v == nil {
+			delete(ev, k)
+		} else {
+			ev[k] = v
+		}
+	}
+	raw, _ := json.Marshal(ev)
+	return raw
+}
+
+func TestAcceptAttackValidation(t *testing.T) {
+	h := newTestHandler(t, &model.NodeStatus{}, &model.SystemConfig{})
+	now := h.Now()
+
+	for _, tc := range []struct {
+		name   string
+		fields map[string]interface{}
+		want   string // Part of the error, empty when the event is accepted
+	}{
+		{"minimal", nil, ""},
+		{"status default and case", map[string]interface{}{"severity": "CRITICAL"}, ""},
+		{"own sensor", map[string]interface{}{"sensor": "probe-1", "node": "probe-1"}, ""},
+		{"legacy id", map[string]interface{}{"id": "whatever"}, ""},
+		{"unknown field", map[string]interface{}{"extra": 1}, `unknown field "extra"`},
+		{"other sensor", map[string]interface{}{"sensor": "probe-2"}, "scoped to sensor probe-1"},
+		{"other node", map[string]interface{}{"node": "probe-2"}, "scoped to sensor probe-1"},
+		{"no source", map[string]interface{}{"sourceIp": nil}, "sourceIp is required"},
+		{"bad source", map[string]interface{}{"sourceIp": "10.0.0.300"}, "not an IP address"},
+		{"no severity", map[string]interface{}{"severity": nil}, "severity is required"},
+		{"bad severity", map[string]interface{}{"severity": "urgent"}, `severity "urgent" is not one of`},
+		{"bad status", map[string]interface{}{"status": "gone"}, `status "gone" is not one of`},
+		{"no method", map[string]interface{}{"method": nil}, "method must be"},
+		{"bad method", map[string]interface{}{"method": "SSH;DROP"}, "method must be"},
+		{"long location", map[string]interface{}{"location": strings.Repeat("é", 129)}, "location can be at most 128"},
+		{"large payload", map[string]interface{}{"payload": strings.Repeat("x", ingestMaxPayload+1)}, "payload can be at most"},
+		{"slightly ahead", map[string]interface{}{"timestamp": now.Add(time.Minute)}, ""},
+		{"far ahead", map[string]interface{}{"timestamp": now.Add(time.Hour)}, "ahead of the server clock"},
+		{"too old", map[string]interface{}{"timestamp": now.Add(-8 * 24 * time.Hour)}, "more than 7 days old"},
+		{"wrong type", map[string]interface{}{"severity": 3}, "invalid event"},
+	} {
+		store, err := h.acceptAttack(testIngestKey, attackJSON(tc.fields))
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: refused: %v", tc.name, err)
+		case tc.want == "" && store == nil:
+			t.Errorf("%s: accepted without a store", tc.name)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: error %v, want one with %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestAcceptAttackCorrectsDriftFirst(t *testing.T) {
+	h := newTestHandler(t, &model.NodeStatus{}, &model.SystemConfig{})
+	checked := h.Now()
+	// The probe's clock runs 10 minutes ahead, more than an event may be in the future
+	if err := h.DB.Create(&model.NodeStatus{ID: "probe-1", ClockOffset: 10 * 60 * 1000, ClockCheckedAt: &checked}).Error; err != nil {
+		t.Fatal(err)
+	}
+	stamped := h.Now().Add(8 * time.Minute)
+
+	if _, err := h.acceptAttack(testIngestKey, attackJSON(map[string]interface{}{"node": "probe-1", "timestamp": stamped})); err != nil {
+		t.Fatalf("event of a drifting probe refused: %v", err)
+	}
+	// Without the node the time is taken as it is
+	if _, err := h.acceptAttack(testIngestKey, attackJSON(map[string]interface{}{"timestamp": stamped})); err == nil {
+		t.Fatal("event 8 minutes ahead accepted without a node to correct it")
+	}
+}
+
+func TestIngestTime(t *testing.T) {
+	h := newTestHandler(t)
+	now := h.Now()
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+
+	if got, err := h.ingestTime("time", nil); err != nil || !got.IsZero() {
+		t.Fatalf("no time = %v, %v, want zero", got, err)
+	}
+	if got, err := h.ingestTime("time", at(-time.Hour)); err != nil || !got.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("an hour ago = %v, %v", got, err)
+	}
+	// Ahead within the allowance is taken as now
+	if got, err := h.ingestTime("time", at(2*time.Minute)); err != nil || got.After(h.Now()) {
+		t.Fatalf("2 minutes ahead = %v, %v, want now", got, err)
+	}
+	if got, _ := h.ingestTime("time", at(-time.Hour)); got.Location() != time.UTC {
+		t.Fatalf("time in %v, want UTC", got.Location())
+	}
+}
+
+func TestAcceptCredentialValidation(t *testing.T) {
+	h := newTestHandler(t)
+	for body, want := range map[string]string{
+		`{"service":"SSH","username":"root","password":"x","ip":"::ffff:10.0.0.1"}`:       "",
+		`{"service":"SSH","username":"root","ip":"10.0.0.1","sensor":"other"}`:            "scoped to sensor",
+		`{"service":"","username":"root","ip":"10.0.0.1"}`:                                "service must be",
+		`{"service":"SSH","username":"root"}`:                                             "ip is required",
+		`{"service":"SSH","username":"root","ip":"10.0.0.1","port":22}`:                   `unknown field "port"`,
+		`{"service":"SSH","ip":"10.0.0.1","password":"` + strings.Repeat("p", 257) + `"}`: "password can be at most 256",
+		`{"service":"SSH","ip":"10.0.0.1"} {}`:                                            "data after the object",
+	} {
+		_, err := h.acceptCredential(testIngestKey, json.RawMessage(body))
+		switch {
+		case want == "" && err != nil:
+			t.Errorf("%s: refused: %v", body, err)
+		case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+			t.Errorf("%.60s: error %v, want one with %q", body, err, want)
+		}
+	}
+}
+
+func TestReadIngestBatch(t *testing.T) {
+	for _, tc := range []struct {
+		name, contentType, body string
+		events                  int
+		single                  bool
+		err                     string
+	}{
+		{"object", "application/json", `{"a":1}`, 1, true, ""},
+		{"array", "application/json", ` [{"a":1},{"b":2}] `, 2, false, ""},
+		{"array of one", "application/json", `[{"a":1}]`, 1, false, ""},
+		{"concatenated", "application/json", `{"a":1}{"b":2}`, 2, false, ""},
+		{"ndjson", "application/x-ndjson", "{\"a\":1}\n\n{broken\n{\"b\":2}\n", 3, false, ""},
+		{"empty", "application/json", "  \n", 0, false, "body is empty"},
+		{"broken array", "application/json", `[{"a":1},`, 0, false, "invalid JSON array"},
+		{"broken object", "application/json", `{"a":1} {"b"`, 0, false, "invalid JSON after 1 events"},
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/ingest", strings.NewReader(tc.body))
+		c.Request.Header.Set("Content-Type", tc.contentType)
+		items, single, err := readIngestBatch(c)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: error %v, want one with %q", tc.name, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || len(items) != tc.events || single != tc.single {
+			t.Errorf("%s: %d events, single %v, %v, want %d and %v", tc.name, len(items), single, err, tc.events, tc.single)
+		}
+	}
+}
+
+func TestReadIngestBatchBodyLimit(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	body := fmt.Sprintf(`{"payload":"%s"}`, strings.Repeat("x", ingestMaxBody))
+	c.Request = httptest.NewRequest("POST", "/ingest", strings.NewReader(body))
+	if _, _, err := readIngestBatch(c); err == nil {
+		t.Fatal("a body over ingestMaxBody was read")
+	}
+}
@@ -0,0 +1,407 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	ingestKeyPrefix = "prts_"
+	// ingestKeyContext is where IngestKeyRequired leaves the key for the handlers
+	ingestKeyContext = "ingestKey"
+	// ingestSignatureSkew is how far the timestamp of a signed request may be off our
+	// clock, a signature is remembered that long so it can not be replayed
+	ingestSignatureSkew = 5 * time.Minute
+	// ingestDefaultRateLimit is the events per minute of a key created without a limit
+	ingestDefaultRateLimit = 600
+	ingestMaxRateLimit     = 1000000
+
+	// ingestRejectionRetention and ingestRejectionMax bound the dead letters, the
+	// oldest go first
+	ingestRejectionRetention = 30 * 24 * time.Hour
+	ingestRejectionMax       = 50000
+)
+
+var ingestSensorPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,63}$`)
+
+// ingestBuckets are the token buckets of the keys by ID, they fill at the key's rate up
+// to its burst
+var ingestBuckets = struct {
+	sync.Mutex
+	m map[string]*ingestBucket
+}{m: map[string]*ingestBucket{}}
+
+type ingestBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// takeIngestTokens charges n events to the bucket of a key. When it does not hold them
+// nothing is taken and wait says when it will, a wait of zero means never: n is over
+// the burst of the key.
+func takeIngestTokens(key model.IngestKey, n int) (ok bool, wait time.Duration) {
+	rate := float64(key.RateLimit) / 60 // Per second
+	burst := float64(ingestBurst(key))
+	if float64(n) > burst || rate <= 0 {
+		return false, 0
+	}
+	now := time.Now()
+	ingestBuckets.Lock()
+	defer ingestBuckets.Unlock()
+	b := ingestBuckets.m[key.ID]
+	if b == nil {
+		b = &ingestBucket{tokens: burst, last: now}
+		ingestBuckets.m[key.ID] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < float64(n) {
+		return false, time.Duration((float64(n) - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens -= float64(n)
+	return true, 0
+}
+
+func ingestBurst(key model.IngestKey) int {
+	if key.Burst > 0 {
+		return key.Burst
+	}
+	return max(key.RateLimit, 1)
+}
+
+func resetIngestBucket(id string) {
+	ingestBuckets.Lock()
+	delete(ingestBuckets.m, id)
+	ingestBuckets.Unlock()
+}
+
+// retryAfter is the Retry-After header for a wait, in whole seconds rounded up
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
+
+// ingestSignatures are the signatures seen within the skew, by when they may be forgotten
+var ingestSignatures = struct {
+	sync.Mutex
+	m         map[string]time.Time
+	lastPrune time.Time
+}{m: map[string]time.Time{}}
+
+// firstUse records a signature and reports whether it is new
+func firstUse(signature string, now time.Time) bool {
+	ingestSignatures.Lock()
+	defer ingestSignatures.Unlock()
+	if now.Sub(ingestSignatures.lastPrune) > time.Minute {
+		for sig, until := range ingestSignatures.m {
+			if now.After(until) {
+				delete(ingestSignatures.m, sig)
+			}
+		}
+		ingestSignatures.lastPrune = now
+	}
+	if until, seen := ingestSignatures.m[signature]; seen && now.Before(until) {
+		return false
+	}
+	ingestSignatures.m[signature] = now.Add(2 * ingestSignatureSkew)
+	return true
+}
+
+// ingestKeyString is the key as sensors hold it
+func ingestKeyString(key model.IngestKey) string {
+	return ingestKeyPrefix + key.KeyID + "_" + key.Secret
+}
+
+// IngestSignature signs a request to the ingest API: HMAC-SHA256 under the whole key of
+// the timestamp in Unix seconds, the method, the path with its query and the SHA-256 of
+// the body, one per line, in lowercase hex
+func IngestSignature(key, timestamp, method, requestURI string, body []byte) string {
+	digest := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s", timestamp, method, requestURI, hex.EncodeToString(digest[:]))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var errInvalidIngestKey = errors.New("Invalid ingest key")
+
+// IngestKeyRequired authenticates the sensors posting to the ingest API. A request
+// carries its key as a bearer token, or is signed with it: X-Prts-Key-Id names the key,
+// X-Prts-Timestamp and X-Prts-Signature carry the IngestSignature.
+func (h *Handler) IngestKeyRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, status, err := h.authenticateIngest(c)
+		if err != nil {
+			ingestFailures.Inc("unauthorized")
+			if status == http.StatusUnauthorized {
+				c.Header("WWW-Authenticate", `Bearer realm="prts ingest"`)
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(ingestKeyContext, key)
+		c.Next()
+	}
+}
+
+func (h *Handler) authenticateIngest(c *gin.Context) (model.IngestKey, int, error) {
+	var key model.IngestKey
+	signature := c.GetHeader("X-Prts-Signature")
+	if signature == "" {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			return key, http.StatusUnauthorized, errors.New("Ingest key required")
+		}
+		keyID, secret, _ := strings.Cut(strings.TrimPrefix(token, ingestKeyPrefix), "_")
+		if keyID == "" || h.DB.Where("key_id = ?", keyID).Limit(1).Find(&key).RowsAffected == 0 || !secretEqual(secret, key.Secret) {
+			return key, http.StatusUnauthorized, errInvalidIngestKey
+		}
+		if key.RequireSignature {
+			return key, http.StatusUnauthorized, errors.New("This key only accepts signed requests")
+		}
+		status, err := checkIngestKey(key, h.Now())
+		return key, status, err
+	}
+
+	keyID := c.GetHeader("X-Prts-Key-Id")
+	if keyID == "" || h.DB.Where("key_id = ?", keyID).Limit(1).Find(&key).RowsAffected == 0 {
+		return key, http.StatusUnauthorized, errInvalidIngestKey
+	}
+	timestamp := c.GetHeader("X-Prts-Timestamp")
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	now := h.Now()
+	if err != nil || now.Sub(time.Unix(sec, 0)).Abs() > ingestSignatureSkew {
+		return key, http.StatusUnauthorized, errors.New("X-Prts-Timestamp is missing or too far from the server clock")
+	}
+	// The body is signed, read it here and hand the handler a copy
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, ingestMaxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return key, http.StatusRequestEntityTooLarge, fmt.Errorf("Request body is larger than %d bytes", ingestMaxBody)
+		}
+		return key, http.StatusBadRequest, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	want := IngestSignature(ingestKeyString(key), timestamp, c.Request.Method, c.Request.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(want)) {
+		return key, http.StatusUnauthorized, errors.New("Invalid request signature")
+	}
+	if !firstUse(key.ID+":"+want, time.Now()) {
+		return key, http.StatusUnauthorized, errors.New("Request signature was already used")
+	}
+	status, err := checkIngestKey(key, now)
+	return key, status, err
+}
+
+func checkIngestKey(key model.IngestKey, now time.Time) (int, error) {
+	switch {
+	case !key.Enabled:
+		return http.StatusForbidden, errors.New("Ingest key is disabled")
+	case key.ExpiresAt != nil && now.After(*key.ExpiresAt):
+		return http.StatusForbidden, errors.New("Ingest key has expired")
+	}
+	return 0, nil
+}
+
+// noteIngestKeyUse adds a request to the counters of its key
+func (h *Handler) noteIngestKeyUse(c *gin.Context, key model.IngestKey, accepted, rejected int, throttled bool) {
+	ingestKeyEvents.Add(float64(accepted), key.KeyID, "accepted")
+	ingestKeyEvents.Add(float64(rejected), key.KeyID, "rejected")
+	updates := map[string]interface{}{
+		"accepted":     gorm.Expr("accepted + ?", accepted),
+		"rejected":     gorm.Expr("rejected + ?", rejected),
+		"last_used_at": h.Now(),
+		"last_used_ip": c.RemoteIP(),
+	}
+	if throttled {
+		ingestKeyEvents.Inc(key.KeyID, "throttled")
+		updates["throttled"] = gorm.Expr("throttled + 1")
+	}
+	h.DB.Model(&model.IngestKey{}).Where("id = ?", key.ID).Updates(updates)
+}
+
+func newIngestKeyID() string {
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes(5)))
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+func normalizeIngestKey(k *model.IngestKey) error {
+	k.Name = strings.TrimSpace(k.Name)
+	k.Sensor = strings.TrimSpace(k.Sensor)
+	switch {
+	case k.Name == "" || len([]rune(k.Name)) > 64:
+		return errors.New("name must be 1 to 64 characters")
+	case !ingestSensorPattern.MatchString(k.Sensor):
+		return errors.New("sensor must be 1 to 64 letters, digits and . _ : -, starting with a letter or digit")
+	case k.RateLimit < 0 || k.RateLimit > ingestMaxRateLimit:
+		return fmt.Errorf("rateLimit must be between 0 and %d", ingestMaxRateLimit)
+	case k.Burst < 0 || k.Burst > ingestMaxRateLimit:
+		return fmt.Errorf("burst must be between 0 and %d", ingestMaxRateLimit)
+	}
+	if k.RateLimit == 0 {
+		k.RateLimit = ingestDefaultRateLimit
+	}
+	if k.ExpiresAt != nil {
+		at := k.ExpiresAt.UTC()
+		k.ExpiresAt = &at
+	}
+	return nil
+}
+
+// ingestKeyIssued is a key as created or rotated, the only time its secret is shown
+type ingestKeyIssued struct {
+	model.IngestKey
+	Key string `json:"key"`
+}
+
+func (h *Handler) GetIngestKeys(c *gin.Context) {
+	keys := []model.IngestKey{}
+	h.DB.Order("created_at").Find(&keys)
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *Handler) CreateIngestKey(c *gin.Context) {
+	var req model.IngestKey
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeIngestKey(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key := model.IngestKey{
+		ID: fmt.Sprintf("IK-%d", time.Now().UnixNano()), Name: req.Name, Sensor: req.Sensor, Enabled: req.Enabled,
+		RequireSignature: req.RequireSignature, RateLimit: req.RateLimit, Burst: req.Burst, ExpiresAt: req.ExpiresAt,
+		KeyID: newIngestKeyID(), Secret: hex.EncodeToString(randomBytes(24)),
+	}
+	if err := h.DB.Create(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create key"})
+		return
+	}
+	c.JSON(http.StatusOK, ingestKeyIssued{IngestKey: key, Key: ingestKeyString(key)})
+}
+
+// UpdateIngestKey changes the settings of a key, its secret and counters stay
+func (h *Handler) UpdateIngestKey(c *gin.Context) {
+	var key model.IngestKey
+	if err := h.DB.First(&key, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+	var req model.IngestKey
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeIngestKey(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key.Name, key.Sensor, key.Enabled, key.RequireSignature = req.Name, req.Sensor, req.Enabled, req.RequireSignature
+	key.RateLimit, key.Burst, key.ExpiresAt = req.RateLimit, req.Burst, req.ExpiresAt
+	if err := h.DB.Save(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update key"})
+		return
+	}
+	resetIngestBucket(key.ID)
+	c.JSON(http.StatusOK, key)
+}
+
+// RotateIngestKey replaces the secret of a key, the old one stops working at once
+func (h *Handler) RotateIngestKey(c *gin.Context) {
+	var key model.IngestKey
+	if err := h.DB.First(&key, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+	key.Secret = hex.EncodeToString(randomBytes(24))
+	if err := h.DB.Save(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate key"})
+		return
+	}
+	c.JSON(http.StatusOK, ingestKeyIssued{IngestKey: key, Key: ingestKeyString(key)})
+}
+
+func (h *Handler) DeleteIngestKey(c *gin.Context) {
+	res := h.DB.Delete(&model.IngestKey{}, "id = ?", c.Param("id"))
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete key"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key not found"})
+		return
+	}
+	resetIngestBucket(c.Param("id"))
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+var ingestRejectionListSpec = listSpec{
+	timeColumn:  "time",
+	sorts:       map[string]string{"time": "time"},
+	defaultSort: "-time",
+	filters:     map[string]string{"endpoint": "endpoint", "keyId": "key_id", "sensor": "sensor"},
+	ipFilters:   map[string]string{"ip": "remote_ip"},
+	search:      []string{"error", "payload"},
+}
+
+func (h *Handler) GetIngestRejections(c *gin.Context) {
+	listQuery[model.IngestRejection](h, c, ingestRejectionListSpec)
+}
+
+// DeleteIngestRejections clears the dead letters, or those of the key given as keyId
+func (h *Handler) DeleteIngestRejections(c *gin.Context) {
+	q := h.DB.Where("1 = 1")
+	if keyID := c.Query("keyId"); keyID != "" {
+		q = h.DB.Where("key_id = ?", keyID)
+	}
+	res := q.Delete(&model.IngestRejection{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rejected events"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "deleted": res.RowsAffected})
+}
+
+// RunIngestRejectionRetention drops dead letters past their retention, and the oldest
+// beyond the row limit
+func (h *Handler) RunIngestRejectionRetention() {
+	prune := func() {
+		h.DB.Where("time < ?", h.Now().Add(-ingestRejectionRetention)).Delete(&model.IngestRejection{})
+		var last model.IngestRejection
+		// IDs are ULIDs, they sort by when the event was rejected
+		if h.DB.Select("id").Order("id desc").Offset(ingestRejectionMax).Limit(1).Find(&last).RowsAffected > 0 {
+			h.DB.Where("id <= ?", last.ID).Delete(&model.IngestRejection{})
+		}
+	}
+	prune()
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		prune()
+	}
+}
@@ -0,0 +1,556 @@
+package api
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// leakAlertInterval suppresses repeated alerts for the same account, attacker and match kind
+const leakAlertInterval = 10 * time.Minute
+
+var (
+	leakAlertMu    sync.Mutex
+	leakAlertSeen  = map[string]time.Time{}
+	leakAlertPrune time.Time
+)
+
+// inventoryHashTriedMax bounds the tried passwords remembered with their matches
+const inventoryHashTriedMax = 10000
+
+// The salted hashes of the inventory are indexed by hash type and salt so that a tried
+// password is hashed once per salt, not once per account. Bots replay the same lists,
+// tried passwords keep their matches until the inventory changes.
+var (
+	inventoryHashMu         sync.Mutex
+	inventoryHashGroups     []inventoryHashGroup
+	inventoryHashLoaded     bool
+	inventoryHashGeneration int
+	inventoryHashTried      = map[string][]model.InventoryAccount{}
+)
+
+type inventoryHashGroup struct {
+	hashType string
+	salt     []byte
+	accounts map[string][]model.InventoryAccount // By hex digest
+}
+
+// saltedDigests are the hash types that are cheap enough to compare against every account
+var saltedDigests = map[string]func() hash.Hash{
+	"sha256":  sha256.New,
+	"sha":     sha1.New,
+	"ssha":    sha1.New,
+	"ssha256": sha256.New,
+	"ssha512": sha512.New,
+}
+
+// ldapSchemes maps RFC 2307 userPassword schemes to hash types
+var ldapSchemes = map[string]string{
+	"{SHA}":     "sha",
+	"{SSHA}":    "ssha",
+	"{SSHA256}": "ssha256",
+	"{SSHA512}": "ssha512",
+}
+
+// parseInventoryHash normalizes an imported password hash. Plain hex digests are
+// SHA-256(salt + password), LDAP values are read from their {SCHEME} prefix.
+func parseInventoryHash(raw, salt string) (hashType, digest, saltHex string, err error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", "", "", nil
+	}
+	if strings.HasPrefix(strings.ToUpper(raw), "{CRYPT}") {
+		raw = raw[len("{CRYPT}"):]
+	}
+	if strings.HasPrefix(raw, "$2a$") || strings.HasPrefix(raw, "$2b$") || strings.HasPrefix(raw, "$2y$") {
+		return "bcrypt", raw, "", nil
+	}
+	if end := strings.Index(raw, "}"); strings.HasPrefix(raw, "{") && end > 0 {
+		scheme := strings.ToUpper(raw[:end+1])
+		kind, ok := ldapSchemes[scheme]
+		if !ok {
+			return "", "", "", fmt.Errorf("unsupported password scheme %s", scheme)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(raw[end+1:])
+		size := saltedDigests[kind]().Size()
+		if err != nil || len(decoded) < size {
+			return "", "", "", fmt.Errorf("malformed %s value", scheme)
+		}
+		return kind, hex.EncodeToString(decoded[:size]), hex.EncodeToString(decoded[size:]), nil
+	}
+	if decoded, err := hex.DecodeString(raw); err == nil && len(decoded) == sha256.Size {
+		return "sha256", strings.ToLower(raw), hex.EncodeToString([]byte(salt)), nil
+	}
+	return "", "", "", errors.New("unsupported password hash, expected bcrypt, LDAP {SCHEME} or hex SHA-256")
+}
+
+// inventoryPasswordMatches compares a tried password with the stored hash of an account
+func inventoryPasswordMatches(acc model.InventoryAccount, password string) bool {
+	if acc.PasswordHash == "" || password == "" {
+		return false
+	}
+	if acc.HashType == "bcrypt" {
+		return bcrypt.CompareHashAndPassword([]byte(acc.PasswordHash), []byte(password)) == nil
+	}
+	if _, ok := saltedDigests[acc.HashType]; !ok {
+		return false
+	}
+	salt, _ := hex.DecodeString(acc.Salt)
+	want, _ := hex.DecodeString(acc.PasswordHash)
+	return subtle.ConstantTimeCompare(saltedDigest(acc.HashType, salt, password), want) == 1
+}
+
+// saltedDigest hashes a password the way the given salted hash type stores it
+func saltedDigest(hashType string, salt []byte, password string) []byte {
+	d := saltedDigests[hashType]()
+	if hashType == "sha256" {
+		// Inventory exports put the salt first, LDAP schemes append it
+		d.Write(salt)
+		d.Write([]byte(password))
+	} else {
+		d.Write([]byte(password))
+		d.Write(salt)
+	}
+	return d.Sum(nil)
+}
+
+// invalidateInventoryHashes drops the hash index after the inventory changed
+func invalidateInventoryHashes() {
+	inventoryHashMu.Lock()
+	inventoryHashLoaded = false
+	inventoryHashGroups = nil
+	inventoryHashGeneration++
+	inventoryHashTried = map[string][]model.InventoryAccount{}
+	inventoryHashMu.Unlock()
+}
+
+// passwordAccounts returns the inventory accounts with a salted hash of the password
+func (h *Handler) passwordAccounts(password string) []model.InventoryAccount {
+	inventoryHashMu.Lock()
+	if matches, ok := inventoryHashTried[password]; ok {
+		inventoryHashMu.Unlock()
+		return matches
+	}
+	if !inventoryHashLoaded {
+		var hashed []model.InventoryAccount
+		h.DB.Where("hash_type IN ?", []string{"sha256", "sha", "ssha", "ssha256", "ssha512"}).Find(&hashed)
+		bySalt := map[string]int{}
+		inventoryHashGroups = nil
+		for _, acc := range hashed {
+			key := acc.HashType + "|" + acc.Salt
+			i, ok := bySalt[key]
+			if !ok {
+				salt, _ := hex.DecodeString(acc.Salt)
+				i = len(inventoryHashGroups)
+				bySalt[key] = i
+				inventoryHashGroups = append(inventoryHashGroups, inventoryHashGroup{hashType: acc.HashType, salt: salt, accounts: map[string][]model.InventoryAccount{}})
+			}
+			digest := strings.ToLower(acc.PasswordHash)
+			inventoryHashGroups[i].accounts[digest] = append(inventoryHashGroups[i].accounts[digest], acc)
+		}
+		inventoryHashLoaded = true
+	}
+	groups, generation := inventoryHashGroups, inventoryHashGeneration
+	inventoryHashMu.Unlock()
+
+	var matches []model.InventoryAccount
+	for _, g := range groups {
+		matches = append(matches, g.accounts[hex.EncodeToString(saltedDigest(g.hashType, g.salt, password))]...)
+	}
+
+	inventoryHashMu.Lock()
+	if generation == inventoryHashGeneration {
+		if len(inventoryHashTried) >= inventoryHashTriedMax {
+			inventoryHashTried = map[string][]model.InventoryAccount{}
+		}
+		inventoryHashTried[password] = matches
+	}
+	inventoryHashMu.Unlock()
+	return matches
+}
+
+// checkLeakedCredential raises a critical alert when a captured login uses a real account
+// name or the password of a real account
+func (h *Handler) checkLeakedCredential(service, username, password, ip string) {
+	name := strings.ToLower(strings.TrimSpace(username))
+
+	var matched []model.InventoryAccount
+	if name != "" {
+		h.DB.Where("username = ? OR (email <> '' AND email = ?)", name, name).Find(&matched)
+	}
+	for _, acc := range matched {
+		kind := "username"
+		if inventoryPasswordMatches(acc, password) {
+			kind = "password"
+		}
+		h.raiseLeakAlert(acc, kind, service, username, ip)
+	}
+	if password == "" {
+		return
+	}
+
+	// A real password tried under another name still means it leaked
+	for _, acc := range h.passwordAccounts(password) {
+		if acc.Username == name || acc.Email == name {
+			continue
+		}
+		h.raiseLeakAlert(acc, "reused_password", service, username, ip)
+	}
+}
+
+func (h *Handler) raiseLeakAlert(acc model.InventoryAccount, kind, service, triedUser, ip string) {
+	now := h.Now()
+	h.DB.Model(&acc).Updates(map[string]interface{}{"hit_count": gorm.Expr("hit_count + 1"), "last_hit": now})
+
+	key := acc.ID + "|" + kind + "|" + ip
+	leakAlertMu.Lock()
+	if now.Sub(leakAlertPrune) >= leakAlertInterval {
+		for k, t := range leakAlertSeen {
+			if now.Sub(t) >= leakAlertInterval {
+				delete(leakAlertSeen, k)
+			}
+		}
+		leakAlertPrune = now
+	}
+	last, seen := leakAlertSeen[key]
+	if !seen || now.Sub(last) >= leakAlertInterval {
+		leakAlertSeen[key] = now
+	}
+	leakAlertMu.Unlock()
+	if seen && now.Sub(last) < leakAlertInterval {
+		return
+	}
+
+	log.Printf("Leaked credential: %s match for inventory account %s from %s (%s)", kind, acc.Username, ip, service)
+
+	h.raiseAlert(alert{
+		Title:    "msg_leaked_credential_title",
+		Content:  fmt.Sprintf("msg_leaked_credential_%s|account:%s,user:%s,service:%s,ip:%s", kind, acc.Username, triedUser, service, ip),
+		Type:     "security",
+		Severity: "critical",
+	})
+}
+
+// GetInventoryAccounts lists the account inventory, hashes are never returned
+func (h *Handler) GetInventoryAccounts(c *gin.Context) {
+	var accounts []model.InventoryAccount
+	h.DB.Order("username asc").Find(&accounts)
+	c.JSON(http.StatusOK, accounts)
+}
+
+func (h *Handler) DeleteInventoryAccount(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.DB.Delete(&model.InventoryAccount{}, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+	invalidateInventoryHashes()
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// importInventory upserts accounts by username. With replace set, accounts of the same
+// source that are missing from the import are dropped.
+func (h *Handler) importInventory(source string, accounts []model.InventoryAccount, replace bool) (int, int, error) {
+	now := h.Now()
+	imported, removed := 0, 0
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		keep := make([]string, 0, len(accounts))
+		for i, acc := range accounts {
+			acc.Source = source
+			acc.ImportTime = now
+			var existing model.InventoryAccount
+			if tx.Where("username = ?", acc.Username).Limit(1).Find(&existing).RowsAffected > 0 {
+				acc.ID = existing.ID
+				acc.HitCount = existing.HitCount
+				acc.LastHit = existing.LastHit
+			} else {
+				acc.ID = fmt.Sprintf("INV-%d-%d", time.Now().UnixNano(), i)
+			}
+			if err := tx.Save(&acc).Error; err != nil {
+				return err
+			}
+			keep = append(keep, acc.Username)
+			imported++
+		}
+		if replace {
+			q := tx.Where("source = ?", source)
+			if len(keep) > 0 {
+				q = q.Where("username NOT IN ?", keep)
+			}
+			res := q.Delete(&model.InventoryAccount{})
+			if res.Error != nil {
+				return res.Error
+			}
+			removed = int(res.RowsAffected)
+		}
+		return nil
+	})
+	invalidateInventoryHashes()
+	return imported, removed, err
+}
+
+// parseInventoryCSV reads username[,salt,password_hash] rows. A header row may name
+// the columns username, email, name, salt and password_hash in any order.
+func parseInventoryCSV(r io.Reader) ([]model.InventoryAccount, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	cols := map[string]int{"username": 0, "salt": 1, "password_hash": 2}
+	if len(records) > 0 {
+		header := map[string]int{}
+		for i, name := range records[0] {
+			name = strings.ToLower(strings.TrimSpace(name))
+			switch name {
+			case "hash", "password", "passwordhash":
+				name = "password_hash"
+			case "displayname", "display_name", "cn":
+				name = "name"
+			case "mail":
+				name = "email"
+			}
+			header[name] = i
+		}
+		if _, ok := header["username"]; ok {
+			cols = header
+			records = records[1:]
+		}
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	seen := map[string]bool{}
+	var accounts []model.InventoryAccount
+	for n, rec := range records {
+		username := strings.ToLower(field(rec, "username"))
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		hashType, digest, salt, err := parseInventoryHash(field(rec, "password_hash"), field(rec, "salt"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		accounts = append(accounts, model.InventoryAccount{
+			Username:     username,
+			DisplayName:  field(rec, "name"),
+			Email:        strings.ToLower(field(rec, "email")),
+			HashType:     hashType,
+			PasswordHash: digest,
+			Salt:         salt,
+		})
+	}
+	return accounts, nil
+}
+
+// ImportInventoryCSV accepts a CSV upload (multipart "file" or raw body)
+func (h *Handler) ImportInventoryCSV(c *gin.Context) {
+	var src io.Reader = c.Request.Body
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+			return
+		}
+		defer f.Close()
+		src = f
+	}
+
+	accounts, err := parseInventoryCSV(io.LimitReader(src, 32<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	imported, removed, err := h.importInventory("csv", accounts, c.Query("replace") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import accounts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "imported": imported, "removed": removed})
+}
+
+type ldapInventoryConfig struct {
+	URL                string `json:"url"` // ldap://host:389 or ldaps://host:636
+	BindDN             string `json:"bindDn"`
+	BindPassword       string `json:"bindPassword,omitempty"`
+	BaseDN             string `json:"baseDn"`
+	Filter             string `json:"filter"`
+	UsernameAttr       string `json:"usernameAttr"`
+	EmailAttr          string `json:"emailAttr"`
+	NameAttr           string `json:"nameAttr"`
+	PasswordAttr       string `json:"passwordAttr"` // Optional, e.g. userPassword when the bind account may read it
+	StartTLS           bool   `json:"startTls"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+}
+
+func (h *Handler) getLDAPInventoryConfig() ldapInventoryConfig {
+	var cfg model.SystemConfig
+	h.DB.Where("key = ?", "ldap_inventory_config").Find(&cfg)
+
+	ldapCfg := ldapInventoryConfig{}
+	if cfg.Value != "" {
+		json.Unmarshal([]byte(cfg.Value), &ldapCfg)
+	}
+	if ldapCfg.Filter == "" {
+		ldapCfg.Filter = "(objectClass=person)"
+	}
+	if ldapCfg.UsernameAttr == "" {
+		ldapCfg.UsernameAttr = "uid"
+	}
+	if ldapCfg.EmailAttr == "" {
+		ldapCfg.EmailAttr = "mail"
+	}
+	if ldapCfg.NameAttr == "" {
+		ldapCfg.NameAttr = "cn"
+	}
+	return ldapCfg
+}
+
+// GetLDAPInventoryConfig returns the directory settings without the bind password
+func (h *Handler) GetLDAPInventoryConfig(c *gin.Context) {
+	cfg := h.getLDAPInventoryConfig()
+	hasPassword := cfg.BindPassword != ""
+	cfg.BindPassword = ""
+	c.JSON(http.StatusOK, gin.H{"config": cfg, "hasPassword": hasPassword})
+}
+
+// UpdateLDAPInventoryConfig stores the directory settings, an empty password keeps the old one
+func (h *Handler) UpdateLDAPInventoryConfig(c *gin.Context) {
+	var req ldapInventoryConfig
+	if err := c.ShouldBindJSON(&req); err != nil || req.URL == "" || req.BaseDN == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url and baseDn are required"})
+		return
+	}
+	if req.BindPassword == "" {
+		req.BindPassword = h.getLDAPInventoryConfig().BindPassword
+	}
+	val, _ := json.Marshal(req)
+
+	var cfg model.SystemConfig
+	h.DB.Where("key = ?", "ldap_inventory_config").First(&cfg)
+	if cfg.ID == 0 {
+		cfg.Key = "ldap_inventory_config"
+		cfg.Value = string(val)
+		h.DB.Create(&cfg)
+	} else {
+		h.DB.Model(&cfg).Update("value", string(val))
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// fetchLDAPAccounts runs the configured search and maps entries to inventory accounts
+func fetchLDAPAccounts(cfg ldapInventoryConfig) ([]model.InventoryAccount, int, error) {
+	tlsCfg := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	conn, err := ldap.DialURL(cfg.URL, ldap.DialWithTLSConfig(tlsCfg))
+	if err != nil {
+		return nil, 0, fmt.Errorf("connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(30 * time.Second)
+
+	if cfg.StartTLS {
+		if err := conn.StartTLS(tlsCfg); err != nil {
+			return nil, 0, fmt.Errorf("starttls: %v", err)
+		}
+	}
+	if cfg.BindDN != "" {
+		if err := conn.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			return nil, 0, fmt.Errorf("bind: %v", err)
+		}
+	}
+
+	attrs := []string{cfg.UsernameAttr, cfg.EmailAttr, cfg.NameAttr}
+	if cfg.PasswordAttr != "" {
+		attrs = append(attrs, cfg.PasswordAttr)
+	}
+	req := ldap.NewSearchRequest(cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, cfg.Filter, attrs, nil)
+	res, err := conn.SearchWithPaging(req, 500)
+	if err != nil {
+		return nil, 0, fmt.Errorf("search: %v", err)
+	}
+
+	seen := map[string]bool{}
+	skipped := 0
+	var accounts []model.InventoryAccount
+	for _, entry := range res.Entries {
+		username := strings.ToLower(entry.GetAttributeValue(cfg.UsernameAttr))
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		acc := model.InventoryAccount{
+			Username:    username,
+			DisplayName: entry.GetAttributeValue(cfg.NameAttr),
+			Email:       strings.ToLower(entry.GetAttributeValue(cfg.EmailAttr)),
+		}
+		if cfg.PasswordAttr != "" {
+			if raw := entry.GetRawAttributeValue(cfg.PasswordAttr); len(raw) > 0 {
+				hashType, digest, salt, err := parseInventoryHash(string(raw), "")
+				if err != nil {
+					// Plaintext or unknown schemes are never stored
+					skipped++
+				} else {
+					acc.HashType, acc.PasswordHash, acc.Salt = hashType, digest, salt
+				}
+			}
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, skipped, nil
+}
+
+// SyncInventoryLDAP imports the directory, accounts that left it are removed
+func (h *Handler) SyncInventoryLDAP(c *gin.Context) {
+	cfg := h.getLDAPInventoryConfig()
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "LDAP directory is not configured"})
+		return
+	}
+
+	accounts, skipped, err := fetchLDAPAccounts(cfg)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "LDAP " + err.Error()})
+		return
+	}
+	if len(accounts) == 0 {
+		// Refuse to wipe the inventory because of a mistyped filter or base DN
+		c.JSON(http.StatusBadGateway, gin.H{"error": "LDAP search returned no accounts"})
+		return
+	}
+	imported, removed, err := h.importInventory("ldap", accounts, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import accounts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "imported": imported, "removed": removed, "skippedHashes": skipped})
+}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type metricTier struct {
+	name      string
+	step      time.Duration
+	retention time.Duration
+}
+
+// metricTiers go from finest to coarsest. Every report is added to all of them, so a
+// range past the retention of one tier is still answered by the next.
+var metricTiers = []metricTier{
+	{"10s", 10 * time.Second, 24 * time.Hour},
+	{"5m", 5 * time.Minute, 30 * 24 * time.Hour},
+	{"1h", time.Hour, 365 * 24 * time.Hour},
+}
+
+// metricSteps are tried in order when a request leaves the step open
+var metricSteps = []time.Duration{10 * time.Second, 30 * time.Second, time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+const (
+	// metricFlushInterval is how long reports are summed in memory before they are written
+	metricFlushInterval = 10 * time.Second
+	metricMaxPoints     = 2000
+)
+
+type metricKey struct {
+	node, tier string
+	bucket     time.Time
+}
+
+// metricBuffer sums node reports until the next flush, probes report every few seconds
+// and writing each one to every tier would keep SQLite busy
+var metricBuffer = struct {
+	sync.Mutex
+	pending map[metricKey]*model.NodeMetric
+}{pending: map[metricKey]*model.NodeMetric{}}
+
+// recordNodeMetrics adds a node report to the buckets of every tier
+func recordNodeMetrics(node model.NodeStatus, at time.Time) {
+	metricBuffer.Lock()
+	defer metricBuffer.Unlock()
+	for _, tier := range metricTiers {
+		key := metricKey{node.ID, tier.name, at.UTC().Truncate(tier.step)}
+		m := metricBuffer.pending[key]
+		if m == nil {
+			m = &model.NodeMetric{NodeID: key.node, Tier: key.tier, Bucket: key.bucket}
+			metricBuffer.pending[key] = m
+		}
+		m.Samples++
+		m.Load += float64(node.Load)
+		m.LoadMax = math.Max(m.LoadMax, float64(node.Load))
+		m.Memory += float64(node.MemoryUsage)
+		m.MemoryMax = math.Max(m.MemoryMax, float64(node.MemoryUsage))
+		if node.Temperature > 0 {
+			m.Temperature += node.Temperature
+			m.TempSamples++
+		}
+		m.NetUp += node.NetUp
+		m.NetUpMax = math.Max(m.NetUpMax, node.NetUp)
+		m.NetDown += node.NetDown
+		m.NetDownMax = math.Max(m.NetDownMax, node.NetDown)
+	}
+}
+
+// flushNodeMetrics adds the buffered sums to the stored buckets
+func (h *Handler) flushNodeMetrics() {
+	metricBuffer.Lock()
+	pending := metricBuffer.pending
+	metricBuffer.pending = map[metricKey]*model.NodeMetric{}
+	metricBuffer.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	rows := make([]model.NodeMetric, 0, len(pending))
+	for _, m := range pending {
+		rows = append(rows, *m)
+	}
+	add := func(col string) clause.Expr { return gorm.Expr("node_metrics." + col + " + excluded." + col) }
+	highest := func(col string) clause.Expr { return gorm.Expr("MAX(node_metrics." + col + ", excluded." + col + ")") }
+	err := h.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "node_id"}, {Name: "tier"}, {Name: "bucket"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"samples": add("samples"), "load": add("load"), "memory": add("memory"),
+			"temperature": add("temperature"), "temp_samples": add("temp_samples"),
+			"net_up": add("net_up"), "net_down": add("net_down"),
+			"load_max": highest("load_max"), "memory_max": highest("memory_max"),
+			"net_up_max": highest("net_up_max"), "net_down_max": highest("net_down_max"),
+		}),
+	}).CreateInBatches(rows, 500).Error
+	if err != nil {
+		log.Printf("Metrics: failed to store %d buckets: %v", len(rows), err)
+	}
+}
+
+// RunNodeMetrics writes buffered node telemetry and drops buckets past the retention
+// of their tier
+func (h *Handler) RunNodeMetrics() {
+	flush := time.NewTicker(metricFlushInterval)
+	prune := time.NewTicker(time.Hour)
+	defer flush.Stop()
+	defer prune.Stop()
+	for {
+		select {
+		case <-flush.C:
+			h.flushNodeMetrics()
+		case <-prune.C:
+			now := h.Now()
+			for _, tier := range metricTiers {
+				h.DB.Where("tier = ? AND bucket < ?", tier.name, now.Add(-tier.retention)).Delete(&model.NodeMetric{})
+			}
+		}
+	}
+}
+
+type metricPoint struct {
+	Time        time.Time `json:"time"`
+	Samples     int       `json:"samples"`
+	Load        float64   `json:"load"`
+	LoadMax     float64   `json:"loadMax"`
+	Memory      float64   `json:"memory"`
+	MemoryMax   float64   `json:"memoryMax"`
+	Temperature *float64  `json:"temperature"` // Null when the node has no sensor
+	NetUp       float64   `json:"netUp"`
+	NetUpMax    float64   `json:"netUpMax"`
+	NetDown     float64   `json:"netDown"`
+	NetDownMax  float64   `json:"netDownMax"`
+}
+
+// pickMetricTier is the coarsest tier that divides the step and still holds from
+func pickMetricTier(from time.Time, step time.Duration, now time.Time) (metricTier, error) {
+	for i := len(metricTiers) - 1; i >= 0; i-- {
+		tier := metricTiers[i]
+		if step%tier.step == 0 && !from.Before(now.Add(-tier.retention)) {
+			return tier, nil
+		}
+	}
+	return metricTier{}, errors.New("no tier keeps this step that far back, use a longer step or a shorter range")
+}
+
+// GetNodeMetrics returns the telemetry history of a node in buckets of step. Buckets
+// without reports are left out, they are gaps where the node was offline.
+func (h *Handler) GetNodeMetrics(c *gin.Context) {
+	var node model.NodeStatus
+	if h.DB.Select("id").Where("id = ?", c.Param("id")).Limit(1).Find(&node).RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+
+	now := h.Now()
+	to, from := now, now.Add(-time.Hour)
+	for param, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := c.Query(param); v != "" {
+			t, ok := parseTimeParam(v)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 time"})
+				return
+			}
+			*target = t
+		}
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	var step time.Duration
+	if v := c.Query("step"); v != "" {
+		var err error
+		if step, err = parseInterval(v, 10*time.Second); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		for _, s := range metricSteps {
+			step = s
+			if to.Sub(from)/s <= 360 {
+				break
+			}
+		}
+	}
+	if to.Sub(from)/step > metricMaxPoints {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many points, use a longer step"})
+		return
+	}
+	start := from.UTC().Truncate(step)
+	tier, err := pickMetricTier(start, step, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Reports still in the buffer belong to the answer too
+	h.flushNodeMetrics()
+
+	var rows []model.NodeMetric
+	h.DB.Where("node_id = ? AND tier = ? AND bucket >= ? AND bucket < ?", node.ID, tier.name, start, to).Order("bucket").Find(&rows)
+
+	sums := map[time.Time]*model.NodeMetric{}
+	for i := range rows {
+		r := &rows[i]
+		bucket := r.Bucket.UTC().Truncate(step)
+		s := sums[bucket]
+		if s == nil {
+			first := *r
+			first.Bucket = bucket
+			sums[bucket] = &first
+			continue
+		}
+		s.Samples += r.Samples
+		s.Load += r.Load
+		s.LoadMax = math.Max(s.LoadMax, r.LoadMax)
+		s.Memory += r.Memory
+		s.MemoryMax = math.Max(s.MemoryMax, r.MemoryMax)
+		s.Temperature += r.Temperature
+		s.TempSamples += r.TempSamples
+		s.NetUp += r.NetUp
+		s.NetUpMax = math.Max(s.NetUpMax, r.NetUpMax)
+		s.NetDown += r.NetDown
+		s.NetDownMax = math.Max(s.NetDownMax, r.NetDownMax)
+	}
+
+	points := make([]metricPoint, 0, len(sums))
+	for _, s := range sums {
+		n := float64(s.Samples)
+		p := metricPoint{
+			Time: s.Bucket, Samples: s.Samples,
+			Load: round2(s.Load / n), LoadMax: s.LoadMax,
+			Memory: round2(s.Memory / n), MemoryMax: s.MemoryMax,
+			NetUp: round2(s.NetUp / n), NetUpMax: round2(s.NetUpMax),
+			NetDown: round2(s.NetDown / n), NetDownMax: round2(s.NetDownMax),
+		}
+		if s.TempSamples > 0 {
+			temp := round2(s.Temperature / float64(s.TempSamples))
+			p.Temperature = &temp
+		}
+		points = append(points, p)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+
+	c.JSON(http.StatusOK, gin.H{
+		"nodeId": node.ID,
+		"from":   start,
+		"to":     to.UTC(),
+		"step":   formatInterval(step),
+		"tier":   tier.name,
+		"points": points,
+	})
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// systemSample is one reading of the server's own load, rates are averaged since the
+// reading before it
+type systemSample struct {
+	Time   time.Time `json:"time"`
+	CPU    float64   `json:"cpu"`
+	Memory float64   `json:"memory"`
+	Up     float64   `json:"up"` // MB/s
+	Down   float64   `json:"down"`
+}
+
+const (
+	systemSampleInterval = 5 * time.Second
+	systemSampleKeep     = 120 // Ten minutes of readings
+)
+
+var systemSamples = struct {
+	sync.Mutex
+	history []systemSample
+}{}
+
+// RunSystemSampler reads the server's CPU, memory and network every few seconds. CPU
+// and network are counters, so each reading is the delta over the interval.
+func (h *Handler) RunSystemSampler() {
+	// Prime the CPU counters, the first reading would be the average since boot
+	cpu.Percent(0, false)
+	var lastSent, lastRecv uint64
+	var lastTime time.Time
+	if io, err := net.IOCounters(false); err == nil && len(io) > 0 {
+		lastSent, lastRecv, lastTime = io[0].BytesSent, io[0].BytesRecv, time.Now()
+	}
+
+	ticker := time.NewTicker(systemSampleInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		sample := systemSample{Time: h.Now()}
+		if perc, err := cpu.Percent(0, false); err == nil && len(perc) > 0 {
+			sample.CPU = round2(perc[0])
+		}
+		if v, err := mem.VirtualMemory(); err == nil {
+			sample.Memory = round2(v.UsedPercent)
+		}
+		if io, err := net.IOCounters(false); err == nil && len(io) > 0 {
+			now := time.Now()
+			elapsed := now.Sub(lastTime).Seconds()
+			// Counters start over when an interface is reset
+			if elapsed > 0 && !lastTime.IsZero() && io[0].BytesSent >= lastSent && io[0].BytesRecv >= lastRecv {
+				sample.Up = round2(float64(io[0].BytesSent-lastSent) / (1024 * 1024) / elapsed)
+				sample.Down = round2(float64(io[0].BytesRecv-lastRecv) / (1024 * 1024) / elapsed)
+			}
+			lastSent, lastRecv, lastTime = io[0].BytesSent, io[0].BytesRecv, now
+		}
+
+		systemSamples.Lock()
+		systemSamples.history = append(systemSamples.history, sample)
+		if len(systemSamples.history) > systemSampleKeep {
+			systemSamples.history = systemSamples.history[len(systemSamples.history)-systemSampleKeep:]
+		}
+		systemSamples.Unlock()
+	}
+}
+
+// GetSystemStats returns the latest reading of the server and the ones before it
+func (h *Handler) GetSystemStats(c *gin.Context) {
+	systemSamples.Lock()
+	history := append([]systemSample(nil), systemSamples.history...)
+	systemSamples.Unlock()
+
+	latest := systemSample{Time: h.Now()}
+	if len(history) > 0 {
+		latest = history[len(history)-1]
+	} else if v, err := mem.VirtualMemory(); err == nil {
+		// Nothing sampled yet, rates need two readings
+		latest.Memory = round2(v.UsedPercent)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cpu":     int(latest.CPU),
+		"memory":  int(latest.Memory),
+		"network": gin.H{"up": latest.Up, "down": latest.Down},
+		"history": history,
+	})
+}
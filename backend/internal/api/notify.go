@@ -0,0 +1,705 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// notifyEvent is something that happened which channels can be told about. Texts are
+// i18n keys with parameters, every channel renders them in its own language.
+type notifyEvent struct {
+	ID       string            `json:"id"`
+	Event    string            `json:"event"` // node_offline, attack, ...
+	Type     string            `json:"type"`  // system, security, attack
+	Severity string            `json:"severity"`
+	Time     time.Time         `json:"time"`
+	TitleKey string            `json:"titleKey"`
+	TextKey  string            `json:"textKey"`
+	Params   map[string]string `json:"params"`
+}
+
+// notificationEvents are the event kinds rules can pick, besides the message types
+var notificationEvents = []string{
+	"attack", "node_offline", "node_online", "node_clock_drift", "node_clock_synced",
+	"decoy_compromised", "leaked_credential", "blocklist_failed", "ntp_failed", "alert_rate_limited",
+}
+
+var notificationTypes = []string{"system", "security", "attack"}
+
+// severityRanks orders the severities of messages and attacks on one scale
+var severityRanks = map[string]int{"info": 0, "low": 1, "medium": 2, "warning": 2, "high": 3, "critical": 4}
+
+// notifyWake starts a delivery run without waiting for the next tick
+var notifyWake = make(chan struct{}, 1)
+
+const deliveryRetention = 30 * 24 * time.Hour
+
+// announceMessage pushes a new message to the subscribed users and routes it to the
+// notification channels. Alerts get here through raiseAlert once the message is stored.
+func (h *Handler) announceMessage(msg model.Message) {
+	h.pushMessage("NEW_MESSAGE", msg)
+
+	h.notify(messageEvent(msg))
+}
+
+// messageEvent is the event of a message, its kind is the title key without msg_ and _title
+func messageEvent(msg model.Message) notifyEvent {
+	textKey, params := parseMessageText(msg.Content)
+	return notifyEvent{
+		ID:       msg.ID,
+		Event:    alertEvent(msg.Title),
+		Type:     msg.Type,
+		Severity: msg.Severity,
+		Time:     msg.LastSeen,
+		TitleKey: msg.Title,
+		TextKey:  textKey,
+		Params:   params,
+	}
+}
+
+// parseMessageText splits "key|name:value,name:value" content, values may hold colons
+func parseMessageText(content string) (string, map[string]string) {
+	key, rest, _ := strings.Cut(content, "|")
+	params := map[string]string{}
+	if rest == "" {
+		return key, params
+	}
+	for _, part := range strings.Split(rest, ",") {
+		if k, v, ok := strings.Cut(part, ":"); ok && k != "" {
+			params[k] = v
+		}
+	}
+	return key, params
+}
+
+// ruleMatches checks the severity floor and the event list, which may name event kinds
+// or message types
+func ruleMatches(rule model.NotificationRule, ev notifyEvent) bool {
+	if severityRanks[strings.ToLower(ev.Severity)] < severityRanks[rule.MinSeverity] {
+		return false
+	}
+	if strings.TrimSpace(rule.Events) == "" {
+		return true
+	}
+	for _, e := range strings.Split(rule.Events, ",") {
+		if e = strings.TrimSpace(e); e == ev.Event || e == ev.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// notify queues a delivery for every channel an enabled rule routes the event to
+func (h *Handler) notify(ev notifyEvent) {
+	var rules []model.NotificationRule
+	h.DB.Where("enabled = ?", true).Order("created_at").Find(&rules)
+
+	routed := map[string]string{} // Channel ID to the first rule that picked it
+	var channelIDs []string
+	for _, rule := range rules {
+		if !ruleMatches(rule, ev) {
+			continue
+		}
+		for _, id := range splitList(rule.Channels) {
+			if _, ok := routed[id]; !ok {
+				routed[id] = rule.ID
+				channelIDs = append(channelIDs, id)
+			}
+		}
+	}
+	if len(channelIDs) == 0 {
+		return
+	}
+
+	var channels []model.NotificationChannel
+	h.DB.Where("id IN ? AND enabled = ?", channelIDs, true).Find(&channels)
+	queued := 0
+	for _, ch := range channels {
+		d, err := newDelivery(ch, ev, routed[ch.ID], h.Now())
+		if err != nil {
+			log.Printf("Notification channel %s: %v", ch.Name, err)
+		}
+		if h.DB.Create(&d).Error == nil && d.Status == "pending" {
+			queued++
+		}
+	}
+	if queued > 0 {
+		select {
+		case notifyWake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// notifyTemplateData is what channel templates see
+type notifyTemplateData struct {
+	ID       string
+	Event    string
+	Type     string
+	Severity string
+	Time     string // RFC 3339 in UTC
+	Title    string // The built-in title in the channel's language
+	Text     string
+	Params   map[string]string
+}
+
+// defaultTemplates are used when a channel leaves its templates empty
+var defaultTemplates = map[string][2]string{
+	"en": {"[PRTS] {{.Title}}", "{{.Text}}\n\nSeverity: {{.Severity}}\nTime: {{.Time}}"},
+	"zh": {"[PRTS] {{.Title}}", "{{.Text}}\n\n级别：{{.Severity}}\n时间：{{.Time}}"},
+}
+
+// renderNotification applies the channel's templates to an event
+func renderNotification(ch model.NotificationChannel, ev notifyEvent) (string, string, error) {
+	lang := ch.Lang
+	if _, ok := defaultTemplates[lang]; !ok {
+		lang = "en"
+	}
+	data := notifyTemplateData{
+		ID: ev.ID, Event: ev.Event, Type: ev.Type, Severity: ev.Severity,
+		Time:   ev.Time.UTC().Format(time.RFC3339),
+		Title:  notificationText(lang, ev.TitleKey, ev.Params),
+		Text:   notificationText(lang, ev.TextKey, ev.Params),
+		Params: ev.Params,
+	}
+	titleTmpl, bodyTmpl := ch.TitleTemplate, ch.BodyTemplate
+	if strings.TrimSpace(titleTmpl) == "" {
+		titleTmpl = defaultTemplates[lang][0]
+	}
+	if strings.TrimSpace(bodyTmpl) == "" {
+		bodyTmpl = defaultTemplates[lang][1]
+	}
+	title, err := executeTemplate("title", titleTmpl, data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := executeTemplate("body", bodyTmpl, data)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(title), strings.TrimSpace(body), nil
+}
+
+func executeTemplate(name, text string, data notifyTemplateData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s template: %v", name, err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("%s template: %v", name, err)
+	}
+	return sb.String(), nil
+}
+
+// notificationText fills a message key from the catalog, unknown keys are kept as they are
+func notificationText(lang, key string, params map[string]string) string {
+	text, ok := notificationTexts[lang][key]
+	if !ok {
+		if text, ok = notificationTexts["en"][key]; !ok {
+			return key
+		}
+	}
+	for k, v := range params {
+		text = strings.ReplaceAll(text, "{"+k+"}", v)
+	}
+	return text
+}
+
+// newDelivery renders an event for a channel. A template that fails to render gives a
+// failed delivery so the log shows why nothing was sent.
+func newDelivery(ch model.NotificationChannel, ev notifyEvent, ruleID string, now time.Time) (model.NotificationDelivery, error) {
+	data, _ := json.Marshal(ev)
+	d := model.NotificationDelivery{
+		EventID:     ev.ID,
+		Event:       ev.Event,
+		Severity:    ev.Severity,
+		ChannelID:   ch.ID,
+		ChannelName: ch.Name,
+		ChannelType: ch.Type,
+		RuleID:      ruleID,
+		Data:        string(data),
+		Status:      "pending",
+		NextAttempt: &now,
+		CreatedAt:   now,
+	}
+	title, body, err := renderNotification(ch, ev)
+	if err != nil {
+		d.Status, d.NextAttempt, d.LastError = "failed", nil, err.Error()
+		return d, err
+	}
+	d.Title, d.Body = title, body
+	return d, nil
+}
+
+// deliveryBackoff is the wait after a failed attempt, doubling from 30 seconds up to an hour
+func deliveryBackoff(attempts int) time.Duration {
+	wait := 30 * time.Second
+	for i := 1; i < attempts && wait < time.Hour; i++ {
+		wait *= 2
+	}
+	if wait > time.Hour {
+		wait = time.Hour
+	}
+	return wait
+}
+
+// attemptDelivery sends once and records the outcome, failures are rescheduled until the
+// channel's attempts are used up
+func (h *Handler) attemptDelivery(d *model.NotificationDelivery) {
+	var ch model.NotificationChannel
+	var code int
+	err := h.DB.Where("id = ?", d.ChannelID).First(&ch).Error
+	if err != nil {
+		err = errors.New("channel was deleted")
+	} else {
+		code, err = sendNotification(ch, *d)
+	}
+
+	now := h.Now()
+	d.Attempts++
+	d.StatusCode = code
+	if err == nil {
+		d.Status, d.NextAttempt, d.LastError, d.SentAt = "sent", nil, "", &now
+	} else {
+		d.LastError = err.Error()
+		if ch.ID == "" || d.Attempts >= max(ch.MaxAttempts, 1) {
+			d.Status, d.NextAttempt = "failed", nil
+		} else {
+			next := now.Add(deliveryBackoff(d.Attempts))
+			d.NextAttempt = &next
+		}
+		log.Printf("Notification %d to %s failed (attempt %d): %v", d.ID, d.ChannelName, d.Attempts, err)
+	}
+	h.DB.Save(d)
+}
+
+// deliverDue sends every pending delivery whose time has come
+func (h *Handler) deliverDue() {
+	for {
+		var due []model.NotificationDelivery
+		h.DB.Where("status = ? AND next_attempt <= ?", "pending", h.Now()).Order("id").Limit(50).Find(&due)
+		for i := range due {
+			h.attemptDelivery(&due[i])
+		}
+		if len(due) < 50 {
+			return
+		}
+	}
+}
+
+// RunNotifications works through the delivery queue, woken by new events and every few
+// seconds for retries. The log is trimmed to 30 days.
+func (h *Handler) RunNotifications() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+	for {
+		h.deliverDue()
+		if time.Since(lastPrune) >= time.Hour {
+			h.DB.Where("created_at < ? AND status <> ?", h.Now().Add(-deliveryRetention), "pending").Delete(&model.NotificationDelivery{})
+			lastPrune = time.Now()
+		}
+		select {
+		case <-ticker.C:
+		case <-notifyWake:
+		}
+	}
+}
+
+// channelRequest is a channel as the API reads and writes it, with its settings spelled out
+type channelRequest struct {
+	model.NotificationChannel
+	Settings channelSettings `json:"settings"`
+}
+
+type channelView struct {
+	model.NotificationChannel
+	Settings    channelSettings `json:"settings"`
+	HasSecret   bool            `json:"hasSecret"`
+	HasPassword bool            `json:"hasPassword"`
+}
+
+func viewChannel(ch model.NotificationChannel) channelView {
+	var s channelSettings
+	json.Unmarshal([]byte(ch.Settings), &s)
+	v := channelView{NotificationChannel: ch, HasSecret: s.Secret != "", HasPassword: s.Password != ""}
+	s.Secret, s.Password = "", ""
+	v.Settings = s
+	return v
+}
+
+var sampleEvent = notifyEvent{
+	ID:       "test",
+	Event:    "test",
+	Type:     "system",
+	Severity: "info",
+	TitleKey: "notify_test_title",
+	TextKey:  "notify_test_content",
+	Params:   map[string]string{},
+}
+
+// normalizeChannel validates a channel and its settings and checks its templates render
+func normalizeChannel(ch *model.NotificationChannel, s *channelSettings) error {
+	ch.Name = strings.TrimSpace(ch.Name)
+	if ch.Name == "" {
+		return errors.New("name is required")
+	}
+	if ch.Lang == "" {
+		ch.Lang = "en"
+	}
+	if _, ok := defaultTemplates[ch.Lang]; !ok {
+		return errors.New("lang must be en or zh")
+	}
+	if ch.MaxAttempts <= 0 {
+		ch.MaxAttempts = 5
+	}
+	if ch.MaxAttempts > 20 {
+		return errors.New("maxAttempts can be at most 20")
+	}
+	if err := s.validate(ch.Type); err != nil {
+		return err
+	}
+	ev := sampleEvent
+	ev.Time = time.Now()
+	if _, _, err := renderNotification(*ch, ev); err != nil {
+		return err
+	}
+	val, _ := json.Marshal(s)
+	ch.Settings = string(val)
+	return nil
+}
+
+func (h *Handler) GetNotificationChannels(c *gin.Context) {
+	var channels []model.NotificationChannel
+	h.DB.Order("created_at").Find(&channels)
+	views := make([]channelView, len(channels))
+	for i, ch := range channels {
+		views[i] = viewChannel(ch)
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func (h *Handler) CreateNotificationChannel(c *gin.Context) {
+	var req channelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ch := req.NotificationChannel
+	if err := normalizeChannel(&ch, &req.Settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ch.ID = fmt.Sprintf("NC-%d", time.Now().UnixNano())
+	ch.CreatedAt, ch.UpdatedAt = h.Now(), h.Now()
+	if err := h.DB.Create(&ch).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name is already used by another channel"})
+		return
+	}
+	c.JSON(http.StatusOK, viewChannel(ch))
+}
+
+// UpdateNotificationChannel replaces a channel's settings, empty secrets keep the stored
+// ones. The type is fixed.
+func (h *Handler) UpdateNotificationChannel(c *gin.Context) {
+	var ch model.NotificationChannel
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&ch).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	var req channelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Type != ch.Type {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type cannot be changed"})
+		return
+	}
+	var old channelSettings
+	json.Unmarshal([]byte(ch.Settings), &old)
+	if req.Settings.Secret == "" {
+		req.Settings.Secret = old.Secret
+	}
+	if req.Settings.Password == "" {
+		req.Settings.Password = old.Password
+	}
+
+	updated := req.NotificationChannel
+	updated.ID, updated.CreatedAt, updated.UpdatedAt = ch.ID, ch.CreatedAt, h.Now()
+	if err := normalizeChannel(&updated, &req.Settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.DB.Save(&updated).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name is already used by another channel"})
+		return
+	}
+	c.JSON(http.StatusOK, viewChannel(updated))
+}
+
+// DeleteNotificationChannel removes a channel and takes it out of the routing rules
+func (h *Handler) DeleteNotificationChannel(c *gin.Context) {
+	id := c.Param("id")
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&model.NotificationChannel{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		var rules []model.NotificationRule
+		tx.Where("channels LIKE ?", "%"+id+"%").Find(&rules)
+		for _, rule := range rules {
+			var keep []string
+			for _, ch := range splitList(rule.Channels) {
+				if ch != id {
+					keep = append(keep, ch)
+				}
+			}
+			if err := tx.Model(&rule).Update("channels", strings.Join(keep, ",")).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete channel"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// TestNotificationChannel sends a sample notification right away, also to a disabled
+// channel, and returns the logged delivery
+func (h *Handler) TestNotificationChannel(c *gin.Context) {
+	var ch model.NotificationChannel
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&ch).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	ev := sampleEvent
+	ev.ID = fmt.Sprintf("test-%d", time.Now().UnixNano())
+	ev.Time = h.Now()
+	d, err := newDelivery(ch, ev, "", h.Now())
+	if err != nil {
+		h.DB.Create(&d)
+		c.JSON(http.StatusOK, d)
+		return
+	}
+	// Logged first so the webhook carries its delivery ID, the queue skips it without a next attempt
+	d.NextAttempt = nil
+	h.DB.Create(&d)
+	code, err := sendNotification(ch, d)
+	now := h.Now()
+	d.Attempts, d.StatusCode = 1, code
+	if err == nil {
+		d.Status, d.SentAt = "sent", &now
+	} else {
+		d.Status, d.LastError = "failed", err.Error()
+	}
+	h.DB.Save(&d)
+	c.JSON(http.StatusOK, d)
+}
+
+// normalizeRule checks the severity, the events and that every channel exists
+func (h *Handler) normalizeRule(rule *model.NotificationRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+	if rule.MinSeverity == "" {
+		rule.MinSeverity = "info"
+	}
+	if _, ok := severityRanks[rule.MinSeverity]; !ok {
+		return fmt.Errorf("unknown severity %q", rule.MinSeverity)
+	}
+	events := splitList(rule.Events)
+	for _, e := range events {
+		if !slices.Contains(notificationEvents, e) && !slices.Contains(notificationTypes, e) {
+			return fmt.Errorf("unknown event %q", e)
+		}
+	}
+	rule.Events = strings.Join(events, ",")
+
+	channels := splitList(rule.Channels)
+	if len(channels) == 0 {
+		return errors.New("at least one channel is required")
+	}
+	var count int64
+	h.DB.Model(&model.NotificationChannel{}).Where("id IN ?", channels).Count(&count)
+	if int(count) != len(channels) {
+		return errors.New("unknown channel")
+	}
+	rule.Channels = strings.Join(channels, ",")
+	return nil
+}
+
+func (h *Handler) GetNotificationRules(c *gin.Context) {
+	var rules []model.NotificationRule
+	h.DB.Order("created_at").Find(&rules)
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "events": notificationEvents, "types": notificationTypes})
+}
+
+func (h *Handler) CreateNotificationRule(c *gin.Context) {
+	var rule model.NotificationRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.normalizeRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule.ID = fmt.Sprintf("NR-%d", time.Now().UnixNano())
+	rule.CreatedAt = h.Now()
+	if err := h.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rule"})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *Handler) UpdateNotificationRule(c *gin.Context) {
+	var rule model.NotificationRule
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+	id, createdAt := rule.ID, rule.CreatedAt
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule.ID, rule.CreatedAt = id, createdAt
+	if err := h.normalizeRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.DB.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rule"})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *Handler) DeleteNotificationRule(c *gin.Context) {
+	if err := h.DB.Delete(&model.NotificationRule{}, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+var deliveryListSpec = listSpec{
+	timeColumn:  "created_at",
+	sorts:       map[string]string{"createdAt": "created_at", "attempts": "attempts"},
+	defaultSort: "-createdAt",
+	filters:     map[string]string{"status": "status", "channel": "channel_id", "event": "event", "severity": "severity"},
+	search:      []string{"title", "body", "last_error"},
+}
+
+func (h *Handler) GetNotificationDeliveries(c *gin.Context) {
+	listQuery[model.NotificationDelivery](h, c, deliveryListSpec)
+}
+
+// RetryNotificationDelivery queues a delivery again with a fresh set of attempts
+func (h *Handler) RetryNotificationDelivery(c *gin.Context) {
+	var d model.NotificationDelivery
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&d).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+	if d.Status != "failed" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only failed deliveries can be retried"})
+		return
+	}
+	if d.Title == "" && d.Body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the delivery was never rendered, fix the channel templates instead"})
+		return
+	}
+	now := h.Now()
+	h.DB.Model(&d).Updates(map[string]interface{}{"status": "pending", "attempts": 0, "next_attempt": now})
+	select {
+	case notifyWake <- struct{}{}:
+	default:
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// notificationTexts are the message texts of the frontend catalog that alerts use, in
+// the languages channels can pick
+var notificationTexts = map[string]map[string]string{
+	"en": {
+		"msg_node_online_title":                 "Node Online Notification",
+		"msg_node_online_content":               "Probe node [{name}] ({id}) has successfully connected to the Neural Hub.",
+		"msg_node_offline_title":                "Node Offline",
+		"msg_node_offline_content":              "Probe node [{name}] ({id}) has disconnected from the Neural Hub.",
+		"msg_decoy_compromised_title":           "Decoy Compromised",
+		"msg_decoy_compromised_content":         "Decoy [{name}] on {node} was touched by {process} from {ip}.",
+		"msg_leaked_credential_title":           "Real Account Credential Tried",
+		"msg_leaked_credential_username":        "Inventory account [{account}] was tried as {user} against {service} from {ip}.",
+		"msg_leaked_credential_password":        "The real password of [{account}] was used against {service} from {ip}.",
+		"msg_leaked_credential_reused_password": "The real password of [{account}] was tried under the name {user} against {service} from {ip}.",
+		"msg_blocklist_failed_title":            "Blocklist Refresh Failed",
+		"msg_blocklist_failed":                  "Blocklist subscription [{name}] could not be refreshed: {error}. Existing rules are kept until their TTL runs out.",
+		"msg_ntp_failed_title":                  "NTP Sync Failed",
+		"msg_ntp_failed_content":                "None of the NTP servers ({servers}) answered. The last offset of {offset}s stays in effect.",
+		"msg_node_clock_drift_title":            "Probe Clock Drift",
+		"msg_node_clock_drift_content":          "The clock of probe node [{name}] ({id}) is off by {offset}ms.",
+		"msg_node_clock_synced_title":           "Probe Clock Back In Sync",
+		"msg_node_clock_synced_content":         "The clock of probe node [{name}] ({id}) is back in sync ({offset}ms).",
+		"msg_attack_title":                      "Critical Attack",
+		"msg_attack_content":                    "{method} attack from {ip} on {node}, {status}: {payload}",
+		"msg_attack_group_content":              "{count} critical {method} attacks from {sources} addresses.",
+		"msg_alert_rate_limited_title":          "Alerts Rate Limited",
+		"msg_alert_rate_limited_content":        "{count} alerts of {sources} kinds were folded here because the message center hit its rate limit.",
+		"notify_test_title":                     "Test Notification",
+		"notify_test_content":                   "This channel is set up correctly.",
+	},
+	"zh": {
+		"msg_node_online_title":                 "节点上线通知",
+		"msg_node_online_content":               "探针节点 [{name}] ({id}) 已成功连接至神经枢纽。",
+		"msg_node_offline_title":                "节点离线",
+		"msg_node_offline_content":              "探针节点 [{name}] ({id}) 与神经枢纽的连接已断开。",
+		"msg_decoy_compromised_title":           "诱饵被触发",
+		"msg_decoy_compromised_content":         "节点 {node} 上的诱饵 [{name}] 被 {process} 触碰，来源 {ip}。",
+		"msg_leaked_credential_title":           "真实账号凭据被尝试",
+		"msg_leaked_credential_username":        "清单账号 [{account}] 被以 {user} 名义在 {service} 上尝试，来源 {ip}。",
+		"msg_leaked_credential_password":        "账号 [{account}] 的真实密码被用于 {service}，来源 {ip}。",
+		"msg_leaked_credential_reused_password": "账号 [{account}] 的真实密码以用户名 {user} 在 {service} 上被尝试，来源 {ip}。",
+		"msg_blocklist_failed_title":            "黑名单订阅刷新失败",
+		"msg_blocklist_failed":                  "黑名单订阅 [{name}] 刷新失败：{error}。现有规则将保留至其有效期结束。",
+		"msg_ntp_failed_title":                  "NTP 同步失败",
+		"msg_ntp_failed_content":                "NTP 服务器 ({servers}) 均无响应，继续沿用上次的偏移量 {offset}s。",
+		"msg_node_clock_drift_title":            "探针时钟偏差",
+		"msg_node_clock_drift_content":          "探针节点 [{name}] ({id}) 的时钟偏差为 {offset}ms。",
+		"msg_node_clock_synced_title":           "探针时钟已恢复",
+		"msg_node_clock_synced_content":         "探针节点 [{name}] ({id}) 的时钟已恢复同步 ({offset}ms)。",
+		"msg_attack_title":                      "严重攻击",
+		"msg_attack_content":                    "来自 {ip} 的 {method} 攻击，节点 {node}，{status}：{payload}",
+		"msg_attack_group_content":              "来自 {sources} 个地址的 {count} 次严重 {method} 攻击。",
+		"msg_alert_rate_limited_title":          "告警已限流",
+		"msg_alert_rate_limited_content":        "消息中心触发限流，{sources} 类共 {count} 条告警已合并至此。",
+		"notify_test_title":                     "测试通知",
+		"notify_test_content":                   "该通知渠道配置正确。",
+	},
+}
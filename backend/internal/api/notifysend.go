@@ -0,0 +1,353 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/model"
+)
+
+var notifyClient = &http.Client{Timeout: 10 * time.Second}
+
+// channelSettings holds the settings of every channel type, each type reads its own.
+// Secret signs webhook bodies with HMAC-SHA256 and is the signing secret of DingTalk and
+// Feishu bots.
+type channelSettings struct {
+	URL      string `json:"url,omitempty"` // webhook, slack, dingtalk, feishu, wecom
+	Secret   string `json:"secret,omitempty"`
+	Host     string `json:"host,omitempty"` // email
+	Port     int    `json:"port,omitempty"`
+	TLS      string `json:"tls,omitempty"` // none, starttls, tls
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"` // Comma-separated
+	// InsecureSkipVerify accepts self-signed certificates of internal mail and webhook servers
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+func (s *channelSettings) validate(kind string) error {
+	switch kind {
+	case "webhook", "slack", "dingtalk", "feishu", "wecom":
+		u, err := url.Parse(strings.TrimSpace(s.URL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("url must be an http or https address")
+		}
+		s.URL = u.String()
+	case "email":
+		s.Host = strings.TrimSpace(s.Host)
+		if s.Host == "" {
+			return errors.New("host is required")
+		}
+		if s.TLS == "" {
+			s.TLS = "starttls"
+		}
+		if s.TLS != "none" && s.TLS != "starttls" && s.TLS != "tls" {
+			return errors.New("tls must be none, starttls or tls")
+		}
+		if s.Port == 0 {
+			s.Port = map[string]int{"none": 25, "starttls": 587, "tls": 465}[s.TLS]
+		}
+		if s.Port < 1 || s.Port > 65535 {
+			return errors.New("port must be between 1 and 65535")
+		}
+		if _, err := mail.ParseAddress(s.From); err != nil {
+			return errors.New("from must be an email address")
+		}
+		if _, err := mail.ParseAddressList(s.To); err != nil || strings.TrimSpace(s.To) == "" {
+			return errors.New("to must list email addresses, comma-separated")
+		}
+	default:
+		return errors.New("type must be webhook, email, slack, dingtalk, feishu or wecom")
+	}
+	return nil
+}
+
+// sendNotification makes one attempt. The status code is the HTTP status, or the SMTP
+// reply code for email, 0 when nothing was answered.
+func sendNotification(ch model.NotificationChannel, d model.NotificationDelivery) (int, error) {
+	var s channelSettings
+	if err := json.Unmarshal([]byte(ch.Settings), &s); err != nil {
+		return 0, fmt.Errorf("channel settings: %v", err)
+	}
+	switch ch.Type {
+	case "webhook":
+		return sendWebhook(s, d)
+	case "email":
+		return sendEmail(s, d)
+	case "slack":
+		return postJSON(s, s.URL, map[string]interface{}{"text": "*" + d.Title + "*\n" + d.Body}, nil)
+	case "dingtalk":
+		return sendDingTalk(s, d)
+	case "feishu":
+		return sendFeishu(s, d)
+	case "wecom":
+		body := map[string]interface{}{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"content": "**" + d.Title + "**\n" + d.Body},
+		}
+		return postJSON(s, s.URL, body, checkErrcode)
+	}
+	return 0, fmt.Errorf("unknown channel type %q", ch.Type)
+}
+
+func httpClient(s channelSettings) *http.Client {
+	if !s.InsecureSkipVerify {
+		return notifyClient
+	}
+	return &http.Client{
+		Timeout:   notifyClient.Timeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+}
+
+// postJSON sends a body and treats any non-2xx answer as a failure. Bot APIs answer 200
+// for rejected messages too, check reads their error field.
+func postJSON(s channelSettings, target string, payload interface{}, check func([]byte) error) (int, error) {
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(s, req, check)
+}
+
+func doRequest(s channelSettings, req *http.Request, check func([]byte) error) (int, error) {
+	resp, err := httpClient(s).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(answer)))
+	}
+	if check != nil {
+		if err := check(answer); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// checkErrcode reads the answer of DingTalk and WeCom bots, errcode 0 is success
+func checkErrcode(answer []byte) error {
+	var r struct {
+		Errcode int    `json:"errcode"`
+		Errmsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(answer, &r); err != nil {
+		return fmt.Errorf("unreadable answer: %s", strings.TrimSpace(string(answer)))
+	}
+	if r.Errcode != 0 {
+		return fmt.Errorf("errcode %d: %s", r.Errcode, r.Errmsg)
+	}
+	return nil
+}
+
+// sendWebhook posts the event with the rendered texts. With a secret the body is signed:
+// X-PRTS-Signature is sha256= and the hex HMAC-SHA256 of the X-PRTS-Timestamp value, a
+// dot and the body, so receivers can refuse replays.
+func sendWebhook(s channelSettings, d model.NotificationDelivery) (int, error) {
+	var ev notifyEvent
+	json.Unmarshal([]byte(d.Data), &ev)
+	body, _ := json.Marshal(map[string]interface{}{
+		"delivery": d.ID,
+		"id":       ev.ID,
+		"event":    ev.Event,
+		"type":     ev.Type,
+		"severity": ev.Severity,
+		"time":     ev.Time.UTC(),
+		"title":    d.Title,
+		"text":     d.Body,
+		"params":   ev.Params,
+	})
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PRTS-Notifier")
+	req.Header.Set("X-PRTS-Event", ev.Event)
+	req.Header.Set("X-PRTS-Delivery", strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set("X-PRTS-Timestamp", timestamp)
+	if s.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.Secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		req.Header.Set("X-PRTS-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return doRequest(s, req, nil)
+}
+
+// sendDingTalk posts markdown, a signing secret adds timestamp and sign to the URL
+func sendDingTalk(s channelSettings, d model.NotificationDelivery) (int, error) {
+	target := s.URL
+	if s.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(s.Secret))
+		mac.Write([]byte(timestamp + "\n" + s.Secret))
+		sign := url.QueryEscape(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + "timestamp=" + timestamp + "&sign=" + sign
+	}
+	body := map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"title": d.Title, "text": "### " + d.Title + "\n\n" + strings.ReplaceAll(d.Body, "\n", "\n\n")},
+	}
+	return postJSON(s, target, body, checkErrcode)
+}
+
+// sendFeishu posts text, a signing secret adds timestamp and sign to the body. Feishu
+// signs with the timestamp and secret as the key and an empty message.
+func sendFeishu(s channelSettings, d model.NotificationDelivery) (int, error) {
+	body := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": d.Title + "\n" + d.Body},
+	}
+	if s.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+s.Secret))
+		body["timestamp"] = timestamp
+		body["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	return postJSON(s, s.URL, body, func(answer []byte) error {
+		var r struct {
+			Code       *int   `json:"code"`
+			StatusCode *int   `json:"StatusCode"` // Older bot API
+			Msg        string `json:"msg"`
+		}
+		if err := json.Unmarshal(answer, &r); err != nil {
+			return fmt.Errorf("unreadable answer: %s", strings.TrimSpace(string(answer)))
+		}
+		if r.Code != nil && *r.Code != 0 {
+			return fmt.Errorf("code %d: %s", *r.Code, r.Msg)
+		}
+		if r.StatusCode != nil && *r.StatusCode != 0 {
+			return fmt.Errorf("code %d: %s", *r.StatusCode, r.Msg)
+		}
+		return nil
+	})
+}
+
+// sendEmail delivers a plain text mail. Credentials are only sent over TLS or to a
+// server on the loopback address.
+func sendEmail(s channelSettings, d model.NotificationDelivery) (int, error) {
+	from, _ := mail.ParseAddress(s.From)
+	to, err := mail.ParseAddressList(s.To)
+	if err != nil {
+		return 0, err
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	tlsCfg := &tls.Config{ServerName: s.Host, InsecureSkipVerify: s.InsecureSkipVerify}
+
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if s.TLS == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsCfg)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return 0, err
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return smtpCode(err), err
+	}
+	defer client.Close()
+
+	if s.TLS == "starttls" {
+		if err := client.StartTLS(tlsCfg); err != nil {
+			return smtpCode(err), fmt.Errorf("starttls: %v", err)
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return smtpCode(err), fmt.Errorf("auth: %v", err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return smtpCode(err), err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt.Address); err != nil {
+			return smtpCode(err), fmt.Errorf("%s: %v", rcpt.Address, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return smtpCode(err), err
+	}
+	w.Write(buildEmail(from, to, d))
+	if err := w.Close(); err != nil {
+		return smtpCode(err), err
+	}
+	client.Quit()
+	return 250, nil
+}
+
+func smtpCode(err error) int {
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return tpErr.Code
+	}
+	return 0
+}
+
+// buildEmail writes the message with a UTF-8 subject and a base64 body, texts may be Chinese
+func buildEmail(from *mail.Address, to []*mail.Address, d model.NotificationDelivery) []byte {
+	recipients := make([]string, len(to))
+	for i, a := range to {
+		recipients[i] = a.String()
+	}
+	var id [12]byte
+	rand.Read(id[:])
+	domain := "prts.local"
+	if at := strings.LastIndex(from.Address, "@"); at >= 0 {
+		domain = from.Address[at+1:]
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", d.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id[:]), domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(strings.ReplaceAll(d.Body, "\n", "\r\n")))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes()
+}
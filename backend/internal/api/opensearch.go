@@ -0,0 +1,864 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/metrics"
+	"backend/internal/model"
+	"backend/internal/spool"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// openSearchSpoolDir holds one spool per output, named by its ID
+const openSearchSpoolDir = "spool/opensearch"
+
+const (
+	// openSearchMaxBatchBytes keeps bulk requests in the size range clusters handle well
+	openSearchMaxBatchBytes = 5 << 20
+	openSearchTimeout       = 30 * time.Second
+	openSearchMaxBackoff    = time.Minute
+	// openSearchMaxResponse bounds the bulk response, it has an item per document
+	openSearchMaxResponse = 64 << 20
+)
+
+// openSearchClient talks to the REST API of one cluster
+type openSearchClient struct {
+	out  model.OpenSearchOutput
+	http *http.Client
+}
+
+func newOpenSearchClient(out model.OpenSearchOutput) (*openSearchClient, error) {
+	cfg, err := outputTLSConfig(out.CACert, out.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	return &openSearchClient{out: out, http: &http.Client{Timeout: openSearchTimeout, Transport: transport}}, nil
+}
+
+// statusError is a response the cluster refused, 429 and 5xx are worth retrying
+type statusError struct {
+	code       int
+	retryAfter time.Duration
+	msg        string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.code, e.msg)
+}
+
+func (c *openSearchClient) do(method, path, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, c.out.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.out.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.out.APIKey)
+	} else if c.out.Username != "" {
+		req.SetBasicAuth(c.out.Username, c.out.Password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, openSearchMaxResponse))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		e := &statusError{code: resp.StatusCode, msg: errorReason(data)}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			e.retryAfter = time.Duration(secs) * time.Second
+		}
+		return nil, e
+	}
+	return data, nil
+}
+
+// errorReason digs the reason out of an error response, or returns the start of the body
+func errorReason(data []byte) string {
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && len(body.Error) > 0 {
+		var e struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		}
+		if json.Unmarshal(body.Error, &e) == nil && e.Reason != "" {
+			return e.Type + ": " + e.Reason
+		}
+		var s string
+		if json.Unmarshal(body.Error, &s) == nil {
+			return s
+		}
+	}
+	return truncateRunes(strings.TrimSpace(string(data)), 200)
+}
+
+type clusterInfo struct {
+	ClusterName string `json:"cluster_name"`
+	Version     struct {
+		Number       string `json:"number"`
+		Distribution string `json:"distribution"` // opensearch, empty on Elasticsearch
+	} `json:"version"`
+}
+
+func (c *openSearchClient) info() (clusterInfo, error) {
+	var info clusterInfo
+	data, err := c.do(http.MethodGet, "/", "", nil)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, errors.New("the URL does not answer like an OpenSearch or Elasticsearch cluster")
+	}
+	return info, nil
+}
+
+// putTemplate installs the index template of the prefix, new daily indexes pick it up
+func (c *openSearchClient) putTemplate() error {
+	body, _ := json.Marshal(indexTemplate(c.out.IndexPrefix))
+	_, err := c.do(http.MethodPut, "/_index_template/"+c.out.IndexPrefix, "application/json", body)
+	return err
+}
+
+// bulkResult sorts the documents of a bulk request by what became of them
+type bulkResult struct {
+	indexed    int
+	retry      []int // Documents refused for now, usually 429 from a full write queue
+	rejected   int   // Documents the cluster will never take, such as mapping conflicts
+	lastReject string
+}
+
+func (c *openSearchClient) bulk(recs [][]byte) (bulkResult, error) {
+	var res bulkResult
+	data, err := c.do(http.MethodPost, "/_bulk", "application/x-ndjson", bytes.Join(recs, nil))
+	if err != nil {
+		return res, err
+	}
+	var body struct {
+		Errors bool                          `json:"errors"`
+		Items  []map[string]bulkItemResponse `json:"items"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return res, fmt.Errorf("unreadable bulk response: %w", err)
+	}
+	if len(body.Items) != len(recs) {
+		return res, fmt.Errorf("bulk response has %d items for %d documents", len(body.Items), len(recs))
+	}
+	for i, item := range body.Items {
+		r := item["index"]
+		switch {
+		case r.Status >= 200 && r.Status < 300:
+			res.indexed++
+		case r.Status == http.StatusTooManyRequests || r.Status >= 500:
+			res.retry = append(res.retry, i)
+		default:
+			res.rejected++
+			res.lastReject = fmt.Sprintf("%s %s: %s: %s", r.Index, r.ID, r.Error.Type, r.Error.Reason)
+		}
+	}
+	return res, nil
+}
+
+type bulkItemResponse struct {
+	Index  string `json:"_index"`
+	ID     string `json:"_id"`
+	Status int    `json:"status"`
+	Error  struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// openSearchOutput indexes the spool of one output into its cluster
+type openSearchOutput struct {
+	out    model.OpenSearchOutput
+	client *openSearchClient
+	spool  *spool.Spool
+	wake   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+
+	queued, indexed, rejected, retried, failures, spoolErrors atomic.Uint64
+
+	mu            sync.Mutex
+	lastError     string
+	lastErrorAt   *time.Time
+	lastIndexedAt *time.Time
+}
+
+// openSearchOutputs are the running outputs by ID, disabled outputs have none
+var openSearchOutputs = struct {
+	sync.RWMutex
+	m map[string]*openSearchOutput
+}{m: map[string]*openSearchOutput{}}
+
+// StartOpenSearchOutputs opens the spool of every enabled output and starts indexing,
+// documents queued before a restart go out first
+func (h *Handler) StartOpenSearchOutputs() {
+	var outs []model.OpenSearchOutput
+	h.DB.Find(&outs)
+	for _, out := range outs {
+		if out.Enabled {
+			if err := startOpenSearchOutput(out); err != nil {
+				log.Printf("OpenSearch: failed to start %s: %v", out.Name, err)
+			}
+		}
+	}
+}
+
+func startOpenSearchOutput(out model.OpenSearchOutput) error {
+	client, err := newOpenSearchClient(out)
+	if err != nil {
+		return err
+	}
+	sp, err := spool.Open(filepath.Join(openSearchSpoolDir, out.ID), int64(out.MaxQueueMB)<<20)
+	if err != nil {
+		return err
+	}
+	o := &openSearchOutput{
+		out: out, client: client, spool: sp,
+		wake: make(chan struct{}, 1), stop: make(chan struct{}), done: make(chan struct{}),
+	}
+	openSearchOutputs.Lock()
+	openSearchOutputs.m[out.ID] = o
+	openSearchOutputs.Unlock()
+	go o.run()
+	return nil
+}
+
+// stopOpenSearchOutput ends an output, the spool is kept unless it is removed
+func stopOpenSearchOutput(id string, remove bool) {
+	openSearchOutputs.Lock()
+	o := openSearchOutputs.m[id]
+	delete(openSearchOutputs.m, id)
+	openSearchOutputs.Unlock()
+	if o == nil {
+		if remove {
+			os.RemoveAll(filepath.Join(openSearchSpoolDir, id))
+		}
+		return
+	}
+	close(o.stop)
+	<-o.done
+	if remove {
+		o.spool.Remove()
+	} else {
+		o.spool.Close()
+	}
+}
+
+// forwardOpenSearch queues an event for every output whose filter takes it
+func forwardOpenSearch(ev siemEvent) {
+	openSearchOutputs.RLock()
+	defer openSearchOutputs.RUnlock()
+	for _, o := range openSearchOutputs.m {
+		if !ev.matches(o.out.Kinds, o.out.MinSeverity) {
+			continue
+		}
+		rec, err := bulkRecord(o.out.IndexPrefix, ev)
+		if err == nil {
+			err = o.spool.Append(rec)
+		}
+		if err != nil {
+			o.spoolErrors.Add(1)
+			log.Printf("OpenSearch: failed to queue %s %s for %s: %v", ev.Kind, ev.ID, o.out.Name, err)
+			continue
+		}
+		o.queued.Add(1)
+		select {
+		case o.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run sends a batch once batchSize documents are queued or the oldest waited flushSeconds.
+// The spool only moves on when every document of the batch was indexed or rejected for
+// good, documents throttled with a 429 are sent again on their own.
+func (o *openSearchOutput) run() {
+	defer close(o.done)
+	flush := time.Duration(o.out.FlushSeconds) * time.Second
+	backoff := time.Second
+	templateReady := o.out.SkipTemplate
+	var batch [][]byte
+	var pos spool.Position
+	var read int
+	var since time.Time // When documents were first seen waiting
+
+	for {
+		if !templateReady {
+			if err := o.client.putTemplate(); err != nil {
+				o.fail(fmt.Errorf("installing the index template: %w", err))
+				if !o.sleep(backoff) {
+					return
+				}
+				backoff = min(backoff*2, openSearchMaxBackoff)
+				continue
+			}
+			templateReady = true
+		}
+
+		if batch == nil {
+			pending, _, _ := o.spool.Stats()
+			if pending == 0 {
+				since = time.Time{}
+				if !o.idle(0) {
+					return
+				}
+				continue
+			}
+			if since.IsZero() {
+				since = time.Now()
+			}
+			if wait := flush - time.Since(since); pending < o.out.BatchSize && wait > 0 {
+				if !o.idle(wait) {
+					return
+				}
+				continue
+			}
+			recs, next, err := o.spool.ReadBytes(o.out.BatchSize, openSearchMaxBatchBytes)
+			if err != nil {
+				o.fail(err)
+				if !o.sleep(backoff) {
+					return
+				}
+				continue
+			}
+			if len(recs) == 0 {
+				since = time.Time{}
+				continue
+			}
+			batch, pos, read = recs, next, len(recs)
+		}
+
+		res, err := o.client.bulk(batch)
+		if err != nil {
+			o.failures.Add(1)
+			o.fail(err)
+			wait := backoff
+			var se *statusError
+			if errors.As(err, &se) && se.retryAfter > 0 {
+				wait = se.retryAfter
+			}
+			if !o.sleep(wait) {
+				return
+			}
+			backoff = min(backoff*2, openSearchMaxBackoff)
+			continue
+		}
+		now := time.Now().UTC()
+		o.indexed.Add(uint64(res.indexed))
+		if res.rejected > 0 {
+			o.rejected.Add(uint64(res.rejected))
+			o.fail(errors.New(res.lastReject))
+		}
+		if res.indexed > 0 {
+			o.mu.Lock()
+			o.lastIndexedAt = &now
+			o.mu.Unlock()
+		}
+		if len(res.retry) > 0 {
+			o.retried.Add(uint64(len(res.retry)))
+			retry := make([][]byte, len(res.retry))
+			for i, j := range res.retry {
+				retry[i] = batch[j]
+			}
+			batch = retry
+			if !o.sleep(backoff) {
+				return
+			}
+			backoff = min(backoff*2, openSearchMaxBackoff)
+			continue
+		}
+		o.spool.Commit(pos, read)
+		batch, since, backoff = nil, time.Time{}, time.Second
+	}
+}
+
+func (o *openSearchOutput) fail(err error) {
+	now := time.Now().UTC()
+	o.mu.Lock()
+	o.lastError, o.lastErrorAt = err.Error(), &now
+	o.mu.Unlock()
+	log.Printf("OpenSearch: %s: %v", o.out.Name, err)
+}
+
+// sleep pauses the run loop, false means the output was stopped meanwhile
+func (o *openSearchOutput) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-o.stop:
+		return false
+	}
+}
+
+// idle waits for new documents, at most d unless it is zero
+func (o *openSearchOutput) idle(d time.Duration) bool {
+	var timeout <-chan time.Time
+	if d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-timeout:
+	case <-o.wake:
+	case <-o.stop:
+		return false
+	}
+	return true
+}
+
+var indexPrefixPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// normalizeOpenSearchOutput validates an output and fills in the defaults
+func normalizeOpenSearchOutput(out *model.OpenSearchOutput) error {
+	out.Name = strings.TrimSpace(out.Name)
+	if out.Name == "" {
+		return errors.New("name is required")
+	}
+	out.URL = strings.TrimRight(strings.TrimSpace(out.URL), "/")
+	u, err := url.Parse(out.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an http or https address such as https://localhost:9200")
+	}
+	if out.IndexPrefix == "" {
+		out.IndexPrefix = siemAppName
+	}
+	if !indexPrefixPattern.MatchString(out.IndexPrefix) {
+		return errors.New("indexPrefix must be lowercase letters, digits, '-', '_' or '.'")
+	}
+	kinds := splitList(out.Kinds)
+	for _, kind := range kinds {
+		if !slices.Contains(siemKinds, kind) {
+			return fmt.Errorf("unknown event kind %s", kind)
+		}
+	}
+	out.Kinds = strings.Join(kinds, ",")
+	if out.MinSeverity == "" {
+		out.MinSeverity = "info"
+	}
+	if _, ok := siemSeverities[out.MinSeverity]; !ok {
+		return errors.New("minSeverity must be info, low, medium, high or critical")
+	}
+	if out.BatchSize <= 0 {
+		out.BatchSize = 500
+	}
+	if out.BatchSize > 10000 {
+		return errors.New("batchSize can be at most 10000")
+	}
+	if out.FlushSeconds <= 0 {
+		out.FlushSeconds = 5
+	}
+	if out.FlushSeconds > 300 {
+		return errors.New("flushSeconds can be at most 300")
+	}
+	if _, err := outputTLSConfig(out.CACert, out.InsecureSkipVerify); err != nil {
+		return err
+	}
+	if out.MaxQueueMB <= 0 {
+		out.MaxQueueMB = 256
+	}
+	if out.MaxQueueMB > 4096 {
+		return errors.New("maxQueueMb can be at most 4096")
+	}
+	return nil
+}
+
+// openSearchOutputView leaves out the credentials and adds the state of the running output
+type openSearchOutputView struct {
+	model.OpenSearchOutput
+	HasPassword   bool       `json:"hasPassword"`
+	HasAPIKey     bool       `json:"hasApiKey"`
+	Running       bool       `json:"running"`
+	QueueLength   int        `json:"queueLength"`
+	QueueBytes    int64      `json:"queueBytes"`
+	Queued        uint64     `json:"queued"` // Counters since the server started
+	Indexed       uint64     `json:"indexed"`
+	Rejected      uint64     `json:"rejected"`
+	Retried       uint64     `json:"retried"`
+	Failures      uint64     `json:"failures"`
+	Dropped       uint64     `json:"dropped"`
+	LastError     string     `json:"lastError"`
+	LastErrorAt   *time.Time `json:"lastErrorAt"`
+	LastIndexedAt *time.Time `json:"lastIndexedAt"`
+}
+
+func viewOpenSearchOutput(out model.OpenSearchOutput) openSearchOutputView {
+	v := openSearchOutputView{OpenSearchOutput: out, HasPassword: out.Password != "", HasAPIKey: out.APIKey != ""}
+	v.Password, v.APIKey = "", ""
+	openSearchOutputs.RLock()
+	o := openSearchOutputs.m[out.ID]
+	openSearchOutputs.RUnlock()
+	if o == nil {
+		return v
+	}
+	var dropped uint64
+	v.Running = true
+	v.QueueLength, v.QueueBytes, dropped = o.spool.Stats()
+	v.Queued, v.Indexed, v.Rejected = o.queued.Load(), o.indexed.Load(), o.rejected.Load()
+	v.Retried, v.Failures = o.retried.Load(), o.failures.Load()
+	v.Dropped = dropped + o.spoolErrors.Load()
+	o.mu.Lock()
+	v.LastError, v.LastErrorAt, v.LastIndexedAt = o.lastError, o.lastErrorAt, o.lastIndexedAt
+	o.mu.Unlock()
+	return v
+}
+
+func (h *Handler) GetOpenSearchOutputs(c *gin.Context) {
+	var outs []model.OpenSearchOutput
+	h.DB.Order("created_at").Find(&outs)
+	views := make([]openSearchOutputView, len(outs))
+	for i, out := range outs {
+		views[i] = viewOpenSearchOutput(out)
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func (h *Handler) CreateOpenSearchOutput(c *gin.Context) {
+	var out model.OpenSearchOutput
+	if err := c.ShouldBindJSON(&out); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeOpenSearchOutput(&out); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	out.ID = fmt.Sprintf("OS-%d", time.Now().UnixNano())
+	out.CreatedAt, out.UpdatedAt = h.Now(), h.Now()
+	if err := h.DB.Create(&out).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name is already used by another output"})
+		return
+	}
+	if out.Enabled {
+		if err := startOpenSearchOutput(out); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open the queue: " + err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, viewOpenSearchOutput(out))
+}
+
+// UpdateOpenSearchOutput replaces an output and restarts it. Empty credentials keep the
+// stored ones, queued documents keep the index they were queued for.
+func (h *Handler) UpdateOpenSearchOutput(c *gin.Context) {
+	var old model.OpenSearchOutput
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&old).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Output not found"})
+		return
+	}
+	var out model.OpenSearchOutput
+	if err := c.ShouldBindJSON(&out); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeOpenSearchOutput(&out); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if out.Password == "" {
+		out.Password = old.Password
+	}
+	if out.APIKey == "" {
+		out.APIKey = old.APIKey
+	}
+	out.ID, out.CreatedAt, out.UpdatedAt = old.ID, old.CreatedAt, h.Now()
+	if err := h.DB.Save(&out).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name is already used by another output"})
+		return
+	}
+	stopOpenSearchOutput(out.ID, false)
+	if out.Enabled {
+		if err := startOpenSearchOutput(out); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open the queue: " + err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, viewOpenSearchOutput(out))
+}
+
+// DeleteOpenSearchOutput removes an output and discards its queue, indexed documents stay
+func (h *Handler) DeleteOpenSearchOutput(c *gin.Context) {
+	id := c.Param("id")
+	res := h.DB.Delete(&model.OpenSearchOutput{}, "id = ?", id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete output"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Output not found"})
+		return
+	}
+	stopOpenSearchOutput(id, true)
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// TestOpenSearchOutput checks the cluster answers with the credentials and installs the
+// index template, so a permission problem shows up before documents queue
+func (h *Handler) TestOpenSearchOutput(c *gin.Context) {
+	var out model.OpenSearchOutput
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&out).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Output not found"})
+		return
+	}
+	client, err := newOpenSearchClient(out)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	info, err := client.info()
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if !out.SkipTemplate {
+		if err := client.putTemplate(); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Installing the index template failed: " + err.Error()})
+			return
+		}
+	}
+	distribution := info.Version.Distribution
+	if distribution == "" {
+		distribution = "elasticsearch"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success", "cluster": info.ClusterName, "version": info.Version.Number,
+		"distribution": distribution, "template": !out.SkipTemplate,
+	})
+}
+
+// BackfillOptions narrow a backfill, zero values take everything
+type BackfillOptions struct {
+	Kinds     []string
+	Since     time.Time
+	Until     time.Time
+	BatchSize int
+	// Progress is called after every bulk request with the totals of the kind so far
+	Progress func(kind string, indexed, rejected int)
+}
+
+// BackfillResult counts the documents of a backfill per kind
+type BackfillResult struct {
+	Indexed  map[string]int
+	Rejected map[string]int
+}
+
+// BackfillOpenSearch indexes the stored history into the cluster of an output, past its
+// queue. The filter of the output applies as it does to live events, and documents keep
+// their IDs, so a backfill can run again or overlap the live feed without duplicates.
+func BackfillOpenSearch(db *gorm.DB, out model.OpenSearchOutput, opts BackfillOptions) (BackfillResult, error) {
+	res := BackfillResult{Indexed: map[string]int{}, Rejected: map[string]int{}}
+	if err := normalizeOpenSearchOutput(&out); err != nil {
+		return res, err
+	}
+	client, err := newOpenSearchClient(out)
+	if err != nil {
+		return res, err
+	}
+	if !out.SkipTemplate {
+		if err := client.putTemplate(); err != nil {
+			return res, fmt.Errorf("installing the index template: %w", err)
+		}
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = out.BatchSize
+	}
+
+	for _, kind := range siemKinds {
+		if len(opts.Kinds) > 0 && !slices.Contains(opts.Kinds, kind) {
+			continue
+		}
+		send := func(events []siemEvent) error {
+			var recs [][]byte
+			for _, ev := range events {
+				if !ev.matches(out.Kinds, out.MinSeverity) {
+					continue
+				}
+				rec, err := bulkRecord(out.IndexPrefix, ev)
+				if err != nil {
+					return err
+				}
+				recs = append(recs, rec)
+			}
+			for len(recs) > 0 {
+				n, size := 0, 0
+				for n < len(recs) && (n == 0 || size+len(recs[n]) <= openSearchMaxBatchBytes) {
+					size += len(recs[n])
+					n++
+				}
+				indexed, rejected, err := bulkWithRetry(client, recs[:n])
+				if err != nil {
+					return err
+				}
+				res.Indexed[kind] += indexed
+				res.Rejected[kind] += rejected
+				if opts.Progress != nil {
+					opts.Progress(kind, res.Indexed[kind], res.Rejected[kind])
+				}
+				recs = recs[n:]
+			}
+			return nil
+		}
+		if err := backfillKind(db, kind, opts, send); err != nil {
+			return res, fmt.Errorf("%s: %w", kind, err)
+		}
+	}
+	return res, nil
+}
+
+// backfillKind reads the table of a kind in batches and hands them on as events
+func backfillKind(db *gorm.DB, kind string, opts BackfillOptions, send func([]siemEvent) error) error {
+	switch kind {
+	case "attack":
+		return backfillRows(db, "timestamp", opts, attackSiemEvent, send)
+	case "scan":
+		return backfillRows(db, "start", opts, scanSiemEvent, send)
+	case "credential":
+		return backfillRows(db, "time", opts, credentialSiemEvent, send)
+	case "decoy":
+		return backfillRows(db, "time", opts, decoySiemEvent, send)
+	case "sample":
+		return backfillRows(db, "last_time", opts, sampleSiemEvent, send)
+	case "login":
+		return backfillRows(db, "time", opts, loginSiemEvent, send)
+	}
+	return nil
+}
+
+func backfillRows[T any](db *gorm.DB, timeColumn string, opts BackfillOptions, event func(T) siemEvent, send func([]siemEvent) error) error {
+	q := db.Model(new(T))
+	if !opts.Since.IsZero() {
+		q = q.Where(timeColumn+" >= ?", opts.Since.UTC())
+	}
+	if !opts.Until.IsZero() {
+		q = q.Where(timeColumn+" < ?", opts.Until.UTC())
+	}
+	var rows []T
+	return q.FindInBatches(&rows, opts.BatchSize, func(tx *gorm.DB, batch int) error {
+		events := make([]siemEvent, len(rows))
+		for i, row := range rows {
+			events[i] = event(row)
+		}
+		return send(events)
+	}).Error
+}
+
+// bulkWithRetry sends one bulk request until every document was indexed or rejected for
+// good. It gives up after a few minutes of refusals, the backfill can run again.
+func bulkWithRetry(client *openSearchClient, recs [][]byte) (indexed, rejected int, err error) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		res, err := client.bulk(recs)
+		if err == nil {
+			indexed += res.indexed
+			rejected += res.rejected
+			if res.rejected > 0 {
+				log.Printf("OpenSearch backfill: %d documents rejected, last: %s", res.rejected, res.lastReject)
+			}
+			if len(res.retry) == 0 {
+				return indexed, rejected, nil
+			}
+			retry := make([][]byte, len(res.retry))
+			for i, j := range res.retry {
+				retry[i] = recs[j]
+			}
+			recs = retry
+		}
+		if attempt == 10 {
+			if err == nil {
+				err = fmt.Errorf("%d documents still throttled", len(recs))
+			}
+			return indexed, rejected, err
+		}
+		wait := backoff
+		var se *statusError
+		if errors.As(err, &se) {
+			if se.code != http.StatusTooManyRequests && se.code < 500 {
+				return indexed, rejected, err
+			}
+			if se.retryAfter > 0 {
+				wait = se.retryAfter
+			}
+		} else if err != nil {
+			log.Printf("OpenSearch backfill: bulk request failed, retrying in %s: %v", wait, err)
+		}
+		time.Sleep(wait)
+		backoff = min(backoff*2, openSearchMaxBackoff)
+	}
+}
+
+// writeOpenSearchMetrics exports the indexing counters and queue depth of every running output
+func writeOpenSearchMetrics(w *metrics.Writer) {
+	openSearchOutputs.RLock()
+	outputs := make([]*openSearchOutput, 0, len(openSearchOutputs.m))
+	for _, o := range openSearchOutputs.m {
+		outputs = append(outputs, o)
+	}
+	openSearchOutputs.RUnlock()
+	if len(outputs) == 0 {
+		return
+	}
+	slices.SortFunc(outputs, func(a, b *openSearchOutput) int { return strings.Compare(a.out.Name, b.out.Name) })
+
+	family := func(name, kind, help string, value func(*openSearchOutput) float64) {
+		w.Header(name, kind, help)
+		for _, o := range outputs {
+			w.Sample(name, value(o), "output", o.out.Name)
+		}
+	}
+	family("prts_opensearch_docs_queued_total", "counter", "Documents queued for an OpenSearch output.",
+		func(o *openSearchOutput) float64 { return float64(o.queued.Load()) })
+	family("prts_opensearch_docs_indexed_total", "counter", "Documents the cluster indexed.",
+		func(o *openSearchOutput) float64 { return float64(o.indexed.Load()) })
+	family("prts_opensearch_docs_rejected_total", "counter", "Documents the cluster refused for good, such as mapping conflicts.",
+		func(o *openSearchOutput) float64 { return float64(o.rejected.Load()) })
+	family("prts_opensearch_docs_retried_total", "counter", "Documents sent again after the cluster throttled them.",
+		func(o *openSearchOutput) float64 { return float64(o.retried.Load()) })
+	family("prts_opensearch_bulk_failures_total", "counter", "Bulk requests that failed as a whole.",
+		func(o *openSearchOutput) float64 { return float64(o.failures.Load()) })
+	family("prts_opensearch_docs_dropped_total", "counter", "Documents lost because the queue was full or could not be written.",
+		func(o *openSearchOutput) float64 {
+			_, _, dropped := o.spool.Stats()
+			return float64(dropped + o.spoolErrors.Load())
+		})
+	family("prts_opensearch_queue_docs", "gauge", "Documents waiting in the on-disk queue of an OpenSearch output.",
+		func(o *openSearchOutput) float64 {
+			pending, _, _ := o.spool.Stats()
+			return float64(pending)
+		})
+	family("prts_opensearch_queue_bytes", "gauge", "Size of the on-disk queue of an OpenSearch output.",
+		func(o *openSearchOutput) float64 {
+			_, size, _ := o.spool.Stats()
+			return float64(size)
+		})
+}
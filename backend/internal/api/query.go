@@ -0,0 +1,238 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	listDefaultLimit = 100
+	listMaxLimit     = 1000
+	// listMaxAddresses caps the distinct addresses an IP range filter may expand to
+	listMaxAddresses = 20000
+)
+
+// listSpec describes what a list endpoint lets the caller filter and sort on. Columns
+// named here are trusted, query parameters only ever pick among them.
+type listSpec struct {
+	timeColumn  string            // Column of the from/to range
+	sorts       map[string]string // Sort key of the API to column, id breaks ties
+	defaultSort string            // Sort key, a leading - sorts descending
+	filters     map[string]string // Parameter to column, comma-separated values match any of them
+	ipFilters   map[string]string // Parameter to column, takes addresses, CIDR blocks and ranges
+	search      []string          // Columns matched by q
+}
+
+// listCursor is the position after the last row of a page. It carries the sort key so a
+// cursor can not be replayed against another order.
+type listCursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    json.RawMessage `json:"id"`
+}
+
+// listQuery answers a list request with one page of T. The body stays a plain array,
+// X-Total-Count carries the number of matching rows and X-Next-Cursor the position of
+// the next page, it is left out on the last page. Pages are picked with cursor or offset.
+// Scopes narrow the rows before the filters of the spec, e.g. to join per-user state.
+func listQuery[T any](h *Handler, c *gin.Context, spec listSpec, scopes ...func(*gorm.DB) *gorm.DB) {
+	q := h.DB.Model(new(T))
+	for _, scope := range scopes {
+		q = scope(q)
+	}
+	q, err := spec.apply(q, c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sortKey := c.DefaultQuery("sort", spec.defaultSort)
+	column, ok := spec.sorts[strings.TrimPrefix(sortKey, "-")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot sort by " + sortKey})
+		return
+	}
+	dir, cmp := "asc", ">"
+	if strings.HasPrefix(sortKey, "-") {
+		dir, cmp = "desc", "<"
+	}
+
+	limit := listDefaultLimit
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		limit = min(limit, listMaxLimit)
+	}
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count rows"})
+		return
+	}
+
+	stmt := &gorm.Statement{DB: h.DB}
+	if err := stmt.Parse(new(T)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sortField, idField := stmt.Schema.LookUpField(column), stmt.Schema.LookUpField("id")
+
+	if raw := c.Query("cursor"); raw != "" {
+		value, id, err := decodeListCursor(raw, sortKey, sortField.FieldType, idField.FieldType)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		q = q.Where(fmt.Sprintf("(%[1]s %[2]s ?) OR (%[1]s = ? AND id %[2]s ?)", column, cmp), value, value, id)
+	} else if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must not be negative"})
+			return
+		}
+		q = q.Offset(offset)
+	}
+
+	rows := []T{}
+	if err := q.Order(fmt.Sprintf("%s %s, id %s", column, dir, dir)).Limit(limit + 1).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load rows"})
+		return
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := reflect.ValueOf(&rows[limit-1]).Elem()
+		ctx := c.Request.Context()
+		cursor := listCursor{Sort: sortKey}
+		cursor.Value, _ = json.Marshal(sortField.ReflectValueOf(ctx, last).Interface())
+		cursor.ID, _ = json.Marshal(idField.ReflectValueOf(ctx, last).Interface())
+		raw, _ := json.Marshal(cursor)
+		c.Header("X-Next-Cursor", base64.RawURLEncoding.EncodeToString(raw))
+	}
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.JSON(http.StatusOK, rows)
+}
+
+func decodeListCursor(raw, sortKey string, valueType, idType reflect.Type) (interface{}, interface{}, error) {
+	invalid := errors.New("invalid cursor")
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, nil, invalid
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, nil, invalid
+	}
+	if cursor.Sort != sortKey {
+		return nil, nil, errors.New("cursor belongs to another sort order")
+	}
+	value, id := reflect.New(valueType), reflect.New(idType)
+	if json.Unmarshal(cursor.Value, value.Interface()) != nil || json.Unmarshal(cursor.ID, id.Interface()) != nil {
+		return nil, nil, invalid
+	}
+	return value.Elem().Interface(), id.Elem().Interface(), nil
+}
+
+// apply adds the time range, field filters and search of the request to q
+func (spec listSpec) apply(q *gorm.DB, c *gin.Context) (*gorm.DB, error) {
+	if spec.timeColumn != "" {
+		for param, op := range map[string]string{"from": ">=", "to": "<="} {
+			v := c.Query(param)
+			if v == "" {
+				continue
+			}
+			t, ok := parseTimeParam(v)
+			if !ok {
+				return nil, fmt.Errorf("%s must be an RFC 3339 time", param)
+			}
+			q = q.Where(spec.timeColumn+" "+op+" ?", t)
+		}
+	}
+
+	for param, column := range spec.filters {
+		if values := splitList(c.Query(param)); len(values) > 0 {
+			q = q.Where(column+" IN ?", values)
+		}
+	}
+
+	for param, column := range spec.ipFilters {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		var err error
+		if q, err = filterAddresses(q, column, v); err != nil {
+			return nil, fmt.Errorf("%s: %v", param, err)
+		}
+	}
+
+	if text := strings.TrimSpace(c.Query("q")); text != "" && len(spec.search) > 0 {
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text) + "%"
+		var conds []string
+		var args []interface{}
+		for _, column := range spec.search {
+			conds = append(conds, column+` LIKE ? ESCAPE '\'`)
+			args = append(args, pattern)
+		}
+		q = q.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+	return q, nil
+}
+
+// filterAddresses narrows q to rows whose address column falls in any of the
+// comma-separated addresses, CIDR blocks or ranges. Addresses are stored as text, so
+// blocks and ranges are matched against the distinct addresses of the filtered rows
+// and turned into an IN list that can use the index of the column.
+func filterAddresses(q *gorm.DB, column, value string) (*gorm.DB, error) {
+	var exact []string
+	var spans []addrRange
+	for _, item := range splitList(value) {
+		canonical, span, err := parseRuleAddress(item)
+		if err != nil {
+			return nil, err
+		}
+		if span.start == span.end {
+			exact = append(exact, canonical)
+		} else {
+			spans = append(spans, span)
+		}
+	}
+	if len(spans) == 0 {
+		return q.Where(column+" IN ?", exact), nil
+	}
+
+	var candidates []string
+	q.Session(&gorm.Session{}).Distinct(column).Pluck(column, &candidates)
+	matched := exact
+	for _, candidate := range candidates {
+		addr, err := netip.ParseAddr(candidate)
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+		for _, span := range spans {
+			if span.contains(addrRange{addr, addr}) {
+				matched = append(matched, candidate)
+				break
+			}
+		}
+	}
+	if len(matched) > listMaxAddresses {
+		return nil, fmt.Errorf("matches more than %d addresses, narrow it down", listMaxAddresses)
+	}
+	if len(matched) == 0 {
+		return q.Where("1 = 0"), nil
+	}
+	return q.Where(column+" IN ?", matched), nil
+}
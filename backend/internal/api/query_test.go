@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestHandler opens a fresh database with the tables of models, stored in UTC like
+// the server does
+func newTestHandler(t *testing.T, models ...interface{}) *Handler {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+		Logger:  logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return &Handler{DB: db}
+}
+
+// getAttacks runs GetAttacks with the query string
+func getAttacks(h *Handler, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/attacks?"+query, nil)
+	h.GetAttacks(c)
+	return w
+}
+
+// seedAttacks stores n attacks over a few timestamps, so most of them tie on the time
+func seedAttacks(t *testing.T, h *Handler, n int) []model.AttackLog {
+	t.Helper()
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	severities := []string{"low", "medium", "high"}
+	var rows []model.AttackLog
+	for i := 0; i < n; i++ {
+		rows = append(rows, model.AttackLog{
+			ID:        fmt.Sprintf("A-%03d", (i*7)%n), // Insertion order differs from ID order
+			Timestamp: base.Add(time.Duration(i%5) * time.Minute),
+			SourceIP:  fmt.Sprintf("10.0.0.%d", i%4),
+			Method:    "SSH",
+			Severity:  severities[i%3],
+			Status:    "monitored",
+		})
+	}
+	if err := h.DB.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+// pageThrough follows X-Next-Cursor from the first page to the last
+func pageThrough(t *testing.T, h *Handler, query string) []model.AttackLog {
+	t.Helper()
+	var all []model.AttackLog
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("cursor never ran out")
+		}
+		q := query
+		if cursor != "" {
+			q += "&cursor=" + cursor
+		}
+		w := getAttacks(h, q)
+		if w.Code != http.StatusOK {
+			t.Fatalf("page %d: status %d: %s", pages, w.Code, w.Body.String())
+		}
+		var page []model.AttackLog
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, page...)
+		if cursor = w.Header().Get("X-Next-Cursor"); cursor == "" {
+			return all
+		}
+	}
+}
+
+func ids(rows []model.AttackLog) []string {
+	out := make([]string, len(rows))
+	for i, r := range rows {
+		out[i] = r.ID
+	}
+	return out
+}
+
+func TestListQueryCursorPagesThroughTies(t *testing.T) {
+	h := newTestHandler(t, &model.AttackLog{})
+	rows := seedAttacks(t, h, 23)
+
+	for _, tc := range []struct {
+		sort string
+		less func(a, b model.AttackLog) int
+	}{
+		{"-timestamp", func(a, b model.AttackLog) int {
+			if c := b.Timestamp.Compare(a.Timestamp); c != 0 {
+				return c
+			}
+			return strings.Compare(b.ID, a.ID)
+		}},
+		{"sourceIp", func(a, b model.AttackLog) int {
+			if c := strings.Compare(a.SourceIP, b.SourceIP); c != 0 {
+				return c
+			}
+			return strings.Compare(a.ID, b.ID)
+		}},
+	} {
+		t.Run(tc.sort, func(t *testing.T) {
+			want := slices.Clone(rows)
+			slices.SortFunc(want, tc.less)
+			got := pageThrough(t, h, "limit=4&sort="+tc.sort)
+			if !slices.Equal(ids(got), ids(want)) {
+				t.Fatalf("pages gave\n%v\nwant\n%v", ids(got), ids(want))
+			}
+		})
+	}
+}
+
+func TestListQueryCursorKeepsFilters(t *testing.T) {
+	h := newTestHandler(t, &model.AttackLog{})
+	seedAttacks(t, h, 23)
+
+	w := getAttacks(h, "limit=3&severity=high")
+	if total := w.Header().Get("X-Total-Count"); total != "7" {
+		t.Fatalf("X-Total-Count = %s, want 7", total)
+	}
+	for _, row := range pageThrough(t, h, "limit=3&severity=high") {
+		if row.Severity != "high" {
+			t.Fatalf("row %s has severity %s", row.ID, row.Severity)
+		}
+	}
+	if got := pageThrough(t, h, "limit=3&severity=high"); len(got) != 7 {
+		t.Fatalf("got %d rows over the pages, want 7", len(got))
+	}
+}
+
+func TestListQueryLastPageHasNoCursor(t *testing.T) {
+	h := newTestHandler(t, &model.AttackLog{})
+	seedAttacks(t, h, 5)
+
+	if w := getAttacks(h, "limit=5"); w.Header().Get("X-Next-Cursor") != "" {
+		t.Fatal("a page holding every row has a next cursor")
+	}
+	if w := getAttacks(h, "limit=4"); w.Header().Get("X-Next-Cursor") == "" {
+		t.Fatal("a partial page has no next cursor")
+	}
+}
+
+func TestListQueryRejectsBadCursors(t *testing.T) {
+	h := newTestHandler(t, &model.AttackLog{})
+	seedAttacks(t, h, 10)
+
+	next := getAttacks(h, "limit=2&sort=-timestamp").Header().Get("X-Next-Cursor")
+	for name, query := range map[string]string{
+		"other sort": "sort=timestamp&cursor=" + next,
+		"not base64": "cursor=!!!",
+		"not json":   "cursor=bm90IGpzb24",
+		"bad value":  "cursor=eyJzIjoiLXRpbWVzdGFtcCIsInYiOiJ4IiwiaWQiOiJ5In0",
+		"bad sort":   "sort=payload",
+		"bad limit":  "limit=-1",
+	} {
+		if w := getAttacks(h, query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, w.Code)
+		}
+	}
+}
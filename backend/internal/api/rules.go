@@ -0,0 +1,377 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// ruleDiffMaxChanges is how far behind a probe may be and still get a diff instead of a snapshot
+	ruleDiffMaxChanges = 256
+	// ruleChangeRetention is how many change log entries are kept for diffs
+	ruleChangeRetention = 4096
+	// ruleResendInterval keeps a slow probe from being flooded while it catches up
+	ruleResendInterval = 15 * time.Second
+)
+
+var (
+	ruleSendMu   sync.Mutex
+	ruleSentTime = map[string]time.Time{}
+)
+
+// ruleSnapshot replaces the probe's whole rule set
+type ruleSnapshot struct {
+	Version int64                     `json:"version"`
+	Rules   []model.AccessControlRule `json:"rules"`
+}
+
+// ruleDiff moves a probe from version From to Version, rules are added or replaced by ID
+type ruleDiff struct {
+	From    int64                     `json:"from"`
+	Version int64                     `json:"version"`
+	Add     []model.AccessControlRule `json:"add"`
+	Remove  []string                  `json:"remove"`
+}
+
+func (h *Handler) currentRuleVersion() int64 {
+	var version int64
+	h.DB.Model(&model.AccessRuleChange{}).Select("COALESCE(MAX(version), 0)").Scan(&version)
+	return version
+}
+
+// recordRuleChange appends to the change log and returns the new rule set version
+func recordRuleChange(tx *gorm.DB, op string, ruleIDs ...string) (int64, error) {
+	now := GetNow()
+	var change model.AccessRuleChange
+	for _, id := range ruleIDs {
+		change = model.AccessRuleChange{Op: op, RuleID: id, Time: now}
+		if err := tx.Create(&change).Error; err != nil {
+			return 0, err
+		}
+	}
+	if change.Version > ruleChangeRetention {
+		// The newest reset and the newest change of every rule that still exists stay, they
+		// are the baseline of the per-rule versions
+		baseline := tx.Model(&model.AccessRuleChange{}).Select("MAX(version)").
+			Where("rule_id = '' OR rule_id IN (?)", tx.Model(&model.AccessControlRule{}).Select("id")).Group("rule_id")
+		if err := tx.Where("version <= ? AND version NOT IN (?)", change.Version-ruleChangeRetention, baseline).Delete(&model.AccessRuleChange{}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return change.Version, nil
+}
+
+// EnsureRuleVersion starts the change log so that probes which never synced are behind
+func (h *Handler) EnsureRuleVersion() {
+	if h.currentRuleVersion() == 0 {
+		recordRuleChange(h.DB, "reset", "")
+	}
+}
+
+// ruleExpired reports whether a rule's expire time has passed, permanent rules have none
+func ruleExpired(rule model.AccessControlRule, now time.Time) bool {
+	return rule.ExpireTime != nil && rule.ExpireTime.Before(now)
+}
+
+// activeRules loads the rules probes should enforce, rules past their expire time are
+// marked expired and logged as removals
+func (h *Handler) activeRules() []model.AccessControlRule {
+	now := h.Now()
+	var expired []string
+	h.DB.Model(&model.AccessControlRule{}).Where("status = ? AND expire_time IS NOT NULL AND expire_time <= ?", "active", now).Pluck("id", &expired)
+	if len(expired) > 0 {
+		h.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&model.AccessControlRule{}).Where("id IN ?", expired).Update("status", "expired").Error; err != nil {
+				return err
+			}
+			_, err := recordRuleChange(tx, "remove", expired...)
+			return err
+		})
+		log.Printf("Expired %d access rules", len(expired))
+	}
+
+	var rules []model.AccessControlRule
+	h.DB.Where("status = ?", "active").Find(&rules)
+	return rules
+}
+
+// buildRuleDiff folds the change log after from into the final state of every touched rule
+// as the node sees it, rules outside its scope are removals.
+// ok is false when the log no longer reaches back far enough or the probe is too far behind.
+func (h *Handler) buildRuleDiff(node model.NodeStatus, from, current int64) (ruleDiff, bool) {
+	diff := ruleDiff{From: from, Version: current, Add: []model.AccessControlRule{}, Remove: []string{}}
+	if from <= 0 || from >= current || current-from > ruleDiffMaxChanges {
+		return diff, false
+	}
+	var changes []model.AccessRuleChange
+	h.DB.Where("version > ? AND version <= ?", from, current).Order("version asc").Find(&changes)
+	// Pruning leaves gaps behind the baseline rows it keeps, every version must be there
+	if int64(len(changes)) != current-from {
+		return diff, false
+	}
+	touched := map[string]bool{}
+	var ids []string
+	for _, ch := range changes {
+		if ch.Op == "reset" {
+			return diff, false
+		}
+		if !touched[ch.RuleID] {
+			touched[ch.RuleID] = true
+			ids = append(ids, ch.RuleID)
+		}
+	}
+
+	live := map[string]model.AccessControlRule{}
+	if len(ids) > 0 {
+		var rules []model.AccessControlRule
+		h.DB.Where("id IN ?", ids).Find(&rules)
+		now := h.Now()
+		for _, r := range rules {
+			if r.Status == "active" && !ruleExpired(r, now) && ruleAppliesTo(r, node) {
+				live[r.ID] = r
+			}
+		}
+	}
+	for _, id := range ids {
+		if r, ok := live[id]; ok {
+			diff.Add = append(diff.Add, r)
+		} else {
+			diff.Remove = append(diff.Remove, id)
+		}
+	}
+	return diff, true
+}
+
+// sendRules brings a probe from the version it reported to the current one, with a diff
+// when it is only slightly behind and a full snapshot otherwise. Only the rules in the
+// node's scope are sent.
+func (h *Handler) sendRules(nodeID string, from int64, forceSnapshot bool) bool {
+	node := h.loadTargetNode(nodeID)
+	// The version is read before the rules, a change in between is then already in them
+	// and sent again later, never stamped as applied without being there
+	current := h.currentRuleVersion()
+	rules := rulesForNode(h.activeRules(), node)
+
+	var msg []byte
+	if !forceSnapshot {
+		if diff, ok := h.buildRuleDiff(node, from, current); ok {
+			msg, _ = json.Marshal(map[string]interface{}{
+				"type": "RULES_DIFF",
+				"data": diff,
+			})
+			log.Printf("Sending rule diff v%d -> v%d to %s (+%d -%d)", from, current, nodeID, len(diff.Add), len(diff.Remove))
+		}
+	}
+	if msg == nil {
+		msg, _ = json.Marshal(map[string]interface{}{
+			"type": "SYNC_RULES",
+			"data": ruleSnapshot{Version: current, Rules: rules},
+		})
+		log.Printf("Sending rule snapshot v%d to %s (%d rules)", current, nodeID, len(rules))
+	}
+
+	ruleSendMu.Lock()
+	ruleSentTime[nodeID] = time.Now()
+	ruleSendMu.Unlock()
+	return h.Hub.SendToNode(nodeID, msg)
+}
+
+// syncRulesToNode pushes the node's full effective rule set, used when enforcement is
+// switched back on or the node's groups change
+func (h *Handler) syncRulesToNode(nodeID string) {
+	h.sendRules(nodeID, 0, true)
+}
+
+// reconcileNodeRules runs on every node report and catches up probes that are behind
+func (h *Handler) reconcileNodeRules(nodeID string, applied int64) {
+	if applied >= h.currentRuleVersion() {
+		return
+	}
+	ruleSendMu.Lock()
+	last := ruleSentTime[nodeID]
+	ruleSendMu.Unlock()
+	if time.Since(last) < ruleResendInterval {
+		return
+	}
+	h.sendRules(nodeID, applied, false)
+}
+
+// pushRuleChanges sends every connected probe what it is missing
+func (h *Handler) pushRuleChanges() {
+	nodeIDs := h.Hub.NodeIDs()
+	if len(nodeIDs) == 0 {
+		return
+	}
+	var nodes []model.NodeStatus
+	h.DB.Select("id", "rule_version").Where("id IN ?", nodeIDs).Find(&nodes)
+	for _, node := range nodes {
+		h.sendRules(node.ID, node.RuleVersion, false)
+	}
+}
+
+// RunAccessRuleExpiry retires expired rules once a minute so probes drop them without a manual sync
+func (h *Handler) RunAccessRuleExpiry() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		before := h.currentRuleVersion()
+		h.activeRules()
+		if h.currentRuleVersion() != before {
+			h.pushRuleChanges()
+		}
+	}
+}
+
+func (h *Handler) GetAccessControlRules(c *gin.Context) {
+	var rules []model.AccessControlRule
+	h.DB.Find(&rules)
+	c.Header("X-Rule-Version", strconv.FormatInt(h.currentRuleVersion(), 10))
+	c.JSON(http.StatusOK, h.accessRuleViews(rules))
+}
+
+func (h *Handler) CreateAccessControlRule(c *gin.Context) {
+	var rule model.AccessControlRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rule.ID == "" {
+		rule.ID = fmt.Sprintf("AC-%d", time.Now().UnixNano())
+	}
+	rule.AddTime = h.Now()
+	if rule.ExpireTime != nil {
+		expire := rule.ExpireTime.UTC()
+		rule.ExpireTime = &expire
+	}
+	if rule.Status == "" {
+		rule.Status = "active"
+	}
+	if err := normalizeAccessRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Rules of a blocklist are replaced on every refresh
+	var owned int64
+	h.DB.Model(&model.BlocklistSubscription{}).Where("name = ?", rule.Source).Count(&owned)
+	if owned > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Source is reserved for a blocklist subscription"})
+		return
+	}
+
+	// Exact duplicates are refused, rules of the opposite type over the same addresses
+	// need force=true so that exceptions are deliberate
+	var conflicts []ruleOverlap
+	for _, o := range h.findRuleOverlaps(rule) {
+		if o.Relation == "duplicate" && !o.Conflict && o.Rule.Protocol == rule.Protocol && o.Rule.Ports == rule.Ports && o.Rule.Direction == rule.Direction &&
+			o.Rule.Scope == rule.Scope && o.Rule.Targets == rule.Targets {
+			c.JSON(http.StatusConflict, gin.H{"error": "An identical rule already exists", "overlaps": []ruleOverlap{o}})
+			return
+		}
+		if o.Conflict {
+			conflicts = append(conflicts, o)
+		}
+	}
+	if len(conflicts) > 0 && c.Query("force") != "true" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Rule conflicts with existing rules", "overlaps": conflicts})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&rule).Error; err != nil {
+			return err
+		}
+		_, err := recordRuleChange(tx, "add", rule.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rule"})
+		return
+	}
+	go h.pushRuleChanges()
+	c.JSON(http.StatusOK, h.accessRuleViews([]model.AccessControlRule{rule})[0])
+}
+
+func (h *Handler) DeleteAccessControlRule(c *gin.Context) {
+	id := c.Param("id")
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&model.AccessControlRule{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		_, err := recordRuleChange(tx, "remove", id)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
+		return
+	}
+	go h.pushRuleChanges()
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted"})
+}
+
+// SyncAccessRules forces a full snapshot of its effective rule set to every connected probe
+func (h *Handler) SyncAccessRules(c *gin.Context) {
+	rules := h.activeRules()
+	nodeIDs := h.Hub.NodeIDs()
+	for _, nodeID := range nodeIDs {
+		h.sendRules(nodeID, 0, true)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sync command broadcasted", "count": len(rules), "nodes": len(nodeIDs), "version": h.currentRuleVersion()})
+}
+
+type ruleDrift struct {
+	NodeID         string `json:"nodeId"`
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	AppliedVersion int64  `json:"appliedVersion"`
+	CurrentVersion int64  `json:"currentVersion"`
+	Behind         int64  `json:"behind"` // Number of rule changes not yet applied
+	InSync         bool   `json:"inSync"`
+	FirewallStatus string `json:"firewallStatus"`
+	FirewallError  string `json:"firewallError"`
+}
+
+// GetRuleDrift shows per node how far the applied rule set lags behind the server
+func (h *Handler) GetRuleDrift(c *gin.Context) {
+	current := h.currentRuleVersion()
+	var nodes []model.NodeStatus
+	h.DB.Find(&nodes)
+
+	drift := make([]ruleDrift, 0, len(nodes))
+	for _, node := range nodes {
+		behind := current - node.RuleVersion
+		if behind < 0 {
+			behind = 0
+		}
+		drift = append(drift, ruleDrift{
+			NodeID:         node.ID,
+			Name:           node.Name,
+			Status:         node.Status,
+			AppliedVersion: node.RuleVersion,
+			CurrentVersion: current,
+			Behind:         behind,
+			InSync:         behind == 0,
+			FirewallStatus: node.FirewallStatus,
+			FirewallError:  node.FirewallError,
+		})
+	}
+	c.JSON(http.StatusOK, drift)
+}
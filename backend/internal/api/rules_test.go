@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func TestRuleChangePruningKeepsBaseline(t *testing.T) {
+	h := newTestHandler(t, &model.AccessControlRule{}, &model.AccessRuleChange{})
+	h.EnsureRuleVersion()
+	if err := h.DB.Create(&model.AccessControlRule{ID: "r1", Status: "active"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recordRuleChange(h.DB, "add", "r1"); err != nil {
+		t.Fatal(err)
+	}
+	// Rules that are gone by now fill the log well past the retention
+	gone := make([]string, ruleChangeRetention+100)
+	for i := range gone {
+		gone[i] = fmt.Sprintf("gone-%d", i)
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := recordRuleChange(tx, "remove", gone...)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := h.currentRuleVersion()
+	if want := int64(2 + len(gone)); current != want {
+		t.Fatalf("version = %d, want %d", current, want)
+	}
+	versions := h.ruleChangeVersions()
+	if versions[""] != 1 || versions["r1"] != 2 {
+		t.Fatalf("baseline reset v%d and r1 v%d, want v1 and v2", versions[""], versions["r1"])
+	}
+	if _, ok := versions["gone-0"]; ok {
+		t.Fatal("the old change of a deleted rule was kept")
+	}
+	var kept int64
+	h.DB.Model(&model.AccessRuleChange{}).Count(&kept)
+	if kept != ruleChangeRetention+2 {
+		t.Fatalf("%d changes kept, want the retention and 2 baseline rows", kept)
+	}
+
+	// The baseline rows do not make the log look like it reaches back to them
+	if _, ok := h.buildRuleDiff(model.NodeStatus{}, 2, 100); ok {
+		t.Fatal("diff built across pruned versions")
+	}
+	if diff, ok := h.buildRuleDiff(model.NodeStatus{}, current-10, current); !ok || len(diff.Remove) != 10 {
+		t.Fatalf("recent diff = %+v, %v, want 10 removals", diff, ok)
+	}
+}
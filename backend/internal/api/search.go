@@ -0,0 +1,425 @@
+package api
+
+import (
+	"html"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// searchSchemaVersion is stored with the engine under the search_index config key, a
+// new version rebuilds the documents from their records
+const searchSchemaVersion = "1"
+
+// searchEngine is the full-text index in use: fts5 when SQLite is built with it (the
+// sqlite_fts5 build tag), fts4 which the driver always has, like for other databases.
+var searchEngine = "like"
+
+var searchTables = map[string]string{"fts5": "search_fts5", "fts4": "search_fts4"}
+
+// searchRebuilding guards against two rebuilds running at once
+var searchRebuilding atomic.Bool
+
+// Snippet markers, replaced by <mark> once the text around them is escaped
+const (
+	searchMarkOpen  = "\x02"
+	searchMarkClose = "\x03"
+)
+
+// commandMethods and requestMethods pick the column an attack payload is indexed under
+var (
+	commandMethods = map[string]bool{"SSH": true, "TELNET": true, "FTP": true, "SHELL": true, "REDIS": true, "MYSQL": true}
+	requestMethods = map[string]bool{"HTTP": true, "HTTPS": true, "WEB": true}
+)
+
+func attackSearchDocument(a model.AttackLog) model.SearchDocument {
+	doc := model.SearchDocument{Kind: "attack", RefID: a.ID, IP: a.SourceIP, Time: a.Timestamp}
+	switch method := strings.ToUpper(a.Method); {
+	case commandMethods[method]:
+		doc.Command = a.Payload
+	case requestMethods[method]:
+		doc.Body = a.Payload
+	default:
+		doc.Payload = a.Payload
+	}
+	return doc
+}
+
+func credentialSearchDocument(cred model.AccountCredential) model.SearchDocument {
+	return model.SearchDocument{Kind: "credential", RefID: cred.ID, IP: cred.IP, Time: cred.Time, Username: cred.Username, Password: cred.Password}
+}
+
+func sampleSearchDocument(s model.SampleLog) model.SearchDocument {
+	return model.SearchDocument{Kind: "sample", RefID: s.ID, IP: s.AttackerIP, Time: s.LastTime, FileName: s.FileName}
+}
+
+// StartSearchIndex picks the full-text engine and brings the index up to date. Documents
+// are rebuilt from their records when the schema version changed, the index alone when
+// only the engine did, e.g. after switching to a binary built with FTS5.
+func (h *Handler) StartSearchIndex() {
+	engine := "like"
+	if h.DB.Dialector.Name() == "sqlite" {
+		columns := strings.Join(searchColumns, ", ")
+		// Probe quietly, a build without FTS5 is expected. An existing table is created
+		// without loading its module, so reading it is the real test.
+		probe := h.DB.Session(&gorm.Session{Logger: h.DB.Logger.LogMode(logger.Silent)})
+		available := func(table, using string) error {
+			if err := probe.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS " + table + " USING " + using).Error; err != nil {
+				return err
+			}
+			return probe.Exec("SELECT rowid FROM " + table + " LIMIT 0").Error
+		}
+		if available("search_fts5", "fts5("+columns+", content='search_documents', content_rowid='id')") == nil {
+			engine = "fts5"
+		} else if err := available("search_fts4", "fts4("+columns+", content='search_documents')"); err == nil {
+			engine = "fts4"
+		} else {
+			log.Printf("Search: no full-text index available, falling back to LIKE: %v", err)
+		}
+	}
+
+	var cfg model.SystemConfig
+	h.DB.Where("key = ?", "search_index").Limit(1).Find(&cfg)
+	version, indexed, _ := strings.Cut(cfg.Value, ":")
+
+	searchRebuilding.Store(true)
+	defer searchRebuilding.Store(false)
+	if version != searchSchemaVersion {
+		if err := h.rebuildSearchDocuments(engine); err != nil {
+			log.Printf("Search: failed to build documents: %v", err)
+			return
+		}
+	} else if indexed != engine && engine != "like" {
+		if err := h.DB.Exec("INSERT INTO " + searchTables[engine] + "(" + searchTables[engine] + ") VALUES('rebuild')").Error; err != nil {
+			log.Printf("Search: failed to rebuild the %s index: %v", engine, err)
+			return
+		}
+		log.Printf("Search: rebuilt the %s index", engine)
+	}
+	searchEngine = engine
+
+	cfg.Key, cfg.Value = "search_index", searchSchemaVersion+":"+engine
+	cfg.Description = "Search document version and full-text engine"
+	h.DB.Save(&cfg)
+}
+
+// rebuildSearchDocuments recreates every document from the attack, credential and
+// sample tables and rebuilds the full-text index over them
+func (h *Handler) rebuildSearchDocuments(engine string) error {
+	started := time.Now()
+	if err := h.DB.Where("1 = 1").Delete(&model.SearchDocument{}).Error; err != nil {
+		return err
+	}
+
+	total := 0
+	insert := func(docs []model.SearchDocument) error {
+		if len(docs) == 0 {
+			return nil
+		}
+		total += len(docs)
+		// Records ingested meanwhile already have their document
+		return h.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&docs).Error
+	}
+
+	var attacks []model.AttackLog
+	err := h.DB.FindInBatches(&attacks, 500, func(tx *gorm.DB, batch int) error {
+		docs := make([]model.SearchDocument, len(attacks))
+		for i, a := range attacks {
+			docs[i] = attackSearchDocument(a)
+		}
+		return insert(docs)
+	}).Error
+	if err != nil {
+		return err
+	}
+	var creds []model.AccountCredential
+	err = h.DB.FindInBatches(&creds, 500, func(tx *gorm.DB, batch int) error {
+		docs := make([]model.SearchDocument, len(creds))
+		for i, cred := range creds {
+			docs[i] = credentialSearchDocument(cred)
+		}
+		return insert(docs)
+	}).Error
+	if err != nil {
+		return err
+	}
+	var samples []model.SampleLog
+	err = h.DB.FindInBatches(&samples, 500, func(tx *gorm.DB, batch int) error {
+		docs := make([]model.SearchDocument, len(samples))
+		for i, s := range samples {
+			docs[i] = sampleSearchDocument(s)
+		}
+		return insert(docs)
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	if table := searchTables[engine]; table != "" {
+		if err := h.DB.Exec("INSERT INTO " + table + "(" + table + ") VALUES('rebuild')").Error; err != nil {
+			return err
+		}
+	}
+	log.Printf("Search: indexed %d documents with %s in %v", total, engine, time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// indexSearchDocument adds the document of a record, replacing the one it had
+func (h *Handler) indexSearchDocument(doc model.SearchDocument) {
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := removeSearchDocuments(tx, "kind = ? AND ref_id = ?", doc.Kind, doc.RefID); err != nil {
+			return err
+		}
+		if err := tx.Create(&doc).Error; err != nil {
+			return err
+		}
+		table := searchTables[searchEngine]
+		if table == "" {
+			return nil
+		}
+		return tx.Exec("INSERT INTO "+table+"(rowid, "+strings.Join(searchColumns, ", ")+") VALUES (?, ?, ?, ?, ?, ?, ?)",
+			doc.ID, doc.Payload, doc.Command, doc.Body, doc.Username, doc.Password, doc.FileName).Error
+	})
+	if err != nil {
+		log.Printf("Search: failed to index %s %s: %v", doc.Kind, doc.RefID, err)
+	}
+}
+
+// touchSearchDocument moves the time of a document whose text did not change
+func (h *Handler) touchSearchDocument(kind, refID string, t time.Time) {
+	h.DB.Model(&model.SearchDocument{}).Where("kind = ? AND ref_id = ?", kind, refID).Update("time", t)
+}
+
+// unindexSearchDocuments drops the documents matching the condition
+func (h *Handler) unindexSearchDocuments(query string, args ...interface{}) {
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		return removeSearchDocuments(tx, query, args...)
+	}); err != nil {
+		log.Printf("Search: failed to remove documents: %v", err)
+	}
+}
+
+// removeSearchDocuments deletes documents and their index entries. An external content
+// index reads the old text to remove its entries, so they go first.
+func removeSearchDocuments(tx *gorm.DB, query string, args ...interface{}) error {
+	switch searchEngine {
+	case "fts5":
+		columns := strings.Join(searchColumns, ", ")
+		if err := tx.Exec("INSERT INTO search_fts5(search_fts5, rowid, "+columns+") SELECT 'delete', id, "+columns+" FROM search_documents WHERE "+query, args...).Error; err != nil {
+			return err
+		}
+	case "fts4":
+		if err := tx.Exec("DELETE FROM search_fts4 WHERE rowid IN (SELECT id FROM search_documents WHERE "+query+")", args...).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where(query, args...).Delete(&model.SearchDocument{}).Error
+}
+
+var searchListSpec = listSpec{
+	timeColumn: "d.time",
+	filters:    map[string]string{"kind": "d.kind"},
+	ipFilters:  map[string]string{"ip": "d.ip"},
+}
+
+type searchHit struct {
+	Kind    string    `json:"kind"`
+	RefID   string    `json:"refId"`
+	IP      string    `json:"ip"`
+	Time    time.Time `json:"time"`
+	Snippet string    `json:"snippet"` // HTML, the text is escaped and matches are wrapped in <mark>
+}
+
+// Search finds attacks, credentials and samples by their text. q takes words, "phrases",
+// prefix*, field:term scoping, AND, OR, NOT or -term and parentheses. Results come newest
+// first, sort=relevance ranks them instead where the engine can.
+func (h *Handler) Search(c *gin.Context) {
+	node, err := parseSearch(c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	engine := searchEngine
+	table := searchTables[engine]
+
+	q := h.DB.Table("search_documents AS d")
+	if table == "" {
+		expr, args := node.likeExpr()
+		q = q.Where(expr, args...)
+	} else {
+		q = q.Joins("JOIN "+table+" ON "+table+".rowid = d.id").Where(table+" MATCH ?", node.matchExpr(engine))
+	}
+	if q, err = searchListSpec.apply(q, c); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order := "d.time DESC, d.id DESC"
+	switch c.DefaultQuery("sort", "-time") {
+	case "-time":
+	case "time":
+		order = "d.time ASC, d.id ASC"
+	case "relevance":
+		if engine != "fts5" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "relevance needs the FTS5 index, this server uses " + engine})
+			return
+		}
+		order = "search_fts5.rank, d.id DESC"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot sort by " + c.Query("sort")})
+		return
+	}
+
+	limit, offset := 50, 0
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		limit = min(limit, listMaxLimit)
+	}
+	if v := c.Query("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must not be negative"})
+			return
+		}
+	}
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "search failed: " + err.Error()})
+		return
+	}
+
+	sel := "d.kind, d.ref_id, d.ip, d.time"
+	switch engine {
+	case "fts5":
+		sel += ", snippet(search_fts5, -1, '" + searchMarkOpen + "', '" + searchMarkClose + "', '…', 16) AS snippet"
+	case "fts4":
+		sel += ", snippet(search_fts4, '" + searchMarkOpen + "', '" + searchMarkClose + "', '…', -1, 16) AS snippet"
+	default:
+		sel += ", " + strings.Join(searchColumns, ", ")
+	}
+	var rows []struct {
+		model.SearchDocument
+		Snippet string
+	}
+	if err := q.Select(sel).Order(order).Limit(limit).Offset(offset).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "search failed: " + err.Error()})
+		return
+	}
+
+	hits := make([]searchHit, len(rows))
+	terms := searchTerms(node)
+	for i, row := range rows {
+		snippet := row.Snippet
+		if table == "" {
+			snippet = likeSnippet(row.SearchDocument, terms)
+		}
+		hits[i] = searchHit{Kind: row.Kind, RefID: row.RefID, IP: row.IP, Time: row.Time, Snippet: markSnippet(snippet)}
+	}
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.Header("X-Search-Engine", engine)
+	c.JSON(http.StatusOK, hits)
+}
+
+// ReindexSearch rebuilds every document and the full-text index from the records
+func (h *Handler) ReindexSearch(c *gin.Context) {
+	if !searchRebuilding.CompareAndSwap(false, true) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The search index is being rebuilt"})
+		return
+	}
+	defer searchRebuilding.Store(false)
+
+	if err := h.rebuildSearchDocuments(searchEngine); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rebuild the search index: " + err.Error()})
+		return
+	}
+	var count int64
+	h.DB.Model(&model.SearchDocument{}).Count(&count)
+	c.JSON(http.StatusOK, gin.H{"status": "success", "engine": searchEngine, "documents": count})
+}
+
+// markSnippet escapes a snippet and turns its match markers into <mark> tags
+func markSnippet(s string) string {
+	s = html.EscapeString(s)
+	return strings.NewReplacer(searchMarkOpen, "<mark>", searchMarkClose, "</mark>").Replace(s)
+}
+
+// likeSnippet cuts the text around the first match out of a document found without an
+// index, marking every term in it
+func likeSnippet(doc model.SearchDocument, terms []*searchNode) string {
+	const window = 48
+	fields := map[string]string{"payload": doc.Payload, "command": doc.Command, "body": doc.Body, "username": doc.Username, "password": doc.Password, "file_name": doc.FileName}
+	for _, col := range searchColumns {
+		text := fields[col]
+		lower := lowerASCII(text)
+		first := -1
+		for _, term := range terms {
+			if len(term.columns) > 0 && !slices.Contains(term.columns, col) {
+				continue
+			}
+			if i := strings.Index(lower, lowerASCII(term.text)); i >= 0 && (first < 0 || i < first) {
+				first = i
+			}
+		}
+		if first < 0 {
+			continue
+		}
+
+		start, end := max(0, first-window), min(len(text), first+2*window)
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+		var b strings.Builder
+		if start > 0 {
+			b.WriteString("…")
+		}
+		part, partLower := text[start:end], lower[start:end]
+		for i := 0; i < len(part); {
+			matched := 0
+			for _, term := range terms {
+				if n := len(term.text); n > matched && strings.HasPrefix(partLower[i:], lowerASCII(term.text)) {
+					matched = n
+				}
+			}
+			if matched > 0 {
+				b.WriteString(searchMarkOpen + part[i:i+matched] + searchMarkClose)
+				i += matched
+				continue
+			}
+			b.WriteByte(part[i])
+			i++
+		}
+		if end < len(text) {
+			b.WriteString("…")
+		}
+		return b.String()
+	}
+	return ""
+}
+
+// lowerASCII lowers ASCII letters only so byte offsets stay valid in the original text
+func lowerASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,387 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// searchFields maps the field names of the query language to index columns. payload
+// scopes every column that holds attack traffic.
+var searchFields = map[string][]string{
+	"payload":  {"payload", "command", "body"},
+	"command":  {"command"},
+	"cmd":      {"command"},
+	"body":     {"body"},
+	"user":     {"username"},
+	"username": {"username"},
+	"pass":     {"password"},
+	"password": {"password"},
+	"file":     {"file_name"},
+	"filename": {"file_name"},
+}
+
+// searchColumns are the text columns of the index in declaration order
+var searchColumns = []string{"payload", "command", "body", "username", "password", "file_name"}
+
+// searchNode is a parsed search query. Leaves are terms, a phrase matches its words in
+// order and a prefix term matches every word starting with it.
+type searchNode struct {
+	op       string // term, and, or, not
+	columns  []string
+	text     string
+	phrase   bool
+	prefix   bool
+	children []*searchNode
+}
+
+type searchToken struct {
+	kind  string // word, phrase, and, or, not, (, )
+	field string
+	text  string
+}
+
+// lexSearch splits a query into words, quoted phrases, operators and parentheses. A word
+// or phrase may carry a field: prefix, a leading - negates it.
+func lexSearch(q string) ([]searchToken, error) {
+	var tokens []searchToken
+	r := []rune(q)
+	for i := 0; i < len(r); {
+		switch {
+		case unicode.IsSpace(r[i]):
+			i++
+		case r[i] == '(' || r[i] == ')':
+			tokens = append(tokens, searchToken{kind: string(r[i])})
+			i++
+		case r[i] == '-' && i+1 < len(r) && !unicode.IsSpace(r[i+1]) && (i == 0 || unicode.IsSpace(r[i-1]) || r[i-1] == '('):
+			tokens = append(tokens, searchToken{kind: "not"})
+			i++
+		default:
+			start := i
+			field := ""
+			for i < len(r) && !unicode.IsSpace(r[i]) && r[i] != '(' && r[i] != ')' && r[i] != '"' {
+				if r[i] == ':' && field == "" {
+					if _, ok := searchFields[strings.ToLower(string(r[start:i]))]; ok {
+						field = strings.ToLower(string(r[start:i]))
+						start = i + 1
+					}
+				}
+				i++
+			}
+			if i < len(r) && r[i] == '"' && start == i {
+				end := strings.IndexRune(string(r[i+1:]), '"')
+				if end < 0 {
+					return nil, errors.New("unterminated phrase")
+				}
+				text := string(r[i+1:])[:end]
+				i += 1 + len([]rune(text)) + 1
+				tokens = append(tokens, searchToken{kind: "phrase", field: field, text: text})
+				continue
+			}
+			word := string(r[start:i])
+			if field == "" {
+				switch word {
+				case "AND", "OR", "NOT":
+					tokens = append(tokens, searchToken{kind: strings.ToLower(word)})
+					continue
+				}
+			}
+			if word == "" {
+				if field != "" {
+					return nil, fmt.Errorf("%s: needs a term", field)
+				}
+				// A quote inside a word, take it literally
+				word = string(r[i])
+				i++
+			}
+			tokens = append(tokens, searchToken{kind: "word", field: field, text: word})
+		}
+	}
+	return tokens, nil
+}
+
+type searchParser struct {
+	tokens []searchToken
+	pos    int
+}
+
+// parseSearch turns a query into a tree. Terms next to each other must all match, OR
+// and NOT work as usual and parentheses group.
+func parseSearch(q string) (*searchNode, error) {
+	tokens, err := lexSearch(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("query is empty")
+	}
+	p := &searchParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].kind)
+	}
+	if err := checkSearchNegation(node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (p *searchParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].kind
+	}
+	return ""
+}
+
+func (p *searchParser) parseOr() (*searchNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	node := &searchNode{op: "or", children: []*searchNode{left}}
+	for p.peek() == "or" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, right)
+	}
+	if len(node.children) == 1 {
+		return left, nil
+	}
+	return node, nil
+}
+
+func (p *searchParser) parseAnd() (*searchNode, error) {
+	node := &searchNode{op: "and"}
+	for {
+		switch p.peek() {
+		case "", ")", "or":
+			if len(node.children) == 0 {
+				return nil, errors.New("missing term")
+			}
+			if len(node.children) == 1 {
+				return node.children[0], nil
+			}
+			return node, nil
+		case "and":
+			p.pos++
+			continue
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+	}
+}
+
+func (p *searchParser) parseUnary() (*searchNode, error) {
+	if p.peek() == "not" {
+		p.pos++
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if child.op == "not" {
+			return child.children[0], nil
+		}
+		return &searchNode{op: "not", children: []*searchNode{child}}, nil
+	}
+	if p.peek() == "(" {
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return node, nil
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("missing term")
+	}
+
+	tok := p.tokens[p.pos]
+	if tok.kind != "word" && tok.kind != "phrase" {
+		return nil, fmt.Errorf("unexpected %q", tok.kind)
+	}
+	p.pos++
+	node := &searchNode{op: "term", columns: searchFields[tok.field], text: tok.text, phrase: tok.kind == "phrase"}
+	if !node.phrase && strings.HasSuffix(node.text, "*") {
+		node.prefix = true
+		node.text = strings.TrimRight(node.text, "*")
+	}
+	if len(searchWords(node.text)) == 0 {
+		return nil, fmt.Errorf("%q has no searchable characters", tok.text)
+	}
+	return node, nil
+}
+
+// checkSearchNegation makes sure every NOT has something to be subtracted from, full-text
+// indexes can only exclude matches from other matches
+func checkSearchNegation(node *searchNode) error {
+	switch node.op {
+	case "not":
+		return errors.New("NOT needs a term to exclude from, e.g. curl NOT wget")
+	case "or":
+		for _, child := range node.children {
+			if child.op == "not" {
+				return errors.New("NOT can not be an alternative of OR, group it: (a NOT b) OR c")
+			}
+			if err := checkSearchNegation(child); err != nil {
+				return err
+			}
+		}
+	case "and":
+		positive := 0
+		for _, child := range node.children {
+			target := child
+			if child.op == "not" {
+				target = child.children[0]
+			} else {
+				positive++
+			}
+			if err := checkSearchNegation(target); err != nil {
+				return err
+			}
+		}
+		if positive == 0 {
+			return errors.New("NOT needs a term to exclude from, e.g. curl NOT wget")
+		}
+	}
+	return nil
+}
+
+// searchWords splits text the way the index tokenizer does, on anything that is not a
+// letter or digit
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchTerms lists the positive terms of a query, used to highlight without an index
+func searchTerms(node *searchNode) []*searchNode {
+	switch node.op {
+	case "term":
+		return []*searchNode{node}
+	case "not":
+		return nil
+	}
+	var terms []*searchNode
+	for _, child := range node.children {
+		terms = append(terms, searchTerms(child)...)
+	}
+	return terms
+}
+
+// matchExpr renders the tree as an FTS MATCH expression. Terms are re-tokenized and
+// quoted so nothing a user types is read as FTS syntax.
+func (n *searchNode) matchExpr(engine string) string {
+	switch n.op {
+	case "term":
+		return n.termExpr(engine)
+	case "or":
+		parts := make([]string, len(n.children))
+		for i, child := range n.children {
+			parts[i] = child.matchExpr(engine)
+		}
+		return "(" + strings.Join(parts, " OR ") + ")"
+	}
+
+	var positive, negative []string
+	for _, child := range n.children {
+		if child.op == "not" {
+			negative = append(negative, child.children[0].matchExpr(engine))
+		} else {
+			positive = append(positive, child.matchExpr(engine))
+		}
+	}
+	expr := "(" + strings.Join(positive, " AND ") + ")"
+	for _, neg := range negative {
+		expr = "(" + expr + " NOT " + neg + ")"
+	}
+	return expr
+}
+
+func (n *searchNode) termExpr(engine string) string {
+	words := searchWords(n.text)
+	phrase := `"` + strings.Join(words, " ")
+	if n.prefix {
+		if engine == "fts4" {
+			phrase += `*"`
+		} else {
+			phrase += `" *`
+		}
+	} else {
+		phrase += `"`
+	}
+	if len(n.columns) == 0 {
+		return phrase
+	}
+
+	if engine == "fts5" {
+		return "{" + strings.Join(n.columns, " ") + "} : " + phrase
+	}
+	// FTS4 filters single words by column but not phrases, so a scoped phrase has to
+	// match as a phrase and have all its words in one of the columns
+	var alternatives []string
+	for _, col := range n.columns {
+		scoped := make([]string, len(words))
+		for i, w := range words {
+			scoped[i] = col + ":" + w
+			if n.prefix && i == len(words)-1 {
+				scoped[i] += "*"
+			}
+		}
+		alternatives = append(alternatives, "("+strings.Join(scoped, " AND ")+")")
+	}
+	scoped := "(" + strings.Join(alternatives, " OR ") + ")"
+	if len(words) == 1 {
+		return scoped
+	}
+	return "(" + phrase + " AND " + scoped + ")"
+}
+
+// likeExpr renders the tree as SQL over the document table for databases without a
+// full-text index. Words are matched as substrings, so it finds more than the index does.
+func (n *searchNode) likeExpr() (string, []interface{}) {
+	switch n.op {
+	case "term":
+		columns := n.columns
+		if len(columns) == 0 {
+			columns = searchColumns
+		}
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(n.text) + "%"
+		conds := make([]string, len(columns))
+		args := make([]interface{}, len(columns))
+		for i, col := range columns {
+			conds[i] = col + ` LIKE ? ESCAPE '\'`
+			args[i] = pattern
+		}
+		return "(" + strings.Join(conds, " OR ") + ")", args
+	case "not":
+		expr, args := n.children[0].likeExpr()
+		return "NOT " + expr, args
+	}
+	joiner := " AND "
+	if n.op == "or" {
+		joiner = " OR "
+	}
+	var parts []string
+	var args []interface{}
+	for _, child := range n.children {
+		expr, childArgs := child.likeExpr()
+		parts = append(parts, expr)
+		args = append(args, childArgs...)
+	}
+	return "(" + strings.Join(parts, joiner) + ")", args
+}
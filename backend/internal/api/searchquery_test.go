@@ -0,0 +1,108 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSearchMatchExpr(t *testing.T) {
+	for _, tc := range []struct {
+		query, fts5, fts4 string
+	}{
+		{`curl`, `"curl"`, `"curl"`},
+		{`curl wget`, `("curl" AND "wget")`, `("curl" AND "wget")`},
+		{`curl AND wget`, `("curl" AND "wget")`, `("curl" AND "wget")`},
+		{`curl OR wget`, `("curl" OR "wget")`, `("curl" OR "wget")`},
+		{`curl -wget`, `(("curl") NOT "wget")`, `(("curl") NOT "wget")`},
+		{`curl NOT wget`, `(("curl") NOT "wget")`, `(("curl") NOT "wget")`},
+		{`NOT NOT curl`, `"curl"`, `"curl"`},
+		{`(curl OR wget) tmp`, `(("curl" OR "wget") AND "tmp")`, `(("curl" OR "wget") AND "tmp")`},
+		{`wget*`, `"wget" *`, `"wget*"`},
+		{`USER:root`, `{username} : "root"`, `((username:root))`},
+		{`cmd:"rm -rf /"`, `{command} : "rm rf"`, `("rm rf" AND ((command:rm AND command:rf)))`},
+		{`payload:bin*`, `{payload command body} : "bin" *`, `((payload:bin*) OR (command:bin*) OR (body:bin*))`},
+		// Not a field, the colon is just a separator inside the word
+		{`host:8080`, `"host 8080"`, `"host 8080"`},
+		// Quotes and FTS operators in a term never reach the index as syntax
+		{`a"b c"`, `("a" AND "b c")`, `("a" AND "b c")`},
+		{`x-NEAR-y`, `"x near y"`, `"x near y"`},
+	} {
+		node, err := parseSearch(tc.query)
+		if err != nil {
+			t.Errorf("parseSearch(%q): %v", tc.query, err)
+			continue
+		}
+		if got := node.matchExpr("fts5"); got != tc.fts5 {
+			t.Errorf("parseSearch(%q) fts5 = %s, want %s", tc.query, got, tc.fts5)
+		}
+		if got := node.matchExpr("fts4"); got != tc.fts4 {
+			t.Errorf("parseSearch(%q) fts4 = %s, want %s", tc.query, got, tc.fts4)
+		}
+	}
+}
+
+func TestParseSearchErrors(t *testing.T) {
+	for query, want := range map[string]string{
+		``:                 "query is empty",
+		`   `:              "query is empty",
+		`-curl`:            "NOT needs a term",
+		`NOT curl`:         "NOT needs a term",
+		`curl OR -wget`:    "NOT can not be an alternative",
+		`(-curl) OR wget`:  "NOT can not be an alternative",
+		`"unterminated`:    "unterminated phrase",
+		`(curl`:            "missing )",
+		`curl)`:            `unexpected ")"`,
+		`OR curl`:          "missing term",
+		`curl OR`:          "missing term",
+		`()`:               "missing term",
+		`user:`:            "user: needs a term",
+		`***`:              "no searchable characters",
+		`cmd:"-- ; --"`:    "no searchable characters",
+		`curl (wget OR )`:  "missing term",
+		`curl -(wget -sh)`: "",
+	} {
+		_, err := parseSearch(query)
+		switch {
+		case want == "" && err != nil:
+			t.Errorf("parseSearch(%q): %v", query, err)
+		case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+			t.Errorf("parseSearch(%q) = %v, want an error with %q", query, err, want)
+		}
+	}
+}
+
+func TestSearchLikeExpr(t *testing.T) {
+	node, err := parseSearch(`cmd:50%_ -pass:x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, args := node.likeExpr()
+	want := `((command LIKE ? ESCAPE '\') AND NOT (password LIKE ? ESCAPE '\'))`
+	if expr != want {
+		t.Fatalf("likeExpr = %s, want %s", expr, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{`%50\%\_%`, `%x%`}) {
+		t.Fatalf("likeExpr args = %q", args)
+	}
+
+	// Terms without a field look through every column
+	node, _ = parseSearch(`curl`)
+	if _, args := node.likeExpr(); len(args) != len(searchColumns) {
+		t.Fatalf("unscoped term has %d args, want %d", len(args), len(searchColumns))
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	node, err := parseSearch(`curl OR (wget -busybox) user:"root admin"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, term := range searchTerms(node) {
+		got = append(got, term.text)
+	}
+	if want := []string{"curl", "wget", "root admin"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("searchTerms = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,595 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/metrics"
+	"backend/internal/model"
+	"backend/internal/spool"
+
+	"github.com/gin-gonic/gin"
+)
+
+// siemSpoolDir holds one spool per destination, named by its ID
+const siemSpoolDir = "spool/siem"
+
+const (
+	siemBatchSize    = 100
+	siemDialTimeout  = 5 * time.Second
+	siemWriteTimeout = 10 * time.Second
+	siemMaxBackoff   = time.Minute
+	// siemMaxDatagram keeps UDP messages inside one datagram, longer ones are cut
+	siemMaxDatagram = 65000
+)
+
+// siemOutput forwards the spool of one destination to its collector
+type siemOutput struct {
+	dest  model.SyslogDestination
+	spool *spool.Spool
+	wake  chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	conn  net.Conn // Only touched by the run loop
+
+	queued, sent, failures, spoolErrors atomic.Uint64
+
+	mu          sync.Mutex
+	connected   bool
+	lastError   string
+	lastErrorAt *time.Time
+	lastSentAt  *time.Time
+}
+
+// siemOutputs are the running outputs by destination ID, disabled destinations have none
+var siemOutputs = struct {
+	sync.RWMutex
+	m map[string]*siemOutput
+}{m: map[string]*siemOutput{}}
+
+// StartSiemForwarding opens the spool of every enabled destination and starts sending,
+// events queued before a restart go out first
+func (h *Handler) StartSiemForwarding() {
+	var dests []model.SyslogDestination
+	h.DB.Find(&dests)
+	for _, dest := range dests {
+		if dest.Enabled {
+			if err := h.startSiemOutput(dest); err != nil {
+				log.Printf("SIEM: failed to start %s: %v", dest.Name, err)
+			}
+		}
+	}
+}
+
+func (h *Handler) startSiemOutput(dest model.SyslogDestination) error {
+	sp, err := spool.Open(filepath.Join(siemSpoolDir, dest.ID), int64(dest.MaxQueueMB)<<20)
+	if err != nil {
+		return err
+	}
+	o := &siemOutput{
+		dest: dest, spool: sp,
+		wake: make(chan struct{}, 1), stop: make(chan struct{}), done: make(chan struct{}),
+	}
+	siemOutputs.Lock()
+	siemOutputs.m[dest.ID] = o
+	siemOutputs.Unlock()
+	go o.run()
+	return nil
+}
+
+// stopSiemOutput ends the output of a destination, the spool is kept unless it is removed
+func stopSiemOutput(id string, remove bool) {
+	siemOutputs.Lock()
+	o := siemOutputs.m[id]
+	delete(siemOutputs.m, id)
+	siemOutputs.Unlock()
+	if o == nil {
+		if remove {
+			os.RemoveAll(filepath.Join(siemSpoolDir, id))
+		}
+		return
+	}
+	close(o.stop)
+	<-o.done
+	if remove {
+		o.spool.Remove()
+	} else {
+		o.spool.Close()
+	}
+}
+
+// exportEvent hands a new record to the syslog destinations, the OpenSearch outputs and
+// the event bus
+func (h *Handler) exportEvent(ev siemEvent) {
+	forwardSiem(ev)
+	forwardOpenSearch(ev)
+	forwardEventBus(ev)
+}
+
+// forwardSiem queues an event for every destination whose filter takes it
+func forwardSiem(ev siemEvent) {
+	siemOutputs.RLock()
+	defer siemOutputs.RUnlock()
+	for _, o := range siemOutputs.m {
+		if !ev.matches(o.dest.Kinds, o.dest.MinSeverity) {
+			continue
+		}
+		if err := o.spool.Append(formatSyslog(o.dest, ev)); err != nil {
+			o.spoolErrors.Add(1)
+			log.Printf("SIEM: failed to queue %s %s for %s: %v", ev.Kind, ev.ID, o.dest.Name, err)
+			continue
+		}
+		o.queued.Add(1)
+		select {
+		case o.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run sends what is queued and waits for more, a failed send is retried from the same
+// record with a growing pause
+func (o *siemOutput) run() {
+	defer close(o.done)
+	defer o.disconnect()
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	backoff := time.Second
+	for {
+		recs, pos, err := o.spool.Read(siemBatchSize)
+		if err == nil && len(recs) > 0 {
+			err = o.send(recs)
+			if err == nil {
+				o.spool.Commit(pos, len(recs))
+				o.sent.Add(uint64(len(recs)))
+				now := time.Now().UTC()
+				o.mu.Lock()
+				o.lastSentAt = &now
+				o.mu.Unlock()
+				backoff = time.Second
+				continue
+			}
+		}
+		wait := ticker.C
+		if err != nil {
+			o.failures.Add(1)
+			o.disconnect()
+			now := time.Now().UTC()
+			o.mu.Lock()
+			o.lastError, o.lastErrorAt = err.Error(), &now
+			o.mu.Unlock()
+			log.Printf("SIEM: sending to %s failed, retrying in %s: %v", o.dest.Name, backoff, err)
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-o.stop:
+				timer.Stop()
+				return
+			}
+			backoff = min(backoff*2, siemMaxBackoff)
+			continue
+		}
+		select {
+		case <-wait:
+		case <-o.wake:
+		case <-o.stop:
+			return
+		}
+	}
+}
+
+func (o *siemOutput) send(recs [][]byte) error {
+	if o.conn != nil && !connAlive(o.conn) {
+		o.disconnect()
+	}
+	if o.conn == nil {
+		conn, err := dialSyslog(o.dest)
+		if err != nil {
+			return err
+		}
+		o.conn = conn
+		o.mu.Lock()
+		o.connected = true
+		o.mu.Unlock()
+	}
+	return writeSyslog(o.conn, o.dest, recs)
+}
+
+func (o *siemOutput) disconnect() {
+	if o.conn != nil {
+		o.conn.Close()
+		o.conn = nil
+	}
+	o.mu.Lock()
+	o.connected = false
+	o.mu.Unlock()
+}
+
+func dialSyslog(dest model.SyslogDestination) (net.Conn, error) {
+	addr := net.JoinHostPort(dest.Host, strconv.Itoa(dest.Port))
+	dialer := &net.Dialer{Timeout: siemDialTimeout}
+	switch dest.Protocol {
+	case "udp":
+		return dialer.Dial("udp", addr)
+	case "tcp":
+		return dialer.Dial("tcp", addr)
+	}
+	cfg, err := syslogTLSConfig(dest)
+	if err != nil {
+		return nil, err
+	}
+	return tls.DialWithDialer(dialer, "tcp", addr, cfg)
+}
+
+func syslogTLSConfig(dest model.SyslogDestination) (*tls.Config, error) {
+	cfg, err := outputTLSConfig(dest.CACert, dest.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ServerName = dest.Host
+	return cfg, nil
+}
+
+// outputTLSConfig trusts caCert on top of the system roots, for collectors with a private CA
+func outputTLSConfig(caCert string, insecure bool) (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: insecure, MinVersion: tls.VersionTLS12}
+	if strings.TrimSpace(caCert) != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(caCert)) {
+			return nil, errors.New("caCert holds no PEM certificate")
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// connAlive notices a collector that closed the connection, the next write would
+// otherwise vanish into the dead socket. Collectors never send, a read only times out.
+func connAlive(conn net.Conn) bool {
+	if _, ok := conn.(*net.UDPConn); ok {
+		return true
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	defer conn.SetReadDeadline(time.Time{})
+	var buf [1]byte
+	_, err := conn.Read(buf[:])
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// writeSyslog sends records, one datagram each over UDP and framed over a stream
+func writeSyslog(conn net.Conn, dest model.SyslogDestination, recs [][]byte) error {
+	conn.SetWriteDeadline(time.Now().Add(siemWriteTimeout))
+	if dest.Protocol == "udp" {
+		for _, rec := range recs {
+			if len(rec) > siemMaxDatagram {
+				rec = rec[:siemMaxDatagram]
+			}
+			if _, err := conn.Write(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var buf []byte
+	for _, rec := range recs {
+		if dest.Framing == "newline" {
+			buf = append(append(buf, rec...), '\n')
+		} else {
+			buf = append(append(strconv.AppendInt(buf, int64(len(rec)), 10), ' '), rec...)
+		}
+	}
+	_, err := conn.Write(buf)
+	return err
+}
+
+// normalizeSyslogDestination validates a destination and fills in the defaults
+func normalizeSyslogDestination(dest *model.SyslogDestination) error {
+	dest.Name = strings.TrimSpace(dest.Name)
+	dest.Host = strings.TrimSpace(dest.Host)
+	if dest.Name == "" {
+		return errors.New("name is required")
+	}
+	if dest.Host == "" {
+		return errors.New("host is required")
+	}
+	if dest.Protocol == "" {
+		dest.Protocol = "udp"
+	}
+	if dest.Protocol != "udp" && dest.Protocol != "tcp" && dest.Protocol != "tls" {
+		return errors.New("protocol must be udp, tcp or tls")
+	}
+	if dest.Port == 0 {
+		dest.Port = map[string]int{"udp": 514, "tcp": 514, "tls": 6514}[dest.Protocol]
+	}
+	if dest.Port < 1 || dest.Port > 65535 {
+		return errors.New("port must be between 1 and 65535")
+	}
+	if dest.Format == "" {
+		dest.Format = "cef"
+	}
+	if dest.Format != "cef" && dest.Format != "leef" && dest.Format != "json" {
+		return errors.New("format must be cef, leef or json")
+	}
+	// RFC 5425 frames syslog over TLS with octet counting, plain TCP collectors mostly split on newlines
+	if dest.Framing == "" {
+		dest.Framing = map[string]string{"udp": "", "tcp": "newline", "tls": "octet"}[dest.Protocol]
+	}
+	if dest.Protocol != "udp" && dest.Framing != "octet" && dest.Framing != "newline" {
+		return errors.New("framing must be octet or newline")
+	}
+	if dest.Facility < 0 || dest.Facility > 23 {
+		return errors.New("facility must be between 0 and 23")
+	}
+	kinds := splitList(dest.Kinds)
+	for _, kind := range kinds {
+		if !slices.Contains(siemKinds, kind) {
+			return fmt.Errorf("unknown event kind %s", kind)
+		}
+	}
+	dest.Kinds = strings.Join(kinds, ",")
+	if dest.MinSeverity == "" {
+		dest.MinSeverity = "info"
+	}
+	if _, ok := siemSeverities[dest.MinSeverity]; !ok {
+		return errors.New("minSeverity must be info, low, medium, high or critical")
+	}
+	if dest.Protocol == "tls" {
+		if _, err := syslogTLSConfig(*dest); err != nil {
+			return err
+		}
+	}
+	if dest.MaxQueueMB <= 0 {
+		dest.MaxQueueMB = 64
+	}
+	if dest.MaxQueueMB > 4096 {
+		return errors.New("maxQueueMb can be at most 4096")
+	}
+	return nil
+}
+
+// syslogDestinationView adds the delivery state of the running output
+type syslogDestinationView struct {
+	model.SyslogDestination
+	Running     bool       `json:"running"`
+	Connected   bool       `json:"connected"`
+	QueueLength int        `json:"queueLength"`
+	QueueBytes  int64      `json:"queueBytes"`
+	Queued      uint64     `json:"queued"` // Counters since the server started
+	Sent        uint64     `json:"sent"`
+	Failures    uint64     `json:"failures"`
+	Dropped     uint64     `json:"dropped"`
+	LastError   string     `json:"lastError"`
+	LastErrorAt *time.Time `json:"lastErrorAt"`
+	LastSentAt  *time.Time `json:"lastSentAt"`
+}
+
+func viewSyslogDestination(dest model.SyslogDestination) syslogDestinationView {
+	v := syslogDestinationView{SyslogDestination: dest}
+	siemOutputs.RLock()
+	o := siemOutputs.m[dest.ID]
+	siemOutputs.RUnlock()
+	if o == nil {
+		return v
+	}
+	var dropped uint64
+	v.Running = true
+	v.QueueLength, v.QueueBytes, dropped = o.spool.Stats()
+	v.Queued, v.Sent, v.Failures = o.queued.Load(), o.sent.Load(), o.failures.Load()
+	v.Dropped = dropped + o.spoolErrors.Load()
+	o.mu.Lock()
+	v.Connected, v.LastError, v.LastErrorAt, v.LastSentAt = o.connected, o.lastError, o.lastErrorAt, o.lastSentAt
+	o.mu.Unlock()
+	return v
+}
+
+func (h *Handler) GetSyslogDestinations(c *gin.Context) {
+	var dests []model.SyslogDestination
+	h.DB.Order("created_at").Find(&dests)
+	views := make([]syslogDestinationView, len(dests))
+	for i, dest := range dests {
+		views[i] = viewSyslogDestination(dest)
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func (h *Handler) CreateSyslogDestination(c *gin.Context) {
+	var dest model.SyslogDestination
+	if err := c.ShouldBindJSON(&dest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeSyslogDestination(&dest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dest.ID = fmt.Sprintf("SD-%d", time.Now().UnixNano())
+	dest.CreatedAt, dest.UpdatedAt = h.Now(), h.Now()
+	if err := h.DB.Create(&dest).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name is already used by another destination"})
+		return
+	}
+	if dest.Enabled {
+		if err := h.startSiemOutput(dest); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open the queue: " + err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, viewSyslogDestination(dest))
+}
+
+// UpdateSyslogDestination replaces a destination and restarts its output. Queued events
+// keep the format they were rendered in.
+func (h *Handler) UpdateSyslogDestination(c *gin.Context) {
+	var old model.SyslogDestination
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&old).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Destination not found"})
+		return
+	}
+	var dest model.SyslogDestination
+	if err := c.ShouldBindJSON(&dest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeSyslogDestination(&dest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dest.ID, dest.CreatedAt, dest.UpdatedAt = old.ID, old.CreatedAt, h.Now()
+	if err := h.DB.Save(&dest).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name is already used by another destination"})
+		return
+	}
+	stopSiemOutput(dest.ID, false)
+	if dest.Enabled {
+		if err := h.startSiemOutput(dest); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open the queue: " + err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, viewSyslogDestination(dest))
+}
+
+// DeleteSyslogDestination removes a destination and discards its queue
+func (h *Handler) DeleteSyslogDestination(c *gin.Context) {
+	id := c.Param("id")
+	res := h.DB.Delete(&model.SyslogDestination{}, "id = ?", id)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete destination"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Destination not found"})
+		return
+	}
+	stopSiemOutput(id, true)
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// TestSyslogDestination sends the latest record of every kind the destination takes
+// straight to the collector, past the queue, so the SIEM side can check its parsing
+func (h *Handler) TestSyslogDestination(c *gin.Context) {
+	var dest model.SyslogDestination
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&dest).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Destination not found"})
+		return
+	}
+
+	var recs [][]byte
+	var sent []string
+	for _, ev := range h.latestEvents() {
+		if kinds := splitList(dest.Kinds); len(kinds) > 0 && !slices.Contains(kinds, ev.Kind) {
+			continue
+		}
+		recs = append(recs, formatSyslog(dest, ev))
+		sent = append(sent, ev.Kind)
+	}
+	if len(recs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No records of the kinds this destination takes"})
+		return
+	}
+	conn, err := dialSyslog(dest)
+	if err == nil {
+		err = writeSyslog(conn, dest, recs)
+		conn.Close()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "sent": sent})
+}
+
+// latestEvents are the newest record of every kind, what the output tests send
+func (h *Handler) latestEvents() []siemEvent {
+	var events []siemEvent
+	var attack model.AttackLog
+	if h.DB.Order("timestamp desc").Limit(1).Find(&attack).RowsAffected > 0 {
+		events = append(events, attackSiemEvent(attack))
+	}
+	var scan model.ScanLog
+	if h.DB.Order("start desc").Limit(1).Find(&scan).RowsAffected > 0 {
+		events = append(events, scanSiemEvent(scan))
+	}
+	var cred model.AccountCredential
+	if h.DB.Order("time desc").Limit(1).Find(&cred).RowsAffected > 0 {
+		events = append(events, credentialSiemEvent(cred))
+	}
+	var decoy model.DecoyLog
+	if h.DB.Order("time desc").Limit(1).Find(&decoy).RowsAffected > 0 {
+		events = append(events, decoySiemEvent(decoy))
+	}
+	var sample model.SampleLog
+	if h.DB.Order("last_time desc").Limit(1).Find(&sample).RowsAffected > 0 {
+		events = append(events, sampleSiemEvent(sample))
+	}
+	var login model.LoginLog
+	if h.DB.Order("time desc").Limit(1).Find(&login).RowsAffected > 0 {
+		events = append(events, loginSiemEvent(login))
+	}
+	return events
+}
+
+// writeSiemMetrics exports the delivery counters and queue depth of every running output
+func writeSiemMetrics(w *metrics.Writer) {
+	siemOutputs.RLock()
+	outputs := make([]*siemOutput, 0, len(siemOutputs.m))
+	for _, o := range siemOutputs.m {
+		outputs = append(outputs, o)
+	}
+	siemOutputs.RUnlock()
+	if len(outputs) == 0 {
+		return
+	}
+	slices.SortFunc(outputs, func(a, b *siemOutput) int { return strings.Compare(a.dest.Name, b.dest.Name) })
+
+	family := func(name, kind, help string, value func(*siemOutput) float64) {
+		w.Header(name, kind, help)
+		for _, o := range outputs {
+			w.Sample(name, value(o), "destination", o.dest.Name)
+		}
+	}
+	family("prts_siem_events_queued_total", "counter", "Events queued for a syslog destination.",
+		func(o *siemOutput) float64 { return float64(o.queued.Load()) })
+	family("prts_siem_events_sent_total", "counter", "Events written to a syslog destination.",
+		func(o *siemOutput) float64 { return float64(o.sent.Load()) })
+	family("prts_siem_send_failures_total", "counter", "Failed connections or writes to a syslog destination.",
+		func(o *siemOutput) float64 { return float64(o.failures.Load()) })
+	family("prts_siem_events_dropped_total", "counter", "Events lost because the queue was full or could not be written.",
+		func(o *siemOutput) float64 {
+			_, _, dropped := o.spool.Stats()
+			return float64(dropped + o.spoolErrors.Load())
+		})
+	family("prts_siem_queue_events", "gauge", "Events waiting in the on-disk queue of a syslog destination.",
+		func(o *siemOutput) float64 {
+			pending, _, _ := o.spool.Stats()
+			return float64(pending)
+		})
+	family("prts_siem_queue_bytes", "gauge", "Size of the on-disk queue of a syslog destination.",
+		func(o *siemOutput) float64 {
+			_, size, _ := o.spool.Stats()
+			return float64(size)
+		})
+	family("prts_siem_connected", "gauge", "Whether a stream to the syslog destination is open.",
+		func(o *siemOutput) float64 {
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			return metrics.Bool(o.connected)
+		})
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/model"
+)
+
+// Identity of the events in the CEF and LEEF headers
+const (
+	siemVendor  = "PRTS"
+	siemProduct = "Honeypot"
+	siemVersion = "1.0"
+	siemAppName = "prts"
+)
+
+// siemKinds are the records that are forwarded, destinations filter on them
+var siemKinds = []string{"attack", "scan", "credential", "decoy", "sample", "login"}
+
+// siemSeverities map onto CEF's 0-10 scale and the syslog severity of the header
+var siemSeverities = map[string]struct{ cef, syslog int }{
+	"info":     {1, 6}, // informational
+	"low":      {3, 5}, // notice
+	"medium":   {5, 4}, // warning
+	"high":     {8, 3}, // error
+	"critical": {10, 2},
+}
+
+var siemHostname = func() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "-"
+	}
+	return host
+}()
+
+// siemEvent is a record on its way to a SIEM. Fields use CEF extension keys, custom ones
+// (cs1 to cs6) carry a label which LEEF uses as the key.
+type siemEvent struct {
+	Kind     string
+	ID       string
+	Class    string // CEF Device Event Class ID and LEEF event ID, e.g. attack:SSH
+	Name     string
+	Severity string
+	Time     time.Time
+	SourceIP string
+	Node     string
+	Fields   []siemField
+	Record   interface{} // The stored row, the JSON format carries it as is
+}
+
+type siemField struct {
+	key, label, value string
+}
+
+// matches applies the filter of an output, comma-separated kinds with empty for all
+func (ev siemEvent) matches(kinds, minSeverity string) bool {
+	if kinds := splitList(kinds); len(kinds) > 0 && !slices.Contains(kinds, ev.Kind) {
+		return false
+	}
+	return severityRanks[ev.Severity] >= severityRanks[minSeverity]
+}
+
+// custom fills the custom string slots in order
+func custom(fields []siemField, pairs ...string) []siemField {
+	for i := 0; i+1 < len(pairs) && i/2 < 6; i += 2 {
+		if pairs[i+1] != "" {
+			fields = append(fields, siemField{fmt.Sprintf("cs%d", i/2+1), pairs[i], pairs[i+1]})
+		}
+	}
+	return fields
+}
+
+func field(key, value string) siemField {
+	return siemField{key: key, value: value}
+}
+
+// observer names what saw an event: the probe, or the other sensor whose log an adapter read
+func observer(node, sensor string) string {
+	if node != "" {
+		return node
+	}
+	return sensor
+}
+
+func attackSiemEvent(a model.AttackLog) siemEvent {
+	severity := strings.ToLower(a.Severity)
+	if _, ok := siemSeverities[severity]; !ok {
+		severity = "low"
+	}
+	method := strings.ToUpper(a.Method)
+	return siemEvent{
+		Kind: "attack", ID: a.ID, Class: "attack:" + method, Name: "Honeypot attack over " + method,
+		Severity: severity, Time: a.Timestamp, SourceIP: a.SourceIP, Node: observer(a.Node, a.Sensor),
+		Fields: []siemField{field("app", method), field("act", a.Status), field("msg", truncateRunes(a.Payload, 1023))},
+		Record: a,
+	}
+}
+
+var scanSeverities = map[string]string{"malicious": "high", "high risk": "high", "suspicious": "medium", "low": "low"}
+
+func scanSiemEvent(s model.ScanLog) siemEvent {
+	severity := scanSeverities[strings.ToLower(s.Threat)]
+	if severity == "" {
+		severity = "low"
+	}
+	fields := []siemField{field("proto", s.Type), field("cnt", strconv.Itoa(s.Count))}
+	return siemEvent{
+		Kind: "scan", ID: s.ID, Class: "scan:" + strings.ToUpper(s.Type), Name: "Port scan",
+		Severity: severity, Time: s.Start, SourceIP: s.IP, Node: observer(s.Node, s.Sensor),
+		Fields: custom(fields, "ports", s.Ports, "threat", s.Threat, "duration", s.Duration),
+		Record: s,
+	}
+}
+
+func credentialSiemEvent(cred model.AccountCredential) siemEvent {
+	fields := []siemField{field("app", cred.Service), field("suser", cred.Username), field("cnt", strconv.Itoa(cred.Count))}
+	return siemEvent{
+		Kind: "credential", ID: cred.ID, Class: "credential:" + strings.ToLower(cred.Service), Name: "Captured login attempt",
+		Severity: "medium", Time: cred.Time, SourceIP: cred.IP, Node: cred.Sensor,
+		Fields: custom(fields, "password", cred.Password),
+		Record: cred,
+	}
+}
+
+var decoySeverities = map[string]string{"Compromised": "critical", "Failed": "low"}
+
+func decoySiemEvent(d model.DecoyLog) siemEvent {
+	severity := decoySeverities[d.Status]
+	if severity == "" {
+		severity = "info"
+	}
+	name := "Decoy " + strings.ToLower(d.Status)
+	if d.Status == "Compromised" {
+		name = "Decoy triggered"
+	}
+	// The bait is a planted secret, it stays out of the SIEM
+	d.BaitSecret = ""
+	fields := []siemField{
+		field("act", d.Status), field("fname", d.Path), field("sproc", d.Process),
+		field("requestClientApplication", d.UserAgent), field("msg", truncateRunes(d.Detail, 1023)),
+	}
+	return siemEvent{
+		Kind: "decoy", ID: d.ID, Class: "decoy:" + strings.ToLower(d.Type), Name: name,
+		Severity: severity, Time: d.Time, SourceIP: d.SourceIP, Node: d.Node,
+		Fields: custom(fields, "decoyName", d.DecoyName, "decoyType", d.Type, "device", d.Device),
+		Record: d,
+	}
+}
+
+var sampleSeverities = map[string]string{"malicious": "high", "suspicious": "medium", "safe": "info"}
+
+func sampleSiemEvent(s model.SampleLog) siemEvent {
+	severity := sampleSeverities[strings.ToLower(s.ThreatLevel)]
+	if severity == "" {
+		severity = "low"
+	}
+	fields := []siemField{
+		field("fname", s.FileName), field("fileType", s.FileType), field("fileHash", s.SHA256),
+		field("cnt", strconv.Itoa(s.CaptureCount)),
+	}
+	return siemEvent{
+		Kind: "sample", ID: s.ID, Class: "sample:" + strings.ToLower(s.ThreatLevel), Name: "Malware sample captured",
+		Severity: severity, Time: s.LastTime, SourceIP: s.AttackerIP, Node: observer(s.SourceNode, s.Sensor),
+		Fields: custom(fields, "fileSize", s.FileSize, "threatLevel", s.ThreatLevel),
+		Record: s,
+	}
+}
+
+func loginSiemEvent(l model.LoginLog) siemEvent {
+	severity, name := "info", "Console login"
+	if l.Status != "success" {
+		severity, name = "low", "Console login failed"
+	}
+	return siemEvent{
+		Kind: "login", ID: strconv.FormatUint(uint64(l.ID), 10), Class: "login:" + l.Status, Name: name,
+		Severity: severity, Time: l.Time, SourceIP: l.IP,
+		Fields: []siemField{field("suser", l.Username), field("outcome", l.Status), field("requestClientApplication", l.Device)},
+		Record: l,
+	}
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// formatSyslog renders an event as one RFC 5424 message, the body in the destination's format
+func formatSyslog(dest model.SyslogDestination, ev siemEvent) []byte {
+	sev := siemSeverities[ev.Severity]
+	var body string
+	switch dest.Format {
+	case "cef":
+		body = formatCEF(ev)
+	case "leef":
+		body = formatLEEF(ev)
+	default:
+		body = formatSiemJSON(ev)
+	}
+	return []byte(fmt.Sprintf("<%d>1 %s %s %s - %s - %s",
+		dest.Facility*8+sev.syslog, ev.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		siemHostname, siemAppName, ev.Kind, body))
+}
+
+// common are the fields every event carries ahead of its own
+func (ev siemEvent) common() []siemField {
+	fields := []siemField{
+		field("rt", strconv.FormatInt(ev.Time.UnixMilli(), 10)),
+		field("cat", ev.Kind),
+		field("externalId", ev.ID),
+	}
+	if _, err := netip.ParseAddr(ev.SourceIP); err == nil {
+		fields = append(fields, field("src", ev.SourceIP))
+	}
+	if ev.Node != "" {
+		fields = append(fields, field("dvchost", ev.Node))
+	}
+	return fields
+}
+
+var (
+	cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefValueEscaper  = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+)
+
+func formatCEF(ev siemEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%s|%s|%d|", siemVendor, siemProduct, siemVersion,
+		cefHeaderEscaper.Replace(ev.Class), cefHeaderEscaper.Replace(ev.Name), siemSeverities[ev.Severity].cef)
+	first := true
+	write := func(key, value string) {
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(key + "=" + cefValueEscaper.Replace(value))
+	}
+	for _, f := range append(ev.common(), ev.Fields...) {
+		if f.value == "" {
+			continue
+		}
+		if f.label != "" {
+			write(f.key+"Label", f.label)
+		}
+		write(f.key, f.value)
+	}
+	return b.String()
+}
+
+// leefKeys rename CEF keys to the predefined LEEF attributes, the rest keep their name
+var leefKeys = map[string]string{"suser": "usrName", "dvchost": "node", "rt": "", "app": "service"}
+
+var (
+	leefHeaderEscaper = strings.NewReplacer("|", "_", "\t", " ", "\r", " ", "\n", " ")
+	leefValueEscaper  = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+)
+
+// formatLEEF writes LEEF 1.0, attributes are separated by tabs
+func formatLEEF(ev siemEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "LEEF:1.0|%s|%s|%s|%s|", siemVendor, siemProduct, siemVersion, leefHeaderEscaper.Replace(ev.Class))
+	fmt.Fprintf(&b, "devTime=%s\tdevTimeFormat=MMM dd yyyy HH:mm:ss.SSS z\tsev=%d",
+		ev.Time.UTC().Format("Jan 02 2006 15:04:05.000 MST"), siemSeverities[ev.Severity].cef)
+	for _, f := range append(ev.common(), ev.Fields...) {
+		key := f.key
+		if f.label != "" {
+			key = f.label
+		} else if k, ok := leefKeys[key]; ok {
+			key = k
+		}
+		if key == "" || f.value == "" {
+			continue
+		}
+		b.WriteString("\t" + key + "=" + leefValueEscaper.Replace(f.value))
+	}
+	return b.String()
+}
+
+func formatSiemJSON(ev siemEvent) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"@timestamp": ev.Time.UTC().Format(time.RFC3339Nano),
+		"vendor":     siemVendor,
+		"product":    siemProduct,
+		"kind":       ev.Kind,
+		"id":         ev.ID,
+		"class":      ev.Class,
+		"name":       ev.Name,
+		"severity":   ev.Severity,
+		"sourceIp":   ev.SourceIP,
+		"node":       ev.Node,
+		"record":     ev.Record,
+	})
+	return string(data)
+}
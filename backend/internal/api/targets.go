@@ -0,0 +1,256 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// splitList turns a comma-separated column into trimmed, de-duplicated entries
+func splitList(s string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		out = append(out, item)
+	}
+	return out
+}
+
+// normalizeGroups lowercases and sorts group names so matching is case-insensitive
+func normalizeGroups(groups []string) string {
+	list := splitList(strings.ToLower(strings.Join(groups, ",")))
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+// normalizeRuleTargets checks the scope of a rule, an empty scope targets every node
+func normalizeRuleTargets(rule *model.AccessControlRule) error {
+	rule.Scope = strings.ToLower(strings.TrimSpace(rule.Scope))
+	switch rule.Scope {
+	case "", "all":
+		rule.Scope = "all"
+		rule.Targets = ""
+		return nil
+	case "nodes":
+		list := splitList(rule.Targets)
+		sort.Strings(list)
+		rule.Targets = strings.Join(list, ",")
+	case "groups":
+		rule.Targets = normalizeGroups([]string{rule.Targets})
+	default:
+		return fmt.Errorf("scope must be all, nodes or groups")
+	}
+	if rule.Targets == "" {
+		return fmt.Errorf("scope %s needs at least one target", rule.Scope)
+	}
+	return nil
+}
+
+// ruleAppliesTo reports whether a node has to enforce a rule
+func ruleAppliesTo(rule model.AccessControlRule, node model.NodeStatus) bool {
+	switch rule.Scope {
+	case "nodes":
+		for _, id := range splitList(rule.Targets) {
+			if id == node.ID {
+				return true
+			}
+		}
+		return false
+	case "groups":
+		groups := splitList(node.Groups)
+		for _, target := range splitList(rule.Targets) {
+			for _, g := range groups {
+				if g == target {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// rulesForNode narrows a rule set to the effective set of one node
+func rulesForNode(rules []model.AccessControlRule, node model.NodeStatus) []model.AccessControlRule {
+	effective := make([]model.AccessControlRule, 0, len(rules))
+	for _, rule := range rules {
+		if ruleAppliesTo(rule, node) {
+			effective = append(effective, rule)
+		}
+	}
+	return effective
+}
+
+// loadTargetNode returns the node a rule set is computed for. Nodes that are not
+// registered yet only receive the rules scoped to all nodes.
+func (h *Handler) loadTargetNode(nodeID string) model.NodeStatus {
+	node := model.NodeStatus{ID: nodeID}
+	h.DB.Select("id", "groups").Where("id = ?", nodeID).Find(&node)
+	return node
+}
+
+// ruleTargetsIntersect reports whether two rules can be enforced on the same node
+func ruleTargetsIntersect(a, b model.AccessControlRule, nodes []model.NodeStatus) bool {
+	if (a.Scope != "nodes" && a.Scope != "groups") || (b.Scope != "nodes" && b.Scope != "groups") {
+		return true
+	}
+	if a.Scope == b.Scope {
+		// Shared targets intersect even before a node joins the group
+		for _, x := range splitList(a.Targets) {
+			for _, y := range splitList(b.Targets) {
+				if x == y {
+					return true
+				}
+			}
+		}
+	}
+	for _, node := range nodes {
+		if ruleAppliesTo(a, node) && ruleAppliesTo(b, node) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleChangeVersions maps each rule to the version of its last logged change, the empty
+// ID holds the last reset, which stands in for every rule
+func (h *Handler) ruleChangeVersions() map[string]int64 {
+	var rows []struct {
+		RuleID  string
+		Version int64
+	}
+	h.DB.Model(&model.AccessRuleChange{}).Select("rule_id, MAX(version) AS version").Group("rule_id").Scan(&rows)
+	versions := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		versions[row.RuleID] = row.Version
+	}
+	return versions
+}
+
+// accessRuleView is a rule as listed by the API, with the nodes in its scope and the
+// online nodes that have applied its current state with enforcement switched on
+type accessRuleView struct {
+	model.AccessControlRule
+	TargetNodes    []string `json:"targetNodes"`
+	EnforcingNodes []string `json:"enforcingNodes"`
+}
+
+func (h *Handler) accessRuleViews(rules []model.AccessControlRule) []accessRuleView {
+	var nodes []model.NodeStatus
+	h.DB.Select("id", "status", "groups", "rule_version", "firewall_status").Find(&nodes)
+	versions := h.ruleChangeVersions()
+
+	views := make([]accessRuleView, 0, len(rules))
+	for _, rule := range rules {
+		view := accessRuleView{AccessControlRule: rule, TargetNodes: []string{}, EnforcingNodes: []string{}}
+		for _, node := range nodes {
+			if !ruleAppliesTo(rule, node) {
+				continue
+			}
+			view.TargetNodes = append(view.TargetNodes, node.ID)
+			if rule.Status == "active" && node.Status == "online" && node.FirewallStatus == "active" && node.RuleVersion >= max(versions[rule.ID], versions[""]) {
+				view.EnforcingNodes = append(view.EnforcingNodes, node.ID)
+			}
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// UpdateAccessRuleScope retargets a rule, nodes that leave the scope get it as a removal
+func (h *Handler) UpdateAccessRuleScope(c *gin.Context) {
+	var req struct {
+		Scope   string `json:"scope"`
+		Targets string `json:"targets"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var rule model.AccessControlRule
+	if err := h.DB.Where("id = ?", c.Param("id")).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+	rule.Scope, rule.Targets = req.Scope, req.Targets
+	if err := normalizeRuleTargets(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&rule).Updates(map[string]interface{}{"scope": rule.Scope, "targets": rule.Targets}).Error; err != nil {
+			return err
+		}
+		_, err := recordRuleChange(tx, "update", rule.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rule"})
+		return
+	}
+	go h.pushRuleChanges()
+	c.JSON(http.StatusOK, h.accessRuleViews([]model.AccessControlRule{rule})[0])
+}
+
+// UpdateNodeGroups replaces the groups of a node and resends its effective rule set,
+// group membership is not part of the rule change log so the node gets a snapshot
+func (h *Handler) UpdateNodeGroups(c *gin.Context) {
+	var req struct {
+		Groups []string `json:"groups"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := c.Param("id")
+	groups := normalizeGroups(req.Groups)
+	res := h.DB.Model(&model.NodeStatus{}).Where("id = ?", id).Update("groups", groups)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update node groups"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+	go h.syncRulesToNode(id)
+	c.JSON(http.StatusOK, gin.H{"status": "success", "groups": splitList(groups)})
+}
+
+// GetNodeGroups lists every group in use with its member nodes
+func (h *Handler) GetNodeGroups(c *gin.Context) {
+	var nodes []model.NodeStatus
+	h.DB.Select("id", "groups").Find(&nodes)
+
+	members := map[string][]string{}
+	for _, node := range nodes {
+		for _, g := range splitList(node.Groups) {
+			members[g] = append(members[g], node.ID)
+		}
+	}
+	names := make([]string, 0, len(members))
+	for g := range members {
+		names = append(names, g)
+	}
+	sort.Strings(names)
+
+	groups := make([]gin.H, 0, len(names))
+	for _, g := range names {
+		groups = append(groups, gin.H{"name": g, "nodes": members[g]})
+	}
+	c.JSON(http.StatusOK, groups)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// timeSchemaVersion is stored under the time_schema config key once string time columns
+// have been rewritten as UTC timestamps
+const timeSchemaVersion = "1"
+
+// legacyTimeLayouts are the spellings found in databases from before the time columns were
+// typed. Layouts without a zone were written in the server's local time.
+var legacyTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00", // Written by the SQLite driver
+	time.RFC3339Nano,
+	"2006/01/02 15:04:05",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006/01/02",
+	"2006-01-02",
+}
+
+// parseLegacyTime reads a stored time string, ok is false for empty values, the
+// permanent markers and anything unreadable
+func parseLegacyTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range legacyTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseTimeParam reads a time given in a query, RFC 3339 is expected but the local
+// spellings of the old API are still accepted
+func parseTimeParam(s string) (time.Time, bool) {
+	return parseLegacyTime(s)
+}
+
+// timeColumn is a column that used to hold formatted strings. Nullable columns get NULL
+// for values that are not a time, the others the zero time.
+type timeColumn struct {
+	table, column string
+	nullable      bool
+}
+
+var legacyTimeColumns = []timeColumn{
+	{"attack_sources", "first_time", false},
+	{"account_credentials", "time", false},
+	{"inventory_accounts", "import_time", false},
+	{"inventory_accounts", "last_hit", true},
+	{"decoy_logs", "time", false},
+	{"decoy_logs", "deploy_time", false},
+	{"canary_tokens", "create_time", false},
+	{"canary_tokens", "last_triggered", true},
+	{"scan_logs", "start", false},
+	{"sample_logs", "last_time", false},
+	{"vuln_rules", "last_hit_time", true},
+	{"vuln_rules", "update_time", false},
+	{"access_control_rules", "expire_time", true},
+	{"access_control_rules", "add_time", false},
+	{"access_rule_changes", "time", false},
+	{"blocklist_subscriptions", "last_fetch", true},
+	{"blocklist_subscriptions", "last_success", true},
+	{"blocklist_subscriptions", "previewed_at", true},
+	{"blocklist_subscriptions", "created_at", false},
+	{"login_logs", "time", false},
+	{"reports", "create_time", false},
+	// Typed before, but written with the server's zone
+	{"attack_logs", "timestamp", false},
+	{"messages", "time", false},
+	{"login_attempts", "last_time", false},
+	{"login_attempts", "locked_until", false},
+}
+
+// MigrateTimeColumns rewrites every stored time as a UTC timestamp. AutoMigrate has
+// already changed the column types, but SQLite keeps the old text in place, and text
+// in mixed formats and zones neither sorts nor compares. Runs once per database.
+func MigrateTimeColumns(db *gorm.DB) error {
+	var cfg model.SystemConfig
+	if db.Where("key = ?", "time_schema").Limit(1).Find(&cfg).RowsAffected > 0 && cfg.Value == timeSchemaVersion {
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, col := range legacyTimeColumns {
+			if !tx.Migrator().HasColumn(col.table, col.column) {
+				continue
+			}
+			var rows []struct {
+				RowID int64
+				Value *string
+			}
+			// CAST keeps the driver from converting values that already look like a time
+			if err := tx.Table(col.table).Select("rowid AS row_id, CAST(`" + col.column + "` AS TEXT) AS value").Scan(&rows).Error; err != nil {
+				return err
+			}
+
+			converted, cleared := 0, 0
+			for _, row := range rows {
+				var value interface{}
+				if row.Value != nil {
+					if t, ok := parseLegacyTime(*row.Value); ok {
+						value = t
+						converted++
+					}
+				}
+				if value == nil {
+					if row.Value == nil && col.nullable {
+						continue
+					}
+					if !col.nullable {
+						value = time.Time{}
+					}
+					cleared++
+				}
+				if err := tx.Exec("UPDATE `"+col.table+"` SET `"+col.column+"` = ? WHERE rowid = ?", value, row.RowID).Error; err != nil {
+					return err
+				}
+			}
+			if cleared > 0 {
+				log.Printf("Time migration: %s.%s had %d values without a readable time", col.table, col.column, cleared)
+			}
+			if converted > 0 {
+				log.Printf("Time migration: converted %d values in %s.%s", converted, col.table, col.column)
+			}
+		}
+
+		cfg.Key, cfg.Value = "time_schema", timeSchemaVersion
+		cfg.Description = "Version of the stored time format"
+		return tx.Save(&cfg).Error
+	})
+	return err
+}
@@ -0,0 +1,398 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rollupSchemaVersion is stored under the rollup_schema config key once the attack log
+// has been rolled up, a new version rolls it up again
+const rollupSchemaVersion = "1"
+
+// trendMaxPoints caps the buckets one trend request may return
+const trendMaxPoints = 2000
+
+type rollupResolution struct {
+	name      string
+	step      time.Duration
+	retention time.Duration // Zero keeps buckets for good
+}
+
+// rollupResolutions go from coarsest to finest, a trend reads the coarsest that fits
+var rollupResolutions = []rollupResolution{
+	{"day", 24 * time.Hour, 0},
+	{"hour", time.Hour, 90 * 24 * time.Hour},
+	{"minute", time.Minute, 48 * time.Hour},
+}
+
+// rollupDimensions are the groupings counted per bucket, total counts every attack
+var rollupDimensions = []string{"total", "service", "severity", "node", "country"}
+
+// trendIntervals are tried in order when a request leaves the interval open
+var trendIntervals = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 4 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+
+type rollupKey struct {
+	resolution string
+	bucket     time.Time
+	dimension  string
+	value      string
+}
+
+// addAttack counts an attack into every resolution it is still kept at
+func addAttack(counts map[rollupKey]int64, a model.AttackLog, country string, now time.Time) {
+	values := map[string]string{
+		"total":    "",
+		"service":  strings.ToUpper(a.Method),
+		"severity": strings.ToLower(a.Severity),
+		"node":     a.Node,
+		"country":  country,
+	}
+	at := a.Timestamp.UTC()
+	for _, res := range rollupResolutions {
+		if res.retention > 0 && at.Before(now.Add(-res.retention)) {
+			continue
+		}
+		bucket := at.Truncate(res.step)
+		for _, dim := range rollupDimensions {
+			value := values[dim]
+			if value == "" && dim != "total" {
+				value = "unknown"
+			}
+			counts[rollupKey{res.name, bucket, dim, value}]++
+		}
+	}
+}
+
+func upsertRollups(db *gorm.DB, counts map[rollupKey]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	rows := make([]model.AttackRollup, 0, len(counts))
+	for k, n := range counts {
+		rows = append(rows, model.AttackRollup{Resolution: k.resolution, Bucket: k.bucket, Dimension: k.dimension, Value: k.value, Count: n})
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "resolution"}, {Name: "bucket"}, {Name: "dimension"}, {Name: "value"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("attack_rollups.count + excluded.count")}),
+	}).CreateInBatches(rows, 500).Error
+}
+
+// attackCountry is the country code of the source of an attack: internal for private
+// addresses, else the code known for the source, else the one in the location
+func attackCountry(a model.AttackLog, known string) string {
+	if addr, err := netip.ParseAddr(a.SourceIP); err == nil && (addr.IsPrivate() || addr.IsLoopback()) {
+		return "internal"
+	}
+	if known != "" {
+		return strings.ToUpper(known)
+	}
+	return locationCountry(a.Location)
+}
+
+// locationCountry takes the country code from a location such as "Moscow, RU"
+func locationCountry(location string) string {
+	parts := strings.Split(location, ",")
+	code := strings.TrimSpace(parts[len(parts)-1])
+	if len(code) != 2 {
+		return ""
+	}
+	return strings.ToUpper(code)
+}
+
+// rollupAttack adds a freshly ingested attack to its buckets
+func (h *Handler) rollupAttack(a model.AttackLog) {
+	counts := map[rollupKey]int64{}
+	var src model.AttackSource
+	h.DB.Select("country").Where("ip = ? AND country <> ''", a.SourceIP).Limit(1).Find(&src)
+	addAttack(counts, a, attackCountry(a, src.Country), h.Now())
+	if err := upsertRollups(h.DB, counts); err != nil {
+		log.Printf("Trends: failed to roll up attack %s: %v", a.ID, err)
+	}
+}
+
+// StartAttackRollups rolls up the attack log once, for databases from before rollups
+// were kept on ingest
+func (h *Handler) StartAttackRollups() {
+	var cfg model.SystemConfig
+	if h.DB.Where("key = ?", "rollup_schema").Limit(1).Find(&cfg); cfg.Value == rollupSchemaVersion {
+		return
+	}
+
+	started := time.Now()
+	var sources []model.AttackSource
+	h.DB.Select("ip", "country").Where("country <> ''").Find(&sources)
+	countries := make(map[string]string, len(sources))
+	for _, s := range sources {
+		countries[s.IP] = s.Country
+	}
+
+	now := h.Now()
+	total := 0
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&model.AttackRollup{}).Error; err != nil {
+			return err
+		}
+		var attacks []model.AttackLog
+		return tx.FindInBatches(&attacks, 1000, func(batch *gorm.DB, _ int) error {
+			counts := map[rollupKey]int64{}
+			for _, a := range attacks {
+				addAttack(counts, a, attackCountry(a, countries[a.SourceIP]), now)
+			}
+			total += len(attacks)
+			return upsertRollups(tx, counts)
+		}).Error
+	})
+	if err != nil {
+		log.Printf("Trends: failed to roll up the attack log: %v", err)
+		return
+	}
+
+	cfg.Key, cfg.Value = "rollup_schema", rollupSchemaVersion
+	cfg.Description = "Version of the attack trend rollups"
+	h.DB.Save(&cfg)
+	log.Printf("Trends: rolled up %d attacks in %v", total, time.Since(started).Round(time.Millisecond))
+}
+
+// RunRollupRetention drops minute and hour buckets past their retention every hour
+func (h *Handler) RunRollupRetention() {
+	prune := func() {
+		now := h.Now()
+		for _, res := range rollupResolutions {
+			if res.retention > 0 {
+				h.DB.Where("resolution = ? AND bucket < ?", res.name, now.Add(-res.retention).Truncate(res.step)).Delete(&model.AttackRollup{})
+			}
+		}
+	}
+	prune()
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		prune()
+	}
+}
+
+type trendQuery struct {
+	from, to time.Time
+	interval time.Duration // Zero picks one
+	groupBy  string
+	top      int // Series beyond the top ones are summed up as other, zero keeps all
+	loc      *time.Location
+}
+
+type trendPoint struct {
+	Time   time.Time        `json:"time"`
+	Total  int64            `json:"total"`
+	Values map[string]int64 `json:"values"`
+}
+
+type trendResult struct {
+	From       time.Time    `json:"from"`
+	To         time.Time    `json:"to"`
+	Interval   string       `json:"interval"`
+	Resolution string       `json:"resolution"`
+	GroupBy    string       `json:"groupBy"`
+	Series     []string     `json:"series"` // Ordered by their count over the range
+	Points     []trendPoint `json:"points"`
+}
+
+// alignTrend moves t to the start of its interval in the zone of the query, so days
+// start at local midnight and weeks on Monday
+func alignTrend(t time.Time, interval time.Duration, loc *time.Location) time.Time {
+	_, offset := t.In(loc).Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(interval).Add(-shift).UTC()
+}
+
+// attackTrend sums the rollups of a range into buckets of the interval
+func (h *Handler) attackTrend(q trendQuery) (trendResult, error) {
+	if !q.to.After(q.from) {
+		return trendResult{}, errors.New("from must be before to")
+	}
+	if q.interval == 0 {
+		for _, iv := range trendIntervals {
+			q.interval = iv
+			if q.to.Sub(q.from)/iv <= 200 {
+				break
+			}
+		}
+	}
+	if q.to.Sub(q.from)/q.interval > trendMaxPoints {
+		return trendResult{}, fmt.Errorf("more than %d points, use a longer interval", trendMaxPoints)
+	}
+
+	// The coarsest resolution that divides the interval, lines up with the zone and
+	// still covers the start of the range
+	start := alignTrend(q.from, q.interval, q.loc)
+	_, offset := start.In(q.loc).Zone()
+	var res *rollupResolution
+	for i, r := range rollupResolutions {
+		if q.interval%r.step != 0 || (time.Duration(offset)*time.Second)%r.step != 0 {
+			continue
+		}
+		if r.retention > 0 && start.Before(h.Now().Add(-r.retention)) {
+			continue
+		}
+		res = &rollupResolutions[i]
+		break
+	}
+	if res == nil {
+		return trendResult{}, errors.New("no rollup covers this interval that far back, use a longer interval or a shorter range")
+	}
+
+	var rows []model.AttackRollup
+	err := h.DB.Where("resolution = ? AND dimension = ? AND bucket >= ? AND bucket < ?", res.name, q.groupBy, start, q.to).Find(&rows).Error
+	if err != nil {
+		return trendResult{}, err
+	}
+
+	sums := map[string]int64{}
+	for _, r := range rows {
+		sums[r.Value] += r.Count
+	}
+	series := make([]string, 0, len(sums))
+	for v := range sums {
+		series = append(series, v)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if sums[series[i]] != sums[series[j]] {
+			return sums[series[i]] > sums[series[j]]
+		}
+		return series[i] < series[j]
+	})
+	name := func(v string) string { return v }
+	if q.groupBy == "total" {
+		name = func(string) string { return "total" }
+		series = []string{"total"}
+	} else if q.top > 0 && len(series) > q.top {
+		kept := map[string]bool{}
+		for _, v := range series[:q.top] {
+			kept[v] = true
+		}
+		series = append(series[:q.top:q.top], "other")
+		name = func(v string) string {
+			if kept[v] {
+				return v
+			}
+			return "other"
+		}
+	}
+
+	result := trendResult{
+		From: start, To: q.to.UTC(), Interval: formatInterval(q.interval),
+		Resolution: res.name, GroupBy: q.groupBy, Series: series, Points: []trendPoint{},
+	}
+	index := map[time.Time]int{}
+	for t := start; t.Before(q.to); t = alignTrend(t.Add(q.interval*3/2), q.interval, q.loc) {
+		point := trendPoint{Time: t, Values: make(map[string]int64, len(series))}
+		for _, s := range series {
+			point.Values[s] = 0
+		}
+		index[t] = len(result.Points)
+		result.Points = append(result.Points, point)
+	}
+	for _, r := range rows {
+		i, ok := index[alignTrend(r.Bucket, q.interval, q.loc)]
+		if !ok {
+			continue
+		}
+		result.Points[i].Values[name(r.Value)] += r.Count
+		result.Points[i].Total += r.Count
+	}
+	return result, nil
+}
+
+// parseInterval reads an interval such as 10s, 5m, 1h or 7d, it has to be a whole
+// number of units
+func parseInterval(s string, unit time.Duration) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, errors.New("invalid interval " + s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < unit || d%unit != 0 {
+		return 0, fmt.Errorf("interval must be a multiple of %s, e.g. 1h or 1d", formatInterval(unit))
+	}
+	return d, nil
+}
+
+func formatInterval(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+// GetAttackTrends returns attack counts over a range in buckets of interval, grouped
+// by service, severity, node or country. The range defaults to the last 24 hours and
+// tz, an IANA zone name, decides where days start.
+func (h *Handler) GetAttackTrends(c *gin.Context) {
+	q := trendQuery{to: h.Now(), groupBy: c.DefaultQuery("groupBy", "total"), top: 5, loc: time.UTC}
+	q.from = q.to.Add(-24 * time.Hour)
+	for param, target := range map[string]*time.Time{"from": &q.from, "to": &q.to} {
+		if v := c.Query(param); v != "" {
+			t, ok := parseTimeParam(v)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 time"})
+				return
+			}
+			*target = t
+		}
+	}
+	if v := c.Query("interval"); v != "" {
+		var err error
+		if q.interval, err = parseInterval(v, time.Minute); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !slices.Contains(rollupDimensions, q.groupBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot group by " + q.groupBy})
+		return
+	}
+	if v := c.Query("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "top must not be negative"})
+			return
+		}
+		q.top = n
+	}
+	if v := c.Query("tz"); v != "" {
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown time zone " + v})
+			return
+		}
+		q.loc = loc
+	}
+
+	result, err := h.attackTrend(q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
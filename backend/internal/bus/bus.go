@@ -0,0 +1,91 @@
+// Package bus publishes messages to Kafka and NATS through franz-go and nats.go: Kafka
+// records with headers, acknowledged by all in-sync replicas, and NATS messages with
+// headers, optionally stored by JetStream.
+package bus
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is one record for a topic or subject
+type Message struct {
+	ID      string    `json:"id"`    // JetStream drops a second message with the same ID
+	Topic   string    `json:"topic"` // Kafka topic or NATS subject
+	Key     []byte    `json:"key"`   // Picks the Kafka partition, messages with one key stay in order
+	Value   []byte    `json:"value"`
+	Headers []Header  `json:"headers"`
+	Time    time.Time `json:"time"`
+}
+
+type Header struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Rejection is a message the broker refused for good, sending it again would not help
+type Rejection struct {
+	Index  int
+	Reason string
+}
+
+// Publisher sends messages and waits until the broker has them. An error means none can be
+// taken as delivered, some may still have arrived and will arrive again on a retry.
+// Close drops the connection, a later Publish connects again.
+type Publisher interface {
+	Publish(msgs []Message) ([]Rejection, error)
+	Close() error
+}
+
+// Config is the connection of a publisher
+type Config struct {
+	Servers  []string    // Kafka bootstrap brokers as host:port, NATS URLs or host:port
+	TLS      *tls.Config // Nil for plain connections
+	Username string      // Kafka SASL PLAIN, NATS user
+	Password string
+	Token    string // NATS only
+	ClientID string
+	Timeout  time.Duration // Per connection attempt and per request
+}
+
+func (cfg Config) timeout() time.Duration {
+	if cfg.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return cfg.Timeout
+}
+
+// New returns an unconnected publisher, it connects on the first publish
+func New(kind string, cfg Config, jetStream bool) (Publisher, error) {
+	if len(cfg.Servers) == 0 {
+		return nil, errors.New("no servers given")
+	}
+	switch strings.ToLower(kind) {
+	case "kafka":
+		return NewKafkaProducer(cfg)
+	case "nats":
+		return NewNATSPublisher(cfg, jetStream)
+	}
+	return nil, errors.New("unknown bus " + kind)
+}
+
+// CheckServer validates a server address of the bus: host:port for Kafka, a nats:// or
+// tls:// URL or host:port for NATS
+func CheckServer(kind, server string) error {
+	if strings.ToLower(kind) == "nats" {
+		_, _, err := natsAddr(server)
+		return err
+	}
+	host, port, err := net.SplitHostPort(server)
+	if err == nil && host == "" {
+		err = errors.New("missing host")
+	}
+	if n, perr := strconv.Atoi(port); err == nil && (perr != nil || n < 1 || n > 65535) {
+		err = errors.New("invalid port")
+	}
+	return err
+}
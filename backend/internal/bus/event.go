@@ -0,0 +1,79 @@
+package bus
+
+import (
+	"encoding/json"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Schema names the envelope and its version. Fields are only ever added within a
+// version, a change that breaks consumers gets a new one.
+const Schema = "prts.event.v1"
+
+// Content types of the two encodings, sent along in the content-type header
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf; messageType=prts.event.v1.Event"
+)
+
+// Event is the envelope of every message, event.proto describes its Protobuf form. Data
+// is the stored record of the type as JSON in both encodings, so the envelope does not
+// change when a record gains a column.
+type Event struct {
+	Schema   string          `json:"schema"`
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`  // attack, scan, credential, decoy, sample, login, node
+	Class    string          `json:"class"` // Type and subtype, e.g. attack:SSH or node:offline
+	Name     string          `json:"name"`
+	Severity string          `json:"severity"` // info, low, medium, high, critical
+	Time     time.Time       `json:"time"`
+	SourceIP string          `json:"sourceIp,omitempty"`
+	Node     string          `json:"node,omitempty"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Encode renders the event as JSON or as Protobuf, with the matching content type
+func (e Event) Encode(protobuf bool) ([]byte, string, error) {
+	if protobuf {
+		return e.MarshalProto(), ContentTypeProtobuf, nil
+	}
+	data, err := json.Marshal(e)
+	return data, ContentTypeJSON, err
+}
+
+// MarshalProto encodes the event as a prts.event.v1.Event message
+func (e Event) MarshalProto() []byte {
+	var b []byte
+	str := func(num protowire.Number, s string) {
+		if s != "" {
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, s)
+		}
+	}
+	str(1, e.Schema)
+	str(2, e.ID)
+	str(3, e.Type)
+	str(4, e.Class)
+	str(5, e.Name)
+	str(6, e.Severity)
+	if !e.Time.IsZero() {
+		// google.protobuf.Timestamp
+		var ts []byte
+		ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(e.Time.Unix()))
+		if nanos := e.Time.Nanosecond(); nanos != 0 {
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(nanos))
+		}
+		b = protowire.AppendTag(b, 7, protowire.BytesType)
+		b = protowire.AppendBytes(b, ts)
+	}
+	str(8, e.SourceIP)
+	str(9, e.Node)
+	if len(e.Data) > 0 {
+		b = protowire.AppendTag(b, 10, protowire.BytesType)
+		b = protowire.AppendBytes(b, e.Data)
+	}
+	return b
+}
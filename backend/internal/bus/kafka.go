@@ -0,0 +1,118 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+)
+
+// kafkaRefused are errors of the records themselves, sending them again can not help
+var kafkaRefused = []error{kerr.CorruptMessage, kerr.MessageTooLarge, kerr.RecordListTooLarge, kerr.InvalidRecord}
+
+// KafkaProducer publishes through franz-go. Records are acknowledged by all in-sync
+// replicas and keys pick partitions with murmur2 like the Java client, so the events of
+// one source land where other producers put theirs. Close drops the client, the next
+// Publish makes a new one.
+type KafkaProducer struct {
+	cfg    Config
+	opts   []kgo.Opt
+	client *kgo.Client
+}
+
+func NewKafkaProducer(cfg Config) (*KafkaProducer, error) {
+	if cfg.ClientID == "" {
+		cfg.ClientID = "prts"
+	}
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Servers...),
+		kgo.ClientID(cfg.ClientID),
+		kgo.DialTimeout(cfg.timeout()),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+		// Idempotent writes need a cluster ACL on older brokers, without them one request
+		// per broker is in flight and the order of a partition is kept all the same
+		kgo.DisableIdempotentWrite(),
+		kgo.ProducerBatchCompression(kgo.NoCompression()),
+		kgo.ProduceRequestTimeout(cfg.timeout()),
+		kgo.RecordDeliveryTimeout(3 * cfg.timeout()),
+		kgo.UnknownTopicRetries(3),
+		kgo.AllowAutoTopicCreation(), // The broker setting still decides
+	}
+	if cfg.TLS != nil {
+		opts = append(opts, kgo.DialTLSConfig(cfg.TLS.Clone()))
+	}
+	if cfg.Username != "" {
+		opts = append(opts, kgo.SASL(plain.Auth{User: cfg.Username, Pass: cfg.Password}.AsMechanism()))
+	}
+	// Made here once so bad settings show up before the first batch
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &KafkaProducer{cfg: cfg, opts: opts, client: client}, nil
+}
+
+// Publish writes the messages and waits for every one of them. The client retries lost
+// leaders and timeouts itself, records a broker refuses for their content are rejected.
+func (p *KafkaProducer) Publish(msgs []Message) ([]Rejection, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+	if p.client == nil {
+		client, err := kgo.NewClient(p.opts...)
+		if err != nil {
+			return nil, err
+		}
+		p.client = client
+	}
+	records := make([]*kgo.Record, len(msgs))
+	index := make(map[*kgo.Record]int, len(msgs))
+	for i, m := range msgs {
+		rec := &kgo.Record{Topic: m.Topic, Key: m.Key, Value: m.Value, Timestamp: m.Time}
+		for _, h := range m.Headers {
+			rec.Headers = append(rec.Headers, kgo.RecordHeader{Key: h.Key, Value: []byte(h.Value)})
+		}
+		records[i], index[rec] = rec, i
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 4*p.cfg.timeout())
+	defer cancel()
+	results := p.client.ProduceSync(ctx, records...)
+
+	// Results come in the order the records completed
+	var rejected []Rejection
+	var firstErr error
+	for _, res := range results {
+		i := index[res.Record]
+		switch {
+		case res.Err == nil:
+		case refusedByKafka(res.Err):
+			rejected = append(rejected, Rejection{Index: i, Reason: res.Err.Error()})
+		case firstErr == nil:
+			firstErr = fmt.Errorf("%s: %w", msgs[i].Topic, res.Err)
+		}
+	}
+	slices.SortFunc(rejected, func(a, b Rejection) int { return a.Index - b.Index })
+	return rejected, firstErr
+}
+
+func (p *KafkaProducer) Close() error {
+	if p.client == nil {
+		return nil
+	}
+	p.client.Close()
+	p.client = nil
+	return nil
+}
+
+func refusedByKafka(err error) bool {
+	for _, refused := range kafkaRefused {
+		if errors.Is(err, refused) {
+			return true
+		}
+	}
+	return false
+}
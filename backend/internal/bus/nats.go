@@ -0,0 +1,200 @@
+package bus
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATSPublisher publishes with headers through nats.go. Core NATS only confirms that the
+// server read the messages, with JetStream every message waits for the stream to store it.
+// It is not safe for concurrent use.
+type NATSPublisher struct {
+	cfg       Config
+	servers   []string // As URLs
+	jetStream bool
+
+	nc *nats.Conn
+	js jetstream.JetStream
+}
+
+func NewNATSPublisher(cfg Config, jetStream bool) (*NATSPublisher, error) {
+	if cfg.ClientID == "" {
+		cfg.ClientID = "prts"
+	}
+	p := &NATSPublisher{cfg: cfg, jetStream: jetStream}
+	for _, server := range cfg.Servers {
+		addr, useTLS, err := natsAddr(server)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", server, err)
+		}
+		scheme := "nats"
+		if useTLS {
+			scheme = "tls"
+		}
+		p.servers = append(p.servers, scheme+"://"+addr)
+	}
+	return p, nil
+}
+
+// Publish sends the batch and waits for the server, or for JetStream to ack every
+// message. Messages over the server's payload limit are rejected unsent.
+func (p *NATSPublisher) Publish(msgs []Message) ([]Rejection, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+	if p.nc == nil || p.nc.IsClosed() {
+		if err := p.connect(); err != nil {
+			return nil, err
+		}
+	}
+	var rejected []Rejection
+	reject := func(i int) {
+		rejected = append(rejected, Rejection{Index: i, Reason: fmt.Sprintf("message exceeds the server's max_payload of %d bytes", p.nc.MaxPayload())})
+	}
+
+	if !p.jetStream {
+		for i, m := range msgs {
+			msg := natsMessage(m)
+			if err := p.nc.PublishMsg(msg); errors.Is(err, nats.ErrMaxPayload) {
+				reject(i)
+			} else if err != nil {
+				p.Close()
+				return nil, err
+			}
+		}
+		if err := p.nc.FlushTimeout(p.cfg.timeout()); err != nil {
+			p.Close()
+			return nil, err
+		}
+		return rejected, nil
+	}
+
+	acks := make(map[int]jetstream.PubAckFuture, len(msgs))
+	for i, m := range msgs {
+		msg := natsMessage(m)
+		ack, err := p.js.PublishMsgAsync(msg)
+		if errors.Is(err, nats.ErrMaxPayload) {
+			reject(i)
+			continue
+		}
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		acks[i] = ack
+	}
+	deadline := time.NewTimer(p.cfg.timeout())
+	defer deadline.Stop()
+	for i, ack := range acks {
+		select {
+		case <-ack.Ok():
+		case err := <-ack.Err():
+			if errors.Is(err, jetstream.ErrNoStreamResponse) {
+				err = errors.New("no JetStream stream takes the subject")
+			}
+			p.Close()
+			return nil, fmt.Errorf("%s: %w", msgs[i].Topic, err)
+		case <-deadline.C:
+			p.Close()
+			return nil, fmt.Errorf("no JetStream ack for %d of %d messages", len(acks), len(msgs))
+		}
+		delete(acks, i)
+	}
+	return rejected, nil
+}
+
+func (p *NATSPublisher) Close() error {
+	if p.nc == nil {
+		return nil
+	}
+	p.nc.Close()
+	p.nc, p.js = nil, nil
+	return nil
+}
+
+func (p *NATSPublisher) connect() error {
+	opts := []nats.Option{
+		nats.Name(p.cfg.ClientID),
+		nats.Timeout(p.cfg.timeout()),
+		// Publishes fail while the connection is down instead of waiting in a buffer that
+		// is lost when the process stops, the spool keeps them instead
+		nats.ReconnectBufSize(-1),
+	}
+	if p.cfg.TLS != nil {
+		opts = append(opts, nats.Secure(p.cfg.TLS.Clone()))
+	}
+	if p.cfg.Username != "" {
+		opts = append(opts, nats.UserInfo(p.cfg.Username, p.cfg.Password))
+	}
+	if p.cfg.Token != "" {
+		opts = append(opts, nats.Token(p.cfg.Token))
+	}
+	nc, err := nats.Connect(strings.Join(p.servers, ","), opts...)
+	if err != nil {
+		return err
+	}
+	if !nc.HeadersSupported() {
+		nc.Close()
+		return errors.New("the server does not support headers, NATS 2.2 or later is needed")
+	}
+	p.nc = nc
+	if p.jetStream {
+		if p.js, err = jetstream.New(nc, jetstream.WithPublishAsyncTimeout(p.cfg.timeout())); err != nil {
+			p.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// natsMessage carries the event ID as JetStream's deduplication ID, so a message sent
+// again after a lost ack is stored once
+func natsMessage(m Message) *nats.Msg {
+	msg := nats.NewMsg(m.Topic)
+	msg.Data = m.Value
+	if m.ID != "" {
+		msg.Header.Set(jetstream.MsgIDHeader, natsHeaderValue(m.ID))
+	}
+	if len(m.Key) > 0 {
+		msg.Header.Set("prts-key", natsHeaderValue(string(m.Key)))
+	}
+	for _, h := range m.Headers {
+		msg.Header.Add(h.Key, natsHeaderValue(h.Value))
+	}
+	return msg
+}
+
+var natsHeaderEscaper = strings.NewReplacer("\r", " ", "\n", " ")
+
+func natsHeaderValue(s string) string {
+	return natsHeaderEscaper.Replace(s)
+}
+
+// natsAddr takes nats://, tls:// or a bare host:port, the port defaulting to 4222
+func natsAddr(server string) (addr string, useTLS bool, err error) {
+	if !strings.Contains(server, "://") {
+		server = "nats://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", false, err
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return "", false, fmt.Errorf("unsupported scheme %s", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", false, errors.New("missing host")
+	}
+	port := u.Port()
+	if port == "" {
+		port = "4222"
+	}
+	return net.JoinHostPort(u.Hostname(), port), u.Scheme == "tls", nil
+}
@@ -0,0 +1,52 @@
+package ingest
+
+import (
+	"embed"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+//go:embed adapters/*.json
+var builtinFS embed.FS
+
+var builtins = loadBuiltins()
+
+func loadBuiltins() map[string]*Adapter {
+	entries, err := builtinFS.ReadDir("adapters")
+	if err != nil {
+		panic(err)
+	}
+	m := map[string]*Adapter{}
+	for _, e := range entries {
+		data, err := builtinFS.ReadFile("adapters/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		a, err := ParseSpec(data)
+		if err != nil {
+			panic(fmt.Sprintf("built-in adapter %s: %v", e.Name(), err))
+		}
+		if a.Name()+".json" != e.Name() {
+			panic("built-in adapter " + e.Name() + " is named " + a.Name())
+		}
+		m[a.Name()] = a
+	}
+	return m
+}
+
+// Builtin returns the adapter shipped under a name
+func Builtin(name string) (*Adapter, bool) {
+	a, ok := builtins[name]
+	return a, ok
+}
+
+// Builtins lists the shipped adapters by name
+func Builtins() []*Adapter {
+	list := make([]*Adapter, 0, len(builtins))
+	for _, a := range builtins {
+		list = append(list, a)
+	}
+	slices.SortFunc(list, func(a, b *Adapter) int { return strings.Compare(a.Name(), b.Name()) })
+	return list
+}
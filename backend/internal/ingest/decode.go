@@ -0,0 +1,184 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MaxLineSize bounds one log line, longer ones fail to decode
+const MaxLineSize = 1 << 20
+
+// Decoder turns log lines into events. A line is a JSON object, or a row of Zeek's tab
+// separated format whose # header lines name the fields of the rows that follow. Zeek's
+// log path from the #path header, or the one the decoder was created with, is set as
+// _path on events that do not carry it.
+type Decoder struct {
+	path       string
+	fields     []string
+	separator  string
+	emptyField string
+	unsetField string
+}
+
+func NewDecoder(logPath string) *Decoder {
+	d := &Decoder{path: logPath}
+	d.resetZeek()
+	return d
+}
+
+func (d *Decoder) resetZeek() {
+	d.fields = nil
+	d.separator = "\t"
+	d.emptyField, d.unsetField = "(empty)", "-"
+}
+
+// Decode returns the event of a line, nil without an error for blank and header lines
+func (d *Decoder) Decode(line string) (Event, error) {
+	line = strings.TrimRight(line, "\r\n")
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case trimmed == "":
+		return nil, nil
+	case trimmed[0] == '{':
+		return d.decodeJSON([]byte(trimmed))
+	case trimmed[0] == '#':
+		d.header(line)
+		return nil, nil
+	case d.fields != nil:
+		return d.decodeZeek(line)
+	}
+	return nil, errors.New("not a JSON object and no Zeek #fields header seen")
+}
+
+func (d *Decoder) decodeJSON(data []byte) (Event, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var ev Event
+	if err := dec.Decode(&ev); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if ev == nil {
+		return nil, errors.New("not a JSON object")
+	}
+	if _, ok := ev["_path"]; !ok && d.path != "" {
+		ev["_path"] = d.path
+	}
+	return ev, nil
+}
+
+func (d *Decoder) header(line string) {
+	name, value, _ := strings.Cut(line[1:], " ")
+	if name != "separator" {
+		// Every other header is separated by the separator it declared
+		name, value, _ = strings.Cut(line[1:], d.separator)
+	}
+	switch name {
+	case "separator":
+		d.resetZeek()
+		d.separator = unescapeZeek(value)
+	case "empty_field":
+		d.emptyField = value
+	case "unset_field":
+		d.unsetField = value
+	case "path":
+		d.path = value
+	case "fields":
+		d.fields = strings.Split(value, d.separator)
+	}
+}
+
+func (d *Decoder) decodeZeek(line string) (Event, error) {
+	values := strings.Split(line, d.separator)
+	if len(values) != len(d.fields) {
+		return nil, fmt.Errorf("%d values for %d Zeek fields", len(values), len(d.fields))
+	}
+	ev := Event{}
+	for i, v := range values {
+		switch v {
+		case d.unsetField:
+			continue
+		case d.emptyField:
+			v = ""
+		}
+		ev[d.fields[i]] = unescapeZeek(v)
+	}
+	if d.path != "" {
+		ev["_path"] = d.path
+	}
+	return ev, nil
+}
+
+// unescapeZeek decodes the \xNN escapes Zeek writes for separators and unprintable bytes,
+// values of sets keep their comma separators
+func unescapeZeek(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if n, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// Each decodes a body: a JSON array of objects or one event per line. fn gets the line
+// number, counting from 1, the line or array element as read, and the event or its
+// decode error. It returns false to stop.
+func (d *Decoder) Each(r io.Reader, fn func(line int, raw string, ev Event, err error) bool) error {
+	br := bufio.NewReader(r)
+	// Look past leading whitespace for the [ of an array without consuming the lines
+	array := false
+	for n := 1; n <= 4096; n++ {
+		b, err := br.Peek(n)
+		if len(b) < n {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if c := b[n-1]; c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			array = c == '['
+			break
+		}
+	}
+	if array {
+		var elements []json.RawMessage
+		if err := json.NewDecoder(br).Decode(&elements); err != nil {
+			return fmt.Errorf("invalid JSON array: %w", err)
+		}
+		for i, raw := range elements {
+			ev, err := d.decodeJSON(raw)
+			if !fn(i+1, string(raw), ev, err) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(make([]byte, 64*1024), MaxLineSize)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		ev, err := d.Decode(line)
+		if ev == nil && err == nil {
+			continue
+		}
+		if !fn(n, line, ev, err) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
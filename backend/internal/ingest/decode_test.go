@@ -0,0 +1,202 @@
+package ingest
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	d := NewDecoder("eve.json")
+	ev, err := d.Decode(`{"src_ip":"10.0.0.1","src_port":4444,"alert":{"signature":"ET SCAN"}}` + "\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev["_path"] != "eve.json" {
+		t.Fatalf("_path = %v, want the decoder's log path", ev["_path"])
+	}
+	if port, ok := ev["src_port"].(json.Number); !ok || port.String() != "4444" {
+		t.Fatalf("src_port = %#v, numbers should stay json.Number", ev["src_port"])
+	}
+
+	// A _path of the event itself wins
+	ev, _ = d.Decode(`{"_path":"dns"}`)
+	if ev["_path"] != "dns" {
+		t.Fatalf("_path = %v, want dns", ev["_path"])
+	}
+
+	for _, line := range []string{`{"broken":`, `{"a":1`, `{"a" 1}`} {
+		if _, err := d.Decode(line); err == nil {
+			t.Errorf("Decode(%q) took invalid JSON", line)
+		}
+	}
+}
+
+func TestDecodeBlankAndUnknown(t *testing.T) {
+	d := NewDecoder("")
+	if ev, err := d.Decode("   "); ev != nil || err != nil {
+		t.Fatalf("blank line = %v, %v", ev, err)
+	}
+	if _, err := d.Decode("plain text"); err == nil {
+		t.Fatal("a line that is neither JSON nor Zeek decoded")
+	}
+}
+
+func TestDecodeZeek(t *testing.T) {
+	d := NewDecoder("")
+	header := []string{
+		`#separator \x09`,
+		"#set_separator\t,",
+		"#empty_field\t(empty)",
+		"#unset_field\t-",
+		"#path\tconn",
+		"#fields\tts\tid.orig_h\tservice\thistory\tnote",
+		"#types\ttime\taddr\tstring\tstring\tstring",
+	}
+	for _, line := range header {
+		if ev, err := d.Decode(line); ev != nil || err != nil {
+			t.Fatalf("header %q = %v, %v", line, ev, err)
+		}
+	}
+
+	ev, err := d.Decode("1700000000.1\t10.0.0.9\t(empty)\t-\ta\\x09b\\x2cc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Event{"ts": "1700000000.1", "id.orig_h": "10.0.0.9", "service": "", "note": "a\tb,c", "_path": "conn"}
+	if !reflect.DeepEqual(ev, want) {
+		t.Fatalf("row = %v, want %v", ev, want)
+	}
+
+	if _, err := d.Decode("1\t2"); err == nil || !strings.Contains(err.Error(), "2 values for 5 Zeek fields") {
+		t.Fatalf("short row = %v", err)
+	}
+}
+
+func TestDecodeZeekOtherSeparator(t *testing.T) {
+	d := NewDecoder("ssh")
+	for _, line := range []string{`#separator \x7c`, "#fields|ts|auth_success"} {
+		d.Decode(line)
+	}
+	ev, err := d.Decode("1|T")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev["auth_success"] != "T" || ev["_path"] != "ssh" {
+		t.Fatalf("row = %v", ev)
+	}
+}
+
+type eachCall struct {
+	line int
+	raw  string
+	err  bool
+}
+
+func each(t *testing.T, body string, stopAfter int) []eachCall {
+	t.Helper()
+	var calls []eachCall
+	err := NewDecoder("").Each(strings.NewReader(body), func(line int, raw string, ev Event, err error) bool {
+		calls = append(calls, eachCall{line, raw, err != nil})
+		return stopAfter == 0 || len(calls) < stopAfter
+	})
+	if err != nil {
+		t.Fatalf("Each: %v", err)
+	}
+	return calls
+}
+
+func TestEachLines(t *testing.T) {
+	body := "{\"a\":1}\n\n#comment\n{bad\n{\"b\":2}\n"
+	got := each(t, body, 0)
+	want := []eachCall{{1, `{"a":1}`, false}, {4, `{bad`, true}, {5, `{"b":2}`, false}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls = %+v, want %+v", got, want)
+	}
+	if got := each(t, body, 1); len(got) != 1 {
+		t.Fatalf("%d calls after fn returned false, want 1", len(got))
+	}
+}
+
+func TestEachArray(t *testing.T) {
+	got := each(t, "\n  [{\"a\":1}, 2, {\"b\":2}]", 0)
+	want := []eachCall{{1, `{"a":1}`, false}, {2, `2`, true}, {3, `{"b":2}`, false}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls = %+v, want %+v", got, want)
+	}
+
+	err := NewDecoder("").Each(strings.NewReader(`[{"a":1}`), func(int, string, Event, error) bool { return true })
+	if err == nil {
+		t.Fatal("an unterminated array decoded")
+	}
+}
+
+func TestEachEmpty(t *testing.T) {
+	if got := each(t, "", 0); len(got) != 0 {
+		t.Fatalf("empty body gave %+v", got)
+	}
+	if got := each(t, " \n\t\n", 0); len(got) != 0 {
+		t.Fatalf("blank body gave %+v", got)
+	}
+}
+
+// decodeAndMap runs a body through the decoder and a built-in adapter
+func decodeAndMap(t *testing.T, adapter, logPath, body string) []Record {
+	t.Helper()
+	a, ok := Builtin(adapter)
+	if !ok {
+		t.Fatalf("no built-in adapter %s", adapter)
+	}
+	var records []Record
+	err := NewDecoder(logPath).Each(strings.NewReader(body), func(line int, raw string, ev Event, err error) bool {
+		if err != nil {
+			t.Fatalf("line %d: %v", line, err)
+		}
+		recs, err := a.Map(ev)
+		if err != nil {
+			t.Fatalf("line %d: %v", line, err)
+		}
+		records = append(records, recs...)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestBuiltinCowrie(t *testing.T) {
+	body := `{"eventid":"cowrie.login.success","src_ip":"203.0.113.5","username":"root","password":"toor","protocol":"telnet","timestamp":"2026-01-02T03:04:05.123456Z","sensor":"hp1"}
+{"eventid":"cowrie.session.connect","src_ip":"203.0.113.5"}
+`
+	records := decodeAndMap(t, "cowrie", "", body)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want a credential and an attack: %+v", len(records), records)
+	}
+	cred, attack := records[0], records[1]
+	if cred.Kind != "credential" || cred.Fields["service"] != "Telnet" || cred.Fields["password"] != "toor" {
+		t.Fatalf("credential = %+v", cred)
+	}
+	if attack.Kind != "attack" || attack.Fields["method"] != "TELNET" || attack.Fields["status"] != "compromised" {
+		t.Fatalf("attack = %+v", attack)
+	}
+	if cred.Sensor != "hp1" || cred.Time == nil || cred.Time.Nanosecond() != 123456000 {
+		t.Fatalf("sensor %q and time %v of the event were not kept", cred.Sensor, cred.Time)
+	}
+}
+
+func TestBuiltinZeekTSV(t *testing.T) {
+	body := "#separator \\x09\n#path\thttp\n#fields\tts\tid.orig_h\tmethod\thost\turi\tstatus_code\tuser_agent\n" +
+		"1700000000.5\t198.51.100.7\tGET\texample.com\t/admin\t404\tcurl/8.0\n"
+	records := decodeAndMap(t, "zeek", "", body)
+	if len(records) != 1 || records[0].Rule != "http request" {
+		t.Fatalf("records = %+v", records)
+	}
+	if got := records[0].Fields["payload"]; got != "GET example.com/admin 404 curl/8.0" {
+		t.Fatalf("payload = %q", got)
+	}
+	if records[0].Time == nil || records[0].Time.Unix() != 1700000000 {
+		t.Fatalf("time = %v", records[0].Time)
+	}
+}
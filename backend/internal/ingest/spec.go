@@ -0,0 +1,490 @@
+// Package ingest maps the logs of other sensors onto PRTS records. An adapter is a
+// declarative spec: rules pick the events they apply to by matching fields against
+// regular expressions, and fill the fields of the record they produce from templates.
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec is an adapter as it is stored and shipped, see the built-ins under adapters/
+type Spec struct {
+	Name        string                       `json:"name"`
+	Description string                       `json:"description,omitempty"`
+	Time        string                       `json:"time"`             // Template of the event time, empty or unparsable means arrival time
+	Sensor      string                       `json:"sensor,omitempty"` // Template of the sensor name, the source's name when it renders empty
+	Maps        map[string]map[string]string `json:"maps,omitempty"`   // Value tables usable as filters, "*" catches the rest
+	Rules       []Rule                       `json:"rules"`
+}
+
+// Rule turns the events it matches into one record. Every matching rule of an adapter
+// produces its record, so one event can become an attack and a credential.
+type Rule struct {
+	Name   string            `json:"name"`
+	When   map[string]string `json:"when"`             // Field path to regexp, all must match, a missing field is empty
+	Unless map[string]string `json:"unless,omitempty"` // Field path to regexp, any match skips the rule
+	Record string            `json:"record"`           // attack, credential, scan or sample
+	Fields map[string]string `json:"fields"`           // Record field to template
+}
+
+// RecordFields are the fields each record kind takes, the required ones must not render empty
+var RecordFields = map[string][]string{
+	"attack":     {"sourceIp", "method", "payload", "severity", "status", "location"},
+	"credential": {"ip", "service", "username", "password"},
+	"scan":       {"ip", "type", "threat", "ports", "count", "duration", "location"},
+	"sample":     {"sha256", "fileName", "fileSize", "fileType", "threatLevel", "status", "attackerIp"},
+}
+
+// addressFields must hold an IP address when they are set
+var addressFields = []string{"sourceIp", "ip", "attackerIp"}
+
+var requiredFields = map[string][]string{
+	"attack":     {"sourceIp"},
+	"credential": {"ip", "service"},
+	"scan":       {"ip"},
+	"sample":     {"sha256"},
+}
+
+// Event is one decoded log line
+type Event map[string]interface{}
+
+// Record is what a rule made of an event
+type Record struct {
+	Kind   string            `json:"kind"`
+	Rule   string            `json:"rule"`
+	Time   *time.Time        `json:"time,omitempty"` // Nil when the event has none
+	Sensor string            `json:"sensor,omitempty"`
+	Fields map[string]string `json:"fields"`
+}
+
+// Adapter is a compiled spec
+type Adapter struct {
+	Spec   Spec
+	time   template
+	sensor template
+	rules  []rule
+}
+
+type rule struct {
+	Rule
+	when   []condition
+	unless []condition
+	fields map[string]template
+}
+
+type condition struct {
+	path string
+	re   *regexp.Regexp
+}
+
+var (
+	nameRe     = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+	builtinFns = map[string]func(string) string{
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		"trim":  strings.TrimSpace,
+		"base":  baseName,
+	}
+)
+
+// ParseSpec reads and compiles the JSON of an adapter
+func ParseSpec(data []byte) (*Adapter, error) {
+	var spec Spec
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid adapter JSON: %w", err)
+	}
+	return Compile(spec)
+}
+
+// Compile checks a spec and prepares its templates and expressions
+func Compile(spec Spec) (*Adapter, error) {
+	if !nameRe.MatchString(spec.Name) {
+		return nil, errors.New("name must be 1 to 32 lowercase letters, digits, dashes or underscores")
+	}
+	for name := range spec.Maps {
+		if _, ok := builtinFns[name]; ok {
+			return nil, fmt.Errorf("map %s shadows the built-in filter of that name", name)
+		}
+	}
+	if len(spec.Rules) == 0 {
+		return nil, errors.New("at least one rule is required")
+	}
+	a := &Adapter{Spec: spec}
+	var err error
+	if a.time, err = a.parseTemplate(spec.Time); err != nil {
+		return nil, fmt.Errorf("time: %w", err)
+	}
+	if a.sensor, err = a.parseTemplate(spec.Sensor); err != nil {
+		return nil, fmt.Errorf("sensor: %w", err)
+	}
+	for i, r := range spec.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		compiled, err := a.compileRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Name, err)
+		}
+		a.rules = append(a.rules, compiled)
+	}
+	return a, nil
+}
+
+func (a *Adapter) compileRule(r Rule) (rule, error) {
+	allowed, ok := RecordFields[r.Record]
+	if !ok {
+		return rule{}, fmt.Errorf("unknown record %q, use attack, credential, scan or sample", r.Record)
+	}
+	if len(r.When) == 0 {
+		return rule{}, errors.New("when needs at least one condition")
+	}
+	compiled := rule{Rule: r, fields: map[string]template{}}
+	var err error
+	if compiled.when, err = compileConditions(r.When); err != nil {
+		return rule{}, err
+	}
+	if compiled.unless, err = compileConditions(r.Unless); err != nil {
+		return rule{}, err
+	}
+	for field, text := range r.Fields {
+		if !slices.Contains(allowed, field) {
+			return rule{}, fmt.Errorf("%s records have no field %s, use %s", r.Record, field, strings.Join(allowed, ", "))
+		}
+		if compiled.fields[field], err = a.parseTemplate(text); err != nil {
+			return rule{}, fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	for _, field := range requiredFields[r.Record] {
+		if _, ok := r.Fields[field]; !ok {
+			return rule{}, fmt.Errorf("%s records need the field %s", r.Record, field)
+		}
+	}
+	return compiled, nil
+}
+
+func compileConditions(m map[string]string) ([]condition, error) {
+	conds := make([]condition, 0, len(m))
+	for p, expr := range m {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		conds = append(conds, condition{path: p, re: re})
+	}
+	// Sorted so the first failing condition is always the same one
+	slices.SortFunc(conds, func(a, b condition) int { return strings.Compare(a.path, b.path) })
+	return conds, nil
+}
+
+// Name is the name the adapter is reached under
+func (a *Adapter) Name() string {
+	return a.Spec.Name
+}
+
+// Map runs the rules over an event. No record and no error means no rule applies, an
+// error reports the first matching rule that left a required field empty.
+func (a *Adapter) Map(ev Event) ([]Record, error) {
+	var at *time.Time
+	if t, ok := ParseTime(a.time.render(a, ev)); ok {
+		at = &t
+	}
+	sensor := a.sensor.render(a, ev)
+	var records []Record
+	var firstErr error
+	for _, r := range a.rules {
+		if !r.matches(ev) {
+			continue
+		}
+		rec := Record{Kind: r.Record, Rule: r.Name, Time: at, Sensor: sensor, Fields: make(map[string]string, len(r.fields))}
+		for field, t := range r.fields {
+			if v := strings.TrimSpace(t.render(a, ev)); v != "" {
+				rec.Fields[field] = v
+			}
+		}
+		if err := rec.check(); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", r.Name, err)
+			}
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, firstErr
+}
+
+func (r rule) matches(ev Event) bool {
+	for _, c := range r.when {
+		if !c.re.MatchString(lookupString(ev, c.path)) {
+			return false
+		}
+	}
+	for _, c := range r.unless {
+		if c.re.MatchString(lookupString(ev, c.path)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (rec Record) check() error {
+	for _, field := range requiredFields[rec.Kind] {
+		if rec.Fields[field] == "" {
+			return fmt.Errorf("%s is empty", field)
+		}
+	}
+	for _, field := range addressFields {
+		if v, ok := rec.Fields[field]; ok {
+			if _, err := netip.ParseAddr(v); err != nil {
+				return fmt.Errorf("%s %q is not an IP address", field, v)
+			}
+		}
+	}
+	return nil
+}
+
+// template is literal text with {expressions}. An expression lists alternatives, field
+// paths or 'quoted literals', separated by commas, the first that is not empty wins. Filters
+// follow after pipes: lower, upper, trim, base or the name of a map of the spec.
+// Example: {app_proto,proto,'tcp'|upper}
+type template []segment
+
+type segment struct {
+	text    string
+	alts    []alternative
+	filters []string
+}
+
+type alternative struct {
+	path    string
+	literal string
+	quoted  bool
+}
+
+func (a *Adapter) parseTemplate(s string) (template, error) {
+	var t template
+	for s != "" {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			t = append(t, segment{text: s})
+			break
+		}
+		if open > 0 {
+			t = append(t, segment{text: s[:open]})
+		}
+		end := strings.IndexByte(s[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed { in %q", s)
+		}
+		seg, err := a.parseExpression(s[open+1 : open+end])
+		if err != nil {
+			return nil, err
+		}
+		t = append(t, seg)
+		s = s[open+end+1:]
+	}
+	return t, nil
+}
+
+func (a *Adapter) parseExpression(expr string) (segment, error) {
+	var seg segment
+	rest := expr
+	for {
+		rest = strings.TrimLeft(rest, " ")
+		var alt alternative
+		if strings.HasPrefix(rest, "'") {
+			end := strings.IndexByte(rest[1:], '\'')
+			if end < 0 {
+				return seg, fmt.Errorf("unclosed quote in {%s}", expr)
+			}
+			alt = alternative{literal: rest[1 : end+1], quoted: true}
+			rest = rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, ",|")
+			if end < 0 {
+				end = len(rest)
+			}
+			alt.path = strings.TrimSpace(rest[:end])
+			if alt.path == "" {
+				return seg, fmt.Errorf("empty field path in {%s}", expr)
+			}
+			rest = rest[end:]
+		}
+		seg.alts = append(seg.alts, alt)
+		rest = strings.TrimLeft(rest, " ")
+		if strings.HasPrefix(rest, ",") {
+			rest = rest[1:]
+			continue
+		}
+		break
+	}
+	if rest != "" && rest[0] != '|' {
+		return seg, fmt.Errorf("unexpected %q in {%s}", rest, expr)
+	}
+	if rest != "" {
+		for _, f := range strings.Split(rest[1:], "|") {
+			f = strings.TrimSpace(f)
+			if _, ok := builtinFns[f]; !ok && a.Spec.Maps[f] == nil {
+				return seg, fmt.Errorf("unknown filter %q in {%s}", f, expr)
+			}
+			seg.filters = append(seg.filters, f)
+		}
+	}
+	return seg, nil
+}
+
+func (t template) render(a *Adapter, ev Event) string {
+	var b strings.Builder
+	for _, seg := range t {
+		if seg.alts == nil {
+			b.WriteString(seg.text)
+			continue
+		}
+		var v string
+		for _, alt := range seg.alts {
+			if alt.quoted {
+				v = alt.literal
+			} else {
+				v = lookupString(ev, alt.path)
+			}
+			if v != "" {
+				break
+			}
+		}
+		for _, f := range seg.filters {
+			if fn, ok := builtinFns[f]; ok {
+				v = fn(v)
+			} else if mapped, ok := a.Spec.Maps[f][v]; ok {
+				v = mapped
+			} else if mapped, ok := a.Spec.Maps[f]["*"]; ok {
+				v = mapped
+			}
+		}
+		b.WriteString(v)
+	}
+	return b.String()
+}
+
+// lookup follows a dotted path. Keys may contain dots themselves, as Zeek's id.orig_h
+// does, so the longest key that matches wins. Numeric parts index arrays.
+func lookup(v interface{}, p string) (interface{}, bool) {
+	switch x := v.(type) {
+	case Event:
+		return lookup(map[string]interface{}(x), p)
+	case map[string]interface{}:
+		if val, ok := x[p]; ok {
+			return val, true
+		}
+		for i := len(p) - 1; i > 0; i-- {
+			if p[i] != '.' {
+				continue
+			}
+			if val, ok := x[p[:i]]; ok {
+				if found, ok := lookup(val, p[i+1:]); ok {
+					return found, true
+				}
+			}
+		}
+	case []interface{}:
+		head, tail, _ := strings.Cut(p, ".")
+		if i, err := strconv.Atoi(head); err == nil && i >= 0 && i < len(x) {
+			if tail == "" {
+				return x[i], true
+			}
+			return lookup(x[i], tail)
+		}
+	}
+	return nil, false
+}
+
+// lookupString renders a field, arrays of plain values are joined with commas and
+// objects are compact JSON
+func lookupString(ev Event, p string) string {
+	v, ok := lookup(ev, p)
+	if !ok {
+		return ""
+	}
+	return valueString(v)
+}
+
+func valueString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	case bool:
+		return strconv.FormatBool(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, 0, len(x))
+		for _, item := range x {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				data, _ := json.Marshal(x)
+				return string(data)
+			}
+			parts = append(parts, valueString(item))
+		}
+		return strings.Join(parts, ", ")
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func baseName(s string) string {
+	if s == "" {
+		return ""
+	}
+	// URLs lose their query first
+	if i := strings.IndexAny(s, "?#"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimRight(strings.ReplaceAll(s, "\\", "/"), "/")
+	if s == "" {
+		return ""
+	}
+	return path.Base(s)
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700", // Suricata
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999", // OpenCanary
+}
+
+// ParseTime reads the timestamps of the supported sensors: RFC 3339 and its variants
+// without a colon in the offset or without a zone, which is taken as UTC, and Unix epoch
+// seconds with a fraction as Zeek writes them
+func ParseTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil && secs > 0 {
+		whole := int64(secs)
+		return time.Unix(whole, int64((secs-float64(whole))*1e9)).Round(time.Microsecond).UTC(), true
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,178 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// Follower reads the lines appended to a log file. It keeps reading a file that was
+// rotated away until its end and then moves on to the new file at the path, and starts
+// over when the file is truncated in place.
+type Follower struct {
+	Path   string
+	Offset int64 // Bytes of the current file consumed, always at a line boundary
+	Limit  int   // Lines one Poll reads at most, 0 for no limit
+
+	file     *os.File
+	info     os.FileInfo
+	partial  []byte
+	skipping bool
+}
+
+// Poll reads what was appended since the last call and passes each complete line,
+// without its newline, to fn. A line that is still being written waits for the next call.
+// more reports that Limit stopped it short of the end. Opening a file in the middle first
+// replays its leading # lines, so a decoder learns the fields of a Zeek log before its rows.
+func (f *Follower) Poll(fn func(line string)) (lines int, more bool, err error) {
+	if f.file == nil {
+		if err := f.open(fn); err != nil {
+			return 0, false, err
+		}
+	}
+	n, more, err := f.drain(fn, f.Limit)
+	if err != nil || more {
+		return n, more, err
+	}
+
+	current, statErr := os.Stat(f.Path)
+	switch {
+	case statErr != nil:
+		// Rotated away and not created again yet, or gone, the open file is all there is
+		if !errors.Is(statErr, os.ErrNotExist) {
+			return n, false, statErr
+		}
+	case !os.SameFile(f.info, current):
+		// The writer has moved on, what the old file still had was drained above
+		if len(f.partial) > 0 {
+			fn(string(f.partial))
+			n++
+		}
+		f.Close()
+		f.Offset = 0
+		return n, true, nil
+	case current.Size() < f.Offset:
+		// Truncated in place, as copytruncate does
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return n, false, err
+		}
+		f.Offset, f.partial, f.skipping = 0, nil, false
+		return n, true, nil
+	}
+	return n, false, nil
+}
+
+func (f *Follower) open(fn func(line string)) error {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if info.Size() < f.Offset {
+		// Replaced or truncated while nobody was reading
+		f.Offset = 0
+	}
+	if f.Offset > 0 {
+		replayHeaders(file, f.Offset, fn)
+	}
+	if _, err := file.Seek(f.Offset, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.info, f.partial, f.skipping = file, info, nil, false
+	return nil
+}
+
+func replayHeaders(file *os.File, limit int64, fn func(line string)) {
+	scanner := bufio.NewScanner(io.LimitReader(file, limit))
+	scanner.Buffer(make([]byte, 64*1024), MaxLineSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 || line[0] != '#' {
+			return
+		}
+		fn(line)
+	}
+}
+
+// drain reads to the end of the file or until limit lines were passed on, in which case
+// the file is put back at Offset to go on from there
+func (f *Follower) drain(fn func(line string), limit int) (n int, more bool, err error) {
+	buf := make([]byte, 64*1024)
+	for {
+		if limit > 0 && n >= limit {
+			if _, err := f.file.Seek(f.Offset+int64(len(f.partial)), io.SeekStart); err != nil {
+				return n, false, err
+			}
+			return n, true, nil
+		}
+		read, err := f.file.Read(buf)
+		data := buf[:read]
+		if f.skipping {
+			// The rest of a line over MaxLineSize
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				f.Offset += int64(len(data))
+				data = nil
+			} else {
+				f.Offset += int64(i) + 1
+				data = data[i+1:]
+				f.skipping = false
+			}
+		}
+		for len(data) > 0 && (limit <= 0 || n < limit) {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				break
+			}
+			line := data[:i]
+			data = data[i+1:]
+			if len(f.partial)+len(line) > MaxLineSize {
+				// Its end came in the same read that took it over the limit
+				f.Offset += int64(len(f.partial)+len(line)) + 1
+				f.partial = nil
+				continue
+			}
+			if len(f.partial) > 0 {
+				line = append(f.partial, line...)
+				f.partial = nil
+			}
+			f.Offset += int64(len(line)) + 1
+			fn(string(bytes.TrimSuffix(line, []byte("\r"))))
+			n++
+		}
+		if limit > 0 && n >= limit && len(data) > 0 {
+			// Read past the limit, these bytes come again after the seek back
+			continue
+		}
+		if len(f.partial)+len(data) > MaxLineSize {
+			// No decoder takes it, skip to the next line rather than buffer without end
+			f.Offset += int64(len(f.partial) + len(data))
+			f.partial, f.skipping = nil, true
+		} else {
+			f.partial = append(f.partial, data...)
+		}
+		if err == io.EOF {
+			return n, false, nil
+		}
+		if err != nil {
+			return n, false, err
+		}
+	}
+}
+
+// Close releases the file, the next Poll opens the path again at Offset
+func (f *Follower) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file, f.info, f.partial = nil, nil, nil
+	return err
+}
@@ -0,0 +1,182 @@
+package ingest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// poll runs Poll until it has nothing more to give right now
+func poll(t *testing.T, f *Follower) []string {
+	t.Helper()
+	var lines []string
+	for i := 0; ; i++ {
+		if i > 100 {
+			t.Fatal("Poll kept reporting more")
+		}
+		_, more, err := f.Poll(func(line string) { lines = append(lines, line) })
+		if err != nil {
+			t.Fatalf("Poll: %v", err)
+		}
+		if !more {
+			return lines
+		}
+	}
+}
+
+func expectLines(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		short := make([]string, len(got))
+		for i, line := range got {
+			if len(line) > 80 {
+				line = fmt.Sprintf("%s... (%d bytes)", line[:20], len(line))
+			}
+			short[i] = line
+		}
+		t.Fatalf("lines = %q, want %q", short, want)
+	}
+}
+
+func TestFollowerWaitsForCompleteLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeFile(t, path, "one\r\ntwo\nthr")
+	f := &Follower{Path: path}
+	defer f.Close()
+
+	expectLines(t, poll(t, f), "one", "two")
+	if f.Offset != int64(len("one\r\ntwo\n")) {
+		t.Fatalf("Offset = %d, want the end of the last complete line", f.Offset)
+	}
+	expectLines(t, poll(t, f))
+	appendFile(t, path, "ee\nfour\n")
+	expectLines(t, poll(t, f), "three", "four")
+}
+
+func TestFollowerLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeFile(t, path, "1\n2\n3\n4\n5\n")
+	f := &Follower{Path: path, Limit: 2}
+	defer f.Close()
+
+	var got []string
+	n, more, err := f.Poll(func(line string) { got = append(got, line) })
+	if err != nil || n != 2 || !more {
+		t.Fatalf("first Poll = %d, %v, %v, want 2 lines and more", n, more, err)
+	}
+	if f.Offset != 4 {
+		t.Fatalf("Offset = %d after two lines, want 4", f.Offset)
+	}
+	got = append(got, poll(t, f)...)
+	expectLines(t, got, "1", "2", "3", "4", "5")
+}
+
+func TestFollowerRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeFile(t, path, "a1\n")
+	f := &Follower{Path: path}
+	defer f.Close()
+	expectLines(t, poll(t, f), "a1")
+
+	// The writer still finishes its line in the old file after the rename
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path+".1", "a2\na3")
+	expectLines(t, poll(t, f), "a2")
+
+	writeFile(t, path, "b1\nb2\n")
+	expectLines(t, poll(t, f), "a3", "b1", "b2")
+	if f.Offset != int64(len("b1\nb2\n")) {
+		t.Fatalf("Offset = %d, want it counted in the new file", f.Offset)
+	}
+	appendFile(t, path, "b3\n")
+	expectLines(t, poll(t, f), "b3")
+}
+
+func TestFollowerTruncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeFile(t, path, "first line\nsecond line\n")
+	f := &Follower{Path: path}
+	defer f.Close()
+	expectLines(t, poll(t, f), "first line", "second line")
+
+	// copytruncate empties the file in place, the writer goes on at the start
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "new\n")
+	expectLines(t, poll(t, f), "new")
+	if f.Offset != 4 {
+		t.Fatalf("Offset = %d, want 4", f.Offset)
+	}
+}
+
+func TestFollowerReopenReplaysHeaders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conn.log")
+	head := "#separator \\x09\n#fields\tts\tuid\n"
+	writeFile(t, path, head+"1\tA\n2\tB\n")
+
+	// Opening in the middle, e.g. after a restart, replays the header before going on
+	f := &Follower{Path: path, Offset: int64(len(head + "1\tA\n"))}
+	defer f.Close()
+	expectLines(t, poll(t, f), "#separator \\x09", "#fields\tts\tuid", "2\tB")
+}
+
+func TestFollowerReopenAfterShrink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeFile(t, path, "x\n")
+	// Replaced by a shorter file while nobody was reading
+	f := &Follower{Path: path, Offset: 100}
+	defer f.Close()
+	expectLines(t, poll(t, f), "x")
+}
+
+func TestFollowerSkipsOverlongLines(t *testing.T) {
+	// Ending in the read that goes over the limit, and reads after it
+	for _, size := range []int{MaxLineSize + 10, MaxLineSize + 200<<10} {
+		path := filepath.Join(t.TempDir(), "app.log")
+		long := strings.Repeat("x", size)
+		writeFile(t, path, "before\n"+long+"\nafter\n")
+		f := &Follower{Path: path}
+
+		expectLines(t, poll(t, f), "before", "after")
+		if want := int64(len("before\n" + long + "\nafter\n")); f.Offset != want {
+			t.Fatalf("line of %d bytes: Offset = %d, want %d", size, f.Offset, want)
+		}
+		f.Close()
+	}
+}
+
+func TestFollowerMissingFile(t *testing.T) {
+	f := &Follower{Path: filepath.Join(t.TempDir(), "absent.log")}
+	if _, _, err := f.Poll(func(string) {}); !os.IsNotExist(err) {
+		t.Fatalf("Poll of a missing file = %v, want a not exist error", err)
+	}
+}
@@ -0,0 +1,241 @@
+// Package metrics writes the Prometheus text exposition format and keeps the few
+// counters and histograms the server and the probe export. Gauges are read at scrape
+// time and written directly.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	textContentType        = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Negotiate picks OpenMetrics when the scraper asks for it and the classic text format otherwise
+func Negotiate(accept string) (contentType string, openMetrics bool) {
+	for _, part := range strings.Split(accept, ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && mediaType == "application/openmetrics-text" {
+			return openMetricsContentType, true
+		}
+	}
+	return textContentType, false
+}
+
+// Writer emits metric families. Samples must follow the header of their family.
+type Writer struct {
+	w           io.Writer
+	openMetrics bool
+}
+
+func NewWriter(w io.Writer, openMetrics bool) *Writer {
+	return &Writer{w: w, openMetrics: openMetrics}
+}
+
+// Header starts a family, kind is counter, gauge or histogram. OpenMetrics names counter
+// families without the _total suffix of their samples.
+func (w *Writer) Header(name, kind, help string) {
+	if w.openMetrics && kind == "counter" {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	fmt.Fprintf(w.w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+}
+
+// Sample writes one series, labels are name, value pairs
+func (w *Writer) Sample(name string, value float64, labels ...string) {
+	io.WriteString(w.w, name)
+	if len(labels) > 0 {
+		io.WriteString(w.w, "{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				io.WriteString(w.w, ",")
+			}
+			fmt.Fprintf(w.w, `%s="%s"`, labels[i], escapeLabel(labels[i+1]))
+		}
+		io.WriteString(w.w, "}")
+	}
+	io.WriteString(w.w, " "+formatValue(value)+"\n")
+}
+
+// Gauge writes a family with a single unlabeled sample
+func (w *Writer) Gauge(name, help string, value float64) {
+	w.Header(name, "gauge", help)
+	w.Sample(name, value)
+}
+
+// Close ends the exposition, OpenMetrics requires an explicit end marker
+func (w *Writer) Close() {
+	if w.openMetrics {
+		io.WriteString(w.w, "# EOF\n")
+	}
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Bool renders a flag as a gauge value
+func Bool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// seriesKey joins label values, the separator can not appear in UTF-8 text
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+func labelPairs(names, values []string) []string {
+	pairs := make([]string, 0, 2*len(names))
+	for i, name := range names {
+		pairs = append(pairs, name, values[i])
+	}
+	return pairs
+}
+
+// CounterVec is a counter family partitioned by label values
+type CounterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	series     map[string]*counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	value  float64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{name: name, help: help, labels: labels, series: map[string]*counterSeries{}}
+}
+
+// Add increases the series for the label values, which must match the label names in order
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if len(values) != len(c.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", c.name, len(c.labels), len(values)))
+	}
+	key := seriesKey(values)
+	c.mu.Lock()
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{values: append([]string(nil), values...)}
+		c.series[key] = s
+	}
+	s.value += delta
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Delete drops the series for the label values
+func (c *CounterVec) Delete(values ...string) {
+	c.mu.Lock()
+	delete(c.series, seriesKey(values))
+	c.mu.Unlock()
+}
+
+// Write emits the family in a stable order, a family without series still gets its header
+func (c *CounterVec) Write(w *Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w.Header(c.name, "counter", c.help)
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		w.Sample(c.name, s.value, labelPairs(c.labels, s.values)...)
+	}
+}
+
+// HistogramVec counts observations into cumulative buckets per label values
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	series     map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64 // Per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec takes the upper bounds of the buckets in increasing order, +Inf is implied
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*histogramSeries{}}
+}
+
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	if len(values) != len(h.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", h.name, len(h.labels), len(values)))
+	}
+	key := seriesKey(values)
+	h.mu.Lock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{values: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+	h.mu.Unlock()
+}
+
+func (h *HistogramVec) Write(w *Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w.Header(h.name, "histogram", h.help)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		labels := labelPairs(h.labels, s.values)
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			w.Sample(h.name+"_bucket", float64(cumulative), append(labels, "le", formatValue(bound))...)
+		}
+		w.Sample(h.name+"_bucket", float64(s.count), append(labels, "le", "+Inf")...)
+		w.Sample(h.name+"_sum", s.sum, labels...)
+		w.Sample(h.name+"_count", float64(s.count), labels...)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
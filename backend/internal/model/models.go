@@ -18,35 +18,39 @@ type User struct {
 
 type AttackLog struct {
 	ID        string    `json:"id" gorm:"primaryKey"`
-	Timestamp time.Time `json:"timestamp"`
-	SourceIP  string    `json:"sourceIp"`
+	Timestamp time.Time `json:"timestamp" gorm:"index;index:idx_attack_severity_time,priority:2"`
+	SourceIP  string    `json:"sourceIp" gorm:"index"`
 	Location  string    `json:"location"`
 	Method    string    `json:"method"`
 	Payload   string    `json:"payload"`
-	Severity  string    `json:"severity"` // low, medium, high, critical
-	Status    string    `json:"status"`   // blocked, monitored, compromised
+	Severity  string    `json:"severity" gorm:"index:idx_attack_severity_time,priority:1"` // low, medium, high, critical
+	Status    string    `json:"status"`                                                    // blocked, monitored, compromised
+	Node      string    `json:"node,omitempty" gorm:"index"`                               // Reporting probe, its clock drift is corrected in Timestamp
+	Sensor    string    `json:"sensor,omitempty" gorm:"index"`                             // Other sensor whose log it came from, adapter/name
 }
 
 type AttackSource struct {
-	ID          string `json:"id" gorm:"primaryKey"`
-	IP          string `json:"ip"`
-	Country     string `json:"country"`
-	Verdict     string `json:"verdict"` // unknown, low, medium, high
-	AttackCount int    `json:"attackCount"`
-	ScanCount   int    `json:"scanCount"`
-	Nodes       string `json:"nodes"`     // Store as comma-separated or JSON
-	FirstTime   string `json:"firstTime"` // Format: 2025/11/23 23:19:27
-	Tags        string `json:"tags"`      // Store as comma-separated or JSON
+	ID          string    `json:"id" gorm:"primaryKey"`
+	IP          string    `json:"ip" gorm:"index"`
+	Country     string    `json:"country"`
+	Verdict     string    `json:"verdict"` // unknown, low, medium, high
+	AttackCount int       `json:"attackCount"`
+	ScanCount   int       `json:"scanCount"`
+	Nodes       string    `json:"nodes"` // Store as comma-separated or JSON
+	FirstTime   time.Time `json:"firstTime" gorm:"index"`
+	Tags        string    `json:"tags"` // Store as comma-separated or JSON
 }
 
+// AccountCredential is unique per (service, username, password, ip), repeats bump Count and Time
 type AccountCredential struct {
-	ID       string `json:"id" gorm:"primaryKey"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Service  string `json:"service"`
-	Count    int    `json:"count"`
-	IP       string `json:"ip"`
-	Time     string `json:"time"`
+	ID       string    `json:"id" gorm:"primaryKey"`
+	Username string    `json:"username" gorm:"uniqueIndex:idx_credential_key,priority:2"`
+	Password string    `json:"password" gorm:"uniqueIndex:idx_credential_key,priority:3"`
+	Service  string    `json:"service" gorm:"uniqueIndex:idx_credential_key,priority:1"`
+	Count    int       `json:"count"`
+	IP       string    `json:"ip" gorm:"uniqueIndex:idx_credential_key,priority:4;index:idx_credential_ip"`
+	Time     time.Time `json:"time" gorm:"index"`
+	Sensor   string    `json:"sensor,omitempty"` // Other sensor that saw the last attempt, adapter/name
 }
 
 type NodeStatus struct {
@@ -71,15 +75,253 @@ type NodeStatus struct {
 	FirewallStatus string  `json:"firewallStatus"` // active, inactive, error
 	FirewallError  string  `json:"firewallError"`
 	FirewallInfo   string  `json:"firewallInfo"`
+	RuleVersion    int64   `json:"ruleVersion"` // Access rule version applied by the probe
+	Groups         string  `json:"groups"`      // Comma-separated group names used to target access rules, e.g. internet-facing
+	// Clock is the local time of the probe when it sent the report, it is not stored
+	Clock          *time.Time `json:"clock,omitempty" gorm:"-"`
+	ClockOffset    int64      `json:"clockOffset"` // Milliseconds the probe clock is ahead of the server
+	ClockCheckedAt *time.Time `json:"clockCheckedAt"`
+	ClockDrift     bool       `json:"clockDrift"` // Offset beyond the drift threshold of the NTP settings
 }
 
 type Message struct {
-	ID      string    `json:"id" gorm:"primaryKey"`
-	Title   string    `json:"title"`
-	Content string    `json:"content"`
-	Time    time.Time `json:"time"`
-	Type    string    `json:"type"` // system, security, report
-	Read    bool      `json:"read"`
+	ID       string    `json:"id" gorm:"primaryKey"`
+	Title    string    `json:"title"`
+	Content  string    `json:"content"`
+	Time     time.Time `json:"time" gorm:"index"`
+	Type     string    `json:"type"`                           // system, security, report
+	Severity string    `json:"severity" gorm:"default:'info'"` // info, warning, critical
+
+	// Alert state. Repeats with the same fingerprint inside the window fold into the message.
+	Fingerprint    string     `json:"fingerprint" gorm:"index"`
+	Node           string     `json:"node" gorm:"index"` // Node ID the alert is about, silences match it
+	Count          int        `json:"count" gorm:"default:1"`
+	Sources        string     `json:"sources"` // Distinct sources of a group, comma-separated, capped
+	LastSeen       time.Time  `json:"lastSeen" gorm:"index"`
+	Status         string     `json:"status" gorm:"default:'firing';index"` // firing, acknowledged, resolved
+	Assignee       string     `json:"assignee" gorm:"index"`
+	AcknowledgedBy string     `json:"acknowledgedBy"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt"`
+	ResolvedBy     string     `json:"resolvedBy"` // system when a later alert ended it
+	ResolvedAt     *time.Time `json:"resolvedAt"`
+}
+
+// MessageState is what one user did with a message, without a row it is unread and in the inbox
+type MessageState struct {
+	MessageID  string     `json:"messageId" gorm:"primaryKey"`
+	Username   string     `json:"username" gorm:"primaryKey;index"`
+	Read       bool       `json:"read"`
+	ReadAt     *time.Time `json:"readAt"`
+	Archived   bool       `json:"archived"` // Dismissed from the inbox, the message stays for everyone else
+	ArchivedAt *time.Time `json:"archivedAt"`
+}
+
+// MessageSubscription picks the messages a user sees and gets pushed, without a row it is all of them
+type MessageSubscription struct {
+	Username    string    `json:"username" gorm:"primaryKey"`
+	Types       string    `json:"types"`       // Comma-separated message types, empty for all
+	MinSeverity string    `json:"minSeverity"` // info, warning, critical
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// AlertSilence keeps alerts out of the message center and the notification channels for
+// a maintenance window
+type AlertSilence struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	Node       string    `json:"node" gorm:"index"` // Node ID, empty for every node
+	Event      string    `json:"event"`             // Event kind such as node_offline, empty for every alert
+	Reason     string    `json:"reason"`
+	StartsAt   time.Time `json:"startsAt"`
+	EndsAt     time.Time `json:"endsAt" gorm:"index"`
+	CreatedBy  string    `json:"createdBy"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Suppressed int       `json:"suppressed"` // Alerts it kept back
+}
+
+// NotificationChannel is a destination for alerts. Settings holds the JSON settings of
+// its type, the secrets in it are never returned by the API.
+type NotificationChannel struct {
+	ID            string    `json:"id" gorm:"primaryKey"`
+	Name          string    `json:"name" gorm:"uniqueIndex"`
+	Type          string    `json:"type"` // webhook, email, slack, dingtalk, feishu, wecom
+	Enabled       bool      `json:"enabled"`
+	Settings      string    `json:"-"`
+	Lang          string    `json:"lang"`          // en, zh, language of the built-in texts
+	TitleTemplate string    `json:"titleTemplate"` // text/template, empty for the default
+	BodyTemplate  string    `json:"bodyTemplate"`
+	MaxAttempts   int       `json:"maxAttempts"` // Sends before a delivery is given up
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// SyslogDestination is a SIEM collector that events are forwarded to over syslog. Events
+// wait in an on-disk spool per destination until the collector took them.
+type SyslogDestination struct {
+	ID                 string    `json:"id" gorm:"primaryKey"`
+	Name               string    `json:"name" gorm:"uniqueIndex"`
+	Enabled            bool      `json:"enabled"`
+	Host               string    `json:"host"`
+	Port               int       `json:"port"`
+	Protocol           string    `json:"protocol"`    // udp, tcp, tls
+	Format             string    `json:"format"`      // cef, leef, json (RFC 5424 with a JSON body)
+	Framing            string    `json:"framing"`     // tcp and tls: octet (RFC 6587 octet counting) or newline
+	Facility           int       `json:"facility"`    // Syslog facility, 16 is local0
+	Kinds              string    `json:"kinds"`       // Comma-separated event kinds, empty for all
+	MinSeverity        string    `json:"minSeverity"` // info, low, medium, high, critical
+	CACert             string    `json:"caCert"`      // PEM, trusted besides the system roots
+	InsecureSkipVerify bool      `json:"insecureSkipVerify"`
+	MaxQueueMB         int       `json:"maxQueueMb"` // Spool limit, the oldest events are dropped past it
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+// OpenSearchOutput bulk indexes events into an OpenSearch or Elasticsearch cluster, one
+// index per kind and day. Like syslog destinations it queues through an on-disk spool.
+type OpenSearchOutput struct {
+	ID                 string    `json:"id" gorm:"primaryKey"`
+	Name               string    `json:"name" gorm:"uniqueIndex"`
+	Enabled            bool      `json:"enabled"`
+	URL                string    `json:"url"` // Base URL of the cluster, e.g. https://localhost:9200
+	Username           string    `json:"username"`
+	Password           string    `json:"password"`
+	APIKey             string    `json:"apiKey"`      // Base64 id:key, used instead of basic auth
+	IndexPrefix        string    `json:"indexPrefix"` // Indexes are <prefix>-<kind>-yyyy.mm.dd
+	Kinds              string    `json:"kinds"`       // Comma-separated event kinds, empty for all
+	MinSeverity        string    `json:"minSeverity"`
+	BatchSize          int       `json:"batchSize"`    // Documents per bulk request
+	FlushSeconds       int       `json:"flushSeconds"` // A smaller batch goes out after this long
+	SkipTemplate       bool      `json:"skipTemplate"` // The index template is installed by hand
+	CACert             string    `json:"caCert"`
+	InsecureSkipVerify bool      `json:"insecureSkipVerify"`
+	MaxQueueMB         int       `json:"maxQueueMb"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+// EventBusOutput publishes events and node status changes to a Kafka topic or a NATS
+// subject for downstream pipelines, queued through an on-disk spool like the other outputs
+type EventBusOutput struct {
+	ID                 string    `json:"id" gorm:"primaryKey"`
+	Name               string    `json:"name" gorm:"uniqueIndex"`
+	Enabled            bool      `json:"enabled"`
+	Type               string    `json:"type"`     // kafka, nats
+	Servers            string    `json:"servers"`  // Comma-separated Kafka bootstrap brokers or NATS URLs
+	Topic              string    `json:"topic"`    // Kafka topic or NATS subject, {type} is replaced by the event type
+	Encoding           string    `json:"encoding"` // json, protobuf
+	Kinds              string    `json:"kinds"`    // Comma-separated event types, node for status changes, empty for all
+	MinSeverity        string    `json:"minSeverity"`
+	JetStream          bool      `json:"jetStream"` // NATS: wait for a stream to store every message
+	Username           string    `json:"username"`  // Kafka SASL PLAIN or NATS user
+	Password           string    `json:"password"`
+	Token              string    `json:"token"` // NATS auth token
+	TLS                bool      `json:"tls"`
+	CACert             string    `json:"caCert"`
+	InsecureSkipVerify bool      `json:"insecureSkipVerify"`
+	MaxQueueMB         int       `json:"maxQueueMb"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+// IngestAdapter maps the log format of another sensor onto records. Spec is the JSON of
+// an ingest.Spec, a stored adapter with the name of a built-in one replaces it.
+type IngestAdapter struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex"`
+	Spec      string    `json:"spec"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// IngestTail follows a log file of another sensor through an adapter. Offset is saved
+// as lines are read, so a restart goes on where it stopped.
+type IngestTail struct {
+	ID          string     `json:"id" gorm:"primaryKey"`
+	Path        string     `json:"path" gorm:"uniqueIndex"`
+	Adapter     string     `json:"adapter"`
+	Sensor      string     `json:"sensor"` // Sensor name when the log does not carry one
+	Enabled     bool       `json:"enabled"`
+	FromStart   bool       `json:"fromStart"` // Read what the file already holds, otherwise only new lines
+	Offset      int64      `json:"offset"`
+	Events      int64      `json:"events"`
+	Records     int64      `json:"records"`
+	Failed      int64      `json:"failed"`
+	LastError   string     `json:"lastError"`
+	LastErrorAt *time.Time `json:"lastErrorAt"`
+	LastReadAt  *time.Time `json:"lastReadAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// IngestKey lets one sensor post to the ingest API. The key is prts_<KeyID>_<Secret>,
+// sent as a bearer token or used to sign requests with HMAC-SHA256. Every record it
+// posts is tagged with Sensor.
+type IngestKey struct {
+	ID               string     `json:"id" gorm:"primaryKey"`
+	Name             string     `json:"name"`
+	KeyID            string     `json:"keyId" gorm:"uniqueIndex"` // Public part, names the key in signed requests
+	Secret           string     `json:"-"`
+	Sensor           string     `json:"sensor"`
+	Enabled          bool       `json:"enabled"`
+	RequireSignature bool       `json:"requireSignature"` // Refuse bearer tokens, only signed requests
+	RateLimit        int        `json:"rateLimit"`        // Events per minute
+	Burst            int        `json:"burst"`            // Events at once, 0 for a minute's worth
+	ExpiresAt        *time.Time `json:"expiresAt"`
+	Accepted         int64      `json:"accepted"`
+	Rejected         int64      `json:"rejected"`
+	Throttled        int64      `json:"throttled"`
+	LastUsedAt       *time.Time `json:"lastUsedAt"`
+	LastUsedIP       string     `json:"lastUsedIp"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+}
+
+// IngestRejection is an event the ingest API refused, kept with the reason so the sender
+// can be fixed. Payload is the event as posted, cut at a size limit.
+type IngestRejection struct {
+	ID       string    `json:"id" gorm:"primaryKey"` // ULID
+	Time     time.Time `json:"time" gorm:"index"`
+	Endpoint string    `json:"endpoint" gorm:"index"` // attack, credential, logs/<adapter>
+	KeyID    string    `json:"keyId" gorm:"index"`
+	Sensor   string    `json:"sensor"`
+	RemoteIP string    `json:"remoteIp"`
+	Index    int       `json:"index"` // Position of the event in its batch, from 1
+	Error    string    `json:"error"`
+	Payload  string    `json:"payload"`
+}
+
+// NotificationRule routes events to channels. Every enabled rule that matches applies, a
+// channel is notified once per event.
+type NotificationRule struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name"`
+	Enabled     bool      `json:"enabled"`
+	MinSeverity string    `json:"minSeverity"` // info, low, medium, warning, high, critical
+	Events      string    `json:"events"`      // Comma-separated event kinds or message types, empty for all
+	Channels    string    `json:"channels"`    // Comma-separated channel IDs
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// NotificationDelivery is one event sent to one channel. The table is the delivery log
+// and the retry queue, pending rows are sent once NextAttempt has passed.
+type NotificationDelivery struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	EventID     string     `json:"eventId" gorm:"index"`
+	Event       string     `json:"event"` // Event kind, e.g. node_offline, test for channel tests
+	Severity    string     `json:"severity"`
+	ChannelID   string     `json:"channelId" gorm:"index"`
+	ChannelName string     `json:"channelName"`
+	ChannelType string     `json:"channelType"`
+	RuleID      string     `json:"ruleId"`
+	Title       string     `json:"title"` // Rendered with the channel's templates
+	Body        string     `json:"body"`
+	Data        string     `json:"-"`                                               // The event as JSON, the payload of webhooks
+	Status      string     `json:"status" gorm:"index:idx_delivery_due,priority:1"` // pending, sent, failed
+	Attempts    int        `json:"attempts"`
+	NextAttempt *time.Time `json:"nextAttempt" gorm:"index:idx_delivery_due,priority:2"`
+	LastError   string     `json:"lastError"`
+	StatusCode  int        `json:"statusCode"` // HTTP status of the last attempt, SMTP reply code for email
+	CreatedAt   time.Time  `json:"createdAt" gorm:"index"`
+	SentAt      *time.Time `json:"sentAt"`
 }
 
 type SystemConfig struct {
@@ -137,56 +379,96 @@ type LoginAttempt struct {
 }
 
 type ScanLog struct {
-	ID       string `json:"id" gorm:"primaryKey"`
-	IP       string `json:"ip"`
-	Threat   string `json:"threat"` // Malicious, High Risk, Suspicious, Low
-	Node     string `json:"node"`
-	Location string `json:"location"`
-	Type     string `json:"type"` // TCP, UDP, ICMP, etc.
-	Count    int    `json:"count"`
-	Ports    string `json:"ports"`
-	Start    string `json:"start"`
-	Duration string `json:"duration"`
+	ID       string    `json:"id" gorm:"primaryKey"`
+	IP       string    `json:"ip" gorm:"index"`
+	Threat   string    `json:"threat"` // Malicious, High Risk, Suspicious, Low
+	Node     string    `json:"node" gorm:"index"`
+	Location string    `json:"location"`
+	Type     string    `json:"type"` // TCP, UDP, ICMP, etc.
+	Count    int       `json:"count"`
+	Ports    string    `json:"ports"`
+	Start    time.Time `json:"start" gorm:"index"`
+	Duration string    `json:"duration"`
+	Sensor   string    `json:"sensor,omitempty" gorm:"index"` // Other sensor whose log it came from, adapter/name
+}
+
+// InventoryAccount is a real account of the organization, passwords are only ever kept as salted hashes
+type InventoryAccount struct {
+	ID           string     `json:"id" gorm:"primaryKey"`
+	Username     string     `json:"username" gorm:"uniqueIndex"` // Lowercased
+	DisplayName  string     `json:"displayName"`
+	Email        string     `json:"email" gorm:"index"` // Lowercased
+	Source       string     `json:"source"`             // csv, ldap
+	HashType     string     `json:"hashType"`           // bcrypt, sha256, sha, ssha, ssha256, ssha512, empty without hash
+	PasswordHash string     `json:"-"`
+	Salt         string     `json:"-"` // Hex encoded
+	ImportTime   time.Time  `json:"importTime"`
+	HitCount     int        `json:"hitCount"`
+	LastHit      *time.Time `json:"lastHit"` // null until the first match
 }
 
 type DecoyLog struct {
-	ID         string `json:"id" gorm:"primaryKey"`
-	Type       string `json:"type"`
-	Status     string `json:"status"`
-	Device     string `json:"device"`
-	SourceIP   string `json:"sourceIp"`
-	Time       string `json:"time"`
-	Result     string `json:"result"`
-	DecoyName  string `json:"decoyName"`
-	DeployTime string `json:"deployTime"`
-	Node       string `json:"node"`
+	ID         string    `json:"id" gorm:"primaryKey"`
+	Type       string    `json:"type"`   // File, SSHKey, BrowserPassword, AWSCredentials, Registry, Process, Document, CanaryToken
+	Status     string    `json:"status"` // Deploying, Active, Failed, Compromised, Removed
+	Device     string    `json:"device"`
+	SourceIP   string    `json:"sourceIp" gorm:"index"`
+	Time       time.Time `json:"time" gorm:"index"`
+	Result     string    `json:"result"`
+	DecoyName  string    `json:"decoyName"`
+	DeployTime time.Time `json:"deployTime"`
+	Node       string    `json:"node"`
+	DecoyID    string    `json:"decoyId" gorm:"index"` // Set on trigger events, points to the deployment row
+	Path       string    `json:"path"`
+	Process    string    `json:"process"`
+	Detail     string    `json:"detail"`
+	BaitUser   string    `json:"baitUser"`
+	BaitSecret string    `json:"baitSecret"`
+	UserAgent  string    `json:"userAgent"`
+}
+
+type CanaryToken struct {
+	ID            string     `json:"id" gorm:"primaryKey"`
+	Token         string     `json:"token" gorm:"uniqueIndex"`
+	Type          string     `json:"type"`                 // http, dns, docx, pdf, aws
+	DecoyID       string     `json:"decoyId" gorm:"index"` // Decoy that embeds this token
+	Memo          string     `json:"memo"`
+	URL           string     `json:"url"`
+	Hostname      string     `json:"hostname"`
+	AccessKeyID   string     `json:"accessKeyId" gorm:"index"`
+	SecretKey     string     `json:"secretKey"`
+	Status        string     `json:"status"` // active, disabled
+	CreateTime    time.Time  `json:"createTime"`
+	TriggerCount  int        `json:"triggerCount"`
+	LastTriggered *time.Time `json:"lastTriggered"`
 }
 
 type SampleLog struct {
-	ID           string `json:"id" gorm:"primaryKey"`
-	FileName     string `json:"fileName"`
-	FileSize     string `json:"fileSize"`
-	FileType     string `json:"fileType"`
-	ThreatLevel  string `json:"threatLevel"` // malicious, suspicious, safe, unknown
-	Status       string `json:"status"`      // completed, analyzing, queued
-	CaptureCount int    `json:"captureCount"`
-	LastTime     string `json:"lastTime"`
-	AttackerIP   string `json:"attackerIp"`
-	SourceNode   string `json:"sourceNode"`
-	SHA256       string `json:"sha256"`
+	ID           string    `json:"id" gorm:"primaryKey"`
+	FileName     string    `json:"fileName"`
+	FileSize     string    `json:"fileSize"`
+	FileType     string    `json:"fileType"`
+	ThreatLevel  string    `json:"threatLevel"` // malicious, suspicious, safe, unknown
+	Status       string    `json:"status"`      // completed, analyzing, queued
+	CaptureCount int       `json:"captureCount"`
+	LastTime     time.Time `json:"lastTime" gorm:"index"`
+	AttackerIP   string    `json:"attackerIp" gorm:"index"`
+	SourceNode   string    `json:"sourceNode"`
+	SHA256       string    `json:"sha256" gorm:"index"`
+	Sensor       string    `json:"sensor,omitempty"` // Other sensor that captured it last, adapter/name
 }
 
 type VulnRule struct {
-	ID          string `json:"id" gorm:"primaryKey"`
-	Name        string `json:"name"`
-	Type        string `json:"type"`
-	Severity    string `json:"severity"` // low, medium, high
-	HitCount    int    `json:"hitCount"`
-	LastHitTime string `json:"lastHitTime"`
-	Creator     string `json:"creator"`
-	Status      string `json:"status"` // active, inactive
-	UpdateTime  string `json:"updateTime"`
-	Updater     string `json:"updater"`
+	ID          string     `json:"id" gorm:"primaryKey"`
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Severity    string     `json:"severity"` // low, medium, high
+	HitCount    int        `json:"hitCount"`
+	LastHitTime *time.Time `json:"lastHitTime"`
+	Creator     string     `json:"creator"`
+	Status      string     `json:"status"` // active, inactive
+	UpdateTime  time.Time  `json:"updateTime"`
+	Updater     string     `json:"updater"`
 }
 
 type TrafficRule struct {
@@ -208,34 +490,132 @@ type DefenseStrategy struct {
 }
 
 type AccessControlRule struct {
-	ID         string `json:"id" gorm:"primaryKey"`
-	IP         string `json:"ip"`
-	Type       string `json:"type"` // blacklist, whitelist
-	Reason     string `json:"reason"`
-	Source     string `json:"source"`
-	ExpireTime string `json:"expireTime"`
-	AddTime    string `json:"addTime"`
-	Status     string `json:"status"`
+	ID         string     `json:"id" gorm:"primaryKey"`
+	IP         string     `json:"ip"`                         // Address, CIDR block or start-end range, IPv4 or IPv6
+	Type       string     `json:"type"`                       // blacklist, whitelist
+	Protocol   string     `json:"protocol"`                   // any, tcp, udp, icmp
+	Ports      string     `json:"ports"`                      // e.g. 22,80,8000-8100, empty for all ports
+	Direction  string     `json:"direction"`                  // inbound, outbound, both
+	Scope      string     `json:"scope" gorm:"default:'all'"` // all, nodes, groups
+	Targets    string     `json:"targets"`                    // Comma-separated node IDs or group names, per Scope
+	Reason     string     `json:"reason"`
+	Source     string     `json:"source"`
+	ExpireTime *time.Time `json:"expireTime" gorm:"index"` // null for permanent rules
+	AddTime    time.Time  `json:"addTime"`
+	Status     string     `json:"status"`
+}
+
+// AccessRuleChange logs every change to the access rules, Version is the rule set version it produced
+type AccessRuleChange struct {
+	Version int64     `json:"version" gorm:"primaryKey;autoIncrement"`
+	Op      string    `json:"op"` // add, update, remove, reset
+	RuleID  string    `json:"ruleId"`
+	Time    time.Time `json:"time"`
+}
+
+// BlocklistSubscription is an external list of addresses reconciled into access rules,
+// the rules it owns carry its Name as Source
+type BlocklistSubscription struct {
+	ID              string     `json:"id" gorm:"primaryKey"`
+	Name            string     `json:"name" gorm:"uniqueIndex"`
+	URL             string     `json:"url"`             // http(s) source, or
+	Path            string     `json:"path"`            // a local file on the server
+	Format          string     `json:"format"`          // plain, spamhaus, csv
+	RuleType        string     `json:"ruleType"`        // blacklist, whitelist
+	Scope           string     `json:"scope"`           // Scope of the generated rules: all, nodes, groups
+	Targets         string     `json:"targets"`         // Comma-separated node IDs or group names
+	RefreshInterval int        `json:"refreshInterval"` // Minutes between fetches
+	RuleTTL         int        `json:"ruleTtl"`         // Minutes a rule outlives the last successful fetch, 0 for permanent
+	MaxEntries      int        `json:"maxEntries"`      // Fetches with more entries are refused
+	Status          string     `json:"status"`          // pending, active, paused
+	EntryCount      int        `json:"entryCount"`
+	LastFetch       *time.Time `json:"lastFetch"`
+	LastSuccess     *time.Time `json:"lastSuccess"`
+	LastError       string     `json:"lastError"`
+	PreviewedAt     *time.Time `json:"previewedAt"` // null until previewed
+	ETag            string     `json:"-" gorm:"column:etag"`
+	LastModified    string     `json:"-"`
+	CreatedAt       time.Time  `json:"createdAt"`
 }
 
 type LoginLog struct {
-	ID       uint   `json:"id" gorm:"primaryKey"`
-	Username string `json:"username"`
-	Time     string `json:"time"`
-	IP       string `json:"ip"`
-	Status   string `json:"status"` // success, failure
-	Device   string `json:"device"`
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	Username string    `json:"username" gorm:"index"`
+	Time     time.Time `json:"time" gorm:"index"`
+	IP       string    `json:"ip" gorm:"index"`
+	Status   string    `json:"status"` // success, failure
+	Device   string    `json:"device"`
+}
+
+// TimeSyncLog is one NTP sync round over the configured servers
+type TimeSyncLog struct {
+	ID      uint      `json:"id" gorm:"primaryKey"`
+	Time    time.Time `json:"time" gorm:"index"`
+	Trigger string    `json:"trigger"` // manual, schedule
+	Status  string    `json:"status"`  // synchronized, failed
+	Offset  float64   `json:"offset"`  // Seconds, the offset in effect after the round
+	Servers int       `json:"servers"`
+	Used    int       `json:"used"` // Servers left after outliers were rejected
+	Error   string    `json:"error"`
+	Results string    `json:"results"` // JSON list of the answer of every server
+}
+
+// NodeMetric sums the telemetry a node reported within one bucket of a tier, averages
+// are the sums divided by the samples
+type NodeMetric struct {
+	ID          uint      `json:"-" gorm:"primaryKey"`
+	NodeID      string    `json:"nodeId" gorm:"uniqueIndex:idx_node_metric,priority:1"`
+	Tier        string    `json:"tier" gorm:"uniqueIndex:idx_node_metric,priority:2"` // 10s, 5m, 1h
+	Bucket      time.Time `json:"bucket" gorm:"uniqueIndex:idx_node_metric,priority:3"`
+	Samples     int       `json:"samples"`
+	Load        float64   `json:"load"` // CPU percent
+	LoadMax     float64   `json:"loadMax"`
+	Memory      float64   `json:"memory"` // Percent in use
+	MemoryMax   float64   `json:"memoryMax"`
+	Temperature float64   `json:"temperature"`
+	TempSamples int       `json:"tempSamples"` // Reports with a temperature, probes without a sensor send 0
+	NetUp       float64   `json:"netUp"`       // MB/s
+	NetUpMax    float64   `json:"netUpMax"`
+	NetDown     float64   `json:"netDown"`
+	NetDownMax  float64   `json:"netDownMax"`
+}
+
+// AttackRollup counts the attacks of one dimension value in one time bucket. Buckets are
+// added to on ingest, so trends never scan the attack log and outlive its retention.
+type AttackRollup struct {
+	ID         uint      `json:"-" gorm:"primaryKey"`
+	Resolution string    `json:"resolution" gorm:"uniqueIndex:idx_rollup_key,priority:1"` // minute, hour, day
+	Bucket     time.Time `json:"bucket" gorm:"uniqueIndex:idx_rollup_key,priority:2"`     // UTC start of the bucket
+	Dimension  string    `json:"dimension" gorm:"uniqueIndex:idx_rollup_key,priority:3"`  // total, service, severity, node, country
+	Value      string    `json:"value" gorm:"uniqueIndex:idx_rollup_key,priority:4"`
+	Count      int64     `json:"count"`
+}
+
+// SearchDocument is the searchable text of an attack, credential or sample. The full-text
+// index reads its columns, so it is written together with the record it points to.
+type SearchDocument struct {
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	Kind     string    `json:"kind" gorm:"uniqueIndex:idx_search_ref,priority:1"` // attack, credential, sample
+	RefID    string    `json:"refId" gorm:"uniqueIndex:idx_search_ref,priority:2"`
+	IP       string    `json:"ip" gorm:"index"`
+	Time     time.Time `json:"time" gorm:"index"`
+	Payload  string    `json:"payload"` // Attack payloads that are neither commands nor requests
+	Command  string    `json:"command"` // Shell session commands
+	Body     string    `json:"body"`    // HTTP requests
+	Username string    `json:"username"`
+	Password string    `json:"password"`
+	FileName string    `json:"fileName"`
 }
 
 type Report struct {
-	ID         string `json:"id" gorm:"primaryKey"`
-	Name       string `json:"name"`
-	Module     string `json:"module"`
-	Type       string `json:"type"` // daily, weekly, custom
-	Size       string `json:"size"`
-	Status     string `json:"status"` // success, generating, failed
-	Creator    string `json:"creator"`
-	CreateTime string `json:"createTime"`
+	ID         string    `json:"id" gorm:"primaryKey"`
+	Name       string    `json:"name"`
+	Module     string    `json:"module"`
+	Type       string    `json:"type"` // daily, weekly, custom
+	Size       string    `json:"size"`
+	Status     string    `json:"status"` // success, generating, failed
+	Creator    string    `json:"creator"`
+	CreateTime time.Time `json:"createTime"`
 }
 
 type LoginRequest struct {
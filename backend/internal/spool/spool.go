@@ -0,0 +1,370 @@
+// Package spool is a first-in first-out queue of records kept on disk. Records are
+// appended to segment files and read back in order, a cursor file remembers what the
+// consumer committed. Outputs queue through it so a receiver that is down, or a server
+// restart, does not lose events.
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// segmentSize is where a new segment file is started, whole segments are dropped
+	// when the spool is over its limit
+	segmentSize = 4 << 20
+	// maxRecord guards against reading a corrupt length as a huge allocation
+	maxRecord  = 16 << 20
+	cursorFile = "cursor"
+)
+
+// Position is the place after the last record of a Read, Commit moves the cursor there
+type Position struct {
+	segment uint64
+	offset  int64
+	gen     uint64
+}
+
+// Spool is safe for concurrent use. One consumer reads and commits, any number of
+// producers append.
+type Spool struct {
+	dir      string
+	maxBytes int64
+
+	mu       sync.Mutex
+	segments []uint64         // Sequence numbers of the segment files, oldest first
+	sizes    map[uint64]int64 // Bytes per segment
+	write    *os.File         // Last segment, open for appending
+	cursor   Position
+	pending  int   // Records after the cursor
+	size     int64 // Bytes over all segments
+	dropped  uint64
+}
+
+// Open loads the spool in dir, creating it when needed. maxBytes caps the segments on
+// disk, once it is reached the oldest segment is dropped with its records.
+func Open(dir string, maxBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Spool{dir: dir, maxBytes: maxBytes, sizes: map[uint64]int64{}}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".seg") {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, ".seg"), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		s.segments = append(s.segments, seq)
+		s.sizes[seq] = info.Size()
+		s.size += info.Size()
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i] < s.segments[j] })
+
+	if data, err := os.ReadFile(filepath.Join(dir, cursorFile)); err == nil {
+		var c struct {
+			Segment uint64 `json:"segment"`
+			Offset  int64  `json:"offset"`
+		}
+		if json.Unmarshal(data, &c) == nil {
+			s.cursor.segment, s.cursor.offset = c.Segment, c.Offset
+		}
+	}
+	if len(s.segments) == 0 {
+		s.segments = []uint64{1}
+		s.sizes[1] = 0
+	}
+	if s.cursor.segment < s.segments[0] || s.cursor.segment > s.segments[len(s.segments)-1] {
+		s.cursor = Position{segment: s.segments[0]}
+	}
+
+	// A crash can leave half a record at the end of the last segment
+	last := s.segments[len(s.segments)-1]
+	valid, _, err := s.scan(last, 0)
+	if err != nil {
+		return nil, err
+	}
+	if valid < s.sizes[last] {
+		if err := os.Truncate(s.path(last), valid); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		s.size -= s.sizes[last] - valid
+		s.sizes[last] = valid
+	}
+
+	for _, seq := range s.segments {
+		if seq < s.cursor.segment {
+			continue
+		}
+		from := int64(0)
+		if seq == s.cursor.segment {
+			from = s.cursor.offset
+		}
+		_, n, err := s.scan(seq, from)
+		if err != nil {
+			return nil, err
+		}
+		s.pending += n
+	}
+
+	s.write, err = os.OpenFile(s.path(last), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%016d.seg", seq))
+}
+
+// scan walks the records of a segment from an offset. It returns the offset after the
+// last complete record and the number of records.
+func (s *Spool) scan(seq uint64, from int64) (int64, int, error) {
+	f, err := os.Open(s.path(seq))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(from, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	r := bufio.NewReader(f)
+	off, n := from, 0
+	var head [4]byte
+	for {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			break
+		}
+		length := binary.BigEndian.Uint32(head[:])
+		if length > maxRecord {
+			break
+		}
+		if _, err := r.Discard(int(length)); err != nil {
+			break
+		}
+		off += 4 + int64(length)
+		n++
+	}
+	return off, n, nil
+}
+
+// Append adds a record at the end
+func (s *Spool) Append(rec []byte) error {
+	if len(rec) > maxRecord {
+		return fmt.Errorf("record of %d bytes is over the %d byte limit", len(rec), maxRecord)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.write == nil {
+		return errors.New("spool is closed")
+	}
+
+	last := s.segments[len(s.segments)-1]
+	if s.sizes[last] >= segmentSize {
+		next := last + 1
+		f, err := os.OpenFile(s.path(next), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		s.write.Close()
+		s.write, last = f, next
+		s.segments = append(s.segments, next)
+		s.sizes[next] = 0
+	}
+
+	buf := make([]byte, 4+len(rec))
+	binary.BigEndian.PutUint32(buf, uint32(len(rec)))
+	copy(buf[4:], rec)
+	if _, err := s.write.Write(buf); err != nil {
+		return err
+	}
+	s.sizes[last] += int64(len(buf))
+	s.size += int64(len(buf))
+	s.pending++
+
+	for s.maxBytes > 0 && s.size > s.maxBytes && len(s.segments) > 1 {
+		s.dropOldest()
+	}
+	return nil
+}
+
+// dropOldest removes the first segment, records in it that were not committed are lost
+func (s *Spool) dropOldest() {
+	seq := s.segments[0]
+	if s.cursor.segment == seq {
+		_, n, _ := s.scan(seq, s.cursor.offset)
+		s.pending -= n
+		s.dropped += uint64(n)
+		s.cursor = Position{segment: s.segments[1], gen: s.cursor.gen + 1}
+		s.saveCursor()
+	}
+	os.Remove(s.path(seq))
+	s.size -= s.sizes[seq]
+	delete(s.sizes, seq)
+	s.segments = s.segments[1:]
+}
+
+// Read returns up to max records after the cursor without moving it
+func (s *Spool) Read(max int) ([][]byte, Position, error) {
+	return s.ReadBytes(max, 0)
+}
+
+// ReadBytes is Read that also stops before the records add up to more than maxBytes,
+// the first record is returned whatever its size. Zero leaves the size open.
+func (s *Spool) ReadBytes(max int, maxBytes int64) ([][]byte, Position, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pos := s.cursor
+	var recs [][]byte
+	var budget *int64
+	if maxBytes > 0 {
+		budget = &maxBytes
+	}
+	for len(recs) < max {
+		batch, next, full, err := s.readSegment(pos, max-len(recs), budget, len(recs))
+		if err != nil {
+			return nil, s.cursor, err
+		}
+		recs = append(recs, batch...)
+		pos = next
+		if len(recs) >= max || full {
+			break
+		}
+		// Past the end of a full segment the next one goes on
+		i := sort.Search(len(s.segments), func(i int) bool { return s.segments[i] > pos.segment })
+		if i == len(s.segments) || pos.offset < s.sizes[pos.segment] {
+			break
+		}
+		pos = Position{segment: s.segments[i], gen: pos.gen}
+	}
+	return recs, pos, nil
+}
+
+// readSegment reads records of one segment from pos. A budget is spent on the records,
+// full reports that the next one did not fit. taken counts the records read before, the
+// first of a batch goes over the budget rather than never being read.
+func (s *Spool) readSegment(pos Position, max int, budget *int64, taken int) (recs [][]byte, next Position, full bool, err error) {
+	f, err := os.Open(s.path(pos.segment))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, pos, false, nil
+	}
+	if err != nil {
+		return nil, pos, false, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(pos.offset, io.SeekStart); err != nil {
+		return nil, pos, false, err
+	}
+	r := bufio.NewReader(f)
+	var head [4]byte
+	for len(recs) < max && pos.offset < s.sizes[pos.segment] {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			break
+		}
+		length := binary.BigEndian.Uint32(head[:])
+		if length > maxRecord {
+			// Corrupt from here on, skip the rest of the segment
+			pos.offset = s.sizes[pos.segment]
+			break
+		}
+		if budget != nil {
+			if int64(length) > *budget && taken+len(recs) > 0 {
+				return recs, pos, true, nil
+			}
+			*budget -= int64(length)
+		}
+		rec := make([]byte, length)
+		if _, err := io.ReadFull(r, rec); err != nil {
+			break
+		}
+		recs = append(recs, rec)
+		pos.offset += 4 + int64(length)
+	}
+	return recs, pos, false, nil
+}
+
+// Commit moves the cursor past n records returned by Read. A position from before the
+// spool dropped segments is ignored, those records are gone already.
+func (s *Spool) Commit(pos Position, n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pos.gen != s.cursor.gen {
+		return nil
+	}
+	s.cursor = pos
+	s.pending = max(s.pending-n, 0)
+	// Segments before the cursor are done, the segment being written stays
+	for len(s.segments) > 1 && s.segments[0] < pos.segment {
+		seq := s.segments[0]
+		os.Remove(s.path(seq))
+		s.size -= s.sizes[seq]
+		delete(s.sizes, seq)
+		s.segments = s.segments[1:]
+	}
+	return s.saveCursor()
+}
+
+func (s *Spool) saveCursor() error {
+	data, _ := json.Marshal(map[string]interface{}{"segment": s.cursor.segment, "offset": s.cursor.offset})
+	tmp := filepath.Join(s.dir, cursorFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, cursorFile))
+}
+
+// Stats reports the records waiting, the bytes on disk and the records dropped over the limit
+func (s *Spool) Stats() (pending int, size int64, dropped uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending, s.size, s.dropped
+}
+
+// SetLimit changes the cap on the bytes on disk, it applies from the next Append
+func (s *Spool) SetLimit(maxBytes int64) {
+	s.mu.Lock()
+	s.maxBytes = maxBytes
+	s.mu.Unlock()
+}
+
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.write == nil {
+		return nil
+	}
+	err := s.write.Close()
+	s.write = nil
+	return err
+}
+
+// Remove closes the spool and deletes its directory with everything queued
+func (s *Spool) Remove() error {
+	s.Close()
+	return os.RemoveAll(s.dir)
+}
@@ -0,0 +1,220 @@
+package spool
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func appendAll(t *testing.T, s *Spool, recs ...string) {
+	t.Helper()
+	for _, rec := range recs {
+		if err := s.Append([]byte(rec)); err != nil {
+			t.Fatalf("Append(%q): %v", rec, err)
+		}
+	}
+}
+
+func readStrings(t *testing.T, s *Spool, max int) ([]string, Position) {
+	t.Helper()
+	recs, pos, err := s.Read(max)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	out := make([]string, len(recs))
+	for i, rec := range recs {
+		out[i] = string(rec)
+	}
+	return out, pos
+}
+
+func equal(a, b []string) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func TestAppendReadCommit(t *testing.T) {
+	s, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	appendAll(t, s, "a", "b", "c")
+
+	got, pos := readStrings(t, s, 2)
+	if !equal(got, []string{"a", "b"}) {
+		t.Fatalf("first read = %v", got)
+	}
+	// Reading again without a commit returns the same records
+	if again, _ := readStrings(t, s, 2); !equal(again, got) {
+		t.Fatalf("read without commit = %v, want %v", again, got)
+	}
+	if err := s.Commit(pos, len(got)); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _, _ := s.Stats(); pending != 1 {
+		t.Fatalf("pending = %d, want 1", pending)
+	}
+	if got, _ := readStrings(t, s, 10); !equal(got, []string{"c"}) {
+		t.Fatalf("read after commit = %v", got)
+	}
+}
+
+func TestReadBytesBudget(t *testing.T) {
+	s, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	appendAll(t, s, "0123456789", "0123456789", "x")
+
+	recs, _, err := s.ReadBytes(10, 15)
+	if err != nil || len(recs) != 1 {
+		t.Fatalf("ReadBytes(10, 15) = %d records, %v, want 1", len(recs), err)
+	}
+	// The first record is returned even when it alone is over the budget
+	recs, _, err = s.ReadBytes(10, 5)
+	if err != nil || len(recs) != 1 {
+		t.Fatalf("ReadBytes(10, 5) = %d records, %v, want 1", len(recs), err)
+	}
+}
+
+func TestReopenKeepsCursor(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendAll(t, s, "a", "b", "c")
+	_, pos := readStrings(t, s, 1)
+	if err := s.Commit(pos, 1); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if pending, _, _ := s.Stats(); pending != 2 {
+		t.Fatalf("pending after reopen = %d, want 2", pending)
+	}
+	if got, _ := readStrings(t, s, 10); !equal(got, []string{"b", "c"}) {
+		t.Fatalf("read after reopen = %v", got)
+	}
+}
+
+func TestReopenTruncatedTail(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendAll(t, s, "first", "second")
+	seg := s.path(s.segments[len(s.segments)-1])
+	s.Close()
+
+	// A crash in the middle of a write leaves a length prefix and part of the record
+	f, err := os.OpenFile(seg, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 100, 'p', 'a', 'r'})
+	f.Close()
+
+	s, err = Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if pending, _, _ := s.Stats(); pending != 2 {
+		t.Fatalf("pending = %d, want 2", pending)
+	}
+	appendAll(t, s, "third")
+	if got, _ := readStrings(t, s, 10); !equal(got, []string{"first", "second", "third"}) {
+		t.Fatalf("read = %v", got)
+	}
+	info, err := os.Stat(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(3*4 + len("first") + len("second") + len("third")); info.Size() != want {
+		t.Fatalf("segment is %d bytes, want %d", info.Size(), want)
+	}
+}
+
+func TestSizeLimitDropsOldestSegment(t *testing.T) {
+	s, err := Open(t.TempDir(), segmentSize+segmentSize/2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	rec := bytes.Repeat([]byte("x"), 64<<10)
+	perSegment := segmentSize / (4 + len(rec))
+	total := 2*perSegment + 4
+	for i := 0; i < total; i++ {
+		copy(rec, fmt.Sprintf("%06d", i))
+		if err := s.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, size, dropped := s.Stats()
+	if dropped == 0 {
+		t.Fatal("nothing was dropped over the limit")
+	}
+	if size > segmentSize+segmentSize/2 {
+		t.Fatalf("%d bytes on disk, over the limit", size)
+	}
+	if pending+int(dropped) != total {
+		t.Fatalf("pending %d + dropped %d != %d appended", pending, dropped, total)
+	}
+	recs, _, err := s.Read(1)
+	if err != nil || len(recs) != 1 {
+		t.Fatalf("Read = %d records, %v", len(recs), err)
+	}
+	if first := string(recs[0][:6]); first != fmt.Sprintf("%06d", dropped) {
+		t.Fatalf("oldest record left is %s, want %06d", first, dropped)
+	}
+}
+
+func TestCommitFromBeforeDropIsIgnored(t *testing.T) {
+	s, err := Open(t.TempDir(), segmentSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rec := bytes.Repeat([]byte("x"), 64<<10)
+	if err := s.Append(rec); err != nil {
+		t.Fatal(err)
+	}
+	_, stale, err := s.Read(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2*segmentSize/len(rec); i++ {
+		if err := s.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, _, _ := s.Stats()
+	if err := s.Commit(stale, 1); err != nil {
+		t.Fatal(err)
+	}
+	if after, _, _ := s.Stats(); after != before {
+		t.Fatalf("stale commit changed pending from %d to %d", before, after)
+	}
+}
+
+func TestAppendTooLarge(t *testing.T) {
+	s, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Append(make([]byte, maxRecord+1)); err == nil {
+		t.Fatal("a record over maxRecord was accepted")
+	}
+}
@@ -0,0 +1,80 @@
+// Package ulid makes Universally Unique Lexicographically Sortable Identifiers: 48 bits
+// of milliseconds since the epoch and 80 random bits, written as 26 characters of
+// Crockford's base32. IDs made by one process sort in the order they were made, within a
+// millisecond the random part counts up instead of being drawn again.
+package ulid
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var state struct {
+	sync.Mutex
+	ms      uint64
+	entropy [10]byte
+}
+
+// New returns the ULID for the current time
+func New() string {
+	return Make(time.Now())
+}
+
+// Make returns a ULID for t. A time before the last one made is taken as that last time,
+// so IDs keep sorting when the clock steps back.
+func Make(t time.Time) string {
+	ms := uint64(t.UnixMilli())
+	state.Lock()
+	if ms <= state.ms {
+		ms = state.ms
+		if !increment(&state.entropy) {
+			// Used up 2^80 IDs in one millisecond, borrow the next one
+			ms++
+			rand.Read(state.entropy[:])
+		}
+	} else {
+		rand.Read(state.entropy[:])
+	}
+	state.ms = ms
+	var id [16]byte
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	copy(id[6:], state.entropy[:])
+	state.Unlock()
+	return encode(id)
+}
+
+func increment(b *[10]byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encode writes the 128 bits as 26 base32 digits, the first one holds the top 3 bits
+func encode(id [16]byte) string {
+	var out [26]byte
+	// 130 bits of digits for 128 bits of ID, walk them from the least significant end
+	var acc uint32
+	bits := 0
+	pos := len(out) - 1
+	for i := len(id) - 1; i >= 0; i-- {
+		acc |= uint32(id[i]) << bits
+		bits += 8
+		for bits >= 5 {
+			out[pos] = alphabet[acc&31]
+			pos--
+			acc >>= 5
+			bits -= 5
+		}
+	}
+	out[pos] = alphabet[acc&31]
+	return string(out[:])
+}
@@ -0,0 +1,88 @@
+package ulid
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	var zero, ones [16]byte
+	for i := range ones {
+		ones[i] = 0xff
+	}
+	for _, tc := range []struct {
+		id   [16]byte
+		want string
+	}{
+		{zero, "00000000000000000000000000"},
+		{ones, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+		{[16]byte{15: 1}, "00000000000000000000000001"},
+		{[16]byte{15: 32}, "00000000000000000000000010"},
+		{[16]byte{0: 0x80}, "40000000000000000000000000"},
+	} {
+		if got := encode(tc.id); got != tc.want {
+			t.Errorf("encode(%x) = %s, want %s", tc.id, got, tc.want)
+		}
+	}
+}
+
+// decodeTime reads the milliseconds back from the first 10 characters
+func decodeTime(id string) int64 {
+	var ms int64
+	for _, c := range id[:10] {
+		ms = ms<<5 | int64(strings.IndexRune(alphabet, c))
+	}
+	return ms
+}
+
+func TestMakeCarriesTime(t *testing.T) {
+	// Ahead of every ID made so far, a time before the last one would not be taken
+	at := time.Now().Add(time.Minute)
+	id := Make(at)
+	if len(id) != 26 {
+		t.Fatalf("%s has %d characters", id, len(id))
+	}
+	for _, c := range id {
+		if !strings.ContainsRune(alphabet, c) {
+			t.Fatalf("%s has %q outside Crockford's base32", id, c)
+		}
+	}
+	if got := decodeTime(id); got != at.UnixMilli() {
+		t.Fatalf("time of %s = %d, want %d", id, got, at.UnixMilli())
+	}
+}
+
+func TestIDsSortInOrderMade(t *testing.T) {
+	prev := New()
+	for i := 0; i < 10000; i++ {
+		id := New()
+		if id <= prev {
+			t.Fatalf("%s made after %s does not sort after it", id, prev)
+		}
+		prev = id
+	}
+
+	// A clock that steps back keeps the last time and counts up
+	late := Make(time.Now().Add(time.Hour))
+	early := Make(time.Now())
+	if early <= late {
+		t.Fatalf("%s made after %s does not sort after it", early, late)
+	}
+	if decodeTime(early) != decodeTime(late) {
+		t.Fatal("an ID made with an earlier clock did not keep the last time")
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	b := [10]byte{9: 0xff}
+	if !increment(&b) || b != [10]byte{8: 1} {
+		t.Fatalf("increment carried to %x", b)
+	}
+	for i := range b {
+		b[i] = 0xff
+	}
+	if increment(&b) {
+		t.Fatal("increment of the largest value did not report the overflow")
+	}
+}
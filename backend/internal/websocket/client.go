@@ -27,6 +27,9 @@ type Client struct {
 	conn   *websocket.Conn
 	send   chan []byte
 	NodeID string
+
+	// Username is set on frontend connections, messages are pushed per user
+	Username string
 }
 
 func (c *Client) readPump() {
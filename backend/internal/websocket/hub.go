@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 
 	"github.com/gorilla/websocket"
 )
@@ -27,6 +28,19 @@ type Hub struct {
 	handler      func([]byte, *Client)
 	onDisconnect func(*Client)
 	mu           sync.RWMutex
+
+	broadcasts atomic.Uint64 // Messages fanned out to the clients
+	dropped    atomic.Uint64 // Deliveries skipped because a client's send buffer was full
+}
+
+// HubStats is a snapshot of the hub for monitoring
+type HubStats struct {
+	Clients       int // Every connection, probes included
+	Nodes         int // Connections bound to a probe
+	QueueLength   int // Broadcasts waiting for the hub loop
+	QueueCapacity int
+	Broadcasts    uint64
+	Dropped       uint64
 }
 
 func NewHub(handler func([]byte, *Client), onDisconnect func(*Client)) *Hub {
@@ -69,11 +83,13 @@ func (h *Hub) Run() {
 			}
 			h.mu.Unlock()
 		case message := <-h.broadcast:
+			h.broadcasts.Add(1)
 			h.mu.RLock()
 			for client := range h.clients {
 				select {
 				case client.send <- message:
 				default:
+					h.dropped.Add(1)
 					close(client.send)
 					// We need to upgrade lock to delete, or just mark for deletion?
 					// Deleting while iterating with RLock is bad.
@@ -106,6 +122,34 @@ func (h *Hub) Broadcast(msg []byte) {
 	h.broadcast <- msg
 }
 
+// SendToUser pushes a message to every connection the user signed in with. A full
+// send buffer skips the connection like a broadcast does.
+func (h *Hub) SendToUser(username string, msg []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		if client.Username != username {
+			continue
+		}
+		select {
+		case client.send <- msg:
+		default:
+			h.dropped.Add(1)
+		}
+	}
+}
+
+// DisconnectUser closes the connections of a user, their read pumps unregister them
+func (h *Hub) DisconnectUser(username string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		if client.Username == username {
+			client.conn.Close()
+		}
+	}
+}
+
 func (h *Hub) BindNode(nodeID string, client *Client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -132,6 +176,31 @@ func (h *Hub) SendToNode(nodeID string, msg []byte) bool {
 	return false
 }
 
+// NodeIDs lists the probes that currently hold a connection
+func (h *Hub) NodeIDs() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ids := make([]string, 0, len(h.nodeMap))
+	for id := range h.nodeMap {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Stats reports connection counts, the broadcast backlog and delivery counters
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return HubStats{
+		Clients:       len(h.clients),
+		Nodes:         len(h.nodeMap),
+		QueueLength:   len(h.broadcast),
+		QueueCapacity: cap(h.broadcast),
+		Broadcasts:    h.broadcasts.Load(),
+		Dropped:       h.dropped.Load(),
+	}
+}
+
 func (h *Hub) IsActiveClient(client *Client) bool {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -141,13 +210,15 @@ func (h *Hub) IsActiveClient(client *Client) bool {
 	return h.nodeMap[client.NodeID] == client
 }
 
-func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
+// ServeWs upgrades the request, username is the signed in user of a frontend and empty
+// for probes
+func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, username string) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256)}
+	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256), Username: username}
 	client.hub.register <- client
 
 	// Allow collection of memory referenced by the caller by doing all work in
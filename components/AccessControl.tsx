@@ -7,6 +7,7 @@ import { ShieldCheck, Plus, RefreshCw, Trash2, Lock, Unlock, Server, Shield, Mon
 import { useNotification } from './NotificationSystem';
 import { AccessControlRule } from '../types';
 import { authFetch } from '../services/aiService';
+import { formatDateTime } from '../time';
 
 // 复用自律防御的切换开关组件
 const ToggleSwitch: React.FC<{ checked: boolean, onChange: () => void, loading?: boolean }> = ({ checked, onChange, loading }) => (
@@ -306,21 +307,14 @@ export const AccessControl: React.FC = () => {
             return;
         }
 
-        // Calculate Expiration
+        // Calculate Expiration, sent as RFC 3339 and null for permanent rules
         let expireTime: string | null = null;
         if (formDuration !== 'permanent') {
             const now = new Date();
             if (formDuration === '1h') now.setHours(now.getHours() + 1);
             else if (formDuration === '24h') now.setHours(now.getHours() + 24);
             else if (formDuration === '7d') now.setDate(now.getDate() + 7);
-            
-            // Format: YYYY-MM-DD HH:mm:ss
-            expireTime = now.getFullYear() + '/' + 
-                         String(now.getMonth() + 1).padStart(2, '0') + '/' + 
-                         String(now.getDate()).padStart(2, '0') + ' ' + 
-                         String(now.getHours()).padStart(2, '0') + ':' + 
-                         String(now.getMinutes()).padStart(2, '0') + ':' + 
-                         String(now.getSeconds()).padStart(2, '0');
+            expireTime = now.toISOString();
         }
 
         const defaultReason = formType === 'blacklist' ? t('ac_default_blacklist', lang) : t('ac_default_whitelist', lang);
@@ -329,7 +323,7 @@ export const AccessControl: React.FC = () => {
             ip: formIp,
             type: formType,
             reason: formReason || defaultReason,
-            expireTime: expireTime || t('ac_permanent', lang),
+            expireTime,
             source: 'PRTS',
             status: 'active'
         };
@@ -522,7 +516,7 @@ export const AccessControl: React.FC = () => {
                                                     {rule.source === 'PRTS' ? t('ac_source_prts', lang) : t('ac_source_system', lang)}
                                                 </span>
                                             </td>
-                                            <td className="p-4 text-ark-text">{rule.expireTime || t('ac_permanent', lang)}</td>
+                                            <td className="p-4 text-ark-text">{rule.expireTime ? formatDateTime(rule.expireTime) : t('ac_permanent', lang)}</td>
                                             <td className="p-4 text-right">
                                                 {rule.source === 'PRTS' ? (
                                                     <button onClick={() => handleDelete(rule.id)} className="p-1.5 hover:bg-ark-danger/20 text-ark-subtext hover:text-ark-danger transition-colors rounded-sm">
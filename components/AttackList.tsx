@@ -8,6 +8,7 @@ import { t } from '../i18n';
 import { ImageWorldMap } from './WorldMap';
 import { useNotification } from './NotificationSystem';
 import { ArkDateRangePicker } from './ArkDateRangePicker';
+import { formatClock, formatDateTime, rangeParams } from '../time';
 
 const FilterGroup: React.FC<{ label: string, children: React.ReactNode, className?: string }> = ({ label, children, className = "" }) => (
     <div className={`flex items-center border border-ark-border bg-ark-panel h-[32px] ${className}`}>
@@ -53,30 +54,53 @@ export const AttackList: React.FC = () => {
   const { notify } = useNotification();
   const [expandedLogId, setExpandedLogId] = useState<string | null>(null);
   const [isFiltersOpen, setIsFiltersOpen] = useState(false);
-  const [dateRange, setDateRange] = useState({ start: '2025-11-30T00:00', end: '2025-12-06T23:59' });
+  const [dateRange, setDateRange] = useState({ start: '', end: '' });
+  const [ipFilter, setIpFilter] = useState('');
+  const [searchFilter, setSearchFilter] = useState('');
   const [attacks, setAttacks] = useState<AttackLog[]>([]);
+  const [total, setTotal] = useState(0);
+  const [nextCursor, setNextCursor] = useState<string | null>(null);
   const [loading, setLoading] = useState(true);
+  const [loadingMore, setLoadingMore] = useState(false);
 
   const enabled = modules.attack;
 
-  const fetchAttacks = async () => {
-    setLoading(true);
+  // Filtering, sorting and paging happen on the server, a cursor loads the next page
+  const fetchAttacks = async (cursor?: string) => {
+    const params = rangeParams(dateRange);
+    if (ipFilter.trim()) params.set('ip', ipFilter.trim());
+    if (searchFilter.trim()) params.set('q', searchFilter.trim());
+    if (cursor) params.set('cursor', cursor);
+
+    cursor ? setLoadingMore(true) : setLoading(true);
     try {
-      const response = await authFetch('/api/v1/attacks');
+      const response = await authFetch(`/api/v1/attacks?${params.toString()}`);
       if (response.ok) {
         const data = await response.json();
-        setAttacks(data);
+        setAttacks(prev => cursor ? [...prev, ...data] : data);
+        setTotal(parseInt(response.headers.get('X-Total-Count') || '0'));
+        setNextCursor(response.headers.get('X-Next-Cursor'));
+      } else {
+        const data = await response.json();
+        notify('warning', t('op_failed', lang), data.error);
       }
     } catch (error) {
       console.error("Failed to fetch attacks", error);
     } finally {
       setLoading(false);
+      setLoadingMore(false);
     }
   };
 
   useEffect(() => {
     fetchAttacks();
-  }, [authFetch]);
+  }, [authFetch, dateRange]);
+
+  const clearFilters = () => {
+      setIpFilter('');
+      setSearchFilter('');
+      setDateRange({ start: '', end: '' });
+  };
 
   // Combine historical and real-time attacks
   const allAttacks = [...rtAttacks, ...attacks];
@@ -102,7 +126,7 @@ export const AttackList: React.FC = () => {
                        log.method === 'HTTP' ? 'Elasticsearch' : 
                        log.method === 'SMB' ? 'SMB Port' : 'Service Probe',
           port: log.method === 'SSH' ? '22' : log.method === 'HTTP' ? '9200' : '445',
-          victimNode: 'Internal Node (26.44.0.122)',
+          victimNode: log.sensor || log.node || 'Internal Node (26.44.0.122)',
           country: t(locKey as any, lang),
       };
   });
@@ -182,10 +206,10 @@ export const AttackList: React.FC = () => {
                     <ArkDateRangePicker value={dateRange} onChange={setDateRange} className="w-full" />
                 </div>
                 <FilterGroup label={t('filter_attacker_ip', lang)}>
-                    <input type="text" placeholder={t('filter_placeholder_search', lang)} className="w-full h-full px-2 bg-transparent outline-none text-xs text-ark-text placeholder-ark-subtext/50" />
+                    <input type="text" value={ipFilter} onChange={e => setIpFilter(e.target.value)} onKeyDown={e => e.key === 'Enter' && fetchAttacks()} placeholder={t('filter_placeholder_ip_range', lang)} className="w-full h-full px-2 bg-transparent outline-none text-xs text-ark-text placeholder-ark-subtext/50" />
                 </FilterGroup>
                 <FilterGroup label={t('filter_location', lang)}>
-                    <input type="text" placeholder={t('filter_placeholder_search', lang)} className="w-full h-full px-2 bg-transparent outline-none text-xs text-ark-text placeholder-ark-subtext/50" />
+                    <input type="text" value={searchFilter} onChange={e => setSearchFilter(e.target.value)} onKeyDown={e => e.key === 'Enter' && fetchAttacks()} placeholder={t('filter_placeholder_search', lang)} className="w-full h-full px-2 bg-transparent outline-none text-xs text-ark-text placeholder-ark-subtext/50" />
                 </FilterGroup>
                 <FilterGroup label={t('filter_threat_tags', lang)}>
                     <input type="text" placeholder={t('filter_placeholder_tags', lang)} className="w-full h-full px-2 bg-transparent outline-none text-xs text-ark-text placeholder-ark-subtext/50" />
@@ -203,7 +227,7 @@ export const AttackList: React.FC = () => {
                         </select>
                         <ChevronDown size={12} className="absolute right-2 text-ark-subtext pointer-events-none" />
                     </FilterGroup>
-                    <button className="flex items-center justify-center px-3 h-[32px] border border-ark-border hover:border-ark-primary hover:text-ark-primary transition-colors text-xs text-ark-subtext bg-ark-bg/30 min-w-fit">
+                    <button onClick={clearFilters} className="flex items-center justify-center px-3 h-[32px] border border-ark-border hover:border-ark-primary hover:text-ark-primary transition-colors text-xs text-ark-subtext bg-ark-bg/30 min-w-fit">
                         <X size={14} className="mr-1" />
                     </button>
                 </div>
@@ -259,7 +283,8 @@ export const AttackList: React.FC = () => {
              <button className="px-3 py-1 bg-ark-text text-ark-bg font-bold">{t('sort_time', lang)}</button>
              <button className="px-3 py-1 bg-ark-panel hover:bg-ark-active transition-colors">{t('sort_count', lang)}</button>
           </div>
-          <button className="flex items-center gap-1 hover:text-ark-primary transition-colors">
+          <span className="font-mono">{t('al_total', lang)}: {total}</span>
+          <button onClick={() => fetchAttacks()} className="flex items-center gap-1 hover:text-ark-primary transition-colors">
               <RefreshCw size={14} /> {t('refresh', lang)}
           </button>
       </div>
@@ -282,7 +307,7 @@ export const AttackList: React.FC = () => {
                                      <span className="font-bold text-ark-text">{log.sourceIp}</span>
                                      <span className="text-[10px] bg-ark-subtext/10 px-1 text-ark-subtext border border-ark-border rounded-sm">{log.country}</span>
                                  </div>
-                                 <span className="text-xs font-mono text-ark-subtext">{log.timestamp.split(' ')[1]}</span>
+                                 <span className="text-xs font-mono text-ark-subtext">{formatClock(log.timestamp)}</span>
                              </div>
                              <div className="grid grid-cols-2 gap-2 text-xs text-ark-subtext mb-2" onClick={() => toggleExpand(log.id)}>
                                  <div className="flex items-center gap-1.5 truncate">
@@ -308,7 +333,7 @@ export const AttackList: React.FC = () => {
                                     <div className="font-mono text-xs space-y-2 text-ark-subtext bg-black/20 p-3 rounded-sm">
                                         <div className="flex flex-col gap-1">
                                             <span className="opacity-50">{t('al_full_time', lang)}</span>
-                                            <span className="text-ark-text">{log.timestamp}</span>
+                                            <span className="text-ark-text">{formatDateTime(log.timestamp)}</span>
                                         </div>
                                         <div className="flex flex-col gap-1">
                                             <span className="opacity-50">{t('col_payload', lang)}:</span>
@@ -345,7 +370,7 @@ export const AttackList: React.FC = () => {
                                         <div className={`text-ark-subtext transition-colors ${isExpanded ? 'text-ark-primary' : ''}`}>
                                             {isExpanded ? <MinusSquare size={14} /> : <PlusSquare size={14} />}
                                         </div>
-                                        {log.timestamp}
+                                        {formatDateTime(log.timestamp)}
                                     </div>
                                     <div className="font-bold text-ark-text">
                                         <div className="flex items-center gap-2">
@@ -379,7 +404,7 @@ export const AttackList: React.FC = () => {
                                                 </div>
                                             </div>
                                             <div className="font-mono text-xs space-y-2 text-ark-subtext">
-                                                <div className="grid grid-cols-[100px_1fr]"><span>{t('col_timestamp', lang)}:</span><span className="text-ark-text">{log.timestamp}</span></div>
+                                                <div className="grid grid-cols-[100px_1fr]"><span>{t('col_timestamp', lang)}:</span><span className="text-ark-text">{formatDateTime(log.timestamp)}</span></div>
                                                 <div className="grid grid-cols-[100px_1fr]"><span>{t('col_payload', lang)}:</span><span className="text-ark-danger break-all">{log.payload}</span></div>
                                                 <div className="grid grid-cols-[100px_1fr]"><span>{t('label_raw_data', lang)}</span><span className="text-ark-subtext opacity-70 break-all">0000 45 00 00 3c 1c 46 40 00 40 06 b1 e6 c0 a8 00 08 ...</span></div>
                                             </div>
@@ -391,9 +416,15 @@ export const AttackList: React.FC = () => {
                      })}
                  </div>
                  
-                 {/* Table Footer Indicator to ensure clean ending */}
+                 {/* Table Footer: next page or a clean ending */}
                  <div className="p-2 border-t border-ark-border/50 dark:border-gray-800 text-center">
-                     <span className="text-[10px] text-ark-subtext opacity-30 font-mono tracking-widest uppercase">{t('al_end_logs', lang)}</span>
+                     {nextCursor ? (
+                         <button onClick={() => fetchAttacks(nextCursor)} disabled={loadingMore} className="text-xs text-ark-primary font-mono hover:underline disabled:opacity-50">
+                             {loadingMore ? `${t('loading', lang)}...` : t('al_load_more', lang)}
+                         </button>
+                     ) : (
+                         <span className="text-[10px] text-ark-subtext opacity-30 font-mono tracking-widest uppercase">{t('al_end_logs', lang)}</span>
+                     )}
                  </div>
              </div>
          </div>
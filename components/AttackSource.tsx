@@ -7,6 +7,7 @@ import { Users, X, AlertTriangle, Radar, Trash2, User, ChevronLeft, ChevronRight
 import { ArkDateRangePicker } from './ArkDateRangePicker';
 import { useNotification } from './NotificationSystem';
 import { HackerProfile, AccountCredential } from '../types';
+import { formatDateTime, rangeParams } from '../time';
 
 const FilterInput: React.FC<{ label?: string, placeholder?: string, width?: string, children?: React.ReactNode }> = ({ label, placeholder, width = "w-full", children }) => (
     <div className={`flex items-center border border-ark-border bg-ark-panel h-[32px] ${width}`}>
@@ -46,7 +47,7 @@ export const AttackSource: React.FC = () => {
     const { lang, modules, toggleModule, authFetch } = useApp();
     const { notify } = useNotification();
     const enabled = modules.attackSource;
-    const [dateRange, setDateRange] = useState({ start: '2025-11-20T00:00', end: '2025-12-06T23:59' });
+    const [dateRange, setDateRange] = useState({ start: '', end: '' });
     const [sources, setSources] = useState<HackerProfile[]>([]);
     const [credentials, setCredentials] = useState<AccountCredential[]>([]);
     const [loading, setLoading] = useState(true);
@@ -56,8 +57,8 @@ export const AttackSource: React.FC = () => {
         setLoading(true);
         try {
             const [sourcesRes, credsRes] = await Promise.all([
-                authFetch('/api/v1/attack-sources'),
-                authFetch('/api/v1/account-credentials')
+                authFetch(`/api/v1/attack-sources?${rangeParams(dateRange)}`),
+                authFetch(`/api/v1/account-credentials?${rangeParams(dateRange)}`)
             ]);
             
             if (sourcesRes.ok) {
@@ -83,7 +84,7 @@ export const AttackSource: React.FC = () => {
 
     useEffect(() => {
         fetchData();
-    }, []);
+    }, [dateRange]);
 
     const handleToggle = async () => {
         setIsToggling(true);
@@ -209,7 +210,7 @@ export const AttackSource: React.FC = () => {
                                         <td className="p-4 text-ark-subtext">
                                             {item.nodes?.map(n => n === 'Internal Node' ? t('val_builtin_node', lang) : n).join(', ')}
                                         </td>
-                                        <td className="p-4 text-ark-subtext">{item.firstTime}</td>
+                                        <td className="p-4 text-ark-subtext">{formatDateTime(item.firstTime)}</td>
                                         <td className="p-4">
                                             <div className="flex items-center justify-center gap-3">
                                                 <button 
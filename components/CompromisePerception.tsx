@@ -6,6 +6,7 @@ import { t } from '../i18n';
 import { Inbox, ChevronRight, Calendar, Download, Upload, FileText, Activity, Globe, Plus, Box, RefreshCw } from 'lucide-react';
 import { ArkDateRangePicker } from './ArkDateRangePicker';
 import { useNotification } from './NotificationSystem';
+import { formatDateTime, rangeParams } from '../time';
 
 const DecoyAnimation = () => {
     const { lang } = useApp();
@@ -46,7 +47,7 @@ export const CompromisePerception: React.FC = () => {
     const { notify } = useNotification();
     const [activeTab, setActiveTab] = useState<'data' | 'mgmt'>('data');
     const enabled = modules.persistence;
-    const [dateRange, setDateRange] = useState({ start: '2025-11-06T00:00', end: '2025-12-05T23:59' });
+    const [dateRange, setDateRange] = useState({ start: '', end: '' });
     const [logs, setLogs] = useState<any[]>([]);
     const [loading, setLoading] = useState(true);
     const [pendingDeploys, setPendingDeploys] = useState<Set<number>>(new Set());
@@ -55,7 +56,7 @@ export const CompromisePerception: React.FC = () => {
     const fetchLogs = async () => {
         setLoading(true);
         try {
-            const response = await authFetch('/api/v1/decoys');
+            const response = await authFetch(`/api/v1/decoys?${rangeParams(dateRange)}`);
             if (response.ok) {
                 const data = await response.json();
                 setLogs(data);
@@ -69,7 +70,7 @@ export const CompromisePerception: React.FC = () => {
 
     useEffect(() => {
         fetchLogs();
-    }, [authFetch]);
+    }, [authFetch, dateRange]);
 
     const getStatusStyle = (status: string) => {
         if (status === 'Compromised') return 'text-red-500 bg-red-500/10 border-red-500/30';
@@ -272,10 +273,10 @@ export const CompromisePerception: React.FC = () => {
                                                     </td>
                                                     <td className="p-4 text-ark-text">{log.device}</td>
                                                     <td className="p-4 text-ark-subtext">{log.sourceIp}</td>
-                                                    <td className="p-4 text-ark-subtext">{log.time}</td>
+                                                    <td className="p-4 text-ark-subtext">{formatDateTime(log.time)}</td>
                                                     <td className="p-4 text-ark-text">{log.result}</td>
                                                     <td className="p-4 text-ark-primary">{log.decoyName}</td>
-                                                    <td className="p-4 text-ark-subtext">{log.deployTime}</td>
+                                                    <td className="p-4 text-ark-subtext">{formatDateTime(log.deployTime)}</td>
                                                     <td className="p-4 text-ark-text">{log.node}</td>
                                                 </tr>
                                             ))
@@ -2,12 +2,12 @@
 import React, { useState, useEffect } from 'react';
 import { ArkCard, ArkHexagon, ArkLoading } from './ArknightsUI';
 import { ResponsiveContainer, AreaChart, Area, XAxis, YAxis, CartesianGrid, Tooltip, PieChart, Pie, Cell, LineChart, Line } from 'recharts';
-import { DASHBOARD_TREND_DATA } from '../constants';
 import { Globe, User, Server, Camera, Database, LayoutTemplate, Activity, Cpu, HardDrive, Wifi, ArrowUp, ArrowDown, Zap, Monitor, Shield, ShieldCheck, Filter, ShieldAlert } from 'lucide-react';
 import { useApp } from '../AppContext';
 import { t } from '../i18n';
 import { useNavigate } from 'react-router-dom';
 import { ImageWorldMap } from './WorldMap';
+import { formatClock, formatDateTime } from '../time';
 
 // --- Sub-components ---
 
@@ -118,7 +118,7 @@ const HackerProfileCard: React.FC<{ profile: any, lang: string }> = ({ profile,
             <div className="flex-1 min-w-0">
                 <div className="flex items-center justify-between mb-1">
                     <span className="font-mono font-bold text-ark-text group-hover:text-ark-primary transition-colors">{profile.ip}</span>
-                    <span className="text-[9px] text-ark-subtext font-mono bg-ark-bg px-1 rounded-sm border border-ark-border">{profile.lastSeen || profile.firstTime}</span>
+                    <span className="text-[9px] text-ark-subtext font-mono bg-ark-bg px-1 rounded-sm border border-ark-border">{profile.lastSeen || formatDateTime(profile.firstTime)}</span>
                 </div>
                 <div className="flex items-center justify-between">
                     <div className="flex items-center gap-1 text-[10px] text-ark-subtext truncate">
@@ -361,6 +361,23 @@ const SystemMonitor: React.FC = () => {
         return () => window.removeEventListener('PRTS_NODE_UPDATE', handleNodeUpdate);
     }, [selectedNodeId, nodes.length]);
 
+    // Start the charts from the stored history of the node instead of from zero
+    useEffect(() => {
+        if (!selectedNodeId) return;
+        const fetchHistory = async () => {
+            try {
+                const from = new Date(Date.now() - 200 * 1000).toISOString();
+                const response = await authFetch(`/api/v1/nodes/${encodeURIComponent(selectedNodeId)}/metrics?step=10s&from=${from}`);
+                if (!response.ok) return;
+                const data = await response.json();
+                const points = (data.points || []).slice(-20).map((p: any) => ({ cpu: p.load, mem: p.memory, up: p.netUp, down: p.netDown }));
+                const padding = Array(20 - points.length).fill(0).map(() => ({ cpu: 0, mem: 0, up: 0, down: 0 }));
+                setHistory([...padding, ...points].map((p, i) => ({ ...p, time: i })));
+            } catch (e) {}
+        };
+        fetchHistory();
+    }, [selectedNodeId, authFetch]);
+
     useEffect(() => {
         const fetchInitialNodes = async () => {
             try {
@@ -499,6 +516,9 @@ const SystemMonitor: React.FC = () => {
 
 // --- Main Dashboard Component ---
 
+// The busiest services get the accent colors, the rest are summed up in grey
+const TREND_COLORS = ['#f97316', '#eab308', '#22c55e', '#64748b'];
+
 export const Dashboard: React.FC = () => {
   const { lang, darkMode, authFetch } = useApp();
   const navigate = useNavigate();
@@ -524,6 +544,10 @@ export const Dashboard: React.FC = () => {
   
   const gridColor = darkMode ? '#333' : '#e5e5e5';
 
+  // Attacks per service by hour, the server rolls them up on ingest
+  const trendSeries: string[] = stats?.attackTrend?.series || [];
+  const trendData = (stats?.attackTrend?.points || []).map((p: any) => ({ name: formatClock(p.time).slice(0, 5), ...p.values }));
+
   if (loading && !stats) {
     return <ArkLoading label="INITIALIZING_DASHBOARD_CORE" />;
   }
@@ -565,27 +589,25 @@ export const Dashboard: React.FC = () => {
         <ArkCard title={t('attack_trend', lang)} className="flex-1 min-h-[300px]">
              <div className="w-full h-full min-w-0 relative">
                 <ResponsiveContainer width="100%" height="100%" minWidth={0} minHeight={0}>
-                    <AreaChart data={stats?.trendData || DASHBOARD_TREND_DATA} margin={{ top: 10, right: 10, left: 0, bottom: 0 }}>
+                    <AreaChart data={trendData} margin={{ top: 10, right: 10, left: 0, bottom: 0 }}>
                         <defs>
-                            <linearGradient id="colorCore" x1="0" y1="0" x2="0" y2="1">
-                                <stop offset="5%" stopColor="#f97316" stopOpacity={0.8}/>
-                                <stop offset="95%" stopColor="#f97316" stopOpacity={0}/>
-                            </linearGradient>
-                            <linearGradient id="colorEsxi" x1="0" y1="0" x2="0" y2="1">
-                                <stop offset="5%" stopColor="#eab308" stopOpacity={0.8}/>
-                                <stop offset="95%" stopColor="#eab308" stopOpacity={0}/>
-                            </linearGradient>
+                            {trendSeries.map((name: string, i: number) => (
+                                <linearGradient key={name} id={`colorTrend${i}`} x1="0" y1="0" x2="0" y2="1">
+                                    <stop offset="5%" stopColor={TREND_COLORS[i % TREND_COLORS.length]} stopOpacity={0.8}/>
+                                    <stop offset="95%" stopColor={TREND_COLORS[i % TREND_COLORS.length]} stopOpacity={0}/>
+                                </linearGradient>
+                            ))}
                         </defs>
                         <CartesianGrid strokeDasharray="3 3" stroke={gridColor} vertical={false} />
                         <XAxis dataKey="name" stroke="var(--ark-subtext)" tick={{fontFamily: 'monospace', fontSize: 10}} axisLine={false} tickLine={false} dy={10} />
-                        <YAxis stroke="var(--ark-subtext)" tick={{fontFamily: 'monospace', fontSize: 10}} axisLine={false} tickLine={false} />
+                        <YAxis stroke="var(--ark-subtext)" tick={{fontFamily: 'monospace', fontSize: 10}} axisLine={false} tickLine={false} allowDecimals={false} />
                         <Tooltip 
                             contentStyle={{ backgroundColor: 'var(--ark-panel)', borderColor: 'var(--ark-primary)', color: 'var(--ark-text)', borderRadius: 0 }}
                             itemStyle={{ fontFamily: 'monospace', fontSize: '12px' }}
                         />
-                        <Area type="monotone" dataKey="coremail" stackId="1" stroke="#f97316" fill="url(#colorCore)" strokeWidth={2} />
-                        <Area type="monotone" dataKey="esxi" stackId="1" stroke="#eab308" fill="url(#colorEsxi)" strokeWidth={2} />
-                        <Area type="monotone" dataKey="elastic" stackId="1" stroke="#22c55e" fill="#22c55e" fillOpacity={0.2} strokeWidth={2} />
+                        {trendSeries.map((name: string, i: number) => (
+                            <Area key={name} type="monotone" dataKey={name} name={name === 'other' ? t('dash_trend_other', lang) : name} stackId="1" stroke={TREND_COLORS[i % TREND_COLORS.length]} fill={`url(#colorTrend${i})`} strokeWidth={2} />
+                        ))}
                     </AreaChart>
                 </ResponsiveContainer>
              </div>
@@ -1,25 +1,109 @@
 
 
 import React, { useState } from 'react';
-import { ArkCard, ArkButton, ArkPageHeader } from './ArknightsUI';
+import { ArkCard, ArkButton, ArkPageHeader, ArkBadge, ArkModal, ArkInput } from './ArknightsUI';
 import { useApp } from '../AppContext';
 import { t } from '../i18n';
-import { Bell, Info, AlertTriangle, Trash2, MailOpen, FileText, RefreshCw } from 'lucide-react';
+import { Bell, Info, AlertTriangle, Trash2, MailOpen, FileText, RefreshCw, UserCheck, SlidersHorizontal } from 'lucide-react';
 import { useNotification } from './NotificationSystem';
+import { formatDateTime } from '../time';
+import { AlertStatus, Message, MessageSubscription } from '../types';
+
+const STATUS_FILTERS: AlertStatus[] = ['firing', 'acknowledged', 'resolved'];
+const MESSAGE_TYPES = ['system', 'security', 'report'];
+const SUBSCRIPTION_SEVERITIES: MessageSubscription['minSeverity'][] = ['info', 'warning', 'critical'];
+
+const selectClass = "w-full bg-ark-bg border-b-2 border-ark-border px-3 py-2 text-sm text-ark-text focus:outline-none focus:border-ark-primary font-mono";
 
 export const MessageCenter: React.FC = () => {
-    const { lang, messages, markAllRead, deleteMessage, toggleRead } = useApp();
+    const { lang, user, messages, unreadCount, markAllRead, deleteMessage, toggleRead, upsertMessage, authFetch } = useApp();
     const { notify } = useNotification();
-    const [filter, setFilter] = useState<'all' | 'unread' | 'system' | 'security'>('all');
+    const [filter, setFilter] = useState<'all' | 'unread' | 'system' | 'security' | AlertStatus>('all');
     const [pendingMessages, setPendingMessages] = useState<Set<string>>(new Set());
     const [isMarkingAll, setIsMarkingAll] = useState(false);
+    const [assigning, setAssigning] = useState<Message | null>(null);
+    const [assignee, setAssignee] = useState('');
+    const [subscription, setSubscription] = useState<MessageSubscription | null>(null);
+    const [isSavingSubscription, setIsSavingSubscription] = useState(false);
 
     const filteredMessages = messages.filter(msg => {
         if (filter === 'all') return true;
         if (filter === 'unread') return !msg.read;
+        if (STATUS_FILTERS.includes(filter as AlertStatus)) return (msg.status || 'firing') === filter;
         return msg.type === filter;
     });
 
+    // Lifecycle changes come back as the updated message, the websocket pushes them to everyone else
+    const updateAlert = async (id: string, action: 'ack' | 'resolve' | 'assign', body?: object) => {
+        if (pendingMessages.has(id)) return false;
+        setPendingMessages(prev => new Set(prev).add(id));
+        let ok = false;
+        try {
+            const res = await authFetch(`/api/v1/messages/${id}/${action}`, { method: 'POST', body: body ? JSON.stringify(body) : undefined });
+            const data = await res.json();
+            if (res.ok) {
+                upsertMessage(data);
+                ok = true;
+            } else {
+                notify('error', t('op_failed', lang), data.error);
+            }
+        } catch (e) {
+            notify('error', t('op_failed', lang), t('err_network', lang));
+        }
+        setPendingMessages(prev => {
+            const next = new Set(prev);
+            next.delete(id);
+            return next;
+        });
+        return ok;
+    };
+
+    const handleAssign = async () => {
+        if (assigning && await updateAlert(assigning.id, 'assign', { assignee: assignee.trim() })) {
+            setAssigning(null);
+        }
+    };
+
+    const openSubscription = async () => {
+        try {
+            const res = await authFetch('/api/v1/messages/subscription');
+            if (res.ok) setSubscription(await res.json());
+        } catch (e) {
+            notify('error', t('op_failed', lang), t('err_network', lang));
+        }
+    };
+
+    // Every type selected is stored as none, which also takes in types added later
+    const toggleSubscriptionType = (type: string) => {
+        if (!subscription) return;
+        const types = subscription.types ? subscription.types.split(',') : MESSAGE_TYPES;
+        const next = types.includes(type) ? types.filter(x => x !== type) : [...types, type];
+        if (next.length === 0) return;
+        setSubscription({ ...subscription, types: next.length === MESSAGE_TYPES.length ? '' : next.join(',') });
+    };
+
+    // The inbox and the pushes only cover the subscription, so reload once it changed
+    const saveSubscription = async () => {
+        if (!subscription || isSavingSubscription) return;
+        setIsSavingSubscription(true);
+        try {
+            const res = await authFetch('/api/v1/messages/subscription', { method: 'POST', body: JSON.stringify(subscription) });
+            if (res.ok) {
+                notify('success', t('op_success', lang), t('mc_subscription_saved', lang));
+                setSubscription(null);
+                window.location.reload();
+            } else {
+                notify('error', t('op_failed', lang), (await res.json()).error);
+            }
+        } catch (e) {
+            notify('error', t('op_failed', lang), t('err_network', lang));
+        }
+        setIsSavingSubscription(false);
+    };
+
+    const statusBadge = (status: AlertStatus = 'firing') =>
+        status === 'resolved' ? 'success' : status === 'acknowledged' ? 'info' : 'warn';
+
     const getTypeIcon = (type: string) => {
         switch(type) {
             case 'security': return <AlertTriangle className="text-ark-danger" size={18} />;
@@ -33,9 +117,9 @@ export const MessageCenter: React.FC = () => {
         setPendingMessages(prev => new Set(prev).add(id));
         const success = await deleteMessage(id);
         if (success) {
-            notify('success', t('op_success', lang), t('op_item_deleted', lang));
+            notify('success', t('op_success', lang), t('mc_dismissed', lang));
         } else {
-            notify('error', t('op_failed', lang), 'Failed to delete message');
+            notify('error', t('op_failed', lang), t('mc_dismiss_failed', lang));
         }
         setPendingMessages(prev => {
             const next = new Set(prev);
@@ -58,9 +142,14 @@ export const MessageCenter: React.FC = () => {
                 title={t('mc_title', lang)} 
                 subtitle={t('mc_subtitle', lang)}
                 extra={
-                    <ArkButton variant="ghost" size="sm" onClick={handleMarkAllRead} loading={isMarkingAll}>
-                        <MailOpen size={14} className="mr-2" /> {t('mc_mark_all_read', lang)}
-                    </ArkButton>
+                    <div className="flex gap-2">
+                        <ArkButton variant="ghost" size="sm" onClick={openSubscription}>
+                            <SlidersHorizontal size={14} className="mr-2" /> {t('mc_subscription', lang)}
+                        </ArkButton>
+                        <ArkButton variant="ghost" size="sm" onClick={handleMarkAllRead} loading={isMarkingAll}>
+                            <MailOpen size={14} className="mr-2" /> {t('mc_mark_all_read', lang)}
+                        </ArkButton>
+                    </div>
                 }
             />
 
@@ -79,7 +168,7 @@ export const MessageCenter: React.FC = () => {
                         className={`text-left px-4 py-3 text-sm font-bold border-l-2 transition-all flex justify-between items-center ${filter === 'unread' ? 'bg-ark-active/20 text-ark-primary border-ark-primary' : 'bg-transparent text-ark-subtext border-transparent hover:bg-ark-active/10 hover:text-ark-text'}`}
                     >
                         {t('mc_filter_unread', lang)}
-                        <span className="text-xs bg-ark-bg px-2 py-0.5 rounded-full opacity-70">{messages.filter(m => !m.read).length}</span>
+                        <span className="text-xs bg-ark-bg px-2 py-0.5 rounded-full opacity-70">{unreadCount}</span>
                     </button>
                     <div className="h-[1px] bg-ark-border my-2 hidden md:block"></div>
                     <button 
@@ -94,6 +183,17 @@ export const MessageCenter: React.FC = () => {
                     >
                         {t('mc_filter_security', lang)}
                     </button>
+                    <div className="h-[1px] bg-ark-border my-2 hidden md:block"></div>
+                    {STATUS_FILTERS.map(status => (
+                        <button 
+                            key={status}
+                            onClick={() => setFilter(status)}
+                            className={`text-left px-4 py-3 text-sm font-bold border-l-2 transition-all flex justify-between items-center ${filter === status ? 'bg-ark-active/20 text-ark-primary border-ark-primary' : 'bg-transparent text-ark-subtext border-transparent hover:bg-ark-active/10 hover:text-ark-text'}`}
+                        >
+                            {t(`mc_status_${status}`, lang)}
+                            <span className="text-xs bg-ark-bg px-2 py-0.5 rounded-full opacity-70">{messages.filter(m => (m.status || 'firing') === status).length}</span>
+                        </button>
+                    ))}
                 </div>
 
                 {/* Message List */}
@@ -109,9 +209,13 @@ export const MessageCenter: React.FC = () => {
                                         <div className="flex justify-between items-start mb-1">
                                             <h3 className={`text-base font-bold ${!msg.read ? 'text-ark-text' : 'text-ark-subtext'}`}>
                                                 {msg.title}
+                                                {(msg.count || 1) > 1 && <span className="ml-2 text-xs font-mono text-ark-primary">×{msg.count}</span>}
                                                 {!msg.read && <span className="ml-2 w-2 h-2 inline-block bg-ark-primary rounded-full animate-pulse"></span>}
                                             </h3>
-                                            <span className="text-xs font-mono text-ark-subtext whitespace-nowrap ml-4">{msg.time}</span>
+                                            <span className="text-xs font-mono text-ark-subtext whitespace-nowrap ml-4 text-right">
+                                                {formatDateTime(msg.time)}
+                                                {(msg.count || 1) > 1 && msg.lastSeen && <span className="block">{t('mc_last_seen', lang)} {formatDateTime(msg.lastSeen)}</span>}
+                                            </span>
                                         </div>
                                         <p className="text-sm text-ark-text/80 leading-relaxed mb-3">
                                             {msg.content}
@@ -120,7 +224,26 @@ export const MessageCenter: React.FC = () => {
                                             <span className="text-[10px] font-mono uppercase tracking-wider text-ark-subtext bg-ark-bg px-2 py-0.5 rounded-sm border border-ark-border">
                                                 {msg.type === 'system' ? t('mc_type_system', lang) : msg.type === 'security' ? t('mc_type_security', lang) : t('mc_type_report', lang)}
                                             </span>
+                                            <ArkBadge type={statusBadge(msg.status)}>{t(`mc_status_${msg.status || 'firing'}`, lang)}</ArkBadge>
+                                            {msg.assignee && (
+                                                <span className="text-xs font-mono text-ark-subtext flex items-center gap-1">
+                                                    <UserCheck size={12} /> {msg.assignee}
+                                                </span>
+                                            )}
                                             <div className="flex-1"></div>
+                                            {(msg.status || 'firing') === 'firing' && (
+                                                <button onClick={() => updateAlert(msg.id, 'ack')} className="text-xs text-ark-primary hover:underline font-mono" disabled={pendingMessages.has(msg.id)}>
+                                                    {t('mc_acknowledge', lang)}
+                                                </button>
+                                            )}
+                                            {msg.status !== 'resolved' && (
+                                                <button onClick={() => updateAlert(msg.id, 'resolve')} className="text-xs text-ark-primary hover:underline font-mono" disabled={pendingMessages.has(msg.id)}>
+                                                    {t('mc_resolve', lang)}
+                                                </button>
+                                            )}
+                                            <button onClick={() => { setAssigning(msg); setAssignee(msg.assignee || user?.username || ''); }} className="text-xs text-ark-primary hover:underline font-mono">
+                                                {t('mc_assign', lang)}
+                                            </button>
                                             <button 
                                                 onClick={() => toggleRead(msg.id)}
                                                 className="text-xs text-ark-primary hover:underline font-mono"
@@ -137,7 +260,7 @@ export const MessageCenter: React.FC = () => {
                                                 ) : (
                                                     <Trash2 size={14} />
                                                 )}
-                                                {t('mc_delete', lang)}
+                                                {t('mc_dismiss', lang)}
                                             </button>
                                         </div>
                                     </div>
@@ -152,6 +275,60 @@ export const MessageCenter: React.FC = () => {
                     </div>
                 </div>
             </div>
+
+            <ArkModal
+                isOpen={!!assigning}
+                onClose={() => setAssigning(null)}
+                title={t('mc_assign', lang)}
+                icon={<UserCheck size={18} />}
+                footer={<>
+                    <ArkButton variant="ghost" onClick={() => setAssigning(null)}>{t('btn_cancel', lang)}</ArkButton>
+                    <ArkButton variant="primary" onClick={handleAssign}>{t('btn_save', lang)}</ArkButton>
+                </>}
+            >
+                <div className="space-y-2">
+                    <p className="text-xs text-ark-subtext">{t('mc_assign_hint', lang)}</p>
+                    <ArkInput value={assignee} onChange={e => setAssignee(e.target.value)} placeholder={t('mc_assignee', lang)} />
+                </div>
+            </ArkModal>
+
+            <ArkModal
+                isOpen={!!subscription}
+                onClose={() => setSubscription(null)}
+                title={t('mc_subscription', lang)}
+                icon={<SlidersHorizontal size={18} />}
+                footer={<>
+                    <ArkButton variant="ghost" onClick={() => setSubscription(null)}>{t('btn_cancel', lang)}</ArkButton>
+                    <ArkButton variant="primary" onClick={saveSubscription} loading={isSavingSubscription}>{t('btn_save', lang)}</ArkButton>
+                </>}
+            >
+                {subscription && (
+                    <div className="space-y-4">
+                        <p className="text-xs text-ark-subtext">{t('mc_subscription_hint', lang)}</p>
+                        <div className="space-y-1">
+                            <label className="text-xs font-bold text-ark-subtext uppercase">{t('mc_subscription_types', lang)}</label>
+                            <div className="flex flex-wrap gap-2 pt-1">
+                                {MESSAGE_TYPES.map(type => {
+                                    const on = !subscription.types || subscription.types.split(',').includes(type);
+                                    return (
+                                        <button key={type} type="button" onClick={() => toggleSubscriptionType(type)}
+                                            className={`px-2 py-1 text-xs border transition-colors ${on ? 'border-ark-primary bg-ark-primary/10 text-ark-primary' : 'border-ark-border text-ark-subtext hover:text-ark-text'}`}>
+                                            {t(`mc_type_${type}`, lang)}
+                                        </button>
+                                    );
+                                })}
+                            </div>
+                        </div>
+                        <div className="space-y-1">
+                            <label className="text-xs font-bold text-ark-subtext uppercase">{t('mc_subscription_min_severity', lang)}</label>
+                            <select className={selectClass} value={subscription.minSeverity || 'info'}
+                                onChange={e => setSubscription({ ...subscription, minSeverity: e.target.value as MessageSubscription['minSeverity'] })}>
+                                {SUBSCRIPTION_SEVERITIES.map(s => <option key={s} value={s}>{t(`nc_sev_${s}`, lang)}</option>)}
+                            </select>
+                        </div>
+                    </div>
+                )}
+            </ArkModal>
         </div>
     );
 };
\ No newline at end of file
@@ -0,0 +1,709 @@
+import React, { useState, useEffect, useCallback } from 'react';
+import { ArkButton, ArkBadge, ArkInput, ArkModal, ArkLoading } from './ArknightsUI';
+import { useApp } from '../AppContext';
+import { t } from '../i18n';
+import { BellRing, Plus, RefreshCw, FileEdit, Trash2, Send, RotateCcw, Route, ScrollText, BellOff, Layers } from 'lucide-react';
+import { NotificationChannel, NotificationChannelType, NotificationRule, NotificationDelivery, AlertSilence, NodeStatus } from '../types';
+import { useNotification } from './NotificationSystem';
+import { formatDateTime } from '../time';
+
+const CHANNEL_TYPES: NotificationChannelType[] = ['webhook', 'email', 'slack', 'dingtalk', 'feishu', 'wecom'];
+const SEVERITIES = ['info', 'low', 'medium', 'warning', 'high', 'critical'];
+const PAGE_SIZE = 20;
+
+const emptyChannel = (): NotificationChannel => ({
+    id: '', name: '', type: 'webhook', enabled: true, lang: 'zh',
+    titleTemplate: '', bodyTemplate: '', maxAttempts: 5, settings: {},
+});
+
+const emptyRule = (): NotificationRule => ({
+    id: '', name: '', enabled: true, minSeverity: 'warning', events: '', channels: '',
+});
+
+const Field: React.FC<{ label: string, hint?: string, children: React.ReactNode }> = ({ label, hint, children }) => (
+    <label className="block space-y-1">
+        <span className="text-xs font-mono text-ark-subtext">{label}</span>
+        {children}
+        {hint && <span className="block text-[10px] text-ark-subtext/70">{hint}</span>}
+    </label>
+);
+
+const selectClass = "w-full bg-ark-bg border-b-2 border-ark-border px-3 py-2 text-sm text-ark-text focus:outline-none focus:border-ark-primary font-mono";
+
+const toggleList = (list: string, item: string) => {
+    const items = list.split(',').filter(Boolean);
+    return (items.includes(item) ? items.filter(i => i !== item) : [...items, item]).join(',');
+};
+
+// toLocalInput renders a time for a datetime-local input in the browser's zone
+const toLocalInput = (d: Date) => {
+    const pad = (n: number) => String(n).padStart(2, '0');
+    return `${d.getFullYear()}-${pad(d.getMonth() + 1)}-${pad(d.getDate())}T${pad(d.getHours())}:${pad(d.getMinutes())}`;
+};
+
+const emptySilence = () => ({
+    node: '', event: '', reason: '',
+    startsAt: toLocalInput(new Date()),
+    endsAt: toLocalInput(new Date(Date.now() + 2 * 3600 * 1000)),
+});
+
+const statusBadge = (status: string) =>
+    status === 'sent' ? 'success' : status === 'failed' ? 'error' : 'warn';
+
+export const NotificationChannels: React.FC = () => {
+    const { lang, authFetch } = useApp();
+    const { notify } = useNotification();
+    const [channels, setChannels] = useState<NotificationChannel[]>([]);
+    const [rules, setRules] = useState<NotificationRule[]>([]);
+    const [events, setEvents] = useState<string[]>([]);
+    const [types, setTypes] = useState<string[]>([]);
+    const [deliveries, setDeliveries] = useState<NotificationDelivery[]>([]);
+    const [deliveryTotal, setDeliveryTotal] = useState(0);
+    const [deliveryPage, setDeliveryPage] = useState(0);
+    const [statusFilter, setStatusFilter] = useState('');
+    const [loading, setLoading] = useState(true);
+    const [editChannel, setEditChannel] = useState<NotificationChannel | null>(null);
+    const [editRule, setEditRule] = useState<NotificationRule | null>(null);
+    const [saving, setSaving] = useState(false);
+    const [testing, setTesting] = useState<string | null>(null);
+    const [silences, setSilences] = useState<AlertSilence[]>([]);
+    const [nodes, setNodes] = useState<NodeStatus[]>([]);
+    const [alertCfg, setAlertCfg] = useState({ dedupeWindow: 10, groupWindow: 10, rateLimit: 30 });
+    const [editSilence, setEditSilence] = useState<ReturnType<typeof emptySilence> | null>(null);
+
+    const fetchConfig = useCallback(async () => {
+        setLoading(true);
+        try {
+            const [chRes, ruleRes] = await Promise.all([
+                authFetch('/api/v1/notifications/channels'),
+                authFetch('/api/v1/notifications/rules'),
+            ]);
+            if (chRes.ok) setChannels(await chRes.json());
+            if (ruleRes.ok) {
+                const data = await ruleRes.json();
+                setRules(data.rules || []);
+                setEvents(data.events || []);
+                setTypes(data.types || []);
+            }
+        } catch (e) {
+            console.error("Failed to fetch notification settings", e);
+        } finally {
+            setLoading(false);
+        }
+    }, [authFetch]);
+
+    const fetchDeliveries = useCallback(async () => {
+        const params = new URLSearchParams({ limit: String(PAGE_SIZE), offset: String(deliveryPage * PAGE_SIZE) });
+        if (statusFilter) params.set('status', statusFilter);
+        try {
+            const res = await authFetch(`/api/v1/notifications/deliveries?${params}`);
+            if (res.ok) {
+                setDeliveries(await res.json());
+                setDeliveryTotal(parseInt(res.headers.get('X-Total-Count') || '0'));
+            }
+        } catch (e) {
+            console.error("Failed to fetch deliveries", e);
+        }
+    }, [authFetch, deliveryPage, statusFilter]);
+
+    const fetchAlerts = useCallback(async () => {
+        try {
+            const [silRes, cfgRes, nodeRes] = await Promise.all([
+                authFetch('/api/v1/alerts/silences'),
+                authFetch('/api/v1/alerts/config'),
+                authFetch('/api/v1/nodes'),
+            ]);
+            if (silRes.ok) setSilences(await silRes.json());
+            if (cfgRes.ok) setAlertCfg(await cfgRes.json());
+            if (nodeRes.ok) setNodes(await nodeRes.json());
+        } catch (e) {
+            console.error("Failed to fetch alert settings", e);
+        }
+    }, [authFetch]);
+
+    useEffect(() => { fetchConfig(); }, [fetchConfig]);
+    useEffect(() => { fetchAlerts(); }, [fetchAlerts]);
+    useEffect(() => { fetchDeliveries(); }, [fetchDeliveries]);
+
+    const request = async (url: string, method: string, body?: unknown) => {
+        try {
+            const res = await authFetch(url, { method, body: body === undefined ? undefined : JSON.stringify(body) });
+            const data = await res.json().catch(() => ({}));
+            if (!res.ok) {
+                notify('error', t('op_failed', lang), data.error || res.statusText);
+                return null;
+            }
+            return data;
+        } catch (e) {
+            notify('error', t('op_failed', lang), t('err_network', lang));
+            return null;
+        }
+    };
+
+    const saveChannel = async () => {
+        if (!editChannel) return;
+        setSaving(true);
+        const url = editChannel.id ? `/api/v1/notifications/channels/${editChannel.id}` : '/api/v1/notifications/channels';
+        const data = await request(url, 'POST', editChannel);
+        setSaving(false);
+        if (data) {
+            notify('success', t('op_success', lang), t('nc_channel_saved', lang));
+            setEditChannel(null);
+            fetchConfig();
+        }
+    };
+
+    const deleteChannel = async (ch: NotificationChannel) => {
+        if (!window.confirm(t('nc_confirm_delete_channel', lang, { name: ch.name }))) return;
+        if (await request(`/api/v1/notifications/channels/${ch.id}`, 'DELETE')) fetchConfig();
+    };
+
+    const testChannel = async (ch: NotificationChannel) => {
+        setTesting(ch.id);
+        const d: NotificationDelivery | null = await request(`/api/v1/notifications/channels/${ch.id}/test`, 'POST');
+        setTesting(null);
+        if (d) {
+            if (d.status === 'sent') notify('success', t('op_success', lang), t('nc_test_sent', lang));
+            else notify('error', t('nc_test_failed', lang), d.lastError);
+            fetchDeliveries();
+        }
+    };
+
+    const saveRule = async () => {
+        if (!editRule) return;
+        setSaving(true);
+        const url = editRule.id ? `/api/v1/notifications/rules/${editRule.id}` : '/api/v1/notifications/rules';
+        const data = await request(url, 'POST', editRule);
+        setSaving(false);
+        if (data) {
+            notify('success', t('op_success', lang), t('nc_rule_saved', lang));
+            setEditRule(null);
+            fetchConfig();
+        }
+    };
+
+    const deleteRule = async (rule: NotificationRule) => {
+        if (!window.confirm(t('nc_confirm_delete_rule', lang, { name: rule.name }))) return;
+        if (await request(`/api/v1/notifications/rules/${rule.id}`, 'DELETE')) fetchConfig();
+    };
+
+    const retryDelivery = async (d: NotificationDelivery) => {
+        if (await request(`/api/v1/notifications/deliveries/${d.id}/retry`, 'POST')) {
+            notify('success', t('op_success', lang), t('nc_retry_queued', lang));
+            fetchDeliveries();
+        }
+    };
+
+    const saveAlertConfig = async () => {
+        if (await request('/api/v1/alerts/config', 'POST', alertCfg)) {
+            notify('success', t('op_success', lang), t('nc_alert_config_saved', lang));
+        }
+    };
+
+    const saveSilence = async () => {
+        if (!editSilence) return;
+        setSaving(true);
+        const data = await request('/api/v1/alerts/silences', 'POST', {
+            ...editSilence,
+            startsAt: new Date(editSilence.startsAt).toISOString(),
+            endsAt: new Date(editSilence.endsAt).toISOString(),
+        });
+        setSaving(false);
+        if (data) {
+            setEditSilence(null);
+            fetchAlerts();
+        }
+    };
+
+    const endSilence = async (sil: AlertSilence) => {
+        if (await request(`/api/v1/alerts/silences/${sil.id}`, 'DELETE')) fetchAlerts();
+    };
+
+    const nodeName = (id: string) => nodes.find(n => n.id === id)?.name || id;
+    const silenceState = (sil: AlertSilence) => {
+        const now = Date.now();
+        if (new Date(sil.endsAt).getTime() <= now) return 'ended';
+        return new Date(sil.startsAt).getTime() <= now ? 'active' : 'scheduled';
+    };
+
+    const setSetting = (key: string, value: unknown) =>
+        setEditChannel(prev => prev && { ...prev, settings: { ...prev.settings, [key]: value } });
+
+    const channelName = (id: string) => channels.find(c => c.id === id)?.name || id;
+    const eventLabel = (e: string) => t(`nc_event_${e}`, lang);
+    const pages = Math.max(1, Math.ceil(deliveryTotal / PAGE_SIZE));
+
+    return (
+        <div className="flex flex-col gap-4 pb-6 min-h-full">
+            {/* Description Block */}
+            <div className="bg-ark-panel border border-ark-border p-6 shadow-sm">
+                <div className="flex items-center gap-2 mb-3 font-bold text-ark-text">
+                    <BellRing className="text-ark-primary" size={20} />
+                    {t('nc_title', lang)}
+                </div>
+                <div className="text-xs text-ark-subtext font-mono space-y-1.5 leading-relaxed pl-7">
+                    <p>{t('nc_desc', lang)}</p>
+                    <p>• {t('nc_desc_rules', lang)}</p>
+                    <p>• {t('nc_desc_retry', lang)}</p>
+                </div>
+            </div>
+
+            {/* Channels */}
+            <div className="bg-ark-panel border border-ark-border shadow-sm relative">
+                {loading && <ArkLoading label="FETCHING_CHANNELS" />}
+                <div className="flex items-center justify-between p-4 border-b border-ark-border">
+                    <div className="flex items-center gap-2 text-sm font-bold text-ark-text">
+                        <Send size={16} className="text-ark-primary" /> {t('nc_channels', lang)}
+                    </div>
+                    <div className="flex gap-2">
+                        <ArkButton variant="ghost" className="h-[32px] px-4" onClick={fetchConfig} disabled={loading}>
+                            <RefreshCw size={14} className={`mr-1 ${loading ? 'animate-spin' : ''}`} /> {t('refresh', lang)}
+                        </ArkButton>
+                        <ArkButton variant="primary" size="sm" onClick={() => setEditChannel(emptyChannel())}>
+                            <Plus size={14} className="mr-1" /> {t('nc_add_channel', lang)}
+                        </ArkButton>
+                    </div>
+                </div>
+                <div className="overflow-x-auto custom-scrollbar">
+                    <table className="w-full text-left text-sm min-w-[800px]">
+                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                            <tr>
+                                <th className="p-4">{t('nc_col_name', lang)}</th>
+                                <th className="p-4">{t('nc_col_type', lang)}</th>
+                                <th className="p-4">{t('nc_col_target', lang)}</th>
+                                <th className="p-4">{t('nc_col_lang', lang)}</th>
+                                <th className="p-4">{t('nc_col_status', lang)}</th>
+                                <th className="p-4 text-center">{t('nc_col_op', lang)}</th>
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                            {channels.length === 0 && (
+                                <tr><td colSpan={6} className="p-6 text-center text-ark-subtext">{t('nc_no_channels', lang)}</td></tr>
+                            )}
+                            {channels.map(ch => (
+                                <tr key={ch.id} className="hover:bg-ark-active/5 transition-colors">
+                                    <td className="p-4 text-ark-text font-bold">{ch.name}</td>
+                                    <td className="p-4 text-ark-text">{t(`nc_type_${ch.type}`, lang)}</td>
+                                    <td className="p-4 text-ark-subtext break-all">
+                                        {ch.type === 'email' ? `${ch.settings.host}:${ch.settings.port} → ${ch.settings.to}` : ch.settings.url}
+                                    </td>
+                                    <td className="p-4 text-ark-subtext uppercase">{ch.lang}</td>
+                                    <td className="p-4">
+                                        <ArkBadge type={ch.enabled ? 'success' : 'neutral'}>{t(ch.enabled ? 'nc_enabled' : 'nc_disabled', lang)}</ArkBadge>
+                                    </td>
+                                    <td className="p-4">
+                                        <div className="flex items-center justify-center gap-3">
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_test', lang)} onClick={() => testChannel(ch)} disabled={testing === ch.id}>
+                                                {testing === ch.id ? <RefreshCw size={14} className="animate-spin" /> : <Send size={14} />}
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_edit', lang)} onClick={() => setEditChannel({ ...ch, settings: { ...ch.settings } })}>
+                                                <FileEdit size={14} />
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-red-500 transition-colors" title={t('nc_delete', lang)} onClick={() => deleteChannel(ch)}>
+                                                <Trash2 size={14} />
+                                            </button>
+                                        </div>
+                                    </td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+
+            {/* Routing Rules */}
+            <div className="bg-ark-panel border border-ark-border shadow-sm">
+                <div className="flex items-center justify-between p-4 border-b border-ark-border">
+                    <div className="flex items-center gap-2 text-sm font-bold text-ark-text">
+                        <Route size={16} className="text-ark-primary" /> {t('nc_rules', lang)}
+                    </div>
+                    <ArkButton variant="primary" size="sm" onClick={() => setEditRule(emptyRule())} disabled={channels.length === 0}>
+                        <Plus size={14} className="mr-1" /> {t('nc_add_rule', lang)}
+                    </ArkButton>
+                </div>
+                <div className="overflow-x-auto custom-scrollbar">
+                    <table className="w-full text-left text-sm min-w-[800px]">
+                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                            <tr>
+                                <th className="p-4">{t('nc_col_name', lang)}</th>
+                                <th className="p-4">{t('nc_col_min_severity', lang)}</th>
+                                <th className="p-4">{t('nc_col_events', lang)}</th>
+                                <th className="p-4">{t('nc_col_channels', lang)}</th>
+                                <th className="p-4">{t('nc_col_status', lang)}</th>
+                                <th className="p-4 text-center">{t('nc_col_op', lang)}</th>
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                            {rules.length === 0 && (
+                                <tr><td colSpan={6} className="p-6 text-center text-ark-subtext">{t('nc_no_rules', lang)}</td></tr>
+                            )}
+                            {rules.map(rule => (
+                                <tr key={rule.id} className="hover:bg-ark-active/5 transition-colors">
+                                    <td className="p-4 text-ark-text font-bold">{rule.name}</td>
+                                    <td className="p-4 text-ark-text">{t(`nc_sev_${rule.minSeverity}`, lang)}</td>
+                                    <td className="p-4 text-ark-subtext">
+                                        {rule.events ? rule.events.split(',').map(eventLabel).join(', ') : t('nc_all_events', lang)}
+                                    </td>
+                                    <td className="p-4 text-ark-subtext">{rule.channels.split(',').filter(Boolean).map(channelName).join(', ')}</td>
+                                    <td className="p-4">
+                                        <ArkBadge type={rule.enabled ? 'success' : 'neutral'}>{t(rule.enabled ? 'nc_enabled' : 'nc_disabled', lang)}</ArkBadge>
+                                    </td>
+                                    <td className="p-4">
+                                        <div className="flex items-center justify-center gap-3">
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_edit', lang)} onClick={() => setEditRule({ ...rule })}>
+                                                <FileEdit size={14} />
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-red-500 transition-colors" title={t('nc_delete', lang)} onClick={() => deleteRule(rule)}>
+                                                <Trash2 size={14} />
+                                            </button>
+                                        </div>
+                                    </td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+
+            {/* Folding and Rate Limit */}
+            <div className="bg-ark-panel border border-ark-border shadow-sm">
+                <div className="flex items-center gap-2 p-4 border-b border-ark-border text-sm font-bold text-ark-text">
+                    <Layers size={16} className="text-ark-primary" /> {t('nc_alert_folding', lang)}
+                </div>
+                <div className="p-4 grid grid-cols-1 md:grid-cols-4 gap-4 items-end">
+                    <Field label={t('nc_dedupe_window', lang)} hint={t('nc_dedupe_window_hint', lang)}>
+                        <ArkInput type="number" min={0} max={1440} value={alertCfg.dedupeWindow} onChange={e => setAlertCfg({ ...alertCfg, dedupeWindow: parseInt(e.target.value) || 0 })} />
+                    </Field>
+                    <Field label={t('nc_group_window', lang)} hint={t('nc_group_window_hint', lang)}>
+                        <ArkInput type="number" min={0} max={1440} value={alertCfg.groupWindow} onChange={e => setAlertCfg({ ...alertCfg, groupWindow: parseInt(e.target.value) || 0 })} />
+                    </Field>
+                    <Field label={t('nc_rate_limit', lang)} hint={t('nc_rate_limit_hint', lang)}>
+                        <ArkInput type="number" min={0} value={alertCfg.rateLimit} onChange={e => setAlertCfg({ ...alertCfg, rateLimit: parseInt(e.target.value) || 0 })} />
+                    </Field>
+                    <div className="flex justify-end">
+                        <ArkButton variant="primary" size="sm" onClick={saveAlertConfig}>{t('btn_save', lang)}</ArkButton>
+                    </div>
+                </div>
+            </div>
+
+            {/* Silences */}
+            <div className="bg-ark-panel border border-ark-border shadow-sm">
+                <div className="flex items-center justify-between p-4 border-b border-ark-border">
+                    <div className="flex items-center gap-2 text-sm font-bold text-ark-text">
+                        <BellOff size={16} className="text-ark-primary" /> {t('nc_silences', lang)}
+                    </div>
+                    <ArkButton variant="primary" size="sm" onClick={() => setEditSilence(emptySilence())}>
+                        <Plus size={14} className="mr-1" /> {t('nc_add_silence', lang)}
+                    </ArkButton>
+                </div>
+                <div className="overflow-x-auto custom-scrollbar">
+                    <table className="w-full text-left text-sm min-w-[800px]">
+                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                            <tr>
+                                <th className="p-4">{t('nc_col_node', lang)}</th>
+                                <th className="p-4">{t('nc_col_event', lang)}</th>
+                                <th className="p-4">{t('nc_col_window', lang)}</th>
+                                <th className="p-4">{t('nc_col_reason', lang)}</th>
+                                <th className="p-4">{t('nc_col_suppressed', lang)}</th>
+                                <th className="p-4">{t('nc_col_status', lang)}</th>
+                                <th className="p-4 text-center">{t('nc_col_op', lang)}</th>
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                            {silences.length === 0 && (
+                                <tr><td colSpan={7} className="p-6 text-center text-ark-subtext">{t('nc_no_silences', lang)}</td></tr>
+                            )}
+                            {silences.map(sil => {
+                                const state = silenceState(sil);
+                                return (
+                                    <tr key={sil.id} className="hover:bg-ark-active/5 transition-colors">
+                                        <td className="p-4 text-ark-text font-bold">{sil.node ? nodeName(sil.node) : t('nc_all_nodes', lang)}</td>
+                                        <td className="p-4 text-ark-text">{sil.event ? eventLabel(sil.event) : t('nc_all_events', lang)}</td>
+                                        <td className="p-4 text-ark-subtext whitespace-nowrap">{formatDateTime(sil.startsAt)} → {formatDateTime(sil.endsAt)}</td>
+                                        <td className="p-4 text-ark-subtext">{sil.reason}{sil.createdBy && <span className="block opacity-70">{sil.createdBy}</span>}</td>
+                                        <td className="p-4 text-ark-text">{sil.suppressed}</td>
+                                        <td className="p-4">
+                                            <ArkBadge type={state === 'active' ? 'warn' : state === 'scheduled' ? 'info' : 'neutral'}>{t(`nc_silence_${state}`, lang)}</ArkBadge>
+                                        </td>
+                                        <td className="p-4 text-center">
+                                            {state !== 'ended' && (
+                                                <button className="text-ark-subtext hover:text-red-500 transition-colors" title={t('nc_end_silence', lang)} onClick={() => endSilence(sil)}>
+                                                    <Trash2 size={14} />
+                                                </button>
+                                            )}
+                                        </td>
+                                    </tr>
+                                );
+                            })}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+
+            {/* Delivery Log */}
+            <div className="flex-1 flex flex-col bg-ark-panel border border-ark-border shadow-sm">
+                <div className="flex items-center justify-between p-4 border-b border-ark-border">
+                    <div className="flex items-center gap-2 text-sm font-bold text-ark-text">
+                        <ScrollText size={16} className="text-ark-primary" /> {t('nc_deliveries', lang)}
+                    </div>
+                    <div className="flex gap-2 items-center">
+                        <select className={`${selectClass} h-[32px] py-0 w-36`} value={statusFilter} onChange={e => { setStatusFilter(e.target.value); setDeliveryPage(0); }}>
+                            <option value="">{t('filter_all', lang)}</option>
+                            {['pending', 'sent', 'failed'].map(s => <option key={s} value={s}>{t(`nc_status_${s}`, lang)}</option>)}
+                        </select>
+                        <ArkButton variant="ghost" className="h-[32px] px-4" onClick={fetchDeliveries}>
+                            <RefreshCw size={14} className="mr-1" /> {t('refresh', lang)}
+                        </ArkButton>
+                    </div>
+                </div>
+                <div className="overflow-x-auto custom-scrollbar flex-1">
+                    <table className="w-full text-left text-sm min-w-[1000px]">
+                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                            <tr>
+                                <th className="p-4">{t('nc_col_time', lang)}</th>
+                                <th className="p-4">{t('nc_col_event', lang)}</th>
+                                <th className="p-4">{t('nc_col_channel', lang)}</th>
+                                <th className="p-4 w-[30%]">{t('nc_col_title', lang)}</th>
+                                <th className="p-4">{t('nc_col_status', lang)}</th>
+                                <th className="p-4">{t('nc_col_attempts', lang)}</th>
+                                <th className="p-4 text-center">{t('nc_col_op', lang)}</th>
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                            {deliveries.length === 0 && (
+                                <tr><td colSpan={7} className="p-6 text-center text-ark-subtext">{t('nc_no_deliveries', lang)}</td></tr>
+                            )}
+                            {deliveries.map(d => (
+                                <tr key={d.id} className="hover:bg-ark-active/5 transition-colors">
+                                    <td className="p-4 text-ark-subtext whitespace-nowrap">{formatDateTime(d.createdAt)}</td>
+                                    <td className="p-4 text-ark-text">{d.event === 'test' ? t('nc_test', lang) : eventLabel(d.event)}</td>
+                                    <td className="p-4 text-ark-text">{d.channelName}</td>
+                                    <td className="p-4 text-ark-subtext" title={d.body}>
+                                        <div className="line-clamp-2">{d.title}</div>
+                                        {d.lastError && <div className="text-red-500 mt-1 break-all">{d.statusCode ? `[${d.statusCode}] ` : ''}{d.lastError}</div>}
+                                    </td>
+                                    <td className="p-4">
+                                        <ArkBadge type={statusBadge(d.status)}>{t(`nc_status_${d.status}`, lang)}</ArkBadge>
+                                        {d.status === 'pending' && d.nextAttempt && (
+                                            <div className="text-[10px] text-ark-subtext mt-1">{formatDateTime(d.nextAttempt)}</div>
+                                        )}
+                                    </td>
+                                    <td className="p-4 text-ark-text">{d.attempts}</td>
+                                    <td className="p-4 text-center">
+                                        {d.status === 'failed' && d.title && (
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_retry', lang)} onClick={() => retryDelivery(d)}>
+                                                <RotateCcw size={14} />
+                                            </button>
+                                        )}
+                                    </td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+                <div className="p-3 border-t border-ark-border bg-ark-bg flex justify-end items-center gap-4 text-xs font-mono text-ark-subtext">
+                    <span>{t('total_records', lang)} {deliveryTotal}</span>
+                    <div className="flex gap-1 items-center">
+                        <ArkButton variant="ghost" size="sm" disabled={deliveryPage === 0} onClick={() => setDeliveryPage(p => p - 1)}>‹</ArkButton>
+                        <span className="px-2">{deliveryPage + 1} / {pages}</span>
+                        <ArkButton variant="ghost" size="sm" disabled={deliveryPage + 1 >= pages} onClick={() => setDeliveryPage(p => p + 1)}>›</ArkButton>
+                    </div>
+                </div>
+            </div>
+
+            {/* Channel Editor */}
+            <ArkModal
+                isOpen={!!editChannel}
+                onClose={() => setEditChannel(null)}
+                title={t(editChannel?.id ? 'nc_edit_channel' : 'nc_add_channel', lang)}
+                icon={<Send size={18} />}
+                maxWidth="max-w-2xl"
+                footer={<>
+                    <ArkButton variant="ghost" onClick={() => setEditChannel(null)}>{t('btn_cancel', lang)}</ArkButton>
+                    <ArkButton variant="primary" onClick={saveChannel} disabled={saving}>{t('btn_save', lang)}</ArkButton>
+                </>}
+            >
+                {editChannel && (
+                    <div className="grid grid-cols-1 md:grid-cols-2 gap-4 max-h-[65vh] overflow-y-auto custom-scrollbar pr-1">
+                        <Field label={t('nc_col_name', lang)}>
+                            <ArkInput value={editChannel.name} onChange={e => setEditChannel({ ...editChannel, name: e.target.value })} />
+                        </Field>
+                        <Field label={t('nc_col_type', lang)}>
+                            <select className={selectClass} value={editChannel.type} disabled={!!editChannel.id}
+                                onChange={e => setEditChannel({ ...editChannel, type: e.target.value as NotificationChannelType, settings: {} })}>
+                                {CHANNEL_TYPES.map(ct => <option key={ct} value={ct}>{t(`nc_type_${ct}`, lang)}</option>)}
+                            </select>
+                        </Field>
+
+                        {editChannel.type === 'email' ? (<>
+                            <Field label={t('nc_smtp_host', lang)}>
+                                <ArkInput value={editChannel.settings.host || ''} onChange={e => setSetting('host', e.target.value)} />
+                            </Field>
+                            <Field label={t('nc_smtp_port', lang)} hint={t('nc_smtp_port_hint', lang)}>
+                                <ArkInput type="number" min={0} max={65535} value={editChannel.settings.port || ''} onChange={e => setSetting('port', parseInt(e.target.value) || 0)} />
+                            </Field>
+                            <Field label={t('nc_smtp_tls', lang)}>
+                                <select className={selectClass} value={editChannel.settings.tls || 'starttls'} onChange={e => setSetting('tls', e.target.value)}>
+                                    <option value="starttls">STARTTLS</option>
+                                    <option value="tls">TLS</option>
+                                    <option value="none">{t('nc_smtp_tls_none', lang)}</option>
+                                </select>
+                            </Field>
+                            <Field label={t('nc_smtp_from', lang)}>
+                                <ArkInput value={editChannel.settings.from || ''} onChange={e => setSetting('from', e.target.value)} />
+                            </Field>
+                            <Field label={t('nc_smtp_to', lang)} hint={t('nc_smtp_to_hint', lang)}>
+                                <ArkInput value={editChannel.settings.to || ''} onChange={e => setSetting('to', e.target.value)} />
+                            </Field>
+                            <Field label={t('nc_smtp_username', lang)}>
+                                <ArkInput value={editChannel.settings.username || ''} onChange={e => setSetting('username', e.target.value)} />
+                            </Field>
+                            <Field label={t('nc_smtp_password', lang)} hint={editChannel.hasPassword ? t('nc_secret_kept', lang) : undefined}>
+                                <ArkInput type="password" value={editChannel.settings.password || ''} onChange={e => setSetting('password', e.target.value)} />
+                            </Field>
+                        </>) : (<>
+                            <div className="md:col-span-2">
+                                <Field label={t('nc_url', lang)} hint={t(`nc_url_hint_${editChannel.type}`, lang)}>
+                                    <ArkInput value={editChannel.settings.url || ''} onChange={e => setSetting('url', e.target.value)} />
+                                </Field>
+                            </div>
+                            {editChannel.type !== 'slack' && (
+                                <Field label={t('nc_secret', lang)} hint={editChannel.hasSecret ? t('nc_secret_kept', lang) : t(`nc_secret_hint_${editChannel.type}`, lang)}>
+                                    <ArkInput type="password" value={editChannel.settings.secret || ''} onChange={e => setSetting('secret', e.target.value)} />
+                                </Field>
+                            )}
+                        </>)}
+
+                        <Field label={t('nc_col_lang', lang)}>
+                            <select className={selectClass} value={editChannel.lang} onChange={e => setEditChannel({ ...editChannel, lang: e.target.value as 'en' | 'zh' })}>
+                                <option value="zh">中文</option>
+                                <option value="en">English</option>
+                            </select>
+                        </Field>
+                        <Field label={t('nc_max_attempts', lang)}>
+                            <ArkInput type="number" min={1} max={20} value={editChannel.maxAttempts} onChange={e => setEditChannel({ ...editChannel, maxAttempts: parseInt(e.target.value) || 0 })} />
+                        </Field>
+                        <div className="md:col-span-2">
+                            <Field label={t('nc_title_template', lang)} hint={t('nc_template_hint', lang)}>
+                                <ArkInput value={editChannel.titleTemplate} placeholder="[PRTS] {{.Title}}" onChange={e => setEditChannel({ ...editChannel, titleTemplate: e.target.value })} />
+                            </Field>
+                        </div>
+                        <div className="md:col-span-2">
+                            <Field label={t('nc_body_template', lang)}>
+                                <textarea rows={4} className={`${selectClass} resize-y`} value={editChannel.bodyTemplate} placeholder={'{{.Text}}\n\n{{.Severity}} {{.Time}}'}
+                                    onChange={e => setEditChannel({ ...editChannel, bodyTemplate: e.target.value })} />
+                            </Field>
+                        </div>
+                        <label className="flex items-center gap-2 text-xs text-ark-text">
+                            <input type="checkbox" checked={editChannel.enabled} onChange={e => setEditChannel({ ...editChannel, enabled: e.target.checked })} />
+                            {t('nc_enabled', lang)}
+                        </label>
+                        <label className="flex items-center gap-2 text-xs text-ark-text">
+                            <input type="checkbox" checked={!!editChannel.settings.insecureSkipVerify} onChange={e => setSetting('insecureSkipVerify', e.target.checked)} />
+                            {t('nc_insecure', lang)}
+                        </label>
+                    </div>
+                )}
+            </ArkModal>
+
+            {/* Silence Editor */}
+            <ArkModal
+                isOpen={!!editSilence}
+                onClose={() => setEditSilence(null)}
+                title={t('nc_add_silence', lang)}
+                icon={<BellOff size={18} />}
+                maxWidth="max-w-lg"
+                footer={<>
+                    <ArkButton variant="ghost" onClick={() => setEditSilence(null)}>{t('btn_cancel', lang)}</ArkButton>
+                    <ArkButton variant="primary" onClick={saveSilence} disabled={saving}>{t('btn_save', lang)}</ArkButton>
+                </>}
+            >
+                {editSilence && (
+                    <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+                        <Field label={t('nc_col_node', lang)}>
+                            <select className={selectClass} value={editSilence.node} onChange={e => setEditSilence({ ...editSilence, node: e.target.value })}>
+                                <option value="">{t('nc_all_nodes', lang)}</option>
+                                {nodes.map(n => <option key={n.id} value={n.id}>{n.name} ({n.id})</option>)}
+                            </select>
+                        </Field>
+                        <Field label={t('nc_col_event', lang)}>
+                            <select className={selectClass} value={editSilence.event} onChange={e => setEditSilence({ ...editSilence, event: e.target.value })}>
+                                <option value="">{t('nc_all_events', lang)}</option>
+                                {events.map(ev => <option key={ev} value={ev}>{eventLabel(ev)}</option>)}
+                            </select>
+                        </Field>
+                        <Field label={t('nc_silence_start', lang)}>
+                            <ArkInput type="datetime-local" value={editSilence.startsAt} onChange={e => setEditSilence({ ...editSilence, startsAt: e.target.value })} />
+                        </Field>
+                        <Field label={t('nc_silence_end', lang)}>
+                            <ArkInput type="datetime-local" value={editSilence.endsAt} onChange={e => setEditSilence({ ...editSilence, endsAt: e.target.value })} />
+                        </Field>
+                        <div className="md:col-span-2">
+                            <Field label={t('nc_col_reason', lang)}>
+                                <ArkInput value={editSilence.reason} onChange={e => setEditSilence({ ...editSilence, reason: e.target.value })} />
+                            </Field>
+                        </div>
+                    </div>
+                )}
+            </ArkModal>
+
+            {/* Rule Editor */}
+            <ArkModal
+                isOpen={!!editRule}
+                onClose={() => setEditRule(null)}
+                title={t(editRule?.id ? 'nc_edit_rule' : 'nc_add_rule', lang)}
+                icon={<Route size={18} />}
+                maxWidth="max-w-2xl"
+                footer={<>
+                    <ArkButton variant="ghost" onClick={() => setEditRule(null)}>{t('btn_cancel', lang)}</ArkButton>
+                    <ArkButton variant="primary" onClick={saveRule} disabled={saving}>{t('btn_save', lang)}</ArkButton>
+                </>}
+            >
+                {editRule && (
+                    <div className="space-y-4 max-h-[65vh] overflow-y-auto custom-scrollbar pr-1">
+                        <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+                            <Field label={t('nc_col_name', lang)}>
+                                <ArkInput value={editRule.name} onChange={e => setEditRule({ ...editRule, name: e.target.value })} />
+                            </Field>
+                            <Field label={t('nc_col_min_severity', lang)}>
+                                <select className={selectClass} value={editRule.minSeverity} onChange={e => setEditRule({ ...editRule, minSeverity: e.target.value })}>
+                                    {SEVERITIES.map(s => <option key={s} value={s}>{t(`nc_sev_${s}`, lang)}</option>)}
+                                </select>
+                            </Field>
+                        </div>
+                        <Field label={t('nc_col_events', lang)} hint={t('nc_events_hint', lang)}>
+                            <div className="flex flex-wrap gap-2 pt-1">
+                                {[...types, ...events].filter((e, i, all) => all.indexOf(e) === i).map(e => {
+                                    const on = editRule.events.split(',').includes(e);
+                                    return (
+                                        <button key={e} type="button" onClick={() => setEditRule({ ...editRule, events: toggleList(editRule.events, e) })}
+                                            className={`px-2 py-1 text-xs border transition-colors ${on ? 'border-ark-primary bg-ark-primary/10 text-ark-primary' : 'border-ark-border text-ark-subtext hover:text-ark-text'}`}>
+                                            {types.includes(e) && !events.includes(e) ? t(`nc_type_group_${e}`, lang) : eventLabel(e)}
+                                        </button>
+                                    );
+                                })}
+                            </div>
+                        </Field>
+                        <Field label={t('nc_col_channels', lang)}>
+                            <div className="flex flex-wrap gap-2 pt-1">
+                                {channels.map(ch => {
+                                    const on = editRule.channels.split(',').includes(ch.id);
+                                    return (
+                                        <button key={ch.id} type="button" onClick={() => setEditRule({ ...editRule, channels: toggleList(editRule.channels, ch.id) })}
+                                            className={`px-2 py-1 text-xs border transition-colors ${on ? 'border-ark-primary bg-ark-primary/10 text-ark-primary' : 'border-ark-border text-ark-subtext hover:text-ark-text'}`}>
+                                            {ch.name}
+                                        </button>
+                                    );
+                                })}
+                            </div>
+                        </Field>
+                        <label className="flex items-center gap-2 text-xs text-ark-text">
+                            <input type="checkbox" checked={editRule.enabled} onChange={e => setEditRule({ ...editRule, enabled: e.target.checked })} />
+                            {t('nc_enabled', lang)}
+                        </label>
+                    </div>
+                )}
+            </ArkModal>
+        </div>
+    );
+};
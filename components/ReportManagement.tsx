@@ -7,6 +7,7 @@ import { FileText, Calendar, X, Inbox, ChevronDown, Plus, Download, Trash2, Eye,
 import { Report } from '../types';
 import { ArkDateRangePicker } from './ArkDateRangePicker';
 import { useNotification } from './NotificationSystem';
+import { formatDateTime } from '../time';
 
 export const ReportManagement: React.FC = () => {
     const { lang, authFetch } = useApp();
@@ -199,7 +200,7 @@ export const ReportManagement: React.FC = () => {
                                              </div>
                                          </td>
                                          <td className="p-4 text-ark-subtext">{report.creator}</td>
-                                         <td className="p-4 text-ark-subtext">{report.createTime}</td>
+                                         <td className="p-4 text-ark-subtext">{formatDateTime(report.createTime)}</td>
                                          <td className="p-4">
                                              <div className="flex items-center justify-center gap-2">
                                                  <button className="p-1.5 hover:bg-ark-active/20 text-ark-subtext hover:text-ark-primary transition-colors rounded-sm" title={t('rm_op_view', lang)}>
@@ -7,6 +7,7 @@ import { Lang } from '../types';
 import { Network, Database, Cloud, FileCode, Search, RefreshCw, X, Download, Trash2, Box, Eye, Calendar, Skull, Server, ChevronLeft, ChevronRight, FileText } from 'lucide-react';
 import { useNotification } from './NotificationSystem';
 import { ArkDateRangePicker } from './ArkDateRangePicker';
+import { formatDateTime, rangeParams } from '../time';
 
 // Reusing Filter Components for consistency
 const FilterInput: React.FC<{ label?: string, placeholder?: string, width?: string, children?: React.ReactNode }> = ({ label, placeholder, width = "w-full", children }) => (
@@ -90,7 +91,7 @@ export const SampleDetection: React.FC = () => {
     const { lang, modules, toggleModule, authFetch } = useApp();
     const { notify } = useNotification();
     const enabled = modules.payload;
-    const [dateRange, setDateRange] = useState({ start: '2025-11-07T00:00', end: '2025-12-06T23:59' });
+    const [dateRange, setDateRange] = useState({ start: '', end: '' });
     const [logs, setLogs] = useState<any[]>([]);
     const [loading, setLoading] = useState(true);
     const [pendingSamples, setPendingSamples] = useState<Set<string>>(new Set());
@@ -98,7 +99,7 @@ export const SampleDetection: React.FC = () => {
     const fetchLogs = async () => {
         setLoading(true);
         try {
-            const response = await authFetch('/api/v1/samples');
+            const response = await authFetch(`/api/v1/samples?${rangeParams(dateRange)}`);
             if (response.ok) {
                 const data = await response.json();
                 setLogs(data);
@@ -112,7 +113,7 @@ export const SampleDetection: React.FC = () => {
 
     useEffect(() => {
         fetchLogs();
-    }, [authFetch]);
+    }, [authFetch, dateRange]);
 
     const handleAnalysis = () => notify('info', t('op_success', lang), t('op_analysis_start', lang));
     const handleDownload = () => notify('success', t('op_success', lang), t('op_download_start', lang));
@@ -308,9 +309,9 @@ export const SampleDetection: React.FC = () => {
                                                     </div>
                                                 </td>
                                                 <td className="p-4 text-ark-text font-bold pl-8">{log.captureCount}</td>
-                                                <td className="p-4 text-ark-subtext">{log.lastTime}</td>
+                                                <td className="p-4 text-ark-subtext">{formatDateTime(log.lastTime)}</td>
                                                 <td className="p-4 text-ark-text hover:text-ark-primary cursor-pointer">{log.attackerIp}</td>
-                                                <td className="p-4 text-ark-subtext">{log.sourceNode}</td>
+                                                <td className="p-4 text-ark-subtext">{log.sourceNode || log.sensor}</td>
                                                 <td className="p-4">
                                                     <div className="flex items-center gap-2 group/hash">
                                                         <span className="text-ark-subtext max-w-[100px] truncate">{log.sha256}</span>
@@ -6,6 +6,7 @@ import { t } from '../i18n';
 import { RefreshCw, Settings, Activity, ChevronRight, Clock, MapPin, Network } from 'lucide-react';
 import { ArkDateRangePicker } from './ArkDateRangePicker';
 import { useNotification } from './NotificationSystem';
+import { formatDateTime, rangeParams } from '../time';
 
 const ScanningAnimation = () => {
     const { lang } = useApp();
@@ -63,7 +64,7 @@ export const ScanningPerception: React.FC = () => {
     const { lang, modules, toggleModule, authFetch } = useApp();
     const { notify } = useNotification();
     const enabled = modules.scanning;
-    const [dateRange, setDateRange] = useState({ start: '2023-10-24T00:00', end: '2023-10-24T23:59' });
+    const [dateRange, setDateRange] = useState({ start: '', end: '' });
     const [logs, setLogs] = useState<any[]>([]);
     const [loading, setLoading] = useState(true);
     const [isToggling, setIsToggling] = useState(false);
@@ -71,7 +72,7 @@ export const ScanningPerception: React.FC = () => {
     const fetchLogs = async () => {
         setLoading(true);
         try {
-            const response = await authFetch('/api/v1/scans');
+            const response = await authFetch(`/api/v1/scans?${rangeParams(dateRange)}`);
             if (response.ok) {
                 const data = await response.json();
                 setLogs(data);
@@ -85,7 +86,7 @@ export const ScanningPerception: React.FC = () => {
 
     useEffect(() => {
         fetchLogs();
-    }, [authFetch]);
+    }, [authFetch, dateRange]);
 
     const handleToggle = async () => {
         setIsToggling(true);
@@ -215,7 +216,7 @@ export const ScanningPerception: React.FC = () => {
                                      <div className="flex justify-between items-start mb-2">
                                          <div className="flex flex-col">
                                              <span className="font-bold text-ark-text text-sm">{log.ip}</span>
-                                             <span className="text-[10px] text-ark-subtext font-mono mt-0.5">{log.start}</span>
+                                             <span className="text-[10px] text-ark-subtext font-mono mt-0.5">{formatDateTime(log.start)}</span>
                                          </div>
                                          <span className={`px-2 py-0.5 border text-[10px] rounded-sm uppercase font-bold ${
                                              log.threat === 'Malicious' ? 'border-red-500/50 text-red-500 bg-red-500/10' :
@@ -234,7 +235,7 @@ export const ScanningPerception: React.FC = () => {
                                      <div className="grid grid-cols-2 gap-3 text-xs text-ark-subtext mb-3">
                                          <div className="flex items-center gap-1.5 overflow-hidden">
                                              <MapPin size={12} className="shrink-0" />
-                                             <span className="truncate">{log.node} ({log.location})</span>
+                                             <span className="truncate">{log.node || log.sensor} ({log.location})</span>
                                          </div>
                                          <div className="flex items-center gap-1.5 justify-end">
                                              <Clock size={12} className="shrink-0" />
@@ -295,7 +296,7 @@ export const ScanningPerception: React.FC = () => {
                                              </td>
                                              <td className="p-4">
                                                  <div className="flex flex-col">
-                                                     <span className="text-ark-text">{log.node}</span>
+                                                     <span className="text-ark-text">{log.node || log.sensor}</span>
                                                      <div className="flex items-center gap-1 text-[10px] text-ark-subtext">
                                                          <MapPin size={10} /> {log.location}
                                                      </div>
@@ -306,7 +307,7 @@ export const ScanningPerception: React.FC = () => {
                                              </td>
                                              <td className="p-4 text-right font-bold text-ark-text">{log.count}</td>
                                              <td className="p-4 text-ark-subtext truncate max-w-[200px]" title={log.ports}>{log.ports}</td>
-                                             <td className="p-4 text-ark-subtext">{log.start}</td>
+                                             <td className="p-4 text-ark-subtext">{formatDateTime(log.start)}</td>
                                              <td className="p-4 text-ark-text flex items-center gap-1">
                                                  <Clock size={12} className="text-ark-subtext" /> {log.duration}
                                              </td>
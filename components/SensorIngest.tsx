@@ -0,0 +1,718 @@
+import React, { useState, useEffect, useCallback } from 'react';
+import { ArkButton, ArkBadge, ArkInput, ArkModal, ArkLoading } from './ArknightsUI';
+import { useApp } from '../AppContext';
+import { t } from '../i18n';
+import { Antenna, Plus, RefreshCw, FileEdit, Trash2, Eye, Copy, RotateCcw, FileText, KeyRound, Inbox } from 'lucide-react';
+import { IngestAdapter, IngestTail, IngestPreview, IngestKey, IngestRejection } from '../types';
+import { useNotification } from './NotificationSystem';
+import { formatDateTime } from '../time';
+
+const emptySpec = {
+    name: '',
+    description: '',
+    time: '{timestamp}',
+    rules: [{ name: 'event', when: { eventid: '.' }, record: 'attack', fields: { sourceIp: '{src_ip}', payload: '{message}' } }],
+};
+
+const emptyTail = (adapter: string): IngestTail => ({
+    id: '', path: '', adapter, sensor: '', enabled: true, fromStart: false,
+});
+
+const emptyKey = (): KeyEdit => ({
+    id: '', name: '', keyId: '', sensor: '', enabled: true, requireSignature: false, rateLimit: 600, burst: 0, expires: '',
+});
+
+// KeyEdit holds the expiry as the datetime-local input has it
+type KeyEdit = IngestKey & { expires: string };
+
+// toLocalInput renders a time for a datetime-local input in the browser's zone
+const toLocalInput = (d: Date) => {
+    const pad = (n: number) => String(n).padStart(2, '0');
+    return `${d.getFullYear()}-${pad(d.getMonth() + 1)}-${pad(d.getDate())}T${pad(d.getHours())}:${pad(d.getMinutes())}`;
+};
+
+const REJECTION_PAGE = 50;
+
+interface AdapterEdit {
+    id?: string; // Set when a stored adapter is edited
+    spec: string;
+    sample: string;
+    path: string;
+}
+
+const Field: React.FC<{ label: string, hint?: string, children: React.ReactNode }> = ({ label, hint, children }) => (
+    <label className="block space-y-1">
+        <span className="text-xs font-mono text-ark-subtext">{label}</span>
+        {children}
+        {hint && <span className="block text-[10px] text-ark-subtext/70">{hint}</span>}
+    </label>
+);
+
+const selectClass = "w-full bg-ark-bg border-b-2 border-ark-border px-3 py-2 text-sm text-ark-text focus:outline-none focus:border-ark-primary font-mono";
+const textareaClass = "w-full bg-ark-bg border border-ark-border px-3 py-2 text-xs text-ark-text focus:outline-none focus:border-ark-primary font-mono custom-scrollbar";
+
+export const SensorIngest: React.FC = () => {
+    const { lang, authFetch } = useApp();
+    const { notify } = useNotification();
+    const [adapters, setAdapters] = useState<IngestAdapter[]>([]);
+    const [tails, setTails] = useState<IngestTail[]>([]);
+    const [loading, setLoading] = useState(true);
+    const [saving, setSaving] = useState(false);
+    const [editAdapter, setEditAdapter] = useState<AdapterEdit | null>(null);
+    const [preview, setPreview] = useState<IngestPreview[] | null>(null);
+    const [previewing, setPreviewing] = useState(false);
+    const [editTail, setEditTail] = useState<IngestTail | null>(null);
+    const [keys, setKeys] = useState<IngestKey[]>([]);
+    const [editKey, setEditKey] = useState<KeyEdit | null>(null);
+    const [issued, setIssued] = useState<IngestKey | null>(null);
+    const [rejections, setRejections] = useState<IngestRejection[]>([]);
+    const [rejectionTotal, setRejectionTotal] = useState(0);
+    const [rejectionKey, setRejectionKey] = useState('');
+    const [openRejection, setOpenRejection] = useState<string | null>(null);
+
+    const load = useCallback(async () => {
+        const params = new URLSearchParams({ limit: String(REJECTION_PAGE) });
+        if (rejectionKey) params.set('keyId', rejectionKey);
+        const [adapterRes, tailRes, keyRes, rejectionRes] = await Promise.all([
+            authFetch('/api/v1/ingest/adapters'),
+            authFetch('/api/v1/ingest/tails'),
+            authFetch('/api/v1/ingest/keys'),
+            authFetch(`/api/v1/ingest/rejections?${params}`),
+        ]);
+        if (adapterRes.ok) setAdapters(await adapterRes.json() || []);
+        if (tailRes.ok) setTails(await tailRes.json() || []);
+        if (keyRes.ok) setKeys(await keyRes.json());
+        if (rejectionRes.ok) {
+            setRejections(await rejectionRes.json());
+            setRejectionTotal(parseInt(rejectionRes.headers.get('X-Total-Count') || '0'));
+        }
+    }, [authFetch, rejectionKey]);
+
+    const fetchAll = useCallback(async () => {
+        setLoading(true);
+        try {
+            await load();
+        } catch (e) {
+            console.error("Failed to fetch ingest adapters", e);
+        } finally {
+            setLoading(false);
+        }
+    }, [load]);
+
+    useEffect(() => { fetchAll(); }, [fetchAll]);
+
+    // Tails move their offsets and counters on their own, keep them fresh while the page is open
+    useEffect(() => {
+        const timer = setInterval(() => { load().catch(() => { /* the next tick tries again */ }); }, 10000);
+        return () => clearInterval(timer);
+    }, [load]);
+
+    const request = async (url: string, method: string, body?: unknown) => {
+        try {
+            const res = await authFetch(url, { method, body: body === undefined ? undefined : JSON.stringify(body) });
+            const data = await res.json().catch(() => ({}));
+            if (!res.ok) {
+                notify('error', t('op_failed', lang), data.error || res.statusText);
+                return null;
+            }
+            return data;
+        } catch (e) {
+            notify('error', t('op_failed', lang), t('err_network', lang));
+            return null;
+        }
+    };
+
+    const parseSpec = (text: string) => {
+        try {
+            return JSON.parse(text);
+        } catch (e) {
+            notify('error', t('op_failed', lang), t('ingest_spec_invalid', lang, { error: (e as Error).message }));
+            return null;
+        }
+    };
+
+    const openAdapter = (a: IngestAdapter | null) => {
+        setPreview(null);
+        setEditAdapter({
+            // A built-in that is not overridden yet is saved as a new adapter under its name
+            id: a?.id,
+            spec: JSON.stringify(a ? a.spec : emptySpec, null, 2),
+            sample: '',
+            path: '',
+        });
+    };
+
+    const saveAdapter = async () => {
+        if (!editAdapter) return;
+        const spec = parseSpec(editAdapter.spec);
+        if (!spec) return;
+        setSaving(true);
+        const url = editAdapter.id ? `/api/v1/ingest/adapters/${editAdapter.id}` : '/api/v1/ingest/adapters';
+        const data = await request(url, 'POST', spec);
+        setSaving(false);
+        if (data) {
+            notify('success', t('op_success', lang), t('ingest_adapter_saved', lang));
+            setEditAdapter(null);
+            fetchAll();
+        }
+    };
+
+    const removeAdapter = async (a: IngestAdapter) => {
+        const key = a.overrides ? 'ingest_adapter_confirm_restore' : 'ingest_adapter_confirm_delete';
+        if (!window.confirm(t(key, lang, { name: a.name }))) return;
+        if (await request(`/api/v1/ingest/adapters/${a.id}`, 'DELETE')) fetchAll();
+    };
+
+    const runPreview = async () => {
+        if (!editAdapter) return;
+        const spec = parseSpec(editAdapter.spec);
+        if (!spec) return;
+        setPreviewing(true);
+        const data: IngestPreview[] | null = await request('/api/v1/ingest/adapters/preview', 'POST', {
+            spec, sample: editAdapter.sample, path: editAdapter.path,
+        });
+        setPreviewing(false);
+        if (data) setPreview(data);
+    };
+
+    const saveTail = async () => {
+        if (!editTail) return;
+        setSaving(true);
+        const url = editTail.id ? `/api/v1/ingest/tails/${editTail.id}` : '/api/v1/ingest/tails';
+        const data = await request(url, 'POST', editTail);
+        setSaving(false);
+        if (data) {
+            notify('success', t('op_success', lang), t('ingest_tail_saved', lang));
+            setEditTail(null);
+            fetchAll();
+        }
+    };
+
+    const removeTail = async (tail: IngestTail) => {
+        if (!window.confirm(t('ingest_tail_confirm_delete', lang, { path: tail.path }))) return;
+        if (await request(`/api/v1/ingest/tails/${tail.id}`, 'DELETE')) fetchAll();
+    };
+
+    const saveKey = async () => {
+        if (!editKey) return;
+        setSaving(true);
+        const { expires, ...key } = editKey;
+        const body = { ...key, expiresAt: expires ? new Date(expires).toISOString() : null };
+        const url = editKey.id ? `/api/v1/ingest/keys/${editKey.id}` : '/api/v1/ingest/keys';
+        const data: IngestKey | null = await request(url, 'POST', body);
+        setSaving(false);
+        if (data) {
+            notify('success', t('op_success', lang), t('ingest_key_saved', lang));
+            setEditKey(null);
+            if (data.key) setIssued(data);
+            fetchAll();
+        }
+    };
+
+    const rotateKey = async (k: IngestKey) => {
+        if (!window.confirm(t('ingest_key_confirm_rotate', lang, { name: k.name }))) return;
+        const data: IngestKey | null = await request(`/api/v1/ingest/keys/${k.id}/rotate`, 'POST');
+        if (data) {
+            setIssued(data);
+            fetchAll();
+        }
+    };
+
+    const removeKey = async (k: IngestKey) => {
+        if (!window.confirm(t('ingest_key_confirm_delete', lang, { name: k.name }))) return;
+        if (await request(`/api/v1/ingest/keys/${k.id}`, 'DELETE')) fetchAll();
+    };
+
+    const clearRejections = async () => {
+        if (!window.confirm(t('ingest_rejections_confirm_clear', lang))) return;
+        const query = rejectionKey ? `?keyId=${encodeURIComponent(rejectionKey)}` : '';
+        if (await request(`/api/v1/ingest/rejections${query}`, 'DELETE')) fetchAll();
+    };
+
+    const copyKey = async (key: string) => {
+        try {
+            await navigator.clipboard.writeText(key);
+            notify('success', t('op_success', lang), t('ingest_key_copied', lang));
+        } catch (e) {
+            notify('error', t('op_failed', lang), t('ingest_key_copy_failed', lang));
+        }
+    };
+
+    const keyBadge = (k: IngestKey) => {
+        if (!k.enabled) return <ArkBadge type="neutral">{t('nc_disabled', lang)}</ArkBadge>;
+        if (k.expiresAt && new Date(k.expiresAt) < new Date()) return <ArkBadge type="error">{t('ingest_key_expired', lang)}</ArkBadge>;
+        return <ArkBadge type="success">{t('nc_enabled', lang)}</ArkBadge>;
+    };
+
+    const adapterBadge = (a: IngestAdapter) => {
+        if (a.overrides) return <ArkBadge type="warn">{t('ingest_overrides', lang)}</ArkBadge>;
+        if (a.builtin) return <ArkBadge type="neutral">{t('ingest_builtin', lang)}</ArkBadge>;
+        return <ArkBadge type="success">{t('ingest_custom', lang)}</ArkBadge>;
+    };
+
+    const tailBadge = (tail: IngestTail) => {
+        if (!tail.enabled) return <ArkBadge type="neutral">{t('nc_disabled', lang)}</ArkBadge>;
+        if (tail.running && !tail.lastError) return <ArkBadge type="success">{t('ingest_following', lang)}</ArkBadge>;
+        return <ArkBadge type={tail.lastError ? 'error' : 'warn'}>{t('ingest_stalled', lang)}</ArkBadge>;
+    };
+
+    return (
+        <div className="flex flex-col gap-4 pb-6 min-h-full">
+            {/* Description Block */}
+            <div className="bg-ark-panel border border-ark-border p-6 shadow-sm">
+                <div className="flex items-center gap-2 mb-3 font-bold text-ark-text">
+                    <Antenna className="text-ark-primary" size={20} />
+                    {t('ingest_title', lang)}
+                </div>
+                <div className="text-xs text-ark-subtext font-mono space-y-1.5 leading-relaxed pl-7">
+                    <p>{t('ingest_desc', lang)}</p>
+                    <p>• {t('ingest_desc_http', lang)}</p>
+                    <p>• {t('ingest_desc_keys', lang)}</p>
+                    <p>• {t('ingest_desc_tail', lang)}</p>
+                    <p>• {t('ingest_desc_custom', lang)}</p>
+                </div>
+            </div>
+
+            {/* Adapters */}
+            <div className="bg-ark-panel border border-ark-border shadow-sm relative">
+                {loading && <ArkLoading label="FETCHING_ADAPTERS" />}
+                <div className="flex items-center justify-between p-4 border-b border-ark-border">
+                    <div className="flex items-center gap-2 text-sm font-bold text-ark-text">
+                        <Antenna size={16} className="text-ark-primary" /> {t('ingest_adapters', lang)}
+                    </div>
+                    <div className="flex gap-2">
+                        <ArkButton variant="ghost" className="h-[32px] px-4" onClick={fetchAll} disabled={loading}>
+                            <RefreshCw size={14} className={`mr-1 ${loading ? 'animate-spin' : ''}`} /> {t('refresh', lang)}
+                        </ArkButton>
+                        <ArkButton variant="primary" size="sm" onClick={() => openAdapter(null)}>
+                            <Plus size={14} className="mr-1" /> {t('ingest_adapter_add', lang)}
+                        </ArkButton>
+                    </div>
+                </div>
+                <div className="overflow-x-auto custom-scrollbar">
+                    <table className="w-full text-left text-sm min-w-[900px]">
+                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                            <tr>
+                                <th className="p-4">{t('nc_col_name', lang)}</th>
+                                <th className="p-4">{t('ingest_col_description', lang)}</th>
+                                <th className="p-4">{t('ingest_col_rules', lang)}</th>
+                                <th className="p-4">{t('ingest_col_endpoint', lang)}</th>
+                                <th className="p-4">{t('nc_col_status', lang)}</th>
+                                <th className="p-4 text-center">{t('nc_col_op', lang)}</th>
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                            {adapters.map(a => (
+                                <tr key={a.name} className="hover:bg-ark-active/5 transition-colors">
+                                    <td className="p-4 text-ark-text font-bold">{a.name}</td>
+                                    <td className="p-4 text-ark-subtext max-w-[320px]">{a.description || '-'}</td>
+                                    <td className="p-4 text-ark-text">{a.rules}</td>
+                                    <td className="p-4 text-ark-subtext break-all">POST /api/v1/ingest/logs/{a.name}</td>
+                                    <td className="p-4">
+                                        {adapterBadge(a)}
+                                        {a.updatedAt && <span className="block text-ark-subtext mt-1">{formatDateTime(a.updatedAt)}</span>}
+                                    </td>
+                                    <td className="p-4">
+                                        <div className="flex items-center justify-center gap-3">
+                                            {a.id ? (
+                                                <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_edit', lang)} onClick={() => openAdapter(a)}>
+                                                    <FileEdit size={14} />
+                                                </button>
+                                            ) : (
+                                                <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('ingest_customize', lang)} onClick={() => openAdapter(a)}>
+                                                    <Copy size={14} />
+                                                </button>
+                                            )}
+                                            {a.id && (
+                                                <button className="text-ark-subtext hover:text-red-500 transition-colors" title={t(a.overrides ? 'ingest_restore' : 'nc_delete', lang)} onClick={() => removeAdapter(a)}>
+                                                    {a.overrides ? <RotateCcw size={14} /> : <Trash2 size={14} />}
+                                                </button>
+                                            )}
+                                        </div>
+                                    </td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+
+            {/* Ingest Keys */}
+            <div className="bg-ark-panel border border-ark-border shadow-sm">
+                <div className="flex items-center justify-between p-4 border-b border-ark-border">
+                    <div className="flex items-center gap-2 text-sm font-bold text-ark-text">
+                        <KeyRound size={16} className="text-ark-primary" /> {t('ingest_keys', lang)}
+                    </div>
+                    <ArkButton variant="primary" size="sm" onClick={() => setEditKey(emptyKey())}>
+                        <Plus size={14} className="mr-1" /> {t('ingest_key_add', lang)}
+                    </ArkButton>
+                </div>
+                <div className="overflow-x-auto custom-scrollbar">
+                    <table className="w-full text-left text-sm min-w-[1000px]">
+                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                            <tr>
+                                <th className="p-4">{t('nc_col_name', lang)}</th>
+                                <th className="p-4">{t('ingest_sensor', lang)}</th>
+                                <th className="p-4">{t('ingest_col_rate', lang)}</th>
+                                <th className="p-4">{t('ingest_col_usage', lang)}</th>
+                                <th className="p-4">{t('ingest_col_last_used', lang)}</th>
+                                <th className="p-4">{t('nc_col_status', lang)}</th>
+                                <th className="p-4 text-center">{t('nc_col_op', lang)}</th>
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                            {keys.length === 0 && (
+                                <tr><td colSpan={7} className="p-6 text-center text-ark-subtext">{t('ingest_keys_none', lang)}</td></tr>
+                            )}
+                            {keys.map(k => (
+                                <tr key={k.id} className="hover:bg-ark-active/5 transition-colors">
+                                    <td className="p-4 text-ark-text font-bold">
+                                        {k.name}
+                                        <span className="block text-ark-subtext font-normal">prts_{k.keyId}_…</span>
+                                    </td>
+                                    <td className="p-4 text-ark-text">{k.sensor}</td>
+                                    <td className="p-4 text-ark-subtext whitespace-nowrap">
+                                        {t('ingest_rate', lang, { rate: k.rateLimit, burst: k.burst || k.rateLimit })}
+                                        {k.requireSignature && <span className="block text-ark-text">{t('ingest_signed_only', lang)}</span>}
+                                    </td>
+                                    <td className="p-4 text-ark-subtext whitespace-nowrap">
+                                        {t('ingest_usage', lang, { accepted: k.accepted ?? 0, rejected: k.rejected ?? 0 })}
+                                        {!!k.throttled && <span className="block text-red-500">{t('ingest_throttled', lang, { n: k.throttled })}</span>}
+                                    </td>
+                                    <td className="p-4 text-ark-subtext whitespace-nowrap">
+                                        {k.lastUsedAt ? formatDateTime(k.lastUsedAt) : '-'}
+                                        {k.lastUsedIp && <span className="block">{k.lastUsedIp}</span>}
+                                    </td>
+                                    <td className="p-4">
+                                        {keyBadge(k)}
+                                        {k.expiresAt && <span className="block text-ark-subtext mt-1">{t('ingest_key_expires', lang, { time: formatDateTime(k.expiresAt) })}</span>}
+                                    </td>
+                                    <td className="p-4">
+                                        <div className="flex items-center justify-center gap-3">
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_edit', lang)}
+                                                onClick={() => setEditKey({ ...k, expires: k.expiresAt ? toLocalInput(new Date(k.expiresAt)) : '' })}>
+                                                <FileEdit size={14} />
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('ingest_key_rotate', lang)} onClick={() => rotateKey(k)}>
+                                                <RotateCcw size={14} />
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-red-500 transition-colors" title={t('nc_delete', lang)} onClick={() => removeKey(k)}>
+                                                <Trash2 size={14} />
+                                            </button>
+                                        </div>
+                                    </td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+
+            {/* Tails */}
+            <div className="flex-1 bg-ark-panel border border-ark-border shadow-sm">
+                <div className="flex items-center justify-between p-4 border-b border-ark-border">
+                    <div className="flex items-center gap-2 text-sm font-bold text-ark-text">
+                        <FileText size={16} className="text-ark-primary" /> {t('ingest_tails', lang)}
+                    </div>
+                    <ArkButton variant="primary" size="sm" onClick={() => setEditTail(emptyTail(adapters[0]?.name || ''))}>
+                        <Plus size={14} className="mr-1" /> {t('ingest_tail_add', lang)}
+                    </ArkButton>
+                </div>
+                <div className="overflow-x-auto custom-scrollbar">
+                    <table className="w-full text-left text-sm min-w-[1000px]">
+                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                            <tr>
+                                <th className="p-4">{t('ingest_col_path', lang)}</th>
+                                <th className="p-4">{t('ingest_col_adapter', lang)}</th>
+                                <th className="p-4">{t('ingest_col_progress', lang)}</th>
+                                <th className="p-4">{t('ingest_col_last_read', lang)}</th>
+                                <th className="p-4">{t('nc_col_status', lang)}</th>
+                                <th className="p-4 text-center">{t('nc_col_op', lang)}</th>
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                            {tails.length === 0 && (
+                                <tr><td colSpan={6} className="p-6 text-center text-ark-subtext">{t('ingest_tails_none', lang)}</td></tr>
+                            )}
+                            {tails.map(tail => (
+                                <tr key={tail.id} className="hover:bg-ark-active/5 transition-colors">
+                                    <td className="p-4 text-ark-text font-bold break-all">{tail.path}</td>
+                                    <td className="p-4 text-ark-text">
+                                        {tail.adapter}
+                                        {tail.sensor && <span className="block text-ark-subtext">{t('ingest_sensor', lang)}: {tail.sensor}</span>}
+                                    </td>
+                                    <td className="p-4 text-ark-subtext whitespace-nowrap">
+                                        {t('ingest_progress', lang, { events: tail.events ?? 0, records: tail.records ?? 0, failed: tail.failed ?? 0 })}
+                                        <span className="block">{t('ingest_offset', lang, { n: tail.offset ?? 0 })}</span>
+                                    </td>
+                                    <td className="p-4 text-ark-subtext whitespace-nowrap">{tail.lastReadAt ? formatDateTime(tail.lastReadAt) : '-'}</td>
+                                    <td className="p-4 max-w-[260px]">
+                                        {tailBadge(tail)}
+                                        {tail.enabled && tail.lastError && (
+                                            <div className="text-red-500 mt-1 break-all" title={tail.lastErrorAt ? formatDateTime(tail.lastErrorAt) : undefined}>{tail.lastError}</div>
+                                        )}
+                                    </td>
+                                    <td className="p-4">
+                                        <div className="flex items-center justify-center gap-3">
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_edit', lang)} onClick={() => setEditTail({ ...tail })}>
+                                                <FileEdit size={14} />
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-red-500 transition-colors" title={t('nc_delete', lang)} onClick={() => removeTail(tail)}>
+                                                <Trash2 size={14} />
+                                            </button>
+                                        </div>
+                                    </td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+
+            {/* Rejected Events */}
+            <div className="bg-ark-panel border border-ark-border shadow-sm">
+                <div className="flex items-center justify-between p-4 border-b border-ark-border">
+                    <div className="flex items-center gap-2 text-sm font-bold text-ark-text">
+                        <Inbox size={16} className="text-ark-primary" /> {t('ingest_rejections', lang)}
+                        <span className="text-xs font-mono text-ark-subtext font-normal">({rejectionTotal})</span>
+                    </div>
+                    <div className="flex gap-2">
+                        <select className={`${selectClass} w-48`} value={rejectionKey} onChange={e => setRejectionKey(e.target.value)}>
+                            <option value="">{t('ingest_all_keys', lang)}</option>
+                            {keys.map(k => <option key={k.id} value={k.keyId}>{k.name}</option>)}
+                        </select>
+                        <ArkButton variant="ghost" size="sm" onClick={clearRejections} disabled={rejectionTotal === 0}>
+                            <Trash2 size={14} className="mr-1" /> {t('ingest_rejections_clear', lang)}
+                        </ArkButton>
+                    </div>
+                </div>
+                <div className="overflow-x-auto custom-scrollbar">
+                    <table className="w-full text-left text-sm min-w-[1000px]">
+                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                            <tr>
+                                <th className="p-4">{t('ingest_col_time', lang)}</th>
+                                <th className="p-4">{t('ingest_col_endpoint', lang)}</th>
+                                <th className="p-4">{t('ingest_sensor', lang)}</th>
+                                <th className="p-4">{t('ingest_col_error', lang)}</th>
+                                <th className="p-4 text-center">{t('ingest_col_payload', lang)}</th>
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                            {rejections.length === 0 && (
+                                <tr><td colSpan={5} className="p-6 text-center text-ark-subtext">{t('ingest_rejections_none', lang)}</td></tr>
+                            )}
+                            {rejections.map(r => (
+                                <React.Fragment key={r.id}>
+                                    <tr className="hover:bg-ark-active/5 transition-colors">
+                                        <td className="p-4 text-ark-subtext whitespace-nowrap">{formatDateTime(r.time)}</td>
+                                        <td className="p-4 text-ark-text">
+                                            {r.endpoint}
+                                            {r.index > 0 && <span className="block text-ark-subtext">{t('ingest_event_index', lang, { n: r.index })}</span>}
+                                        </td>
+                                        <td className="p-4 text-ark-text">
+                                            {r.sensor}
+                                            <span className="block text-ark-subtext">{r.remoteIp}</span>
+                                        </td>
+                                        <td className="p-4 text-red-500 break-all max-w-[420px]">{r.error}</td>
+                                        <td className="p-4 text-center">
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors disabled:opacity-30" title={t('ingest_col_payload', lang)}
+                                                disabled={!r.payload} onClick={() => setOpenRejection(openRejection === r.id ? null : r.id)}>
+                                                <Eye size={14} />
+                                            </button>
+                                        </td>
+                                    </tr>
+                                    {openRejection === r.id && (
+                                        <tr>
+                                            <td colSpan={5} className="px-4 pb-4">
+                                                <pre className="bg-ark-bg border border-ark-border p-3 text-[11px] text-ark-text whitespace-pre-wrap break-all max-h-64 overflow-y-auto custom-scrollbar">{r.payload}</pre>
+                                            </td>
+                                        </tr>
+                                    )}
+                                </React.Fragment>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+                {rejectionTotal > rejections.length && (
+                    <div className="p-3 border-t border-ark-border bg-ark-bg text-[10px] font-mono text-ark-subtext">
+                        {t('ingest_rejections_more', lang, { shown: rejections.length, total: rejectionTotal })}
+                    </div>
+                )}
+            </div>
+
+            {/* Adapter Editor */}
+            <ArkModal
+                isOpen={!!editAdapter}
+                onClose={() => setEditAdapter(null)}
+                title={t(editAdapter?.id ? 'ingest_adapter_edit' : 'ingest_adapter_add', lang)}
+                icon={<Antenna size={18} />}
+                maxWidth="max-w-5xl"
+                footer={<>
+                    <ArkButton variant="ghost" onClick={() => setEditAdapter(null)}>{t('btn_cancel', lang)}</ArkButton>
+                    <ArkButton variant="primary" onClick={saveAdapter} disabled={saving}>{t('btn_save', lang)}</ArkButton>
+                </>}
+            >
+                {editAdapter && (
+                    <div className="grid grid-cols-1 lg:grid-cols-2 gap-4 max-h-[70vh] overflow-y-auto custom-scrollbar pr-1">
+                        <Field label={t('ingest_spec', lang)} hint={t('ingest_spec_hint', lang)}>
+                            <textarea className={textareaClass} rows={26} spellCheck={false} value={editAdapter.spec}
+                                onChange={e => setEditAdapter({ ...editAdapter, spec: e.target.value })} />
+                        </Field>
+                        <div className="space-y-4">
+                            <Field label={t('ingest_sample', lang)} hint={t('ingest_sample_hint', lang)}>
+                                <textarea className={textareaClass} rows={8} spellCheck={false} value={editAdapter.sample}
+                                    onChange={e => setEditAdapter({ ...editAdapter, sample: e.target.value })} />
+                            </Field>
+                            <div className="flex items-end gap-2">
+                                <div className="flex-1">
+                                    <Field label={t('ingest_log_path', lang)}>
+                                        <ArkInput value={editAdapter.path} placeholder="conn" onChange={e => setEditAdapter({ ...editAdapter, path: e.target.value })} />
+                                    </Field>
+                                </div>
+                                <ArkButton variant="ghost" onClick={runPreview} disabled={previewing || !editAdapter.sample.trim()}>
+                                    {previewing ? <RefreshCw size={14} className="mr-1 animate-spin" /> : <Eye size={14} className="mr-1" />} {t('ingest_preview', lang)}
+                                </ArkButton>
+                            </div>
+                            {preview && (
+                                <div className="space-y-2 font-mono text-[11px]">
+                                    {preview.length === 0 && <div className="text-ark-subtext">{t('ingest_preview_empty', lang)}</div>}
+                                    {preview.map(p => (
+                                        <div key={p.line} className="border border-ark-border p-2 bg-ark-bg">
+                                            <div className="text-ark-subtext mb-1">{t('ingest_line', lang, { n: p.line })}</div>
+                                            {p.error && <div className="text-red-500 break-all">{p.error}</div>}
+                                            {!p.error && p.records.length === 0 && <div className="text-ark-subtext">{t('ingest_no_rule', lang)}</div>}
+                                            {p.records.map((r, i) => (
+                                                <div key={i} className="mt-1">
+                                                    <ArkBadge type="warn">{t(`siem_kind_${r.kind}`, lang)}</ArkBadge>
+                                                    <span className="ml-2 text-ark-subtext">{r.rule}</span>
+                                                    {r.time && <span className="ml-2 text-ark-subtext">{formatDateTime(r.time)}</span>}
+                                                    {r.sensor && <span className="ml-2 text-ark-subtext">@{r.sensor}</span>}
+                                                    <div className="pl-2 mt-1 text-ark-text break-all">
+                                                        {Object.entries(r.fields).map(([k, v]) => (
+                                                            <div key={k}><span className="text-ark-subtext">{k}:</span> {v}</div>
+                                                        ))}
+                                                    </div>
+                                                </div>
+                                            ))}
+                                        </div>
+                                    ))}
+                                </div>
+                            )}
+                        </div>
+                    </div>
+                )}
+            </ArkModal>
+
+            {/* Tail Editor */}
+            <ArkModal
+                isOpen={!!editTail}
+                onClose={() => setEditTail(null)}
+                title={t(editTail?.id ? 'ingest_tail_edit' : 'ingest_tail_add', lang)}
+                icon={<FileText size={18} />}
+                maxWidth="max-w-xl"
+                footer={<>
+                    <ArkButton variant="ghost" onClick={() => setEditTail(null)}>{t('btn_cancel', lang)}</ArkButton>
+                    <ArkButton variant="primary" onClick={saveTail} disabled={saving}>{t('btn_save', lang)}</ArkButton>
+                </>}
+            >
+                {editTail && (
+                    <div className="space-y-4">
+                        <Field label={t('ingest_col_path', lang)} hint={t('ingest_path_hint', lang)}>
+                            <ArkInput value={editTail.path} placeholder="/var/log/suricata/eve.json" onChange={e => setEditTail({ ...editTail, path: e.target.value })} />
+                        </Field>
+                        <Field label={t('ingest_col_adapter', lang)}>
+                            <select className={selectClass} value={editTail.adapter} onChange={e => setEditTail({ ...editTail, adapter: e.target.value })}>
+                                {adapters.map(a => <option key={a.name} value={a.name}>{a.name}</option>)}
+                            </select>
+                        </Field>
+                        <Field label={t('ingest_sensor', lang)} hint={t('ingest_sensor_hint', lang)}>
+                            <ArkInput value={editTail.sensor} onChange={e => setEditTail({ ...editTail, sensor: e.target.value })} />
+                        </Field>
+                        {!editTail.id && (
+                            <label className="flex items-center gap-2 text-xs font-mono text-ark-text">
+                                <input type="checkbox" checked={editTail.fromStart} onChange={e => setEditTail({ ...editTail, fromStart: e.target.checked })} />
+                                {t('ingest_from_start', lang)}
+                            </label>
+                        )}
+                        <label className="flex items-center gap-2 text-xs font-mono text-ark-text">
+                            <input type="checkbox" checked={editTail.enabled} onChange={e => setEditTail({ ...editTail, enabled: e.target.checked })} />
+                            {t('nc_enabled', lang)}
+                        </label>
+                    </div>
+                )}
+            </ArkModal>
+            {/* Key Editor */}
+            <ArkModal
+                isOpen={!!editKey}
+                onClose={() => setEditKey(null)}
+                title={t(editKey?.id ? 'ingest_key_edit' : 'ingest_key_add', lang)}
+                icon={<KeyRound size={18} />}
+                maxWidth="max-w-xl"
+                footer={<>
+                    <ArkButton variant="ghost" onClick={() => setEditKey(null)}>{t('btn_cancel', lang)}</ArkButton>
+                    <ArkButton variant="primary" onClick={saveKey} disabled={saving}>{t('btn_save', lang)}</ArkButton>
+                </>}
+            >
+                {editKey && (
+                    <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+                        <Field label={t('nc_col_name', lang)}>
+                            <ArkInput value={editKey.name} onChange={e => setEditKey({ ...editKey, name: e.target.value })} />
+                        </Field>
+                        <Field label={t('ingest_sensor', lang)} hint={t('ingest_key_sensor_hint', lang)}>
+                            <ArkInput value={editKey.sensor} placeholder="dmz-suricata" onChange={e => setEditKey({ ...editKey, sensor: e.target.value })} />
+                        </Field>
+                        <Field label={t('ingest_rate_limit', lang)} hint={t('ingest_rate_limit_hint', lang)}>
+                            <ArkInput type="number" min={1} value={editKey.rateLimit} onChange={e => setEditKey({ ...editKey, rateLimit: parseInt(e.target.value) || 0 })} />
+                        </Field>
+                        <Field label={t('ingest_burst', lang)} hint={t('ingest_burst_hint', lang)}>
+                            <ArkInput type="number" min={0} value={editKey.burst} onChange={e => setEditKey({ ...editKey, burst: parseInt(e.target.value) || 0 })} />
+                        </Field>
+                        <Field label={t('ingest_key_expiry', lang)} hint={t('ingest_key_expiry_hint', lang)}>
+                            <ArkInput type="datetime-local" value={editKey.expires} onChange={e => setEditKey({ ...editKey, expires: e.target.value })} />
+                        </Field>
+                        <div className="space-y-3 pt-5">
+                            <label className="flex items-center gap-2 text-xs font-mono text-ark-text">
+                                <input type="checkbox" checked={editKey.requireSignature} onChange={e => setEditKey({ ...editKey, requireSignature: e.target.checked })} />
+                                {t('ingest_require_signature', lang)}
+                            </label>
+                            <label className="flex items-center gap-2 text-xs font-mono text-ark-text">
+                                <input type="checkbox" checked={editKey.enabled} onChange={e => setEditKey({ ...editKey, enabled: e.target.checked })} />
+                                {t('nc_enabled', lang)}
+                            </label>
+                        </div>
+                    </div>
+                )}
+            </ArkModal>
+
+            {/* Issued Key */}
+            <ArkModal
+                isOpen={!!issued}
+                onClose={() => setIssued(null)}
+                title={t('ingest_key_issued', lang)}
+                icon={<KeyRound size={18} />}
+                maxWidth="max-w-2xl"
+                footer={<ArkButton variant="primary" onClick={() => setIssued(null)}>{t('ingest_key_done', lang)}</ArkButton>}
+            >
+                {issued?.key && (
+                    <div className="space-y-4 font-mono text-xs">
+                        <p className="text-ark-subtext">{t('ingest_key_issued_hint', lang, { name: issued.name })}</p>
+                        <div className="flex items-center gap-2">
+                            <code className="flex-1 bg-ark-bg border border-ark-border p-2 text-ark-text break-all">{issued.key}</code>
+                            <ArkButton variant="ghost" size="sm" onClick={() => copyKey(issued.key!)}>
+                                <Copy size={14} />
+                            </ArkButton>
+                        </div>
+                        <div className="space-y-1 text-ark-subtext">
+                            <p>{t('ingest_key_bearer_hint', lang)}</p>
+                            <code className="block bg-ark-bg border border-ark-border p-2 text-ark-text break-all">Authorization: Bearer {issued.key}</code>
+                            <p className="pt-2">{t('ingest_key_sign_hint', lang)}</p>
+                            <code className="block bg-ark-bg border border-ark-border p-2 text-ark-text whitespace-pre-wrap break-all">{`X-Prts-Key-Id: ${issued.keyId}
+X-Prts-Timestamp: <unix seconds>
+X-Prts-Signature: hex(HMAC-SHA256(key, timestamp + "\\n" + method + "\\n" + path?query + "\\n" + hex(SHA256(body))))`}</code>
+                        </div>
+                    </div>
+                )}
+            </ArkModal>
+        </div>
+    );
+};
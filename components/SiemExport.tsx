@@ -0,0 +1,753 @@
+import React, { useState, useEffect, useCallback } from 'react';
+import { ArkButton, ArkBadge, ArkInput, ArkModal, ArkLoading } from './ArknightsUI';
+import { useApp } from '../AppContext';
+import { t } from '../i18n';
+import { Radio, Plus, RefreshCw, FileEdit, Trash2, Send, Database, Share2 } from 'lucide-react';
+import { SiemKind, SyslogDestination, OpenSearchOutput, EventBusKind, EventBusOutput } from '../types';
+import { useNotification } from './NotificationSystem';
+import { formatDateTime } from '../time';
+
+const KINDS: SiemKind[] = ['attack', 'scan', 'credential', 'decoy', 'sample', 'login'];
+const BUS_KINDS: EventBusKind[] = [...KINDS, 'node'];
+const SEVERITIES = ['info', 'low', 'medium', 'high', 'critical'];
+const DEFAULT_PORTS = { udp: 514, tcp: 514, tls: 6514 };
+const DEFAULT_TOPICS = { kafka: 'prts-events', nats: 'prts.events.{type}' };
+const DEFAULT_SERVERS = { kafka: 'localhost:9092', nats: 'nats://localhost:4222' };
+
+const emptyDestination = (): SyslogDestination => ({
+    id: '', name: '', enabled: true, host: '', port: 514, protocol: 'udp', format: 'cef', framing: '',
+    facility: 16, kinds: '', minSeverity: 'info', caCert: '', insecureSkipVerify: false, maxQueueMb: 64,
+});
+
+const emptyOutput = (): OpenSearchOutput => ({
+    id: '', name: '', enabled: true, url: 'https://localhost:9200', username: '', password: '', apiKey: '',
+    indexPrefix: 'prts', kinds: '', minSeverity: 'info', batchSize: 500, flushSeconds: 5,
+    skipTemplate: false, caCert: '', insecureSkipVerify: false, maxQueueMb: 256,
+});
+
+const emptyBusOutput = (): EventBusOutput => ({
+    id: '', name: '', enabled: true, type: 'kafka', servers: DEFAULT_SERVERS.kafka, topic: DEFAULT_TOPICS.kafka,
+    encoding: 'json', kinds: '', minSeverity: 'info', jetStream: false, username: '', password: '', token: '',
+    tls: false, caCert: '', insecureSkipVerify: false, maxQueueMb: 256,
+});
+
+const Field: React.FC<{ label: string, hint?: string, children: React.ReactNode }> = ({ label, hint, children }) => (
+    <label className="block space-y-1">
+        <span className="text-xs font-mono text-ark-subtext">{label}</span>
+        {children}
+        {hint && <span className="block text-[10px] text-ark-subtext/70">{hint}</span>}
+    </label>
+);
+
+const selectClass = "w-full bg-ark-bg border-b-2 border-ark-border px-3 py-2 text-sm text-ark-text focus:outline-none focus:border-ark-primary font-mono";
+
+const toggleList = (list: string, item: string) => {
+    const items = list.split(',').filter(Boolean);
+    return (items.includes(item) ? items.filter(i => i !== item) : [...items, item]).join(',');
+};
+
+const formatBytes = (n: number) =>
+    n >= 1 << 20 ? `${(n / (1 << 20)).toFixed(1)} MB` : n >= 1024 ? `${(n / 1024).toFixed(1)} KB` : `${n} B`;
+
+export const SiemExport: React.FC = () => {
+    const { lang, authFetch } = useApp();
+    const { notify } = useNotification();
+    const [destinations, setDestinations] = useState<SyslogDestination[]>([]);
+    const [loading, setLoading] = useState(true);
+    const [edit, setEdit] = useState<SyslogDestination | null>(null);
+    const [saving, setSaving] = useState(false);
+    const [testing, setTesting] = useState<string | null>(null);
+    const [outputs, setOutputs] = useState<OpenSearchOutput[]>([]);
+    const [editOutput, setEditOutput] = useState<OpenSearchOutput | null>(null);
+    const [busOutputs, setBusOutputs] = useState<EventBusOutput[]>([]);
+    const [editBus, setEditBus] = useState<EventBusOutput | null>(null);
+
+    const load = useCallback(async () => {
+        const [destRes, outRes, busRes] = await Promise.all([
+            authFetch('/api/v1/siem/destinations'),
+            authFetch('/api/v1/opensearch/outputs'),
+            authFetch('/api/v1/eventbus/outputs'),
+        ]);
+        if (destRes.ok) setDestinations(await destRes.json());
+        if (outRes.ok) setOutputs(await outRes.json());
+        if (busRes.ok) setBusOutputs(await busRes.json());
+    }, [authFetch]);
+
+    const fetchAll = useCallback(async () => {
+        setLoading(true);
+        try {
+            await load();
+        } catch (e) {
+            console.error("Failed to fetch SIEM outputs", e);
+        } finally {
+            setLoading(false);
+        }
+    }, [load]);
+
+    useEffect(() => { fetchAll(); }, [fetchAll]);
+
+    // Queue and delivery counters move on their own, keep them fresh while the page is open
+    useEffect(() => {
+        const timer = setInterval(() => { load().catch(() => { /* the next tick tries again */ }); }, 10000);
+        return () => clearInterval(timer);
+    }, [load]);
+
+    const request = async (url: string, method: string, body?: unknown) => {
+        try {
+            const res = await authFetch(url, { method, body: body === undefined ? undefined : JSON.stringify(body) });
+            const data = await res.json().catch(() => ({}));
+            if (!res.ok) {
+                notify('error', t('op_failed', lang), data.error || res.statusText);
+                return null;
+            }
+            return data;
+        } catch (e) {
+            notify('error', t('op_failed', lang), t('err_network', lang));
+            return null;
+        }
+    };
+
+    const save = async () => {
+        if (!edit) return;
+        setSaving(true);
+        const url = edit.id ? `/api/v1/siem/destinations/${edit.id}` : '/api/v1/siem/destinations';
+        const data = await request(url, 'POST', edit);
+        setSaving(false);
+        if (data) {
+            notify('success', t('op_success', lang), t('siem_saved', lang));
+            setEdit(null);
+            fetchAll();
+        }
+    };
+
+    const remove = async (d: SyslogDestination) => {
+        if (!window.confirm(t('siem_confirm_delete', lang, { name: d.name }))) return;
+        if (await request(`/api/v1/siem/destinations/${d.id}`, 'DELETE')) fetchAll();
+    };
+
+    const test = async (d: SyslogDestination) => {
+        setTesting(d.id);
+        const data: { sent?: string[] } | null = await request(`/api/v1/siem/destinations/${d.id}/test`, 'POST');
+        setTesting(null);
+        if (data) {
+            const kinds = (data.sent || []).map(k => t(`siem_kind_${k}`, lang)).join(', ');
+            notify('success', t('op_success', lang), kinds ? t('siem_test_sent', lang, { kinds }) : t('siem_test_empty', lang));
+        }
+    };
+
+    const saveOutput = async () => {
+        if (!editOutput) return;
+        setSaving(true);
+        const url = editOutput.id ? `/api/v1/opensearch/outputs/${editOutput.id}` : '/api/v1/opensearch/outputs';
+        const data = await request(url, 'POST', editOutput);
+        setSaving(false);
+        if (data) {
+            notify('success', t('op_success', lang), t('siem_os_saved', lang));
+            setEditOutput(null);
+            fetchAll();
+        }
+    };
+
+    const removeOutput = async (o: OpenSearchOutput) => {
+        if (!window.confirm(t('siem_os_confirm_delete', lang, { name: o.name }))) return;
+        if (await request(`/api/v1/opensearch/outputs/${o.id}`, 'DELETE')) fetchAll();
+    };
+
+    const testOutput = async (o: OpenSearchOutput) => {
+        setTesting(o.id);
+        const data: { cluster?: string, distribution?: string, version?: string } | null = await request(`/api/v1/opensearch/outputs/${o.id}/test`, 'POST');
+        setTesting(null);
+        if (data) {
+            notify('success', t('op_success', lang), t('siem_os_test_ok', lang, {
+                cluster: data.cluster || '-', distribution: data.distribution || '', version: data.version || '',
+            }));
+        }
+    };
+
+    const saveBus = async () => {
+        if (!editBus) return;
+        setSaving(true);
+        const url = editBus.id ? `/api/v1/eventbus/outputs/${editBus.id}` : '/api/v1/eventbus/outputs';
+        const data = await request(url, 'POST', editBus);
+        setSaving(false);
+        if (data) {
+            notify('success', t('op_success', lang), t('siem_bus_saved', lang));
+            setEditBus(null);
+            fetchAll();
+        }
+    };
+
+    const removeBus = async (o: EventBusOutput) => {
+        if (!window.confirm(t('siem_bus_confirm_delete', lang, { name: o.name }))) return;
+        if (await request(`/api/v1/eventbus/outputs/${o.id}`, 'DELETE')) fetchAll();
+    };
+
+    const testBus = async (o: EventBusOutput) => {
+        setTesting(o.id);
+        const data: { sent?: string[] } | null = await request(`/api/v1/eventbus/outputs/${o.id}/test`, 'POST');
+        setTesting(null);
+        if (data) {
+            const kinds = (data.sent || []).map(k => t(`siem_kind_${k}`, lang)).join(', ');
+            notify('success', t('op_success', lang), kinds ? t('siem_test_sent', lang, { kinds }) : t('siem_test_empty', lang));
+        }
+    };
+
+    const setBusType = (type: EventBusOutput['type']) => {
+        if (!editBus) return;
+        // Swap the defaults of the other bus unless they were edited
+        const servers = editBus.servers === DEFAULT_SERVERS[editBus.type] ? DEFAULT_SERVERS[type] : editBus.servers;
+        const topic = editBus.topic === DEFAULT_TOPICS[editBus.type] ? DEFAULT_TOPICS[type] : editBus.topic;
+        setEditBus({ ...editBus, type, servers, topic, jetStream: type === 'nats' && editBus.jetStream });
+    };
+
+    const setProtocol = (protocol: SyslogDestination['protocol']) => {
+        if (!edit) return;
+        // Follow the protocol's well-known port unless one was picked by hand
+        const port = Object.values(DEFAULT_PORTS).includes(edit.port) ? DEFAULT_PORTS[protocol] : edit.port;
+        setEdit({ ...edit, protocol, port, framing: protocol === 'udp' ? '' : edit.framing });
+    };
+
+    const deliveryBadge = (d: Pick<SyslogDestination, 'enabled' | 'connected' | 'lastError'>) => {
+        if (!d.enabled) return <ArkBadge type="neutral">{t('nc_disabled', lang)}</ArkBadge>;
+        if (d.connected) return <ArkBadge type="success">{t('siem_connected', lang)}</ArkBadge>;
+        return <ArkBadge type={d.lastError ? 'error' : 'warn'}>{t('siem_disconnected', lang)}</ArkBadge>;
+    };
+
+    return (
+        <div className="flex flex-col gap-4 pb-6 min-h-full">
+            {/* Description Block */}
+            <div className="bg-ark-panel border border-ark-border p-6 shadow-sm">
+                <div className="flex items-center gap-2 mb-3 font-bold text-ark-text">
+                    <Radio className="text-ark-primary" size={20} />
+                    {t('siem_title', lang)}
+                </div>
+                <div className="text-xs text-ark-subtext font-mono space-y-1.5 leading-relaxed pl-7">
+                    <p>{t('siem_desc', lang)}</p>
+                    <p>• {t('siem_desc_formats', lang)}</p>
+                    <p>• {t('siem_desc_queue', lang)}</p>
+                    <p>• {t('siem_desc_opensearch', lang)}</p>
+                    <p>• {t('siem_desc_eventbus', lang)}</p>
+                </div>
+            </div>
+
+            {/* Destinations */}
+            <div className="flex-1 bg-ark-panel border border-ark-border shadow-sm relative">
+                {loading && <ArkLoading label="FETCHING_DESTINATIONS" />}
+                <div className="flex items-center justify-between p-4 border-b border-ark-border">
+                    <div className="flex items-center gap-2 text-sm font-bold text-ark-text">
+                        <Send size={16} className="text-ark-primary" /> {t('siem_destinations', lang)}
+                    </div>
+                    <div className="flex gap-2">
+                        <ArkButton variant="ghost" className="h-[32px] px-4" onClick={fetchAll} disabled={loading}>
+                            <RefreshCw size={14} className={`mr-1 ${loading ? 'animate-spin' : ''}`} /> {t('refresh', lang)}
+                        </ArkButton>
+                        <ArkButton variant="primary" size="sm" onClick={() => setEdit(emptyDestination())}>
+                            <Plus size={14} className="mr-1" /> {t('siem_add', lang)}
+                        </ArkButton>
+                    </div>
+                </div>
+                <div className="overflow-x-auto custom-scrollbar">
+                    <table className="w-full text-left text-sm min-w-[1000px]">
+                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                            <tr>
+                                <th className="p-4">{t('nc_col_name', lang)}</th>
+                                <th className="p-4">{t('siem_col_target', lang)}</th>
+                                <th className="p-4">{t('siem_col_format', lang)}</th>
+                                <th className="p-4">{t('siem_col_filter', lang)}</th>
+                                <th className="p-4">{t('siem_col_queue', lang)}</th>
+                                <th className="p-4">{t('siem_col_delivery', lang)}</th>
+                                <th className="p-4">{t('nc_col_status', lang)}</th>
+                                <th className="p-4 text-center">{t('nc_col_op', lang)}</th>
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                            {destinations.length === 0 && (
+                                <tr><td colSpan={8} className="p-6 text-center text-ark-subtext">{t('siem_none', lang)}</td></tr>
+                            )}
+                            {destinations.map(d => (
+                                <tr key={d.id} className="hover:bg-ark-active/5 transition-colors">
+                                    <td className="p-4 text-ark-text font-bold">{d.name}</td>
+                                    <td className="p-4 text-ark-subtext break-all">
+                                        <span className="uppercase text-ark-text">{d.protocol}</span> {d.host}:{d.port}
+                                    </td>
+                                    <td className="p-4 text-ark-text uppercase">
+                                        {d.format}
+                                        {d.framing && <span className="block text-[10px] text-ark-subtext normal-case">{t(`siem_framing_${d.framing}`, lang)}</span>}
+                                    </td>
+                                    <td className="p-4 text-ark-subtext">
+                                        {d.kinds ? d.kinds.split(',').map(k => t(`siem_kind_${k}`, lang)).join(', ') : t('siem_all_kinds', lang)}
+                                        <span className="block">≥ {t(`nc_sev_${d.minSeverity}`, lang)}</span>
+                                    </td>
+                                    <td className="p-4 text-ark-text whitespace-nowrap">
+                                        {d.queueLength ?? 0} / {formatBytes(d.queueBytes ?? 0)}
+                                        {!!d.dropped && <span className="block text-red-500">{t('siem_dropped', lang, { n: d.dropped })}</span>}
+                                    </td>
+                                    <td className="p-4 text-ark-subtext whitespace-nowrap">
+                                        {t('siem_sent_count', lang, { sent: d.sent ?? 0, failures: d.failures ?? 0 })}
+                                        {d.lastSentAt && <span className="block">{formatDateTime(d.lastSentAt)}</span>}
+                                    </td>
+                                    <td className="p-4 max-w-[220px]">
+                                        {deliveryBadge(d)}
+                                        {d.enabled && !d.connected && d.lastError && (
+                                            <div className="text-red-500 mt-1 break-all" title={d.lastErrorAt ? formatDateTime(d.lastErrorAt) : undefined}>{d.lastError}</div>
+                                        )}
+                                    </td>
+                                    <td className="p-4">
+                                        <div className="flex items-center justify-center gap-3">
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_test', lang)} onClick={() => test(d)} disabled={testing === d.id}>
+                                                {testing === d.id ? <RefreshCw size={14} className="animate-spin" /> : <Send size={14} />}
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_edit', lang)} onClick={() => setEdit({ ...d })}>
+                                                <FileEdit size={14} />
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-red-500 transition-colors" title={t('nc_delete', lang)} onClick={() => remove(d)}>
+                                                <Trash2 size={14} />
+                                            </button>
+                                        </div>
+                                    </td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+
+            {/* OpenSearch Outputs */}
+            <div className="bg-ark-panel border border-ark-border shadow-sm">
+                <div className="flex items-center justify-between p-4 border-b border-ark-border">
+                    <div className="flex items-center gap-2 text-sm font-bold text-ark-text">
+                        <Database size={16} className="text-ark-primary" /> {t('siem_os_outputs', lang)}
+                    </div>
+                    <ArkButton variant="primary" size="sm" onClick={() => setEditOutput(emptyOutput())}>
+                        <Plus size={14} className="mr-1" /> {t('siem_os_add', lang)}
+                    </ArkButton>
+                </div>
+                <div className="overflow-x-auto custom-scrollbar">
+                    <table className="w-full text-left text-sm min-w-[1000px]">
+                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                            <tr>
+                                <th className="p-4">{t('nc_col_name', lang)}</th>
+                                <th className="p-4">{t('siem_os_col_cluster', lang)}</th>
+                                <th className="p-4">{t('siem_col_filter', lang)}</th>
+                                <th className="p-4">{t('siem_col_queue', lang)}</th>
+                                <th className="p-4">{t('siem_col_delivery', lang)}</th>
+                                <th className="p-4">{t('nc_col_status', lang)}</th>
+                                <th className="p-4 text-center">{t('nc_col_op', lang)}</th>
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                            {outputs.length === 0 && (
+                                <tr><td colSpan={7} className="p-6 text-center text-ark-subtext">{t('siem_os_none', lang)}</td></tr>
+                            )}
+                            {outputs.map(o => (
+                                <tr key={o.id} className="hover:bg-ark-active/5 transition-colors">
+                                    <td className="p-4 text-ark-text font-bold">{o.name}</td>
+                                    <td className="p-4 text-ark-subtext break-all">
+                                        {o.url}
+                                        <span className="block text-ark-text">{o.indexPrefix}-*-yyyy.mm.dd</span>
+                                    </td>
+                                    <td className="p-4 text-ark-subtext">
+                                        {o.kinds ? o.kinds.split(',').map(k => t(`siem_kind_${k}`, lang)).join(', ') : t('siem_all_kinds', lang)}
+                                        <span className="block">≥ {t(`nc_sev_${o.minSeverity}`, lang)}</span>
+                                    </td>
+                                    <td className="p-4 text-ark-text whitespace-nowrap">
+                                        {o.queueLength ?? 0} / {formatBytes(o.queueBytes ?? 0)}
+                                        {!!o.dropped && <span className="block text-red-500">{t('siem_dropped', lang, { n: o.dropped })}</span>}
+                                    </td>
+                                    <td className="p-4 text-ark-subtext whitespace-nowrap">
+                                        {t('siem_os_indexed_count', lang, { indexed: o.indexed ?? 0, rejected: o.rejected ?? 0 })}
+                                        {!!o.retried && <span className="block">{t('siem_os_retried', lang, { n: o.retried })}</span>}
+                                        {o.lastIndexedAt && <span className="block">{formatDateTime(o.lastIndexedAt)}</span>}
+                                    </td>
+                                    <td className="p-4 max-w-[220px]">
+                                        <ArkBadge type={!o.enabled ? 'neutral' : o.lastError && (o.queueLength ?? 0) > 0 ? 'error' : 'success'}>
+                                            {t(o.enabled ? 'nc_enabled' : 'nc_disabled', lang)}
+                                        </ArkBadge>
+                                        {o.enabled && o.lastError && (
+                                            <div className="text-red-500 mt-1 break-all" title={o.lastErrorAt ? formatDateTime(o.lastErrorAt) : undefined}>{o.lastError}</div>
+                                        )}
+                                    </td>
+                                    <td className="p-4">
+                                        <div className="flex items-center justify-center gap-3">
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_test', lang)} onClick={() => testOutput(o)} disabled={testing === o.id}>
+                                                {testing === o.id ? <RefreshCw size={14} className="animate-spin" /> : <Send size={14} />}
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_edit', lang)} onClick={() => setEditOutput({ ...o, password: '', apiKey: '' })}>
+                                                <FileEdit size={14} />
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-red-500 transition-colors" title={t('nc_delete', lang)} onClick={() => removeOutput(o)}>
+                                                <Trash2 size={14} />
+                                            </button>
+                                        </div>
+                                    </td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+                <div className="p-3 border-t border-ark-border bg-ark-bg text-[10px] font-mono text-ark-subtext">
+                    {t('siem_os_backfill_hint', lang)} <code className="text-ark-text">go run ./cmd/opensearch-backfill -output NAME -since 2025-01-01</code>
+                </div>
+            </div>
+
+            {/* Event Bus Outputs */}
+            <div className="bg-ark-panel border border-ark-border shadow-sm">
+                <div className="flex items-center justify-between p-4 border-b border-ark-border">
+                    <div className="flex items-center gap-2 text-sm font-bold text-ark-text">
+                        <Share2 size={16} className="text-ark-primary" /> {t('siem_bus_outputs', lang)}
+                    </div>
+                    <ArkButton variant="primary" size="sm" onClick={() => setEditBus(emptyBusOutput())}>
+                        <Plus size={14} className="mr-1" /> {t('siem_bus_add', lang)}
+                    </ArkButton>
+                </div>
+                <div className="overflow-x-auto custom-scrollbar">
+                    <table className="w-full text-left text-sm min-w-[1000px]">
+                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                            <tr>
+                                <th className="p-4">{t('nc_col_name', lang)}</th>
+                                <th className="p-4">{t('siem_bus_col_target', lang)}</th>
+                                <th className="p-4">{t('siem_col_filter', lang)}</th>
+                                <th className="p-4">{t('siem_col_queue', lang)}</th>
+                                <th className="p-4">{t('siem_col_delivery', lang)}</th>
+                                <th className="p-4">{t('nc_col_status', lang)}</th>
+                                <th className="p-4 text-center">{t('nc_col_op', lang)}</th>
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                            {busOutputs.length === 0 && (
+                                <tr><td colSpan={7} className="p-6 text-center text-ark-subtext">{t('siem_bus_none', lang)}</td></tr>
+                            )}
+                            {busOutputs.map(o => (
+                                <tr key={o.id} className="hover:bg-ark-active/5 transition-colors">
+                                    <td className="p-4 text-ark-text font-bold">{o.name}</td>
+                                    <td className="p-4 text-ark-subtext break-all">
+                                        <span className="uppercase text-ark-text">{o.type}</span> {o.servers}
+                                        <span className="block text-ark-text">{o.topic} <span className="text-ark-subtext uppercase">{o.encoding}</span></span>
+                                    </td>
+                                    <td className="p-4 text-ark-subtext">
+                                        {o.kinds ? o.kinds.split(',').map(k => t(`siem_kind_${k}`, lang)).join(', ') : t('siem_all_kinds', lang)}
+                                        <span className="block">≥ {t(`nc_sev_${o.minSeverity}`, lang)}</span>
+                                    </td>
+                                    <td className="p-4 text-ark-text whitespace-nowrap">
+                                        {o.queueLength ?? 0} / {formatBytes(o.queueBytes ?? 0)}
+                                        {!!o.dropped && <span className="block text-red-500">{t('siem_dropped', lang, { n: o.dropped })}</span>}
+                                    </td>
+                                    <td className="p-4 text-ark-subtext whitespace-nowrap">
+                                        {t('siem_bus_published_count', lang, { published: o.published ?? 0, rejected: o.rejected ?? 0 })}
+                                        {o.lastPublishedAt && <span className="block">{formatDateTime(o.lastPublishedAt)}</span>}
+                                    </td>
+                                    <td className="p-4 max-w-[220px]">
+                                        {deliveryBadge(o)}
+                                        {o.enabled && !o.connected && o.lastError && (
+                                            <div className="text-red-500 mt-1 break-all" title={o.lastErrorAt ? formatDateTime(o.lastErrorAt) : undefined}>{o.lastError}</div>
+                                        )}
+                                    </td>
+                                    <td className="p-4">
+                                        <div className="flex items-center justify-center gap-3">
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_test', lang)} onClick={() => testBus(o)} disabled={testing === o.id}>
+                                                {testing === o.id ? <RefreshCw size={14} className="animate-spin" /> : <Send size={14} />}
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-ark-primary transition-colors" title={t('nc_edit', lang)} onClick={() => setEditBus({ ...o, password: '', token: '' })}>
+                                                <FileEdit size={14} />
+                                            </button>
+                                            <button className="text-ark-subtext hover:text-red-500 transition-colors" title={t('nc_delete', lang)} onClick={() => removeBus(o)}>
+                                                <Trash2 size={14} />
+                                            </button>
+                                        </div>
+                                    </td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+
+            {/* Destination Editor */}
+            <ArkModal
+                isOpen={!!edit}
+                onClose={() => setEdit(null)}
+                title={t(edit?.id ? 'siem_edit' : 'siem_add', lang)}
+                icon={<Radio size={18} />}
+                maxWidth="max-w-2xl"
+                footer={<>
+                    <ArkButton variant="ghost" onClick={() => setEdit(null)}>{t('btn_cancel', lang)}</ArkButton>
+                    <ArkButton variant="primary" onClick={save} disabled={saving}>{t('btn_save', lang)}</ArkButton>
+                </>}
+            >
+                {edit && (
+                    <div className="grid grid-cols-1 md:grid-cols-2 gap-4 max-h-[65vh] overflow-y-auto custom-scrollbar pr-1">
+                        <Field label={t('nc_col_name', lang)}>
+                            <ArkInput value={edit.name} onChange={e => setEdit({ ...edit, name: e.target.value })} />
+                        </Field>
+                        <Field label={t('siem_protocol', lang)}>
+                            <select className={selectClass} value={edit.protocol} onChange={e => setProtocol(e.target.value as SyslogDestination['protocol'])}>
+                                <option value="udp">UDP</option>
+                                <option value="tcp">TCP</option>
+                                <option value="tls">TLS</option>
+                            </select>
+                        </Field>
+                        <Field label={t('siem_host', lang)}>
+                            <ArkInput value={edit.host} onChange={e => setEdit({ ...edit, host: e.target.value })} />
+                        </Field>
+                        <Field label={t('siem_port', lang)}>
+                            <ArkInput type="number" min={1} max={65535} value={edit.port} onChange={e => setEdit({ ...edit, port: parseInt(e.target.value) || 0 })} />
+                        </Field>
+                        <Field label={t('siem_col_format', lang)}>
+                            <select className={selectClass} value={edit.format} onChange={e => setEdit({ ...edit, format: e.target.value as SyslogDestination['format'] })}>
+                                <option value="cef">CEF</option>
+                                <option value="leef">LEEF</option>
+                                <option value="json">{t('siem_format_json', lang)}</option>
+                            </select>
+                        </Field>
+                        <Field label={t('siem_framing', lang)} hint={t('siem_framing_hint', lang)}>
+                            <select className={selectClass} value={edit.framing} disabled={edit.protocol === 'udp'}
+                                onChange={e => setEdit({ ...edit, framing: e.target.value as SyslogDestination['framing'] })}>
+                                <option value="">{t('siem_framing_default', lang)}</option>
+                                <option value="octet">{t('siem_framing_octet', lang)}</option>
+                                <option value="newline">{t('siem_framing_newline', lang)}</option>
+                            </select>
+                        </Field>
+                        <Field label={t('siem_facility', lang)} hint={t('siem_facility_hint', lang)}>
+                            <ArkInput type="number" min={0} max={23} value={edit.facility} onChange={e => setEdit({ ...edit, facility: parseInt(e.target.value) || 0 })} />
+                        </Field>
+                        <Field label={t('siem_max_queue', lang)} hint={t('siem_max_queue_hint', lang)}>
+                            <ArkInput type="number" min={1} max={4096} value={edit.maxQueueMb} onChange={e => setEdit({ ...edit, maxQueueMb: parseInt(e.target.value) || 0 })} />
+                        </Field>
+                        <div className="md:col-span-2">
+                            <Field label={t('siem_kinds', lang)} hint={t('siem_kinds_hint', lang)}>
+                                <div className="flex flex-wrap gap-2 pt-1">
+                                    {KINDS.map(k => {
+                                        const on = edit.kinds.split(',').includes(k);
+                                        return (
+                                            <button key={k} type="button" onClick={() => setEdit({ ...edit, kinds: toggleList(edit.kinds, k) })}
+                                                className={`px-2 py-1 text-xs border transition-colors ${on ? 'border-ark-primary bg-ark-primary/10 text-ark-primary' : 'border-ark-border text-ark-subtext hover:text-ark-text'}`}>
+                                                {t(`siem_kind_${k}`, lang)}
+                                            </button>
+                                        );
+                                    })}
+                                </div>
+                            </Field>
+                        </div>
+                        <Field label={t('nc_col_min_severity', lang)}>
+                            <select className={selectClass} value={edit.minSeverity} onChange={e => setEdit({ ...edit, minSeverity: e.target.value })}>
+                                {SEVERITIES.map(s => <option key={s} value={s}>{t(`nc_sev_${s}`, lang)}</option>)}
+                            </select>
+                        </Field>
+                        {edit.protocol === 'tls' && (<>
+                            <div className="md:col-span-2">
+                                <Field label={t('siem_ca_cert', lang)} hint={t('siem_ca_cert_hint', lang)}>
+                                    <textarea rows={4} className={`${selectClass} resize-y text-xs`} value={edit.caCert} placeholder="-----BEGIN CERTIFICATE-----"
+                                        onChange={e => setEdit({ ...edit, caCert: e.target.value })} />
+                                </Field>
+                            </div>
+                            <label className="flex items-center gap-2 text-xs text-ark-text">
+                                <input type="checkbox" checked={edit.insecureSkipVerify} onChange={e => setEdit({ ...edit, insecureSkipVerify: e.target.checked })} />
+                                {t('nc_insecure', lang)}
+                            </label>
+                        </>)}
+                        <label className="flex items-center gap-2 text-xs text-ark-text">
+                            <input type="checkbox" checked={edit.enabled} onChange={e => setEdit({ ...edit, enabled: e.target.checked })} />
+                            {t('nc_enabled', lang)}
+                        </label>
+                    </div>
+                )}
+            </ArkModal>
+
+            {/* OpenSearch Output Editor */}
+            <ArkModal
+                isOpen={!!editOutput}
+                onClose={() => setEditOutput(null)}
+                title={t(editOutput?.id ? 'siem_os_edit' : 'siem_os_add', lang)}
+                icon={<Database size={18} />}
+                maxWidth="max-w-2xl"
+                footer={<>
+                    <ArkButton variant="ghost" onClick={() => setEditOutput(null)}>{t('btn_cancel', lang)}</ArkButton>
+                    <ArkButton variant="primary" onClick={saveOutput} disabled={saving}>{t('btn_save', lang)}</ArkButton>
+                </>}
+            >
+                {editOutput && (
+                    <div className="grid grid-cols-1 md:grid-cols-2 gap-4 max-h-[65vh] overflow-y-auto custom-scrollbar pr-1">
+                        <Field label={t('nc_col_name', lang)}>
+                            <ArkInput value={editOutput.name} onChange={e => setEditOutput({ ...editOutput, name: e.target.value })} />
+                        </Field>
+                        <Field label={t('siem_os_prefix', lang)} hint={t('siem_os_prefix_hint', lang)}>
+                            <ArkInput value={editOutput.indexPrefix} onChange={e => setEditOutput({ ...editOutput, indexPrefix: e.target.value })} />
+                        </Field>
+                        <div className="md:col-span-2">
+                            <Field label={t('siem_os_url', lang)}>
+                                <ArkInput value={editOutput.url} onChange={e => setEditOutput({ ...editOutput, url: e.target.value })} />
+                            </Field>
+                        </div>
+                        <Field label={t('nc_smtp_username', lang)}>
+                            <ArkInput value={editOutput.username} onChange={e => setEditOutput({ ...editOutput, username: e.target.value })} />
+                        </Field>
+                        <Field label={t('nc_smtp_password', lang)} hint={editOutput.hasPassword ? t('nc_secret_kept', lang) : undefined}>
+                            <ArkInput type="password" value={editOutput.password || ''} onChange={e => setEditOutput({ ...editOutput, password: e.target.value })} />
+                        </Field>
+                        <div className="md:col-span-2">
+                            <Field label={t('siem_os_api_key', lang)} hint={editOutput.hasApiKey ? t('nc_secret_kept', lang) : t('siem_os_api_key_hint', lang)}>
+                                <ArkInput type="password" value={editOutput.apiKey || ''} onChange={e => setEditOutput({ ...editOutput, apiKey: e.target.value })} />
+                            </Field>
+                        </div>
+                        <Field label={t('siem_os_batch_size', lang)}>
+                            <ArkInput type="number" min={1} max={10000} value={editOutput.batchSize} onChange={e => setEditOutput({ ...editOutput, batchSize: parseInt(e.target.value) || 0 })} />
+                        </Field>
+                        <Field label={t('siem_os_flush', lang)} hint={t('siem_os_flush_hint', lang)}>
+                            <ArkInput type="number" min={1} max={300} value={editOutput.flushSeconds} onChange={e => setEditOutput({ ...editOutput, flushSeconds: parseInt(e.target.value) || 0 })} />
+                        </Field>
+                        <div className="md:col-span-2">
+                            <Field label={t('siem_kinds', lang)} hint={t('siem_kinds_hint', lang)}>
+                                <div className="flex flex-wrap gap-2 pt-1">
+                                    {KINDS.map(k => {
+                                        const on = editOutput.kinds.split(',').includes(k);
+                                        return (
+                                            <button key={k} type="button" onClick={() => setEditOutput({ ...editOutput, kinds: toggleList(editOutput.kinds, k) })}
+                                                className={`px-2 py-1 text-xs border transition-colors ${on ? 'border-ark-primary bg-ark-primary/10 text-ark-primary' : 'border-ark-border text-ark-subtext hover:text-ark-text'}`}>
+                                                {t(`siem_kind_${k}`, lang)}
+                                            </button>
+                                        );
+                                    })}
+                                </div>
+                            </Field>
+                        </div>
+                        <Field label={t('nc_col_min_severity', lang)}>
+                            <select className={selectClass} value={editOutput.minSeverity} onChange={e => setEditOutput({ ...editOutput, minSeverity: e.target.value })}>
+                                {SEVERITIES.map(s => <option key={s} value={s}>{t(`nc_sev_${s}`, lang)}</option>)}
+                            </select>
+                        </Field>
+                        <Field label={t('siem_max_queue', lang)} hint={t('siem_max_queue_hint', lang)}>
+                            <ArkInput type="number" min={1} max={4096} value={editOutput.maxQueueMb} onChange={e => setEditOutput({ ...editOutput, maxQueueMb: parseInt(e.target.value) || 0 })} />
+                        </Field>
+                        {editOutput.url.startsWith('https') && (
+                            <div className="md:col-span-2">
+                                <Field label={t('siem_ca_cert', lang)} hint={t('siem_ca_cert_hint', lang)}>
+                                    <textarea rows={4} className={`${selectClass} resize-y text-xs`} value={editOutput.caCert} placeholder="-----BEGIN CERTIFICATE-----"
+                                        onChange={e => setEditOutput({ ...editOutput, caCert: e.target.value })} />
+                                </Field>
+                            </div>
+                        )}
+                        <label className="flex items-center gap-2 text-xs text-ark-text">
+                            <input type="checkbox" checked={!editOutput.skipTemplate} onChange={e => setEditOutput({ ...editOutput, skipTemplate: !e.target.checked })} />
+                            {t('siem_os_manage_template', lang)}
+                        </label>
+                        {editOutput.url.startsWith('https') && (
+                            <label className="flex items-center gap-2 text-xs text-ark-text">
+                                <input type="checkbox" checked={editOutput.insecureSkipVerify} onChange={e => setEditOutput({ ...editOutput, insecureSkipVerify: e.target.checked })} />
+                                {t('nc_insecure', lang)}
+                            </label>
+                        )}
+                        <label className="flex items-center gap-2 text-xs text-ark-text">
+                            <input type="checkbox" checked={editOutput.enabled} onChange={e => setEditOutput({ ...editOutput, enabled: e.target.checked })} />
+                            {t('nc_enabled', lang)}
+                        </label>
+                    </div>
+                )}
+            </ArkModal>
+            {/* Event Bus Output Editor */}
+            <ArkModal
+                isOpen={!!editBus}
+                onClose={() => setEditBus(null)}
+                title={t(editBus?.id ? 'siem_bus_edit' : 'siem_bus_add', lang)}
+                icon={<Share2 size={18} />}
+                maxWidth="max-w-2xl"
+                footer={<>
+                    <ArkButton variant="ghost" onClick={() => setEditBus(null)}>{t('btn_cancel', lang)}</ArkButton>
+                    <ArkButton variant="primary" onClick={saveBus} disabled={saving}>{t('btn_save', lang)}</ArkButton>
+                </>}
+            >
+                {editBus && (
+                    <div className="grid grid-cols-1 md:grid-cols-2 gap-4 max-h-[65vh] overflow-y-auto custom-scrollbar pr-1">
+                        <Field label={t('nc_col_name', lang)}>
+                            <ArkInput value={editBus.name} onChange={e => setEditBus({ ...editBus, name: e.target.value })} />
+                        </Field>
+                        <Field label={t('siem_bus_type', lang)}>
+                            <select className={selectClass} value={editBus.type} onChange={e => setBusType(e.target.value as EventBusOutput['type'])}>
+                                <option value="kafka">Kafka</option>
+                                <option value="nats">NATS</option>
+                            </select>
+                        </Field>
+                        <div className="md:col-span-2">
+                            <Field label={t('siem_bus_servers', lang)} hint={t(`siem_bus_servers_hint_${editBus.type}`, lang)}>
+                                <ArkInput value={editBus.servers} onChange={e => setEditBus({ ...editBus, servers: e.target.value })} />
+                            </Field>
+                        </div>
+                        <Field label={t(editBus.type === 'kafka' ? 'siem_bus_topic' : 'siem_bus_subject', lang)} hint={t('siem_bus_topic_hint', lang)}>
+                            <ArkInput value={editBus.topic} onChange={e => setEditBus({ ...editBus, topic: e.target.value })} />
+                        </Field>
+                        <Field label={t('siem_bus_encoding', lang)}>
+                            <select className={selectClass} value={editBus.encoding} onChange={e => setEditBus({ ...editBus, encoding: e.target.value as EventBusOutput['encoding'] })}>
+                                <option value="json">{t('siem_bus_encoding_json', lang)}</option>
+                                <option value="protobuf">{t('siem_bus_encoding_protobuf', lang)}</option>
+                            </select>
+                        </Field>
+                        <Field label={t('nc_smtp_username', lang)} hint={editBus.type === 'kafka' ? t('siem_bus_sasl_hint', lang) : undefined}>
+                            <ArkInput value={editBus.username} onChange={e => setEditBus({ ...editBus, username: e.target.value })} />
+                        </Field>
+                        <Field label={t('nc_smtp_password', lang)} hint={editBus.hasPassword ? t('nc_secret_kept', lang) : undefined}>
+                            <ArkInput type="password" value={editBus.password || ''} onChange={e => setEditBus({ ...editBus, password: e.target.value })} />
+                        </Field>
+                        {editBus.type === 'nats' && (
+                            <div className="md:col-span-2">
+                                <Field label={t('siem_bus_token', lang)} hint={editBus.hasToken ? t('nc_secret_kept', lang) : undefined}>
+                                    <ArkInput type="password" value={editBus.token || ''} onChange={e => setEditBus({ ...editBus, token: e.target.value })} />
+                                </Field>
+                            </div>
+                        )}
+                        <div className="md:col-span-2">
+                            <Field label={t('siem_kinds', lang)} hint={t('siem_kinds_hint', lang)}>
+                                <div className="flex flex-wrap gap-2 pt-1">
+                                    {BUS_KINDS.map(k => {
+                                        const on = editBus.kinds.split(',').includes(k);
+                                        return (
+                                            <button key={k} type="button" onClick={() => setEditBus({ ...editBus, kinds: toggleList(editBus.kinds, k) })}
+                                                className={`px-2 py-1 text-xs border transition-colors ${on ? 'border-ark-primary bg-ark-primary/10 text-ark-primary' : 'border-ark-border text-ark-subtext hover:text-ark-text'}`}>
+                                                {t(`siem_kind_${k}`, lang)}
+                                            </button>
+                                        );
+                                    })}
+                                </div>
+                            </Field>
+                        </div>
+                        <Field label={t('nc_col_min_severity', lang)}>
+                            <select className={selectClass} value={editBus.minSeverity} onChange={e => setEditBus({ ...editBus, minSeverity: e.target.value })}>
+                                {SEVERITIES.map(s => <option key={s} value={s}>{t(`nc_sev_${s}`, lang)}</option>)}
+                            </select>
+                        </Field>
+                        <Field label={t('siem_max_queue', lang)} hint={t('siem_max_queue_hint', lang)}>
+                            <ArkInput type="number" min={1} max={4096} value={editBus.maxQueueMb} onChange={e => setEditBus({ ...editBus, maxQueueMb: parseInt(e.target.value) || 0 })} />
+                        </Field>
+                        {editBus.type === 'nats' && (
+                            <div className="md:col-span-2">
+                                <label className="flex items-center gap-2 text-xs text-ark-text">
+                                    <input type="checkbox" checked={editBus.jetStream} onChange={e => setEditBus({ ...editBus, jetStream: e.target.checked })} />
+                                    {t('siem_bus_jetstream', lang)}
+                                </label>
+                                {!editBus.jetStream && <span className="block text-[10px] text-ark-subtext/70 mt-1">{t('siem_bus_core_hint', lang)}</span>}
+                            </div>
+                        )}
+                        <label className="flex items-center gap-2 text-xs text-ark-text">
+                            <input type="checkbox" checked={editBus.tls} onChange={e => setEditBus({ ...editBus, tls: e.target.checked })} />
+                            {t('siem_bus_tls', lang)}
+                        </label>
+                        {editBus.tls && (<>
+                            <label className="flex items-center gap-2 text-xs text-ark-text">
+                                <input type="checkbox" checked={editBus.insecureSkipVerify} onChange={e => setEditBus({ ...editBus, insecureSkipVerify: e.target.checked })} />
+                                {t('nc_insecure', lang)}
+                            </label>
+                            <div className="md:col-span-2">
+                                <Field label={t('siem_ca_cert', lang)} hint={t('siem_ca_cert_hint', lang)}>
+                                    <textarea rows={4} className={`${selectClass} resize-y text-xs`} value={editBus.caCert} placeholder="-----BEGIN CERTIFICATE-----"
+                                        onChange={e => setEditBus({ ...editBus, caCert: e.target.value })} />
+                                </Field>
+                            </div>
+                        </>)}
+                        <label className="flex items-center gap-2 text-xs text-ark-text">
+                            <input type="checkbox" checked={editBus.enabled} onChange={e => setEditBus({ ...editBus, enabled: e.target.checked })} />
+                            {t('nc_enabled', lang)}
+                        </label>
+                    </div>
+                )}
+            </ArkModal>
+        </div>
+    );
+};
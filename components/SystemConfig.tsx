@@ -5,6 +5,7 @@ import { useApp } from '../AppContext';
 import { t } from '../i18n';
 import { FileText, Bell, Webhook, Database, Key, LayoutGrid, Clock, Share2, Save, Terminal, Shield, Plus, Copy, RefreshCw, Trash2, ExternalLink, Mail, HardDrive, Lock, Image, MapPin, Radio, Upload, User, LogIn, Activity, Users, X, Sparkles, Network, ShieldAlert } from 'lucide-react';
 import { useNotification } from './NotificationSystem';
+import { formatDateTime } from '../time';
 
 const ConfigTab: React.FC<{ 
     label: string, 
@@ -46,6 +47,26 @@ interface LoginLog {
     device: string;
 }
 
+interface TimeSyncLog {
+    id: number;
+    time: string;
+    trigger: 'manual' | 'schedule';
+    status: 'synchronized' | 'failed';
+    offset: number; // seconds
+    servers: number;
+    used: number;
+    error: string;
+}
+
+interface NodeClock {
+    id: string;
+    name: string;
+    status: string;
+    offset: number; // milliseconds
+    checkedAt: string | null;
+    drift: boolean;
+}
+
 export const SystemConfig: React.FC = () => {
     const { lang, user, authFetch } = useApp();
     const { notify } = useNotification();
@@ -59,6 +80,8 @@ export const SystemConfig: React.FC = () => {
         lastSync: null as string | null
     });
     const [loginLogs, setLoginLogs] = useState<LoginLog[]>([]);
+    const [ntpHistory, setNtpHistory] = useState<TimeSyncLog[]>([]);
+    const [nodeClocks, setNodeClocks] = useState<NodeClock[]>([]);
     const [isLoadingLogs, setIsLoadingLogs] = useState(false);
 
     useEffect(() => {
@@ -100,7 +123,7 @@ export const SystemConfig: React.FC = () => {
                     if (data.custom_config) setCustomConfig(JSON.parse(data.custom_config));
                     
                     // NTP
-                    if (data.ntp_config) setNtpConfig(JSON.parse(data.ntp_config));
+                    if (data.ntp_config) setNtpConfig(prev => ({ ...prev, ...JSON.parse(data.ntp_config) }));
                     if (data.time_offset) {
                         const offsetNs = parseInt(data.time_offset);
                         setNtpStatus(prev => ({
@@ -122,6 +145,33 @@ export const SystemConfig: React.FC = () => {
         fetchConfig();
     }, []);
 
+    const fetchTimeStatus = async () => {
+        try {
+            const token = localStorage.getItem('prts_token');
+            const headers = { 'Authorization': `Bearer ${token}` };
+            const [statusRes, historyRes] = await Promise.all([
+                fetch('/api/v1/system/time-status', { headers }),
+                fetch('/api/v1/system/ntp-history?limit=20', { headers })
+            ]);
+            if (statusRes.ok) {
+                const data = await statusRes.json();
+                setNodeClocks(data.nodes || []);
+                setNtpStatus({ status: data.status === 'failed' ? 'error' : data.status, offset: data.offset, lastSync: data.lastSync });
+            }
+            if (historyRes.ok) setNtpHistory(await historyRes.json());
+        } catch (e) {
+            console.error("Failed to fetch time status", e);
+        }
+    };
+
+    useEffect(() => {
+        if (activeTab === 'ntp') fetchTimeStatus();
+    }, [activeTab]);
+
+    useEffect(() => {
+        if (activeTab === 'api' && user?.role === 'admin') fetchMetricsConfig();
+    }, [activeTab]);
+
     useEffect(() => {
         if (activeTab === 'login') {
             const fetchData = async () => {
@@ -165,6 +215,10 @@ export const SystemConfig: React.FC = () => {
     // API
     const [apiEnabled, setApiEnabled] = useState(true);
     const [aiEnabled, setAiEnabled] = useState(false);
+    const [metricsConfig, setMetricsConfig] = useState({ enabled: false, auth: 'bearer', username: '', allowedNets: '' });
+    const [metricsSecrets, setMetricsSecrets] = useState({ token: '', password: '' });
+    const [metricsStored, setMetricsStored] = useState({ hasToken: false, hasPassword: false });
+    const [isSavingMetrics, setIsSavingMetrics] = useState(false);
     const [aiConfig, setAiConfig] = useState({
         provider: 'google',
         model: 'gemini-3-flash-preview',
@@ -232,7 +286,7 @@ export const SystemConfig: React.FC = () => {
     const [customConfig, setCustomConfig] = useState({ name: 'PRTS HONEYPOT', copyright: '© 2025 RHODES ISLAND' });
 
     // NTP
-    const [ntpConfig, setNtpConfig] = useState({ server: 'pool.ntp.org', interval: 60 });
+    const [ntpConfig, setNtpConfig] = useState({ server: 'pool.ntp.org', interval: 60, driftThreshold: 2000, correctTimestamps: true });
 
 
     // --- Actions ---
@@ -375,6 +429,43 @@ export const SystemConfig: React.FC = () => {
         notify('warning', t('op_success', lang), t('op_key_revoked', lang));
     };
 
+    const fetchMetricsConfig = async () => {
+        try {
+            const res = await authFetch('/api/v1/system/metrics');
+            if (res.ok) {
+                const data = await res.json();
+                setMetricsConfig(prev => ({ ...prev, ...data.config }));
+                setMetricsStored({ hasToken: data.hasToken, hasPassword: data.hasPassword });
+            }
+        } catch (e) {
+            console.error("Failed to fetch metrics config", e);
+        }
+    };
+
+    // Empty secrets keep the stored ones
+    const handleSaveMetrics = async () => {
+        setIsSavingMetrics(true);
+        try {
+            const res = await authFetch('/api/v1/system/metrics', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ ...metricsConfig, ...metricsSecrets })
+            });
+            const data = await res.json();
+            if (!res.ok) {
+                notify('warning', t('op_failed', lang), data.error);
+                return;
+            }
+            setMetricsSecrets({ token: '', password: '' });
+            fetchMetricsConfig();
+            notify('success', t('op_success', lang), t('op_save_success', lang));
+        } catch (e) {
+            notify('error', t('op_failed', lang), t('err_network', lang));
+        } finally {
+            setIsSavingMetrics(false);
+        }
+    };
+
     const handleSync = async () => {
         if (isSyncing) return;
         setIsSyncing(true);
@@ -396,6 +487,7 @@ export const SystemConfig: React.FC = () => {
                 setNtpStatus(prev => ({ ...prev, status: 'error' }));
                 notify('error', t('op_failed', lang), data.error || 'NTP Sync Failed');
             }
+            fetchTimeStatus();
         } catch (e) {
             console.error("NTP Sync Error:", e);
             setNtpStatus(prev => ({ ...prev, status: 'error' }));
@@ -642,6 +734,95 @@ export const SystemConfig: React.FC = () => {
                                  </div>
                              </ArkCard>
 
+                             {user?.role === 'admin' && (
+                             <ArkCard title={t('sc_metrics_title', lang)} sub={t('sc_metrics_subtitle', lang)}>
+                                 <div className="space-y-6">
+                                     <div className="flex items-center justify-between gap-4 p-4 border border-ark-border bg-ark-bg/30">
+                                         <div className="flex items-center gap-4 min-w-0">
+                                             <div className="p-3 bg-ark-active/20 rounded-full text-ark-primary border border-ark-primary/30 shrink-0">
+                                                 <Activity size={20} />
+                                             </div>
+                                             <div className="min-w-0">
+                                                 <h4 className="font-bold text-ark-text">{t('sc_metrics_enable', lang)}</h4>
+                                                 <div className="text-xs text-ark-subtext font-mono mt-1 truncate select-all">{window.location.origin}/metrics</div>
+                                             </div>
+                                         </div>
+                                         <ToggleSwitch checked={metricsConfig.enabled} onChange={() => setMetricsConfig({ ...metricsConfig, enabled: !metricsConfig.enabled })} />
+                                     </div>
+
+                                     <div className="grid grid-cols-1 md:grid-cols-3 gap-2 md:gap-6 items-start md:items-center">
+                                         <label className="text-sm font-bold text-ark-subtext md:text-right">{t('sc_metrics_auth', lang)}</label>
+                                         <div className="md:col-span-2">
+                                             <select
+                                                 value={metricsConfig.auth}
+                                                 onChange={(e) => setMetricsConfig({ ...metricsConfig, auth: e.target.value })}
+                                                 className="w-full bg-transparent border-b-2 border-ark-border px-3 py-2 text-sm outline-none focus:border-ark-primary transition-colors text-ark-text rounded-none"
+                                             >
+                                                 <option value="bearer">{t('sc_metrics_auth_bearer', lang)}</option>
+                                                 <option value="basic">{t('sc_metrics_auth_basic', lang)}</option>
+                                                 <option value="none">{t('sc_metrics_auth_none', lang)}</option>
+                                             </select>
+                                         </div>
+                                     </div>
+
+                                     {metricsConfig.auth === 'bearer' && (
+                                         <div className="grid grid-cols-1 md:grid-cols-3 gap-2 md:gap-6 items-start md:items-center">
+                                             <label className="text-sm font-bold text-ark-subtext md:text-right">{t('sc_metrics_token', lang)}</label>
+                                             <div className="md:col-span-2">
+                                                 <ArkInput
+                                                     type="password"
+                                                     value={metricsSecrets.token}
+                                                     placeholder={metricsStored.hasToken ? t('sc_metrics_secret_kept', lang) : ''}
+                                                     onChange={(e) => setMetricsSecrets({ ...metricsSecrets, token: e.target.value })}
+                                                     className="bg-transparent"
+                                                 />
+                                             </div>
+                                         </div>
+                                     )}
+
+                                     {metricsConfig.auth === 'basic' && (
+                                         <div className="grid grid-cols-1 md:grid-cols-3 gap-2 md:gap-6 items-start md:items-center">
+                                             <label className="text-sm font-bold text-ark-subtext md:text-right">{t('sc_metrics_basic', lang)}</label>
+                                             <div className="md:col-span-2 grid grid-cols-2 gap-4">
+                                                 <ArkInput
+                                                     value={metricsConfig.username}
+                                                     placeholder={t('sc_metrics_username', lang)}
+                                                     onChange={(e) => setMetricsConfig({ ...metricsConfig, username: e.target.value })}
+                                                     className="bg-transparent"
+                                                 />
+                                                 <ArkInput
+                                                     type="password"
+                                                     value={metricsSecrets.password}
+                                                     placeholder={metricsStored.hasPassword ? t('sc_metrics_secret_kept', lang) : t('sc_metrics_password', lang)}
+                                                     onChange={(e) => setMetricsSecrets({ ...metricsSecrets, password: e.target.value })}
+                                                     className="bg-transparent"
+                                                 />
+                                             </div>
+                                         </div>
+                                     )}
+
+                                     <div className="grid grid-cols-1 md:grid-cols-3 gap-2 md:gap-6 items-start md:items-center">
+                                         <label className="text-sm font-bold text-ark-subtext md:text-right">{t('sc_metrics_allowed_nets', lang)}</label>
+                                         <div className="md:col-span-2">
+                                             <ArkInput
+                                                 value={metricsConfig.allowedNets}
+                                                 placeholder="10.0.0.0/8, 192.168.1.20"
+                                                 onChange={(e) => setMetricsConfig({ ...metricsConfig, allowedNets: e.target.value })}
+                                                 className="bg-transparent font-mono"
+                                             />
+                                             <p className="text-[10px] text-ark-subtext font-mono mt-1">{t('sc_metrics_allowed_nets_hint', lang)}</p>
+                                         </div>
+                                     </div>
+
+                                     <div className="flex justify-end pt-4 border-t border-ark-border/50">
+                                         <ArkButton variant="primary" size="sm" onClick={handleSaveMetrics} loading={isSavingMetrics}>
+                                             <Save size={16} className="mr-2" /> {t('sc_btn_save', lang)}
+                                         </ArkButton>
+                                     </div>
+                                 </div>
+                             </ArkCard>
+                             )}
+
                              <ArkCard title={t('sc_ai_title', lang)} sub={t('sc_ai_subtitle', lang)}>
                                 <div className="space-y-6">
                                     <div className="flex items-center justify-between p-4 border border-ark-border bg-ark-bg/20">
@@ -1211,7 +1392,7 @@ export const SystemConfig: React.FC = () => {
                                             ) : loginLogs.length > 0 ? (
                                                 loginLogs.map(log => (
                                                     <tr key={log.id} className="hover:bg-ark-active/5 transition-colors">
-                                                        <td className="p-3 pl-4 text-ark-subtext">{log.time}</td>
+                                                        <td className="p-3 pl-4 text-ark-subtext">{formatDateTime(log.time)}</td>
                                                         <td className="p-3 font-bold text-ark-text">{log.ip}</td>
                                                         <td className="p-3 text-ark-subtext" title={log.device}>
                                                             <div className="flex items-center gap-2">
@@ -1298,6 +1479,21 @@ export const SystemConfig: React.FC = () => {
                                                 onChange={e => setNtpConfig({...ntpConfig, interval: parseInt(e.target.value)})}
                                             />
                                         </div>
+                                        <div className="space-y-1">
+                                            <label className="text-xs font-mono text-ark-subtext">{t('sc_ntp_drift_threshold', lang)}</label>
+                                            <ArkInput 
+                                                type="number" 
+                                                value={ntpConfig.driftThreshold}
+                                                onChange={e => setNtpConfig({...ntpConfig, driftThreshold: parseInt(e.target.value)})}
+                                            />
+                                        </div>
+                                        <div className="flex items-center justify-between p-3 border border-ark-border bg-ark-bg/20">
+                                            <div>
+                                                <div className="text-sm font-bold text-ark-text">{t('sc_ntp_correct_timestamps', lang)}</div>
+                                                <div className="text-xs text-ark-subtext">{t('sc_ntp_correct_timestamps_desc', lang)}</div>
+                                            </div>
+                                            <ToggleSwitch checked={ntpConfig.correctTimestamps} onChange={() => setNtpConfig({...ntpConfig, correctTimestamps: !ntpConfig.correctTimestamps})} />
+                                        </div>
                                         <div className="flex gap-4">
                                             <ArkButton onClick={handleSave} className="flex-1 justify-center" loading={isSaving}>
                                                 <Save size={14} className="mr-2"/> {t('sc_btn_save', lang)}
@@ -1330,13 +1526,81 @@ export const SystemConfig: React.FC = () => {
                                             <>
                                                 <div className="w-full h-[1px] bg-ark-border my-2"/>
                                                 <div className="text-[10px] text-ark-subtext font-mono">
-                                                    {new Date(ntpStatus.lastSync).toLocaleString()}
+                                                    {formatDateTime(ntpStatus.lastSync)}
                                                 </div>
                                             </>
                                         )}
                                     </div>
                                 </div>
                             </ArkCard>
+
+                            <ArkCard title={t('sc_ntp_nodes_title', lang)} sub={t('sc_ntp_nodes_subtitle', lang)} contentClassName="p-0">
+                                <div className="overflow-x-auto">
+                                    <table className="w-full text-left text-sm whitespace-nowrap">
+                                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                                            <tr>
+                                                <th className="p-3 pl-4">{t('sc_ntp_node', lang)}</th>
+                                                <th className="p-3">{t('sc_ntp_node_offset', lang)}</th>
+                                                <th className="p-3">{t('sc_ntp_checked_at', lang)}</th>
+                                                <th className="p-3 text-right pr-4">{t('sc_ntp_status', lang)}</th>
+                                            </tr>
+                                        </thead>
+                                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                                            {nodeClocks.length > 0 ? nodeClocks.map(node => (
+                                                <tr key={node.id} className="hover:bg-ark-active/5 transition-colors">
+                                                    <td className="p-3 pl-4 font-bold text-ark-text">{node.name || node.id}</td>
+                                                    <td className="p-3 text-ark-text">{node.checkedAt ? `${node.offset > 0 ? '+' : ''}${node.offset}ms` : '-'}</td>
+                                                    <td className="p-3 text-ark-subtext">{formatDateTime(node.checkedAt)}</td>
+                                                    <td className="p-3 text-right pr-4">
+                                                        <span className={`px-2 py-0.5 rounded-sm uppercase text-[10px] font-bold border ${node.drift ? 'border-red-500/30 bg-red-500/10 text-red-500' : 'border-green-500/30 bg-green-500/10 text-green-500'}`}>
+                                                            {node.drift ? t('sc_ntp_drifting', lang) : t('sc_ntp_in_sync', lang)}
+                                                        </span>
+                                                    </td>
+                                                </tr>
+                                            )) : (
+                                                <tr>
+                                                    <td colSpan={4} className="p-8 text-center text-ark-subtext">{t('no_data', lang)}</td>
+                                                </tr>
+                                            )}
+                                        </tbody>
+                                    </table>
+                                </div>
+                            </ArkCard>
+
+                            <ArkCard title={t('sc_ntp_history_title', lang)} contentClassName="p-0">
+                                <div className="overflow-x-auto">
+                                    <table className="w-full text-left text-sm whitespace-nowrap">
+                                        <thead className="bg-ark-active/10 text-ark-subtext font-mono text-xs font-bold uppercase border-b border-ark-border">
+                                            <tr>
+                                                <th className="p-3 pl-4">{t('sc_login_log_time', lang)}</th>
+                                                <th className="p-3">{t('sc_ntp_offset', lang)}</th>
+                                                <th className="p-3">{t('sc_ntp_servers_used', lang)}</th>
+                                                <th className="p-3">{t('sc_ntp_trigger', lang)}</th>
+                                                <th className="p-3 text-right pr-4">{t('sc_ntp_status', lang)}</th>
+                                            </tr>
+                                        </thead>
+                                        <tbody className="divide-y divide-ark-border font-mono text-xs">
+                                            {ntpHistory.length > 0 ? ntpHistory.map(entry => (
+                                                <tr key={entry.id} className="hover:bg-ark-active/5 transition-colors" title={entry.error}>
+                                                    <td className="p-3 pl-4 text-ark-subtext">{formatDateTime(entry.time)}</td>
+                                                    <td className="p-3 text-ark-text">{entry.offset > 0 ? '+' : ''}{entry.offset.toFixed(4)}s</td>
+                                                    <td className="p-3 text-ark-text">{entry.used}/{entry.servers}</td>
+                                                    <td className="p-3 text-ark-subtext">{entry.trigger === 'manual' ? t('sc_ntp_trigger_manual', lang) : t('sc_ntp_trigger_schedule', lang)}</td>
+                                                    <td className="p-3 text-right pr-4">
+                                                        <span className={`px-2 py-0.5 rounded-sm uppercase text-[10px] font-bold border ${entry.status === 'synchronized' ? 'border-green-500/30 bg-green-500/10 text-green-500' : 'border-red-500/30 bg-red-500/10 text-red-500'}`}>
+                                                            {entry.status === 'synchronized' ? t('sc_ntp_state_synced', lang) : t('sc_ntp_state_error', lang)}
+                                                        </span>
+                                                    </td>
+                                                </tr>
+                                            )) : (
+                                                <tr>
+                                                    <td colSpan={5} className="p-8 text-center text-ark-subtext">{t('no_data', lang)}</td>
+                                                </tr>
+                                            )}
+                                        </tbody>
+                                    </table>
+                                </div>
+                            </ArkCard>
                         </div>
                     )}
 
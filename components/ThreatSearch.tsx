@@ -0,0 +1,181 @@
+import React, { useState } from 'react';
+import { ArkButton, ArkLoading } from './ArknightsUI';
+import { SearchHit } from '../types';
+import { Search, RefreshCw, X } from 'lucide-react';
+import { useApp } from '../AppContext';
+import { t } from '../i18n';
+import { useNotification } from './NotificationSystem';
+import { ArkDateRangePicker } from './ArkDateRangePicker';
+import { formatDateTime, rangeParams } from '../time';
+
+const PAGE_SIZE = 50;
+
+const KIND_STYLES: Record<SearchHit['kind'], string> = {
+    attack: 'border-red-500/50 text-red-500 bg-red-500/10',
+    credential: 'border-orange-500/50 text-orange-500 bg-orange-500/10',
+    sample: 'border-purple-500/50 text-purple-500 bg-purple-500/10',
+};
+
+const FilterGroup: React.FC<{ label: string, children: React.ReactNode, className?: string }> = ({ label, children, className = "" }) => (
+    <div className={`flex items-center border border-ark-border bg-ark-panel h-[32px] ${className}`}>
+        <div className="px-3 text-xs text-ark-subtext font-mono whitespace-nowrap border-r border-ark-border h-full flex items-center bg-ark-bg/30 min-w-fit">
+            {label}
+        </div>
+        <div className="flex-1 min-w-0 h-full flex items-center relative">
+            {children}
+        </div>
+    </div>
+);
+
+export const ThreatSearch: React.FC = () => {
+    const { lang, authFetch } = useApp();
+    const { notify } = useNotification();
+    const [query, setQuery] = useState('');
+    const [kind, setKind] = useState('');
+    const [ipFilter, setIpFilter] = useState('');
+    const [dateRange, setDateRange] = useState({ start: '', end: '' });
+    const [hits, setHits] = useState<SearchHit[]>([]);
+    const [total, setTotal] = useState(0);
+    const [engine, setEngine] = useState('');
+    const [searched, setSearched] = useState(false);
+    const [loading, setLoading] = useState(false);
+    const [loadingMore, setLoadingMore] = useState(false);
+
+    const runSearch = async (offset = 0) => {
+        if (!query.trim()) return;
+        const params = rangeParams(dateRange);
+        params.set('q', query.trim());
+        params.set('limit', String(PAGE_SIZE));
+        if (offset) params.set('offset', String(offset));
+        if (kind) params.set('kind', kind);
+        if (ipFilter.trim()) params.set('ip', ipFilter.trim());
+
+        offset ? setLoadingMore(true) : setLoading(true);
+        try {
+            const response = await authFetch(`/api/v1/search?${params.toString()}`);
+            const data = await response.json();
+            if (!response.ok) {
+                notify('warning', t('op_failed', lang), data.error);
+                return;
+            }
+            setHits(prev => offset ? [...prev, ...data] : data);
+            setTotal(parseInt(response.headers.get('X-Total-Count') || '0'));
+            setEngine(response.headers.get('X-Search-Engine') || '');
+            setSearched(true);
+        } catch (error) {
+            console.error("Failed to search", error);
+        } finally {
+            setLoading(false);
+            setLoadingMore(false);
+        }
+    };
+
+    const clearFilters = () => {
+        setQuery('');
+        setKind('');
+        setIpFilter('');
+        setDateRange({ start: '', end: '' });
+        setHits([]);
+        setTotal(0);
+        setSearched(false);
+    };
+
+    return (
+        <div className="flex flex-col gap-4 pb-20 md:pb-6 min-h-full">
+            {/* Query */}
+            <div className="bg-ark-panel border border-ark-border p-4 shadow-sm relative">
+                <div className="absolute top-0 left-0 w-1 h-full bg-ark-primary" />
+                <div className="flex items-center gap-3 mb-4">
+                    <div className="p-2 bg-ark-active/20 rounded-sm text-ark-primary border border-ark-primary/20 shrink-0">
+                        <Search size={20} />
+                    </div>
+                    <h2 className="text-lg md:text-xl font-bold text-ark-text">{t('ts_title', lang)}</h2>
+                </div>
+                <div className="flex items-center border border-ark-border bg-ark-bg h-[40px] mb-2">
+                    <Search size={16} className="mx-3 text-ark-subtext shrink-0" />
+                    <input
+                        type="text"
+                        value={query}
+                        onChange={e => setQuery(e.target.value)}
+                        onKeyDown={e => e.key === 'Enter' && runSearch()}
+                        placeholder={t('ts_placeholder', lang)}
+                        className="w-full h-full bg-transparent outline-none text-sm font-mono text-ark-text placeholder-ark-subtext/50"
+                    />
+                </div>
+                <p className="text-[10px] text-ark-subtext font-mono mb-4">{t('ts_syntax', lang)}</p>
+
+                <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-3 gap-3">
+                    <ArkDateRangePicker value={dateRange} onChange={setDateRange} className="w-full" />
+                    <FilterGroup label={t('ts_kind', lang)}>
+                        <select value={kind} onChange={e => setKind(e.target.value)} className="w-full h-full px-2 bg-transparent outline-none text-xs text-ark-text cursor-pointer">
+                            <option value="">{t('filter_all', lang)}</option>
+                            <option value="attack">{t('ts_kind_attack', lang)}</option>
+                            <option value="credential">{t('ts_kind_credential', lang)}</option>
+                            <option value="sample">{t('ts_kind_sample', lang)}</option>
+                        </select>
+                    </FilterGroup>
+                    <FilterGroup label={t('header_attacker_ip', lang)}>
+                        <input
+                            type="text"
+                            value={ipFilter}
+                            onChange={e => setIpFilter(e.target.value)}
+                            onKeyDown={e => e.key === 'Enter' && runSearch()}
+                            placeholder={t('filter_placeholder_ip_range', lang)}
+                            className="w-full h-full px-2 bg-transparent outline-none text-xs text-ark-text placeholder-ark-subtext/50"
+                        />
+                    </FilterGroup>
+                </div>
+
+                <div className="flex justify-end mt-4 pt-3 border-t border-ark-border border-dashed gap-2">
+                    <ArkButton variant="ghost" size="sm" className="gap-2" onClick={clearFilters}>
+                        <X size={14} /> {t('filter_reset', lang)}
+                    </ArkButton>
+                    <ArkButton variant="primary" size="sm" className="gap-2" onClick={() => runSearch()} disabled={loading || !query.trim()}>
+                        <RefreshCw size={14} className={loading ? 'animate-spin' : ''} /> {t('ts_search', lang)}
+                    </ArkButton>
+                </div>
+            </div>
+
+            {/* Results */}
+            <div className="flex-1 flex flex-col min-h-[400px] bg-ark-panel border border-ark-border overflow-hidden shadow-sm">
+                {loading ? (
+                    <ArkLoading label="SEARCHING_INDEX" />
+                ) : (
+                    <div className="flex-1 overflow-auto custom-scrollbar divide-y divide-ark-border">
+                        {searched && hits.length === 0 && (
+                            <div className="p-8 text-center text-xs text-ark-subtext font-mono">{t('ts_no_results', lang)}</div>
+                        )}
+                        {hits.map(hit => (
+                            <div key={`${hit.kind}-${hit.refId}`} className="p-4 hover:bg-ark-active/5 transition-colors">
+                                <div className="flex flex-wrap items-center gap-3 mb-2 text-xs font-mono">
+                                    <span className={`px-2 py-0.5 border text-[10px] rounded-sm uppercase font-bold ${KIND_STYLES[hit.kind]}`}>
+                                        {t(`ts_kind_${hit.kind}`, lang)}
+                                    </span>
+                                    <span className="font-bold text-ark-text">{hit.ip || '-'}</span>
+                                    <span className="text-ark-subtext">{formatDateTime(hit.time)}</span>
+                                    <span className="text-ark-subtext/70 ml-auto">{hit.refId}</span>
+                                </div>
+                                {/* The server escapes the text, only <mark> is markup */}
+                                <div
+                                    className="font-mono text-xs text-ark-text break-all bg-ark-bg/50 border border-ark-border/50 p-2 [&_mark]:bg-ark-primary/30 [&_mark]:text-ark-text"
+                                    dangerouslySetInnerHTML={{ __html: hit.snippet }}
+                                />
+                            </div>
+                        ))}
+                    </div>
+                )}
+                <div className="p-3 border-t border-ark-border bg-ark-bg flex justify-between items-center text-xs text-ark-subtext font-mono">
+                    <span>{engine && `${t('ts_engine', lang)} ${engine.toUpperCase()}`}</span>
+                    <div className="flex items-center gap-4">
+                        <span>{t('al_total', lang)} {total}</span>
+                        {hits.length < total && (
+                            <ArkButton variant="outline" size="sm" onClick={() => runSearch(hits.length)} disabled={loadingMore}>
+                                {loadingMore ? <RefreshCw size={12} className="animate-spin" /> : t('al_load_more', lang)}
+                            </ArkButton>
+                        )}
+                    </div>
+                </div>
+            </div>
+        </div>
+    );
+};
@@ -21,6 +21,7 @@ import {
     RefreshCw
 } from 'lucide-react';
 import { useNotification } from './NotificationSystem';
+import { formatDateTime } from '../time';
 
 // Reusing Filter Components
 const FilterInput: React.FC<{ label?: string, placeholder?: string, width?: string, children?: React.ReactNode }> = ({ label, placeholder, width = "w-full", children }) => (
@@ -332,7 +333,7 @@ export const VulnSimulation: React.FC = () => {
                                                         {rule.hitCount}
                                                     </span>
                                                 </td>
-                                                <td className="p-4 text-ark-subtext">{rule.lastHitTime}</td>
+                                                <td className="p-4 text-ark-subtext">{formatDateTime(rule.lastHitTime)}</td>
                                                 <td className="p-4 text-ark-text">{rule.creator}</td>
                                                 <td className="p-4">
                                                     <ToggleSwitch 
@@ -341,7 +342,7 @@ export const VulnSimulation: React.FC = () => {
                                                         loading={pendingRules.has(rule.id)}
                                                     />
                                                 </td>
-                                                <td className="p-4 text-ark-subtext">{rule.updateTime}</td>
+                                                <td className="p-4 text-ark-subtext">{formatDateTime(rule.updateTime)}</td>
                                                 <td className="p-4 text-ark-text">{rule.updater}</td>
                                                 <td className="p-4">
                                                     <div className="flex items-center justify-center gap-3">
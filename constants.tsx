@@ -29,6 +29,7 @@ export const NAVIGATION: NavItem[] = [
       { id: 'attack-list', labelEn: 'Attack List', labelZh: '攻击列表', path: '/threat-perception/list' },
       { id: 'scanning', labelEn: 'Scanning Perception', labelZh: '扫描感知', path: '/threat-perception/scanning' },
       { id: 'compromise', labelEn: 'Compromise Perception', labelZh: '失陷感知', path: '/threat-perception/compromise' },
+      { id: 'threat-search', labelEn: 'Threat Search', labelZh: '威胁检索', path: '/threat-perception/search' },
     ]
   },
   {
@@ -78,6 +79,9 @@ export const NAVIGATION: NavItem[] = [
     children: [
       { id: 'messages', labelEn: 'Message Center', labelZh: '消息中心', path: '/messages' },
       { id: 'config', labelEn: 'System Config', labelZh: '系统配置', path: '/system/config' },
+      { id: 'notifications', labelEn: 'Alert Notify', labelZh: '告警通知', path: '/system/notifications' },
+      { id: 'siem', labelEn: 'SIEM Export', labelZh: 'SIEM 转发', path: '/system/siem' },
+      { id: 'ingest', labelEn: 'Sensor Ingest', labelZh: '传感器接入', path: '/system/ingest' },
       { id: 'info', labelEn: 'System Info', labelZh: '系统信息', path: '/system/info' },
       { id: 'reports', labelEn: 'Report Mgmt', labelZh: '报表管理', path: '/system/reports' },
     ]
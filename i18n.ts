@@ -674,7 +674,7 @@ const resources: Record<Lang, Record<string, string>> = {
     sc_custom_upload: "Upload Logo",
     sc_ntp_title: "NTP",
     sc_ntp_subtitle: "Network Time Protocol settings.",
-    sc_ntp_server: "NTP Server",
+    sc_ntp_server: "NTP Servers (comma-separated)",
     sc_ntp_interval: "Interval (Min)",
     sc_ntp_sync_now: "Sync Now",
     sc_ntp_status: "Status",
@@ -744,6 +744,385 @@ const resources: Record<Lang, Record<string, string>> = {
     msg_node_online_content: "Probe node [{name}] ({id}) has successfully connected to the Neural Hub.",
     msg_node_offline_title: "Node Offline",
     msg_node_offline_content: "Probe node [{name}] ({id}) has disconnected from the Neural Hub.",
+    msg_decoy_compromised_title: "Decoy Compromised",
+    msg_decoy_compromised_content: "Decoy [{name}] on {node} was touched by {process} from {ip}.",
+    msg_leaked_credential_title: "Real Account Credential Tried",
+    msg_leaked_credential_username: "Inventory account [{account}] was tried as {user} against {service} from {ip}.",
+    msg_leaked_credential_password: "The real password of [{account}] was used against {service} from {ip}.",
+    msg_leaked_credential_reused_password: "The real password of [{account}] was tried under the name {user} against {service} from {ip}.",
+    msg_blocklist_failed_title: "Blocklist Refresh Failed",
+    msg_blocklist_failed: "Blocklist subscription [{name}] could not be refreshed: {error}. Existing rules are kept until their TTL runs out.",
+    sc_ntp_drift_threshold: "Probe Drift Threshold (ms)",
+    sc_ntp_correct_timestamps: "Correct Probe Timestamps",
+    sc_ntp_correct_timestamps_desc: "Shift event times sent by probes by the measured offset of their clock.",
+    sc_ntp_nodes_title: "Probe Clocks",
+    sc_ntp_nodes_subtitle: "Offset of each probe clock, measured from its status reports.",
+    sc_ntp_node: "Node",
+    sc_ntp_node_offset: "Clock Offset",
+    sc_ntp_checked_at: "Checked At",
+    sc_ntp_drifting: "DRIFTING",
+    sc_ntp_in_sync: "IN SYNC",
+    sc_ntp_history_title: "Sync History",
+    sc_ntp_servers_used: "Servers Used",
+    sc_ntp_trigger: "Trigger",
+    sc_ntp_trigger_manual: "Manual",
+    sc_ntp_trigger_schedule: "Scheduled",
+    msg_ntp_failed_title: "NTP Sync Failed",
+    msg_ntp_failed_content: "None of the NTP servers ({servers}) answered. The last offset of {offset}s stays in effect.",
+    msg_node_clock_drift_title: "Probe Clock Drift",
+    msg_node_clock_drift_content: "The clock of probe node [{name}] ({id}) is off by {offset}ms.",
+    msg_node_clock_synced_title: "Probe Clock Back In Sync",
+    msg_node_clock_synced_content: "The clock of probe node [{name}] ({id}) is back in sync ({offset}ms).",
+    filter_placeholder_ip_range: "IP, CIDR or range",
+    al_total: "Total",
+    al_load_more: "Load more",
+    ts_title: "Threat Search",
+    ts_placeholder: "e.g. command:wget \"chmod +x\" NOT busybox",
+    ts_syntax: "Words must all match. \"phrase\", prefix*, OR, NOT or -word, (groups). Fields: payload: command: body: user: password: file:",
+    ts_kind: "Type",
+    ts_kind_attack: "Attack",
+    ts_kind_credential: "Credential",
+    ts_kind_sample: "Sample",
+    ts_search: "Search",
+    ts_no_results: "No records match the query",
+    ts_engine: "Index:",
+    dash_trend_other: "Other",
+    sc_metrics_title: "Prometheus Metrics",
+    sc_metrics_subtitle: "Scrape endpoint for Prometheus and Grafana, authenticated separately from console logins",
+    sc_metrics_enable: "Enable /metrics endpoint",
+    sc_metrics_auth: "Authentication",
+    sc_metrics_auth_bearer: "Bearer token",
+    sc_metrics_auth_basic: "Basic auth",
+    sc_metrics_auth_none: "None (allowlist only)",
+    sc_metrics_token: "Scrape token",
+    sc_metrics_basic: "Credentials",
+    sc_metrics_username: "Username",
+    sc_metrics_password: "Password",
+    sc_metrics_secret_kept: "Stored, leave empty to keep",
+    sc_metrics_allowed_nets: "Allowed addresses",
+    sc_metrics_allowed_nets_hint: "Comma-separated addresses or CIDR blocks, empty allows any address",
+    nc_title: "Alert Notifications",
+    nc_desc: "Alerts are pushed to webhooks, email, Slack, DingTalk, Feishu and WeCom besides the message center.",
+    nc_desc_rules: "Routing rules pick events by kind or type and a minimum severity, each channel gets an event once.",
+    nc_desc_retry: "Failed sends are retried with growing waits up to the channel's attempt limit, every send is logged.",
+    nc_channels: "Channels",
+    nc_rules: "Routing Rules",
+    nc_deliveries: "Delivery Log",
+    nc_add_channel: "Add Channel",
+    nc_edit_channel: "Edit Channel",
+    nc_add_rule: "Add Rule",
+    nc_edit_rule: "Edit Rule",
+    nc_no_channels: "No channels yet",
+    nc_no_rules: "No rules yet, nothing is sent",
+    nc_no_deliveries: "No deliveries",
+    nc_col_name: "Name",
+    nc_col_type: "Type",
+    nc_col_target: "Target",
+    nc_col_lang: "Language",
+    nc_col_status: "Status",
+    nc_col_op: "Actions",
+    nc_col_min_severity: "Min Severity",
+    nc_col_events: "Events",
+    nc_col_channels: "Channels",
+    nc_col_time: "Time",
+    nc_col_event: "Event",
+    nc_col_channel: "Channel",
+    nc_col_title: "Title",
+    nc_col_attempts: "Attempts",
+    nc_enabled: "Enabled",
+    nc_disabled: "Disabled",
+    nc_all_events: "All events",
+    nc_test: "Test",
+    nc_edit: "Edit",
+    nc_delete: "Delete",
+    nc_retry: "Retry",
+    nc_test_sent: "Test notification sent",
+    nc_test_failed: "Test notification failed",
+    nc_channel_saved: "Channel saved",
+    nc_rule_saved: "Rule saved",
+    nc_retry_queued: "Delivery queued again",
+    nc_confirm_delete_channel: "Delete channel {name}? It is also removed from every rule.",
+    nc_confirm_delete_rule: "Delete rule {name}?",
+    nc_type_webhook: "Webhook",
+    nc_type_email: "Email",
+    nc_type_slack: "Slack",
+    nc_type_dingtalk: "DingTalk",
+    nc_type_feishu: "Feishu / Lark",
+    nc_type_wecom: "WeCom",
+    nc_type_group_system: "All system messages",
+    nc_type_group_security: "All security messages",
+    nc_event_attack: "Critical attack",
+    nc_event_node_offline: "Node offline",
+    nc_event_node_online: "Node online",
+    nc_event_node_clock_drift: "Probe clock drift",
+    nc_event_node_clock_synced: "Probe clock synced",
+    nc_event_decoy_compromised: "Decoy compromised",
+    nc_event_leaked_credential: "Real credential tried",
+    nc_event_blocklist_failed: "Blocklist refresh failed",
+    nc_event_ntp_failed: "NTP sync failed",
+    nc_events_hint: "Nothing selected matches every event",
+    nc_sev_info: "Info",
+    nc_sev_low: "Low",
+    nc_sev_medium: "Medium",
+    nc_sev_warning: "Warning",
+    nc_sev_high: "High",
+    nc_sev_critical: "Critical",
+    nc_status_pending: "Pending",
+    nc_status_sent: "Sent",
+    nc_status_failed: "Failed",
+    nc_url: "URL",
+    nc_url_hint_webhook: "Receives the event as JSON",
+    nc_url_hint_slack: "Slack incoming webhook URL",
+    nc_url_hint_dingtalk: "Robot webhook URL with access_token",
+    nc_url_hint_feishu: "Custom bot webhook URL",
+    nc_url_hint_wecom: "Group robot webhook URL with key",
+    nc_secret: "Signing Secret",
+    nc_secret_hint_webhook: "Signs requests in X-PRTS-Signature with HMAC-SHA256",
+    nc_secret_hint_dingtalk: "Secret of the robot's signing security setting",
+    nc_secret_hint_feishu: "Secret of the bot's signature verification",
+    nc_secret_hint_wecom: "Not used by WeCom robots",
+    nc_secret_kept: "Leave empty to keep the stored value",
+    nc_smtp_host: "SMTP Host",
+    nc_smtp_port: "Port",
+    nc_smtp_port_hint: "Empty for the default of the TLS mode",
+    nc_smtp_tls: "Encryption",
+    nc_smtp_tls_none: "None",
+    nc_smtp_from: "From",
+    nc_smtp_to: "To",
+    nc_smtp_to_hint: "Comma-separated addresses",
+    nc_smtp_username: "Username",
+    nc_smtp_password: "Password",
+    nc_max_attempts: "Max Attempts",
+    nc_title_template: "Title Template",
+    nc_body_template: "Body Template",
+    nc_template_hint: "Go templates with .Title .Text .Event .Severity .Time .Params, empty for the default",
+    nc_insecure: "Accept self-signed certificates",
+    msg_attack_title: "Critical Attack",
+    msg_attack_content: "{method} attack from {ip} on {node}, {status}: {payload}",
+    msg_attack_group_content: "{count} critical {method} attacks from {sources} addresses.",
+    msg_alert_rate_limited_title: "Alerts Rate Limited",
+    msg_alert_rate_limited_content: "{count} alerts of {sources} kinds were folded here because the message center hit its rate limit.",
+    mc_status_firing: "Firing",
+    mc_status_acknowledged: "Acknowledged",
+    mc_status_resolved: "Resolved",
+    mc_last_seen: "Last",
+    mc_acknowledge: "Acknowledge",
+    mc_resolve: "Resolve",
+    mc_assign: "Assign",
+    mc_assignee: "Username",
+    mc_assign_hint: "Leave empty to unassign.",
+    nc_event_alert_rate_limited: "Alerts rate limited",
+    nc_alert_folding: "Folding & Rate Limit",
+    nc_alert_config_saved: "Alert settings saved",
+    nc_dedupe_window: "Dedupe Window (min)",
+    nc_dedupe_window_hint: "Identical alerts inside it raise the count, 0 turns it off",
+    nc_group_window: "Group Window (min)",
+    nc_group_window_hint: "Related alerts such as attacks of one method become one summary",
+    nc_rate_limit: "Rate Limit (per min)",
+    nc_rate_limit_hint: "New messages per minute, the rest folds into one, 0 for no limit",
+    nc_silences: "Maintenance Silences",
+    nc_add_silence: "Add Silence",
+    nc_no_silences: "No silences",
+    nc_col_node: "Node",
+    nc_col_window: "Window",
+    nc_col_reason: "Reason",
+    nc_col_suppressed: "Suppressed",
+    nc_all_nodes: "All nodes",
+    nc_silence_active: "Active",
+    nc_silence_scheduled: "Scheduled",
+    nc_silence_ended: "Ended",
+    nc_end_silence: "End",
+    nc_silence_start: "Starts",
+    nc_silence_end: "Ends",
+    mc_subscription: "Subscription",
+    mc_subscription_hint: "Pick the message types and the lowest severity you see in your inbox and get pushed. With no type selected you get all of them.",
+    mc_subscription_types: "Message types",
+    mc_subscription_min_severity: "Lowest severity",
+    mc_subscription_saved: "Subscription saved.",
+    mc_dismiss: "Dismiss",
+    mc_dismissed: "Message removed from your inbox.",
+    mc_dismiss_failed: "Failed to dismiss message",
+    siem_title: "SIEM Export",
+    siem_desc: "Streams attacks, scans, captured credentials, decoy events, samples and console logins to SIEM collectors over syslog.",
+    siem_desc_formats: "Each destination picks UDP, TCP or TLS and CEF, LEEF or RFC 5424 with a JSON body, filtered by event kind and severity.",
+    siem_desc_queue: "Events queue on disk while a collector is unreachable and are sent in order once it is back; past the queue limit the oldest are dropped.",
+    siem_destinations: "Syslog Destinations",
+    siem_add: "Add Destination",
+    siem_edit: "Edit Destination",
+    siem_none: "No destinations configured",
+    siem_saved: "Destination saved",
+    siem_confirm_delete: "Delete destination {name}? Events still queued for it are discarded.",
+    siem_test_sent: "Sent the latest {kinds}",
+    siem_test_empty: "Connected, but there are no events to send yet",
+    siem_col_target: "Target",
+    siem_col_format: "Format",
+    siem_col_filter: "Filter",
+    siem_col_queue: "Queue",
+    siem_col_delivery: "Delivery",
+    siem_connected: "Connected",
+    siem_disconnected: "Disconnected",
+    siem_dropped: "{n} dropped",
+    siem_sent_count: "{sent} sent / {failures} failed",
+    siem_all_kinds: "All kinds",
+    siem_protocol: "Protocol",
+    siem_host: "Host",
+    siem_port: "Port",
+    siem_format_json: "RFC 5424 + JSON",
+    siem_framing: "Framing",
+    siem_framing_hint: "TCP and TLS only. Default is newline for TCP and octet counting for TLS.",
+    siem_framing_default: "Default",
+    siem_framing_octet: "Octet counting",
+    siem_framing_newline: "Newline",
+    siem_facility: "Facility",
+    siem_facility_hint: "0-23, 16 is local0",
+    siem_max_queue: "Queue Limit (MB)",
+    siem_max_queue_hint: "Disk space for events waiting to be sent",
+    siem_kinds: "Event Kinds",
+    siem_kinds_hint: "Leave empty to forward every kind",
+    siem_ca_cert: "CA Certificate",
+    siem_ca_cert_hint: "PEM, trusted in addition to the system roots",
+    siem_kind_attack: "attacks",
+    siem_kind_scan: "scans",
+    siem_kind_credential: "credentials",
+    siem_kind_decoy: "decoy events",
+    siem_kind_sample: "samples",
+    siem_kind_login: "console logins",
+    siem_desc_opensearch: "OpenSearch and Elasticsearch outputs bulk index the same events with ECS fields into one index per kind and day.",
+    siem_os_outputs: "OpenSearch / Elasticsearch",
+    siem_os_add: "Add Output",
+    siem_os_edit: "Edit Output",
+    siem_os_none: "No outputs configured",
+    siem_os_saved: "Output saved",
+    siem_os_confirm_delete: "Delete output {name}? Documents still queued for it are discarded, indexed ones stay in the cluster.",
+    siem_os_test_ok: "Reached {distribution} {version} cluster {cluster}",
+    siem_os_col_cluster: "Cluster / Index",
+    siem_os_indexed_count: "{indexed} indexed / {rejected} rejected",
+    siem_os_retried: "{n} retried after throttling",
+    siem_os_backfill_hint: "History from before an output was added can be indexed with",
+    siem_os_url: "Cluster URL",
+    siem_os_prefix: "Index Prefix",
+    siem_os_prefix_hint: "Indexes are named prefix-kind-yyyy.mm.dd",
+    siem_os_api_key: "API Key",
+    siem_os_api_key_hint: "Base64 id:key, used instead of the user and password",
+    siem_os_batch_size: "Batch Size",
+    siem_os_flush: "Flush Interval (s)",
+    siem_os_flush_hint: "A smaller batch is sent once its oldest document waited this long",
+    siem_os_manage_template: "Install the index template",
+    siem_kind_node: "node status changes",
+    siem_desc_eventbus: "Kafka and NATS outputs publish the events and node status changes as versioned JSON or Protobuf, keyed by source IP, with the node and event type in the headers.",
+    siem_bus_outputs: "Event Bus (Kafka / NATS)",
+    siem_bus_add: "Add Event Bus Output",
+    siem_bus_edit: "Edit Event Bus Output",
+    siem_bus_none: "No Kafka or NATS outputs",
+    siem_bus_saved: "Event bus output saved",
+    siem_bus_confirm_delete: "Delete event bus output {name}? Messages still queued are discarded.",
+    siem_bus_col_target: "Broker / Topic",
+    siem_bus_published_count: "{published} published / {rejected} refused",
+    siem_bus_type: "Bus",
+    siem_bus_servers: "Servers",
+    siem_bus_servers_hint_kafka: "Comma-separated bootstrap brokers as host:port",
+    siem_bus_servers_hint_nats: "Comma-separated nats:// or tls:// URLs",
+    siem_bus_topic: "Topic",
+    siem_bus_subject: "Subject",
+    siem_bus_topic_hint: "{type} is replaced by the event type",
+    siem_bus_encoding: "Encoding",
+    siem_bus_encoding_json: "JSON (prts.event.v1)",
+    siem_bus_encoding_protobuf: "Protobuf (event.proto)",
+    siem_bus_sasl_hint: "SASL PLAIN, empty for none",
+    siem_bus_token: "Auth Token",
+    siem_bus_tls: "Connect over TLS",
+    siem_bus_jetstream: "Wait for JetStream to store every message",
+    siem_bus_core_hint: "Without JetStream, messages with no subscriber are lost",
+    ingest_title: "Sensor Ingest",
+    ingest_desc: "Third-party sensors feed their native logs in through adapters that map each event onto attack, credential, scan and sample records.",
+    ingest_desc_http: "Sensors can POST JSON lines, a JSON array or Zeek TSV logs to the adapter's endpoint with an ingest key; the key names the sensor, and ?sensor= must match it if given.",
+    ingest_desc_tail: "Tails follow log files on this server through rotation and truncation, and resume from the saved offset after a restart.",
+    ingest_desc_custom: "Built-in adapters cover Cowrie, Suricata EVE, Zeek and OpenCanary. Custom adapters are JSON specs, and saving one under a built-in name overrides it.",
+    ingest_adapters: "Adapters",
+    ingest_adapter_add: "New Adapter",
+    ingest_adapter_edit: "Edit Adapter",
+    ingest_adapter_saved: "Adapter saved",
+    ingest_adapter_confirm_delete: "Delete adapter {name}? Tails using it stop reading.",
+    ingest_adapter_confirm_restore: "Remove the override of {name} and restore the built-in adapter?",
+    ingest_builtin: "Built-in",
+    ingest_custom: "Custom",
+    ingest_overrides: "Overrides built-in",
+    ingest_customize: "Customize",
+    ingest_restore: "Restore built-in",
+    ingest_col_description: "Description",
+    ingest_col_rules: "Rules",
+    ingest_col_endpoint: "Endpoint",
+    ingest_spec: "Spec (JSON)",
+    ingest_spec_hint: "Each rule matches events with when/unless regexps on field paths and fills the fields of one record from {field,alt,'literal'|filter} templates.",
+    ingest_spec_invalid: "The spec is not valid JSON: {error}",
+    ingest_sample: "Sample log lines",
+    ingest_sample_hint: "Paste a few lines of the sensor's log to see the records they map to. Nothing is stored.",
+    ingest_log_path: "Zeek log path",
+    ingest_preview: "Preview",
+    ingest_preview_empty: "No events in the sample.",
+    ingest_line: "Line {n}",
+    ingest_no_rule: "No rule applies, the event is skipped.",
+    ingest_tails: "File Tails",
+    ingest_tails_none: "No log files are followed.",
+    ingest_tail_add: "Follow File",
+    ingest_tail_edit: "Edit File Tail",
+    ingest_tail_saved: "File tail saved",
+    ingest_tail_confirm_delete: "Stop following {path}?",
+    ingest_col_path: "Log file",
+    ingest_col_adapter: "Adapter",
+    ingest_col_progress: "Progress",
+    ingest_col_last_read: "Last read",
+    ingest_progress: "{events} events / {records} records / {failed} failed",
+    ingest_offset: "Offset {n} B",
+    ingest_following: "Following",
+    ingest_stalled: "Stalled",
+    ingest_sensor: "Sensor",
+    ingest_sensor_hint: "Tags the records when the log does not name its sensor.",
+    ingest_path_hint: "Absolute path on this server. For Zeek, the file name names the log, such as conn.log.",
+    ingest_from_start: "Read the existing content from the start",
+    ingest_desc_keys: "Every ingest request needs a key bound to one sensor; keys carry their own rate limit and can require HMAC-signed requests. Rejected events are kept below for 30 days.",
+    ingest_keys: "Ingest Keys",
+    ingest_keys_none: "No ingest keys. Sensors cannot send events until one is created.",
+    ingest_key_add: "New Key",
+    ingest_key_edit: "Edit Key",
+    ingest_key_saved: "Ingest key saved.",
+    ingest_key_rotate: "Rotate secret",
+    ingest_key_confirm_rotate: "Rotate the secret of [{name}]? The current key stops working immediately.",
+    ingest_key_confirm_delete: "Delete ingest key [{name}]? Sensors using it will be refused.",
+    ingest_key_copied: "Key copied to clipboard.",
+    ingest_key_copy_failed: "Could not copy the key, select it and copy manually.",
+    ingest_key_expired: "Expired",
+    ingest_key_expires: "until {time}",
+    ingest_key_expiry: "Expires",
+    ingest_key_expiry_hint: "Leave empty for a key that does not expire.",
+    ingest_key_sensor_hint: "Events sent with this key are recorded under this sensor only.",
+    ingest_key_issued: "Key Issued",
+    ingest_key_issued_hint: "This is the only time the key for [{name}] is shown. Store it on the sensor now.",
+    ingest_key_done: "I have stored it",
+    ingest_key_bearer_hint: "Send it as a bearer token:",
+    ingest_key_sign_hint: "Or keep it on the sensor and sign each request (required when signatures are enforced):",
+    ingest_rate_limit: "Rate Limit (events/min)",
+    ingest_rate_limit_hint: "Events over the limit are refused with 429.",
+    ingest_burst: "Burst",
+    ingest_burst_hint: "Largest batch accepted at once; 0 uses the rate limit.",
+    ingest_require_signature: "Require HMAC-signed requests",
+    ingest_signed_only: "signed only",
+    ingest_rate: "{rate}/min, burst {burst}",
+    ingest_usage: "{accepted} accepted, {rejected} rejected",
+    ingest_throttled: "{n} throttled",
+    ingest_col_rate: "Limits",
+    ingest_col_usage: "Usage",
+    ingest_col_last_used: "Last Used",
+    ingest_col_time: "Time",
+    ingest_col_error: "Error",
+    ingest_col_payload: "Payload",
+    ingest_rejections: "Rejected Events",
+    ingest_rejections_none: "No rejected events.",
+    ingest_rejections_clear: "Clear",
+    ingest_rejections_confirm_clear: "Delete the rejected events shown here?",
+    ingest_rejections_more: "Showing the latest {shown} of {total}.",
+    ingest_all_keys: "All keys",
+    ingest_event_index: "event #{n}",
   },
   zh: {
     // Defense Level
@@ -1417,7 +1796,7 @@ const resources: Record<Lang, Record<string, string>> = {
     sc_custom_upload: "上传 Logo",
     sc_ntp_title: "NTP",
     sc_ntp_subtitle: "网络时间协议设置。",
-    sc_ntp_server: "NTP 服务器",
+    sc_ntp_server: "NTP 服务器 (逗号分隔)",
     sc_ntp_interval: "间隔 (分钟)",
     sc_ntp_sync_now: "立即同步",
     sc_ntp_status: "状态",
@@ -1487,6 +1866,385 @@ const resources: Record<Lang, Record<string, string>> = {
     msg_node_online_content: "探针节点 [{name}] ({id}) 已成功连接至神经枢纽。",
     msg_node_offline_title: "节点离线",
     msg_node_offline_content: "探针节点 [{name}] ({id}) 与神经枢纽的连接已断开。",
+    msg_decoy_compromised_title: "诱饵被触发",
+    msg_decoy_compromised_content: "节点 {node} 上的诱饵 [{name}] 被 {process} 触碰，来源 {ip}。",
+    msg_leaked_credential_title: "真实账号凭据被尝试",
+    msg_leaked_credential_username: "清单账号 [{account}] 被以 {user} 名义在 {service} 上尝试，来源 {ip}。",
+    msg_leaked_credential_password: "账号 [{account}] 的真实密码被用于 {service}，来源 {ip}。",
+    msg_leaked_credential_reused_password: "账号 [{account}] 的真实密码以用户名 {user} 在 {service} 上被尝试，来源 {ip}。",
+    msg_blocklist_failed_title: "黑名单订阅刷新失败",
+    msg_blocklist_failed: "黑名单订阅 [{name}] 刷新失败：{error}。现有规则将保留至其有效期结束。",
+    sc_ntp_drift_threshold: "探针时钟偏差阈值 (毫秒)",
+    sc_ntp_correct_timestamps: "校正探针时间戳",
+    sc_ntp_correct_timestamps_desc: "按测得的时钟偏差校正探针上报的事件时间。",
+    sc_ntp_nodes_title: "探针时钟",
+    sc_ntp_nodes_subtitle: "根据状态上报测得的各探针时钟偏差。",
+    sc_ntp_node: "节点",
+    sc_ntp_node_offset: "时钟偏差",
+    sc_ntp_checked_at: "检测时间",
+    sc_ntp_drifting: "偏差过大",
+    sc_ntp_in_sync: "同步正常",
+    sc_ntp_history_title: "同步历史",
+    sc_ntp_servers_used: "采用服务器",
+    sc_ntp_trigger: "触发方式",
+    sc_ntp_trigger_manual: "手动",
+    sc_ntp_trigger_schedule: "定时",
+    msg_ntp_failed_title: "NTP 同步失败",
+    msg_ntp_failed_content: "NTP 服务器 ({servers}) 均无响应，继续沿用上次的偏移量 {offset}s。",
+    msg_node_clock_drift_title: "探针时钟偏差",
+    msg_node_clock_drift_content: "探针节点 [{name}] ({id}) 的时钟偏差为 {offset}ms。",
+    msg_node_clock_synced_title: "探针时钟已恢复",
+    msg_node_clock_synced_content: "探针节点 [{name}] ({id}) 的时钟已恢复同步 ({offset}ms)。",
+    filter_placeholder_ip_range: "IP、CIDR 或地址段",
+    al_total: "总数",
+    al_load_more: "加载更多",
+    ts_title: "威胁检索",
+    ts_placeholder: "例如 command:wget \"chmod +x\" NOT busybox",
+    ts_syntax: "所有词均需匹配。支持 \"短语\"、前缀*、OR、NOT 或 -词、(分组)。字段：payload: command: body: user: password: file:",
+    ts_kind: "类型",
+    ts_kind_attack: "攻击",
+    ts_kind_credential: "凭据",
+    ts_kind_sample: "样本",
+    ts_search: "检索",
+    ts_no_results: "没有匹配的记录",
+    ts_engine: "索引：",
+    dash_trend_other: "其他",
+    sc_metrics_title: "Prometheus 指标",
+    sc_metrics_subtitle: "供 Prometheus 与 Grafana 抓取的端点，认证独立于控制台登录",
+    sc_metrics_enable: "启用 /metrics 端点",
+    sc_metrics_auth: "认证方式",
+    sc_metrics_auth_bearer: "Bearer 令牌",
+    sc_metrics_auth_basic: "Basic 认证",
+    sc_metrics_auth_none: "无（仅地址白名单）",
+    sc_metrics_token: "抓取令牌",
+    sc_metrics_basic: "凭据",
+    sc_metrics_username: "用户名",
+    sc_metrics_password: "密码",
+    sc_metrics_secret_kept: "已保存，留空则保持不变",
+    sc_metrics_allowed_nets: "允许的地址",
+    sc_metrics_allowed_nets_hint: "逗号分隔的地址或 CIDR 网段，留空则不限制",
+    nc_title: "告警通知",
+    nc_desc: "告警除进入消息中心外，还可推送至 Webhook、邮件、Slack、钉钉、飞书和企业微信。",
+    nc_desc_rules: "路由规则按事件种类或类型及最低级别匹配，每个渠道对同一事件只接收一次。",
+    nc_desc_retry: "发送失败会按递增间隔重试，直至达到渠道的最大尝试次数，每次发送都会记录。",
+    nc_channels: "通知渠道",
+    nc_rules: "路由规则",
+    nc_deliveries: "投递记录",
+    nc_add_channel: "新增渠道",
+    nc_edit_channel: "编辑渠道",
+    nc_add_rule: "新增规则",
+    nc_edit_rule: "编辑规则",
+    nc_no_channels: "暂无通知渠道",
+    nc_no_rules: "暂无路由规则，不会发送任何通知",
+    nc_no_deliveries: "暂无投递记录",
+    nc_col_name: "名称",
+    nc_col_type: "类型",
+    nc_col_target: "目标",
+    nc_col_lang: "语言",
+    nc_col_status: "状态",
+    nc_col_op: "操作",
+    nc_col_min_severity: "最低级别",
+    nc_col_events: "事件",
+    nc_col_channels: "渠道",
+    nc_col_time: "时间",
+    nc_col_event: "事件",
+    nc_col_channel: "渠道",
+    nc_col_title: "标题",
+    nc_col_attempts: "尝试次数",
+    nc_enabled: "已启用",
+    nc_disabled: "已停用",
+    nc_all_events: "全部事件",
+    nc_test: "测试",
+    nc_edit: "编辑",
+    nc_delete: "删除",
+    nc_retry: "重试",
+    nc_test_sent: "测试通知已发送",
+    nc_test_failed: "测试通知发送失败",
+    nc_channel_saved: "渠道已保存",
+    nc_rule_saved: "规则已保存",
+    nc_retry_queued: "已重新加入投递队列",
+    nc_confirm_delete_channel: "确定删除渠道 {name}？该渠道也会从所有规则中移除。",
+    nc_confirm_delete_rule: "确定删除规则 {name}？",
+    nc_type_webhook: "Webhook",
+    nc_type_email: "邮件",
+    nc_type_slack: "Slack",
+    nc_type_dingtalk: "钉钉",
+    nc_type_feishu: "飞书",
+    nc_type_wecom: "企业微信",
+    nc_type_group_system: "全部系统消息",
+    nc_type_group_security: "全部安全消息",
+    nc_event_attack: "严重攻击",
+    nc_event_node_offline: "节点离线",
+    nc_event_node_online: "节点上线",
+    nc_event_node_clock_drift: "探针时钟偏差",
+    nc_event_node_clock_synced: "探针时钟恢复",
+    nc_event_decoy_compromised: "诱饵被触发",
+    nc_event_leaked_credential: "真实凭据被尝试",
+    nc_event_blocklist_failed: "黑名单刷新失败",
+    nc_event_ntp_failed: "NTP 同步失败",
+    nc_events_hint: "不选择则匹配全部事件",
+    nc_sev_info: "信息",
+    nc_sev_low: "低",
+    nc_sev_medium: "中",
+    nc_sev_warning: "警告",
+    nc_sev_high: "高",
+    nc_sev_critical: "严重",
+    nc_status_pending: "待发送",
+    nc_status_sent: "已发送",
+    nc_status_failed: "失败",
+    nc_url: "地址",
+    nc_url_hint_webhook: "以 JSON 格式接收事件",
+    nc_url_hint_slack: "Slack Incoming Webhook 地址",
+    nc_url_hint_dingtalk: "带 access_token 的机器人 Webhook 地址",
+    nc_url_hint_feishu: "自定义机器人 Webhook 地址",
+    nc_url_hint_wecom: "带 key 的群机器人 Webhook 地址",
+    nc_secret: "签名密钥",
+    nc_secret_hint_webhook: "使用 HMAC-SHA256 在 X-PRTS-Signature 中签名请求",
+    nc_secret_hint_dingtalk: "机器人安全设置中的加签密钥",
+    nc_secret_hint_feishu: "机器人签名校验密钥",
+    nc_secret_hint_wecom: "企业微信机器人无需密钥",
+    nc_secret_kept: "留空则保留已保存的值",
+    nc_smtp_host: "SMTP 服务器",
+    nc_smtp_port: "端口",
+    nc_smtp_port_hint: "留空使用加密方式的默认端口",
+    nc_smtp_tls: "加密方式",
+    nc_smtp_tls_none: "不加密",
+    nc_smtp_from: "发件人",
+    nc_smtp_to: "收件人",
+    nc_smtp_to_hint: "多个地址以逗号分隔",
+    nc_smtp_username: "用户名",
+    nc_smtp_password: "密码",
+    nc_max_attempts: "最大尝试次数",
+    nc_title_template: "标题模板",
+    nc_body_template: "正文模板",
+    nc_template_hint: "Go 模板，可用 .Title .Text .Event .Severity .Time .Params，留空使用默认模板",
+    nc_insecure: "接受自签名证书",
+    msg_attack_title: "严重攻击",
+    msg_attack_content: "来自 {ip} 的 {method} 攻击，节点 {node}，{status}：{payload}",
+    msg_attack_group_content: "来自 {sources} 个地址的 {count} 次严重 {method} 攻击。",
+    msg_alert_rate_limited_title: "告警已限流",
+    msg_alert_rate_limited_content: "消息中心触发限流，{sources} 类共 {count} 条告警已合并至此。",
+    mc_status_firing: "告警中",
+    mc_status_acknowledged: "已确认",
+    mc_status_resolved: "已解决",
+    mc_last_seen: "最近",
+    mc_acknowledge: "确认",
+    mc_resolve: "解决",
+    mc_assign: "指派",
+    mc_assignee: "用户名",
+    mc_assign_hint: "留空则取消指派。",
+    nc_event_alert_rate_limited: "告警限流",
+    nc_alert_folding: "告警合并与限流",
+    nc_alert_config_saved: "告警设置已保存",
+    nc_dedupe_window: "去重窗口（分钟）",
+    nc_dedupe_window_hint: "窗口内的相同告警只增加计数，0 为关闭",
+    nc_group_window: "分组窗口（分钟）",
+    nc_group_window_hint: "同类告警（如同一方式的攻击）合并为一条汇总",
+    nc_rate_limit: "限流（条/分钟）",
+    nc_rate_limit_hint: "每分钟新消息上限，超出部分合并为一条，0 为不限",
+    nc_silences: "维护静默",
+    nc_add_silence: "新增静默",
+    nc_no_silences: "暂无静默",
+    nc_col_node: "节点",
+    nc_col_window: "时间段",
+    nc_col_reason: "原因",
+    nc_col_suppressed: "已屏蔽",
+    nc_all_nodes: "全部节点",
+    nc_silence_active: "生效中",
+    nc_silence_scheduled: "待生效",
+    nc_silence_ended: "已结束",
+    nc_end_silence: "结束",
+    nc_silence_start: "开始时间",
+    nc_silence_end: "结束时间",
+    mc_subscription: "订阅设置",
+    mc_subscription_hint: "选择收件箱中显示并实时推送的消息类型与最低严重级别。未选择类型时接收全部类型。",
+    mc_subscription_types: "消息类型",
+    mc_subscription_min_severity: "最低严重级别",
+    mc_subscription_saved: "订阅已保存。",
+    mc_dismiss: "移除",
+    mc_dismissed: "消息已从你的收件箱移除。",
+    mc_dismiss_failed: "移除消息失败",
+    siem_title: "SIEM 转发",
+    siem_desc: "将攻击、扫描、捕获凭据、诱饵事件、样本与控制台登录通过 syslog 实时转发至 SIEM 采集器。",
+    siem_desc_formats: "每个目标可选择 UDP、TCP 或 TLS，以及 CEF、LEEF 或带 JSON 正文的 RFC 5424 格式，并按事件类型与等级过滤。",
+    siem_desc_queue: "采集器不可达时事件在磁盘排队，恢复后按序补发；超出队列上限时丢弃最早的事件。",
+    siem_destinations: "Syslog 目标",
+    siem_add: "新增目标",
+    siem_edit: "编辑目标",
+    siem_none: "暂无转发目标",
+    siem_saved: "目标已保存",
+    siem_confirm_delete: "确定删除目标 {name}？其队列中尚未发送的事件将被丢弃。",
+    siem_test_sent: "已发送最新的{kinds}",
+    siem_test_empty: "连接成功，但暂无可发送的事件",
+    siem_col_target: "目标地址",
+    siem_col_format: "格式",
+    siem_col_filter: "过滤",
+    siem_col_queue: "队列",
+    siem_col_delivery: "投递",
+    siem_connected: "已连接",
+    siem_disconnected: "未连接",
+    siem_dropped: "已丢弃 {n}",
+    siem_sent_count: "已发送 {sent} / 失败 {failures}",
+    siem_all_kinds: "全部类型",
+    siem_protocol: "协议",
+    siem_host: "主机",
+    siem_port: "端口",
+    siem_format_json: "RFC 5424 + JSON",
+    siem_framing: "分帧",
+    siem_framing_hint: "仅 TCP 与 TLS。默认 TCP 按换行、TLS 按字节计数。",
+    siem_framing_default: "默认",
+    siem_framing_octet: "字节计数",
+    siem_framing_newline: "换行",
+    siem_facility: "Facility",
+    siem_facility_hint: "0-23，16 为 local0",
+    siem_max_queue: "队列上限 (MB)",
+    siem_max_queue_hint: "待发送事件占用的磁盘空间",
+    siem_kinds: "事件类型",
+    siem_kinds_hint: "留空则转发全部类型",
+    siem_ca_cert: "CA 证书",
+    siem_ca_cert_hint: "PEM 格式，在系统根证书之外额外信任",
+    siem_kind_attack: "攻击",
+    siem_kind_scan: "扫描",
+    siem_kind_credential: "凭据",
+    siem_kind_decoy: "诱饵事件",
+    siem_kind_sample: "样本",
+    siem_kind_login: "控制台登录",
+    siem_desc_opensearch: "OpenSearch 与 Elasticsearch 输出以 ECS 字段批量索引同样的事件，按类型与日期分索引。",
+    siem_os_outputs: "OpenSearch / Elasticsearch",
+    siem_os_add: "新增输出",
+    siem_os_edit: "编辑输出",
+    siem_os_none: "暂无索引输出",
+    siem_os_saved: "输出已保存",
+    siem_os_confirm_delete: "确定删除输出 {name}？队列中尚未发送的文档将被丢弃，已索引的文档保留在集群中。",
+    siem_os_test_ok: "已连接 {distribution} {version} 集群 {cluster}",
+    siem_os_col_cluster: "集群 / 索引",
+    siem_os_indexed_count: "已索引 {indexed} / 拒绝 {rejected}",
+    siem_os_retried: "限流后重试 {n}",
+    siem_os_backfill_hint: "添加输出之前的历史数据可通过以下命令补录：",
+    siem_os_url: "集群地址",
+    siem_os_prefix: "索引前缀",
+    siem_os_prefix_hint: "索引命名为 前缀-类型-yyyy.mm.dd",
+    siem_os_api_key: "API Key",
+    siem_os_api_key_hint: "Base64 编码的 id:key，设置后替代用户名密码",
+    siem_os_batch_size: "批量大小",
+    siem_os_flush: "刷新间隔 (秒)",
+    siem_os_flush_hint: "未满的批次在最早的文档等待该时长后发送",
+    siem_os_manage_template: "安装索引模板",
+    siem_kind_node: "节点状态变化",
+    siem_desc_eventbus: "Kafka 与 NATS 输出以带版本的 JSON 或 Protobuf 发布事件与节点状态变化，按来源 IP 分区，消息头携带节点与事件类型。",
+    siem_bus_outputs: "事件总线 (Kafka / NATS)",
+    siem_bus_add: "添加事件总线输出",
+    siem_bus_edit: "编辑事件总线输出",
+    siem_bus_none: "暂无 Kafka 或 NATS 输出",
+    siem_bus_saved: "事件总线输出已保存",
+    siem_bus_confirm_delete: "删除事件总线输出 {name}？队列中未发送的消息将被丢弃。",
+    siem_bus_col_target: "代理 / 主题",
+    siem_bus_published_count: "已发布 {published} / 被拒 {rejected}",
+    siem_bus_type: "总线",
+    siem_bus_servers: "服务器",
+    siem_bus_servers_hint_kafka: "以逗号分隔的引导代理，格式 host:port",
+    siem_bus_servers_hint_nats: "以逗号分隔的 nats:// 或 tls:// 地址",
+    siem_bus_topic: "主题",
+    siem_bus_subject: "主题 (Subject)",
+    siem_bus_topic_hint: "{type} 会被替换为事件类型",
+    siem_bus_encoding: "编码",
+    siem_bus_encoding_json: "JSON (prts.event.v1)",
+    siem_bus_encoding_protobuf: "Protobuf (event.proto)",
+    siem_bus_sasl_hint: "SASL PLAIN，留空则不认证",
+    siem_bus_token: "认证令牌",
+    siem_bus_tls: "使用 TLS 连接",
+    siem_bus_jetstream: "等待 JetStream 确认存储每条消息",
+    siem_bus_core_hint: "未启用 JetStream 时，没有订阅者的消息会丢失",
+    ingest_title: "传感器接入",
+    ingest_desc: "第三方传感器的原生日志经由适配器映射为攻击、凭据、扫描与样本记录后接入。",
+    ingest_desc_http: "传感器可将 JSON 行、JSON 数组或 Zeek TSV 日志 携带采集密钥 POST 到适配器的端点；传感器名称取自密钥，若附加 ?sensor= 则须与之一致。",
+    ingest_desc_tail: "文件跟踪会跨越日志轮转与截断持续读取本机日志文件，重启后从保存的偏移量继续。",
+    ingest_desc_custom: "内置适配器支持 Cowrie、Suricata EVE、Zeek 与 OpenCanary。自定义适配器为 JSON 规格，以内置名称保存即可覆盖内置适配器。",
+    ingest_adapters: "适配器",
+    ingest_adapter_add: "新建适配器",
+    ingest_adapter_edit: "编辑适配器",
+    ingest_adapter_saved: "适配器已保存",
+    ingest_adapter_confirm_delete: "确认删除适配器 {name}？使用它的文件跟踪将停止读取。",
+    ingest_adapter_confirm_restore: "确认移除 {name} 的覆盖并恢复内置适配器？",
+    ingest_builtin: "内置",
+    ingest_custom: "自定义",
+    ingest_overrides: "覆盖内置",
+    ingest_customize: "自定义",
+    ingest_restore: "恢复内置",
+    ingest_col_description: "描述",
+    ingest_col_rules: "规则数",
+    ingest_col_endpoint: "接入端点",
+    ingest_spec: "规格 (JSON)",
+    ingest_spec_hint: "每条规则以 when/unless 正则匹配字段路径，并用 {field,alt,'literal'|filter} 模板填充一条记录的字段。",
+    ingest_spec_invalid: "规格不是有效的 JSON：{error}",
+    ingest_sample: "示例日志行",
+    ingest_sample_hint: "粘贴几行传感器日志以查看映射出的记录，预览不会写入任何数据。",
+    ingest_log_path: "Zeek 日志类型",
+    ingest_preview: "预览",
+    ingest_preview_empty: "示例中没有事件。",
+    ingest_line: "第 {n} 行",
+    ingest_no_rule: "没有匹配的规则，事件将被跳过。",
+    ingest_tails: "文件跟踪",
+    ingest_tails_none: "暂无跟踪的日志文件。",
+    ingest_tail_add: "跟踪文件",
+    ingest_tail_edit: "编辑文件跟踪",
+    ingest_tail_saved: "文件跟踪已保存",
+    ingest_tail_confirm_delete: "确认停止跟踪 {path}？",
+    ingest_col_path: "日志文件",
+    ingest_col_adapter: "适配器",
+    ingest_col_progress: "进度",
+    ingest_col_last_read: "最近读取",
+    ingest_progress: "{events} 事件 / {records} 记录 / {failed} 失败",
+    ingest_offset: "偏移 {n} B",
+    ingest_following: "跟踪中",
+    ingest_stalled: "已停滞",
+    ingest_sensor: "传感器",
+    ingest_sensor_hint: "日志未标明传感器时用于标注记录。",
+    ingest_path_hint: "本机上的绝对路径。Zeek 日志以文件名区分类型，例如 conn.log。",
+    ingest_from_start: "从头读取已有内容",
+    ingest_desc_keys: "每个采集请求都需要绑定到单个传感器的密钥；密钥各自限速，并可要求 HMAC 签名请求。被拒绝的事件会在下方保留 30 天。",
+    ingest_keys: "采集密钥",
+    ingest_keys_none: "暂无采集密钥，创建前传感器无法上报事件。",
+    ingest_key_add: "新建密钥",
+    ingest_key_edit: "编辑密钥",
+    ingest_key_saved: "采集密钥已保存。",
+    ingest_key_rotate: "轮换密钥",
+    ingest_key_confirm_rotate: "确认轮换 [{name}] 的密钥？当前密钥将立即失效。",
+    ingest_key_confirm_delete: "确认删除采集密钥 [{name}]？使用该密钥的传感器将被拒绝。",
+    ingest_key_copied: "密钥已复制到剪贴板。",
+    ingest_key_copy_failed: "无法复制密钥，请手动选中复制。",
+    ingest_key_expired: "已过期",
+    ingest_key_expires: "有效期至 {time}",
+    ingest_key_expiry: "过期时间",
+    ingest_key_expiry_hint: "留空表示永不过期。",
+    ingest_key_sensor_hint: "使用该密钥上报的事件只会记录在此传感器名下。",
+    ingest_key_issued: "密钥已签发",
+    ingest_key_issued_hint: "[{name}] 的密钥仅显示这一次，请立即保存到传感器上。",
+    ingest_key_done: "已保存",
+    ingest_key_bearer_hint: "以 Bearer 令牌发送：",
+    ingest_key_sign_hint: "或仅保存在传感器上并对每个请求签名（强制签名时必须）：",
+    ingest_rate_limit: "速率限制（事件/分钟）",
+    ingest_rate_limit_hint: "超出限制的事件返回 429。",
+    ingest_burst: "突发上限",
+    ingest_burst_hint: "单次可接受的最大批量；0 表示等于速率限制。",
+    ingest_require_signature: "要求 HMAC 签名请求",
+    ingest_signed_only: "仅签名",
+    ingest_rate: "{rate}/分钟，突发 {burst}",
+    ingest_usage: "接受 {accepted}，拒绝 {rejected}",
+    ingest_throttled: "限流 {n}",
+    ingest_col_rate: "限制",
+    ingest_col_usage: "用量",
+    ingest_col_last_used: "最近使用",
+    ingest_col_time: "时间",
+    ingest_col_error: "错误",
+    ingest_col_payload: "载荷",
+    ingest_rejections: "被拒事件",
+    ingest_rejections_none: "暂无被拒事件。",
+    ingest_rejections_clear: "清空",
+    ingest_rejections_confirm_clear: "确认删除此处显示的被拒事件？",
+    ingest_rejections_more: "显示最近 {shown} 条，共 {total} 条。",
+    ingest_all_keys: "全部密钥",
+    ingest_event_index: "第 {n} 个事件",
   }
 };
 
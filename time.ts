@@ -0,0 +1,32 @@
+// The API sends every timestamp as RFC 3339 in UTC, these helpers render them in the
+// browser's zone as YYYY-MM-DD HH:mm:ss
+
+const pad = (n: number) => String(n).padStart(2, '0');
+
+const parse = (value?: string | null): Date | null => {
+    if (!value) return null;
+    const d = new Date(value);
+    // Go's zero time marks a missing value
+    if (isNaN(d.getTime()) || d.getUTCFullYear() <= 1) return null;
+    return d;
+};
+
+export const formatDateTime = (value?: string | null, fallback = '-'): string => {
+    const d = parse(value);
+    if (!d) return value && isNaN(new Date(value).getTime()) ? value : fallback;
+    return `${d.getFullYear()}-${pad(d.getMonth() + 1)}-${pad(d.getDate())} ${pad(d.getHours())}:${pad(d.getMinutes())}:${pad(d.getSeconds())}`;
+};
+
+export const formatClock = (value?: string | null, fallback = '-'): string => {
+    const d = parse(value);
+    if (!d) return fallback;
+    return `${pad(d.getHours())}:${pad(d.getMinutes())}:${pad(d.getSeconds())}`;
+};
+
+// rangeParams turns the local datetime inputs of ArkDateRangePicker into the from/to
+// query parameters of the list APIs, an empty bound is left open
+export const rangeParams = (range: { start: string; end: string }, params = new URLSearchParams()): URLSearchParams => {
+    if (range.start) params.set('from', new Date(range.start).toISOString());
+    if (range.end) params.set('to', new Date(range.end).toISOString());
+    return params;
+};
@@ -3,6 +3,8 @@ import React from 'react';
 
 export type Lang = 'en' | 'zh';
 
+// Timestamps from the API are RFC 3339 strings in UTC, render them with the helpers in time.ts
+
 export interface User {
   username: string;
   role: 'admin' | 'user';
@@ -18,13 +20,42 @@ export interface NavItem {
   children?: NavItem[];
 }
 
+export type AlertStatus = 'firing' | 'acknowledged' | 'resolved';
+
 export interface Message {
   id: string;
   title: string;
   content: string;
   time: string;
   type: 'system' | 'security' | 'report';
-  read: boolean;
+  read: boolean; // Per user, as are archived messages which stay out of the inbox
+  archived?: boolean;
+  severity?: 'info' | 'warning' | 'critical';
+  // Alert state, repeats inside the folding window raise count instead of adding messages
+  node?: string;
+  count?: number;
+  lastSeen?: string;
+  status?: AlertStatus;
+  assignee?: string;
+  acknowledgedBy?: string;
+  resolvedBy?: string;
+}
+
+// Which messages a user sees and gets pushed, empty types means all of them
+export interface MessageSubscription {
+  types: string; // Comma-separated
+  minSeverity: 'info' | 'warning' | 'critical';
+}
+
+export interface AlertSilence {
+  id: string;
+  node: string; // Empty for every node
+  event: string; // Empty for every alert
+  reason: string;
+  startsAt: string;
+  endsAt: string;
+  createdBy: string;
+  suppressed: number;
 }
 
 export interface AttackLog {
@@ -36,6 +67,8 @@ export interface AttackLog {
   payload: string;
   severity: 'low' | 'medium' | 'high' | 'critical';
   status: 'blocked' | 'monitored' | 'compromised';
+  node?: string;
+  sensor?: string; // Other sensor whose log an adapter read, adapter/name
 }
 
 export interface NodeStatus {
@@ -58,6 +91,11 @@ export interface NodeStatus {
   interface?: string;
   mac?: string;
   firewallStatus?: 'active' | 'inactive' | 'error';
+  ruleVersion?: number;
+  groups?: string; // Comma-separated, e.g. "internet-facing"
+  clockOffset?: number; // Milliseconds the probe clock is ahead of the server
+  clockCheckedAt?: string | null;
+  clockDrift?: boolean;
 }
 
 export interface HackerProfile {
@@ -89,6 +127,7 @@ export interface SampleLog {
   attackerIp: string;
   sourceNode: string;
   sha256: string;
+  sensor?: string;
 }
 
 export interface VulnRule {
@@ -97,7 +136,7 @@ export interface VulnRule {
   type: string;
   severity: 'high' | 'medium' | 'low' | 'suspicious' | 'other';
   hitCount: number;
-  lastHitTime: string;
+  lastHitTime: string | null;
   creator: string;
   status: 'active' | 'inactive';
   updateTime: string;
@@ -148,6 +187,13 @@ export interface AccessControlRule {
   id: string;
   ip: string;
   type: 'blacklist' | 'whitelist';
+  protocol?: 'any' | 'tcp' | 'udp' | 'icmp';
+  ports?: string; // e.g. "22,80,8000-8100", empty for all ports
+  direction?: 'inbound' | 'outbound' | 'both';
+  scope?: 'all' | 'nodes' | 'groups';
+  targets?: string; // Comma-separated node IDs or group names
+  targetNodes?: string[]; // Nodes in scope
+  enforcingNodes?: string[]; // Online nodes that applied the rule with enforcement on
   reason: string;
   addTime: string;
   expireTime: string | null; // null means permanent
@@ -155,6 +201,27 @@ export interface AccessControlRule {
   status: 'active' | 'expired';
 }
 
+export interface BlocklistSubscription {
+  id: string;
+  name: string; // Source of the rules it creates
+  url: string; // Either url or path is set
+  path: string;
+  format: 'plain' | 'spamhaus' | 'csv';
+  ruleType: 'blacklist' | 'whitelist';
+  scope: 'all' | 'nodes' | 'groups';
+  targets: string;
+  refreshInterval: number; // Minutes
+  ruleTtl: number; // Minutes, 0 for permanent
+  maxEntries: number;
+  status: 'pending' | 'active' | 'paused';
+  entryCount: number;
+  lastFetch: string;
+  lastSuccess: string;
+  lastError: string;
+  previewedAt: string;
+  createdAt: string;
+}
+
 export interface DefenseStrategy {
   id: string;
   name: string;
@@ -172,4 +239,253 @@ export interface TrafficRule {
   pattern: string;
   status: 'active' | 'inactive';
   hits: number;
-}
\ No newline at end of file
+}
+// A full-text search hit, snippet is HTML with the text escaped and matches in <mark>
+export interface SearchHit {
+  kind: 'attack' | 'credential' | 'sample';
+  refId: string;
+  ip: string;
+  time: string;
+  snippet: string;
+}
+
+export type NotificationChannelType = 'webhook' | 'email' | 'slack' | 'dingtalk' | 'feishu' | 'wecom';
+
+// Secrets are write-only, hasSecret and hasPassword tell whether one is stored
+export interface NotificationChannelSettings {
+  url?: string;
+  secret?: string;
+  host?: string;
+  port?: number;
+  tls?: 'none' | 'starttls' | 'tls';
+  username?: string;
+  password?: string;
+  from?: string;
+  to?: string; // Comma-separated
+  insecureSkipVerify?: boolean;
+}
+
+export interface NotificationChannel {
+  id: string;
+  name: string;
+  type: NotificationChannelType;
+  enabled: boolean;
+  lang: 'en' | 'zh';
+  titleTemplate: string;
+  bodyTemplate: string;
+  maxAttempts: number;
+  settings: NotificationChannelSettings;
+  hasSecret?: boolean;
+  hasPassword?: boolean;
+  createdAt?: string;
+}
+
+export interface NotificationRule {
+  id: string;
+  name: string;
+  enabled: boolean;
+  minSeverity: string;
+  events: string; // Comma-separated, empty for every event
+  channels: string; // Comma-separated channel IDs
+  createdAt?: string;
+}
+
+export interface NotificationDelivery {
+  id: number;
+  eventId: string;
+  event: string;
+  severity: string;
+  channelId: string;
+  channelName: string;
+  channelType: NotificationChannelType;
+  title: string;
+  body: string;
+  status: 'pending' | 'sent' | 'failed';
+  attempts: number;
+  nextAttempt: string | null;
+  lastError: string;
+  statusCode: number;
+  createdAt: string;
+  sentAt: string | null;
+}
+
+export type SiemKind = 'attack' | 'scan' | 'credential' | 'decoy' | 'sample' | 'login';
+
+export interface SyslogDestination {
+  id: string;
+  name: string;
+  enabled: boolean;
+  host: string;
+  port: number;
+  protocol: 'udp' | 'tcp' | 'tls';
+  format: 'cef' | 'leef' | 'json';
+  framing: '' | 'octet' | 'newline';
+  facility: number;
+  kinds: string; // Comma-separated, empty for every kind
+  minSeverity: string;
+  caCert: string;
+  insecureSkipVerify: boolean;
+  maxQueueMb: number;
+  createdAt?: string;
+  // Delivery state of the running output
+  running?: boolean;
+  connected?: boolean;
+  queueLength?: number;
+  queueBytes?: number;
+  queued?: number;
+  sent?: number;
+  failures?: number;
+  dropped?: number;
+  lastError?: string;
+  lastErrorAt?: string | null;
+  lastSentAt?: string | null;
+}
+
+export interface OpenSearchOutput {
+  id: string;
+  name: string;
+  enabled: boolean;
+  url: string;
+  username: string;
+  password?: string; // Write only, empty keeps the stored one
+  apiKey?: string;
+  indexPrefix: string;
+  kinds: string; // Comma-separated, empty for every kind
+  minSeverity: string;
+  batchSize: number;
+  flushSeconds: number;
+  skipTemplate: boolean;
+  caCert: string;
+  insecureSkipVerify: boolean;
+  maxQueueMb: number;
+  createdAt?: string;
+  hasPassword?: boolean;
+  hasApiKey?: boolean;
+  // Indexing state of the running output
+  running?: boolean;
+  queueLength?: number;
+  queueBytes?: number;
+  queued?: number;
+  indexed?: number;
+  rejected?: number;
+  retried?: number;
+  failures?: number;
+  dropped?: number;
+  lastError?: string;
+  lastErrorAt?: string | null;
+  lastIndexedAt?: string | null;
+}
+
+// Node status changes only go to the event bus
+export type EventBusKind = SiemKind | 'node';
+
+export interface EventBusOutput {
+  id: string;
+  name: string;
+  enabled: boolean;
+  type: 'kafka' | 'nats';
+  servers: string; // Comma-separated Kafka brokers or NATS URLs
+  topic: string; // {type} is replaced by the event type
+  encoding: 'json' | 'protobuf';
+  kinds: string; // Comma-separated, empty for every type
+  minSeverity: string;
+  jetStream: boolean;
+  username: string;
+  password?: string; // Write only, empty keeps the stored one
+  token?: string;
+  tls: boolean;
+  caCert: string;
+  insecureSkipVerify: boolean;
+  maxQueueMb: number;
+  createdAt?: string;
+  hasPassword?: boolean;
+  hasToken?: boolean;
+  // Delivery state of the running output
+  running?: boolean;
+  connected?: boolean;
+  queueLength?: number;
+  queueBytes?: number;
+  queued?: number;
+  published?: number;
+  rejected?: number;
+  failures?: number;
+  dropped?: number;
+  lastError?: string;
+  lastErrorAt?: string | null;
+  lastPublishedAt?: string | null;
+}
+
+export type IngestRecordKind = 'attack' | 'credential' | 'scan' | 'sample';
+
+export interface IngestAdapter {
+  id?: string; // Stored adapters only
+  name: string;
+  description: string;
+  builtin: boolean;
+  overrides: boolean; // Stored under the name of a built-in, which it replaces
+  rules: number;
+  spec: Record<string, unknown>;
+  updatedAt?: string | null;
+}
+
+export interface IngestRecord {
+  kind: IngestRecordKind;
+  rule: string;
+  time?: string;
+  sensor?: string;
+  fields: Record<string, string>;
+}
+
+export interface IngestPreview {
+  line: number;
+  event?: Record<string, unknown>;
+  records: IngestRecord[];
+  error?: string;
+}
+
+export interface IngestTail {
+  id: string;
+  path: string;
+  adapter: string;
+  sensor: string;
+  enabled: boolean;
+  fromStart: boolean;
+  offset?: number;
+  events?: number;
+  records?: number;
+  failed?: number;
+  lastError?: string;
+  lastErrorAt?: string | null;
+  lastReadAt?: string | null;
+  running?: boolean;
+}
+
+export interface IngestKey {
+  id: string;
+  name: string;
+  keyId: string; // Public part of the key, names it in signed requests
+  sensor: string;
+  enabled: boolean;
+  requireSignature: boolean;
+  rateLimit: number; // Events per minute
+  burst: number; // 0 for a minute's worth
+  expiresAt?: string | null;
+  accepted?: number;
+  rejected?: number;
+  throttled?: number;
+  lastUsedAt?: string | null;
+  lastUsedIp?: string;
+  key?: string; // Only in the answer to create and rotate
+}
+
+export interface IngestRejection {
+  id: string;
+  time: string;
+  endpoint: string;
+  keyId: string;
+  sensor: string;
+  remoteIp: string;
+  index: number;
+  error: string;
+  payload: string;
+}